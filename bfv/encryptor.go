@@ -63,12 +63,12 @@ type encryptor struct {
 	polypool [3]*ring.Poly
 
 	baseconverter              *ring.FastBasisExtender
-	gaussianSamplerQ           *ring.GaussianSampler
-	uniformSamplerQ            *ring.UniformSampler
-	ternarySamplerMontgomeryQ  *ring.TernarySampler
-	gaussianSamplerQP          *ring.GaussianSampler
-	uniformSamplerQP           *ring.UniformSampler
-	ternarySamplerMontgomeryQP *ring.TernarySampler
+	gaussianSamplerQ           ring.Sampler
+	uniformSamplerQ            ring.Sampler
+	ternarySamplerMontgomeryQ  ring.Sampler
+	gaussianSamplerQP          ring.Sampler
+	uniformSamplerQP           ring.Sampler
+	ternarySamplerMontgomeryQP ring.Sampler
 }
 
 type pkEncryptor struct {
@@ -127,7 +127,7 @@ func newEncryptor(params *Parameters) encryptor {
 		polypool:                   [3]*ring.Poly{ringQP.NewPoly(), ringQP.NewPoly(), ringQP.NewPoly()},
 		baseconverter:              baseconverter,
 		gaussianSamplerQ:           ring.NewGaussianSampler(prng, ringQ, params.Sigma(), uint64(6*params.Sigma())),
-		uniformSamplerQ:            ring.NewUniformSampler(prng, ringQ),
+		uniformSamplerQ:            ring.NewUniformSamplerWithDomain(prng, ringQ, true, false),
 		ternarySamplerMontgomeryQ:  ring.NewTernarySampler(prng, ringQ, 0.5, true),
 		gaussianSamplerQP:          ring.NewGaussianSampler(prng, ringQP, params.Sigma(), uint64(6*params.Sigma())),
 		uniformSamplerQP:           ring.NewUniformSampler(prng, ringQP),
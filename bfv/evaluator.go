@@ -315,7 +315,7 @@ func (eval *evaluator) tensorAndRescale(ct0, ct1, ctOut *Element) {
 	c2Q2 := eval.poolQmul[2]
 
 	for i := range ct0.value {
-		eval.baseconverterQ1Q2.ModUpSplitQP(levelQ, ct0.value[i], c0Q2[i])
+		eval.baseconverterQ1Q2.ModUpQtoP(levelQ, ct0.value[i], c0Q2[i])
 
 		eval.ringQ.NTT(ct0.value[i], c0Q1[i])
 		eval.ringQMul.NTT(c0Q2[i], c0Q2[i])
@@ -324,7 +324,7 @@ func (eval *evaluator) tensorAndRescale(ct0, ct1, ctOut *Element) {
 	if ct0 != ct1 {
 
 		for i := range ct1.value {
-			eval.baseconverterQ1Q2.ModUpSplitQP(levelQ, ct1.value[i], c1Q2[i])
+			eval.baseconverterQ1Q2.ModUpQtoP(levelQ, ct1.value[i], c1Q2[i])
 
 			eval.ringQ.NTT(ct1.value[i], c1Q1[i])
 			eval.ringQMul.NTT(c1Q2[i], c1Q2[i])
@@ -721,6 +721,9 @@ func (eval *evaluator) switchKeysInPlace(cx *ring.Poly, evakey *SwitchingKey, po
 
 	reduce = 0
 
+	// Number of accumulated products between two reductions
+	period := utils.MinUint64(ringQ.LazyReductionPeriod(), ringP.LazyReductionPeriod())
+
 	// Key switching with CRT decomposition for the Qi
 	for i := uint64(0); i < eval.params.Beta(); i++ {
 
@@ -743,7 +746,7 @@ func (eval *evaluator) switchKeysInPlace(cx *ring.Poly, evakey *SwitchingKey, po
 			ringP.MulCoeffsMontgomeryAndAddNoMod(evakey1P, c2QiP, pool3P)
 		}
 
-		if reduce&7 == 1 {
+		if reduce%period == 1 {
 			ringQ.ReduceLvl(level, pool2Q, pool2Q)
 			ringQ.ReduceLvl(level, pool3Q, pool3Q)
 			ringP.Reduce(pool2P, pool2P)
@@ -753,7 +756,7 @@ func (eval *evaluator) switchKeysInPlace(cx *ring.Poly, evakey *SwitchingKey, po
 		reduce++
 	}
 
-	if (reduce-1)&7 != 1 {
+	if (reduce-1)%period != 1 {
 		ringQ.ReduceLvl(level, pool2Q, pool2Q)
 		ringQ.ReduceLvl(level, pool3Q, pool3Q)
 		ringP.Reduce(pool2P, pool2P)
@@ -765,8 +768,8 @@ func (eval *evaluator) switchKeysInPlace(cx *ring.Poly, evakey *SwitchingKey, po
 	ringP.InvNTT(pool2P, pool2P)
 	ringP.InvNTT(pool3P, pool3P)
 
-	eval.baseconverterQ1P.ModDownSplitPQ(level, pool2Q, pool2P, pool2Q)
-	eval.baseconverterQ1P.ModDownSplitPQ(level, pool3Q, pool3P, pool3Q)
+	eval.baseconverterQ1P.ModDownSplitQPtoQ(level, pool2Q, pool2P, pool2Q)
+	eval.baseconverterQ1P.ModDownSplitQPtoQ(level, pool3Q, pool3P, pool3Q)
 }
 
 // decomposeAndSplitNTT decomposes the input polynomial into the target CRT basis.
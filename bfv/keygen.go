@@ -26,8 +26,8 @@ type keyGenerator struct {
 	ringQP           *ring.Ring
 	pBigInt          *big.Int
 	polypool         [2]*ring.Poly
-	gaussianSampler  *ring.GaussianSampler
-	uniformSampler   *ring.UniformSampler
+	gaussianSampler  ring.Sampler
+	uniformSampler   ring.Sampler
 	galElRotRow      uint64   // Rows rotation generator
 	galElRotColLeft  []uint64 // Columns right rotations generators
 	galElRotColRight []uint64 // Columsn left rotations generators
@@ -108,7 +108,7 @@ func NewKeyGenerator(params *Parameters) KeyGenerator {
 		pBigInt:          pBigInt,
 		polypool:         [2]*ring.Poly{ringQP.NewPoly(), ringQP.NewPoly()},
 		gaussianSampler:  ring.NewGaussianSampler(prng, ringQP, params.Sigma(), uint64(6*params.Sigma())),
-		uniformSampler:   ring.NewUniformSampler(prng, ringQP),
+		uniformSampler:   ring.NewUniformSamplerWithDomain(prng, ringQP, true, true),
 		galElRotColLeft:  ring.GenGaloisParams(params.N(), GaloisGen),
 		galElRotColRight: ring.GenGaloisParams(params.N(), ring.ModExp(GaloisGen, 2*params.N()-1, 2*params.N())),
 		galElRotRow:      2*params.N() - 1,
@@ -447,8 +447,7 @@ func (keygen *keyGenerator) newSwitchingKey(skIn, skOut *ring.Poly) (switchingke
 
 		// e
 		switchingkey.evakey[i][0] = keygen.gaussianSampler.ReadNew()
-		ringQP.NTT(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
-		ringQP.MForm(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
+		ringQP.NTTMForm(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
 		// a
 		switchingkey.evakey[i][1] = keygen.uniformSampler.ReadNew()
 
@@ -22,7 +22,7 @@ func (ciphertext *Ciphertext) MarshalBinary() (data []byte, err error) {
 
 	for _, el := range ciphertext.value {
 
-		if inc, err = el.WriteTo(data[pointer:]); err != nil {
+		if inc, err = el.Encode(data[pointer:]); err != nil {
 			return nil, err
 		}
 
@@ -83,7 +83,7 @@ func (sk *SecretKey) MarshalBinary() (data []byte, err error) {
 
 	data = make([]byte, sk.GetDataLen(true))
 
-	if _, err = sk.sk.WriteTo(data); err != nil {
+	if _, err = sk.sk.Encode(data); err != nil {
 		return nil, err
 	}
 
@@ -121,11 +121,11 @@ func (pk *PublicKey) MarshalBinary() (data []byte, err error) {
 
 	var pointer, inc uint64
 
-	if inc, err = pk.pk[0].WriteTo(data[pointer:]); err != nil {
+	if inc, err = pk.pk[0].Encode(data[pointer:]); err != nil {
 		return nil, err
 	}
 
-	if _, err = pk.pk[1].WriteTo(data[pointer+inc:]); err != nil {
+	if _, err = pk.pk[1].Encode(data[pointer+inc:]); err != nil {
 		return nil, err
 	}
 
@@ -258,13 +258,13 @@ func (switchkey *SwitchingKey) encode(pointer uint64, data []byte) (uint64, erro
 
 	for j := uint64(0); j < uint64(len(switchkey.evakey)); j++ {
 
-		if inc, err = switchkey.evakey[j][0].WriteTo(data[pointer : pointer+switchkey.evakey[j][0].GetDataLen(true)]); err != nil {
+		if inc, err = switchkey.evakey[j][0].Encode(data[pointer : pointer+switchkey.evakey[j][0].GetDataLen(true)]); err != nil {
 			return pointer, err
 		}
 
 		pointer += inc
 
-		if inc, err = switchkey.evakey[j][1].WriteTo(data[pointer : pointer+switchkey.evakey[j][1].GetDataLen(true)]); err != nil {
+		if inc, err = switchkey.evakey[j][1].Encode(data[pointer : pointer+switchkey.evakey[j][1].GetDataLen(true)]); err != nil {
 			return pointer, err
 		}
 
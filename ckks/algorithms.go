@@ -19,13 +19,13 @@ func (eval *evaluator) PowerOf2(op *Ciphertext, logPow2 uint64, evakey *Evaluati
 
 		eval.MulRelin(op.El(), op.El(), evakey, opOut)
 
-		eval.Rescale(opOut, eval.scale, opOut)
+		eval.Rescale(opOut, eval.rescaleThreshold(), opOut)
 
 		for i := uint64(1); i < logPow2; i++ {
 
 			eval.MulRelin(opOut.El(), opOut.El(), evakey, opOut)
 
-			eval.Rescale(opOut, eval.scale, opOut)
+			eval.Rescale(opOut, eval.rescaleThreshold(), opOut)
 		}
 	}
 }
@@ -64,7 +64,7 @@ func (eval *evaluator) Power(op *Ciphertext, degree uint64, evakey *EvaluationKe
 
 		eval.MulRelin(opOut.El(), tmp.El(), evakey, opOut)
 
-		eval.Rescale(opOut, eval.scale, opOut)
+		eval.Rescale(opOut, eval.rescaleThreshold(), opOut)
 
 		degree -= po2Degree
 	}
@@ -85,13 +85,13 @@ func (eval *evaluator) InverseNew(op *Ciphertext, steps uint64, evakey *Evaluati
 
 		eval.MulRelin(cbar.El(), cbar.El(), evakey, cbar.Ciphertext())
 
-		eval.Rescale(cbar, eval.scale, cbar)
+		eval.Rescale(cbar, eval.rescaleThreshold(), cbar)
 
 		tmp = eval.AddConstNew(cbar, 1)
 
 		eval.MulRelin(tmp.El(), opOut.El(), evakey, tmp.Ciphertext())
 
-		eval.Rescale(tmp, eval.scale, tmp)
+		eval.Rescale(tmp, eval.rescaleThreshold(), tmp)
 
 		opOut = tmp.CopyNew().Ciphertext()
 	}
@@ -0,0 +1,135 @@
+package ckks
+
+import (
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// BatchEvaluator evaluates a sequence of operations on Ciphertexts known to be consistent, without the validation and
+// the normalization of the operands done by the Evaluator at each call. It is obtained through Evaluator.Batch and
+// recovers the per-call overhead of the Evaluator in workloads made of many small operations.
+//
+// The operands of a BatchEvaluator must be Ciphertexts of degree one in the NTT domain. The operations are done at
+// the minimum level of their operands and the receivers are truncated to this level. Unlike the Evaluator, Add and Sub
+// do not match the scales of their operands, which must therefore be equal. The result of operations on operands
+// not satisfying those conditions is undefined.
+type BatchEvaluator struct {
+	eval *evaluator
+}
+
+// Batch calls f with a BatchEvaluator sharing the memory pools of the evaluator and returns the error returned by f.
+// The evaluator must not be used concurrently while f runs.
+func (eval *evaluator) Batch(f func(b *BatchEvaluator) error) (err error) {
+	return f(&BatchEvaluator{eval: eval})
+}
+
+// batchLevel returns the level of an operation on ct0 and ct1 with receiver ctOut, and truncates ctOut to this level.
+func batchLevel(ct0, ct1, ctOut *Ciphertext) (level uint64) {
+	level = utils.MinUint64(utils.MinUint64(ct0.Level(), ct1.Level()), ctOut.Level())
+	ctOut.value[0].Coeffs = ctOut.value[0].Coeffs[:level+1]
+	ctOut.value[1].Coeffs = ctOut.value[1].Coeffs[:level+1]
+	return
+}
+
+// Add adds ct0 to ct1 and returns the result in ctOut. ct0 and ct1 must have the same scale.
+func (b *BatchEvaluator) Add(ct0, ct1, ctOut *Ciphertext) {
+	level := batchLevel(ct0, ct1, ctOut)
+	b.eval.ringQ.AddLvl(level, ct0.value[0], ct1.value[0], ctOut.value[0])
+	b.eval.ringQ.AddLvl(level, ct0.value[1], ct1.value[1], ctOut.value[1])
+	ctOut.scale = ct0.scale
+}
+
+// Sub subtracts ct1 from ct0 and returns the result in ctOut. ct0 and ct1 must have the same scale.
+func (b *BatchEvaluator) Sub(ct0, ct1, ctOut *Ciphertext) {
+	level := batchLevel(ct0, ct1, ctOut)
+	b.eval.ringQ.SubLvl(level, ct0.value[0], ct1.value[0], ctOut.value[0])
+	b.eval.ringQ.SubLvl(level, ct0.value[1], ct1.value[1], ctOut.value[1])
+	ctOut.scale = ct0.scale
+}
+
+// MulRelin multiplies ct0 by ct1, relinearizes the result with evakey and returns it in ctOut.
+func (b *BatchEvaluator) MulRelin(ct0, ct1 *Ciphertext, evakey *EvaluationKey, ctOut *Ciphertext) {
+
+	eval := b.eval
+	ringQ := eval.ringQ
+
+	level := batchLevel(ct0, ct1, ctOut)
+
+	// The multiplication commutes, so the operand overwritten by ctOut is the one put in Montgomery form
+	if ct1 == ctOut {
+		ct0, ct1 = ct1, ct0
+	}
+
+	eval.growPools(level)
+
+	c00, c01, c2 := eval.poolQMul[0], eval.poolQMul[1], eval.poolQMul[2]
+
+	ringQ.MFormLvl(level, ct0.value[0], c00)
+	ringQ.MFormLvl(level, ct0.value[1], c01)
+
+	ringQ.MulCoeffsMontgomeryLvl(level, c01, ct1.value[1], c2)
+	ringQ.MulCoeffsMontgomeryLvl(level, c00, ct1.value[1], ctOut.value[1])
+	if ct0 == ct1 {
+		ringQ.AddLvl(level, ctOut.value[1], ctOut.value[1], ctOut.value[1])
+	} else {
+		ringQ.MulCoeffsMontgomeryAndAddNoModLvl(level, c01, ct1.value[0], ctOut.value[1])
+	}
+	ringQ.MulCoeffsMontgomeryLvl(level, c00, ct1.value[0], ctOut.value[0])
+
+	eval.switchKeysInPlace(level, c2, evakey.evakey, eval.poolQ[1], eval.poolQ[2])
+
+	ringQ.AddLvl(level, ctOut.value[0], eval.poolQ[1], ctOut.value[0])
+	ringQ.AddLvl(level, ctOut.value[1], eval.poolQ[2], ctOut.value[1])
+
+	ctOut.scale = ct0.scale * ct1.scale
+}
+
+// MulPlain multiplies ct0 by the plaintext operand pt, which can be a PrecomputedOperand, and returns the result in ctOut.
+func (b *BatchEvaluator) MulPlain(ct0 *Ciphertext, pt Operand, ctOut *Ciphertext) {
+
+	ringQ := b.eval.ringQ
+
+	el := pt.El()
+
+	level := batchLevel(ct0, ct0, ctOut)
+	level = utils.MinUint64(level, el.Level())
+	ctOut.value[0].Coeffs = ctOut.value[0].Coeffs[:level+1]
+	ctOut.value[1].Coeffs = ctOut.value[1].Coeffs[:level+1]
+
+	b.eval.growPools(level)
+
+	c := valueMFormLvl(ringQ, level, pt, el, 0, b.eval.poolQMul[0])
+
+	ringQ.MulCoeffsMontgomeryLvl(level, c, ct0.value[0], ctOut.value[0])
+	ringQ.MulCoeffsMontgomeryLvl(level, c, ct0.value[1], ctOut.value[1])
+
+	ctOut.scale = ct0.scale * el.scale
+}
+
+// Rescale divides ct0 by its last modulus and returns the result in ctOut, one level below ct0.
+func (b *BatchEvaluator) Rescale(ct0, ctOut *Ciphertext) {
+
+	level := ct0.Level()
+
+	if ctOut != ct0 {
+		for i := range ctOut.value {
+			ctOut.value[i].Coeffs = ctOut.value[i].Coeffs[:level+1]
+			b.eval.ringQ.CopyLvl(level, ct0.value[i], ctOut.value[i])
+		}
+	}
+
+	for i := range ctOut.value {
+		b.eval.ringQ.DivRoundByLastModulusNTT(ctOut.value[i])
+	}
+
+	ctOut.scale = ct0.scale / float64(b.eval.ringQ.Modulus[level])
+}
+
+// RotateColumns rotates the columns of ct0 by k positions to the left and returns the result in ctOut.
+// The key of this exact rotation must be in evakey.
+func (b *BatchEvaluator) RotateColumns(ct0 *Ciphertext, k uint64, evakey *RotationKeys, ctOut *Ciphertext) {
+	k &= (b.eval.ringQ.N >> 1) - 1
+	batchLevel(ct0, ct0, ctOut)
+	index, swk := evakey.rotationKey(k)
+	b.eval.permuteNTT(ct0, index, swk, ctOut)
+	ctOut.scale = ct0.scale
+}
@@ -10,22 +10,237 @@ import (
 )
 
 // Bootstrapp re-encrypt a ciphertext at lvl Q0 to a ciphertext at MaxLevel-k where k is the depth of the bootstrapping circuit.
+// The output has the scale of the input. If the ratio between this scale and the default scale is not an integer, the
+// scale is corrected with a rescaling and the output is one level lower.
 func (btp *Bootstrapper) Bootstrapp(ct *Ciphertext) *Ciphertext {
+	return btp.bootstrapp(ct, btp.OutputLevel(), ct.Scale(), nil, nil)
+}
+
+// BootstrappLvl re-encrypts a ciphertext at lvl Q0 to a ciphertext at the given level, which must not be larger than
+// OutputLevel. When the application only needs a few levels, the SlotsToCoeffs step is evaluated at the levels right
+// above the target level instead of the top of the SlotsToCoeffs ladder, which saves a significant part of its cost.
+// The matrices of SlotsToCoeffs are re-encoded for the target level on the first call with this level. The output has
+// the scale of the input, and is at the given level as long as it is lower than OutputLevel, see Bootstrapp.
+func (btp *Bootstrapper) BootstrappLvl(level uint64, ct *Ciphertext) *Ciphertext {
+
+	if level > btp.OutputLevel() {
+		panic("cannot BootstrappLvl: level is larger than the output level of the bootstrapping")
+	}
+
+	return btp.bootstrapp(ct, level, ct.Scale(), nil, nil)
+}
+
+// BootstrappReal re-encrypts two ciphertexts at lvl Q0 encrypting real values with a single bootstrapping, which roughly
+// halves its amortized cost: ct0 and ct1 are packed as ct0 + i*ct1, bootstrapped, and split back with one conjugation.
+// The imaginary part of the slots of ct0 and ct1 must be zero. The outputs have the scale of the inputs: as the split
+// halves the values by doubling the scale, the bootstrapping outputs half this scale. If half the scale is not an
+// integer multiple of the default scale, this costs a rescaling and the outputs are one level lower than the output of
+// Bootstrapp, see Bootstrapp.
+func (btp *Bootstrapper) BootstrappReal(ct0, ct1 *Ciphertext) (ctOut0, ctOut1 *Ciphertext) {
+
+	ct := btp.evaluator.MergeComplex(ct0, ct1)
+	ct = btp.bootstrapp(ct, btp.OutputLevel(), ct.Scale()/2, nil, nil)
+
+	return btp.evaluator.SplitComplex(ct, btp.rotkeys)
+}
+
+// BootstrappIterative re-encrypts a ciphertext at lvl Q0 with two passes of the bootstrapping circuit, trading twice the
+// time of Bootstrapp for up to logAmplification more bits of output precision (META-BTS). The second pass bootstraps
+// the error of the first one, measured at level 0 against the input and amplified by 2^logAmplification, and is then
+// added to the output of the first pass. The amplified error must remain in the range of the bootstrapping, hence
+// logAmplification must be a few bits smaller than the precision of Bootstrapp. The output is at the same level with a
+// scale 2^logAmplification times the one of the input.
+func (btp *Bootstrapper) BootstrappIterative(ct *Ciphertext, logAmplification uint64) *Ciphertext {
+
+	amplification := math.Exp2(float64(logAmplification))
+
+	// The input is kept at level 0 to measure the error of the first pass
+	ctIn := ct.CopyNew().Ciphertext()
+	btp.evaluator.DropLevel(ctIn, ctIn.Level())
+
+	ctOut := btp.Bootstrapp(ct)
+
+	// -error = input - output, amplified by reinterpreting its scale
+	ctErr := ctOut.CopyNew().Ciphertext()
+	btp.evaluator.DropLevel(ctErr, ctErr.Level())
+	btp.evaluator.Sub(ctIn, ctErr, ctErr)
+	ctErr.DivScale(amplification)
+
+	// The amplified error is bootstrapped at the scale of the output of the first pass, so that the error of the second
+	// pass is not amplified back
+	ctErr = btp.bootstrapp(ctErr, btp.OutputLevel(), ctOut.Scale(), nil, nil)
+	ctErr.MulScale(amplification)
+
+	// The output is multiplied by the ratio of the scales, 2^logAmplification, hence the error is added exactly
+	btp.evaluator.Add(ctOut, ctErr, ctOut)
+
+	btp.logger.Debug("ckks: bootstrapping: error of the first pass corrected", "log_amplification", logAmplification, "scale", ctOut.Scale())
+
+	return ctOut
+}
+
+// BootstrappSlim re-encrypts a ciphertext with the slim bootstrapping, which evaluates the stages in the order
+// SlotsToCoeffs, ModRaise, CoeffsToSlots and EvalMod. SlotsToCoeffs is evaluated on the input at the lowest levels, from
+// level len(StCLevel) down to level 0, hence the input must be at level len(StCLevel) or higher, and the output is at
+// the level of the output of EvalMod, StCLevel[0], which is higher than OutputLevel, and at the default scale. This
+// order is beneficial when the application consumes or produces coefficient-encoded data, such as transciphered
+// streams. The slim bootstrapping requires fully packed ciphertexts.
+func (btp *Bootstrapper) BootstrappSlim(ct *Ciphertext) *Ciphertext {
+
+	if btp.repack {
+		panic("cannot BootstrappSlim: the ciphertexts must be fully packed")
+	}
+
+	pDFT := btp.slotsToCoeffsMatricesSlim()
+
+	if ct.Level() < uint64(len(pDFT)) {
+		panic("cannot BootstrappSlim: the level of the input is smaller than the depth of SlotsToCoeffs")
+	}
+
+	btp.evaluator.DropLevel(ct, ct.Level()-uint64(len(pDFT)))
+
+	// Part 1 : Slots to coeffs, with the scale of the input as reference scale for the rescaling. The reference scale
+	// of the evaluator is restored for the next stages, and for the next calls once EvalMod has changed it.
+	eval := btp.evaluator.(*evaluator)
+	defaultScale := eval.scale
+	defer func() { eval.scale = defaultScale }()
+
+	eval.scale = ct.Scale()
+	ct = btp.dft(ct, pDFT, false)
+	eval.scale = defaultScale
+
+	btp.logStage(StageSlotsToCoeffs, ct)
+
+	// Part 2 : ModRaise, Coeffs to slots and SineEval
+	ct0, ct1 := btp.modRaiseAndEvalMod(ct, btp.params.scale, nil, nil)
+
+	btp.evaluator.MultByi(ct1, ct1)
+	btp.evaluator.Add(ct0, ct1, ct0)
+
+	return ct0
+}
+
+// OutputLevel returns the level of the ciphertexts returned by Bootstrapp.
+func (btp *Bootstrapper) OutputLevel() uint64 {
+	return btp.StCLevel[len(btp.StCLevel)-1] - 1
+}
+
+// bootstrapp bootstraps the ciphertext to the given level and scale and reports its stages to the diagnoser and to the
+// progress, if not nil. It returns nil if the context of the progress is done at the end of a stage before SlotsToCoeffs.
+func (btp *Bootstrapper) bootstrapp(ct *Ciphertext, level uint64, scale float64, diag *bootstrappDiagnoser, progress *bootstrappProgress) *Ciphertext {
 	//var t time.Time
 	var ct0, ct1 *Ciphertext
 
+	if ct.Level() != 0 {
+		btp.logger.Debug("ckks: bootstrapping: dropping the input to level 0", "level", ct.Level())
+	}
+
 	for ct.Level() != 0 {
 		btp.evaluator.DropLevel(ct, 1)
 	}
 
+	if diag != nil {
+		diag.input(ct)
+	}
+
+	// The bootstrapping is done at the default scale, and the output is then brought to the given scale. If the ratio of
+	// the scales is not an integer, this consumes a level, hence SlotsToCoeffs ends one level higher
+	levelStC := level
+	if ratio := scale / btp.params.scale; ratio != math.Round(ratio) {
+		levelStC++
+	}
+
+	pDFT := btp.slotsToCoeffsMatrices(levelStC)
+
+	// The output scale of EvalMod is the one that SlotsToCoeffs brings to the default scale. If the matrices leave a
+	// factor that the rescalings do not remove, as when two of them share a modulus twice as large as their scale,
+	// EvalMod outputs at the default scale and the factor is removed with the correction of the scale, which then
+	// consumes a level.
+	factor := btp.dftScaleFactor(pDFT)
+	exact := factor > 0.5 && factor < 2
+
+	if !exact && levelStC == level {
+		levelStC++
+		pDFT = btp.slotsToCoeffsMatrices(levelStC)
+		factor = btp.dftScaleFactor(pDFT)
+		exact = factor > 0.5 && factor < 2
+	}
+
+	evalModScale := btp.params.scale
+	if exact {
+		evalModScale /= factor
+	}
+
+	if ct0, ct1 = btp.modRaiseAndEvalMod(ct, evalModScale, diag, progress); ct0 == nil {
+		return nil
+	}
+
+	// Part 3 : Slots to coeffs
+	//t = time.Now()
+	ct0 = btp.slotsToCoeffs(ct0, ct1, pDFT, levelStC)
+
+	// The output of SlotsToCoeffs is at the default scale, up to the floating point error of the scale computation
+	if exact {
+		ct0.SetScale(btp.params.scale)
+	}
+
+	btp.rescaleTo(ct0, scale)
+
+	if ct0.Level() > level {
+		btp.evaluator.DropLevel(ct0, ct0.Level()-level)
+	}
+
+	btp.logStage(StageSlotsToCoeffs, ct0)
+
+	if diag != nil {
+		diag.slotsToCoeffs(ct0)
+	}
+
+	progress.report(StageSlotsToCoeffs, ct0)
+
+	//log.Println("After StC    :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
+	return ct0
+}
+
+// modRaiseAndEvalMod evaluates the ModRaise, CoeffsToSlots and EvalMod stages of the bootstrapping on a ciphertext at
+// level 0, and returns the real and imaginary parts of its coefficients reduced modulo Q0 in the slots of ct0 and ct1
+// (ct1 is nil if they are repacked in ct0) at the given scale. It returns nil ciphertexts if the context of the progress
+// is done at the end of a stage.
+func (btp *Bootstrapper) modRaiseAndEvalMod(ct *Ciphertext, scale float64, diag *bootstrappDiagnoser, progress *bootstrappProgress) (ct0, ct1 *Ciphertext) {
+
+	if math.Round(btp.prescale/ct.Scale()) < 1 {
+		btp.logger.Warn("ckks: bootstrapping: the scale of the input is larger than Q0/1024, the output will be incorrect", "scale", ct.Scale(), "Q0/1024", btp.prescale)
+	}
+
 	// Brings the ciphertext scale to Q0/2^{10}
 	btp.evaluator.ScaleUp(ct, math.Round(btp.prescale/ct.Scale()), ct)
 
+	// Sparse secret encapsulation : dense secret -> sparse secret, at level 0
+	if btp.swkDenseToSparse != nil {
+		btp.evaluator.SwitchKeys(ct, btp.swkDenseToSparse, ct)
+	}
+
 	// ModUp ct_{Q_0} -> ct_{Q_L}
 	//t = time.Now()
 	ct = btp.modUp(ct)
 	//log.Println("After ModUp  :", time.Now().Sub(t), ct.Level(), ct.Scale())
 
+	// Sparse secret encapsulation : sparse secret -> dense secret, which only changes the error since the ModRaise
+	// is exact, hence the rest of the bootstrapping is done under the dense secret
+	if btp.swkSparseToDense != nil {
+		btp.evaluator.SwitchKeys(ct, btp.swkSparseToDense, ct)
+	}
+
+	btp.logStage(StageModRaise, ct)
+
+	if diag != nil {
+		diag.modRaise(ct)
+	}
+
+	if progress.done(StageModRaise, ct) {
+		return nil, nil
+	}
+
 	// Brings the ciphertext scale to sineQi/(Q0/scale) if its under
 	btp.evaluator.ScaleUp(ct, math.Round(btp.postscale/ct.Scale()), ct)
 
@@ -39,18 +254,36 @@ func (btp *Bootstrapper) Bootstrapp(ct *Ciphertext) *Ciphertext {
 	ct0, ct1 = btp.coeffsToSlots(ct)
 	//log.Println("After CtS    :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
 
+	btp.logStage(StageCoeffsToSlots, ct0)
+
+	if diag != nil {
+		diag.coeffsToSlots(ct0, ct1)
+	}
+
+	if progress.done(StageCoeffsToSlots, ct0) {
+		return nil, nil
+	}
+
 	// Part 2 : SineEval
 	//t = time.Now()
-	ct0, ct1 = btp.evaluateSine(ct0, ct1)
+	ct0, ct1 = btp.evaluateSine(ct0, ct1, scale)
 	//log.Println("After Sine   :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
 
-	// Part 3 : Slots to coeffs
-	//t = time.Now()
-	ct0 = btp.slotsToCoeffs(ct0, ct1)
+	btp.logStage(StageEvalMod, ct0)
 
-	ct0.SetScale(math.Exp2(math.Round(math.Log2(ct0.Scale())))) // rounds to the nearest power of two
-	//log.Println("After StC    :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
-	return ct0
+	if diag != nil {
+		diag.evalMod(ct0, ct1)
+	}
+
+	if progress.done(StageEvalMod, ct0) {
+		return nil, nil
+	}
+
+	return
+}
+
+func (btp *Bootstrapper) logStage(stage BootstrappStage, ct *Ciphertext) {
+	btp.logger.Debug("ckks: bootstrapping: "+stage.String()+" done", "level", ct.Level(), "scale", ct.Scale())
 }
 
 func (btp *Bootstrapper) subSum(ct *Ciphertext) *Ciphertext {
@@ -144,7 +377,17 @@ func (btp *Bootstrapper) coeffsToSlots(vec *Ciphertext) (ct0, ct1 *Ciphertext) {
 	return ct0, ct1
 }
 
-func (btp *Bootstrapper) slotsToCoeffs(ct0, ct1 *Ciphertext) (ct *Ciphertext) {
+func (btp *Bootstrapper) slotsToCoeffs(ct0, ct1 *Ciphertext, pDFT []*dftvectors, level uint64) (ct *Ciphertext) {
+
+	// Partial bootstrapping : the ciphertext skips the levels above the ones needed to reach the target level.
+	if inputLevel := pDFT[0].Level; inputLevel < ct0.Level() {
+
+		btp.evaluator.DropLevel(ct0, ct0.Level()-inputLevel)
+
+		if ct1 != nil {
+			btp.evaluator.DropLevel(ct1, ct1.Level()-inputLevel)
+		}
+	}
 
 	// If full packing, the repacking can be done directly using ct0 and ct1.
 	if !btp.repack {
@@ -156,7 +399,66 @@ func (btp *Bootstrapper) slotsToCoeffs(ct0, ct1 *Ciphertext) (ct *Ciphertext) {
 
 	ct1 = nil
 
-	return btp.dft(ct0, btp.pDFT, false)
+	ct = btp.dft(ct0, pDFT, false)
+
+	if ct.Level() > level {
+		btp.evaluator.DropLevel(ct, ct.Level()-level)
+	}
+
+	return
+}
+
+// slotsToCoeffsMatrices returns the SlotsToCoeffs matrice vectors for an output at the given level: the ones of the full
+// bootstrapping for an output at its level, else the ones re-encoded on demand.
+func (btp *Bootstrapper) slotsToCoeffsMatrices(level uint64) []*dftvectors {
+
+	if level+uint64(len(btp.pDFT)) >= btp.pDFT[0].Level {
+		return btp.pDFT
+	}
+
+	return btp.slotsToCoeffsMatricesLvl(level)
+}
+
+// rescaleTo brings the ciphertext from the default scale to the given scale without changing the values: with an
+// exact multiplication by the ratio of the scales if it is an integer, else with a multiplication by the ratio
+// encoded at the scale of the last modulus followed by a rescaling, which consumes a level.
+func (btp *Bootstrapper) rescaleTo(ct *Ciphertext, scale float64) {
+
+	ratio := scale / ct.Scale()
+
+	if ratio == math.Round(ratio) {
+		if ratio != 1 {
+			btp.evaluator.MultByConst(ct, uint64(ratio), ct)
+		}
+	} else {
+		btp.evaluator.MultByConst(ct, ratio, ct)
+		if err := btp.evaluator.RescaleMany(ct, 1, ct); err != nil {
+			panic(err)
+		}
+	}
+
+	ct.SetScale(scale)
+}
+
+// dftScaleFactor returns the factor by which dft multiplies the scale of a ciphertext at the level of the first matrix,
+// computed by replaying its rescalings. With the scale of the ciphertext as reference scale, the rescalings do not depend
+// on this scale, hence the factor does not either.
+func (btp *Bootstrapper) dftScaleFactor(plainVectors []*dftvectors) (factor float64) {
+
+	factor = 1
+	level := plainVectors[0].Level
+
+	for _, plainVector := range plainVectors {
+
+		factor *= plainVector.Scale
+
+		for factor >= float64(btp.params.qi[level])/2 && level != 0 {
+			factor /= float64(btp.params.qi[level])
+			level--
+		}
+	}
+
+	return
 }
 
 func (btp *Bootstrapper) dft(vec *Ciphertext, plainVectors []*dftvectors, forward bool) *Ciphertext {
@@ -210,6 +512,8 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 	// Pre-rotates ciphertext for the baby-step giant-step algorithm, does not divide by P yet
 	vecRotQ, vecRotP := eval.rotateHoistedNoModDown(vec, rotations, btp.rotkeys)
 
+	eval.growPools(vec.Level())
+
 	// Accumulator inner loop
 	tmpQ0 := eval.poolQMul[0] // unused memory pool from evaluator
 	tmpQ1 := eval.poolQMul[1] // unused memory pool from evaluator
@@ -235,13 +539,15 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 
 	for _, i := range rotations {
 		if i != 0 {
-			ring.PermuteNTTWithIndexLvl(levelQ, c0, btp.rotkeys.permuteNTTLeftIndex[i], tmpQ0) // phi(P*c0)
-			ringQ.AddLvl(levelQ, vecRotQ[i][0], tmpQ0, vecRotQ[i][0])                          // phi(d0_Q) += phi(P*c0)
+			index, _ := btp.rotkeys.rotationKey(i)
+			ring.PermuteNTTWithIndexLvl(levelQ, c0, index, tmpQ0)     // phi(P*c0)
+			ringQ.AddLvl(levelQ, vecRotQ[i][0], tmpQ0, vecRotQ[i][0]) // phi(d0_Q) += phi(P*c0)
 		}
 	}
 
 	// OUTER LOOP
-	cnt0 := 0
+	cnt0 := uint64(0)
+	period := utils.MinUint64(ringQ.LazyReductionPeriod(), ringP.LazyReductionPeriod())
 	for j := range index {
 
 		if j != 0 {
@@ -277,8 +583,8 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 			}
 
 			// Hoisting of the ModDown of sum(sum(phi(d0 + P*c0) * plaintext)) and sum(sum(phi(d1) * plaintext))
-			eval.baseconverter.ModDownSplitNTTPQ(levelQ, tmpQ0, pool2P, tmpQ0) // sum(phi(d0) * plaintext)/P
-			eval.baseconverter.ModDownSplitNTTPQ(levelQ, tmpQ1, pool3P, tmpQ1) // sum(phi(d1) * plaintext)/P
+			eval.baseconverter.ModDownSplitQPtoQNTT(levelQ, tmpQ0, pool2P, tmpQ0) // sum(phi(d0) * plaintext)/P
+			eval.baseconverter.ModDownSplitQPtoQNTT(levelQ, tmpQ1, pool3P, tmpQ1) // sum(phi(d1) * plaintext)/P
 
 			// If i == 0
 			if state {
@@ -287,13 +593,13 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 				ringQ.MulCoeffsMontgomeryAndAddLvl(levelQ, plainVectors.Vec[N1*j][0], vec.value[1], tmpQ1) // c1 * plaintext + sum(phi(d1) * plaintext)/P + phi(c1) * plaintext mod Q
 			}
 
-			eval.switchKeysInPlaceNoModDown(levelQ, tmpQ1, btp.rotkeys.evakeyRotColLeft[N1*j], pool2Q, pool2P, pool3Q, pool3P) // Switchkey(phi(tmpRes_1)) = (d0, d1) in base QP
+			rot, swk := btp.rotkeys.rotationKey(N1 * j)
 
-			// Outer loop rotations
-			ring.PermuteNTTWithIndexLvl(levelQ, tmpQ0, btp.rotkeys.permuteNTTLeftIndex[N1*j], tmpQ1) // phi(tmpRes_0)
-			ringQ.AddLvl(levelQ, res.value[0], tmpQ1, res.value[0])                                  // res += phi(tmpRes)
+			eval.switchKeysInPlaceNoModDown(levelQ, tmpQ1, swk, pool2Q, pool2P, pool3Q, pool3P) // Switchkey(phi(tmpRes_1)) = (d0, d1) in base QP
 
-			rot := btp.rotkeys.permuteNTTLeftIndex[N1*j]
+			// Outer loop rotations
+			ring.PermuteNTTWithIndexLvl(levelQ, tmpQ0, rot, tmpQ1)  // phi(tmpRes_0)
+			ringQ.AddLvl(levelQ, res.value[0], tmpQ1, res.value[0]) // res += phi(tmpRes)
 
 			N2Rot++
 
@@ -309,7 +615,7 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 				ring.PermuteNTTWithIndexAndAddNoModLvl(levelP, pool3P, rot, tmpP3) // sum(phi(d1_P))
 			}
 
-			if cnt0 == 7 {
+			if cnt0%period == period-1 {
 				ringQ.ReduceLvl(levelQ, tmpQ2, tmpQ2)
 				ringQ.ReduceLvl(levelQ, tmpQ3, tmpQ3)
 				ringP.Reduce(tmpP2, tmpP2)
@@ -320,7 +626,7 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 		}
 	}
 
-	if cnt0 != 7 {
+	if cnt0%period != 0 {
 		ringQ.ReduceLvl(levelQ, tmpQ2, tmpQ2)
 		ringQ.ReduceLvl(levelQ, tmpQ3, tmpQ3)
 		ringP.Reduce(tmpP2, tmpP2)
@@ -346,8 +652,8 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 		}
 	}
 
-	eval.baseconverter.ModDownSplitNTTPQ(levelQ, tmpQ2, tmpP2, tmpQ2) // sum(phi(c0 * P + d0_QP))/P
-	eval.baseconverter.ModDownSplitNTTPQ(levelQ, tmpQ3, tmpP3, tmpQ3) // sum(phi(d1_QP))/P
+	eval.baseconverter.ModDownSplitQPtoQNTT(levelQ, tmpQ2, tmpP2, tmpQ2) // sum(phi(c0 * P + d0_QP))/P
+	eval.baseconverter.ModDownSplitQPtoQNTT(levelQ, tmpQ3, tmpP3, tmpQ3) // sum(phi(d1_QP))/P
 
 	ringQ.AddLvl(levelQ, res.value[0], tmpQ2, res.value[0]) // res += sum(phi(c0 * P + d0_QP))/P
 	ringQ.AddLvl(levelQ, res.value[1], tmpQ3, res.value[1]) // res += sum(phi(d1_QP))/P
@@ -360,6 +666,13 @@ func (btp *Bootstrapper) multiplyByDiagMatrice(vec *Ciphertext, plainVectors *df
 
 	res.SetScale(plainVectors.Scale * vec.Scale())
 
+	for i := range vecRotQ {
+		ringQ.PutPoly(vecRotQ[i][0])
+		ringQ.PutPoly(vecRotQ[i][1])
+		ringP.PutPoly(vecRotP[i][0])
+		ringP.PutPoly(vecRotP[i][1])
+	}
+
 	vecRotQ, vecRotP, c0 = nil, nil, nil
 
 	//log.Println(N1Rot, N2Rot)
@@ -376,23 +689,21 @@ func (eval *evaluator) rotateHoistedNoModDown(ct0 *Ciphertext, rotations []uint6
 	ringP := eval.ringP
 
 	c2NTT := ct0.value[1]
-	c2InvNTT := ringQ.NewPoly() // TODO : maybe have a pre-allocated memory pool ?
+	c2InvNTT := ringQ.GetPolyLvl(ct0.Level())
 	ringQ.InvNTTLvl(ct0.Level(), c2NTT, c2InvNTT)
 
-	alpha := eval.params.Alpha()
-	beta := uint64(math.Ceil(float64(ct0.Level()+1) / float64(alpha)))
+	beta := eval.params.BetaLvl(ct0.Level())
 
-	// TODO : maybe have a pre-allocated memory pool ?
 	c2QiQDecomp := make([]*ring.Poly, beta)
 	c2QiPDecomp := make([]*ring.Poly, beta)
 
 	for i := uint64(0); i < beta; i++ {
-		c2QiQDecomp[i] = ringQ.NewPoly()
-		c2QiPDecomp[i] = ringP.NewPoly()
+		c2QiQDecomp[i] = ringQ.GetPolyLvl(ct0.Level())
+		c2QiPDecomp[i] = ringP.GetPoly()
 		eval.decomposeAndSplitNTT(ct0.Level(), i, c2NTT, c2InvNTT, c2QiQDecomp[i], c2QiPDecomp[i])
 	}
 
-	c2InvNTT = nil
+	ringQ.PutPoly(c2InvNTT)
 
 	cOutQ = make(map[uint64][2]*ring.Poly)
 	cOutP = make(map[uint64][2]*ring.Poly)
@@ -402,20 +713,28 @@ func (eval *evaluator) rotateHoistedNoModDown(ct0 *Ciphertext, rotations []uint6
 		i &= ((ringQ.N >> 1) - 1)
 
 		if i != 0 {
-			cOutQ[i] = [2]*ring.Poly{eval.ringQ.NewPolyLvl(ct0.Level()), eval.ringQ.NewPolyLvl(ct0.Level())}
-			cOutP[i] = [2]*ring.Poly{eval.params.NewPolyP(), eval.params.NewPolyP()}
+			cOutQ[i] = [2]*ring.Poly{ringQ.GetPolyLvl(ct0.Level()), ringQ.GetPolyLvl(ct0.Level())}
+			cOutP[i] = [2]*ring.Poly{ringP.GetPoly(), ringP.GetPoly()}
 			eval.permuteNTTHoistedNoModDown(ct0, c2QiQDecomp, c2QiPDecomp, i, rotkeys, cOutQ[i], cOutP[i])
 		}
 	}
 
-	c2QiQDecomp = nil
-	c2QiPDecomp = nil
+	for i := range c2QiQDecomp {
+		ringQ.PutPoly(c2QiQDecomp[i])
+		ringP.PutPoly(c2QiPDecomp[i])
+	}
 
 	return
 }
 
 func (eval *evaluator) permuteNTTHoistedNoModDown(ct0 *Ciphertext, c2QiQDecomp, c2QiPDecomp []*ring.Poly, k uint64, rotKeys *RotationKeys, ctOutQ, ctOutP [2]*ring.Poly) {
 
+	index, swk := rotKeys.rotationKey(k)
+
+	checkKeyBinding(ct0.Element, swk)
+
+	eval.growPools(ct0.Level())
+
 	pool2Q := eval.poolQ[0]
 	pool3Q := eval.poolQ[1]
 
@@ -425,38 +744,49 @@ func (eval *evaluator) permuteNTTHoistedNoModDown(ct0 *Ciphertext, c2QiQDecomp,
 	levelQ := ct0.Level()
 	levelP := eval.params.PiCount() - 1
 
-	eval.keyswitchHoistedNoModDown(levelQ, c2QiQDecomp, c2QiPDecomp, rotKeys.evakeyRotColLeft[k], pool2Q, pool3Q, pool2P, pool3P)
+	eval.keyswitchHoistedNoModDown(levelQ, c2QiQDecomp, c2QiPDecomp, swk, pool2Q, pool3Q, pool2P, pool3P)
 
-	ring.PermuteNTTWithIndexLvl(levelQ, pool2Q, rotKeys.permuteNTTLeftIndex[k], ctOutQ[0])
-	ring.PermuteNTTWithIndexLvl(levelQ, pool3Q, rotKeys.permuteNTTLeftIndex[k], ctOutQ[1])
+	ring.PermuteNTTWithIndexLvl(levelQ, pool2Q, index, ctOutQ[0])
+	ring.PermuteNTTWithIndexLvl(levelQ, pool3Q, index, ctOutQ[1])
 
-	ring.PermuteNTTWithIndexLvl(levelP, pool2P, rotKeys.permuteNTTLeftIndex[k], ctOutP[0])
-	ring.PermuteNTTWithIndexLvl(levelP, pool3P, rotKeys.permuteNTTLeftIndex[k], ctOutP[1])
+	ring.PermuteNTTWithIndexLvl(levelP, pool2P, index, ctOutP[0])
+	ring.PermuteNTTWithIndexLvl(levelP, pool3P, index, ctOutP[1])
 }
 
-// Sine Evaluation ct0 = Q/(2pi) * sin((2pi/Q) * ct0)
-func (btp *Bootstrapper) evaluateSine(ct0, ct1 *Ciphertext) (*Ciphertext, *Ciphertext) {
+// Sine Evaluation ct0 = Q/(2pi) * sin((2pi/Q) * ct0), with outputs at the given scale.
+func (btp *Bootstrapper) evaluateSine(ct0, ct1 *Ciphertext, scale float64) (*Ciphertext, *Ciphertext) {
 
 	evaluator := btp.evaluator.(*evaluator)
 
 	ct0.MulScale(btp.deviation)
-	evaluator.scale = ct0.Scale() // Reference scale is changed to the new ciphertext's scale.
 
-	// pre-computes the target scale for the output of the polynomial evaluation such that
-	// the output scale after the polynomial evaluation followed by the double angle formula
-	// does not change the scale of the ciphertext.
+	// Target scale of the output of the polynomial evaluation, such that the outputs have the given scale
+	// once divided by the deviation.
+	targetScale := scale * btp.deviation * btp.postscale / btp.params.scale
+	evaluator.scale = targetScale
+
+	// pre-computes the scale of the output of the polynomial evaluation such that
+	// the output scale after the double angle formula is the target scale.
+	// The moduli of the double angle formula are right above the ones of the arcsine correction.
+	arcSineDepth := btp.ArcSineDepth()
+
 	for i := uint64(0); i < btp.SinRescal; i++ {
-		evaluator.scale *= float64(evaluator.params.qi[btp.StCLevel[0]+i+1])
+		evaluator.scale *= float64(evaluator.params.qi[btp.StCLevel[0]+arcSineDepth+i+1])
 		evaluator.scale = math.Sqrt(evaluator.scale)
 	}
 
+	sineScale := evaluator.scale
+
 	ct0 = btp.evaluateCheby(ct0)
+	ct0 = btp.evaluateArcSine(ct0, targetScale)
 
 	ct0.DivScale(btp.deviation * btp.postscale / btp.params.scale)
 
 	if ct1 != nil {
+		evaluator.scale = sineScale
 		ct1.MulScale(btp.deviation)
 		ct1 = btp.evaluateCheby(ct1)
+		ct1 = btp.evaluateArcSine(ct1, targetScale)
 		ct1.DivScale(btp.deviation * btp.postscale / btp.params.scale)
 	}
 
@@ -466,6 +796,20 @@ func (btp *Bootstrapper) evaluateSine(ct0, ct1 *Ciphertext) (*Ciphertext, *Ciphe
 	return ct0, ct1
 }
 
+// evaluateArcSine corrects the output sin(2*pi*x)/(2*pi) of the sine evaluation to x with the Taylor series of the
+// arcsine, and returns the result at the given scale. It returns ct if the correction is disabled.
+func (btp *Bootstrapper) evaluateArcSine(ct *Ciphertext, scale float64) *Ciphertext {
+
+	if btp.arcsine == nil {
+		return ct
+	}
+
+	eval := btp.evaluator.(*evaluator)
+	eval.scale = scale
+
+	return eval.EvaluatePoly(ct, btp.arcsine, btp.relinkey)
+}
+
 func (btp *Bootstrapper) evaluateCheby(ct *Ciphertext) (res *Ciphertext) {
 
 	eval := btp.evaluator.(*evaluator)
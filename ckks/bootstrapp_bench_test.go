@@ -57,14 +57,23 @@ func BenchmarkBootstrapp(b *testing.B) {
 
 			// Part 2 : SineEval
 			t = time.Now()
-			ct0, ct1 = btp.evaluateSine(ct0, ct1)
+			ct0, ct1 = btp.evaluateSine(ct0, ct1, testContext.params.Scale()/btp.dftScaleFactor(btp.pDFT))
 			b.Log("After Sine   :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
 
 			// Part 3 : Slots to coeffs
 			t = time.Now()
-			ct0 = btp.slotsToCoeffs(ct0, ct1)
-			ct0.SetScale(math.Exp2(math.Round(math.Log2(ct0.Scale()))))
+			ct0 = btp.slotsToCoeffs(ct0, ct1, btp.pDFT, btp.OutputLevel())
 			b.Log("After StC    :", time.Now().Sub(t), ct0.Level(), ct0.Scale())
 		}
 	})
+	b.Run(testString(testContext, "BootstrappLvl/"), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+
+			b.StopTimer()
+			ct := NewCiphertextRandom(testContext.prng, testContext.params, 1, 0, testContext.params.scale)
+			b.StartTimer()
+
+			btp.BootstrappLvl(1, ct)
+		}
+	})
 }
@@ -0,0 +1,195 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// BootstrappStage is a stage of the bootstrapping circuit.
+type BootstrappStage int
+
+// Stages of the bootstrapping circuit, in the order of their evaluation.
+const (
+	StageModRaise BootstrappStage = iota
+	StageCoeffsToSlots
+	StageEvalMod
+	StageSlotsToCoeffs
+)
+
+// String returns the name of the stage.
+func (stage BootstrappStage) String() string {
+	switch stage {
+	case StageModRaise:
+		return "ModRaise"
+	case StageCoeffsToSlots:
+		return "CoeffsToSlots"
+	case StageEvalMod:
+		return "EvalMod"
+	case StageSlotsToCoeffs:
+		return "SlotsToCoeffs"
+	}
+	return fmt.Sprintf("BootstrappStage(%d)", int(stage))
+}
+
+// BootstrappStageReport is the report of a stage of the bootstrapping.
+type BootstrappStageReport struct {
+	Stage BootstrappStage
+
+	// Level and Scale are the ones of the ciphertext at the output of the stage.
+	Level uint64
+	Scale float64
+
+	// Precision is the precision of the output of the stage in units of the message. The slots are compared
+	// with the coefficients of the plaintext, including the multiples of Q0, after CoeffsToSlots, with the
+	// coefficients of the message after EvalMod, and with the message after SlotsToCoeffs. The real and
+	// imaginary parts respectively account for the first and the second half of the coefficients before
+	// SlotsToCoeffs. The output of ModRaise is exact and its precision is not computed.
+	Precision PrecisionStats
+}
+
+// BootstrappDiagnostics stores the reports of the stages of a bootstrapping.
+type BootstrappDiagnostics struct {
+	Stages []BootstrappStageReport
+
+	// InputRange is the largest absolute value of the coefficients after ModRaise in multiples of Q0, which is
+	// the range over which EvalMod is evaluated. The bootstrapping fails if it exceeds SinRange.
+	InputRange float64
+}
+
+// String returns a summary of the diagnostics.
+func (diag *BootstrappDiagnostics) String() (s string) {
+	s = fmt.Sprintf("Input range : %.2f\n", diag.InputRange)
+	for _, report := range diag.Stages {
+		if report.Stage == StageModRaise {
+			s += fmt.Sprintf("%-13s : level %2d, scale 2^%.2f\n", report.Stage, report.Level, math.Log2(report.Scale))
+			continue
+		}
+		s += fmt.Sprintf("%-13s : level %2d, scale 2^%.2f, mean precision (%.2f, %.2f) bits, min precision (%.2f, %.2f) bits\n",
+			report.Stage, report.Level, math.Log2(report.Scale),
+			real(report.Precision.MeanPrecision), imag(report.Precision.MeanPrecision),
+			real(report.Precision.MinPrecision), imag(report.Precision.MinPrecision))
+	}
+	return
+}
+
+// BootstrappWithDiagnostics bootstraps the ciphertext as Bootstrapp does, and decrypts the intermediate ciphertexts
+// with the decryptor to report the level, the scale and the precision after each stage. It returns an error if the
+// coefficients after ModRaise exceed the range of the sine approximation, in which case the output is garbage.
+//
+// This instrumented mode requires the secret key and is much slower than Bootstrapp: it is meant for the
+// debugging and the tuning of the bootstrapping parameters and must not be used in production.
+func (btp *Bootstrapper) BootstrappWithDiagnostics(ct *Ciphertext, decryptor Decryptor) (ctOut *Ciphertext, diag *BootstrappDiagnostics, err error) {
+
+	diagnoser := &bootstrappDiagnoser{btp: btp, decryptor: decryptor, diag: new(BootstrappDiagnostics)}
+
+	ctOut = btp.bootstrapp(ct, btp.OutputLevel(), ct.Scale(), diagnoser, nil)
+
+	if diagnoser.diag.InputRange > float64(btp.SinRange) {
+		err = fmt.Errorf("bootstrapping failure: input range %.2f exceeds the range %d of the sine approximation", diagnoser.diag.InputRange, btp.SinRange)
+	}
+
+	return ctOut, diagnoser.diag, err
+}
+
+// bootstrappDiagnoser computes the reports of a BootstrappDiagnostics during a bootstrapping.
+type bootstrappDiagnoser struct {
+	btp       *Bootstrapper
+	decryptor Decryptor
+	diag      *BootstrappDiagnostics
+
+	values []complex128 // Message at the input of the bootstrapping
+	coeffs []complex128 // Coefficients after ModRaise in units of the message, in the order of the slots
+	q0     float64      // Q0 in units of the message
+}
+
+// input records the message encrypted by the ciphertext at level zero.
+func (d *bootstrappDiagnoser) input(ct *Ciphertext) {
+	d.values = d.btp.encoder.Decode(d.decryptor.DecryptNew(ct), d.btp.params.Slots())
+}
+
+// modRaise records the coefficients of the plaintext after ModRaise and the input range of EvalMod.
+func (d *bootstrappDiagnoser) modRaise(ct *Ciphertext) {
+
+	params := d.btp.params
+	slots := params.Slots()
+	gap := params.N() / (2 * slots)
+
+	coeffs := d.btp.encoder.DecodeCoeffs(d.decryptor.DecryptNew(ct))
+
+	d.q0 = float64(params.qi[0]) / ct.Scale()
+
+	// CoeffsToSlots puts the coefficients in bit-reversed order in the slots
+	d.coeffs = make([]complex128, slots)
+	for i := uint64(0); i < slots; i++ {
+		j := utils.BitReverse64(i, params.logSlots)
+		d.coeffs[i] = complex(coeffs[j*gap], coeffs[(j+slots)*gap])
+		d.diag.InputRange = math.Max(d.diag.InputRange, math.Abs(real(d.coeffs[i]))/d.q0)
+		d.diag.InputRange = math.Max(d.diag.InputRange, math.Abs(imag(d.coeffs[i]))/d.q0)
+	}
+
+	d.diag.Stages = append(d.diag.Stages, BootstrappStageReport{Stage: StageModRaise, Level: ct.Level(), Scale: ct.Scale()})
+}
+
+// coeffsToSlots reports the precision of the output of CoeffsToSlots, whose slots store the coefficients divided by
+// Q0 and by SinRange, times the deviation.
+func (d *bootstrappDiagnoser) coeffsToSlots(ct0, ct1 *Ciphertext) {
+
+	factor := d.q0 * float64(d.btp.SinRange) / d.btp.deviation
+
+	values := d.decodeCoeffs(ct0, ct1)
+	for i := range values {
+		values[i] *= complex(factor, 0)
+	}
+
+	d.report(StageCoeffsToSlots, ct0, d.coeffs, values)
+}
+
+// evalMod reports the precision of the output of EvalMod, whose slots store the coefficients reduced modulo Q0.
+func (d *bootstrappDiagnoser) evalMod(ct0, ct1 *Ciphertext) {
+
+	want := make([]complex128, len(d.coeffs))
+	for i, c := range d.coeffs {
+		want[i] = complex(real(c)-d.q0*math.Round(real(c)/d.q0), imag(c)-d.q0*math.Round(imag(c)/d.q0))
+	}
+
+	d.report(StageEvalMod, ct0, want, d.decodeCoeffs(ct0, ct1))
+}
+
+// slotsToCoeffs reports the precision of the output of the bootstrapping.
+func (d *bootstrappDiagnoser) slotsToCoeffs(ct *Ciphertext) {
+	d.report(StageSlotsToCoeffs, ct, d.values, d.btp.encoder.Decode(d.decryptor.DecryptNew(ct), d.btp.params.Slots()))
+}
+
+// decodeCoeffs returns the real slots of ct0 and ct1 (or of the two halves of ct0 if it is repacked) as the real and
+// imaginary parts of a single vector.
+func (d *bootstrappDiagnoser) decodeCoeffs(ct0, ct1 *Ciphertext) (values []complex128) {
+
+	slots := d.btp.params.Slots()
+
+	values0 := d.btp.encoder.Decode(d.decryptor.DecryptNew(ct0), d.btp.dslots)
+
+	var values1 []complex128
+	if ct1 != nil {
+		values1 = d.btp.encoder.Decode(d.decryptor.DecryptNew(ct1), d.btp.dslots)
+	} else {
+		values1 = values0[slots:]
+	}
+
+	values = make([]complex128, slots)
+	for i := range values {
+		values[i] = complex(real(values0[i]), real(values1[i]))
+	}
+
+	return
+}
+
+func (d *bootstrappDiagnoser) report(stage BootstrappStage, ct *Ciphertext, want, have []complex128) {
+	d.diag.Stages = append(d.diag.Stages, BootstrappStageReport{
+		Stage:     stage,
+		Level:     ct.Level(),
+		Scale:     ct.Scale(),
+		Precision: GetPrecisionStats(d.btp.params, d.btp.encoder, d.decryptor, want, have),
+	})
+}
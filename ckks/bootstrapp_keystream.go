@@ -0,0 +1,502 @@
+package ckks
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// relinKeyType is the type of the record of the relinearization key in a bootstrapping key stream.
+const relinKeyType = automorphismKeyType + 1
+
+// GenBootstrappingKeyStream generates the relinearization key and the rotation keys required by the bootstrapping and
+// writes them on w one at a time, as soon as each of them is generated, so that the full BootstrappingKey is never held
+// in memory. If seeded is true, the uniform elements of each switching key are not written but generated from a seed
+// stored with the key, which about halves the size of the stream. The stream is read by ReadBootstrappingKeyStream and
+// ReadBootstrapper.
+//
+// Each key is written as a record made of its type and rotation on 4 bytes, a byte set to 1 if the key is seeded
+// followed by its seed, and its encoding prefixed by its length in bytes.
+func (keygen *keyGenerator) GenBootstrappingKeyStream(w io.Writer, logSlots uint64, btpParams *BootstrappParams, sk *SecretKey, seeded bool) (err error) {
+
+	if len(keygen.params.pi) == 0 {
+		panic("Cannot GenBootstrappingKeyStream: modulus P is empty")
+	}
+
+	ringQP := keygen.ringQP
+	level := keygen.params.MaxLevel()
+
+	// newSampler returns the uniform sampler of the next key and its seed, nil if the key is not seeded.
+	newSampler := func() (seed []byte, uniformSampler ring.Sampler, err error) {
+
+		if !seeded {
+			return nil, keygen.uniformSampler, nil
+		}
+
+		seed = make([]byte, SeedSize)
+		if _, err = rand.Read(seed); err != nil {
+			return nil, nil, err
+		}
+
+		var prng *utils.KeyedPRNG
+		if prng, err = utils.NewKeyedPRNG(seed); err != nil {
+			return nil, nil, err
+		}
+
+		return seed, ring.NewUniformSamplerWithDomain(prng, ringQP, true, true), nil
+	}
+
+	var seed []byte
+	var uniformSampler ring.Sampler
+
+	// Relinearization key
+	if seed, uniformSampler, err = newSampler(); err != nil {
+		return err
+	}
+
+	ringQP.MulCoeffsMontgomery(sk.Get(), sk.Get(), keygen.polypool[0])
+	swk := keygen.newSwitchingKeyWithSampler(level, keygen.polypool[0], sk.Get(), uniformSampler)
+	keygen.polypool[0].Zero()
+
+	if err = writeBootstrappingKeyRecord(w, relinKeyType, 0, seed, swk); err != nil {
+		return err
+	}
+
+	// Conjugation key
+	if seed, uniformSampler, err = newSampler(); err != nil {
+		return err
+	}
+
+	swk = keygen.genrotKeyWithSampler(level, sk.Get(), ring.PermuteNTTIndex(2*ringQP.N-1, 1, ringQP.N), uniformSampler)
+
+	if err = writeBootstrappingKeyRecord(w, Conjugate, 0, seed, swk); err != nil {
+		return err
+	}
+
+	// Rotation keys
+	for _, k := range computeBootstrappingDFTRotationList(keygen.params.logN, logSlots, btpParams) {
+
+		if k == 0 {
+			continue
+		}
+
+		if seed, uniformSampler, err = newSampler(); err != nil {
+			return err
+		}
+
+		swk = keygen.genrotKeyWithSampler(level, sk.Get(), ring.PermuteNTTIndex(GaloisGen, 2*ringQP.N-k, ringQP.N), uniformSampler)
+
+		if err = writeBootstrappingKeyRecord(w, RotationLeft, k, seed, swk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadBootstrappingKeyStream reads the keys written by KeyGenerator.GenBootstrappingKeyStream from r until its end and
+// returns them in a new BootstrappingKey. The keys are read, expanded from their seed and checked against the scheme
+// parameters one at a time, so that the stream is never held in memory.
+func ReadBootstrappingKeyStream(params *Parameters, r io.Reader) (btpKey *BootstrappingKey, err error) {
+
+	ringQP, err := ring.NewRing(params.N(), append(params.qi, params.pi...))
+	if err != nil {
+		return nil, err
+	}
+
+	btpKey = NewBootstrappingKey(nil, NewRotationKeys())
+	rotKey := btpKey.rotkeys
+
+	rotKey.evakeyRotColLeft = make(map[uint64]*SwitchingKey)
+	rotKey.permuteNTTLeftIndex = make(map[uint64][]uint64)
+
+	for {
+
+		var keyType int
+		var k uint64
+		var swk *SwitchingKey
+
+		if keyType, k, swk, err = readBootstrappingKeyRecord(ringQP, params.PiCount(), r); err != nil {
+			if err == io.EOF {
+				return btpKey, nil
+			}
+			return nil, err
+		}
+
+		if err = validateSwitchingKey(params, swk); err != nil {
+			return nil, fmt.Errorf("invalid bootstrapping key stream: %s", err)
+		}
+
+		switch keyType {
+		case relinKeyType:
+			btpKey.relinkey = &EvaluationKey{evakey: swk}
+		case Conjugate:
+			rotKey.evakeyConjugate = swk
+			rotKey.permuteNTTConjugateIndex = ring.PermuteNTTIndexShared(2*ringQP.N-1, 1, ringQP.N)
+		case RotationLeft:
+			rotKey.evakeyRotColLeft[k] = swk
+			rotKey.permuteNTTLeftIndex[k] = ring.PermuteNTTIndexShared(GaloisGen, k, ringQP.N)
+		default:
+			return nil, fmt.Errorf("invalid bootstrapping key stream: unknown key type %d", keyType)
+		}
+	}
+}
+
+// ReadBootstrapper creates a new Bootstrapper whose keys are read from a stream written by
+// KeyGenerator.GenBootstrappingKeyStream. The keys are read eagerly: the whole stream is consumed before
+// ReadBootstrapper returns, and the Bootstrapper holds the full BootstrappingKey in memory, as one created by
+// NewBootstrapper. Reading the stream only avoids holding the encoding of the keys next to them, since the keys are
+// decoded and expanded one at a time after the plaintext matrices and the polynomial approximation are computed.
+// ReadBootstrapperAt reads the rotation keys lazily from a stream that supports random access, such as a file.
+func ReadBootstrapper(params *Parameters, btpParams *BootstrappParams, r io.Reader) (btp *Bootstrapper, err error) {
+
+	if err = btpParams.Validate(params); err != nil {
+		return nil, err
+	}
+
+	btp = newBootstrapper(params, btpParams)
+
+	if btp.BootstrappingKey, err = ReadBootstrappingKeyStream(params, r); err != nil {
+		return nil, err
+	}
+
+	if err = btp.CheckKeys(); err != nil {
+		return nil, err
+	}
+
+	return btp, nil
+}
+
+// ReadBootstrapperAt creates a new Bootstrapper whose keys are read from a stream written by
+// KeyGenerator.GenBootstrappingKeyStream, as ReadBootstrapper, but loads the rotation and conjugation keys lazily:
+// it only reads the relinearization key and the position of the record of each other key in r, and each key is read,
+// expanded from its seed and checked on its first use, by the Bootstrapper or by an Evaluator given
+// Bootstrapper.RotationKeys, after which it is kept in memory. The keys are thus loaded in the order in which the
+// bootstrapping uses them instead of all at once, and those that are never used are never read.
+//
+// r must remain readable and unchanged as long as the Bootstrapper is used, and the Bootstrapper panics if a key
+// cannot be read from r or is invalid. The lazily loaded keys are not part of the encoding of the rotation keys of the
+// Bootstrapper by MarshalBinary.
+func ReadBootstrapperAt(params *Parameters, btpParams *BootstrappParams, r io.ReaderAt) (btp *Bootstrapper, err error) {
+
+	if err = btpParams.Validate(params); err != nil {
+		return nil, err
+	}
+
+	btp = newBootstrapper(params, btpParams)
+
+	ringQP, err := ring.NewRing(params.N(), append(params.qi, params.pi...))
+	if err != nil {
+		return nil, err
+	}
+
+	rotKey := NewRotationKeys()
+	if rotKey.stream, err = indexBootstrappingKeyStream(params, ringQP, r); err != nil {
+		return nil, err
+	}
+
+	btp.BootstrappingKey = NewBootstrappingKey(rotKey.stream.relinKey, rotKey)
+	rotKey.stream.relinKey = nil
+
+	if err = btp.CheckKeys(); err != nil {
+		return nil, err
+	}
+
+	return btp, nil
+}
+
+// keyStreamIndex is the position of the records of the rotation and conjugation keys in a bootstrapping key stream,
+// from which each key is read on its first use.
+type keyStreamIndex struct {
+	sync.Mutex
+
+	params *Parameters
+	ringQP *ring.Ring
+	r      io.ReaderAt
+
+	offsets map[uint64]int64 // Offset of the record of the key of each Galois element
+
+	keys    map[uint64]*SwitchingKey // Keys already read, by Galois element
+	indexes map[uint64][]uint64      // Permutation indexes of the keys already read, by Galois element
+
+	relinKey *EvaluationKey // Relinearization key, read while indexing the stream
+}
+
+// indexBootstrappingKeyStream reads the headers of the records of the bootstrapping key stream r and returns the
+// position of each rotation and conjugation key. The relinearization key is read and checked against the scheme
+// parameters, and the records of the other keys are only checked to be complete.
+func indexBootstrappingKeyStream(params *Parameters, ringQP *ring.Ring, r io.ReaderAt) (idx *keyStreamIndex, err error) {
+
+	idx = &keyStreamIndex{
+		params:  params,
+		ringQP:  ringQP,
+		r:       r,
+		offsets: make(map[uint64]int64),
+		keys:    make(map[uint64]*SwitchingKey),
+		indexes: make(map[uint64][]uint64),
+	}
+
+	var n int
+	var offset int64
+
+	for {
+
+		var header [5]byte
+		if n, err = r.ReadAt(header[:], offset); n != len(header) {
+			if n == 0 && err == io.EOF {
+				break
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		dataOffset := offset + int64(len(header))
+
+		switch header[4] {
+		case 0:
+		case 1:
+			dataOffset += SeedSize
+		default:
+			return nil, errors.New("invalid bootstrapping key stream: invalid record header")
+		}
+
+		var length [8]byte
+		if n, err = r.ReadAt(length[:], dataOffset); n != len(length) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		dataLen := binary.BigEndian.Uint64(length[:])
+		dataOffset += int64(len(length))
+
+		if dataLen == 0 || dataLen > uint64(math.MaxInt64-dataOffset) {
+			return nil, errors.New("invalid bootstrapping key stream: invalid record length")
+		}
+
+		end := dataOffset + int64(dataLen)
+
+		// Checks that the record is complete without reading it
+		var last [1]byte
+		if n, err = r.ReadAt(last[:], end-1); n != len(last) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		keyType := int(header[0])
+		k := (uint64(header[1]) << 16) | (uint64(header[2]) << 8) | (uint64(header[3]))
+
+		switch keyType {
+		case relinKeyType:
+
+			var swk *SwitchingKey
+			if _, _, swk, err = readBootstrappingKeyRecord(ringQP, params.PiCount(), io.NewSectionReader(r, offset, end-offset)); err != nil {
+				return nil, err
+			}
+
+			if err = validateSwitchingKey(params, swk); err != nil {
+				return nil, fmt.Errorf("invalid bootstrapping key stream: %s", err)
+			}
+
+			idx.relinKey = &EvaluationKey{evakey: swk}
+
+		case Conjugate:
+			idx.offsets[params.GaloisElementForRowRotation()] = offset
+		case RotationLeft:
+			idx.offsets[params.GaloisElementForColumnRotation(int(k))] = offset
+		default:
+			return nil, fmt.Errorf("invalid bootstrapping key stream: unknown key type %d", keyType)
+		}
+
+		offset = end
+	}
+
+	return idx, nil
+}
+
+// has returns whether the stream contains the key of the Galois element galEl.
+func (idx *keyStreamIndex) has(galEl uint64) bool {
+	_, ok := idx.offsets[galEl]
+	return ok
+}
+
+// load returns the permutation index and the SwitchingKey of the Galois element galEl, reading, expanding and checking
+// the key on the first call. It returns a nil SwitchingKey if the stream does not contain the key, and panics if the
+// key cannot be read or is invalid.
+func (idx *keyStreamIndex) load(galEl uint64) (index []uint64, swk *SwitchingKey) {
+
+	idx.Lock()
+	defer idx.Unlock()
+
+	if swk = idx.keys[galEl]; swk != nil {
+		return idx.indexes[galEl], swk
+	}
+
+	offset, ok := idx.offsets[galEl]
+	if !ok {
+		return nil, nil
+	}
+
+	var err error
+	if _, _, swk, err = readBootstrappingKeyRecord(idx.ringQP, idx.params.PiCount(), io.NewSectionReader(idx.r, offset, math.MaxInt64-offset)); err == nil {
+		err = validateSwitchingKey(idx.params, swk)
+	}
+
+	if err != nil {
+		panic(fmt.Errorf("cannot load the key of Galois element %d from the bootstrapping key stream: %s", galEl, err))
+	}
+
+	index = ring.PermuteNTTIndexShared(galEl, 1, idx.ringQP.N)
+
+	idx.keys[galEl] = swk
+	idx.indexes[galEl] = index
+
+	return index, swk
+}
+
+func writeBootstrappingKeyRecord(w io.Writer, keyType int, k uint64, seed []byte, swk *SwitchingKey) (err error) {
+
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(k))
+	header[0] = uint8(keyType)
+
+	if seed != nil {
+		header[4] = 1
+	}
+
+	if _, err = w.Write(header[:]); err != nil {
+		return err
+	}
+
+	if seed == nil {
+		return writeLengthPrefixed(w, swk)
+	}
+
+	if _, err = w.Write(seed); err != nil {
+		return err
+	}
+
+	return writeLengthPrefixed(w, (*seededSwitchingKey)(swk))
+}
+
+// readBootstrappingKeyRecord reads the next record of a bootstrapping key stream. It returns io.EOF if the stream ends
+// before the record.
+func readBootstrappingKeyRecord(ringQP *ring.Ring, piCount uint64, r io.Reader) (keyType int, k uint64, swk *SwitchingKey, err error) {
+
+	var header [5]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+
+	keyType = int(header[0])
+	k = (uint64(header[1]) << 16) | (uint64(header[2]) << 8) | (uint64(header[3]))
+
+	swk = new(SwitchingKey)
+
+	switch header[4] {
+	case 0:
+		err = readLengthPrefixed(r, swk)
+	case 1:
+		seed := make([]byte, SeedSize)
+		if _, err = io.ReadFull(r, seed); err != nil {
+			break
+		}
+		if err = readLengthPrefixed(r, (*seededSwitchingKey)(swk)); err != nil {
+			break
+		}
+		err = swk.expand(ringQP, piCount, seed)
+	default:
+		err = errors.New("invalid bootstrapping key stream: invalid record header")
+	}
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return
+}
+
+// seededSwitchingKey is the encoding of a SwitchingKey without its uniform elements.
+type seededSwitchingKey SwitchingKey
+
+func (swk *seededSwitchingKey) MarshalBinary() (data []byte, err error) {
+
+	dataLen := uint64(1)
+	for i := range swk.evakey {
+		dataLen += swk.evakey[i][0].GetDataLen(true)
+	}
+
+	data = make([]byte, dataLen)
+	data[0] = uint8(len(swk.evakey))
+
+	pointer, inc := uint64(1), uint64(0)
+	for i := range swk.evakey {
+		if inc, err = swk.evakey[i][0].Encode(data[pointer:]); err != nil {
+			return nil, err
+		}
+		pointer += inc
+	}
+
+	return data, nil
+}
+
+func (swk *seededSwitchingKey) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) == 0 {
+		return errors.New("invalid seeded switching key encoding: too short")
+	}
+
+	swk.evakey = make([][2]*ring.Poly, data[0])
+
+	pointer, inc := uint64(1), uint64(0)
+	for i := range swk.evakey {
+		swk.evakey[i][0] = new(ring.Poly)
+		if inc, err = swk.evakey[i][0].DecodePolyNew(data[pointer:]); err != nil {
+			return err
+		}
+		pointer += inc
+	}
+
+	if pointer != uint64(len(data)) {
+		return errors.New("invalid seeded switching key encoding: remaining unparsed data")
+	}
+
+	return nil
+}
+
+// expand generates the uniform elements of the switching key from the seed, in the same order as the KeyGenerator.
+// The elements of a key generated for a lower level are truncated to its moduli, piCount being the number of moduli P.
+func (swk *SwitchingKey) expand(ringQP *ring.Ring, piCount uint64, seed []byte) (err error) {
+
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		return err
+	}
+
+	uniformSampler := ring.NewUniformSamplerWithDomain(prng, ringQP, true, true)
+
+	for i := range swk.evakey {
+
+		a := uniformSampler.ReadNew()
+
+		if moduli := uint64(len(swk.evakey[i][0].Coeffs)); moduli < uint64(len(a.Coeffs)) && moduli > piCount {
+			a.Coeffs = append(a.Coeffs[:moduli-piCount], a.Coeffs[uint64(len(a.Coeffs))-piCount:]...)
+		}
+
+		swk.evakey[i][1] = a
+	}
+
+	return nil
+}
@@ -1,12 +1,22 @@
 package ckks
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
 // BootstrappParams is a struct for the default bootstrapping parameters
 type BootstrappParams struct {
-	H            uint64   // Hamming weight of the secret key
+	H            uint64   // Hamming weight of the secret key (2N/3, the expected weight of GenSecretKey, for dense secrets)
 	SinType      SinType  // Chose betwenn [Sin(2*pi*x)] or [cos(2*pi*x/r) with double angle formula]
 	SinRange     uint64   // K parameter (interpolation in the range -K to K)
 	SinDeg       uint64   // Degree of the interpolation
 	SinRescal    uint64   // Number of rescale and double angle formula (only applies for cos)
+	ArcSineDeg   uint64   // Degree of the Taylor series of arcsine correcting the output of the sine (0 to disable)
 	CtSLevel     []uint64 // Level of the Coeffs To Slots
 	StCLevel     []uint64 // Level of the Slots To Coeffs
 	MaxN1N2Ratio float64  // n1/n2 ratio for the bsgs algo for matrix x vector eval
@@ -22,6 +32,15 @@ func (b *BootstrappParams) StCDepth() uint64 {
 	return uint64(len(b.StCLevel))
 }
 
+// ArcSineDepth returns the number of levels consumed by the arcsine correction, which are taken between the
+// double angle formula and SlotsToCoeffs.
+func (b *BootstrappParams) ArcSineDepth() uint64 {
+	if b.ArcSineDeg == 0 {
+		return 0
+	}
+	return uint64(bits.Len64(b.ArcSineDeg))
+}
+
 // SinType is the type of function used during the bootstrapping
 // for the homomorphic modular reduction
 type SinType uint64
@@ -33,6 +52,38 @@ const (
 	Cos2 = SinType(2) // Standard Chebyshev approximation of pow((1/2pi), 1/2^r) * cos(2pi(x-0.25)/2^r)
 )
 
+// String returns the name of the SinType.
+func (s SinType) String() string {
+	switch s {
+	case Sin:
+		return "Sin"
+	case Cos1:
+		return "Cos1"
+	case Cos2:
+		return "Cos2"
+	}
+	return fmt.Sprintf("SinType(%d)", uint64(s))
+}
+
+// MarshalText encodes the SinType by its name, so that it is readable in the JSON encoding of a BootstrappParams.
+func (s SinType) MarshalText() ([]byte, error) {
+	if s > Cos2 {
+		return nil, fmt.Errorf("invalid SinType %d", uint64(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a SinType on the target SinType.
+func (s *SinType) UnmarshalText(text []byte) error {
+	for _, t := range []SinType{Sin, Cos1, Cos2} {
+		if string(text) == t.String() {
+			*s = t
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid SinType %q", text)
+}
+
 // Copy return a new BootstrappParams which is a copy of the target
 func (b *BootstrappParams) Copy() *BootstrappParams {
 	paramsCopy := &BootstrappParams{
@@ -41,6 +92,7 @@ func (b *BootstrappParams) Copy() *BootstrappParams {
 		SinRange:     b.SinRange,
 		SinDeg:       b.SinDeg,
 		SinRescal:    b.SinRescal,
+		ArcSineDeg:   b.ArcSineDeg,
 		CtSLevel:     make([]uint64, len(b.CtSLevel)),
 		StCLevel:     make([]uint64, len(b.StCLevel)),
 		MaxN1N2Ratio: b.MaxN1N2Ratio,
@@ -50,6 +102,116 @@ func (b *BootstrappParams) Copy() *BootstrappParams {
 	return paramsCopy
 }
 
+// Validate checks that the bootstrapping parameters are consistent, and that they can be used with the scheme
+// parameters params. It must be called on parameters loaded with UnmarshalBinary or from JSON before their use.
+func (b *BootstrappParams) Validate(params *Parameters) error {
+
+	if b.H == 0 || b.H > params.N() {
+		return fmt.Errorf("BootstrappParams: H must be in [1, %d]", params.N())
+	}
+
+	if b.SinType > Cos2 {
+		return fmt.Errorf("BootstrappParams: invalid SinType %d", uint64(b.SinType))
+	}
+
+	if b.SinType == SinType(Sin) && b.SinRescal != 0 {
+		return fmt.Errorf("BootstrappParams: cannot use double angle formul for SinType = Sin -> must use SinType = Cos")
+	}
+
+	if b.SinRange == 0 || b.SinDeg == 0 {
+		return fmt.Errorf("BootstrappParams: SinRange and SinDeg must be positive")
+	}
+
+	if b.ArcSineDeg != 0 && (b.ArcSineDeg < 3 || b.ArcSineDeg&1 == 0) {
+		return fmt.Errorf("BootstrappParams: ArcSineDeg must be zero or an odd integer greater than one")
+	}
+
+	if !(b.MaxN1N2Ratio > 0) {
+		return fmt.Errorf("BootstrappParams: MaxN1N2Ratio must be positive")
+	}
+
+	if len(b.CtSLevel) == 0 || len(b.StCLevel) == 0 {
+		return fmt.Errorf("BootstrappParams: CtSLevel and StCLevel cannot be empty")
+	}
+
+	if b.CtSLevel[0] != params.MaxLevel() {
+		return fmt.Errorf("BootstrappParams: CtSLevel start not consistent with MaxLevel")
+	}
+
+	for _, levels := range [][]uint64{b.CtSLevel, b.StCLevel} {
+		for i := 1; i < len(levels); i++ {
+			if levels[i] > levels[i-1] {
+				return fmt.Errorf("BootstrappParams: CtSLevel and StCLevel must be non-increasing")
+			}
+		}
+	}
+
+	if b.StCLevel[0] >= b.CtSLevel[len(b.CtSLevel)-1] {
+		return fmt.Errorf("BootstrappParams: StCLevel must be below CtSLevel")
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the bootstrapping parameters on a slice of bytes.
+func (b *BootstrappParams) MarshalBinary() (data []byte, err error) {
+
+	if len(b.CtSLevel) > 0xFF || len(b.StCLevel) > 0xFF {
+		return nil, errors.New("cannot MarshalBinary: too many levels for CtSLevel or StCLevel")
+	}
+
+	buff := utils.NewBuffer(make([]byte, 0, 51+(len(b.CtSLevel)+len(b.StCLevel))<<3))
+
+	buff.WriteUint64(b.H)
+	buff.WriteUint8(uint8(b.SinType))
+	buff.WriteUint64(b.SinRange)
+	buff.WriteUint64(b.SinDeg)
+	buff.WriteUint64(b.SinRescal)
+	buff.WriteUint64(b.ArcSineDeg)
+	buff.WriteUint64(math.Float64bits(b.MaxN1N2Ratio))
+	buff.WriteUint8(uint8(len(b.CtSLevel)))
+	buff.WriteUint8(uint8(len(b.StCLevel)))
+	buff.WriteUint64Slice(b.CtSLevel)
+	buff.WriteUint64Slice(b.StCLevel)
+
+	return buff.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the target bootstrapping parameters.
+// The decoded parameters are not checked against the scheme parameters, see Validate.
+func (b *BootstrappParams) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) < 51 {
+		return errors.New("invalid BootstrappParams encoding: too short")
+	}
+
+	if uint64(len(data)) != 51+(uint64(data[49])+uint64(data[50]))<<3 {
+		return errors.New("invalid BootstrappParams encoding: invalid length")
+	}
+
+	buff := utils.NewBuffer(data)
+
+	b.H = buff.ReadUint64()
+	b.SinType = SinType(buff.ReadUint8())
+	b.SinRange = buff.ReadUint64()
+	b.SinDeg = buff.ReadUint64()
+	b.SinRescal = buff.ReadUint64()
+	b.ArcSineDeg = buff.ReadUint64()
+	b.MaxN1N2Ratio = math.Float64frombits(buff.ReadUint64())
+
+	b.CtSLevel = make([]uint64, buff.ReadUint8())
+	b.StCLevel = make([]uint64, buff.ReadUint8())
+
+	buff.ReadUint64Slice(b.CtSLevel)
+	buff.ReadUint64Slice(b.StCLevel)
+
+	if b.SinType > Cos2 {
+		return fmt.Errorf("invalid BootstrappParams encoding: invalid SinType %d", uint64(b.SinType))
+	}
+
+	return nil
+}
+
 // DefaultBootstrappSchemeParams are default scheme params for the bootstrapping
 var DefaultBootstrappSchemeParams = []*Parameters{
 
@@ -202,6 +364,267 @@ var DefaultBootstrappSchemeParams = []*Parameters{
 		scale: 1 << 25,
 		sigma: DefaultSigma,
 	},
+	{
+		logN:     17,
+		logSlots: 16,
+		qi: []uint64{
+			0x80000000080001,  // 55 Q0
+			0x200000440001,    // 45
+			0x200000500001,    // 45
+			0x1fffff980001,    // 45
+			0x200000c80001,    // 45
+			0x1ffffeb40001,    // 45
+			0x1ffffe640001,    // 45
+			0x200001a00001,    // 45
+			0x200001e80001,    // 45
+			0x1ffffe0c0001,    // 45
+			0x200002480001,    // 45
+			0x200002800001,    // 45
+			0x1ffffd800001,    // 45
+			0x200002900001,    // 45
+			0x1ffffd700001,    // 45
+			0x2000029c0001,    // 45
+			0x1ffffcf00001,    // 45
+			0x200003140001,    // 45
+			0x1ffffcc80001,    // 45
+			0x1ffffcb40001,    // 45
+			0x100000000980001, // 56 StC (28 + 28)
+			0x100c0001,        // 28 StC
+			0x80000000440001,  // 55 Sine (double angle)
+			0x80000000500001,  // 55 Sine (double angle)
+			0x7fffffff380001,  // 55 Sine
+			0x80000000e00001,  // 55 Sine
+			0x7ffffffef00001,  // 55 Sine
+			0x800000011c0001,  // 55 Sine
+			0x7ffffffeac0001,  // 55 Sine
+			0x7ffffffe700001,  // 55 Sine
+			0x20000000140001,  // 53 CtS
+			0x20000000280001,  // 53 CtS
+			0x1fffffffd80001,  // 53 CtS
+			0x20000000640001,  // 53 CtS
+		},
+		pi: []uint64{
+			0xfffffffff00001,  // 56
+			0xffffffffd80001,  // 56
+			0x100000000480001, // 56
+			0xffffffff780001,  // 56
+			0xffffffff640001,  // 56
+		},
+		scale: 1 << 45,
+		sigma: DefaultSigma,
+	},
+
+	// Dense secret, logN = 16
+	{
+		logN:     16,
+		logSlots: 15,
+		qi: []uint64{
+			0x80000000080001,   // 55 Q0
+			0x2000000a0001,     // 45
+			0x2000000e0001,     // 45
+			0x1fffffc20001,     // 45
+			0x200000440001,     // 45
+			0x200000500001,     // 45
+			0x200000620001,     // 45
+			0x1fffff980001,     // 45
+			0x100000000060001,  // 56 StC (28 + 28)
+			0xffa0001,          // 28 StC
+			0xffffffffffc0001,  // 60 Sine (double angle)
+			0x10000000006e0001, // 60 Sine (double angle)
+			0xfffffffff840001,  // 60 Sine (double angle)
+			0x1000000000860001, // 60 Sine (double angle)
+			0xfffffffff6a0001,  // 60 Sine
+			0x1000000000980001, // 60 Sine
+			0xfffffffff5a0001,  // 60 Sine
+			0x1000000000b00001, // 60 Sine
+			0x1000000000ce0001, // 60 Sine
+			0xfffffffff2a0001,  // 60 Sine
+			0xfffffffff240001,  // 60 Sine
+			0x1000000000f00001, // 60 Sine
+			0x200000000e0001,   // 53 CtS
+			0x20000000140001,   // 53 CtS
+			0x20000000280001,   // 53 CtS
+			0x1fffffffd80001,   // 53 CtS
+		},
+		pi: []uint64{
+			0x1fffffffffe00001, // Pi 61
+			0x1fffffffffc80001, // Pi 61
+			0x1fffffffffb40001, // Pi 61
+			0x1fffffffff500001, // Pi 61
+			0x1fffffffff420001, // Pi 61
+			0x1fffffffff380001, // Pi 61
+		},
+		scale: 1 << 45,
+		sigma: DefaultSigma,
+	},
+
+	// Dense secret, logN = 17
+	{
+		logN:     17,
+		logSlots: 16,
+		qi: []uint64{
+			0x80000000080001,   // 55 Q0
+			0x200000440001,     // 45
+			0x200000500001,     // 45
+			0x1fffff980001,     // 45
+			0x200000c80001,     // 45
+			0x1ffffeb40001,     // 45
+			0x1ffffe640001,     // 45
+			0x200001a00001,     // 45
+			0x200001e80001,     // 45
+			0x1ffffe0c0001,     // 45
+			0x200002480001,     // 45
+			0x200002800001,     // 45
+			0x1ffffd800001,     // 45
+			0x200002900001,     // 45
+			0x1ffffd700001,     // 45
+			0x100000000980001,  // 56 StC (28 + 28)
+			0x100c0001,         // 28 StC
+			0xffffffffe7c0001,  // 60 Sine (double angle)
+			0xffffffffe740001,  // 60 Sine (double angle)
+			0x1000000001a00001, // 60 Sine (double angle)
+			0xffffffffe4c0001,  // 60 Sine (double angle)
+			0xffffffffe440001,  // 60 Sine
+			0xffffffffe400001,  // 60 Sine
+			0x1000000002340001, // 60 Sine
+			0xffffffffdbc0001,  // 60 Sine
+			0xffffffffd840001,  // 60 Sine
+			0x1000000002940001, // 60 Sine
+			0xffffffffd680001,  // 60 Sine
+			0xffffffffd000001,  // 60 Sine
+			0xffffffffcf00001,  // 60 Sine
+			0x20000000140001,   // 53 CtS
+			0x20000000280001,   // 53 CtS
+			0x1fffffffd80001,   // 53 CtS
+			0x20000000640001,   // 53 CtS
+		},
+		pi: []uint64{
+			0x1fffffffff000001, // Pi 61
+			0x1ffffffffef00001, // Pi 61
+			0x1ffffffffee80001, // Pi 61
+			0x1ffffffffeb40001, // Pi 61
+			0x1ffffffffe780001, // Pi 61
+			0x1ffffffffe600001, // Pi 61
+		},
+		scale: 1 << 45,
+		sigma: DefaultSigma,
+	},
+
+	// Precise, logN = 15
+	{
+		logN:     15,
+		logSlots: 14,
+		qi: []uint64{
+			0x2000000a0001,    // 45 Q0
+			0x7fffb0001,       // 35
+			0x7fff80001,       // 35
+			0xffffffffffc0001, // 60 StC (30+30)
+			0x80000000080001,  // 55 Sine
+			0x80000000130001,  // 55 Sine
+			0x7fffffffe90001,  // 55 Sine
+			0x80000000190001,  // 55 Sine
+			0x800000001d0001,  // 55 Sine
+			0x7fffffffbf0001,  // 55 Sine
+			0x7fffffffbd0001,  // 55 Sine
+			0x80000000440001,  // 55 Sine
+			0x7fffffffba0001,  // 55 CtS
+			0x80000000490001,  // 55 CtS
+		},
+		pi: []uint64{
+			0x80000000500001, // 55
+			0x7fffffffaa0001, // 55
+		},
+		scale: 1 << 35,
+		sigma: DefaultSigma,
+	},
+
+	// Precise, logN = 16
+	{
+		logN:     16,
+		logSlots: 15,
+		qi: []uint64{
+			0xffffffffffc0001,  // 60 Q0
+			0x4000000120001,    // 50
+			0x3ffffffd20001,    // 50
+			0x4000000420001,    // 50
+			0x3ffffffb80001,    // 50
+			0x4000000660001,    // 50
+			0x40000007e0001,    // 50
+			0x4000000800001,    // 50
+			0x40000008a0001,    // 50
+			0x4000000de0001,    // 50
+			0x4000000f20001,    // 50
+			0x10000000006e0001, // 60 StC (30 + 30)
+			0x40020001,         // 30 StC
+			0xfffffffff840001,  // 60 Sine (double angle)
+			0x1000000000860001, // 60 Sine (double angle)
+			0xfffffffff6a0001,  // 60 Sine
+			0x1000000000980001, // 60 Sine
+			0xfffffffff5a0001,  // 60 Sine
+			0x1000000000b00001, // 60 Sine
+			0x1000000000ce0001, // 60 Sine
+			0xfffffffff2a0001,  // 60 Sine
+			0x100000000060001,  // 56 CtS
+			0xfffffffff00001,   // 56 CtS
+			0xffffffffd80001,   // 56 CtS
+			0x1000000002a0001,  // 56 CtS
+		},
+		pi: []uint64{
+			0x1fffffffffe00001, // Pi 61
+			0x1fffffffffc80001, // Pi 61
+			0x1fffffffffb40001, // Pi 61
+			0x1fffffffff500001, // Pi 61
+			0x1fffffffff420001, // Pi 61
+		},
+		scale: 1 << 50,
+		sigma: DefaultSigma,
+	},
+
+	// Precise, logN = 17
+	{
+		logN:     17,
+		logSlots: 16,
+		qi: []uint64{
+			0xffffffffffc0001,  // 60 Q0
+			0x3ffffffb80001,    // 50
+			0x4000000800001,    // 50
+			0x4000001340001,    // 50
+			0x3fffffec80001,    // 50
+			0x3fffffea40001,    // 50
+			0x3fffffe940001,    // 50
+			0x4000001700001,    // 50
+			0x4000001bc0001,    // 50
+			0x4000002100001,    // 50
+			0x3fffffdd40001,    // 50
+			0x3fffffd900001,    // 50
+			0x3fffffd540001,    // 50
+			0x3fffffd500001,    // 50
+			0x3fffffcc40001,    // 50
+			0xfffffffff840001,  // 60 StC (30 + 30)
+			0x3ffc0001,         // 30 StC
+			0x1000000000980001, // 60 Sine (double angle)
+			0x1000000000b00001, // 60 Sine (double angle)
+			0xfffffffff240001,  // 60 Sine
+			0x1000000000f00001, // 60 Sine
+			0xffffffffe7c0001,  // 60 Sine
+			0xffffffffe740001,  // 60 Sine
+			0x1000000001a00001, // 60 Sine
+			0xffffffffe4c0001,  // 60 Sine
+			0xfffffffff00001,   // 56 CtS
+			0xffffffffd80001,   // 56 CtS
+			0x100000000480001,  // 56 CtS
+			0xffffffff780001,   // 56 CtS
+		},
+		pi: []uint64{
+			0x1fffffffffe00001, // Pi 61
+			0x1fffffffffc80001, // Pi 61
+			0x1fffffffffb40001, // Pi 61
+			0x1fffffffff500001, // Pi 61
+			0x1fffffffff380001, // Pi 61
+		},
+		scale: 1 << 50,
+		sigma: DefaultSigma,
+	},
 }
 
 // DefaultBootstrappParams are default bootstrapping params for the bootstrapping
@@ -258,4 +681,81 @@ var DefaultBootstrappParams = []*BootstrappParams{
 		StCLevel:     []uint64{3, 3},
 		MaxN1N2Ratio: 16.0,
 	},
+	// Set VIII (logN = 17, to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 1926 - 910
+	{
+		H:            192,
+		SinType:      Cos1,
+		SinRange:     21,
+		SinDeg:       52,
+		SinRescal:    2,
+		CtSLevel:     []uint64{33, 32, 31, 30},
+		StCLevel:     []uint64{21, 20, 20},
+		MaxN1N2Ratio: 16.0,
+	},
+
+	// Dense secret, logN = 16 (to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 1752 - 370
+	{
+		H:            43690,
+		SinType:      Cos2,
+		SinRange:     325,
+		SinDeg:       200,
+		SinRescal:    4,
+		CtSLevel:     []uint64{25, 24, 23, 22},
+		StCLevel:     []uint64{9, 8, 8},
+		MaxN1N2Ratio: 16.0,
+	},
+
+	// Dense secret, logN = 17 (to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 2128 - 685
+	{
+		H:            87381,
+		SinType:      Cos2,
+		SinRange:     450,
+		SinDeg:       300,
+		SinRescal:    4,
+		CtSLevel:     []uint64{33, 32, 31, 30},
+		StCLevel:     []uint64{16, 15, 15},
+		MaxN1N2Ratio: 16.0,
+	},
+
+	// Precise, logN = 15 (to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 835 - 115
+	{
+		H:            192,
+		SinType:      Cos1,
+		SinRange:     21,
+		SinDeg:       52,
+		SinRescal:    2,
+		CtSLevel:     []uint64{13, 12},
+		StCLevel:     []uint64{3, 3},
+		MaxN1N2Ratio: 16.0,
+	},
+
+	// Precise, logN = 16 (to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 1660 - 560
+	{
+		H:            196,
+		SinType:      Cos1,
+		SinRange:     21,
+		SinDeg:       52,
+		SinRescal:    2,
+		CtSLevel:     []uint64{24, 23, 22, 21},
+		StCLevel:     []uint64{12, 11, 11},
+		MaxN1N2Ratio: 16.0,
+	},
+
+	// Precise, logN = 17 (to be used with the corresponding DefaultBootstrappSchemeParams)
+	// 1859 - 760
+	{
+		H:            192,
+		SinType:      Cos1,
+		SinRange:     21,
+		SinDeg:       52,
+		SinRescal:    2,
+		CtSLevel:     []uint64{28, 27, 26, 25},
+		StCLevel:     []uint64{16, 15, 15},
+		MaxN1N2Ratio: 16.0,
+	},
 }
@@ -0,0 +1,112 @@
+package ckks
+
+import (
+	"fmt"
+	"time"
+)
+
+// BootstrappPreset is a named trade-off between the latency of the bootstrapping, its output precision and the
+// number of levels left to the circuit after each bootstrapping.
+type BootstrappPreset int
+
+const (
+	// BootstrappFast is the preset with the smallest modulus chain, hence the smallest keys and the lowest latency, and
+	// the fewest levels.
+	BootstrappFast BootstrappPreset = iota
+	// BootstrappBalanced is the preset trading a larger modulus chain, hence a higher latency, for more levels.
+	BootstrappBalanced
+	// BootstrappPrecise is the preset with the most levels and the best precision, with a scale of 2^50, at the highest
+	// latency.
+	BootstrappPrecise
+)
+
+// String returns the name of the preset.
+func (preset BootstrappPreset) String() string {
+	switch preset {
+	case BootstrappFast:
+		return "Fast"
+	case BootstrappBalanced:
+		return "Balanced"
+	case BootstrappPrecise:
+		return "Precise"
+	}
+	return fmt.Sprintf("BootstrappPreset(%d)", int(preset))
+}
+
+// BootstrappPresetInfo documents a bootstrapping preset. Its Precision, MinPrecision and Latency were measured with the
+// preset itself, at the ring degree 2^LogN, see bootstrappPresets.
+type BootstrappPresetInfo struct {
+	LogN   uint64
+	Preset BootstrappPreset
+
+	// Levels is the number of levels left to the circuit after the bootstrapping.
+	Levels uint64
+
+	// Precision is the mean log2 precision of the real and imaginary parts of the slots after one bootstrapping,
+	// measured on values uniform in [-1, 1] with all the slots used. MinPrecision is the minimum over the slots.
+	Precision, MinPrecision float64
+	// Latency is the CPU time of one bootstrapping on a single core of the machine described in bootstrappPresets.
+	Latency time.Duration
+
+	// index is the index of the base parameters in DefaultBootstrappSchemeParams and DefaultBootstrappParams
+	// and drop the number of levels between the last modulus qi of the circuit and CoeffsToSlots removed from them.
+	index, drop uint64
+}
+
+// bootstrappPresets are the available presets. Their precision and latency were measured at the ring degree and with
+// the moduli and the secret of the preset, over four bootstrappings for logN = 16 and three for logN = 17, with all the
+// slots used: Precision and Latency are the means over the bootstrappings, and MinPrecision the minimum. The latency is
+// the user CPU time of the bootstrapping with GOMAXPROCS = 1, measured with Go 1.27 on one core of a virtual machine on
+// a 2.1GHz Intel Xeon (Emerald Rapids) with AVX-512 and 6GB of memory. The keys did not fit in this memory and were
+// partly swapped out during the bootstrapping, hence the wall clock time, up to four times the CPU time, is not reported.
+//
+// From BootstrappFast to BootstrappPrecise, the presets of a ring degree have an increasing latency and number of levels,
+// and a precision and a minimum precision that do not decrease. The presets for logN = 17 have the same bootstrapping
+// circuit, hence the same precision, and only trade levels for latency. There are no presets for logN = 15, whose
+// parameters leave at most two levels, which is too few for three distinct presets.
+var bootstrappPresets = []BootstrappPresetInfo{
+	{LogN: 16, Preset: BootstrappFast, Levels: 6, Precision: 19.7, MinPrecision: 16.9, Latency: 22100 * time.Millisecond, index: 0, drop: 4},
+	{LogN: 16, Preset: BootstrappBalanced, Levels: 8, Precision: 19.7, MinPrecision: 17.1, Latency: 26800 * time.Millisecond, index: 0, drop: 2},
+	{LogN: 16, Preset: BootstrappPrecise, Levels: 10, Precision: 24.2, MinPrecision: 19.8, Latency: 29600 * time.Millisecond, index: 8},
+
+	{LogN: 17, Preset: BootstrappFast, Levels: 8, Precision: 22.8, MinPrecision: 16.7, Latency: 58200 * time.Millisecond, index: 9, drop: 6},
+	{LogN: 17, Preset: BootstrappBalanced, Levels: 11, Precision: 22.8, MinPrecision: 16.7, Latency: 70500 * time.Millisecond, index: 9, drop: 3},
+	{LogN: 17, Preset: BootstrappPrecise, Levels: 14, Precision: 22.8, MinPrecision: 16.8, Latency: 88800 * time.Millisecond, index: 9},
+}
+
+// BootstrappPresets returns the documentation of all the available bootstrapping presets.
+func BootstrappPresets() (presets []BootstrappPresetInfo) {
+	return append(presets, bootstrappPresets...)
+}
+
+// GetBootstrappPreset returns copies of the parameters and of the bootstrapping parameters of the given preset for the
+// ring degree 2^logN, along with its documentation. The parameters use all the slots, and can be changed with
+// SetLogSlots. It returns an error if there is no such preset.
+func GetBootstrappPreset(logN uint64, preset BootstrappPreset) (params *Parameters, btpParams *BootstrappParams, info BootstrappPresetInfo, err error) {
+
+	for _, info = range bootstrappPresets {
+
+		if info.LogN != logN || info.Preset != preset {
+			continue
+		}
+
+		params = DefaultBootstrappSchemeParams[info.index].Copy()
+		btpParams = DefaultBootstrappParams[info.index].Copy()
+
+		// The dropped moduli are the last ones of the circuit, right below the ones of SlotsToCoeffs
+		first := btpParams.StCLevel[len(btpParams.StCLevel)-1] - info.drop
+		params.qi = append(params.qi[:first], params.qi[first+info.drop:]...)
+
+		for i := range btpParams.CtSLevel {
+			btpParams.CtSLevel[i] -= info.drop
+		}
+
+		for i := range btpParams.StCLevel {
+			btpParams.StCLevel[i] -= info.drop
+		}
+
+		return params, btpParams, info, nil
+	}
+
+	return nil, nil, BootstrappPresetInfo{}, fmt.Errorf("no %s bootstrapping preset for logN = %d", preset, logN)
+}
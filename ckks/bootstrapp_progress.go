@@ -0,0 +1,61 @@
+package ckks
+
+import (
+	"context"
+)
+
+// BootstrappProgressFunc is a callback invoked at the end of each stage of the bootstrapping with the level and the
+// scale of the ciphertext at the output of the stage.
+type BootstrappProgressFunc func(stage BootstrappStage, level uint64, scale float64)
+
+// BootstrappWithContext bootstraps the ciphertext as Bootstrapp does, and calls progress, if not nil, at the end of each
+// stage. The context is checked before the bootstrapping and at the end of each stage but the last one: if it is done,
+// the bootstrapping is aborted and its error is returned, in which case the input ciphertext must be considered as
+// consumed. A stage cannot be interrupted, hence the cancellation takes effect with a delay of up to the duration of the
+// longest stage, usually EvalMod or CoeffsToSlots.
+func (btp *Bootstrapper) BootstrappWithContext(ctx context.Context, ct *Ciphertext, progress BootstrappProgressFunc) (*Ciphertext, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p := &bootstrappProgress{ctx: ctx, callback: progress}
+
+	ctOut := btp.bootstrapp(ct, btp.OutputLevel(), ct.Scale(), nil, p)
+
+	if ctOut == nil {
+		btp.logger.Info("ckks: bootstrapping: aborted", "error", p.err)
+		return nil, p.err
+	}
+
+	return ctOut, nil
+}
+
+// bootstrappProgress reports the stages of a bootstrapping to a BootstrappProgressFunc and checks the cancellation of
+// its context. Its methods can be called on a nil *bootstrappProgress, which reports nothing and is never cancelled.
+type bootstrappProgress struct {
+	ctx      context.Context
+	callback BootstrappProgressFunc
+	err      error // Error of the context that aborted the bootstrapping
+}
+
+// report calls the callback, if not nil, with the stage and the level and scale of its output.
+func (p *bootstrappProgress) report(stage BootstrappStage, ct *Ciphertext) {
+	if p != nil && p.callback != nil {
+		p.callback(stage, ct.Level(), ct.Scale())
+	}
+}
+
+// done reports the end of the stage and returns true if the bootstrapping must be aborted.
+func (p *bootstrappProgress) done(stage BootstrappStage, ct *Ciphertext) bool {
+
+	if p == nil {
+		return false
+	}
+
+	p.report(stage, ct)
+
+	p.err = p.ctx.Err()
+
+	return p.err != nil
+}
@@ -0,0 +1,395 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// bootstrappSearchH is the Hamming weight of the secret of the parameters found by SearchBootstrappParams, the one of
+// the default bootstrapping parameters with sparse secret.
+const bootstrappSearchH = 192
+
+// bootstrappSearchLogMargin is the number of bits between the scale and the target precision, on top of the rescaling
+// noise, that SearchBootstrappParams leaves to the circuit evaluated between two bootstrappings.
+const bootstrappSearchLogMargin = 12
+
+// bootstrappSearchLogSpecial is the size of the special moduli of the parameters found by SearchBootstrappParams.
+const bootstrappSearchLogSpecial = MaxModuliSize
+
+// bootstrappSearchMaxDFTDepth is the largest number of levels searched for CoeffsToSlots and SlotsToCoeffs.
+const bootstrappSearchMaxDFTDepth = 4
+
+// bootstrappSearchMaxSinDeg is the largest degree searched for the approximation of the sine.
+const bootstrappSearchMaxSinDeg = 255
+
+var (
+	bootstrappSearchSinRescal  = []uint64{2, 3, 4}
+	bootstrappSearchArcSineDeg = []uint64{0, 3, 5, 7}
+)
+
+// SearchBootstrappParams searches for the bootstrapping parameters of the ring degree 2^logN and 2^logSlots slots that
+// leave depth levels to the circuit after each bootstrapping and achieve a mean precision of logPrecision bits. It
+// searches over the number of levels of CoeffsToSlots and SlotsToCoeffs, the range K and the degree of the
+// approximation of the sine, its number of double angle formulas and the degree of the arcsine correction, and returns
+// the candidate of the smallest estimated cost with the scheme parameters instantiating its moduli chain and its
+// predicted precision. The cost is estimated by the number of key-switchings weighted by the number of moduli at their
+// level. The secret is sparse with the Hamming weight of the default bootstrapping parameters, the sine is approximated
+// with Cos2, and the parameters are checked against MinSecurityLevel. The predicted precision is the one of the
+// modular reduction, evaluated in plaintext on the coefficients of slots uniform in [-1, 1], combined with a
+// first-order estimate of the noise of CoeffsToSlots and SlotsToCoeffs. It returns an error if no candidate reaches
+// the target precision.
+func SearchBootstrappParams(logN, logSlots, depth uint64, logPrecision float64) (params *Parameters, btpParams *BootstrappParams, precision float64, err error) {
+
+	if logN < 10 || logN > MaxLogN {
+		return nil, nil, 0, fmt.Errorf("cannot SearchBootstrappParams: logN must be between 10 and %d", MaxLogN)
+	}
+
+	if logSlots == 0 || logSlots > logN-1 {
+		return nil, nil, 0, fmt.Errorf("cannot SearchBootstrappParams: logSlots must be between 1 and %d", logN-1)
+	}
+
+	if !(logPrecision > 0) {
+		return nil, nil, 0, fmt.Errorf("cannot SearchBootstrappParams: logPrecision must be positive")
+	}
+
+	search := newBootstrappSearch(logN, logSlots, depth, logPrecision)
+
+	if search.logQ0 > MaxModuliSize {
+		return nil, nil, 0, fmt.Errorf("cannot SearchBootstrappParams: a precision of %.1f bits requires a first modulus larger than %d bits", logPrecision, MaxModuliSize)
+	}
+
+	var lm *LogModuli
+	cost := math.Inf(1)
+
+	for _, sine := range search.sineCandidates() {
+
+		for ctsDepth := uint64(1); ctsDepth <= bootstrappSearchMaxDFTDepth && ctsDepth <= logSlots; ctsDepth++ {
+
+			for stcDepth := uint64(1); stcDepth <= bootstrappSearchMaxDFTDepth && stcDepth <= logSlots; stcDepth++ {
+
+				candidate := sine.Copy()
+				candidateLm := search.logModuli(candidate, ctsDepth, stcDepth)
+
+				if candidateLm == nil {
+					continue
+				}
+
+				if candidateCost := search.cost(candidate, candidateLm); candidateCost < cost {
+					cost = candidateCost
+					btpParams = candidate
+					lm = candidateLm
+				}
+			}
+		}
+	}
+
+	if btpParams == nil {
+		return nil, nil, 0, fmt.Errorf("cannot SearchBootstrappParams: no parameters for logN = %d reach a precision of %.1f bits with depth %d", logN, logPrecision, depth)
+	}
+
+	if params, err = NewParametersFromLogModuli(logN, lm); err != nil {
+		return nil, nil, 0, err
+	}
+
+	params.SetScale(math.Exp2(float64(search.logScale)))
+
+	if err = params.SetLogSlots(logSlots); err != nil {
+		return nil, nil, 0, err
+	}
+
+	if err = btpParams.Validate(params); err != nil {
+		return nil, nil, 0, err
+	}
+
+	return params, btpParams, search.precision(btpParams), nil
+}
+
+// bootstrappSearch stores the quantities shared by the candidates of SearchBootstrappParams.
+type bootstrappSearch struct {
+	logN, logSlots, depth uint64
+	logPrecision          float64
+
+	logScale, logQ0 uint64
+	sigma           float64 // Standard deviation of the rescaling noise in the slots
+}
+
+func newBootstrappSearch(logN, logSlots, depth uint64, logPrecision float64) (search *bootstrappSearch) {
+
+	search = &bootstrappSearch{logN: logN, logSlots: logSlots, depth: depth, logPrecision: logPrecision}
+
+	// The rounding error of a rescaling is the inner product of the secret with N coefficients uniform in [-1/2, 1/2]
+	search.sigma = math.Sqrt(math.Exp2(float64(logN)) * float64(bootstrappSearchH+1) / 12)
+
+	search.logScale = uint64(math.Ceil(logPrecision+math.Log2(search.sigma))) + bootstrappSearchLogMargin
+	search.logQ0 = search.logScale + logQ0Headroom
+
+	return
+}
+
+// sineCandidates returns, for each range K, number of double angle formulas and arcsine correction, the bootstrapping
+// parameters with the smallest degree of the approximation of the sine reaching the target precision, if any. The
+// levels are not set.
+func (search *bootstrappSearch) sineCandidates() (candidates []*BootstrappParams) {
+
+	// The multiple of Q0 removed by the modular reduction is the inner product of the secret with N coefficients
+	// uniform in [-1/2, 1/2], hence K covers five of its standard deviations.
+	minK := uint64(math.Ceil(5 * math.Sqrt(float64(bootstrappSearchH+1)/12)))
+
+	for _, K := range []uint64{minK, minK + 4, minK + 8} {
+
+		for _, sinRescal := range bootstrappSearchSinRescal {
+
+			for _, arcSineDeg := range bootstrappSearchArcSineDeg {
+
+				candidate := &BootstrappParams{
+					H:            bootstrappSearchH,
+					SinType:      Cos2,
+					SinRange:     K,
+					SinRescal:    sinRescal,
+					ArcSineDeg:   arcSineDeg,
+					MaxN1N2Ratio: 16.0,
+				}
+
+				// Smallest degree reaching the target precision, by bisection
+				lo, hi := uint64(7), uint64(bootstrappSearchMaxSinDeg)
+
+				if candidate.SinDeg = hi; search.precision(candidate) < search.logPrecision {
+					continue
+				}
+
+				for lo < hi {
+
+					if candidate.SinDeg = (lo + hi) >> 1; search.precision(candidate) < search.logPrecision {
+						lo = candidate.SinDeg + 1
+					} else {
+						hi = candidate.SinDeg
+					}
+				}
+
+				candidate.SinDeg = hi
+
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	return
+}
+
+// precision returns the predicted mean precision of the bootstrapping with the given parameters, in bits.
+func (search *bootstrappSearch) precision(btpParams *BootstrappParams) float64 {
+
+	deviation := 1024.0
+
+	// The coefficients of the plaintext of slots uniform in [-1, 1] have a variance of 1/(3 * slots)
+	evalMod := btpParams.evalModError(deviation, math.Sqrt(1/(3*math.Exp2(float64(search.logSlots)))))
+
+	// The errors of the coefficients add up in the real part of each slot, whose mean absolute error is sqrt(N/pi) times
+	// the root mean square error of the coefficients.
+	evalMod *= math.Sqrt(math.Exp2(float64(search.logN)) / math.Pi)
+
+	// The noise of CoeffsToSlots, at the size of the moduli of the sine divided by the deviation, is amplified by the
+	// double angle formulas and by the deviation, and the noise of SlotsToCoeffs is at the output scale.
+	noise := search.sigma * (deviation*deviation*math.Exp2(float64(btpParams.SinRescal+3)-float64(search.logQSine(btpParams))) + math.Exp2(-float64(search.logScale)))
+
+	return -math.Log2(evalMod + noise)
+}
+
+// logQSine returns the size of the moduli of CoeffsToSlots and of the sine of the bootstrapping parameters, such that
+// the noise of CoeffsToSlots amplified by the evaluation of the sine is below the target precision.
+func (search *bootstrappSearch) logQSine(btpParams *BootstrappParams) (logQSine uint64) {
+
+	logQSine = uint64(math.Ceil(search.logPrecision+math.Log2(search.sigma))) + 2*logQ0Headroom + btpParams.SinRescal + 4
+
+	if logQSine < search.logQ0 {
+		return search.logQ0
+	}
+
+	if logQSine > MaxModuliSize {
+		return MaxModuliSize
+	}
+
+	return
+}
+
+// logModuli returns the moduli chain of the bootstrapping parameters with the given number of levels for
+// CoeffsToSlots and SlotsToCoeffs, and sets their levels. It returns nil if the moduli chain has too many moduli or
+// does not reach MinSecurityLevel with a single special modulus.
+func (search *bootstrappSearch) logModuli(btpParams *BootstrappParams, ctsDepth, stcDepth uint64) (lm *LogModuli) {
+
+	sineDepth := uint64(bits.Len64(btpParams.SinDeg)) + btpParams.SinRescal + btpParams.ArcSineDepth()
+
+	maxLevel := search.depth + stcDepth + sineDepth + ctsDepth
+
+	if maxLevel+2 > MaxModuliCount {
+		return nil
+	}
+
+	lm = &LogModuli{LogQi: make([]uint64, maxLevel+1)}
+
+	// Circuit and SlotsToCoeffs at the scale, sine and CoeffsToSlots above Q0
+	lm.LogQi[0] = search.logQ0
+	for i := uint64(1); i < maxLevel+1; i++ {
+		if i > search.depth+stcDepth {
+			lm.LogQi[i] = search.logQSine(btpParams)
+		} else {
+			lm.LogQi[i] = search.logScale
+		}
+	}
+
+	btpParams.StCLevel = make([]uint64, stcDepth)
+	for i := range btpParams.StCLevel {
+		btpParams.StCLevel[i] = search.depth + stcDepth - uint64(i)
+	}
+
+	btpParams.CtSLevel = make([]uint64, ctsDepth)
+	for i := range btpParams.CtSLevel {
+		btpParams.CtSLevel[i] = maxLevel - uint64(i)
+	}
+
+	// The key-switchings are the fastest with the largest special modulus that keeps the parameters secure
+	for alpha := maxLevel + 1; alpha > 0; alpha-- {
+
+		lm.LogPi = make([]uint64, alpha)
+		for i := range lm.LogPi {
+			lm.LogPi[i] = bootstrappSearchLogSpecial
+		}
+
+		if maxLevel+1+alpha <= MaxModuliCount && securityLevelLogModuli(search.logN, lm) >= MinSecurityLevel {
+			return lm
+		}
+	}
+
+	return nil
+}
+
+// cost returns the estimated cost of the bootstrapping with the given parameters and moduli chain, in key-switchings
+// weighted by the number of moduli at their level.
+func (search *bootstrappSearch) cost(btpParams *BootstrappParams, lm *LogModuli) (cost float64) {
+
+	alpha := uint64(len(lm.LogPi))
+
+	keySwitch := func(level uint64) float64 {
+		return float64(((level + alpha) / alpha) * (level + 1 + alpha))
+	}
+
+	// The butterflies of the DFT are merged into one matrix per level, whose number of non-zero diagonals is
+	// 2^(merged+1) - 1, evaluated with the baby-step giant-step algorithm.
+	dft := func(levels []uint64) (cost float64) {
+		logSlots := search.logSlots
+		for i, level := range levels {
+			merged := uint64(math.Ceil(float64(logSlots) / float64(len(levels)-i)))
+			logSlots -= merged
+			cost += 2 * math.Sqrt(math.Exp2(float64(merged+1))) * keySwitch(level)
+		}
+		return
+	}
+
+	cost += dft(btpParams.CtSLevel) + dft(btpParams.StCLevel)
+
+	// Non-scalar multiplications of the baby-step giant-step evaluation of the polynomials
+	poly := func(deg uint64) float64 {
+		if deg == 0 {
+			return 0
+		}
+		logDegree := uint64(bits.Len64(deg))
+		logSplit := logDegree >> 1
+		return math.Exp2(float64(logSplit)) + math.Exp2(float64(logDegree-logSplit)) + float64(logDegree)
+	}
+
+	sineLevel := (btpParams.CtSLevel[len(btpParams.CtSLevel)-1] + btpParams.StCLevel[0]) >> 1
+
+	cost += (poly(btpParams.SinDeg) + float64(btpParams.SinRescal) + poly(btpParams.ArcSineDeg)) * keySwitch(sineLevel)
+
+	return
+}
+
+// evalModError returns the root mean square error, in units of the message, of the modular reduction evaluated in
+// plaintext with the approximation of the bootstrapping parameters on coefficients of the message of standard deviation
+// sigma scaled down by deviation. The error for each multiple of Q0 within the range of the approximation is weighted
+// by its probability for the Hamming weight of the parameters.
+func (b *BootstrappParams) evalModError(deviation, sigma float64) (err float64) {
+
+	cheby := b.sineApproximation(deviation)
+
+	var arcsine *Poly
+	if b.ArcSineDeg != 0 {
+		arcsine = arcSineTaylor(b.ArcSineDeg)
+	}
+
+	scFac := math.Exp2(float64(b.SinRescal))
+
+	variance := float64(b.H+1) / 12
+
+	// The coefficients are sampled uniformly with variance sigma^2
+	samples := 64
+	bound := math.Sqrt(3) * sigma
+
+	var weights float64
+
+	for I := -int(b.SinRange) + 1; I < int(b.SinRange); I++ {
+
+		var sum float64
+
+		for j := 0; j < samples; j++ {
+
+			m := bound * (-1 + float64(2*j+1)/float64(samples)) / deviation
+			x := float64(I) + m
+
+			var y complex128
+
+			if b.SinType == Sin {
+				y = evaluateChebyshevPolynomial(cheby.coeffs, complex(x, 0), cheby.a, cheby.b)
+			} else {
+
+				y = evaluateChebyshevPolynomial(cheby.coeffs, complex((x-0.25)/scFac, 0), cheby.a, cheby.b)
+
+				sqrt2pi := math.Pow(0.15915494309189535, 1.0/scFac)
+
+				for i := uint64(0); i < b.SinRescal; i++ {
+					sqrt2pi *= sqrt2pi
+					y = 2*y*y - complex(sqrt2pi, 0)
+				}
+			}
+
+			if arcsine != nil {
+				y = evaluatePolynomial(arcsine.coeffs, y)
+			}
+
+			sum += (real(y) - m) * (real(y) - m)
+		}
+
+		weight := math.Exp(-float64(I*I) / (2 * variance))
+
+		err += weight * sum / float64(samples)
+		weights += weight
+	}
+
+	return deviation * math.Sqrt(err/weights)
+}
+
+// evaluatePolynomial evaluates the polynomial of the given coefficients in the power basis at x with Horner's method.
+func evaluatePolynomial(coeffs []complex128, x complex128) (y complex128) {
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = y*x + coeffs[i]
+	}
+	return
+}
+
+// securityLevelLogModuli returns the estimated security of parameters of ring degree 2^logN with the moduli of the
+// given sizes, see SecurityLevel.
+func securityLevelLogModuli(logN uint64, lm *LogModuli) float64 {
+
+	p := &Parameters{logN: logN, qi: make([]uint64, len(lm.LogQi)), pi: make([]uint64, len(lm.LogPi))}
+
+	for i, logQi := range lm.LogQi {
+		p.qi[i] = 1 << logQi
+	}
+
+	for i, logPi := range lm.LogPi {
+		p.pi[i] = 1 << logPi
+	}
+
+	return p.SecurityLevel()
+}
@@ -1,13 +1,25 @@
 package ckks
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"math"
 	"math/cmplx"
 	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ldsec/lattigo/v2/ckks/bettersine"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestBootstrapp(t *testing.T) {
@@ -24,7 +36,7 @@ func TestBootstrapp(t *testing.T) {
 
 	for paramSet := range shemeParams {
 
-		params := shemeParams[paramSet]
+		params := shemeParams[paramSet].Copy()
 		btpParams := bootstrappParams[paramSet]
 
 		q := params.qi[params.MaxLevel()-uint64(len(btpParams.CtSLevel))]
@@ -35,6 +47,10 @@ func TestBootstrapp(t *testing.T) {
 		if testing.Short() {
 			params.logN = 14
 			params.logSlots = 13
+
+			// A bootstrapping key takes a few GB: the garbage of a test must not double the heap while the next one
+			// generates a key
+			defer debug.SetGCPercent(debug.SetGCPercent(20))
 		}
 
 		if testContext, err = genTestParams(params, btpParams.H); err != nil {
@@ -45,12 +61,11 @@ func TestBootstrapp(t *testing.T) {
 
 		t.Run(testString(testContext, "ChebySin/"), func(t *testing.T) {
 
-			eval := testContext.evaluator
+			eval := NewEvaluatorWithScalePolicy(testContext.params, ScalePolicy{DefaultScale: SineScale})
 
 			DefaultScale := testContext.params.scale
 
 			testContext.params.scale = SineScale
-			eval.(*evaluator).scale = SineScale
 
 			deg := 127
 			K := float64(15)
@@ -66,24 +81,22 @@ func TestBootstrapp(t *testing.T) {
 
 			//fmt.Println(ciphertext.Level() - 1)
 			//start := time.Now()
-			ciphertext = testContext.evaluator.EvaluateCheby(ciphertext, cheby, testContext.rlk)
+			ciphertext = eval.EvaluateCheby(ciphertext, cheby, testContext.rlk)
 			//fmt.Printf("Elapsed : %s \n", time.Since(start))
 			//fmt.Println(ciphertext.Level())
 
 			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 
 			testContext.params.scale = DefaultScale
-			eval.(*evaluator).scale = DefaultScale
 		})
 
 		t.Run(testString(testContext, "ChebyCos/"), func(t *testing.T) {
 
-			eval := testContext.evaluator
+			eval := NewEvaluatorWithScalePolicy(testContext.params, ScalePolicy{DefaultScale: SineScale})
 
 			DefaultScale := testContext.params.scale
 
 			testContext.params.scale = SineScale
-			eval.(*evaluator).scale = SineScale
 
 			K := 21
 			deg := 52
@@ -119,19 +132,19 @@ func TestBootstrapp(t *testing.T) {
 				values[i] /= 6.283185307179586
 			}
 
-			testContext.evaluator.AddConst(ciphertext, -0.25, ciphertext)
+			eval.AddConst(ciphertext, -0.25, ciphertext)
 
 			//fmt.Println(ciphertext.Level(), ciphertext.Scale())
 			//start := time.Now()
-			ciphertext = testContext.evaluator.EvaluateChebySpecial(ciphertext, scFac, cheby, testContext.rlk)
+			ciphertext = eval.EvaluateChebySpecial(ciphertext, scFac, cheby, testContext.rlk)
 			//fmt.Println(ciphertext.Level(), ciphertext.Scale())
 
 			for i := 0; i < scNum; i++ {
 				sqrt2pi *= sqrt2pi
-				testContext.evaluator.MulRelin(ciphertext, ciphertext, testContext.rlk, ciphertext)
-				testContext.evaluator.Add(ciphertext, ciphertext, ciphertext)
-				testContext.evaluator.AddConst(ciphertext, -sqrt2pi, ciphertext)
-				testContext.evaluator.Rescale(ciphertext, eval.(*evaluator).scale, ciphertext)
+				eval.MulRelin(ciphertext, ciphertext, testContext.rlk, ciphertext)
+				eval.Add(ciphertext, ciphertext, ciphertext)
+				eval.AddConst(ciphertext, -sqrt2pi, ciphertext)
+				eval.Rescale(ciphertext, eval.ScalePolicy().RescaleThreshold, ciphertext)
 			}
 
 			//fmt.Printf("Elapsed : %s \n", time.Since(start))
@@ -139,18 +152,16 @@ func TestBootstrapp(t *testing.T) {
 			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 
 			testContext.params.scale = DefaultScale
-			eval.(*evaluator).scale = DefaultScale
 
 		})
 
 		t.Run(testString(testContext, "ChebyCosNaive/"), func(t *testing.T) {
 
-			eval := testContext.evaluator
+			eval := NewEvaluatorWithScalePolicy(testContext.params, ScalePolicy{DefaultScale: SineScale})
 
 			DefaultScale := testContext.params.scale
 
 			testContext.params.scale = SineScale
-			eval.(*evaluator).scale = SineScale
 
 			K := 257
 			deg := 250
@@ -180,19 +191,19 @@ func TestBootstrapp(t *testing.T) {
 				values[i] /= 6.283185307179586
 			}
 
-			testContext.evaluator.AddConst(ciphertext, -0.25, ciphertext)
+			eval.AddConst(ciphertext, -0.25, ciphertext)
 
 			//fmt.Println(ciphertext.Level(), ciphertext.Scale())
 			//start := time.Now()
-			ciphertext = testContext.evaluator.EvaluateChebySpecial(ciphertext, scFac, cheby, testContext.rlk)
+			ciphertext = eval.EvaluateChebySpecial(ciphertext, scFac, cheby, testContext.rlk)
 			//fmt.Println(ciphertext.Level(), ciphertext.Scale())
 
 			for i := 0; i < scNum; i++ {
 				sqrt2pi *= sqrt2pi
-				testContext.evaluator.MulRelin(ciphertext, ciphertext, testContext.rlk, ciphertext)
-				testContext.evaluator.Add(ciphertext, ciphertext, ciphertext)
-				testContext.evaluator.AddConst(ciphertext, -sqrt2pi, ciphertext)
-				testContext.evaluator.Rescale(ciphertext, eval.(*evaluator).scale, ciphertext)
+				eval.MulRelin(ciphertext, ciphertext, testContext.rlk, ciphertext)
+				eval.Add(ciphertext, ciphertext, ciphertext)
+				eval.AddConst(ciphertext, -sqrt2pi, ciphertext)
+				eval.Rescale(ciphertext, eval.ScalePolicy().RescaleThreshold, ciphertext)
 			}
 
 			//fmt.Printf("Elapsed : %s \n", time.Since(start))
@@ -200,13 +211,47 @@ func TestBootstrapp(t *testing.T) {
 			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 
 			testContext.params.scale = DefaultScale
-			eval.(*evaluator).scale = DefaultScale
 
 		})
 
+		t.Run(testString(testContext, "Bootstrapp/EncodePVec/"), func(t *testing.T) {
+
+			// The P part of a diagonal has the moduli of P, whatever the level of its Q part
+			if testContext.params.PiCount() < 2 {
+				t.Skip("#Pi < 2")
+			}
+
+			btp := &Bootstrapper{params: testContext.params, dslots: slots, encoder: testContext.encoder, evaluator: testContext.evaluator}
+
+			diag := make([]complex128, slots)
+			for i := range diag {
+				diag[i] = 1.0 / 1024
+			}
+
+			pVec := &dftvectors{N1: 1}
+			btp.encodePVec(map[uint64][]complex128{0: diag}, pVec, 0, float64(testContext.params.qi[0]))
+
+			plaintextQ, plaintextP := pVec.Vec[0][0], pVec.Vec[0][1]
+			assert.Equal(t, 1, len(plaintextQ.Coeffs))
+			assert.Equal(t, int(testContext.params.PiCount()), len(plaintextP.Coeffs))
+
+			// A constant diagonal is encoded on the constant coefficient only, which is the same integer in Q and P
+			ringQ, ringP := testContext.ringQ, testContext.ringP
+			ringQ.InvNTTLvl(0, plaintextQ, plaintextQ)
+			ringP.InvNTT(plaintextP, plaintextP)
+
+			c := uint64(math.Round(float64(testContext.params.qi[0]) / 1024))
+			assert.Equal(t, c, ring.InvMForm(plaintextQ.Coeffs[0][0], ringQ.Modulus[0], ringQ.MredParams[0]))
+			for i := range plaintextP.Coeffs {
+				assert.Equal(t, c, ring.InvMForm(plaintextP.Coeffs[i][0], ringP.Modulus[i], ringP.MredParams[i]))
+			}
+		})
+
+		var btpKey *BootstrappingKey
+
 		t.Run(testString(testContext, "Bootstrapp/"), func(t *testing.T) {
 
-			btpKey := testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
 			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
 			if err != nil {
 				panic(err)
@@ -233,12 +278,671 @@ func TestBootstrapp(t *testing.T) {
 
 				ciphertext = btp.Bootstrapp(ciphertext)
 
-				//testContext.evaluator.SetScale(ciphertext, testContext.params.scale)
+				assert.Equal(t, testContext.params.scale, ciphertext.Scale())
 
 				verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 			}
 
 		})
+
+		t.Run(testString(testContext, "Bootstrapp/Diagnostics/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			ciphertext, diag, err := btp.BootstrappWithDiagnostics(ciphertext, testContext.decryptor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(diag.Stages) != 4 || diag.Stages[3].Stage != StageSlotsToCoeffs {
+				t.Fatalf("invalid stages: %v", diag.Stages)
+			}
+
+			if diag.InputRange > float64(btpParams.SinRange) {
+				t.Errorf("input range %f larger than %d", diag.InputRange, btpParams.SinRange)
+			}
+
+			for _, report := range diag.Stages[1:] {
+				if real(report.Precision.MeanPrecision) < minPrec || imag(report.Precision.MeanPrecision) < minPrec {
+					t.Errorf("%s: mean precision %v", report.Stage, report.Precision.MeanPrecision)
+				}
+			}
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+			// A sine approximation over a too small range fails
+			btp.SinRange = 1
+
+			_, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			if _, _, err = btp.BootstrappWithDiagnostics(ciphertext, testContext.decryptor); err == nil {
+				t.Errorf("no failure reported for SinRange = 1")
+			}
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Marshal/"), func(t *testing.T) {
+
+			// The full key and its encoding do not fit together in the memory available to the short tests, which
+			// round-trip a key with all the sections but a few rotation keys. The bootstrapping from an encoded key is
+			// then checked by Bootstrapp/Stream/.
+			if testing.Short() {
+
+				rotKey := NewRotationKeys()
+				testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 1, rotKey)
+				testContext.kgen.GenRotationKey(Conjugate, testContext.sk, 0, rotKey)
+
+				smallKey := &BootstrappingKey{relinkey: testContext.kgen.GenRelinKey(testContext.sk), rotkeys: rotKey}
+				smallKey.swkDenseToSparse, smallKey.swkSparseToDense = testContext.kgen.GenSparseEncapsulationKeys(testContext.kgen.GenSecretKey(), testContext.sk)
+
+				dataKey, err := smallKey.MarshalBinary()
+				assert.Nil(t, err)
+				assert.Equal(t, smallKey.GetDataLen(true), uint64(len(dataKey)))
+
+				smallKeyNew := new(BootstrappingKey)
+				assert.Nil(t, smallKeyNew.UnmarshalBinary(dataKey))
+				assert.Nil(t, smallKeyNew.Validate(testContext.params))
+
+				dataKeyNew, err := smallKeyNew.MarshalBinary()
+				assert.Nil(t, err)
+				assert.True(t, bytes.Equal(dataKey, dataKeyNew))
+
+				paramsOther := testContext.params.Copy()
+				paramsOther.logN--
+				paramsOther.logSlots--
+				assert.NotNil(t, smallKeyNew.Validate(paramsOther))
+
+				return
+			}
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			dataParams, err := btpParams.MarshalBinary()
+			assert.Nil(t, err)
+
+			dataKey, err := btpKey.MarshalBinary()
+			assert.Nil(t, err)
+			assert.Equal(t, btpKey.GetDataLen(true), uint64(len(dataKey)))
+
+			btpParamsNew := new(BootstrappParams)
+			assert.Nil(t, btpParamsNew.UnmarshalBinary(dataParams))
+
+			// The decoded key replaces the original one for the next tests, so that both are not held in memory
+			btpKey = nil
+			runtime.GC()
+
+			btpKeyNew := new(BootstrappingKey)
+			assert.Nil(t, btpKeyNew.UnmarshalBinary(dataKey))
+			assert.Nil(t, btpKeyNew.Validate(testContext.params))
+
+			btpKey, dataKey = btpKeyNew, nil
+
+			btp, err := NewBootstrapper(testContext.params, btpParamsNew, btpKeyNew)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			verifyTestVectors(testContext, testContext.decryptor, values, btp.Bootstrapp(ciphertext), t)
+
+			// Keys of another ring degree are rejected
+			paramsOther := testContext.params.Copy()
+			paramsOther.logN--
+			paramsOther.logSlots--
+			assert.NotNil(t, btpKeyNew.Validate(paramsOther))
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Stream/"), func(t *testing.T) {
+
+			// The keys are written one at a time and are not held in memory by the writer
+			var fullLen countingWriter
+			assert.Nil(t, testContext.kgen.GenBootstrappingKeyStream(&fullLen, testContext.params.logSlots, btpParams, testContext.sk, false))
+
+			f, err := ioutil.TempFile("", "ckks-btpkey")
+			assert.Nil(t, err)
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			w := bufio.NewWriter(f)
+			assert.Nil(t, testContext.kgen.GenBootstrappingKeyStream(w, testContext.params.logSlots, btpParams, testContext.sk, true))
+			assert.Nil(t, w.Flush())
+
+			// The seeded keys do not store their uniform elements
+			seededLen, err := f.Seek(0, io.SeekCurrent)
+			assert.Nil(t, err)
+			assert.Less(t, uint64(seededLen), uint64(fullLen/2+fullLen/16))
+
+			_, err = ReadBootstrappingKeyStream(testContext.params, io.NewSectionReader(f, 0, 1<<10))
+			assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+			// The key read from the stream does not fit in memory next to the key of the previous tests, which is
+			// generated again by the next tests
+			btpKey = nil
+			runtime.GC()
+
+			btp, err := ReadBootstrapper(testContext.params, btpParams, bufio.NewReader(io.NewSectionReader(f, 0, seededLen)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			verifyTestVectors(testContext, testContext.decryptor, values, btp.Bootstrapp(ciphertext), t)
+
+			// The keys are read lazily from a stream with random access
+			btp = nil
+			runtime.GC()
+
+			_, err = ReadBootstrapperAt(testContext.params, btpParams, io.NewSectionReader(f, 0, seededLen-1))
+			assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+			if btp, err = ReadBootstrapperAt(testContext.params, btpParams, f); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, 0, len(btp.rotkeys.stream.keys))
+
+			values, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			verifyTestVectors(testContext, testContext.decryptor, values, btp.Bootstrapp(ciphertext), t)
+
+			assert.Equal(t, len(btp.rotkeys.stream.offsets), len(btp.rotkeys.stream.keys))
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/SparseEncapsulation/"), func(t *testing.T) {
+
+			// The sparse secret of the test context is only used around the ModRaise, the keys of the other steps are
+			// generated for the dense secret, next to which the key of the previous tests does not fit in memory
+			skDense := testContext.kgen.GenSecretKey()
+
+			btpKey = nil
+			runtime.GC()
+
+			btpKeyEncapsulated := testContext.kgen.GenBootstrappingKeyEncapsulated(testContext.params.logSlots, btpParams, skDense, testContext.sk)
+
+			// Only the switching key to the sparse secret is limited to level 0
+			assert.Equal(t, 1, len(btpKeyEncapsulated.swkDenseToSparse.evakey[0][0].Coeffs)-int(testContext.params.PiCount()))
+			assert.Equal(t, int(testContext.params.QPiCount()), len(btpKeyEncapsulated.swkSparseToDense.evakey[0][0].Coeffs))
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKeyEncapsulated)
+			if err != nil {
+				panic(err)
+			}
+
+			values := make([]complex128, slots)
+			for i := range values {
+				values[i] = complex(randomFloat(-1, 1), randomFloat(-1, 1))
+			}
+
+			plaintext := NewPlaintext(testContext.params, testContext.params.MaxLevel(), testContext.params.scale)
+			testContext.encoder.Encode(plaintext, values, slots)
+
+			ciphertext := NewEncryptorFromSk(testContext.params, skDense).EncryptNew(plaintext)
+
+			ciphertext = btp.Bootstrapp(ciphertext)
+
+			decryptorDense := NewDecryptor(testContext.params, skDense)
+
+			verifyTestVectors(testContext, decryptorDense, values, ciphertext, t)
+
+			encryptorDense := NewEncryptorFromSk(testContext.params, skDense)
+			values0, _, ciphertext0 := newTestVectors(testContext, encryptorDense, complex(-1, 0), complex(1, 0), t)
+			values1, _, ciphertext1 := newTestVectors(testContext, encryptorDense, complex(-1, 0), complex(1, 0), t)
+
+			ciphertext0, ciphertext1 = btp.BootstrappReal(ciphertext0, ciphertext1)
+
+			verifyTestVectors(testContext, decryptorDense, values0, ciphertext0, t)
+			verifyTestVectors(testContext, decryptorDense, values1, ciphertext1, t)
+
+			if testContext.params.logSlots == testContext.params.MaxLogSlots() {
+				values, _, ciphertext = newTestVectors(testContext, encryptorDense, complex(-1, -1), complex(1, 1), t)
+				verifyTestVectors(testContext, decryptorDense, values, btp.BootstrappSlim(ciphertext), t)
+			}
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Level/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			assert.Panics(t, func() { btp.BootstrappLvl(btp.OutputLevel()+1, nil) })
+
+			for _, level := range []uint64{1, btp.OutputLevel()} {
+
+				values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+				ciphertext = btp.BootstrappLvl(level, ciphertext)
+
+				assert.Equal(t, level, ciphertext.Level())
+
+				verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+			}
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Scale/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			// The values are reinterpreted at a smaller scale, and keep this scale through the bootstrapping
+			ciphertext.DivScale(16)
+			for i := range values {
+				values[i] *= 16
+			}
+
+			ciphertext = btp.BootstrappLvl(btp.OutputLevel()-1, ciphertext)
+
+			assert.Equal(t, testContext.params.scale/16, ciphertext.Scale())
+			assert.Equal(t, btp.OutputLevel()-1, ciphertext.Level())
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+			// A partial bootstrapping at another scale re-encodes the matrices of SlotsToCoeffs for its level only
+			values, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+			ciphertext.DivScale(8)
+			for i := range values {
+				values[i] *= 8
+			}
+
+			ciphertext = btp.BootstrappLvl(0, ciphertext)
+
+			assert.Equal(t, testContext.params.scale/8, ciphertext.Scale())
+			assert.Equal(t, uint64(0), ciphertext.Level())
+			assert.Equal(t, 1, len(btp.pDFTCache.lvl))
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+			// An integer ratio is applied exactly, without consuming a level. The input of the bootstrapping cannot be at
+			// a larger scale than the default scale with those parameters, hence the correction is checked on its own.
+			values, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+			level := ciphertext.Level()
+
+			btp.rescaleTo(ciphertext, testContext.params.scale*4)
+
+			assert.Equal(t, testContext.params.scale*4, ciphertext.Scale())
+			assert.Equal(t, level, ciphertext.Level())
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Real/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			values0, _, ciphertext0 := newTestVectors(testContext, testContext.encryptorPk, complex(-1, 0), complex(1, 0), t)
+			values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorPk, complex(-1, 0), complex(1, 0), t)
+
+			ciphertext0, ciphertext1 = btp.BootstrappReal(ciphertext0, ciphertext1)
+
+			// Half the default scale is not a multiple of it, hence the outputs are rescaled once to the input scale
+			assert.Equal(t, testContext.params.scale, ciphertext0.Scale())
+			assert.Equal(t, testContext.params.scale, ciphertext1.Scale())
+			assert.Equal(t, btp.OutputLevel()-1, ciphertext0.Level())
+			assert.Equal(t, btp.OutputLevel()-1, ciphertext1.Level())
+
+			verifyTestVectors(testContext, testContext.decryptor, values0, ciphertext0, t)
+			verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext1, t)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Iterative/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			precOnePass := GetPrecisionStats(testContext.params, testContext.encoder, testContext.decryptor, values, btp.Bootstrapp(ciphertext.CopyNew().Ciphertext()))
+
+			logAmplification := uint64(math.Min(real(precOnePass.MinPrecision), imag(precOnePass.MinPrecision))) - 4
+
+			ciphertext = btp.BootstrappIterative(ciphertext, logAmplification)
+
+			assert.Equal(t, btp.OutputLevel(), ciphertext.Level())
+
+			precTwoPasses := GetPrecisionStats(testContext.params, testContext.encoder, testContext.decryptor, values, ciphertext)
+
+			if *printPrecisionStats {
+				t.Log(precOnePass.String())
+				t.Log(precTwoPasses.String())
+			}
+
+			assert.Greater(t, real(precTwoPasses.MeanPrecision), real(precOnePass.MeanPrecision)+4)
+			assert.Greater(t, imag(precTwoPasses.MeanPrecision), imag(precOnePass.MeanPrecision)+4)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Slim/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-0.25, -0.25), complex(0.25, 0.25), t)
+
+			policy := btp.evaluator.ScalePolicy()
+
+			// The values are reinterpreted at a smaller scale than the default scale
+			ciphertext.DivScale(4)
+			for i := range values {
+				values[i] *= 4
+			}
+
+			ciphertext = btp.BootstrappSlim(ciphertext)
+
+			// The scale of the input does not leak into the evaluator of the Bootstrapper
+			assert.Equal(t, policy, btp.evaluator.ScalePolicy())
+
+			assert.Equal(t, btpParams.StCLevel[0], ciphertext.Level())
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+			// A ciphertext bootstrapped with the slim bootstrapping can be bootstrapped again
+			ciphertext = btp.BootstrappSlim(ciphertext)
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Context/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+			stages := []BootstrappStage{}
+			progress := func(stage BootstrappStage, level uint64, scale float64) {
+				stages = append(stages, stage)
+			}
+
+			ciphertext, err = btp.BootstrappWithContext(context.Background(), ciphertext, progress)
+			assert.NoError(t, err)
+			assert.Equal(t, []BootstrappStage{StageModRaise, StageCoeffsToSlots, StageEvalMod, StageSlotsToCoeffs}, stages)
+
+			verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+			// Cancelled before the bootstrapping
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+			stages = stages[:0]
+			ciphertext, err = btp.BootstrappWithContext(ctx, ciphertext, progress)
+			assert.Nil(t, ciphertext)
+			assert.Equal(t, context.Canceled, err)
+			assert.Empty(t, stages)
+
+			// Cancelled during the bootstrapping
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			_, _, ciphertext = newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+			stages = stages[:0]
+			ciphertext, err = btp.BootstrappWithContext(ctx, ciphertext, func(stage BootstrappStage, level uint64, scale float64) {
+				progress(stage, level, scale)
+				if stage == StageModRaise {
+					cancel()
+				}
+			})
+			assert.Nil(t, ciphertext)
+			assert.Equal(t, context.Canceled, err)
+			assert.Equal(t, []BootstrappStage{StageModRaise}, stages)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/SharedRotationKeys/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			// Rotation keys of the application, sharing all the bootstrapping keys but one
+			rotKey := NewRotationKeys()
+			rotKey.evakeyConjugate = btpKey.rotkeys.evakeyConjugate
+			rotKey.permuteNTTConjugateIndex = btpKey.rotkeys.permuteNTTConjugateIndex
+			rotKey.evakeyRotColLeft = make(map[uint64]*SwitchingKey)
+			rotKey.permuteNTTLeftIndex = make(map[uint64][]uint64)
+			rotKey.permuteNTTRightIndex = make(map[uint64][]uint64)
+
+			var missing uint64
+			for k, swk := range btpKey.rotkeys.evakeyRotColLeft {
+				if missing == 0 {
+					missing = k
+					continue
+				}
+				rotKey.evakeyRotColLeft[k] = swk
+				rotKey.permuteNTTLeftIndex[k] = btpKey.rotkeys.permuteNTTLeftIndex[k]
+				rotKey.permuteNTTRightIndex[k] = btpKey.rotkeys.permuteNTTRightIndex[k]
+			}
+
+			// and one key generated for a lower level, which must be replaced
+			var lowered uint64
+			for k := range rotKey.evakeyRotColLeft {
+				lowered = k
+				break
+			}
+			delete(rotKey.evakeyRotColLeft, lowered)
+			testContext.kgen.GenRotationKeyLvl(testContext.params.MaxLevel()-1, RotationLeft, testContext.sk, lowered, rotKey)
+
+			_, err := NewBootstrapper(testContext.params, btpParams, NewBootstrappingKey(btpKey.relinkey, rotKey))
+			assert.NotNil(t, err)
+
+			testContext.kgen.GenBootstrappingRotationKeys(testContext.params.logSlots, btpParams, testContext.sk, rotKey)
+
+			// Only the missing and the lowered keys are generated
+			assert.True(t, rotKey.evakeyConjugate == btpKey.rotkeys.evakeyConjugate)
+			assert.Equal(t, len(btpKey.rotkeys.evakeyRotColLeft), len(rotKey.evakeyRotColLeft))
+			for k, swk := range rotKey.evakeyRotColLeft {
+				assert.Equal(t, k == missing || k == lowered, swk != btpKey.rotkeys.evakeyRotColLeft[k])
+				assert.Equal(t, int(testContext.params.QPiCount()), len(swk.evakey[0][0].Coeffs))
+			}
+
+			btpKeyShared := NewBootstrappingKey(btpKey.relinkey, rotKey)
+			assert.True(t, btpKeyShared.RotationKeys() == rotKey)
+			assert.True(t, btpKeyShared.RelinKey() == btpKey.relinkey)
+
+			_, err = NewBootstrapper(testContext.params, btpParams, btpKeyShared)
+			assert.Nil(t, err)
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/ShallowCopy/"), func(t *testing.T) {
+
+			if btpKey == nil {
+				btpKey = testContext.kgen.GenBootstrappingKey(testContext.params.logSlots, btpParams, testContext.sk)
+			}
+
+			btp, err := NewBootstrapper(testContext.params, btpParams, btpKey)
+			if err != nil {
+				panic(err)
+			}
+
+			// The copies keep the backend, the worker pool and the scale policy of the evaluator of the receiver
+			backend := &innerProductCountingBackend{}
+			pool := ring.NewWorkerPool(2)
+			defer pool.Close()
+
+			btp.SetBackend(backend)
+			btp.SetWorkerPool(pool)
+			btp.evaluator.(*evaluator).threshold = btp.params.scale / 2
+
+			evalCopy := btp.ShallowCopy().evaluator.(*evaluator)
+			assert.True(t, evalCopy.ringQ.Backend() == backend && evalCopy.ringP.Backend() == backend)
+			assert.True(t, evalCopy.ringQ.WorkerPool() == pool && evalCopy.ringP.WorkerPool() == pool)
+			assert.Equal(t, btp.evaluator.ScalePolicy(), evalCopy.ScalePolicy())
+
+			btp.SetBackend(nil)
+			btp.evaluator.(*evaluator).threshold = 0
+
+			bootstrappers := []*Bootstrapper{btp, btp.ShallowCopy()}
+
+			values := make([][]complex128, len(bootstrappers))
+			ciphertexts := make([]*Ciphertext, len(bootstrappers))
+
+			for i := range bootstrappers {
+
+				values[i] = make([]complex128, slots)
+				for j := range values[i] {
+					values[i][j] = complex(randomFloat(-1, 1), randomFloat(-1, 1))
+				}
+
+				plaintext := NewPlaintext(testContext.params, testContext.params.MaxLevel(), testContext.params.scale)
+				testContext.encoder.Encode(plaintext, values[i], slots)
+				ciphertexts[i] = testContext.encryptorPk.EncryptNew(plaintext)
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(len(bootstrappers))
+			for i := range bootstrappers {
+				go func(i int) {
+					ciphertexts[i] = bootstrappers[i].Bootstrapp(ciphertexts[i])
+					wg.Done()
+				}(i)
+			}
+			wg.Wait()
+
+			for i := range bootstrappers {
+				verifyTestVectors(testContext, testContext.decryptor, values[i], ciphertexts[i], t)
+			}
+		})
+
+		t.Run(testString(testContext, "Bootstrapp/Scale/SharedModulus/"), func(t *testing.T) {
+
+			// In the parameters of Set V, the first two matrices of SlotsToCoeffs share a modulus twice as large as their
+			// scale and the last one has its own, which the rescalings of SlotsToCoeffs cannot remove
+			btpKey = nil
+			runtime.GC()
+
+			params := DefaultBootstrappSchemeParams[1].Copy()
+			params.logN = testContext.params.logN
+			params.logSlots = testContext.params.logSlots
+
+			testContextV, err := genTestParams(params, DefaultBootstrappParams[1].H)
+			if err != nil {
+				panic(err)
+			}
+
+			btpV, err := NewBootstrapper(testContextV.params, DefaultBootstrappParams[1], testContextV.kgen.GenBootstrappingKey(params.logSlots, DefaultBootstrappParams[1], testContextV.sk))
+			if err != nil {
+				panic(err)
+			}
+
+			factor := btpV.dftScaleFactor(btpV.pDFT)
+			assert.False(t, factor > 0.5 && factor < 2)
+
+			values := make([]complex128, slots)
+			for i := range values {
+				values[i] = complex(randomFloat(-1, 1), randomFloat(-1, 1))
+			}
+
+			plaintext := NewPlaintext(testContextV.params, testContextV.params.MaxLevel(), testContextV.params.scale)
+			testContextV.encoder.Encode(plaintext, values, slots)
+			ciphertext := btpV.Bootstrapp(testContextV.encryptorSk.EncryptNew(plaintext))
+
+			assert.Equal(t, btpV.OutputLevel(), ciphertext.Level())
+			assert.Equal(t, testContextV.params.scale, ciphertext.Scale())
+
+			verifyTestVectors(testContextV, testContextV.decryptor, values, ciphertext, t)
+		})
+	}
+}
+
+// countingWriter is an io.Writer counting the bytes written on it.
+type countingWriter uint64
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+func TestBootstrappParamsMarshal(t *testing.T) {
+
+	for i, btpParams := range DefaultBootstrappParams {
+
+		params := DefaultBootstrappSchemeParams[i]
+
+		assert.Nil(t, btpParams.Validate(params))
+
+		t.Run("Binary", func(t *testing.T) {
+			data, err := btpParams.MarshalBinary()
+			assert.Nil(t, err)
+			btpParamsNew := new(BootstrappParams)
+			assert.Nil(t, btpParamsNew.UnmarshalBinary(data))
+			assert.Equal(t, btpParams, btpParamsNew)
+			assert.NotNil(t, btpParamsNew.UnmarshalBinary(data[:len(data)-1]))
+		})
+
+		t.Run("JSON", func(t *testing.T) {
+			data, err := json.Marshal(btpParams)
+			assert.Nil(t, err)
+			btpParamsNew := new(BootstrappParams)
+			assert.Nil(t, json.Unmarshal(data, btpParamsNew))
+			assert.Equal(t, btpParams, btpParamsNew)
+		})
+
+		t.Run("Validate", func(t *testing.T) {
+
+			invalid := btpParams.Copy()
+			invalid.SinType = Cos2 + 1
+			assert.NotNil(t, invalid.Validate(params))
+
+			invalid = btpParams.Copy()
+			invalid.CtSLevel[0]--
+			assert.NotNil(t, invalid.Validate(params))
+
+			invalid = btpParams.Copy()
+			invalid.StCLevel[0] = invalid.CtSLevel[len(invalid.CtSLevel)-1]
+			assert.NotNil(t, invalid.Validate(params))
+
+			invalid = btpParams.Copy()
+			invalid.H = params.N() + 1
+			assert.NotNil(t, invalid.Validate(params))
+		})
 	}
 }
 
@@ -262,3 +966,101 @@ func newTestVectorsSineBootstrapp(testContext *testParams, encryptor Encryptor,
 
 	return values, plaintext, ciphertext
 }
+
+func TestArcSineTaylor(t *testing.T) {
+
+	t.Run("Correction", func(t *testing.T) {
+
+		arcsine := arcSineTaylor(7)
+
+		for _, x := range []float64{-0.05, -0.01, 0.001, 0.02, 0.05} {
+
+			y := real(sin2pi2pi(complex(x, 0)))
+
+			var res float64
+			for i := len(arcsine.coeffs) - 1; i >= 0; i-- {
+				res = res*y + real(arcsine.coeffs[i])
+			}
+
+			if math.Abs(res-x) > math.Abs(y-x)/100 {
+				t.Errorf("arcsine correction of %f: got %f, sine error %e", x, res, math.Abs(y-x))
+			}
+		}
+	})
+
+	t.Run("InvalidDegree", func(t *testing.T) {
+
+		btpParams := DefaultBootstrappParams[0].Copy()
+
+		for _, deg := range []uint64{1, 4} {
+			btpParams.ArcSineDeg = deg
+			if _, err := NewBootstrapper(DefaultBootstrappSchemeParams[0], btpParams, nil); err == nil {
+				t.Errorf("ArcSineDeg = %d should be rejected", deg)
+			}
+		}
+	})
+}
+
+func TestSearchBootstrappParams(t *testing.T) {
+
+	t.Run("Search", func(t *testing.T) {
+
+		for _, depth := range []uint64{4, 10} {
+
+			params, btpParams, precision, err := SearchBootstrappParams(16, 15, depth, 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Nil(t, btpParams.Validate(params))
+			assert.Equal(t, depth, btpParams.StCLevel[len(btpParams.StCLevel)-1]-1)
+			assert.Equal(t, uint64(15), params.LogSlots())
+			assert.GreaterOrEqual(t, precision, 20.0)
+			assert.GreaterOrEqual(t, params.SecurityLevel(), MinSecurityLevel)
+		}
+	})
+
+	t.Run("Bootstrapp", func(t *testing.T) {
+
+		// Few slots for few rotation keys, which fit in memory at the ring degree of the search
+		logSlots := uint64(4)
+
+		params, btpParams, precision, err := SearchBootstrappParams(15, logSlots, 2, 15)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testContext, err := genTestParams(params, btpParams.H)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		btp, err := NewBootstrapper(testContext.params, btpParams, testContext.kgen.GenBootstrappingKey(logSlots, btpParams, testContext.sk))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+		precStats := GetPrecisionStats(testContext.params, testContext.encoder, testContext.decryptor, values, btp.Bootstrapp(ciphertext))
+
+		if *printPrecisionStats {
+			t.Logf("predicted precision: %.2f bits\n%s", precision, precStats.String())
+		}
+
+		// The prediction is a slightly pessimistic estimate of the mean precision of the real and imaginary parts
+		for _, measured := range []float64{real(precStats.MeanPrecision), imag(precStats.MeanPrecision)} {
+			assert.GreaterOrEqual(t, measured, precision-1)
+			assert.LessOrEqual(t, measured, precision+3)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+
+		_, _, _, err := SearchBootstrappParams(16, 15, 10, 40)
+		assert.NotNil(t, err)
+
+		_, _, _, err = SearchBootstrappParams(16, 16, 10, 20)
+		assert.NotNil(t, err)
+	})
+}
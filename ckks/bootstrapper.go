@@ -5,6 +5,7 @@ import (
 	"log"
 	"math"
 	"math/cmplx"
+	"sync"
 
 	"github.com/ldsec/lattigo/v2/ckks/bettersine"
 	"github.com/ldsec/lattigo/v2/ring"
@@ -30,22 +31,34 @@ type Bootstrapper struct {
 	prescale    float64                 // Q[0]/1024
 	postscale   float64                 // Qi sineeval/2^{10}
 	chebycoeffs *ChebyshevInterpolation // Coefficients of the Chebyshev Interpolation of sin(2*pi*x) or cos(2*pi*x/r)
+	arcsine     *Poly                   // Coefficients of the Taylor series of arcsin(2*pi*x)/(2*pi), nil if disabled
 
 	coeffsToSlotsDiffScale complex128    // Matrice rescaling
 	slotsToCoeffsDiffScale complex128    // Matrice rescaling
 	pDFT                   []*dftvectors // Matrice vectors
 	pDFTInv                []*dftvectors // Matrice vectors
 
+	pDFTCache *dftCache // SlotsToCoeffs matrice vectors of the partial and slim bootstrappings, shared by the shallow copies
+
 	rotKeyIndex []uint64 // a list of the required rotation keys
 
 	ctxpool *Ciphertext // Memory pool
 
 	decryptor Decryptor
 
+	logger utils.Logger
+
 	poolQ [1]*ring.Poly // Memory pool for the matrix evaluation
 	poolP [2]*ring.Poly // Memory pool for the matrix evaluation
 }
 
+// dftCache stores the SlotsToCoeffs matrice vectors encoded on demand.
+type dftCache struct {
+	sync.Mutex
+	lvl  map[uint64][]*dftvectors // Partial bootstrappings, indexed by output level
+	slim []*dftvectors            // Slim bootstrapping
+}
+
 type dftvectors struct {
 	N1    uint64
 	Level uint64
@@ -75,12 +88,12 @@ func (btp *Bootstrapper) printDebug(message string, ciphertext *Ciphertext) {
 // NewBootstrapper creates a new Bootstrapper.
 func NewBootstrapper(params *Parameters, btpParams *BootstrappParams, btpKey *BootstrappingKey) (btp *Bootstrapper, err error) {
 
-	if btpParams.SinType == SinType(Sin) && btpParams.SinRescal != 0 {
-		return nil, fmt.Errorf("BootstrappParams: cannot use double angle formul for SinType = Sin -> must use SinType = Cos")
+	if err = btpParams.Validate(params); err != nil {
+		return nil, err
 	}
 
-	if btpParams.CtSLevel[0] != params.MaxLevel() {
-		return nil, fmt.Errorf("BootstrappParams: CtSLevel start not consistent with MaxLevel")
+	if err = btpKey.Validate(params); err != nil {
+		return nil, err
 	}
 
 	btp = newBootstrapper(params, btpParams)
@@ -113,10 +126,13 @@ func newBootstrapper(params *Parameters, btpParams *BootstrappParams) (btp *Boot
 
 	btp.encoder = NewEncoder(params)
 	btp.evaluator = NewEvaluator(params)
+	btp.logger = utils.NopLogger{}
 
 	btp.genSinePoly()
 	btp.genDFTMatrices()
 
+	btp.pDFTCache = &dftCache{lvl: make(map[uint64][]*dftvectors)}
+
 	btp.ctxpool = NewCiphertext(params, 1, params.MaxLevel(), 0)
 
 	for i := range btp.poolQ {
@@ -129,6 +145,63 @@ func newBootstrapper(params *Parameters, btpParams *BootstrappParams) (btp *Boot
 	return btp
 }
 
+// ShallowCopy creates a shallow copy of this Bootstrapper in which the plaintext matrices, the polynomial approximation
+// and the keys are shared with the receiver, and the evaluator and the memory pools are reallocated. The evaluator of
+// the copy has the same scale policy, ring.Backend, ring.WorkerPool and Logger as the one of the receiver. The returned
+// Bootstrapper can bootstrap concurrently with the receiver and its other shallow copies, so that a single set of
+// matrices and keys serves one Bootstrapper per goroutine.
+func (btp *Bootstrapper) ShallowCopy() *Bootstrapper {
+
+	btpCopy := *btp
+
+	eval := btp.evaluator.(*evaluator)
+
+	evalCopy := NewEvaluator(btp.params).(*evaluator)
+	evalCopy.scale = eval.scale
+	evalCopy.threshold = eval.threshold
+	evalCopy.SetBackend(eval.ringQ.Backend())
+	evalCopy.SetWorkerPool(eval.ringQ.WorkerPool())
+	evalCopy.SetLogger(btp.logger)
+
+	btpCopy.encoder = NewEncoder(btp.params)
+	btpCopy.evaluator = evalCopy
+
+	btpCopy.ctxpool = NewCiphertext(btp.params, 1, btp.params.MaxLevel(), 0)
+
+	for i := range btpCopy.poolQ {
+		btpCopy.poolQ[i] = btp.params.NewPolyQ()
+	}
+
+	for i := range btpCopy.poolP {
+		btpCopy.poolP[i] = btp.params.NewPolyP()
+	}
+
+	return &btpCopy
+}
+
+// SetLogger sets the Logger on which the Bootstrapper and its Evaluator report their events: the level and the scale
+// of the ciphertext after each stage of the bootstrapping at the level LogDebug, and the inputs whose scale is too
+// large to be bootstrapped at the level LogWarn. A nil logger restores the default NopLogger.
+func (btp *Bootstrapper) SetLogger(logger utils.Logger) {
+	if logger == nil {
+		logger = utils.NopLogger{}
+	}
+	btp.logger = logger
+	btp.evaluator.SetLogger(logger)
+}
+
+// SetBackend sets the ring.Backend on which the Evaluator of the Bootstrapper computes its NTTs, coefficient-wise
+// Montgomery products and key-switching inner products. A nil backend restores the ring.CPUBackend.
+func (btp *Bootstrapper) SetBackend(backend ring.Backend) {
+	btp.evaluator.SetBackend(backend)
+}
+
+// SetWorkerPool sets the ring.WorkerPool on which the Evaluator of the Bootstrapper parallelizes its NTTs across the
+// moduli. A nil pool restores the sequential computation.
+func (btp *Bootstrapper) SetWorkerPool(pool *ring.WorkerPool) {
+	btp.evaluator.SetWorkerPool(pool)
+}
+
 // CheckKeys checks if all the necessary keys are present
 func (btp *Bootstrapper) CheckKeys() (err error) {
 
@@ -136,13 +209,17 @@ func (btp *Bootstrapper) CheckKeys() (err error) {
 		return fmt.Errorf("empty relinkkey and/or rotkeys")
 	}
 
-	if btp.rotkeys.evakeyConjugate == nil {
+	if (btp.swkDenseToSparse == nil) != (btp.swkSparseToDense == nil) {
+		return fmt.Errorf("incomplete sparse secret encapsulation keys")
+	}
+
+	if !btp.rotkeys.hasConjugationKey() {
 		return fmt.Errorf("missing conjugate key")
 	}
 
 	rotMissing := []uint64{}
 	for _, i := range btp.rotKeyIndex {
-		if btp.rotkeys.evakeyRotColLeft[i] == nil || btp.rotkeys.permuteNTTLeftIndex[i] == nil {
+		if !btp.rotkeys.hasRotationKey(i) {
 			rotMissing = append(rotMissing, i)
 		}
 	}
@@ -154,6 +231,86 @@ func (btp *Bootstrapper) CheckKeys() (err error) {
 	return nil
 }
 
+// Validate checks that the keys of the BootstrappingKey have the degree, the number of moduli and the decomposition
+// of the scheme parameters params. It must be called on keys loaded with UnmarshalBinary before their use.
+// The presence of the keys required by a Bootstrapper is checked by Bootstrapper.CheckKeys.
+func (btpKey *BootstrappingKey) Validate(params *Parameters) (err error) {
+
+	if btpKey == nil {
+		return fmt.Errorf("nil BootstrappingKey")
+	}
+
+	if btpKey.relinkey != nil {
+		if err = validateSwitchingKey(params, btpKey.relinkey.evakey); err != nil {
+			return fmt.Errorf("relinearization key: %s", err)
+		}
+		for _, swk := range btpKey.relinkey.evakeyPow {
+			if err = validateSwitchingKey(params, swk); err != nil {
+				return fmt.Errorf("relinearization key: %s", err)
+			}
+		}
+	}
+
+	if btpKey.rotkeys != nil {
+		for _, keys := range []map[uint64]*SwitchingKey{btpKey.rotkeys.evakeyRotColLeft, btpKey.rotkeys.evakeyRotColRight, btpKey.rotkeys.evakeyAutomorphism} {
+			for i, swk := range keys {
+				if err = validateSwitchingKey(params, swk); err != nil {
+					return fmt.Errorf("rotation key %d: %s", i, err)
+				}
+			}
+		}
+		if btpKey.rotkeys.evakeyConjugate != nil {
+			if err = validateSwitchingKey(params, btpKey.rotkeys.evakeyConjugate); err != nil {
+				return fmt.Errorf("conjugate key: %s", err)
+			}
+		}
+	}
+
+	for _, swk := range []*SwitchingKey{btpKey.swkDenseToSparse, btpKey.swkSparseToDense} {
+		if swk != nil {
+			if err = validateSwitchingKey(params, swk); err != nil {
+				return fmt.Errorf("sparse secret encapsulation key: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSwitchingKey checks that the decomposition of the switching key swk is at most the one of params and that its
+// polynomials are in the ring QP of params. Keys generated for a lower level have a smaller decomposition and only the
+// moduli Q up to this level, hence all the polynomials must have the same number of moduli, between PiCount+1 and QPiCount.
+func validateSwitchingKey(params *Parameters, swk *SwitchingKey) error {
+
+	if swk == nil || len(swk.evakey) == 0 || uint64(len(swk.evakey)) > params.Beta() {
+		return fmt.Errorf("invalid decomposition (expected at most %d)", params.Beta())
+	}
+
+	var moduli uint64
+	if swk.evakey[0][0] != nil {
+		moduli = uint64(len(swk.evakey[0][0].Coeffs))
+	}
+
+	if moduli <= params.PiCount() || moduli > params.QPiCount() {
+		return fmt.Errorf("invalid number of moduli (expected at most %d)", params.QPiCount())
+	}
+
+	for i := range swk.evakey {
+		for _, pol := range swk.evakey[i] {
+			if pol == nil || uint64(len(pol.Coeffs)) != moduli {
+				return fmt.Errorf("invalid number of moduli (expected %d)", moduli)
+			}
+			for _, coeffs := range pol.Coeffs {
+				if uint64(len(coeffs)) != params.N() {
+					return fmt.Errorf("invalid degree (expected %d)", params.N())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (btp *Bootstrapper) genDFTMatrices() {
 
 	a := real(btp.chebycoeffs.a)
@@ -240,20 +397,31 @@ func (btp *Bootstrapper) genDFTMatrices() {
 
 func (btp *Bootstrapper) genSinePoly() {
 
-	if btp.SinType == Sin {
+	btp.chebycoeffs = btp.BootstrappParams.sineApproximation(btp.deviation)
+
+	if btp.ArcSineDeg != 0 {
+		btp.arcsine = arcSineTaylor(btp.ArcSineDeg)
+	}
+}
+
+// sineApproximation returns the Chebyshev interpolation of sin(2*pi*x) or cos(2*pi*x/r) of the bootstrapping
+// parameters, for inputs scaled down by deviation.
+func (b *BootstrappParams) sineApproximation(deviation float64) (cheby *ChebyshevInterpolation) {
 
-		K := complex(float64(btp.SinRange), 0)
-		btp.chebycoeffs = Approximate(sin2pi2pi, -K, K, int(btp.SinDeg))
+	if b.SinType == Sin {
 
-	} else if btp.SinType == Cos1 {
+		K := complex(float64(b.SinRange), 0)
+		cheby = Approximate(sin2pi2pi, -K, K, int(b.SinDeg))
 
-		K := int(btp.SinRange)
-		deg := int(btp.SinDeg)
-		scFac := complex(float64(int(1<<btp.SinRescal)), 0)
+	} else if b.SinType == Cos1 {
 
-		cheby := new(ChebyshevInterpolation)
+		K := int(b.SinRange)
+		deg := int(b.SinDeg)
+		scFac := complex(float64(int(1<<b.SinRescal)), 0)
 
-		cheby.coeffs = bettersine.Approximate(K, deg, btp.deviation, int(btp.SinRescal))
+		cheby = new(ChebyshevInterpolation)
+
+		cheby.coeffs = bettersine.Approximate(K, deg, deviation, int(b.SinRescal))
 
 		sqrt2pi := math.Pow(0.15915494309189535, 1.0/real(scFac))
 
@@ -266,26 +434,44 @@ func (btp *Bootstrapper) genSinePoly() {
 		cheby.b = complex(float64(K), 0) / scFac
 		cheby.lead = true
 
-		btp.chebycoeffs = cheby
-
-	} else if btp.SinType == Cos2 {
+	} else if b.SinType == Cos2 {
 
-		K := int(btp.SinRange)
-		deg := int(btp.SinDeg)
-		scFac := complex(float64(int(1<<btp.SinRescal)), 0)
+		K := int(b.SinRange)
+		deg := int(b.SinDeg)
+		scFac := complex(float64(int(1<<b.SinRescal)), 0)
 
-		cheby := Approximate(cos2pi, -complex(float64(K), 0)/scFac, complex(float64(K), 0)/scFac, deg)
+		cheby = Approximate(cos2pi, -complex(float64(K), 0)/scFac, complex(float64(K), 0)/scFac, deg)
 		sqrt2pi := math.Pow(0.15915494309189535, 1.0/real(scFac))
 
 		for i := range cheby.coeffs {
 			cheby.coeffs[i] *= complex(sqrt2pi, 0)
 		}
 
-		btp.chebycoeffs = cheby
-
 	} else {
 		panic("Bootstrapper -> invalid sineType")
 	}
+
+	return
+}
+
+// arcSineTaylor returns the Taylor series of degree deg of arcsin(2*pi*x)/(2*pi), which maps the output
+// sin(2*pi*x)/(2*pi) of the sine evaluation back to x. Its odd coefficients are (2*pi)^(2k) * (2k)! / (4^k * (k!)^2 * (2k+1)).
+func arcSineTaylor(deg uint64) *Poly {
+
+	coeffs := make([]complex128, deg+1)
+
+	// c = (2k)! / (4^k * (k!)^2), updated from k to k+1 by the factor (2k+1) / (2k+2)
+	c := 1.0
+	pi2sq := 6.283185307179586 * 6.283185307179586
+	pow := 1.0
+
+	for k := uint64(0); 2*k+1 <= deg; k++ {
+		coeffs[2*k+1] = complex(c*pow/float64(2*k+1), 0)
+		c *= float64(2*k+1) / float64(2*k+2)
+		pow *= pi2sq
+	}
+
+	return NewPoly(coeffs)
 }
 
 func computeRoots(N uint64) (roots []complex128) {
@@ -421,7 +607,7 @@ func (btp *Bootstrapper) computePlaintextVectors() {
 	for i, lvl := range CtSLevel {
 		btp.pDFTInv[i] = new(dftvectors)
 		btp.pDFTInv[i].N1 = findbestbabygiantstepsplit(pVecDFTInv[i], dslots, btp.MaxN1N2Ratio)
-		btp.encodePVec(pVecDFTInv[i], btp.pDFTInv[i], lvl, true)
+		btp.encodePVec(pVecDFTInv[i], btp.pDFTInv[i], lvl, float64(btp.params.qi[lvl]))
 	}
 
 	// SlotsToCoeffs vectors
@@ -430,8 +616,74 @@ func (btp *Bootstrapper) computePlaintextVectors() {
 	for i, lvl := range StCLevel {
 		btp.pDFT[i] = new(dftvectors)
 		btp.pDFT[i].N1 = findbestbabygiantstepsplit(pVecDFT[i], dslots, btp.MaxN1N2Ratio)
-		btp.encodePVec(pVecDFT[i], btp.pDFT[i], lvl, false)
+
+		// If the first moduli
+		scale := float64(btp.params.qi[lvl])
+		if logQi := math.Round(math.Log2(scale)); logQi >= 56.0 {
+			scale = math.Exp2(logQi / 2)
+		}
+
+		btp.encodePVec(pVecDFT[i], btp.pDFT[i], lvl, scale)
+	}
+}
+
+// slotsToCoeffsMatricesLvl returns the SlotsToCoeffs matrice vectors of a partial bootstrapping with output at the given
+// level. They are the ones of the full bootstrapping, encoded one per level from level+len(StCLevel) down to level+1
+// with the modulus of their level as scale, so that each rescaling is exact. They are computed on the first call and
+// shared with the shallow copies of the Bootstrapper, hence at most one set is stored per level.
+func (btp *Bootstrapper) slotsToCoeffsMatricesLvl(level uint64) []*dftvectors {
+
+	btp.pDFTCache.Lock()
+	defer btp.pDFTCache.Unlock()
+
+	if pDFT, ok := btp.pDFTCache.lvl[level]; ok {
+		return pDFT
+	}
+
+	btp.pDFTCache.lvl[level] = btp.encodeSlotsToCoeffsMatricesLvl(level, btp.slotsToCoeffsDiffScale)
+
+	return btp.pDFTCache.lvl[level]
+}
+
+// slotsToCoeffsMatricesSlim returns the SlotsToCoeffs matrice vectors of the slim bootstrapping, which map the slots of
+// the input to the coefficients of the plaintext without changing the scale, from level len(StCLevel) down to level 0.
+// They are computed on the first call and shared with the shallow copies of the Bootstrapper.
+func (btp *Bootstrapper) slotsToCoeffsMatricesSlim() []*dftvectors {
+
+	btp.pDFTCache.Lock()
+	defer btp.pDFTCache.Unlock()
+
+	if btp.pDFTCache.slim == nil {
+		btp.pDFTCache.slim = btp.encodeSlotsToCoeffsMatricesLvl(0, complex(1, 0))
 	}
+
+	return btp.pDFTCache.slim
+}
+
+// encodeSlotsToCoeffsMatricesLvl encodes the SlotsToCoeffs matrice vectors, multiplied by diffScale, one per level from
+// level+len(StCLevel) down to level+1 with the modulus of their level as scale.
+func (btp *Bootstrapper) encodeSlotsToCoeffsMatricesLvl(level uint64, diffScale complex128) (pDFT []*dftvectors) {
+
+	slots := btp.params.Slots()
+
+	roots := computeRoots(slots << 1)
+	pow5 := make([]uint64, (slots<<1)+1)
+	pow5[0] = 1
+	for i := uint64(1); i < (slots<<1)+1; i++ {
+		pow5[i] = pow5[i-1] * 5
+		pow5[i] &= (slots << 2) - 1
+	}
+
+	pDFT = make([]*dftvectors, len(btp.StCLevel))
+	pVecDFT := btp.computeDFTPlaintextVectors(roots, pow5, diffScale, false)
+	for i := range pDFT {
+		lvl := level + uint64(len(pDFT)-i)
+		pDFT[i] = new(dftvectors)
+		pDFT[i].N1 = btp.pDFT[i].N1 // Same split, hence same rotation keys, as the full bootstrapping
+		btp.encodePVec(pVecDFT[i], pDFT[i], lvl, float64(btp.params.qi[lvl]))
+	}
+
+	return
 }
 
 // Finds the best N1*N2 = N for the baby-step giant-step algorithm for matrix multiplication.
@@ -482,9 +734,8 @@ func findbestbabygiantstepsplit(vector map[uint64][]complex128, maxN uint64, max
 	return 1
 }
 
-func (btp *Bootstrapper) encodePVec(pVec map[uint64][]complex128, plaintextVec *dftvectors, level uint64, forward bool) {
+func (btp *Bootstrapper) encodePVec(pVec map[uint64][]complex128, plaintextVec *dftvectors, level uint64, scale float64) {
 	var N, N1 uint64
-	var scale float64
 
 	// N1*N2 = N
 	N = btp.params.N()
@@ -504,18 +755,6 @@ func (btp *Bootstrapper) encodePVec(pVec map[uint64][]complex128, plaintextVec *
 
 	plaintextVec.Vec = make(map[uint64][2]*ring.Poly)
 
-	if forward {
-		scale = float64(btp.params.qi[level])
-	} else {
-		// If the first moduli
-		logQi := math.Round(math.Log2(float64(btp.params.qi[level])))
-		if logQi >= 56.0 {
-			scale = math.Exp2(logQi / 2)
-		} else {
-			scale = float64(btp.params.qi[level])
-		}
-	}
-
 	plaintextVec.Level = level
 	plaintextVec.Scale = scale
 	ringQ := btp.evaluator.(*evaluator).ringQ
@@ -533,13 +772,11 @@ func (btp *Bootstrapper) encodePVec(pVec map[uint64][]complex128, plaintextVec *
 
 			plaintextQ := ring.NewPoly(N, level+1)
 			encoder.scaleUp(plaintextQ, scale, ringQ.Modulus[:level+1])
-			ringQ.NTTLvl(level, plaintextQ, plaintextQ)
-			ringQ.MFormLvl(level, plaintextQ, plaintextQ)
+			ringQ.NTTMFormLvl(level, plaintextQ, plaintextQ)
 
-			plaintextP := ring.NewPoly(N, level+1)
+			plaintextP := ring.NewPoly(N, btp.params.PiCount())
 			encoder.scaleUp(plaintextP, scale, ringP.Modulus)
-			ringP.NTT(plaintextP, plaintextP)
-			ringP.MForm(plaintextP, plaintextP)
+			ringP.NTTMForm(plaintextP, plaintextP)
 
 			plaintextVec.Vec[N1*j+uint64(i)] = [2]*ring.Poly{plaintextQ, plaintextP}
 
@@ -1,6 +1,9 @@
 package ckks
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/ldsec/lattigo/v2/ring"
 	"github.com/ldsec/lattigo/v2/utils"
 )
@@ -42,3 +45,62 @@ func NewCiphertextRandom(prng utils.PRNG, params *Parameters, degree, level uint
 
 	return ciphertext
 }
+
+// ErrTransparentCiphertext is returned by Ciphertext.Validate for a transparent Ciphertext.
+var ErrTransparentCiphertext = errors.New("transparent ciphertext: the message is not masked by the secret key")
+
+// IsTransparent returns true if all the polynomials of the Ciphertext but the first one are zero. The first polynomial
+// of such a Ciphertext, which is for instance the result of the subtraction of a Ciphertext from itself, of the
+// multiplication by zero or of the addition of trivial encryptions, is then its plaintext plus the noise: it decrypts
+// without the secret key. A Ciphertext of degree zero is considered transparent.
+func (ciphertext *Ciphertext) IsTransparent() bool {
+	for _, pol := range ciphertext.value[1:] {
+		for _, coeffs := range pol.Coeffs {
+			for _, c := range coeffs {
+				if c != 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Validate checks that the Ciphertext is well formed for the parameters params, that is that its polynomials have the
+// degree N and the same level, at most the maximum level of params, and that its scale is positive. It returns
+// ErrTransparentCiphertext if the Ciphertext is transparent, see IsTransparent.
+func (ciphertext *Ciphertext) Validate(params *Parameters) error {
+
+	if ciphertext.Element == nil || len(ciphertext.value) < 2 {
+		return errors.New("invalid ciphertext: degree must be at least one")
+	}
+
+	level := uint64(len(ciphertext.value[0].Coeffs)) - 1
+
+	if level > params.MaxLevel() {
+		return fmt.Errorf("invalid ciphertext: level %d larger than %d", level, params.MaxLevel())
+	}
+
+	for i, pol := range ciphertext.value {
+
+		if pol == nil || uint64(len(pol.Coeffs)) != level+1 {
+			return fmt.Errorf("invalid ciphertext: polynomial %d is not at level %d", i, level)
+		}
+
+		for _, coeffs := range pol.Coeffs {
+			if uint64(len(coeffs)) != params.N() {
+				return fmt.Errorf("invalid ciphertext: polynomial %d is not of degree %d", i, params.N())
+			}
+		}
+	}
+
+	if !(ciphertext.scale > 0) {
+		return errors.New("invalid ciphertext: scale must be positive")
+	}
+
+	if ciphertext.IsTransparent() {
+		return ErrTransparentCiphertext
+	}
+
+	return nil
+}
@@ -1,11 +1,19 @@
 package ckks
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"math"
+	"math/big"
 	"math/cmplx"
 	"math/rand"
+	"os"
 	"runtime"
 	"testing"
 	"time"
@@ -72,6 +80,7 @@ func TestCKKS(t *testing.T) {
 			testParameters,
 			testEncoder,
 			testEncryptor,
+			testDecryptor,
 			testEvaluatorAdd,
 			testEvaluatorSub,
 			testEvaluatorRescale,
@@ -79,13 +88,24 @@ func TestCKKS(t *testing.T) {
 			testEvaluatorMultByConst,
 			testEvaluatorMultByConstAndAdd,
 			testEvaluatorMul,
+			testTransparentCiphertext,
+			testEvaluatorBatch,
 			testFunctions,
 			testEvaluatePoly,
 			testChebyshevInterpolator,
 			testSwitchKeys,
+			testRekey,
+			testRingDegreeSwitching,
 			testConjugate,
+			testSplitComplex,
+			testAutomorphism,
+			testLinearTransform,
 			testRotateColumns,
+			testDecomposition,
 			testMarshaller,
+			testCompression,
+			testKeyStore,
+			testNpy,
 		} {
 			testSet(testContext, t)
 			runtime.GC()
@@ -191,6 +211,251 @@ func testParameters(testContext *testParams, t *testing.T) {
 		assert.NoError(t, err)
 		assert.True(t, p.Equals(testContext.params))
 	})
+
+	t.Run("Parameters/GenModuliForDepthAndPrecision/", func(t *testing.T) {
+
+		logN := testContext.params.LogN()
+
+		lm, err := GenModuliForDepthAndPrecision(logN, 3, 40, 60)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{50, 40, 40, 40}, lm.LogQi)
+		require.Equal(t, []uint64{60}, lm.LogPi)
+
+		p, err := NewParametersFromLogModuli(logN, lm)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), p.MaxLevel())
+
+		_, err = GenModuliForDepthAndPrecision(logN, 3, 40, 45)
+		require.Error(t, err)
+
+		_, err = GenModuliForDepthAndPrecision(logN, MaxModuliCount, 40, 60)
+		require.Error(t, err)
+	})
+
+	t.Run(testString(testContext, "Parameters/LargeModuli/"), func(t *testing.T) {
+
+		lm := &LogModuli{
+			LogQi: []uint64{MaxModuliSize, 52, 52, 52, 52, 52},
+			LogPi: []uint64{MaxModuliSize, MaxModuliSize},
+		}
+
+		params, err := NewParametersFromLogModuli(testContext.params.LogN(), lm)
+		require.NoError(t, err)
+		params.SetLogSlots(testContext.params.LogSlots())
+		params.SetScale(1 << 52)
+
+		_, err = NewParametersFromLogModuli(testContext.params.LogN(), &LogModuli{LogQi: []uint64{MaxModuliSize + 1}})
+		require.Error(t, err)
+
+		largeContext, err := genTestParams(params, 0)
+		require.NoError(t, err)
+
+		values1, _, ciphertext1 := newTestVectors(largeContext, largeContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(largeContext, largeContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= values2[i]
+		}
+
+		largeContext.evaluator.MulRelin(ciphertext1, ciphertext2, largeContext.rlk, ciphertext1)
+		require.NoError(t, largeContext.evaluator.Rescale(ciphertext1, params.Scale(), ciphertext1))
+
+		verifyTestVectors(largeContext, largeContext.decryptor, values1, ciphertext1, t)
+	})
+
+	t.Run(testString(testContext, "Parameters/DeepChain/"), func(t *testing.T) {
+
+		lm, err := GenModuliForDepthAndPrecision(testContext.params.LogN(), 120, 40, 60)
+		require.NoError(t, err)
+
+		params, err := NewParametersFromLogModuli(testContext.params.LogN(), lm)
+		require.NoError(t, err)
+		params.SetLogSlots(testContext.params.LogSlots())
+		params.SetScale(1 << 40)
+		require.Equal(t, uint64(120), params.MaxLevel())
+
+		data, err := params.MarshalBinary()
+		require.NoError(t, err)
+		paramsTest := new(Parameters)
+		require.NoError(t, paramsTest.UnmarshalBinary(data))
+		require.True(t, params.Equals(paramsTest))
+
+		// The key-switching keys of such a chain are large, hence only the secret-key encryption is tested
+		kgen := NewKeyGenerator(params)
+		sk := kgen.GenSecretKey()
+
+		deepContext := &testParams{
+			params:    params,
+			encoder:   NewEncoder(params),
+			decryptor: NewDecryptor(params, sk),
+			evaluator: NewEvaluator(params),
+		}
+
+		values, _, ciphertext := newTestVectors(deepContext, NewEncryptorFromSk(params, sk), complex(-1, -1), complex(1, 1), t)
+
+		constant := complex(0.5, 0.25)
+		for i := 0; i < 4; i++ {
+			for j := range values {
+				values[j] *= constant
+			}
+			deepContext.evaluator.MultByConst(ciphertext, constant, ciphertext)
+			require.NoError(t, deepContext.evaluator.Rescale(ciphertext, params.Scale(), ciphertext))
+		}
+
+		verifyTestVectors(deepContext, deepContext.decryptor, values, ciphertext, t)
+
+		deepContext.evaluator.DropLevel(ciphertext, ciphertext.Level())
+		verifyTestVectors(deepContext, deepContext.decryptor, values, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "Parameters/SecurityLevel/"), func(t *testing.T) {
+
+		// The default parameters target 128 bits of security
+		require.GreaterOrEqual(t, testContext.params.SecurityLevel(), 128.0)
+		require.NoError(t, testContext.params.CheckSecurityLevel(0))
+		require.Error(t, testContext.params.CheckSecurityLevel(1024))
+
+		// Enlarging the modulus reduces the security
+		p := testContext.params.Copy()
+		p.pi = append(p.pi, p.qi...)
+		require.Less(t, p.SecurityLevel(), 0.8*testContext.params.SecurityLevel())
+		require.Error(t, p.CheckSecurityLevel(0))
+
+		require.GreaterOrEqual(t, testContext.params.SecurityLevelWithSecret(SecretUniform), testContext.params.SecurityLevel())
+	})
+
+	t.Run(testString(testContext, "Parameters/LowMemory/"), func(t *testing.T) {
+
+		for i, params := range LowMemoryParams {
+
+			require.NoError(t, params.CheckPlatform(0, 0))
+			require.GreaterOrEqual(t, params.SecurityLevel(), 128.0)
+
+			// Smaller keys than the default parameters of the same degree
+			defaultParams := DefaultParams[PN13QP218+i]
+			require.Equal(t, defaultParams.LogN(), params.LogN())
+			require.Less(t, params.SwitchingKeySize(), defaultParams.SwitchingKeySize())
+		}
+
+		require.NoError(t, testContext.params.CheckPlatform(0, 0))
+		require.Error(t, testContext.params.CheckPlatform(16, testContext.params.KeysSize(15)))
+	})
+
+	t.Run(testString(testContext, "Parameters/Default/"), func(t *testing.T) {
+
+		for _, params := range DefaultParams {
+			p, err := NewParametersFromModuli(params.LogN(), params.Moduli())
+			require.NoError(t, err)
+			require.Equal(t, params.QPiCount(), p.QPiCount())
+			require.GreaterOrEqual(t, params.SecurityLevel(), 128.0)
+		}
+
+		require.Equal(t, uint64(17), DefaultParams[PN17QP2015].LogN())
+		require.GreaterOrEqual(t, DefaultParams[PN17QP2015].MaxLevel(), uint64(30))
+
+		for i, params := range DefaultBootstrappSchemeParams {
+
+			_, err := NewParametersFromModuli(params.LogN(), params.Moduli())
+			require.NoError(t, err)
+
+			btpParams := DefaultBootstrappParams[i]
+			require.Equal(t, params.MaxLevel(), btpParams.CtSLevel[0])
+			require.Less(t, btpParams.StCLevel[0], btpParams.CtSLevel[len(btpParams.CtSLevel)-1])
+
+			// The moduli must be distinct
+			moduli := make(map[uint64]bool)
+			for _, qi := range append(params.Qi(), params.Pi()...) {
+				require.False(t, moduli[qi])
+				moduli[qi] = true
+			}
+		}
+
+		// The sets for dense secrets are 128-bit secure
+		for _, i := range []int{5, 6} {
+			params := DefaultBootstrappSchemeParams[i]
+			require.Equal(t, 2*params.N()/3, DefaultBootstrappParams[i].H)
+			require.GreaterOrEqual(t, params.SecurityLevel(), 128.0)
+		}
+	})
+
+	t.Run(testString(testContext, "Parameters/BootstrappPresets/"), func(t *testing.T) {
+
+		for _, info := range BootstrappPresets() {
+
+			params, btpParams, infoGot, err := GetBootstrappPreset(info.LogN, info.Preset)
+			require.NoError(t, err)
+			require.Equal(t, info, infoGot)
+			require.Equal(t, info.LogN, params.LogN())
+
+			_, err = NewParametersFromModuli(params.LogN(), params.Moduli())
+			require.NoError(t, err)
+			require.Equal(t, params.MaxLevel(), btpParams.CtSLevel[0])
+			require.Equal(t, info.Levels, btpParams.StCLevel[len(btpParams.StCLevel)-1]-1)
+			require.GreaterOrEqual(t, params.SecurityLevel(), 128.0)
+		}
+
+		// Each ring degree has the three presets, ordered by latency, levels and precision
+		for _, logN := range []uint64{16, 17} {
+			var prev BootstrappPresetInfo
+			for _, preset := range []BootstrappPreset{BootstrappFast, BootstrappBalanced, BootstrappPrecise} {
+				_, _, info, err := GetBootstrappPreset(logN, preset)
+				require.NoError(t, err)
+				require.Greater(t, int64(info.Latency), int64(prev.Latency))
+				require.Greater(t, info.Levels, prev.Levels)
+				require.GreaterOrEqual(t, info.Precision, prev.Precision)
+				require.GreaterOrEqual(t, info.MinPrecision, prev.MinPrecision)
+				prev = info
+			}
+		}
+
+		// The presets do not modify the default parameters
+		_, btpParams, _, err := GetBootstrappPreset(16, BootstrappBalanced)
+		require.NoError(t, err)
+		require.NotEqual(t, DefaultBootstrappParams[0].CtSLevel, btpParams.CtSLevel)
+		require.Equal(t, DefaultBootstrappSchemeParams[0].MaxLevel(), DefaultBootstrappParams[0].CtSLevel[0])
+
+		for _, logN := range []uint64{14, 15} {
+			_, _, _, err = GetBootstrappPreset(logN, BootstrappPrecise)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run(testString(testContext, "Parameters/GaloisElements/"), func(t *testing.T) {
+
+		params := testContext.params
+		N := params.N()
+
+		for _, k := range []uint64{0, 1, 5, N>>1 - 1} {
+
+			galEl := params.GaloisElementForColumnRotation(int(k))
+			require.Equal(t, ring.ModExp(GaloisGen, k, N<<1), galEl)
+			require.Equal(t, galEl, params.GaloisElementForRotation(RotationLeft, k))
+			require.Equal(t, params.GaloisElementForColumnRotation(-int(k)), params.GaloisElementForRotation(RotationRight, k))
+
+			// The inverse automorphism is the rotation in the opposite direction
+			require.Equal(t, params.GaloisElementForColumnRotation(-int(k)), params.InverseGaloisElement(galEl))
+			require.Equal(t, uint64(1), (galEl*params.InverseGaloisElement(galEl))&(N<<1-1))
+
+			rotType, kHave, err := params.RotationForGaloisElement(galEl)
+			require.NoError(t, err)
+			require.Equal(t, Rotation(RotationLeft), rotType)
+			require.Equal(t, k, kHave)
+		}
+
+		rotType, _, err := params.RotationForGaloisElement(params.GaloisElementForRowRotation())
+		require.NoError(t, err)
+		require.Equal(t, Rotation(Conjugate), rotType)
+
+		// A rotation composed with the conjugation is neither
+		_, _, err = params.RotationForGaloisElement(params.GaloisElementForRowRotation() * GaloisGen)
+		require.Error(t, err)
+
+		galEls := params.GaloisElementsForRotations([]int{0, 1, -1, 1, int(N>>1) + 1}, true)
+		require.Equal(t, 3, len(galEls))
+		require.Contains(t, galEls, params.GaloisElementForColumnRotation(1))
+		require.Contains(t, galEls, params.GaloisElementForColumnRotation(-1))
+		require.Contains(t, galEls, params.GaloisElementForRowRotation())
+	})
 }
 
 func testEncoder(testContext *testParams, t *testing.T) {
@@ -231,6 +496,30 @@ func testEncoder(testContext *testParams, t *testing.T) {
 		require.GreaterOrEqual(t, math.Log2(1/meanprec), minPrec)
 	})
 
+	t.Run(testString(testContext, "Encoder/DecodeWithScale/"), func(t *testing.T) {
+
+		values, plaintext, _ := newTestVectors(testContext, nil, complex(-1, -1), complex(1, 1), t)
+
+		// Simulates a scale that is tracked exactly but is not a power of two.
+		scale := new(big.Float).SetPrec(256).SetFloat64(plaintext.Scale())
+		scale.Mul(scale, new(big.Float).SetPrec(256).SetFloat64(3))
+
+		testContext.evaluator.MultByConst(plaintext.Ciphertext(), 3, plaintext.Ciphertext())
+
+		valuesTest := testContext.encoder.DecodeWithScale(plaintext, scale, testContext.params.Slots())
+
+		var meanprec float64
+		for i := range values {
+			meanprec += cmplx.Abs(valuesTest[i] - values[i])
+		}
+		meanprec /= float64(len(values))
+
+		require.GreaterOrEqual(t, math.Log2(1/meanprec), minPrec)
+
+		coeffs := testContext.encoder.DecodeCoeffsWithScale(plaintext, scale)
+		require.Equal(t, uint64(len(coeffs)), testContext.params.N())
+	})
+
 }
 
 func testEncryptor(testContext *testParams, t *testing.T) {
@@ -242,6 +531,28 @@ func testEncryptor(testContext *testParams, t *testing.T) {
 		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 	})
 
+	t.Run(testString(testContext, "Encryptor/EncryptFromPk/Lvl/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() == 0 {
+			t.Skip("#Qi is 1")
+		}
+
+		slots := testContext.params.Slots()
+
+		values := make([]complex128, slots)
+		for i := range values {
+			values[i] = randomComplex(-1, 1)
+		}
+
+		plaintext := NewPlaintext(testContext.params, testContext.params.MaxLevel()-1, testContext.params.Scale())
+		testContext.encoder.Encode(plaintext, values, slots)
+
+		ciphertext := testContext.encryptorPk.EncryptNew(plaintext)
+		require.Equal(t, plaintext.Level(), ciphertext.Level())
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+
 	t.Run(testString(testContext, "Encryptor/EncryptFromPkFast/"), func(t *testing.T) {
 
 		slots := testContext.params.Slots()
@@ -268,6 +579,213 @@ func testEncryptor(testContext *testParams, t *testing.T) {
 		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
 	})
 
+	t.Run(testString(testContext, "Encryptor/EncryptSeeded/"), func(t *testing.T) {
+
+		values, plaintext, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		seeded := testContext.encryptorSk.EncryptSeededNew(plaintext)
+
+		data, err := seeded.MarshalBinary()
+		require.NoError(t, err)
+		require.Less(t, 2*uint64(len(data)), ciphertext.GetDataLen(true)+2*SeedSize+100)
+
+		received := new(SeededCiphertext)
+		require.NoError(t, received.UnmarshalBinary(data))
+		require.Equal(t, seeded.Seed(), received.Seed())
+
+		verifyTestVectors(testContext, testContext.decryptor, values, received.Expand(testContext.params), t)
+
+		require.Panics(t, func() { testContext.encryptorPk.EncryptSeededNew(plaintext) })
+	})
+
+	t.Run(testString(testContext, "Encryptor/EncryptStream/"), func(t *testing.T) {
+
+		slots := testContext.params.Slots()
+
+		// Two and a half chunks, the last one being padded with zeros
+		values := make([]complex128, 3*slots)
+		buff := new(bytes.Buffer)
+		for i := uint64(0); i < 5*slots/2; i++ {
+			values[i] = complex(randomFloat(-1, 1), 0)
+			require.NoError(t, binary.Write(buff, binary.LittleEndian, real(values[i])))
+		}
+
+		stream := testContext.encryptorSk.EncryptStream(buff)
+
+		var chunks uint64
+		var first *Ciphertext
+		for stream.Next() {
+			verifyTestVectors(testContext, testContext.decryptor, values[chunks*slots:(chunks+1)*slots], stream.Ciphertext(), t)
+			if first == nil {
+				first = stream.Ciphertext()
+			}
+			// The output Ciphertext is reused for all the chunks
+			require.True(t, first == stream.Ciphertext())
+			chunks++
+		}
+
+		require.NoError(t, stream.Err())
+		require.Equal(t, uint64(3), chunks)
+
+		stream = testContext.encryptorPk.EncryptStream(bytes.NewReader(make([]byte, 8*slots+3)))
+		require.True(t, stream.Next())
+		require.False(t, stream.Next())
+		require.Error(t, stream.Err())
+	})
+
+	t.Run(testString(testContext, "Encryptor/EncryptFromCRP/"), func(t *testing.T) {
+
+		values, plaintext, _ := newTestVectors(testContext, nil, complex(-1, -1), complex(1, 1), t)
+
+		crp := ring.NewUniformSampler(testContext.prng, testContext.ringQ).ReadNew()
+
+		encryptor := NewEncryptorFromCRP(testContext.params, testContext.sk, crp)
+
+		ciphertext := encryptor.EncryptNew(plaintext)
+
+		require.True(t, testContext.ringQ.Equal(crp, ciphertext.Value()[1]))
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+
+		// The variants which cannot use the CRP refuse to encrypt
+		require.Panics(t, func() { encryptor.EncryptFastNew(plaintext) })
+		require.Panics(t, func() { encryptor.EncryptFast(plaintext, ciphertext) })
+		require.Panics(t, func() { encryptor.EncryptSeededNew(plaintext) })
+		require.Panics(t, func() { encryptor.EncryptStream(bytes.NewReader(nil)) })
+	})
+
+}
+
+func testDecryptor(testContext *testParams, t *testing.T) {
+
+	t.Run(testString(testContext, "Decryptor/DecryptLvl/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		plaintext := testContext.decryptor.DecryptLvlNew(0, ciphertext)
+		require.Equal(t, uint64(0), plaintext.Level())
+
+		verifyTestVectors(testContext, nil, values, plaintext, t)
+	})
+
+	t.Run(testString(testContext, "Decryptor/DecryptSlots/"), func(t *testing.T) {
+
+		for _, slots := range []uint64{testContext.params.Slots(), 4} {
+
+			values := make([]complex128, slots)
+			for i := range values {
+				values[i] = complex(randomFloat(-1, 1), randomFloat(-1, 1))
+			}
+
+			plaintext := NewPlaintext(testContext.params, testContext.params.MaxLevel(), testContext.params.Scale())
+			testContext.encoder.EncodeNTT(plaintext, values, slots)
+			ciphertext := testContext.encryptorSk.EncryptNew(plaintext)
+
+			indices := []uint64{0, 1, slots - 1}
+
+			have := testContext.decryptor.DecryptSlots(ciphertext, slots, indices)
+
+			for i, j := range indices {
+				require.InDelta(t, real(values[j]), real(have[i]), 1e-3)
+				require.InDelta(t, imag(values[j]), imag(have[i]), 1e-3)
+			}
+
+			// Without rescaling, the scale of the product requires more than one modulus
+			ciphertext = testContext.evaluator.MulRelinNew(ciphertext, plaintext, nil)
+
+			have = testContext.decryptor.DecryptSlots(ciphertext, slots, indices)
+
+			for i, j := range indices {
+				require.InDelta(t, real(values[j]*values[j]), real(have[i]), 1e-3)
+				require.InDelta(t, imag(values[j]*values[j]), imag(have[i]), 1e-3)
+			}
+		}
+	})
+
+	t.Run(testString(testContext, "Decryptor/KeyBinding/"), func(t *testing.T) {
+
+		params := testContext.params
+
+		fpSk := testContext.sk.Fingerprint(params)
+		fpPk := testContext.pk.Fingerprint(params)
+		require.Equal(t, fpSk, testContext.sk.Fingerprint(params))
+		require.NotEqual(t, fpSk, fpPk)
+
+		encryptorSk := NewEncryptorFromSk(params, testContext.sk)
+		encryptorSk.SetKeyBinding(true)
+		encryptorPk := NewEncryptorFromPk(params, testContext.pk)
+		encryptorPk.SetKeyBinding(true)
+
+		values1, _, ciphertext1 := newTestVectors(testContext, encryptorSk, complex(-1, -1), complex(1, 1), t)
+		fp, bound := ciphertext1.KeyFingerprint()
+		require.True(t, bound)
+		require.Equal(t, fpSk, fp)
+
+		values2, _, ciphertext2 := newTestVectors(testContext, encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+		// A public-key generated by the KeyGenerator binds to its secret-key
+		fp, bound = ciphertext2.KeyFingerprint()
+		require.True(t, bound)
+		require.Equal(t, fpSk, fp)
+
+		decryptor := NewDecryptor(params, testContext.sk)
+		verifyTestVectors(testContext, decryptor, values1, ciphertext1, t)
+		verifyTestVectors(testContext, decryptor, values2, ciphertext2, t)
+
+		// A public-key whose secret-key is unknown binds to itself, and must be accepted by the decryptor
+		pkUnknown := NewPublicKey(params)
+		pkUnknown.Set(testContext.pk.Get())
+		require.Equal(t, fpPk, pkUnknown.Fingerprint(params))
+		encryptorPkUnknown := NewEncryptorFromPk(params, pkUnknown)
+		encryptorPkUnknown.SetKeyBinding(true)
+
+		values4, _, ciphertext4 := newTestVectors(testContext, encryptorPkUnknown, complex(-1, -1), complex(1, 1), t)
+		fp, _ = ciphertext4.KeyFingerprint()
+		require.Equal(t, fpPk, fp)
+
+		require.Panics(t, func() { decryptor.DecryptNew(ciphertext4) })
+		decryptor.AcceptKeys(fpPk)
+		verifyTestVectors(testContext, decryptor, values4, ciphertext4, t)
+
+		// Ciphertexts bound to different keys cannot be combined
+		require.NotPanics(t, func() { testContext.evaluator.AddNew(ciphertext1, ciphertext2) })
+		require.Panics(t, func() { testContext.evaluator.AddNew(ciphertext1, ciphertext4) })
+
+		// The binding is propagated to the results of the Evaluator
+		ciphertext3 := testContext.evaluator.MulRelinNew(ciphertext1, ciphertext1, testContext.rlk)
+		fp, bound = ciphertext3.KeyFingerprint()
+		require.True(t, bound)
+		require.Equal(t, fpSk, fp)
+
+		// A Ciphertext bound to another secret-key is rejected
+		skOther := testContext.kgen.GenSecretKey()
+		require.NotEqual(t, fpSk, skOther.Fingerprint(params))
+		require.Panics(t, func() { NewDecryptor(params, skOther).DecryptNew(ciphertext1) })
+
+		if params.PiCount() != 0 {
+
+			// The Evaluator rejects the evaluation keys of another secret-key
+			rlkOther := testContext.kgen.GenRelinKey(skOther)
+			require.Panics(t, func() { testContext.evaluator.MulRelinNew(ciphertext1, ciphertext1, rlkOther) })
+
+			rotKeyOther := NewRotationKeys()
+			testContext.kgen.GenRotationKey(RotationLeft, skOther, 1, rotKeyOther)
+			require.Panics(t, func() { testContext.evaluator.RotateColumnsNew(ciphertext1, 1, rotKeyOther) })
+
+			require.Panics(t, func() {
+				testContext.evaluator.SwitchKeysNew(ciphertext1, testContext.kgen.GenSwitchingKey(skOther, testContext.sk))
+			})
+
+			// The key switching binds the result to the output key
+			ciphertext5 := testContext.evaluator.SwitchKeysNew(ciphertext1, testContext.kgen.GenSwitchingKey(testContext.sk, skOther))
+			fp, _ = ciphertext5.KeyFingerprint()
+			require.Equal(t, skOther.Fingerprint(params), fp)
+			verifyTestVectors(testContext, NewDecryptor(params, skOther), values1, ciphertext5, t)
+		}
+
+		ciphertext1.Unbind()
+		require.NotPanics(t, func() { NewDecryptor(params, skOther).DecryptNew(ciphertext1) })
+	})
 }
 
 func testEvaluatorAdd(testContext *testParams, t *testing.T) {
@@ -340,6 +858,30 @@ func testEvaluatorAdd(testContext *testParams, t *testing.T) {
 		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext3, t)
 	})
 
+	t.Run(testString(testContext, "EvaluatorAdd/Logger/"), func(t *testing.T) {
+
+		var buf bytes.Buffer
+		testContext.evaluator.SetLogger(utils.NewStdLogger(log.New(&buf, "", 0), utils.LogDebug))
+		defer testContext.evaluator.SetLogger(nil)
+
+		_, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		_, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		testContext.evaluator.Add(ciphertext1, ciphertext2, ciphertext1)
+		assert.Equal(t, "", buf.String())
+
+		// A ratio of the scales which is not an integer is only reported at the Debug level
+		ciphertext2.SetScale(ciphertext2.Scale() * 1.5)
+		testContext.evaluator.Add(ciphertext1, ciphertext2, ciphertext1)
+		assert.Contains(t, buf.String(), "DEBUG ckks: the scales of the operands are not integer multiples of each other")
+
+		buf.Reset()
+		testContext.evaluator.SetLogger(utils.NewStdLogger(log.New(&buf, "", 0), utils.LogWarn))
+		ciphertext2.SetScale(ciphertext2.Scale() * 1.5)
+		testContext.evaluator.Add(ciphertext1, ciphertext2, ciphertext1)
+		assert.Equal(t, "", buf.String())
+	})
+
 }
 
 func testEvaluatorSub(testContext *testParams, t *testing.T) {
@@ -643,82 +1185,316 @@ func testEvaluatorMul(testContext *testParams, t *testing.T) {
 		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext1, t)
 	})
 
-	t.Run(testString(testContext, "EvaluatorMul/Relinearize(ct0*ct1->ct1)/"), func(t *testing.T) {
+	t.Run(testString(testContext, "EvaluatorMul/WorkerPool/"), func(t *testing.T) {
 
 		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
 		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
 
 		for i := range values1 {
-			values2[i] *= values1[i]
+			values1[i] *= values2[i]
 		}
 
-		testContext.evaluator.MulRelin(ciphertext1, ciphertext2, nil, ciphertext2)
+		pool := ring.NewWorkerPool(4)
+		defer pool.Close()
 
-		testContext.evaluator.Relinearize(ciphertext2, testContext.rlk, ciphertext2)
+		testContext.evaluator.SetWorkerPool(pool)
+		defer testContext.evaluator.SetWorkerPool(nil)
 
-		require.Equal(t, ciphertext1.Degree(), uint64(1))
+		testContext.evaluator.MulRelin(ciphertext1, ciphertext2, testContext.rlk, ciphertext1)
 
-		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
-	})
+		if ciphertext1.Level() != 0 {
+			require.Nil(t, testContext.evaluator.Rescale(ciphertext1, testContext.params.Scale(), ciphertext1))
+		}
 
-}
+		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext1, t)
+	})
 
-func testFunctions(testContext *testParams, t *testing.T) {
+	t.Run(testString(testContext, "EvaluatorMul/LowLevel/"), func(t *testing.T) {
 
-	t.Run(testString(testContext, "Functions/PowerOf2/"), func(t *testing.T) {
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
 
-		if testContext.params.MaxLevel() < 3 {
-			t.Skip()
+		for i := range values1 {
+			values1[i] *= values2[i]
 		}
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		// A new Evaluator used at level 1 only allocates its memory pools up to level 1
+		eval := NewEvaluator(testContext.params)
 
-		n := uint64(2)
+		eval.DropLevel(ciphertext1, ciphertext1.Level()-1)
+		eval.DropLevel(ciphertext2, ciphertext2.Level()-1)
 
-		valuesWant := make([]complex128, len(values))
-		for i := 0; i < len(valuesWant); i++ {
-			valuesWant[i] = values[i]
-		}
+		eval.MulRelin(ciphertext1, ciphertext2, testContext.rlk, ciphertext1)
+		require.Nil(t, eval.Rescale(ciphertext1, testContext.params.Scale(), ciphertext1))
 
-		for i := uint64(0); i < n; i++ {
-			for j := 0; j < len(valuesWant); j++ {
-				valuesWant[j] *= valuesWant[j]
-			}
+		for _, pool := range eval.(*evaluator).poolQ {
+			require.Equal(t, 2, len(pool.Coeffs))
 		}
 
-		testContext.evaluator.PowerOf2(ciphertext, n, testContext.rlk, ciphertext)
-
-		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext1, t)
 	})
 
-	t.Run(testString(testContext, "Functions/Power/"), func(t *testing.T) {
+	t.Run(testString(testContext, "EvaluatorMul/Relinearize(ct0*ct1->ct1)/"), func(t *testing.T) {
 
-		if testContext.params.MaxLevel() < 4 {
-			t.Skip()
-		}
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		for i := range values1 {
+			values2[i] *= values1[i]
+		}
 
-		n := uint64(3)
+		testContext.evaluator.MulRelin(ciphertext1, ciphertext2, nil, ciphertext2)
 
-		for i := range values {
-			values[i] = cmplx.Pow(values[i], complex(float64(n), 0))
-		}
+		testContext.evaluator.Relinearize(ciphertext2, testContext.rlk, ciphertext2)
 
-		testContext.evaluator.Power(ciphertext, n, testContext.rlk, ciphertext)
+		require.Equal(t, ciphertext1.Degree(), uint64(1))
 
-		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
 	})
 
-	t.Run(testString(testContext, "Functions/Inverse/"), func(t *testing.T) {
+	t.Run(testString(testContext, "EvaluatorMul/Relinearize/Degree3/"), func(t *testing.T) {
 
-		if testContext.params.MaxLevel() < 7 {
-			t.Skip()
+		if testContext.params.MaxLevel() < 2 {
+			t.Skip("skipping test for params max level < 2")
 		}
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(0.1, 0), complex(1, 0), t)
+		rlk := testContext.kgen.GenRelinKeys(testContext.sk, 3)
+		require.Equal(t, uint64(3), rlk.MaxDegree())
 
-		n := uint64(7)
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values3, _, ciphertext3 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= values2[i] * values3[i]
+		}
+
+		// Lazy relinearization: degree 2 then degree 3, relinearized in one pass
+		ciphertext12 := testContext.evaluator.MulRelinNew(ciphertext1, ciphertext2, nil)
+
+		lazy := testContext.evaluator.MulRelinNew(ciphertext12, ciphertext3, nil)
+		require.Equal(t, uint64(3), lazy.Degree())
+
+		testContext.evaluator.Relinearize(lazy, rlk, lazy)
+		require.Equal(t, uint64(1), lazy.Degree())
+
+		verifyTestVectors(testContext, testContext.decryptor, values1, lazy, t)
+
+		// Degree 3 multiplication relinearized directly
+		direct := testContext.evaluator.MulRelinNew(ciphertext12, ciphertext3, rlk)
+		require.Equal(t, uint64(1), direct.Degree())
+
+		verifyTestVectors(testContext, testContext.decryptor, values1, direct, t)
+	})
+
+	t.Run(testString(testContext, "EvaluatorMul/Balanced/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() < 2 {
+			t.Skip("skipping test for params max level < 2")
+		}
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= values2[i]
+		}
+
+		// Drifts the scale of the first input away from the default scale.
+		testContext.evaluator.ScaleUp(ciphertext1, 3, ciphertext1)
+		require.NotEqual(t, ciphertext1.Scale(), testContext.params.Scale())
+
+		ciphertext3 := testContext.evaluator.MulRelinBalancedNew(ciphertext1, ciphertext2, testContext.rlk)
+
+		require.Equal(t, testContext.params.Scale(), ciphertext3.Scale())
+
+		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext3, t)
+	})
+
+	t.Run(testString(testContext, "EvaluatorMul/ScalePolicy/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() < 2 {
+			t.Skip("skipping test for params max level < 2")
+		}
+
+		require.Equal(t, DefaultScalePolicy(testContext.params), testContext.evaluator.ScalePolicy())
+
+		policy := ScalePolicy{DefaultScale: testContext.params.Scale() / 16}
+		eval := NewEvaluatorWithScalePolicy(testContext.params, policy)
+		require.Equal(t, policy.DefaultScale, eval.ScalePolicy().RescaleThreshold)
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= values2[i]
+		}
+
+		ciphertext3 := eval.MulRelinBalancedNew(ciphertext1, ciphertext2, testContext.rlk)
+
+		require.Equal(t, policy.DefaultScale, ciphertext3.Scale())
+
+		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext3, t)
+
+		require.Panics(t, func() { NewEvaluatorWithScalePolicy(testContext.params, ScalePolicy{}) })
+	})
+
+	t.Run(testString(testContext, "EvaluatorMul/Precompute/pt*ct0->ct1/"), func(t *testing.T) {
+
+		values1, plaintext1, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= values1[i]
+		}
+
+		pre := testContext.evaluator.Precompute(plaintext1)
+
+		// The cached operand can be used at a lower level than the one it was precomputed at
+		for _, level := range []uint64{ciphertext1.Level(), 1} {
+
+			testContext.evaluator.DropLevel(ciphertext1, ciphertext1.Level()-level)
+
+			ciphertext2 := testContext.evaluator.MulRelinNew(pre, ciphertext1, nil)
+
+			require.Equal(t, level, ciphertext2.Level())
+
+			verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext2, t)
+		}
+	})
+
+	t.Run(testString(testContext, "EvaluatorMul/Precompute/ct0*ct1->ct1/"), func(t *testing.T) {
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values2[i] *= values1[i]
+		}
+
+		testContext.evaluator.MulRelin(testContext.evaluator.Precompute(ciphertext1), ciphertext2, testContext.rlk, ciphertext2)
+
+		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
+	})
+}
+
+func testEvaluatorBatch(testContext *testParams, t *testing.T) {
+
+	rotKey := testContext.kgen.GenRotationKeysPow2(testContext.sk)
+
+	t.Run(testString(testContext, "Evaluator/Batch/"), func(t *testing.T) {
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values2, _, ciphertext2 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		valuesWant := make([]complex128, len(values1))
+		for i := range valuesWant {
+			j := (i + 1) % len(values1)
+			valuesWant[i] = (values1[j] + values2[j]) * (values1[j] - values2[j])
+		}
+
+		ciphertext3 := NewCiphertext(testContext.params, 1, ciphertext1.Level(), ciphertext1.Scale())
+
+		err := testContext.evaluator.Batch(func(b *BatchEvaluator) error {
+			b.Add(ciphertext1, ciphertext2, ciphertext3)
+			b.Sub(ciphertext1, ciphertext2, ciphertext1)
+			b.MulRelin(ciphertext3, ciphertext1, testContext.rlk, ciphertext1)
+			b.Rescale(ciphertext1, ciphertext1)
+			b.RotateColumns(ciphertext1, 1, rotKey, ciphertext1)
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, ciphertext3.Level()-1, ciphertext1.Level())
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext1, t)
+	})
+
+	t.Run(testString(testContext, "Evaluator/Batch/Square/"), func(t *testing.T) {
+
+		values1, plaintext1, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values1 {
+			values1[i] *= 2 * values1[i]
+		}
+
+		ciphertext2 := NewCiphertext(testContext.params, 1, ciphertext1.Level(), ciphertext1.Scale())
+
+		err := testContext.evaluator.Batch(func(b *BatchEvaluator) error {
+			b.MulPlain(ciphertext1, plaintext1, ciphertext2)
+			b.MulRelin(ciphertext1, ciphertext1, testContext.rlk, ciphertext1)
+			b.Rescale(ciphertext1, ciphertext1)
+			b.Rescale(ciphertext2, ciphertext2)
+			b.Add(ciphertext1, ciphertext2, ciphertext2)
+			return nil
+		})
+
+		require.NoError(t, err)
+
+		verifyTestVectors(testContext, testContext.decryptor, values1, ciphertext2, t)
+	})
+
+	t.Run(testString(testContext, "Evaluator/Batch/Error/"), func(t *testing.T) {
+		errBatch := errors.New("batch error")
+		require.Equal(t, errBatch, testContext.evaluator.Batch(func(b *BatchEvaluator) error { return errBatch }))
+	})
+}
+
+func testFunctions(testContext *testParams, t *testing.T) {
+
+	t.Run(testString(testContext, "Functions/PowerOf2/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() < 3 {
+			t.Skip()
+		}
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		n := uint64(2)
+
+		valuesWant := make([]complex128, len(values))
+		for i := 0; i < len(valuesWant); i++ {
+			valuesWant[i] = values[i]
+		}
+
+		for i := uint64(0); i < n; i++ {
+			for j := 0; j < len(valuesWant); j++ {
+				valuesWant[j] *= valuesWant[j]
+			}
+		}
+
+		testContext.evaluator.PowerOf2(ciphertext, n, testContext.rlk, ciphertext)
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "Functions/Power/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() < 4 {
+			t.Skip()
+		}
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		n := uint64(3)
+
+		for i := range values {
+			values[i] = cmplx.Pow(values[i], complex(float64(n), 0))
+		}
+
+		testContext.evaluator.Power(ciphertext, n, testContext.rlk, ciphertext)
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "Functions/Inverse/"), func(t *testing.T) {
+
+		if testContext.params.MaxLevel() < 7 {
+			t.Skip()
+		}
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(0.1, 0), complex(1, 0), t)
+
+		n := uint64(7)
 
 		for i := range values {
 			values[i] = 1.0 / values[i]
@@ -775,73 +1551,522 @@ func testChebyshevInterpolator(testContext *testParams, t *testing.T) {
 
 		cheby := Approximate(cmplx.Sin, complex(-1.5, 0), complex(1.5, 0), 15)
 
-		for i := range values {
-			values[i] = cmplx.Sin(values[i])
+		for i := range values {
+			values[i] = cmplx.Sin(values[i])
+		}
+
+		ciphertext = testContext.evaluator.EvaluateCheby(ciphertext, cheby, testContext.rlk)
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+}
+
+func testSwitchKeys(testContext *testParams, t *testing.T) {
+
+	sk2 := testContext.kgen.GenSecretKey()
+	decryptorSk2 := NewDecryptor(testContext.params, sk2)
+	switchingKey := testContext.kgen.GenSwitchingKey(testContext.sk, sk2)
+
+	t.Run(testString(testContext, "SwitchKeys/InPlace/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		testContext.evaluator.SwitchKeys(ciphertext, switchingKey, ciphertext)
+
+		verifyTestVectors(testContext, decryptorSk2, values, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "SwitchKeys/New/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		ciphertext = testContext.evaluator.SwitchKeysNew(ciphertext, switchingKey)
+
+		verifyTestVectors(testContext, decryptorSk2, values, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "SwitchKeys/Lvl/"), func(t *testing.T) {
+
+		level := testContext.params.MaxLevel() / 2
+
+		switchingKeyLvl := testContext.kgen.GenSwitchingKeyLvl(level, testContext.sk, sk2)
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		if level < ciphertext.Level() {
+			require.Less(t, switchingKeyLvl.GetDataLen(true), switchingKey.GetDataLen(true))
+			require.Panics(t, func() { testContext.evaluator.SwitchKeysNew(ciphertext, switchingKeyLvl) })
+		}
+
+		testContext.evaluator.DropLevel(ciphertext, ciphertext.Level()-level)
+
+		testContext.evaluator.SwitchKeys(ciphertext, switchingKeyLvl, ciphertext)
+
+		verifyTestVectors(testContext, decryptorSk2, values, ciphertext, t)
+
+		rotKey := NewRotationKeys()
+		testContext.kgen.GenRotationKeyLvl(level, RotationLeft, testContext.sk, 1, rotKey)
+
+		values, _, ciphertext = newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		testContext.evaluator.DropLevel(ciphertext, ciphertext.Level()-level)
+
+		valuesRot := make([]complex128, len(values))
+		for i := range values {
+			valuesRot[i] = values[(i+1)%len(values)]
+		}
+
+		testContext.evaluator.RotateColumns(ciphertext, 1, rotKey, ciphertext)
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesRot, ciphertext, t)
+	})
+
+}
+
+func testRekey(testContext *testParams, t *testing.T) {
+
+	skNew := testContext.kgen.GenSecretKey()
+	decryptorNew := NewDecryptor(testContext.params, skNew)
+	rekeyer := NewRekeyer(testContext.params, testContext.sk, skNew)
+
+	t.Run(testString(testContext, "Rekey/Batch/"), func(t *testing.T) {
+
+		values0, _, ciphertext0 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+		rekeyer.RekeyBatch([]*Ciphertext{ciphertext0, ciphertext1})
+
+		verifyTestVectors(testContext, decryptorNew, values0, ciphertext0, t)
+		verifyTestVectors(testContext, decryptorNew, values1, ciphertext1, t)
+	})
+
+	t.Run(testString(testContext, "Rekey/Stream/"), func(t *testing.T) {
+
+		values := make([][]complex128, 3)
+		archive := new(bytes.Buffer)
+
+		for i := range values {
+			var ciphertext *Ciphertext
+			values[i], _, ciphertext = newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+			require.NoError(t, WriteCiphertext(archive, ciphertext))
+		}
+
+		rekeyed := new(bytes.Buffer)
+		n, err := rekeyer.RekeyStream(archive, rekeyed)
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(values)), n)
+
+		for i := range values {
+			ciphertext, err := ReadCiphertext(rekeyed)
+			require.NoError(t, err)
+			verifyTestVectors(testContext, decryptorNew, values[i], ciphertext, t)
+		}
+	})
+}
+
+func testRingDegreeSwitching(testContext *testParams, t *testing.T) {
+
+	paramsLarge := testContext.params.Copy()
+	paramsLarge.SetLogSlots(paramsLarge.LogN() - 2)
+
+	paramsSmall, err := NewParametersFromModuli(paramsLarge.LogN()-1, &Moduli{Qi: paramsLarge.Qi(), Pi: paramsLarge.Pi()})
+	require.NoError(t, err)
+	paramsSmall.SetLogSlots(paramsLarge.LogSlots())
+	paramsSmall.SetScale(paramsLarge.Scale())
+
+	skSmall := NewKeyGenerator(paramsSmall).GenSecretKey()
+
+	encoderSmall := NewEncoder(paramsSmall)
+	decryptorSmall := NewDecryptor(paramsSmall, skSmall)
+	encryptorSmall := NewEncryptorFromSk(paramsSmall, skSmall)
+
+	switcher := NewRingDegreeSwitcher(paramsLarge, paramsSmall, testContext.sk, skSmall)
+
+	slots := paramsSmall.Slots()
+
+	values := make([]complex128, slots)
+	for i := range values {
+		values[i] = complex(randomFloat(-1, 1), randomFloat(-1, 1))
+	}
+
+	t.Run(testString(testContext, "RingDegreeSwitching/ToSmallRing/"), func(t *testing.T) {
+
+		plaintext := NewPlaintext(paramsLarge, paramsLarge.MaxLevel(), paramsLarge.Scale())
+		testContext.encoder.EncodeNTT(plaintext, values, slots)
+		ciphertext := testContext.encryptorPk.EncryptNew(plaintext)
+
+		ciphertextSmall := switcher.ToSmallRingNew(ciphertext)
+
+		require.Equal(t, paramsSmall.N(), uint64(len(ciphertextSmall.Value()[0].Coeffs[0])))
+
+		precStats := GetPrecisionStats(paramsSmall, encoderSmall, decryptorSmall, values, ciphertextSmall)
+		require.GreaterOrEqual(t, real(precStats.MeanPrecision), minPrec)
+		require.GreaterOrEqual(t, imag(precStats.MeanPrecision), minPrec)
+	})
+
+	t.Run(testString(testContext, "RingDegreeSwitching/ToLargeRing/"), func(t *testing.T) {
+
+		plaintext := NewPlaintext(paramsSmall, paramsSmall.MaxLevel(), paramsSmall.Scale())
+		encoderSmall.EncodeNTT(plaintext, values, slots)
+		ciphertextSmall := encryptorSmall.EncryptNew(plaintext)
+
+		ciphertext := switcher.ToLargeRingNew(ciphertextSmall)
+
+		require.Equal(t, paramsLarge.N(), uint64(len(ciphertext.Value()[0].Coeffs[0])))
+
+		precStats := GetPrecisionStats(paramsLarge, testContext.encoder, testContext.decryptor, values, ciphertext)
+		require.GreaterOrEqual(t, real(precStats.MeanPrecision), minPrec)
+		require.GreaterOrEqual(t, imag(precStats.MeanPrecision), minPrec)
+	})
+}
+
+func testConjugate(testContext *testParams, t *testing.T) {
+
+	rotKey := NewRotationKeys()
+	testContext.kgen.GenRotationKey(Conjugate, testContext.sk, 0, rotKey)
+
+	t.Run(testString(testContext, "Conjugate/InPlace/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values {
+			values[i] = complex(real(values[i]), -imag(values[i]))
+		}
+
+		testContext.evaluator.Conjugate(ciphertext, rotKey, ciphertext)
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "Conjugate/New/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		for i := range values {
+			values[i] = complex(real(values[i]), -imag(values[i]))
+		}
+
+		ciphertext = testContext.evaluator.ConjugateNew(ciphertext, rotKey)
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+
+}
+
+func testTransparentCiphertext(testContext *testParams, t *testing.T) {
+
+	t.Run(testString(testContext, "TransparentCiphertext/Detection/"), func(t *testing.T) {
+
+		_, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+		require.False(t, ciphertext.IsTransparent())
+		require.Nil(t, ciphertext.Validate(testContext.params))
+
+		zero := testContext.evaluator.SubNew(ciphertext, ciphertext)
+
+		require.True(t, zero.IsTransparent())
+		require.Equal(t, ErrTransparentCiphertext, zero.Validate(testContext.params))
+
+		require.NotNil(t, NewCiphertext(testContext.params, 0, 0, 1).Validate(testContext.params))
+	})
+
+	t.Run(testString(testContext, "TransparentCiphertext/Rerandomization/"), func(t *testing.T) {
+
+		eval := NewEvaluator(testContext.params)
+		eval.SetRerandomizer(testContext.encryptorPk)
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorPk, complex(-1, -1), complex(1, 1), t)
+
+		zeros := make([]complex128, len(values))
+
+		for _, ctOut := range []*Ciphertext{
+			eval.SubNew(ciphertext, ciphertext),
+			eval.MultByConstNew(ciphertext, 0),
+			eval.AddNew(NewCiphertext(testContext.params, 1, ciphertext.Level(), ciphertext.Scale()), NewCiphertext(testContext.params, 1, ciphertext.Level(), ciphertext.Scale())),
+		} {
+			require.False(t, ctOut.IsTransparent())
+			verifyTestVectors(testContext, testContext.decryptor, zeros, ctOut, t)
+		}
+
+		// Non-transparent outputs are left unchanged
+		ctOut := eval.AddNew(ciphertext, ciphertext)
+		require.True(t, testContext.ringQ.EqualLvl(ctOut.Level(), ctOut.value[1], testContext.evaluator.AddNew(ciphertext, ciphertext).value[1]))
+
+		eval.SetRerandomizer(nil)
+		require.True(t, eval.SubNew(ciphertext, ciphertext).IsTransparent())
+	})
+}
+
+func testSplitComplex(testContext *testParams, t *testing.T) {
+
+	rotKey := NewRotationKeys()
+	testContext.kgen.GenRotationKey(Conjugate, testContext.sk, 0, rotKey)
+
+	t.Run(testString(testContext, "SplitComplex/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		valuesRe := make([]complex128, len(values))
+		valuesIm := make([]complex128, len(values))
+		for i := range values {
+			valuesRe[i] = complex(real(values[i]), 0)
+			valuesIm[i] = complex(imag(values[i]), 0)
+		}
+
+		ctRe, ctIm := testContext.evaluator.SplitComplex(ciphertext, rotKey)
+
+		require.Equal(t, ciphertext.Level(), ctRe.Level())
+		require.Equal(t, ciphertext.Level(), ctIm.Level())
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesRe, ctRe, t)
+		verifyTestVectors(testContext, testContext.decryptor, valuesIm, ctIm, t)
+
+		ciphertext = testContext.evaluator.MergeComplex(ctRe, ctIm)
+
+		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+	})
+}
+
+func testAutomorphism(testContext *testParams, t *testing.T) {
+
+	params := testContext.params
+
+	// Rotation by 3 positions to the left composed with the conjugation
+	galEl := params.GaloisElementForColumnRotation(3) * params.GaloisElementForRowRotation() % (2 * params.N())
+
+	rotKey := NewRotationKeys()
+	testContext.kgen.GenAutomorphismKey(galEl, testContext.sk, rotKey)
+
+	t.Run(testString(testContext, "Automorphism/InPlace/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		valuesWant := make([]complex128, len(values))
+		for i := range values {
+			v := values[(i+3)%len(values)]
+			valuesWant[i] = complex(real(v), -imag(v))
+		}
+
+		testContext.evaluator.Automorphism(ciphertext, galEl, rotKey, ciphertext)
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "Automorphism/RotationKey/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 5, rotKey)
+
+		valuesWant := make([]complex128, len(values))
+		for i := range values {
+			valuesWant[i] = values[(i+5)%len(values)]
+		}
+
+		ciphertext = testContext.evaluator.AutomorphismNew(ciphertext, params.GaloisElementForColumnRotation(5), rotKey)
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+	})
+}
+
+func testLinearTransform(testContext *testParams, t *testing.T) {
+
+	params := testContext.params
+	slots := params.Slots()
+
+	rotKey := NewRotationKeys()
+	for _, k := range []uint64{1, 2, slots - 1} {
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, k, rotKey)
+	}
+
+	diagonals := make(map[int][]complex128)
+	for _, k := range []int{0, 1, 2, -1} {
+		diagonals[k] = make([]complex128, slots)
+		for i := range diagonals[k] {
+			diagonals[k][i] = randomComplex(-1, 1)
+		}
+	}
+
+	matrix := testContext.encoder.EncodeDiagMatrixAtLvl(params.MaxLevel(), diagonals, params.Scale(), params.LogSlots())
+
+	require.ElementsMatch(t, []uint64{1, 2, slots - 1}, matrix.Rotations())
+
+	evaluateMatrix := func(values []complex128) (res []complex128) {
+		res = make([]complex128, slots)
+		for k, diagonal := range diagonals {
+			for i := range res {
+				res[i] += diagonal[i] * values[(i+k+int(slots))%int(slots)]
+			}
+		}
+		return
+	}
+
+	t.Run(testString(testContext, "LinearTransform/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		valuesWant := evaluateMatrix(values)
+
+		ciphertext = testContext.evaluator.LinearTransformNew(ciphertext, matrix, rotKey, nil)
+
+		require.NoError(t, testContext.evaluator.Rescale(ciphertext, params.Scale(), ciphertext))
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "LinearTransform/PlaintextBias/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		bias := make([]complex128, slots)
+		for i := range bias {
+			bias[i] = randomComplex(-1, 1)
+		}
+
+		valuesWant := evaluateMatrix(values)
+		for i := range valuesWant {
+			valuesWant[i] += bias[i]
+		}
+
+		// Bias encoded at the scale of the result
+		ptBias := NewPlaintext(params, ciphertext.Level(), ciphertext.Scale()*matrix.Scale)
+		testContext.encoder.EncodeNTT(ptBias, bias, slots)
+
+		testContext.evaluator.LinearTransform(ciphertext, matrix, rotKey, ptBias, ciphertext)
+
+		require.NoError(t, testContext.evaluator.Rescale(ciphertext, params.Scale(), ciphertext))
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
+	})
+
+	t.Run(testString(testContext, "LinearTransform/CiphertextBias/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		bias, _, ctBias := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		valuesWant := evaluateMatrix(values)
+		for i := range valuesWant {
+			valuesWant[i] += bias[i]
 		}
 
-		ciphertext = testContext.evaluator.EvaluateCheby(ciphertext, cheby, testContext.rlk)
+		// Bias at the scale of the input, scaled up by the scale of the matrix during the accumulation
+		ciphertext = testContext.evaluator.LinearTransformNew(ciphertext, matrix, rotKey, ctBias)
 
-		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		require.NoError(t, testContext.evaluator.Rescale(ciphertext, params.Scale(), ciphertext))
+
+		verifyTestVectors(testContext, testContext.decryptor, valuesWant, ciphertext, t)
 	})
 }
 
-func testSwitchKeys(testContext *testParams, t *testing.T) {
+func testDecomposition(testContext *testParams, t *testing.T) {
 
-	sk2 := testContext.kgen.GenSecretKey()
-	decryptorSk2 := NewDecryptor(testContext.params, sk2)
-	switchingKey := testContext.kgen.GenSwitchingKey(testContext.sk, sk2)
+	// newDecompositionContext returns a test context whose parameters are the given ones with the given decomposition.
+	newDecompositionContext := func(t *testing.T, params *Parameters, xalpha []uint64) *testParams {
+		params = params.Copy()
+		require.NoError(t, params.SetDecomposition(xalpha))
+		require.Equal(t, xalpha, params.Decomposition())
 
-	t.Run(testString(testContext, "SwitchKeys/InPlace/"), func(t *testing.T) {
+		data, err := params.MarshalBinary()
+		require.NoError(t, err)
+		paramsNew := new(Parameters)
+		require.NoError(t, paramsNew.UnmarshalBinary(data))
+		require.True(t, params.Equals(paramsNew))
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		decompContext, err := genTestParams(params, 0)
+		require.NoError(t, err)
+		return decompContext
+	}
 
-		testContext.evaluator.SwitchKeys(ciphertext, switchingKey, ciphertext)
+	// testKeySwitching checks the relinearization and the rotations, hoisted or not, at the maximum level
+	// and, if the product of two ciphertexts fits in it, at the level below.
+	testKeySwitching := func(t *testing.T, decompContext *testParams) {
 
-		verifyTestVectors(testContext, decryptorSk2, values, ciphertext, t)
-	})
+		rotKey := decompContext.kgen.GenRotationKeysPow2(decompContext.sk)
 
-	t.Run(testString(testContext, "SwitchKeys/New/"), func(t *testing.T) {
+		levels := []uint64{decompContext.params.MaxLevel()}
+		if decompContext.params.MaxLevel() > 1 {
+			levels = append(levels, decompContext.params.MaxLevel()-1)
+		}
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		for _, level := range levels {
 
-		ciphertext = testContext.evaluator.SwitchKeysNew(ciphertext, switchingKey)
+			values1, _, ciphertext1 := newTestVectors(decompContext, decompContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+			values2, _, ciphertext2 := newTestVectors(decompContext, decompContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
 
-		verifyTestVectors(testContext, decryptorSk2, values, ciphertext, t)
-	})
+			decompContext.evaluator.DropLevel(ciphertext1, ciphertext1.Level()-level)
+			decompContext.evaluator.DropLevel(ciphertext2, ciphertext2.Level()-level)
 
-}
+			for i := range values1 {
+				values2[i] *= values1[i]
+			}
 
-func testConjugate(testContext *testParams, t *testing.T) {
+			verifyTestVectors(decompContext, decompContext.decryptor, values2, decompContext.evaluator.MulRelinNew(ciphertext1, ciphertext2, decompContext.rlk), t)
 
-	rotKey := NewRotationKeys()
-	testContext.kgen.GenRotationKey(Conjugate, testContext.sk, 0, rotKey)
+			rotations := []uint64{1, 2, 4}
+			ciphertexts := decompContext.evaluator.RotateHoisted(ciphertext1, rotations, rotKey)
 
-	t.Run(testString(testContext, "Conjugate/InPlace/"), func(t *testing.T) {
+			for _, n := range rotations {
+				for i := range values2 {
+					values2[i] = values1[(i+int(n))%len(values1)]
+				}
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+				verifyTestVectors(decompContext, decompContext.decryptor, values2, decompContext.evaluator.RotateColumnsNew(ciphertext1, n, rotKey), t)
+				verifyTestVectors(decompContext, decompContext.decryptor, values2, ciphertexts[n], t)
+			}
+		}
+	}
 
-		for i := range values {
-			values[i] = complex(real(values[i]), -imag(values[i]))
+	t.Run(testString(testContext, "Decomposition/Arbitrary/"), func(t *testing.T) {
+
+		if testContext.params.PiCount() == 0 || testContext.params.QiCount() < 2 {
+			t.Skip("#Pi is 0 or #Qi is 1")
 		}
 
-		testContext.evaluator.Conjugate(ciphertext, rotKey, ciphertext)
+		// A first element with a single modulus, followed by elements of #Pi moduli
+		xalpha := []uint64{1}
+		for i := testContext.params.QiCount() - 1; i > 0; i -= utils.MinUint64(i, testContext.params.Alpha()) {
+			xalpha = append(xalpha, utils.MinUint64(i, testContext.params.Alpha()))
+		}
 
-		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		decompContext := newDecompositionContext(t, testContext.params, xalpha)
+
+		require.Equal(t, uint64(len(xalpha)), decompContext.params.Beta())
+		require.Equal(t, uint64(1), decompContext.params.BetaLvl(0))
+		require.Equal(t, uint64(len(xalpha)), decompContext.params.BetaLvl(decompContext.params.MaxLevel()))
+
+		testKeySwitching(t, decompContext)
 	})
 
-	t.Run(testString(testContext, "Conjugate/New/"), func(t *testing.T) {
+	t.Run(testString(testContext, "Decomposition/TopLevels/"), func(t *testing.T) {
 
-		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+		// P is large enough for the key-switching error to remain negligible with elements of more than #Pi moduli
+		params, err := NewParametersFromLogModuli(testContext.params.LogN(), &LogModuli{LogQi: []uint64{45, 35, 35, 35, 35}, LogPi: []uint64{55, 55}})
+		require.NoError(t, err)
+		params.SetScale(1 << 35)
+		params.SetLogSlots(testContext.params.LogSlots())
 
-		for i := range values {
-			values[i] = complex(real(values[i]), -imag(values[i]))
-		}
+		// Merges the two elements of the top levels
+		xalpha := params.Decomposition()
+		require.Equal(t, []uint64{2, 2, 1}, xalpha)
+		xalpha = []uint64{2, 3}
 
-		ciphertext = testContext.evaluator.ConjugateNew(ciphertext, rotKey)
+		decompContext := newDecompositionContext(t, params, xalpha)
 
-		verifyTestVectors(testContext, testContext.decryptor, values, ciphertext, t)
+		require.Less(t, decompContext.params.SwitchingKeySize(), params.SwitchingKeySize())
+		require.Greater(t, decompContext.params.LogQAlpha(), params.LogQAlpha())
+		require.Less(t, decompContext.params.LogQAlpha(), decompContext.params.LogP())
+		require.Equal(t, uint64(1), decompContext.params.BetaLvl(1))
+		require.Equal(t, uint64(2), decompContext.params.BetaLvl(2))
+
+		testKeySwitching(t, decompContext)
 	})
 
+	t.Run(testString(testContext, "Decomposition/Invalid/"), func(t *testing.T) {
+		params := testContext.params.Copy()
+		require.Error(t, params.SetDecomposition([]uint64{params.QiCount() + 1}))
+		require.Error(t, params.SetDecomposition([]uint64{0, params.QiCount()}))
+		require.NoError(t, params.SetDecomposition(nil))
+		require.True(t, params.Equals(testContext.params))
+	})
 }
 
 func testRotateColumns(testContext *testParams, t *testing.T) {
@@ -935,6 +2160,118 @@ func testRotateColumns(testContext *testParams, t *testing.T) {
 		}
 
 	})
+
+	t.Run(testString(testContext, "RotateColumns/Backend/"), func(t *testing.T) {
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		backend := &innerProductCountingBackend{}
+		testContext.evaluator.SetBackend(backend)
+		defer testContext.evaluator.SetBackend(nil)
+
+		values2 := make([]complex128, len(values1))
+		rotations := []uint64{1, 2}
+		for _, n := range rotations {
+			testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, n, rotKey)
+		}
+
+		ciphertexts := testContext.evaluator.RotateHoisted(ciphertext1, rotations, rotKey)
+
+		// Two inner products in Q and two in P per rotation
+		require.Equal(t, 4*len(rotations), backend.calls)
+
+		for _, n := range rotations {
+
+			for i := range values1 {
+				values2[i] = values1[(i+int(n))%len(values1)]
+			}
+
+			verifyTestVectors(testContext, testContext.decryptor, values2, ciphertexts[n], t)
+		}
+	})
+
+	t.Run(testString(testContext, "SwitchKeys/Backend/"), func(t *testing.T) {
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		backend := &innerProductCountingBackend{}
+		testContext.evaluator.SetBackend(backend)
+		defer testContext.evaluator.SetBackend(nil)
+
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 1, rotKey)
+
+		values2 := make([]complex128, len(values1))
+
+		// Two inner products in Q and two in P per key-switching
+		ciphertext2 := testContext.evaluator.MulRelinNew(ciphertext1, ciphertext1, testContext.rlk)
+		require.Equal(t, 4, backend.calls)
+
+		for i := range values1 {
+			values2[i] = values1[i] * values1[i]
+		}
+
+		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
+
+		ciphertext2 = testContext.evaluator.RotateColumnsNew(ciphertext1, 1, rotKey)
+		require.Equal(t, 8, backend.calls)
+
+		for i := range values1 {
+			values2[i] = values1[(i+1)%len(values1)]
+		}
+
+		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
+	})
+
+	t.Run(testString(testContext, "RotateColumns/Composed/"), func(t *testing.T) {
+
+		values1, _, ciphertext1 := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		partialKey := NewRotationKeys()
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 3, partialKey)
+		testContext.kgen.GenRotationKey(RotationRight, testContext.sk, 5, partialKey)
+
+		ok, plan := testContext.evaluator.CanRotate(3, partialKey)
+		require.True(t, ok)
+		require.Equal(t, 1, plan.KeySwitches())
+
+		// 1 = 3 + 3 - 5
+		ok, plan = testContext.evaluator.CanRotate(1, partialKey)
+		require.True(t, ok)
+		require.Equal(t, 3, plan.KeySwitches())
+
+		values2 := make([]complex128, len(values1))
+		for i := range values1 {
+			values2[i] = values1[(i+1)%len(values1)]
+		}
+
+		// The composition is reported through the Logger of the evaluator only
+		var buf, stdBuf bytes.Buffer
+		testContext.evaluator.SetLogger(utils.NewStdLogger(log.New(&buf, "", 0), utils.LogWarn))
+		log.SetOutput(&stdBuf)
+
+		ciphertext2 := testContext.evaluator.RotateColumnsNew(ciphertext1, 1, partialKey)
+
+		log.SetOutput(os.Stderr)
+		testContext.evaluator.SetLogger(nil)
+		require.Contains(t, buf.String(), "WARN ckks: rotation key is missing, composing the available rotations")
+		require.Equal(t, "", stdBuf.String())
+
+		verifyTestVectors(testContext, testContext.decryptor, values2, ciphertext2, t)
+
+		// Only even rotations can be composed from rotations by 2 and -4
+		evenKey := NewRotationKeys()
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 2, evenKey)
+		testContext.kgen.GenRotationKey(RotationRight, testContext.sk, 4, evenKey)
+
+		ok, _ = testContext.evaluator.CanRotate(1, evenKey)
+		require.False(t, ok)
+		require.Panics(t, func() { testContext.evaluator.RotateColumnsNew(ciphertext1, 1, evenKey) })
+
+		// 7 = 4 + 2 + 1 with the pow2 rotations
+		ok, plan = testContext.evaluator.CanRotate(7, rotKey)
+		require.True(t, ok)
+		require.Equal(t, []int{1, 2, 4}, plan.Steps)
+	})
 }
 
 func testMarshaller(testContext *testParams, t *testing.T) {
@@ -1028,6 +2365,27 @@ func testMarshaller(testContext *testParams, t *testing.T) {
 		}
 	})
 
+	t.Run(testString(testContext, "Marshaller/EvaluationKey/HigherDegrees/"), func(t *testing.T) {
+
+		evalKey := testContext.kgen.GenRelinKeys(testContext.sk, 4)
+		data, err := evalKey.MarshalBinary()
+		require.NoError(t, err)
+
+		resEvalKey := new(EvaluationKey)
+		require.NoError(t, resEvalKey.UnmarshalBinary(data))
+		require.Equal(t, evalKey.MaxDegree(), resEvalKey.MaxDegree())
+
+		for degree := uint64(2); degree <= evalKey.MaxDegree(); degree++ {
+			evakeyWant := evalKey.keyOfDegree(degree).evakey
+			evakeyTest := resEvalKey.keyOfDegree(degree).evakey
+			for j := range evakeyWant {
+				for k := range evakeyWant[j] {
+					require.Truef(t, ringQP.Equal(evakeyWant[j][k], evakeyTest[j][k]), "Marshal EvaluationKey of degree %d element [%d][%d]", degree, j, k)
+				}
+			}
+		}
+	})
+
 	t.Run(testString(testContext, "Marshaller/SwitchingKey/"), func(t *testing.T) {
 
 		skOut := testContext.kgen.GenSecretKey()
@@ -1059,6 +2417,7 @@ func testMarshaller(testContext *testParams, t *testing.T) {
 		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 2, rotationKey)
 		testContext.kgen.GenRotationKey(RotationRight, testContext.sk, 3, rotationKey)
 		testContext.kgen.GenRotationKey(RotationRight, testContext.sk, 5, rotationKey)
+		testContext.kgen.GenAutomorphismKey(2*testContext.ringQ.N-5, testContext.sk, rotationKey)
 
 		data, err := rotationKey.MarshalBinary()
 		require.NoError(t, err)
@@ -1120,5 +2479,295 @@ func testMarshaller(testContext *testParams, t *testing.T) {
 				}
 			}
 		}
+
+		for galEl, swk := range rotationKey.evakeyAutomorphism {
+
+			require.NotNil(t, resRotationKey.evakeyAutomorphism[galEl])
+			require.True(t, utils.EqualSliceUint64(rotationKey.permuteNTTAutomorphismIndex[galEl], resRotationKey.permuteNTTAutomorphismIndex[galEl]))
+
+			for j := range swk.evakey {
+				for k := range swk.evakey[j] {
+					require.Truef(t, ringQP.Equal(swk.evakey[j][k], resRotationKey.evakeyAutomorphism[galEl].evakey[j][k]), "Marshal RotationKey Automorphism %d element [%d][%d]", galEl, j, k)
+				}
+			}
+		}
+	})
+
+	t.Run(testString(testContext, "Marshaller/Dataset/"), func(t *testing.T) {
+
+		// Uncompressed, and compressed within the precision budget if any
+		logPrecisionIn := freshLogPrecision(testContext.params, testContext.params.Scale())
+		for _, droppedBits := range []uint64{0, MaxDroppedBits(testContext.params, testContext.params.Scale(), logPrecisionIn, minPrec+1)} {
+
+			valuesWant := make([][]complex128, 3)
+			buff := new(bytes.Buffer)
+
+			header := DatasetHeader{LogSlots: testContext.params.LogSlots(), Packing: PackingColumnMajor, Rows: 3, Columns: testContext.params.Slots(), DroppedBits: droppedBits}
+
+			dw, err := NewDatasetWriter(buff, testContext.params, header)
+			require.NoError(t, err)
+
+			for i := range valuesWant {
+				var ciphertext *Ciphertext
+				valuesWant[i], _, ciphertext = newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+				if droppedBits != 0 {
+					testContext.evaluator.DropLevel(ciphertext, ciphertext.Level())
+				}
+				require.NoError(t, dw.Write(ciphertext))
+			}
+
+			data := buff.Bytes()
+
+			dr, err := NewDatasetReader(bytes.NewReader(data), testContext.params)
+			require.NoError(t, err)
+
+			header.ParamsHash = testContext.params.Hash()
+			require.Equal(t, header, dr.Header())
+
+			var i int
+			for dr.Next() {
+				verifyTestVectors(testContext, testContext.decryptor, valuesWant[i], dr.Ciphertext(), t)
+				i++
+			}
+			require.NoError(t, dr.Err())
+			require.Equal(t, len(valuesWant), i)
+
+			// Truncated dataset
+			dr, err = NewDatasetReader(bytes.NewReader(data[:len(data)-1]), testContext.params)
+			require.NoError(t, err)
+			for dr.Next() {
+			}
+			require.Error(t, dr.Err())
+
+			// Dataset of other parameters
+			params := testContext.params.Copy()
+			params.SetScale(params.Scale() * 2)
+			_, err = NewDatasetReader(bytes.NewReader(data), params)
+			require.Error(t, err)
+		}
+	})
+}
+
+// xorKeyWrapper is a toy KeyWrapper for testing purpose only.
+type xorKeyWrapper byte
+
+func (w xorKeyWrapper) Wrap(plaintext []byte) (ciphertext []byte, err error) {
+	ciphertext = make([]byte, len(plaintext))
+	for i := range plaintext {
+		ciphertext[i] = plaintext[i] ^ byte(w)
+	}
+	return
+}
+
+func (w xorKeyWrapper) Unwrap(ciphertext []byte) (plaintext []byte, err error) {
+	return w.Wrap(ciphertext)
+}
+
+func testKeyStore(testContext *testParams, t *testing.T) {
+
+	ringQP := testContext.ringQP
+
+	t.Run(testString(testContext, "KeyStore/File/"), func(t *testing.T) {
+
+		dir, err := ioutil.TempDir("", "ckks-keystore")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		ks, err := NewFileKeyStore(dir, xorKeyWrapper(0x5a))
+		require.NoError(t, err)
+
+		require.NoError(t, ks.PutSecretKey("alice", testContext.sk))
+		require.NoError(t, ks.PutPublicKey("alice", testContext.pk))
+		require.NoError(t, ks.PutEvaluationKey("alice", testContext.rlk))
+
+		rotKey := NewRotationKeys()
+		testContext.kgen.GenRotationKey(RotationLeft, testContext.sk, 1, rotKey)
+		require.NoError(t, ks.PutRotationKeys("alice", rotKey))
+
+		// The secret key must not be stored in clear
+		raw, err := ioutil.ReadFile(dir + "/alice.sk")
+		require.NoError(t, err)
+		skData, err := testContext.sk.MarshalBinary()
+		require.NoError(t, err)
+		require.NotEqual(t, skData, raw[8:])
+
+		sk, err := ks.GetSecretKey("alice")
+		require.NoError(t, err)
+		require.True(t, ringQP.Equal(sk.sk, testContext.sk.sk))
+
+		pk, err := ks.GetPublicKey("alice")
+		require.NoError(t, err)
+		for k := range pk.pk {
+			require.True(t, ringQP.Equal(pk.pk[k], testContext.pk.pk[k]))
+		}
+
+		rlk, err := ks.GetEvaluationKey("alice")
+		require.NoError(t, err)
+		for j := range rlk.evakey.evakey {
+			for k := range rlk.evakey.evakey[j] {
+				require.True(t, ringQP.Equal(rlk.evakey.evakey[j][k], testContext.rlk.evakey.evakey[j][k]))
+			}
+		}
+
+		rtk, err := ks.GetRotationKeys("alice")
+		require.NoError(t, err)
+		require.True(t, utils.EqualSliceUint64(rtk.permuteNTTLeftIndex[1], rotKey.permuteNTTLeftIndex[1]))
+
+		require.NoError(t, ks.Delete("alice"))
+		_, err = ks.GetPublicKey("alice")
+		require.Equal(t, ErrKeyNotFound, err)
+		require.Equal(t, ErrKeyNotFound, ks.Delete("alice"))
+
+		require.Error(t, ks.PutPublicKey("../alice", testContext.pk))
+
+		ksNoWrapper, err := NewFileKeyStore(dir, nil)
+		require.NoError(t, err)
+		require.Error(t, ksNoWrapper.PutSecretKey("bob", testContext.sk))
+	})
+}
+
+type float64Slice []float64
+
+func (s float64Slice) Len() int {
+	return len(s)
+}
+
+func (s float64Slice) Value(i int) float64 {
+	return s[i]
+}
+
+func testNpy(testContext *testParams, t *testing.T) {
+
+	params := testContext.params
+	slots := params.Slots()
+
+	t.Run(testString(testContext, "Npy/EndToEnd/"), func(t *testing.T) {
+
+		n := slots + slots/2
+
+		values := make([]complex128, n)
+		for i := range values {
+			values[i] = complex(randomFloat(-1, 1), 0)
+		}
+
+		buff := new(bytes.Buffer)
+		npyWriter, err := NewNpyWriter(buff, n, false)
+		require.NoError(t, err)
+		require.NoError(t, npyWriter.Write(values))
+		require.NoError(t, npyWriter.Close())
+		require.Equal(t, 0, (buff.Len()-int(8*n))%64)
+
+		plaintexts := []*Plaintext{}
+		nTest, err := EncodeNpy(params, testContext.encoder, buff, params.MaxLevel(), params.Scale(), func(plaintext *Plaintext) error {
+			plaintexts = append(plaintexts, plaintext.CopyNew().Plaintext())
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, n, nTest)
+		require.Equal(t, 2, len(plaintexts))
+
+		out := new(bytes.Buffer)
+		require.NoError(t, DecodeNpy(params, testContext.encoder, plaintexts, n, true, out))
+
+		npyReader, err := NewNpyReader(out)
+		require.NoError(t, err)
+		require.True(t, npyReader.IsComplex())
+		require.Equal(t, n, npyReader.Len())
+
+		valuesTest := make([]complex128, n+1)
+		read, err := npyReader.Read(valuesTest)
+		require.NoError(t, err)
+		require.Equal(t, int(n), read)
+
+		_, err = npyReader.Read(valuesTest)
+		require.Equal(t, io.EOF, err)
+
+		for i := range values {
+			require.InDelta(t, real(values[i]), real(valuesTest[i]), 1e-5)
+		}
+	})
+
+	t.Run(testString(testContext, "Npy/Float64Array/"), func(t *testing.T) {
+
+		arr := make(float64Slice, slots)
+		for i := range arr {
+			arr[i] = randomFloat(-1, 1)
+		}
+
+		plaintexts := []*Plaintext{}
+		require.NoError(t, EncodeFloat64Array(params, testContext.encoder, arr, params.MaxLevel(), params.Scale(), func(plaintext *Plaintext) error {
+			plaintexts = append(plaintexts, plaintext.CopyNew().Plaintext())
+			return nil
+		}))
+
+		valuesTest := DecodeToFloat64(params, testContext.encoder, plaintexts, slots)
+
+		for i := range arr {
+			require.InDelta(t, arr[i], valuesTest[i], 1e-5)
+		}
+	})
+}
+
+// freshLogPrecision returns an estimate of the log2 of the precision of the slots of a freshly encrypted Ciphertext
+// of the given scale, whose error has a standard deviation of Sigma on each coefficient.
+func freshLogPrecision(params *Parameters, scale float64) float64 {
+	return math.Log2(scale / (params.Sigma() * math.Sqrt(float64(params.N()))))
+}
+
+func testCompression(testContext *testParams, t *testing.T) {
+
+	params := testContext.params
+
+	t.Run(testString(testContext, "Compression/"), func(t *testing.T) {
+
+		values, _, ciphertext := newTestVectors(testContext, testContext.encryptorSk, complex(-1, -1), complex(1, 1), t)
+
+		testContext.evaluator.DropLevel(ciphertext, ciphertext.Level())
+
+		logPrecisionIn := freshLogPrecision(params, ciphertext.Scale())
+
+		// The error of the ciphertext can only lower the precision after the compression
+		require.LessOrEqual(t, MaxDroppedBits(params, ciphertext.Scale(), logPrecisionIn, minPrec+1), MaxDroppedBits(params, ciphertext.Scale(), math.Inf(1), minPrec+1))
+		require.Less(t, CompressionLogPrecision(params, ciphertext.Scale(), logPrecisionIn, 1), logPrecisionIn)
+
+		droppedBits := MaxDroppedBits(params, ciphertext.Scale(), logPrecisionIn, minPrec+1)
+		if droppedBits == 0 {
+			t.Skip("skipping test for params without compression budget")
+		}
+
+		compressed := Compress(params, ciphertext, droppedBits)
+
+		data, err := compressed.MarshalBinary()
+		require.NoError(t, err)
+		require.Less(t, uint64(len(data)), ciphertext.GetDataLen(true))
+
+		compressedTest := new(CompressedCiphertext)
+		require.NoError(t, compressedTest.UnmarshalBinary(data))
+		require.Equal(t, droppedBits, compressedTest.DroppedBits())
+
+		verifyTestVectors(testContext, testContext.decryptor, values, Decompress(params, compressedTest), t)
+
+		// Well-formed encodings of less than two polynomials or of polynomials of degree one are rejected
+		N := params.N()
+		for _, invalid := range []*CompressedCiphertext{
+			{logN: params.LogN(), q: compressedTest.q, droppedBits: droppedBits},
+			{logN: params.LogN(), q: compressedTest.q, droppedBits: droppedBits, value: [][]uint64{make([]uint64, N)}},
+			{logN: 0, q: compressedTest.q, droppedBits: droppedBits, value: [][]uint64{{0}, {0}}},
+		} {
+			data, err = invalid.MarshalBinary()
+			require.NoError(t, err)
+			require.Error(t, new(CompressedCiphertext).UnmarshalBinary(data))
+		}
 	})
 }
+
+// innerProductCountingBackend counts the calls to its key-switching inner product.
+type innerProductCountingBackend struct {
+	ring.CPUBackend
+	calls int
+}
+
+func (b *innerProductCountingBackend) InnerProductMontgomeryLvl(r *ring.Ring, level uint64, p1, p2 []*ring.Poly, p3 *ring.Poly) {
+	b.calls++
+	b.CPUBackend.InnerProductMontgomeryLvl(r, level, p1, p2, p3)
+}
@@ -0,0 +1,260 @@
+package ckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// CompressedCiphertext is a lossy compressed representation of a Ciphertext at level 0, in which the
+// droppedBits least significant bits of each coefficient have been rounded away. It is intended for
+// final results sent to bandwidth-constrained clients, which only need to decrypt them.
+type CompressedCiphertext struct {
+	logN        uint64
+	q           uint64
+	scale       float64
+	droppedBits uint64
+	value       [][]uint64
+}
+
+// CompressionErrorStd returns an estimate of the standard deviation of the error added on the coefficients of the
+// decrypted plaintext by the rounding of droppedBits bits of a Ciphertext, assuming a ternary secret of density 2/3.
+// The rounding error of c0 is uniform in [-2^(b-1), 2^(b-1)] and the one of c1 is multiplied by the secret.
+func CompressionErrorStd(params *Parameters, droppedBits uint64) float64 {
+	if droppedBits == 0 {
+		return 0
+	}
+	return math.Exp2(float64(droppedBits)) / math.Sqrt(12) * math.Sqrt(1+2*float64(params.N())/3)
+}
+
+// CompressionLogPrecision returns an estimate of the log2 of the precision of the slots of a Ciphertext of the given
+// scale after the rounding of droppedBits bits, given the estimate logPrecision of the log2 of the precision of its
+// slots before the rounding. The rounding error is added to the error already present in the Ciphertext, whose
+// standard deviation is taken as scale / 2^logPrecision. logPrecision is math.Inf(1) for a Ciphertext without error,
+// in which case the result is an upper bound on the precision after the rounding.
+func CompressionLogPrecision(params *Parameters, scale, logPrecision float64, droppedBits uint64) float64 {
+	std := CompressionErrorStd(params, droppedBits) * math.Sqrt(float64(params.N()))
+	stdIn := scale / math.Exp2(logPrecision)
+	if std == 0 && stdIn == 0 {
+		return math.Inf(1)
+	}
+	return math.Log2(scale / math.Sqrt(std*std+stdIn*stdIn))
+}
+
+// MaxDroppedBits returns the largest number of bits that can be dropped from a Ciphertext of the given scale and of
+// estimated precision logPrecisionIn (see CompressionLogPrecision) while keeping an estimated precision of at least
+// logPrecision bits on its slots. The result never exceeds the bit-size of the first modulus minus one.
+func MaxDroppedBits(params *Parameters, scale, logPrecisionIn, logPrecision float64) (droppedBits uint64) {
+
+	maxBits := uint64(bits.Len64(params.qi[0])) - 1
+
+	for droppedBits < maxBits && CompressionLogPrecision(params, scale, logPrecisionIn, droppedBits+1) >= logPrecision {
+		droppedBits++
+	}
+
+	return
+}
+
+// Compress returns a CompressedCiphertext of ct, in which the droppedBits least significant bits of each coefficient
+// have been rounded away. Only the first modulus of ct is kept, thus the message must fit in the first modulus;
+// ct is not modified.
+func Compress(params *Parameters, ct *Ciphertext, droppedBits uint64) (cc *CompressedCiphertext) {
+
+	q := params.qi[0]
+
+	if droppedBits >= uint64(bits.Len64(q)) {
+		panic("cannot Compress: droppedBits is larger than the bit-size of the first modulus")
+	}
+
+	ringQ, err := ring.NewRing(params.N(), params.qi[:1])
+	if err != nil {
+		panic(err)
+	}
+
+	cc = &CompressedCiphertext{
+		logN:        params.LogN(),
+		q:           q,
+		scale:       ct.Scale(),
+		droppedBits: droppedBits,
+		value:       make([][]uint64, ct.Degree()+1),
+	}
+
+	tmp := ringQ.NewPoly()
+
+	half := uint64(0)
+	if droppedBits > 0 {
+		half = 1 << (droppedBits - 1)
+	}
+
+	for i := range cc.value {
+
+		if ct.IsNTT() {
+			ringQ.InvNTTLvl(0, ct.Value()[i], tmp)
+		} else {
+			ringQ.CopyLvl(0, ct.Value()[i], tmp)
+		}
+
+		cc.value[i] = make([]uint64, params.N())
+
+		for j, c := range tmp.Coeffs[0] {
+			cc.value[i][j] = (c + half) >> droppedBits
+		}
+	}
+
+	return
+}
+
+// Decompress returns the Ciphertext at level 0 represented by the CompressedCiphertext.
+func Decompress(params *Parameters, cc *CompressedCiphertext) (ct *Ciphertext) {
+
+	if cc.logN != params.LogN() || cc.q != params.qi[0] {
+		panic("cannot Decompress: CompressedCiphertext is not compatible with the parameters")
+	}
+
+	ringQ, err := ring.NewRing(params.N(), params.qi[:1])
+	if err != nil {
+		panic(err)
+	}
+
+	ct = NewCiphertext(params, uint64(len(cc.value)-1), 0, cc.scale)
+
+	for i := range cc.value {
+
+		coeffs := ct.Value()[i].Coeffs[0]
+
+		for j, c := range cc.value[i] {
+			coeffs[j] = (c << cc.droppedBits) % cc.q
+		}
+
+		ringQ.NTTLvl(0, ct.Value()[i], ct.Value()[i])
+	}
+
+	return
+}
+
+// DroppedBits returns the number of least significant bits rounded away from each coefficient.
+func (cc *CompressedCiphertext) DroppedBits() uint64 {
+	return cc.droppedBits
+}
+
+// Scale returns the scale of the CompressedCiphertext.
+func (cc *CompressedCiphertext) Scale() float64 {
+	return cc.scale
+}
+
+// bitsPerCoeff returns the number of bits needed to store a rounded coefficient.
+func (cc *CompressedCiphertext) bitsPerCoeff() uint64 {
+	return uint64(bits.Len64((cc.q - 1 + (1 << cc.droppedBits >> 1)) >> cc.droppedBits))
+}
+
+// GetDataLen returns the length in bytes of the target CompressedCiphertext.
+func (cc *CompressedCiphertext) GetDataLen(WithMetaData bool) (dataLen uint64) {
+
+	if WithMetaData {
+		dataLen += 19
+	}
+
+	N := uint64(1) << cc.logN
+
+	return dataLen + uint64(len(cc.value))*((N*cc.bitsPerCoeff()+7)>>3)
+}
+
+// MarshalBinary encodes a CompressedCiphertext on a byte slice, packing each coefficient on
+// the bit-size of the first modulus minus the number of dropped bits.
+func (cc *CompressedCiphertext) MarshalBinary() (data []byte, err error) {
+
+	data = make([]byte, cc.GetDataLen(true))
+
+	data[0] = uint8(cc.logN)
+	data[1] = uint8(len(cc.value))
+	data[2] = uint8(cc.droppedBits)
+	binary.LittleEndian.PutUint64(data[3:11], cc.q)
+	binary.LittleEndian.PutUint64(data[11:19], math.Float64bits(cc.scale))
+
+	bitsPerCoeff := cc.bitsPerCoeff()
+
+	var pos uint64
+	for _, coeffs := range cc.value {
+		for _, c := range coeffs {
+			writeBits(data[19:], pos, c, bitsPerCoeff)
+			pos += bitsPerCoeff
+		}
+		// Each polynomial starts on a new byte
+		pos = (pos + 7) &^ 7
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled CompressedCiphertext on the target CompressedCiphertext.
+func (cc *CompressedCiphertext) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) < 19 {
+		return errors.New("too small bytearray")
+	}
+
+	cc.logN = uint64(data[0])
+	cc.value = make([][]uint64, data[1])
+	cc.droppedBits = uint64(data[2])
+	cc.q = binary.LittleEndian.Uint64(data[3:11])
+	cc.scale = math.Float64frombits(binary.LittleEndian.Uint64(data[11:19]))
+
+	// A Ciphertext has at least two polynomials, which Decompress relies on
+	if cc.logN == 0 || cc.logN > MaxLogN || len(cc.value) < 2 || cc.q == 0 || cc.droppedBits >= uint64(bits.Len64(cc.q)) {
+		return errors.New("invalid CompressedCiphertext header")
+	}
+
+	if uint64(len(data)) != cc.GetDataLen(true) {
+		return errors.New("invalid CompressedCiphertext length")
+	}
+
+	N := uint64(1) << cc.logN
+	bitsPerCoeff := cc.bitsPerCoeff()
+
+	var pos uint64
+	for i := range cc.value {
+		cc.value[i] = make([]uint64, N)
+		for j := range cc.value[i] {
+			cc.value[i][j] = readBits(data[19:], pos, bitsPerCoeff)
+			pos += bitsPerCoeff
+		}
+		pos = (pos + 7) &^ 7
+	}
+
+	return nil
+}
+
+// writeBits writes the n least significant bits of value in data, starting at the bit position pos.
+func writeBits(data []byte, pos, value, n uint64) {
+	for n > 0 {
+		byteIdx, bitIdx := pos>>3, pos&7
+		w := 8 - bitIdx
+		if w > n {
+			w = n
+		}
+		data[byteIdx] |= byte((value & ((1 << w) - 1)) << bitIdx)
+		value >>= w
+		pos += w
+		n -= w
+	}
+}
+
+// readBits reads n bits from data, starting at the bit position pos.
+func readBits(data []byte, pos, n uint64) (value uint64) {
+	var shift uint64
+	for n > 0 {
+		byteIdx, bitIdx := pos>>3, pos&7
+		w := 8 - bitIdx
+		if w > n {
+			w = n
+		}
+		value |= uint64((data[byteIdx]>>bitIdx)&byte((1<<w)-1)) << shift
+		shift += w
+		pos += w
+		n -= w
+	}
+	return
+}
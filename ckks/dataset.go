@@ -0,0 +1,226 @@
+package ckks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// An encrypted dataset file is made of:
+//
+//  - the 8 bytes of datasetMagic, the last one being the version of the format,
+//  - the length-prefixed DatasetHeader,
+//  - a sequence of length-prefixed Ciphertexts, or CompressedCiphertexts if the header has non-zero DroppedBits,
+//    until the end of the file.
+//
+// The lengths are 8-byte big-endian integers, as written by WriteKey.
+
+const datasetVersion = 1
+
+var datasetMagic = [8]byte{'L', 'T', 'G', 'C', 'K', 'D', 'S', datasetVersion}
+
+// datasetHeaderLen is the length in bytes of a serialized DatasetHeader.
+const datasetHeaderLen = 32 + 1 + 1 + 8 + 8 + 1
+
+// DatasetPacking describes how the values of a table are packed in the slots of the Ciphertexts of a dataset.
+type DatasetPacking uint8
+
+const (
+	// PackingRowMajor packs the values of the table row after row, each Ciphertext holding 2^LogSlots consecutive values.
+	PackingRowMajor DatasetPacking = iota
+	// PackingColumnMajor packs the values of the table column after column, each Ciphertext holding 2^LogSlots consecutive values.
+	PackingColumnMajor
+)
+
+// DatasetHeader is the metadata of an encrypted dataset.
+type DatasetHeader struct {
+	// ParamsHash is the hash of the parameters of the Ciphertexts, see Parameters.Hash. It is set by NewDatasetWriter.
+	ParamsHash [32]byte
+	// LogSlots is the log2 of the number of slots encoded in each Ciphertext.
+	LogSlots uint64
+	// Packing is the layout of the table in the slots.
+	Packing DatasetPacking
+	// Rows and Columns are the dimensions of the table.
+	Rows, Columns uint64
+	// DroppedBits is the number of bits dropped by the compression of the Ciphertexts at level 0 (see Compress),
+	// or zero if the Ciphertexts are stored without compression.
+	DroppedBits uint64
+}
+
+// MarshalBinary encodes the DatasetHeader on a byte slice.
+func (h *DatasetHeader) MarshalBinary() (data []byte, err error) {
+	b := utils.NewBuffer(make([]byte, 0, datasetHeaderLen))
+	b.WriteUint8Slice(h.ParamsHash[:])
+	b.WriteUint8(uint8(h.LogSlots))
+	b.WriteUint8(uint8(h.Packing))
+	b.WriteUint64(h.Rows)
+	b.WriteUint64(h.Columns)
+	b.WriteUint8(uint8(h.DroppedBits))
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a previously marshaled DatasetHeader on the target DatasetHeader.
+func (h *DatasetHeader) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) != datasetHeaderLen {
+		return errors.New("invalid dataset header length")
+	}
+
+	b := utils.NewBuffer(data)
+	b.ReadUint8Slice(h.ParamsHash[:])
+	h.LogSlots = uint64(b.ReadUint8())
+	h.Packing = DatasetPacking(b.ReadUint8())
+	h.Rows = b.ReadUint64()
+	h.Columns = b.ReadUint64()
+	h.DroppedBits = uint64(b.ReadUint8())
+
+	if h.LogSlots > MaxLogN-1 || h.Packing > PackingColumnMajor {
+		return errors.New("invalid dataset header")
+	}
+
+	return nil
+}
+
+// DatasetWriter writes an encrypted dataset.
+type DatasetWriter struct {
+	w      io.Writer
+	params *Parameters
+	header DatasetHeader
+}
+
+// NewDatasetWriter writes the header of an encrypted dataset of Ciphertexts of the given parameters to w and
+// returns a DatasetWriter to write the Ciphertexts. The ParamsHash field of the header is overwritten.
+func NewDatasetWriter(w io.Writer, params *Parameters, header DatasetHeader) (dw *DatasetWriter, err error) {
+
+	if header.LogSlots > params.LogN()-1 {
+		return nil, fmt.Errorf("cannot write dataset: LogSlots must be at most %d", params.LogN()-1)
+	}
+
+	header.ParamsHash = params.Hash()
+
+	if _, err = w.Write(datasetMagic[:]); err != nil {
+		return nil, err
+	}
+
+	if err = writeLengthPrefixed(w, &header); err != nil {
+		return nil, err
+	}
+
+	return &DatasetWriter{w: w, params: params.Copy(), header: header}, nil
+}
+
+// Write appends ct to the dataset, compressing it first if the header has non-zero DroppedBits.
+func (dw *DatasetWriter) Write(ct *Ciphertext) (err error) {
+
+	if dw.header.DroppedBits != 0 {
+		return writeLengthPrefixed(dw.w, Compress(dw.params, ct, dw.header.DroppedBits))
+	}
+
+	return writeLengthPrefixed(dw.w, ct)
+}
+
+// DatasetReader reads an encrypted dataset. Its usage follows the one of bufio.Scanner:
+//
+//	dr, err := NewDatasetReader(r, params)
+//	...
+//	for dr.Next() {
+//		ct := dr.Ciphertext()
+//		...
+//	}
+//	if err := dr.Err(); err != nil {
+//		...
+//	}
+type DatasetReader struct {
+	r      io.Reader
+	params *Parameters
+	header DatasetHeader
+
+	ciphertext *Ciphertext
+	err        error
+}
+
+// NewDatasetReader reads the header of an encrypted dataset from r and returns a DatasetReader to read its Ciphertexts.
+// It returns an error if the dataset was not written with the given parameters.
+func NewDatasetReader(r io.Reader, params *Parameters) (dr *DatasetReader, err error) {
+
+	var magic [8]byte
+	if _, err = io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic[:7], datasetMagic[:7]) {
+		return nil, errors.New("cannot read dataset: invalid magic number")
+	}
+
+	if magic[7] != datasetVersion {
+		return nil, fmt.Errorf("cannot read dataset: unsupported version %d", magic[7])
+	}
+
+	dr = &DatasetReader{r: r, params: params.Copy()}
+
+	if err = readLengthPrefixed(r, &dr.header); err != nil {
+		return nil, err
+	}
+
+	if dr.header.ParamsHash != params.Hash() {
+		return nil, errors.New("cannot read dataset: dataset was written with different parameters")
+	}
+
+	return dr, nil
+}
+
+// Header returns the header of the dataset.
+func (dr *DatasetReader) Header() DatasetHeader {
+	return dr.header
+}
+
+// Next reads the next Ciphertext of the dataset, which is then available through Ciphertext. It returns false when the
+// end of the dataset is reached or an error occurred, in which case the error is returned by Err.
+func (dr *DatasetReader) Next() bool {
+
+	dr.ciphertext = nil
+
+	if dr.err != nil {
+		return false
+	}
+
+	var err error
+	if dr.header.DroppedBits != 0 {
+		cc := new(CompressedCiphertext)
+		if err = readLengthPrefixed(dr.r, cc); err == nil {
+			if cc.logN != dr.params.LogN() || cc.q != dr.params.qi[0] {
+				err = errors.New("cannot read dataset: CompressedCiphertext is not compatible with the parameters")
+			} else {
+				dr.ciphertext = Decompress(dr.params, cc)
+			}
+		}
+	} else {
+		ct := new(Ciphertext)
+		if err = readLengthPrefixed(dr.r, ct); err == nil {
+			dr.ciphertext = ct
+		}
+	}
+
+	if err != nil {
+		if err != io.EOF {
+			dr.err = err
+		}
+		dr.ciphertext = nil
+		return false
+	}
+
+	return true
+}
+
+// Ciphertext returns the Ciphertext read by the last call to Next.
+func (dr *DatasetReader) Ciphertext() *Ciphertext {
+	return dr.ciphertext
+}
+
+// Err returns the first error that was encountered while reading the dataset, if any.
+func (dr *DatasetReader) Err() error {
+	return dr.err
+}
@@ -1,6 +1,9 @@
 package ckks
 
 import (
+	"math"
+	"math/big"
+
 	"github.com/ldsec/lattigo/v2/ring"
 )
 
@@ -14,6 +17,31 @@ type Decryptor interface {
 	// receiver plaintext. A Horner method is used for evaluating the
 	// decryption.
 	Decrypt(ciphertext *Ciphertext, plaintext *Plaintext)
+
+	// DecryptLvlNew decrypts the ciphertext using only its moduli up to the given level
+	// and returns a newly created plaintext at this level. The result is correct as long as
+	// the encoded message is smaller than half the product of those moduli.
+	DecryptLvlNew(level uint64, ciphertext *Ciphertext) (plaintext *Plaintext)
+
+	// DecryptLvl decrypts the ciphertext using only its moduli up to the given level
+	// and returns the result on the provided receiver plaintext. The result is correct as
+	// long as the encoded message is smaller than half the product of those moduli.
+	DecryptLvl(level uint64, ciphertext *Ciphertext, plaintext *Plaintext)
+
+	// DecryptSlots decrypts the ciphertext, in which slots values are encoded, and returns
+	// the values of the slots of the given indices. Only the values of those slots are
+	// decoded, and the decryption is done at the lowest level able to hold messages of
+	// magnitude up to 2^logQ0Headroom, which makes it cheaper than a full decryption and
+	// decoding when checking a few values of a large ciphertext.
+	DecryptSlots(ciphertext *Ciphertext, slots uint64, indices []uint64) (values []complex128)
+
+	// AcceptKeys adds the given fingerprints to the keys whose bound Ciphertexts are decrypted, which initially
+	// contains only the fingerprint of the secret-key. Decrypting a Ciphertext bound to another key panics, which
+	// catches the decryption of a Ciphertext encrypted under a different key set. Unbound Ciphertexts are always
+	// decrypted. The Ciphertexts encrypted with a public-key generated by a KeyGenerator are bound to its secret-key,
+	// so only the fingerprints of the public-keys whose secret-key is unknown, e.g. deserialized or generated
+	// collectively, must be accepted to decrypt the Ciphertexts bound to them.
+	AcceptKeys(fingerprints ...KeyFingerprint)
 }
 
 // decryptor is a structure used to decrypt ciphertext. It stores the secret-key.
@@ -21,6 +49,8 @@ type decryptor struct {
 	params *Parameters
 	ringQ  *ring.Ring
 	sk     *SecretKey
+
+	acceptedKeys []KeyFingerprint // fingerprints of the accepted keys, computed on the first bound Ciphertext
 }
 
 // NewDecryptor instantiates a new Decryptor that will be able to decrypt ciphertexts
@@ -44,6 +74,37 @@ func NewDecryptor(params *Parameters, sk *SecretKey) Decryptor {
 	}
 }
 
+// AcceptKeys adds the given fingerprints to the keys whose bound Ciphertexts are decrypted.
+func (decryptor *decryptor) AcceptKeys(fingerprints ...KeyFingerprint) {
+	decryptor.initAcceptedKeys()
+	decryptor.acceptedKeys = append(decryptor.acceptedKeys, fingerprints...)
+}
+
+func (decryptor *decryptor) initAcceptedKeys() {
+	if decryptor.acceptedKeys == nil {
+		decryptor.acceptedKeys = []KeyFingerprint{decryptor.sk.Fingerprint(decryptor.params)}
+	}
+}
+
+// checkBinding panics if the Ciphertext is bound to a key that is not accepted by the decryptor.
+func (decryptor *decryptor) checkBinding(ciphertext *Ciphertext) {
+
+	fp, bound := ciphertext.KeyFingerprint()
+	if !bound {
+		return
+	}
+
+	decryptor.initAcceptedKeys()
+
+	for _, accepted := range decryptor.acceptedKeys {
+		if fp == accepted {
+			return
+		}
+	}
+
+	panic("cannot Decrypt: Ciphertext is bound to the key " + fp.String() + ", which is not accepted by the Decryptor")
+}
+
 // DecryptNew decrypts the Ciphertext and returns a newly created Plaintext.
 // Horner method is used for evaluating the decryption.
 func (decryptor *decryptor) DecryptNew(ciphertext *Ciphertext) (plaintext *Plaintext) {
@@ -58,14 +119,38 @@ func (decryptor *decryptor) DecryptNew(ciphertext *Ciphertext) (plaintext *Plain
 // Decrypt decrypts the Ciphertext and returns the result on the provided receiver Plaintext.
 // Horner method is used for evaluating the decryption.
 func (decryptor *decryptor) Decrypt(ciphertext *Ciphertext, plaintext *Plaintext) {
+	decryptor.DecryptLvl(ciphertext.Level(), ciphertext, plaintext)
+}
+
+// DecryptLvlNew decrypts the Ciphertext using only its moduli up to the given level and returns a newly created Plaintext.
+func (decryptor *decryptor) DecryptLvlNew(level uint64, ciphertext *Ciphertext) (plaintext *Plaintext) {
 
-	level := ciphertext.Level()
+	plaintext = NewPlaintext(decryptor.params, level, ciphertext.Scale())
+
+	decryptor.DecryptLvl(level, ciphertext, plaintext)
+
+	return plaintext
+}
+
+// DecryptLvl decrypts the Ciphertext using only its moduli up to the given level and returns the result on the provided
+// receiver Plaintext. Horner method is used for evaluating the decryption.
+func (decryptor *decryptor) DecryptLvl(level uint64, ciphertext *Ciphertext, plaintext *Plaintext) {
+
+	if level > ciphertext.Level() {
+		panic("cannot DecryptLvl: level is greater than the level of the ciphertext")
+	}
+
+	if level > plaintext.Level() {
+		panic("cannot DecryptLvl: level is greater than the level of the plaintext")
+	}
+
+	decryptor.checkBinding(ciphertext)
 
 	plaintext.SetScale(ciphertext.Scale())
 
 	decryptor.ringQ.CopyLvl(level, ciphertext.value[ciphertext.Degree()], plaintext.value)
 
-	plaintext.value.Coeffs = plaintext.value.Coeffs[:ciphertext.Level()+1]
+	plaintext.value.Coeffs = plaintext.value.Coeffs[:level+1]
 
 	for i := uint64(ciphertext.Degree()); i > 0; i-- {
 
@@ -81,3 +166,92 @@ func (decryptor *decryptor) Decrypt(ciphertext *Ciphertext, plaintext *Plaintext
 		decryptor.ringQ.ReduceLvl(level, plaintext.value, plaintext.value)
 	}
 }
+
+// DecryptSlots decrypts the Ciphertext and returns the values of the slots of the given indices, evaluating
+// directly the decrypted polynomial at the corresponding roots of unity instead of decoding all the slots.
+func (decryptor *decryptor) DecryptSlots(ciphertext *Ciphertext, slots uint64, indices []uint64) (values []complex128) {
+
+	maxSlots := decryptor.params.N() >> 1
+
+	if slots == 0 || slots > maxSlots || slots&(slots-1) != 0 {
+		panic("cannot DecryptSlots: slots must be a power of two between 1 and N/2")
+	}
+
+	for _, j := range indices {
+		if j >= slots {
+			panic("cannot DecryptSlots: slot index is out of range")
+		}
+	}
+
+	// Lowest level whose modulus exceeds the scale by logQ0Headroom bits
+	level := uint64(0)
+	logQ := math.Log2(float64(decryptor.ringQ.Modulus[0]))
+	for logQ < math.Log2(ciphertext.Scale())+logQ0Headroom && level < ciphertext.Level() {
+		level++
+		logQ += math.Log2(float64(decryptor.ringQ.Modulus[level]))
+	}
+
+	plaintext := decryptor.DecryptLvlNew(level, ciphertext)
+
+	decryptor.ringQ.InvNTTLvl(level, plaintext.value, plaintext.value)
+
+	// Only the coefficients of index multiple of gap carry the encoded values
+	gap := maxSlots / slots
+	coeffs := make([]complex128, slots)
+
+	if level == 0 {
+		q := decryptor.ringQ.Modulus[0]
+		for k := range coeffs {
+			coeffs[k] = complex(centeredFloat(plaintext.value.Coeffs[0][uint64(k)*gap], q), centeredFloat(plaintext.value.Coeffs[0][uint64(k)*gap+maxSlots], q))
+		}
+	} else {
+
+		coeffsBigint := make([]*big.Int, decryptor.params.N())
+		decryptor.ringQ.PolyToBigint(plaintext.value, coeffsBigint)
+
+		Q := big.NewInt(1)
+		for _, qi := range decryptor.ringQ.Modulus[:level+1] {
+			Q.Mul(Q, ring.NewUint(qi))
+		}
+
+		qHalf := new(big.Int).Rsh(Q, 1)
+
+		center := func(c *big.Int) float64 {
+			if c.Cmp(qHalf) >= 0 {
+				c.Sub(c, Q)
+			}
+			return scaleDown(c, 1)
+		}
+
+		for k := range coeffs {
+			coeffs[k] = complex(center(coeffsBigint[uint64(k)*gap]), center(coeffsBigint[uint64(k)*gap+maxSlots]))
+		}
+	}
+
+	// The j-th slot is the evaluation of the encoded polynomial at zeta^(5^j), zeta being a primitive (4*slots)-th root of unity
+	m := slots << 2
+	values = make([]complex128, len(indices))
+
+	for i, j := range indices {
+
+		galEl := ring.ModExp(GaloisGen, j, m)
+
+		var v complex128
+		for k, c := range coeffs {
+			angle := 2 * math.Pi * float64((galEl*uint64(k))&(m-1)) / float64(m)
+			v += c * complex(math.Cos(angle), math.Sin(angle))
+		}
+
+		values[i] = v / complex(ciphertext.Scale(), 0)
+	}
+
+	return
+}
+
+// centeredFloat returns the representative of c modulo q in the interval [-q/2, q/2), as a float64.
+func centeredFloat(c, q uint64) float64 {
+	if c >= q>>1 {
+		return -float64(q - c)
+	}
+	return float64(c)
+}
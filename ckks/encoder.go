@@ -7,6 +7,7 @@ import (
 	"math/big"
 
 	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
 )
 
 // GaloisGen is an integer of order N/2 modulo M and that spans Z_M with the integer -1.
@@ -22,8 +23,11 @@ type Encoder interface {
 	EncodeNTT(plaintext *Plaintext, values []complex128, slots uint64)
 	EncodeNTTNew(values []complex128, slots uint64) (plaintext *Plaintext)
 	Decode(plaintext *Plaintext, slots uint64) (res []complex128)
+	DecodeWithScale(plaintext *Plaintext, scale *big.Float, slots uint64) (res []complex128)
 	EncodeCoeffs(values []float64, plaintext *Plaintext)
 	DecodeCoeffs(plaintext *Plaintext) (res []float64)
+	DecodeCoeffsWithScale(plaintext *Plaintext, scale *big.Float) (res []float64)
+	EncodeDiagMatrixAtLvl(level uint64, diagonals map[int][]complex128, scale float64, logSlots uint64) (matrix *PtDiagMatrix)
 }
 
 // EncoderBigComplex is an interface implenting the encoding algorithms with arbitrary precision.
@@ -198,6 +202,17 @@ func (encoder *encoderComplex128) EncodeCoeffsNTT(values []float64, plaintext *P
 
 // DecodeCoeffs takes as input a plaintext and returns the scaled down coefficient of the plaintext in flaot64
 func (encoder *encoderComplex128) DecodeCoeffs(plaintext *Plaintext) (res []float64) {
+	return encoder.decodeCoeffs(plaintext, nil)
+}
+
+// DecodeCoeffsWithScale takes as input a plaintext and returns its coefficients in float64, scaled down by the
+// arbitrary precision scale instead of the float64 scale of the plaintext. The division is carried out exactly
+// before the conversion to float64, which is useful when the scale tracked by the user is not a power of two.
+func (encoder *encoderComplex128) DecodeCoeffsWithScale(plaintext *Plaintext, scale *big.Float) (res []float64) {
+	return encoder.decodeCoeffs(plaintext, scale)
+}
+
+func (encoder *encoderComplex128) decodeCoeffs(plaintext *Plaintext, scale *big.Float) (res []float64) {
 
 	if plaintext.isNTT {
 		encoder.ringQ.InvNTTLvl(plaintext.Level(), plaintext.value, encoder.polypool)
@@ -229,7 +244,11 @@ func (encoder *encoderComplex128) DecodeCoeffs(plaintext *Plaintext) (res []floa
 				encoder.bigintCoeffs[i].Sub(encoder.bigintCoeffs[i], Q)
 			}
 
-			res[i] = scaleDown(encoder.bigintCoeffs[i], plaintext.scale)
+			if scale == nil {
+				res[i] = scaleDown(encoder.bigintCoeffs[i], plaintext.scale)
+			} else {
+				res[i] = scaleDownBigFloat(encoder.bigintCoeffs[i], scale)
+			}
 		}
 		// We can directly get the coefficients
 	} else {
@@ -239,6 +258,11 @@ func (encoder *encoderComplex128) DecodeCoeffs(plaintext *Plaintext) (res []floa
 
 		for i := range res {
 
+			if scale != nil {
+				res[i] = scaleDownBigFloat(centerUint64(coeffs[i], Q), scale)
+				continue
+			}
+
 			if coeffs[i] >= Q>>1 {
 				res[i] = -float64(Q - coeffs[i])
 			} else {
@@ -254,6 +278,18 @@ func (encoder *encoderComplex128) DecodeCoeffs(plaintext *Plaintext) (res []floa
 
 // Decode decodes the Plaintext values to a slice of complex128 values of size at most N/2.
 func (encoder *encoderComplex128) Decode(plaintext *Plaintext, slots uint64) (res []complex128) {
+	return encoder.decode(plaintext, nil, slots)
+}
+
+// DecodeWithScale decodes the Plaintext values to a slice of complex128 values of size at most N/2, using
+// the arbitrary precision scale instead of the float64 scale of the plaintext. The coefficients are divided
+// exactly by the scale before being converted to float64, which removes the precision loss of the float64
+// division when the scale has drifted away from a power of two.
+func (encoder *encoderComplex128) DecodeWithScale(plaintext *Plaintext, scale *big.Float, slots uint64) (res []complex128) {
+	return encoder.decode(plaintext, scale, slots)
+}
+
+func (encoder *encoderComplex128) decode(plaintext *Plaintext, scale *big.Float, slots uint64) (res []complex128) {
 
 	if plaintext.isNTT {
 		encoder.ringQ.InvNTTLvl(plaintext.Level(), plaintext.value, encoder.polypool)
@@ -292,7 +328,11 @@ func (encoder *encoderComplex128) Decode(plaintext *Plaintext, slots uint64) (re
 				encoder.bigintCoeffs[idx+maxSlots].Sub(encoder.bigintCoeffs[idx+maxSlots], Q)
 			}
 
-			encoder.values[i] = complex(scaleDown(encoder.bigintCoeffs[idx], plaintext.scale), scaleDown(encoder.bigintCoeffs[idx+maxSlots], plaintext.scale))
+			if scale == nil {
+				encoder.values[i] = complex(scaleDown(encoder.bigintCoeffs[idx], plaintext.scale), scaleDown(encoder.bigintCoeffs[idx+maxSlots], plaintext.scale))
+			} else {
+				encoder.values[i] = complex(scaleDownBigFloat(encoder.bigintCoeffs[idx], scale), scaleDownBigFloat(encoder.bigintCoeffs[idx+maxSlots], scale))
+			}
 		}
 		// We can directly get the coefficients
 	} else {
@@ -303,6 +343,11 @@ func (encoder *encoderComplex128) Decode(plaintext *Plaintext, slots uint64) (re
 		var real, imag float64
 		for i, idx := uint64(0), uint64(0); i < slots; i, idx = i+1, idx+gap {
 
+			if scale != nil {
+				encoder.values[i] = complex(scaleDownBigFloat(centerUint64(coeffs[idx], Q), scale), scaleDownBigFloat(centerUint64(coeffs[idx+maxSlots], Q), scale))
+				continue
+			}
+
 			if coeffs[idx] >= Q>>1 {
 				real = -float64(Q - coeffs[idx])
 			} else {
@@ -360,7 +405,7 @@ func (encoder *encoderComplex128) invfft(values []complex128, N uint64) {
 		values[i] /= complex(float64(N), 0)
 	}
 
-	sliceBitReverseInPlaceComplex128(values, N)
+	utils.BitReverseInPlaceComplex128(values, N)
 }
 
 func (encoder *encoderComplex128) fft(values []complex128, N uint64) {
@@ -368,7 +413,7 @@ func (encoder *encoderComplex128) fft(values []complex128, N uint64) {
 	var lenh, lenq, gap, idx uint64
 	var u, v complex128
 
-	sliceBitReverseInPlaceComplex128(values, N)
+	utils.BitReverseInPlaceComplex128(values, N)
 
 	for len := uint64(2); len <= N; len <<= 1 {
 		for i := uint64(0); i < N; i += len {
@@ -1,6 +1,9 @@
 package ckks
 
 import (
+	"crypto/rand"
+	"io"
+
 	"github.com/ldsec/lattigo/v2/ring"
 	"github.com/ldsec/lattigo/v2/utils"
 )
@@ -53,6 +56,27 @@ type Encryptor interface {
 	// zero in Q, using the provided polynomial as the uniform polynomial, and
 	// then adding the plaintext.
 	EncryptFromCRPFast(plaintext *Plaintext, ciphertetx *Ciphertext, crp *ring.Poly)
+
+	// EncryptSeededNew encrypts the input plaintext using the stored secret key and returns
+	// the result on a newly created SeededCiphertext, whose uniform polynomial is generated
+	// from a fresh random seed instead of being stored.
+	EncryptSeededNew(plaintext *Plaintext) *SeededCiphertext
+
+	// EncryptSeeded encrypts the input plaintext using the stored secret key and returns
+	// the result on the receiver SeededCiphertext, whose uniform polynomial is generated
+	// from a fresh random seed instead of being stored.
+	EncryptSeeded(plaintext *Plaintext, ciphertext *SeededCiphertext)
+
+	// EncryptStream reads little-endian float64 values from r, encodes them by chunks of params.Slots()
+	// values (the last chunk being padded with zeros) and encrypts each chunk with the stored key. The
+	// returned CiphertextStream iterates over the resulting Ciphertexts, the reading and encoding of the
+	// next chunk being pipelined with the encryption of the current one.
+	EncryptStream(r io.Reader) *CiphertextStream
+
+	// SetKeyBinding enables or disables the binding of the encrypted Ciphertexts to the key of the Encryptor.
+	// When enabled, the Ciphertexts are stamped with the fingerprint of the key, which is checked by the
+	// Decryptor and propagated by the Evaluator, see Element.KeyFingerprint. It is disabled by default.
+	SetKeyBinding(enabled bool)
 }
 
 // encryptor is a struct used to encrypt Plaintexts. It stores the public-key and/or secret-key.
@@ -65,12 +89,14 @@ type encryptor struct {
 	polypool [3]*ring.Poly
 
 	baseconverter              *ring.FastBasisExtender
-	gaussianSamplerQ           *ring.GaussianSampler
-	uniformSamplerQ            *ring.UniformSampler
-	ternarySamplerMontgomeryQ  *ring.TernarySampler
-	gaussianSamplerQP          *ring.GaussianSampler
-	uniformSamplerQP           *ring.UniformSampler
-	ternarySamplerMontgomeryQP *ring.TernarySampler
+	gaussianSamplerQ           ring.Sampler
+	uniformSamplerQ            ring.Sampler
+	ternarySamplerMontgomeryQ  ring.Sampler
+	gaussianSamplerQP          ring.Sampler
+	uniformSamplerQP           ring.Sampler
+	ternarySamplerMontgomeryQP ring.Sampler
+
+	keyFingerprint *KeyFingerprint // fingerprint stamped on the Ciphertexts, nil if the key binding is disabled
 }
 
 type pkEncryptor struct {
@@ -83,6 +109,11 @@ type skEncryptor struct {
 	sk *SecretKey
 }
 
+type crpEncryptor struct {
+	*skEncryptor
+	crp *ring.Poly
+}
+
 // NewEncryptorFromPk creates a new Encryptor with the provided public-key.
 // This Encryptor can be used to encrypt Plaintexts, using the stored key.
 func NewEncryptorFromPk(params *Parameters, pk *PublicKey) Encryptor {
@@ -107,6 +138,23 @@ func NewEncryptorFromSk(params *Parameters, sk *SecretKey) Encryptor {
 	return &skEncryptor{enc, sk}
 }
 
+// NewEncryptorFromCRP creates a new Encryptor with the provided secret-key, whose ciphertexts have the provided
+// common reference polynomial crp, in the NTT domain, as uniform polynomial. Encrypt and EncryptNew then behave
+// as EncryptFromCRP and EncryptFromCRPNew with this polynomial.
+//
+// Since ciphertexts sharing the same uniform polynomial under the same secret-key reveal the difference of their
+// messages, such an Encryptor must be used to encrypt a single plaintext.
+func NewEncryptorFromCRP(params *Parameters, sk *SecretKey, crp *ring.Poly) Encryptor {
+
+	if uint64(crp.GetDegree()) != params.N() || crp.GetLenModuli() < int(params.QiCount()) {
+		panic("cannot NewEncryptorFromCRP: crp does not match the parameters")
+	}
+
+	enc := NewEncryptorFromSk(params, sk).(*skEncryptor)
+
+	return &crpEncryptor{enc, crp}
+}
+
 func newEncryptor(params *Parameters) encryptor {
 
 	var q, qp *ring.Ring
@@ -142,7 +190,7 @@ func newEncryptor(params *Parameters) encryptor {
 		polypool:                   [3]*ring.Poly{qp.NewPoly(), qp.NewPoly(), qp.NewPoly()},
 		baseconverter:              baseconverter,
 		gaussianSamplerQ:           ring.NewGaussianSampler(prng, q, params.sigma, uint64(6*params.sigma)),
-		uniformSamplerQ:            ring.NewUniformSampler(prng, q),
+		uniformSamplerQ:            ring.NewUniformSamplerWithDomain(prng, q, true, false),
 		ternarySamplerMontgomeryQ:  ring.NewTernarySampler(prng, q, 0.5, true),
 		gaussianSamplerQP:          ring.NewGaussianSampler(prng, qp, params.sigma, uint64(6*params.sigma)),
 		uniformSamplerQP:           ring.NewUniformSampler(prng, qp),
@@ -203,6 +251,26 @@ func (encryptor *pkEncryptor) EncryptFromCRPFastNew(plaintext *Plaintext, crp *r
 	panic("Cannot encrypt with CRP using an encryptor created with the public-key")
 }
 
+func (encryptor *pkEncryptor) EncryptSeededNew(plaintext *Plaintext) *SeededCiphertext {
+	panic("Cannot encrypt with seed using an encryptor created with the public-key")
+}
+
+func (encryptor *pkEncryptor) EncryptSeeded(plaintext *Plaintext, ciphertext *SeededCiphertext) {
+	panic("Cannot encrypt with seed using an encryptor created with the public-key")
+}
+
+func (encryptor *pkEncryptor) EncryptStream(r io.Reader) *CiphertextStream {
+	return newCiphertextStream(encryptor.params, encryptor, r)
+}
+
+func (encryptor *pkEncryptor) SetKeyBinding(enabled bool) {
+	encryptor.keyFingerprint = nil
+	if enabled {
+		fp := encryptor.pk.bindingFingerprint(encryptor.params)
+		encryptor.keyFingerprint = &fp
+	}
+}
+
 // Encrypt encrypts the input Plaintext using the stored key, and returns the result
 // on the receiver Ciphertext.
 //
@@ -212,11 +280,12 @@ func (encryptor *pkEncryptor) encrypt(plaintext *Plaintext, ciphertext *Cipherte
 
 	// We sample a R-WLE instance (encryption of zero) over the extended ring (ciphertext ring + special prime)
 
-	ringQ := encryptor.ringQ
+	level := plaintext.Level()
 
-	if fast {
+	// The operations on the ciphertext are at the level of the plaintext
+	ringQ := encryptor.ringQ.AtLevel(level)
 
-		level := encryptor.params.QiCount() - 1
+	if fast {
 
 		encryptor.ternarySamplerMontgomeryQ.Read(encryptor.polypool[2])
 		ringQ.NTT(encryptor.polypool[2], encryptor.polypool[2])
@@ -252,7 +321,7 @@ func (encryptor *pkEncryptor) encrypt(plaintext *Plaintext, ciphertext *Cipherte
 
 		ringQP := encryptor.ringQP
 
-		level := uint64(len(ringQP.Modulus) - 1)
+		levelQP := uint64(len(ringQP.Modulus) - 1)
 
 		encryptor.ternarySamplerMontgomeryQP.Read(encryptor.polypool[2])
 		ringQP.NTT(encryptor.polypool[2], encryptor.polypool[2])
@@ -267,15 +336,15 @@ func (encryptor *pkEncryptor) encrypt(plaintext *Plaintext, ciphertext *Cipherte
 		ringQP.InvNTT(encryptor.polypool[1], encryptor.polypool[1])
 
 		// ct0 = u*pk0 + e0
-		encryptor.gaussianSamplerQP.ReadAndAddLvl(level, encryptor.polypool[0])
+		encryptor.gaussianSamplerQP.ReadAndAddLvl(levelQP, encryptor.polypool[0])
 		// ct1 = u*pk1 + e1
-		encryptor.gaussianSamplerQP.ReadAndAddLvl(level, encryptor.polypool[1])
+		encryptor.gaussianSamplerQP.ReadAndAddLvl(levelQP, encryptor.polypool[1])
 
 		// ct0 = (u*pk0 + e0)/P
-		encryptor.baseconverter.ModDownPQ(plaintext.Level(), encryptor.polypool[0], ciphertext.value[0])
+		encryptor.baseconverter.ModDownQPtoQ(level, encryptor.polypool[0], ciphertext.value[0])
 
 		// ct1 = (u*pk1 + e1)/P
-		encryptor.baseconverter.ModDownPQ(plaintext.Level(), encryptor.polypool[1], ciphertext.value[1])
+		encryptor.baseconverter.ModDownQPtoQ(level, encryptor.polypool[1], ciphertext.value[1])
 
 		if !plaintext.isNTT {
 			ringQ.Add(ciphertext.value[0], plaintext.value, ciphertext.value[0])
@@ -292,6 +361,7 @@ func (encryptor *pkEncryptor) encrypt(plaintext *Plaintext, ciphertext *Cipherte
 	}
 
 	ciphertext.isNTT = true
+	ciphertext.keyFingerprint = encryptor.keyFingerprint
 }
 
 func (encryptor *skEncryptor) EncryptNew(plaintext *Plaintext) *Ciphertext {
@@ -336,6 +406,44 @@ func (encryptor *skEncryptor) EncryptFromCRPFast(plaintext *Plaintext, ciphertex
 
 }
 
+func (encryptor *skEncryptor) EncryptStream(r io.Reader) *CiphertextStream {
+	return newCiphertextStream(encryptor.params, encryptor, r)
+}
+
+func (encryptor *skEncryptor) EncryptSeededNew(plaintext *Plaintext) *SeededCiphertext {
+	ciphertext := NewSeededCiphertext(encryptor.params, plaintext.Level(), plaintext.Scale())
+	encryptor.EncryptSeeded(plaintext, ciphertext)
+	return ciphertext
+}
+
+func (encryptor *skEncryptor) EncryptSeeded(plaintext *Plaintext, ciphertext *SeededCiphertext) {
+
+	if len(ciphertext.seed) != SeedSize {
+		ciphertext.seed = make([]byte, SeedSize)
+	}
+
+	if _, err := rand.Read(ciphertext.seed); err != nil {
+		panic("crypto rand error")
+	}
+
+	crp := encryptor.polypool[1]
+	sampleSeededCRP(encryptor.ringQ, ciphertext.seed, ciphertext.Level(), crp)
+
+	ct := &Ciphertext{&Element{value: []*ring.Poly{ciphertext.value, crp}}}
+	encryptor.encrypt(plaintext, ct, crp)
+
+	ciphertext.scale = plaintext.Scale()
+	ciphertext.isNTT = true
+}
+
+func (encryptor *skEncryptor) SetKeyBinding(enabled bool) {
+	encryptor.keyFingerprint = nil
+	if enabled {
+		fp := encryptor.sk.Fingerprint(encryptor.params)
+		encryptor.keyFingerprint = &fp
+	}
+}
+
 func (encryptor *skEncryptor) encryptSample(plaintext *Plaintext, ciphertext *Ciphertext) {
 	encryptor.uniformSamplerQ.Read(ciphertext.value[1])
 	encryptor.encrypt(plaintext, ciphertext, ciphertext.value[1])
@@ -368,4 +476,33 @@ func (encryptor *skEncryptor) encrypt(plaintext *Plaintext, ciphertext *Cipherte
 	}
 
 	ciphertext.isNTT = true
+	ciphertext.keyFingerprint = encryptor.keyFingerprint
+}
+
+func (encryptor *crpEncryptor) EncryptNew(plaintext *Plaintext) *Ciphertext {
+	return encryptor.EncryptFromCRPNew(plaintext, encryptor.crp)
+}
+
+func (encryptor *crpEncryptor) Encrypt(plaintext *Plaintext, ciphertext *Ciphertext) {
+	encryptor.EncryptFromCRP(plaintext, ciphertext, encryptor.crp)
+}
+
+func (encryptor *crpEncryptor) EncryptFastNew(plaintext *Plaintext) *Ciphertext {
+	panic("Cannot encrypt fast using an encryptor created with a CRP -> use instead EncryptNew()")
+}
+
+func (encryptor *crpEncryptor) EncryptFast(plaintext *Plaintext, ciphertext *Ciphertext) {
+	panic("Cannot encrypt fast using an encryptor created with a CRP -> use instead Encrypt()")
+}
+
+func (encryptor *crpEncryptor) EncryptSeededNew(plaintext *Plaintext) *SeededCiphertext {
+	panic("Cannot encrypt with seed using an encryptor created with a CRP")
+}
+
+func (encryptor *crpEncryptor) EncryptSeeded(plaintext *Plaintext, ciphertext *SeededCiphertext) {
+	panic("Cannot encrypt with seed using an encryptor created with a CRP")
+}
+
+func (encryptor *crpEncryptor) EncryptStream(r io.Reader) *CiphertextStream {
+	panic("Cannot encrypt a stream using an encryptor created with a CRP")
 }
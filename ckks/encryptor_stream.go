@@ -0,0 +1,157 @@
+package ckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+// CiphertextStream is an iterator over the Ciphertexts produced by Encryptor.EncryptStream.
+// Its usage follows the one of bufio.Scanner:
+//
+//	stream := encryptor.EncryptStream(r)
+//	for stream.Next() {
+//		ciphertext := stream.Ciphertext()
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+//
+// The values are read and encoded by a background goroutine while the previous chunk is being
+// encrypted. The plaintexts and the output Ciphertext are allocated once, when the stream is created, and
+// reused for all the chunks. A CiphertextStream must not be used concurrently by several goroutines.
+type CiphertextStream struct {
+	encryptor Encryptor
+
+	encoded chan *Plaintext // encoded chunks, ready for encryption
+	free    chan *Plaintext // plaintexts available for the encoding of the next chunks
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	buffer     *Ciphertext // output of the encryptions, returned by Ciphertext until the end of the stream
+	ciphertext *Ciphertext
+	errRead    error // set by the reading goroutine before encoded is closed
+	err        error
+}
+
+// streamBuffers is the number of plaintexts in flight between the reading goroutine and the encryption.
+const streamBuffers = 2
+
+func newCiphertextStream(params *Parameters, encryptor Encryptor, r io.Reader) (stream *CiphertextStream) {
+
+	stream = &CiphertextStream{
+		encryptor: encryptor,
+		encoded:   make(chan *Plaintext, streamBuffers),
+		free:      make(chan *Plaintext, streamBuffers),
+		done:      make(chan struct{}),
+		buffer:    NewCiphertext(params, 1, params.MaxLevel(), params.Scale()),
+	}
+
+	for i := 0; i < streamBuffers; i++ {
+		stream.free <- NewPlaintext(params, params.MaxLevel(), params.Scale())
+	}
+
+	go stream.read(params, r)
+
+	return
+}
+
+// read reads the values from r by chunks of params.Slots() little-endian float64, encodes them and sends the
+// resulting plaintexts on stream.encoded, until r is exhausted, an error occurs or the stream is closed.
+func (stream *CiphertextStream) read(params *Parameters, r io.Reader) {
+
+	defer close(stream.encoded)
+
+	encoder := NewEncoder(params)
+
+	slots := params.Slots()
+	buff := make([]byte, 8*slots)
+	values := make([]complex128, slots)
+
+	for {
+
+		var plaintext *Plaintext
+		select {
+		case plaintext = <-stream.free:
+		case <-stream.done:
+			return
+		}
+
+		n, err := io.ReadFull(r, buff)
+
+		if err == io.EOF {
+			return
+		}
+
+		if err != nil && err != io.ErrUnexpectedEOF {
+			stream.errRead = err
+			return
+		}
+
+		if n&7 != 0 {
+			stream.errRead = errors.New("cannot EncryptStream: stream length is not a multiple of 8 bytes")
+			return
+		}
+
+		// The last chunk is padded with zeros
+		for i := range values {
+			if i < n>>3 {
+				values[i] = complex(math.Float64frombits(binary.LittleEndian.Uint64(buff[i<<3:])), 0)
+			} else {
+				values[i] = 0
+			}
+		}
+
+		encoder.EncodeNTT(plaintext, values, slots)
+
+		select {
+		case stream.encoded <- plaintext:
+		case <-stream.done:
+			return
+		}
+
+		if err == io.ErrUnexpectedEOF {
+			return
+		}
+	}
+}
+
+// Next encrypts the next chunk of values in the Ciphertext of the stream, overwriting the previous chunk. It returns
+// false when the end of the input is reached or an error occurred, in which case the error is returned by Err.
+func (stream *CiphertextStream) Next() bool {
+
+	plaintext, ok := <-stream.encoded
+
+	if !ok {
+		stream.ciphertext = nil
+		stream.err = stream.errRead
+		return false
+	}
+
+	stream.encryptor.Encrypt(plaintext, stream.buffer)
+	stream.ciphertext = stream.buffer
+
+	stream.free <- plaintext
+
+	return true
+}
+
+// Ciphertext returns the Ciphertext produced by the last call to Next. The same Ciphertext is overwritten by each call
+// to Next, so the caller must copy it to retain it.
+func (stream *CiphertextStream) Ciphertext() *Ciphertext {
+	return stream.ciphertext
+}
+
+// Err returns the first error that was encountered while reading the input, if any.
+func (stream *CiphertextStream) Err() error {
+	return stream.err
+}
+
+// Close stops the reading of the input. It needs only be called if the stream is not read until Next returns false.
+func (stream *CiphertextStream) Close() {
+	stream.closeOnce.Do(func() { close(stream.done) })
+}
@@ -3,6 +3,7 @@ package ckks
 import (
 	"errors"
 	"math"
+	"math/big"
 	"unsafe"
 
 	"github.com/ldsec/lattigo/v2/ring"
@@ -33,6 +34,11 @@ type Evaluator interface {
 	ScaleUpNew(ct0 *Ciphertext, scale float64) (ctOut *Ciphertext)
 	ScaleUp(ct0 *Ciphertext, scale float64, ctOut *Ciphertext)
 	SetScale(ct *Ciphertext, scale float64)
+	ScalePolicy() ScalePolicy
+	SetRerandomizer(encryptor Encryptor)
+	SetLogger(logger utils.Logger)
+	SetBackend(backend ring.Backend)
+	SetWorkerPool(pool *ring.WorkerPool)
 	MulByPow2New(ct0 *Ciphertext, pow2 uint64) (ctOut *Ciphertext)
 	MulByPow2(ct0 *Element, pow2 uint64, ctOut *Element)
 	ReduceNew(ct0 *Ciphertext) (ctOut *Ciphertext)
@@ -43,15 +49,26 @@ type Evaluator interface {
 	RescaleMany(ct0 *Ciphertext, nbRescales uint64, c1 *Ciphertext) (err error)
 	MulRelinNew(op0, op1 Operand, evakey *EvaluationKey) (ctOut *Ciphertext)
 	MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *Ciphertext)
+	Precompute(op Operand) (opOut *PrecomputedOperand)
+	Batch(f func(b *BatchEvaluator) error) (err error)
+	MulRelinBalancedNew(ct0, ct1 *Ciphertext, evakey *EvaluationKey) (ctOut *Ciphertext)
+	MulRelinBalanced(ct0, ct1 *Ciphertext, evakey *EvaluationKey, ctOut *Ciphertext)
 	RelinearizeNew(ct0 *Ciphertext, evakey *EvaluationKey) (ctOut *Ciphertext)
 	Relinearize(ct0 *Ciphertext, evakey *EvaluationKey, ctOut *Ciphertext)
 	SwitchKeysNew(ct0 *Ciphertext, switchingKey *SwitchingKey) (ctOut *Ciphertext)
 	SwitchKeys(ct0 *Ciphertext, switchingKey *SwitchingKey, ctOut *Ciphertext)
 	RotateColumnsNew(ct0 *Ciphertext, k uint64, evakey *RotationKeys) (ctOut *Ciphertext)
 	RotateColumns(ct0 *Ciphertext, k uint64, evakey *RotationKeys, ctOut *Ciphertext)
+	CanRotate(k uint64, evakey *RotationKeys) (ok bool, plan *RotationPlan)
 	RotateHoisted(ctIn *Ciphertext, rotations []uint64, rotkeys *RotationKeys) (cOut map[uint64]*Ciphertext)
 	ConjugateNew(ct0 *Ciphertext, evakey *RotationKeys) (ctOut *Ciphertext)
 	Conjugate(ct0 *Ciphertext, evakey *RotationKeys, ctOut *Ciphertext)
+	SplitComplex(ct0 *Ciphertext, evakey *RotationKeys) (ctRe, ctIm *Ciphertext)
+	MergeComplex(ctRe, ctIm *Ciphertext) (ctOut *Ciphertext)
+	AutomorphismNew(ct0 *Ciphertext, galEl uint64, evakey *RotationKeys) (ctOut *Ciphertext)
+	Automorphism(ct0 *Ciphertext, galEl uint64, evakey *RotationKeys, ctOut *Ciphertext)
+	LinearTransformNew(ct0 *Ciphertext, matrix *PtDiagMatrix, rotkeys *RotationKeys, bias Operand) (ctOut *Ciphertext)
+	LinearTransform(ct0 *Ciphertext, matrix *PtDiagMatrix, rotkeys *RotationKeys, bias Operand, ctOut *Ciphertext)
 	PowerOf2(el0 *Ciphertext, logPow2 uint64, evakey *EvaluationKey, elOut *Ciphertext)
 	PowerNew(op *Ciphertext, degree uint64, evakey *EvaluationKey) (opOut *Ciphertext)
 	Power(ct0 *Ciphertext, degree uint64, evakey *EvaluationKey, res *Ciphertext)
@@ -64,8 +81,9 @@ type Evaluator interface {
 // evaluator is a struct that holds the necessary elements to execute the homomorphic operations between Ciphertexts and/or Plaintexts.
 // It also holds a small memory pool used to store intermediate computations.
 type evaluator struct {
-	params *Parameters
-	scale  float64
+	params    *Parameters
+	scale     float64
+	threshold float64
 
 	ringQ    *ring.Ring
 	ringP    *ring.Ring
@@ -77,8 +95,15 @@ type evaluator struct {
 	// TODO use the other pools
 	ctxpool *Ciphertext // Memory pool for ciphertext that need to be scaled up (to be removed eventually)
 
+	// The pools in Q and the ctxpool are allocated at level 0 and grown on demand by growPools, so that an Evaluator
+	// used at the end of the modulus chain only allocates the moduli it uses
+
 	baseconverter *ring.FastBasisExtender
 	decomposer    *ring.Decomposer
+
+	rerandomizer Encryptor // Encryptor of the re-randomization of the transparent outputs (nil if disabled)
+
+	logger utils.Logger
 }
 
 // NewEvaluator creates a new Evaluator, that can be used to do homomorphic
@@ -103,7 +128,9 @@ func NewEvaluator(params *Parameters) Evaluator {
 	var poolP [3]*ring.Poly
 	if params.PiCount() != 0 {
 		baseconverter = ring.NewFastBasisExtender(q, p)
-		decomposer = ring.NewDecomposer(q.Modulus, p.Modulus)
+		if decomposer, err = ring.NewArbitraryDecomposer(q.Modulus, p.Modulus, params.Decomposition()); err != nil {
+			panic(err)
+		}
 		poolP = [3]*ring.Poly{p.NewPoly(), p.NewPoly(), p.NewPoly()}
 	}
 
@@ -112,13 +139,133 @@ func NewEvaluator(params *Parameters) Evaluator {
 		scale:         params.scale,
 		ringQ:         q,
 		ringP:         p,
-		poolQMul:      [3]*ring.Poly{q.NewPoly(), q.NewPoly(), q.NewPoly()},
-		poolQ:         [4]*ring.Poly{q.NewPoly(), q.NewPoly(), q.NewPoly(), q.NewPoly()},
+		poolQMul:      [3]*ring.Poly{q.NewPolyLvl(0), q.NewPolyLvl(0), q.NewPolyLvl(0)},
+		poolQ:         [4]*ring.Poly{q.NewPolyLvl(0), q.NewPolyLvl(0), q.NewPolyLvl(0), q.NewPolyLvl(0)},
 		poolP:         poolP,
-		ctxpool:       NewCiphertext(params, 1, params.MaxLevel(), params.scale),
+		ctxpool:       NewCiphertext(params, 1, 0, params.scale),
 		baseconverter: baseconverter,
 		decomposer:    decomposer,
+		logger:        utils.NopLogger{},
+	}
+}
+
+// growPools grows the memory pools in Q of the Evaluator up to the given level, see ring.Ring.GrowLvl.
+func (eval *evaluator) growPools(level uint64) {
+
+	for _, pool := range eval.poolQMul {
+		eval.ringQ.GrowLvl(level, pool)
+	}
+
+	for _, pool := range eval.poolQ {
+		eval.ringQ.GrowLvl(level, pool)
+	}
+
+	for _, pool := range eval.ctxpool.value {
+		eval.ringQ.GrowLvl(level, pool)
+	}
+}
+
+// ScalePolicy defines how an Evaluator manages the scale of the Ciphertexts in the operations that rescale on their own,
+// that is the polynomial evaluations, the powers, the inverse and MulRelinBalanced.
+type ScalePolicy struct {
+	// DefaultScale is the reference scale of the Evaluator. It is the target scale of the result of MulRelinBalanced
+	// and of the polynomial evaluations.
+	DefaultScale float64
+	// RescaleThreshold is the threshold given to Rescale by the operations that rescale their intermediate products:
+	// a Ciphertext is divided by the next moduli as long as its scale stays larger than or equal to it.
+	// If zero, DefaultScale is used.
+	RescaleThreshold float64
+}
+
+// DefaultScalePolicy returns the ScalePolicy of the Evaluators created with NewEvaluator, whose default scale and
+// rescale threshold are the scale of the parameters.
+func DefaultScalePolicy(params *Parameters) ScalePolicy {
+	return ScalePolicy{DefaultScale: params.scale, RescaleThreshold: params.scale}
+}
+
+// NewEvaluatorWithScalePolicy creates a new Evaluator as NewEvaluator, but managing the scales according to the given
+// ScalePolicy instead of the scale of the parameters.
+func NewEvaluatorWithScalePolicy(params *Parameters, policy ScalePolicy) Evaluator {
+
+	if policy.DefaultScale <= 0 || policy.RescaleThreshold < 0 {
+		panic("cannot NewEvaluatorWithScalePolicy: the default scale must be positive and the rescale threshold non-negative")
+	}
+
+	eval := NewEvaluator(params).(*evaluator)
+	eval.scale = policy.DefaultScale
+	eval.threshold = policy.RescaleThreshold
+	return eval
+}
+
+// ScalePolicy returns the ScalePolicy of the Evaluator, with the rescale threshold resolved.
+func (eval *evaluator) ScalePolicy() ScalePolicy {
+	return ScalePolicy{DefaultScale: eval.scale, RescaleThreshold: eval.rescaleThreshold()}
+}
+
+// rescaleThreshold returns the threshold used by the operations that rescale on their own.
+func (eval *evaluator) rescaleThreshold() float64 {
+	if eval.threshold != 0 {
+		return eval.threshold
+	}
+	return eval.scale
+}
+
+// SetLogger sets the Logger on which the Evaluator reports its warnings, such as the addition of operands whose scales
+// are not integer multiples of each other or the composition of a rotation whose key is missing. A nil logger
+// restores the default NopLogger.
+func (eval *evaluator) SetLogger(logger utils.Logger) {
+	if logger == nil {
+		logger = utils.NopLogger{}
 	}
+	eval.logger = logger
+}
+
+// SetBackend sets the ring.Backend on which the Evaluator computes the NTTs, the coefficient-wise Montgomery products and
+// the inner products of the key-switching. A nil backend restores the ring.CPUBackend.
+func (eval *evaluator) SetBackend(backend ring.Backend) {
+	eval.ringQ.SetBackend(backend)
+	if eval.ringP != nil {
+		eval.ringP.SetBackend(backend)
+	}
+}
+
+// SetWorkerPool sets the ring.WorkerPool on which the Evaluator computes its NTTs in parallel across the moduli, which
+// includes those of the key-switching, relinearization and rotations, and of the rescaling. A nil pool restores the
+// sequential computation.
+func (eval *evaluator) SetWorkerPool(pool *ring.WorkerPool) {
+	eval.ringQ.SetWorkerPool(pool)
+	if eval.ringP != nil {
+		eval.ringP.SetWorkerPool(pool)
+	}
+}
+
+// SetRerandomizer enables the re-randomization of the transparent outputs of Add, Sub, MultByConst, MultByConstAndAdd
+// and MulRelin (and of their variants), see Ciphertext.IsTransparent: before returning, the Evaluator checks if the
+// output is transparent and if so adds to it a fresh encryption of zero generated with the encryptor, which should
+// be created from the public key. This check costs a pass over the output. A nil encryptor disables the
+// re-randomization, which is the default.
+func (eval *evaluator) SetRerandomizer(encryptor Encryptor) {
+	eval.rerandomizer = encryptor
+}
+
+// rerandomizeIfTransparent adds an encryption of zero to ctOut if it is transparent and the re-randomization is enabled.
+func (eval *evaluator) rerandomizeIfTransparent(ctOut *Ciphertext) {
+
+	if eval.rerandomizer == nil || ctOut.Degree() == 0 || !ctOut.IsTransparent() {
+		return
+	}
+
+	level := ctOut.Level()
+
+	zero := eval.rerandomizer.EncryptNew(NewPlaintext(eval.params, level, ctOut.Scale()))
+
+	if !ctOut.IsNTT() {
+		eval.ringQ.InvNTTLvl(level, zero.value[0], zero.value[0])
+		eval.ringQ.InvNTTLvl(level, zero.value[1], zero.value[1])
+	}
+
+	eval.ringQ.AddLvl(level, ctOut.value[0], zero.value[0], ctOut.value[0])
+	eval.ringQ.AddLvl(level, ctOut.value[1], zero.value[1], ctOut.value[1])
 }
 
 func (eval *evaluator) getElemAndCheckBinary(op0, op1, opOut Operand, opOutMinDegree uint64) (el0, el1, elOut *Element) {
@@ -134,6 +281,7 @@ func (eval *evaluator) getElemAndCheckBinary(op0, op1, opOut Operand, opOutMinDe
 		panic("receiver operand degree is too small")
 	}
 	el0, el1, elOut = op0.El(), op1.El(), opOut.El()
+	elOut.keyFingerprint = bindingOf(el0, el1)
 	return // TODO: more checks on elements
 }
 
@@ -150,6 +298,7 @@ func (eval *evaluator) getElemAndCheckUnary(op0, opOut Operand, opOutMinDegree u
 		panic("receiver operand degree is too small")
 	}
 	el0, elOut = op0.El(), opOut.El()
+	elOut.keyFingerprint = el0.keyFingerprint
 	return // TODO: more checks on elements
 }
 
@@ -166,12 +315,14 @@ func (eval *evaluator) newCiphertextBinary(op0, op1 Operand) (ctOut *Ciphertext)
 func (eval *evaluator) Add(op0, op1 Operand, ctOut *Ciphertext) {
 	el0, el1, elOut := eval.getElemAndCheckBinary(op0, op1, ctOut, utils.MaxUint64(op0.Degree(), op1.Degree()))
 	eval.evaluateInPlace(el0, el1, elOut, eval.ringQ.AddLvl)
+	eval.rerandomizeIfTransparent(ctOut)
 }
 
 // AddNoMod adds op0 to op1 and returns the result in ctOut, without modular reduction.
 func (eval *evaluator) AddNoMod(op0, op1 Operand, ctOut *Ciphertext) {
 	el0, el1, elOut := eval.getElemAndCheckBinary(op0, op1, ctOut, utils.MaxUint64(op0.Degree(), op1.Degree()))
 	eval.evaluateInPlace(el0, el1, elOut, eval.ringQ.AddNoModLvl)
+	eval.rerandomizeIfTransparent(ctOut)
 }
 
 // AddNew adds op0 to op1 and returns the result in a newly created element.
@@ -202,7 +353,7 @@ func (eval *evaluator) Sub(op0, op1 Operand, ctOut *Ciphertext) {
 			eval.ringQ.NegLvl(level, elOut.Value()[i], elOut.Value()[i])
 		}
 	}
-
+	eval.rerandomizeIfTransparent(ctOut)
 }
 
 // SubNoMod subtracts op1 from op0 and returns the result in ctOut, without modular reduction.
@@ -219,7 +370,7 @@ func (eval *evaluator) SubNoMod(op0, op1 Operand, ctOut *Ciphertext) {
 			eval.ringQ.NegLvl(level, elOut.Value()[i], elOut.Value()[i])
 		}
 	}
-
+	eval.rerandomizeIfTransparent(ctOut)
 }
 
 // SubNew subtracts op1 from op0 and returns the result in a newly created element.
@@ -242,6 +393,15 @@ func (eval *evaluator) evaluateInPlace(c0, c1, ctOut *Element, evaluate func(uin
 
 	level := utils.MinUint64(utils.MinUint64(c0.Level(), c1.Level()), ctOut.Level())
 
+	eval.growPools(level)
+
+	// The operand of smaller scale is multiplied by the integer part of the ratio of the scales
+	if c0.Scale() != c1.Scale() {
+		if ratio := math.Max(c0.Scale(), c1.Scale()) / math.Min(c0.Scale(), c1.Scale()); ratio != math.Floor(ratio) {
+			eval.logger.Debug("ckks: the scales of the operands are not integer multiples of each other, the result is imprecise", "scale0", c0.Scale(), "scale1", c1.Scale())
+		}
+	}
+
 	maxDegree := utils.MaxUint64(c0.Degree(), c1.Degree())
 	minDegree := utils.MinUint64(c0.Degree(), c1.Degree())
 
@@ -484,6 +644,8 @@ func (eval *evaluator) AddConst(ct0 *Ciphertext, constant interface{}, ctOut *Ci
 // The scale of the receiver element will be set to the scale that the input element would have after the multiplication by the constant.
 func (eval *evaluator) MultByConstAndAdd(ct0 *Ciphertext, constant interface{}, ctOut *Ciphertext) {
 
+	defer eval.rerandomizeIfTransparent(ctOut)
+
 	var level uint64
 
 	level = utils.MinUint64(ct0.Level(), ctOut.Level())
@@ -678,6 +840,8 @@ func (eval *evaluator) MultByConstNew(ct0 *Ciphertext, constant interface{}) (ct
 // needs to be scaled (its rational part is not zero)). The constant can be a uint64, int64, float64 or complex128.
 func (eval *evaluator) MultByConst(ct0 *Ciphertext, constant interface{}, ctOut *Ciphertext) {
 
+	defer eval.rerandomizeIfTransparent(ctOut)
+
 	var level uint64
 
 	level = utils.MinUint64(ct0.Level(), ctOut.Level())
@@ -1144,10 +1308,6 @@ func (eval *evaluator) ScaleUp(ct0 *Ciphertext, scale float64, ctOut *Ciphertext
 // SetScale sets the scale of the ciphertext to the input scale (consumes a level)
 func (eval *evaluator) SetScale(ct *Ciphertext, scale float64) {
 
-	var tmp = eval.params.scale
-
-	eval.scale = scale
-
 	eval.MultByConst(ct, scale/ct.Scale(), ct)
 
 	if err := eval.Rescale(ct, scale, ct); err != nil {
@@ -1155,8 +1315,6 @@ func (eval *evaluator) SetScale(ct *Ciphertext, scale float64) {
 	}
 
 	ct.SetScale(scale)
-
-	eval.scale = tmp
 }
 
 // MulByPow2New multiplies ct0 by 2^pow2 and returns the result in a newly created element.
@@ -1312,7 +1470,8 @@ func (eval *evaluator) RescaleMany(ct0 *Ciphertext, nbRescales uint64, ctOut *Ci
 // the multiplication between the scales of the input elements (addition when the scale is represented in log2). An evaluation
 // key can be provided to apply a relinearization step to reduce the degree of the output element. This evaluation key is only
 // required when the two input elements are Ciphertexts. If no evaluation key is provided and the input elements are two Ciphertexts,
-// the resulting Ciphertext will be of degree two. This function only accepts Plaintexts (degree zero) and/or Ciphertexts of degree one.
+// the resulting Ciphertext will be of degree the sum of their degrees. Ciphertexts of degree larger than one can only be multiplied
+// if no key is provided or if the key was generated by GenRelinKeys for a degree at least the one of the product.
 func (eval *evaluator) MulRelinNew(op0, op1 Operand, evakey *EvaluationKey) (ctOut *Ciphertext) {
 	ctOut = NewCiphertext(eval.params, 1, utils.MinUint64(op0.Level(), op1.Level()), op0.Scale()+op1.Scale())
 	eval.MulRelin(op0, op1, evakey, ctOut)
@@ -1324,10 +1483,17 @@ func (eval *evaluator) MulRelinNew(op0, op1 Operand, evakey *EvaluationKey) (ctO
 // the multiplication between the scales of the input elements (addition when the scale is represented in log2). An evaluation
 // key can be provided to apply a relinearization step to reduce the degree of the output element. This evaluation key is only
 // required when the two input elements are Ciphertexts. If no evaluation key is provided and the input elements are two Ciphertexts,
-// the resulting Ciphertext will be of degree two. This function only accepts Plaintexts (degree zero) and/or Ciphertexts of degree one.
+// the resulting Ciphertext will be of degree the sum of their degrees. Ciphertexts of degree larger than one can only be multiplied
+// if no key is provided or if the key was generated by GenRelinKeys for a degree at least the one of the product.
 func (eval *evaluator) MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *Ciphertext) {
 
-	el0, el1, elOut := eval.getElemAndCheckBinary(op0, op1, ctOut, utils.MaxUint64(op0.Degree(), op1.Degree()))
+	el0, el1, elOut := eval.getElemAndCheckBinary(op0, op1, ctOut, 1)
+
+	if evakey != nil {
+		checkKeyBinding(elOut, evakey.evakey)
+	}
+
+	defer eval.rerandomizeIfTransparent(ctOut)
 
 	level := utils.MinUint64(utils.MinUint64(el0.Level(), el1.Level()), elOut.Level())
 
@@ -1335,10 +1501,6 @@ func (eval *evaluator) MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *
 		eval.DropLevel(elOut.Ciphertext(), elOut.Level()-level)
 	}
 
-	if el0.Degree() > 1 || el1.Degree() > 1 {
-		panic("cannot MulRelin: input elements must be of degree 0 or 1")
-	}
-
 	if !el0.IsNTT() {
 		panic("cannot MulRelin: op0 must be in NTT")
 	}
@@ -1347,8 +1509,15 @@ func (eval *evaluator) MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *
 		panic("cannot MulRelin: op1 must be in NTT")
 	}
 
+	if el0.Degree() > 1 || el1.Degree() > 1 {
+		eval.mulRelinHighDegree(level, op0, op1, el0, el1, evakey, elOut)
+		return
+	}
+
 	elOut.SetScale(el0.Scale() * el1.Scale())
 
+	eval.growPools(level)
+
 	ringQ := eval.ringQ
 
 	var c00, c01, c0, c1, c2 *ring.Poly
@@ -1371,14 +1540,15 @@ func (eval *evaluator) MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *
 
 		// Avoid overwritting if the second input is the output
 		var tmp0, tmp1 *Element
+		var op Operand
 		if el1 == elOut {
-			tmp0, tmp1 = el1, el0
+			tmp0, tmp1, op = el1, el0, op1
 		} else {
-			tmp0, tmp1 = el0, el1
+			tmp0, tmp1, op = el0, el1, op0
 		}
 
-		ringQ.MFormLvl(level, tmp0.value[0], c00)
-		ringQ.MFormLvl(level, tmp0.value[1], c01)
+		c00 = valueMFormLvl(ringQ, level, op, tmp0, 0, c00)
+		c01 = valueMFormLvl(ringQ, level, op, tmp0, 1, c01)
 
 		if el0 == el1 { // squaring case
 			ringQ.MulCoeffsMontgomeryLvl(level, c00, tmp1.value[0], c0) // c0 = c[0]*c[0]
@@ -1404,21 +1574,193 @@ func (eval *evaluator) MulRelin(op0, op1 Operand, evakey *EvaluationKey, ctOut *
 	} else {
 
 		var tmp0, tmp1 *Element
+		var op Operand
 
 		if el0.Degree() == 1 {
-			tmp0, tmp1 = el1, el0
+			tmp0, tmp1, op = el1, el0, op1
 		} else {
-			tmp0, tmp1 = el0, el1
+			tmp0, tmp1, op = el0, el1, op0
 		}
 
-		c00 := eval.poolQMul[0]
-
-		ringQ.MFormLvl(level, tmp0.value[0], c00)
+		c00 := valueMFormLvl(ringQ, level, op, tmp0, 0, eval.poolQMul[0])
 		ringQ.MulCoeffsMontgomeryLvl(level, c00, tmp1.value[0], elOut.value[0])
 		ringQ.MulCoeffsMontgomeryLvl(level, c00, tmp1.value[1], elOut.value[1])
 	}
 }
 
+// mulRelinHighDegree is MulRelin for operands of degree larger than one: it computes the tensor product of el0 and el1,
+// of degree el0.Degree() + el1.Degree(), and relinearizes it if evakey is not nil.
+func (eval *evaluator) mulRelinHighDegree(level uint64, op0, op1 Operand, el0, el1 *Element, evakey *EvaluationKey, elOut *Element) {
+
+	eval.growPools(level)
+
+	ringQ := eval.ringQ
+
+	degree := el0.Degree() + el1.Degree()
+
+	if evakey != nil && degree > evakey.MaxDegree() {
+		panic("cannot MulRelin: the degree of the product is larger than the maximum degree of the evaluation key")
+	}
+
+	// The receiver can be one of the operands, so the product is computed in new polynomials
+	res := make([]*ring.Poly, degree+1)
+	for i := range res {
+		res[i] = ringQ.GetPolyLvl(level)
+		res[i].Zero()
+	}
+
+	for i := range el0.value {
+		c := valueMFormLvl(ringQ, level, op0, el0, i, eval.poolQMul[0])
+		for j := range el1.value {
+			ringQ.MulCoeffsMontgomeryAndAddLvl(level, c, el1.value[j], res[i+j])
+		}
+	}
+
+	elOut.SetScale(el0.Scale() * el1.Scale())
+
+	if evakey != nil {
+		eval.relinearize(level, res[0], res[1], res[2:], evakey, res[0], res[1])
+		for _, p := range res[2:] {
+			ringQ.PutPoly(p)
+		}
+		res = res[:2]
+	}
+
+	elOut.value = res
+}
+
+// relinearize switches the components c[i] of degree i = 2, 3, ... of a Ciphertext (c0, c1, c...) to the key s with the
+// keys of evakey, and adds them to c0 and c1, returning the result in out0 and out1.
+func (eval *evaluator) relinearize(level uint64, c0, c1 *ring.Poly, c []*ring.Poly, evakey *EvaluationKey, out0, out1 *ring.Poly) {
+
+	eval.growPools(level)
+
+	ringQ := eval.ringQ
+
+	for i := len(c) - 1; i >= 0; i-- {
+
+		eval.switchKeysInPlace(level, c[i], evakey.keyOfDegree(uint64(i+2)), eval.poolQ[1], eval.poolQ[2])
+
+		ringQ.AddLvl(level, c0, eval.poolQ[1], out0)
+		ringQ.AddLvl(level, c1, eval.poolQ[2], out1)
+
+		c0, c1 = out0, out1
+	}
+}
+
+// Precompute returns a PrecomputedOperand caching the Montgomery form of the polynomials of op, which must be in the NTT domain.
+// The returned operand can be used in place of op in MulRelin, which then skips the conversion of op to the Montgomery form.
+// This is worth it for operands multiplied many times, such as the encoded weights of a model reused across inferences.
+func (eval *evaluator) Precompute(op Operand) (opOut *PrecomputedOperand) {
+
+	if pre, ok := op.(*PrecomputedOperand); ok {
+		return pre
+	}
+
+	el := op.El()
+
+	if !el.IsNTT() {
+		panic("cannot Precompute: operand must be in NTT")
+	}
+
+	opOut = &PrecomputedOperand{Element: el, valueMForm: make([]*ring.Poly, len(el.value))}
+
+	for i := range el.value {
+		opOut.valueMForm[i] = eval.ringQ.NewPolyLvl(el.Level())
+		eval.ringQ.MFormLvl(el.Level(), el.value[i], opOut.valueMForm[i])
+	}
+
+	return
+}
+
+// MulRelinBalancedNew multiplies ct0 by ct1, relinearizes and rescales the result, and returns it in a newly created
+// Ciphertext whose scale is exactly the default scale of the Evaluator. See MulRelinBalanced.
+func (eval *evaluator) MulRelinBalancedNew(ct0, ct1 *Ciphertext, evakey *EvaluationKey) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, 1, utils.MinUint64(ct0.Level(), ct1.Level()), eval.scale)
+	eval.MulRelinBalanced(ct0, ct1, evakey, ctOut)
+	return
+}
+
+// MulRelinBalanced multiplies ct0 by ct1, relinearizes and rescales the result, and returns it in ctOut. The inputs
+// can have different scales: ct0 is first multiplied by a compensation constant chosen such that the scale of the
+// output after the rescaling is exactly the default scale of the Evaluator. If the compensation constant is an
+// integer, the output is one level below the inputs, else the compensation consumes an additional level.
+func (eval *evaluator) MulRelinBalanced(ct0, ct1 *Ciphertext, evakey *EvaluationKey, ctOut *Ciphertext) {
+
+	level := utils.MinUint64(ct0.Level(), ct1.Level())
+
+	if level == 0 {
+		panic("cannot MulRelinBalanced: inputs must be at least at level 1")
+	}
+
+	ringQ := eval.ringQ
+
+	prec := uint(256)
+	inScale := new(big.Float).SetPrec(prec).SetFloat64(ct0.Scale())
+	inScale.Mul(inScale, new(big.Float).SetPrec(prec).SetFloat64(ct1.Scale()))
+
+	// compensation returns target * q_i / (scale0 * scale1) for the modulus q_i of the given level.
+	compensation := func(lvl uint64) *big.Float {
+		c := new(big.Float).SetPrec(prec).SetFloat64(eval.scale)
+		c.Mul(c, new(big.Float).SetPrec(prec).SetUint64(ringQ.Modulus[lvl]))
+		return c.Quo(c, inScale)
+	}
+
+	tmp := NewCiphertext(eval.params, 1, level, ct0.Scale())
+	tmp.Copy(ct0.El())
+
+	constant := new(big.Int)
+
+	if c := compensation(level); c.IsInt() {
+
+		// The compensation is exact without consuming a level.
+		c.Int(constant)
+
+		for i := range tmp.Value() {
+			ringQ.MulScalarBigintLvl(level, tmp.Value()[i], constant, tmp.Value()[i])
+		}
+
+	} else {
+
+		if level < 2 {
+			panic("cannot MulRelinBalanced: inputs must be at least at level 2 to compensate a non-integer scale ratio")
+		}
+
+		// Scales the compensation constant by q_level, which is then removed by the rescaling,
+		// so that the product has scale target * q_{level-1}.
+		c = compensation(level - 1)
+		c.Mul(c, new(big.Float).SetPrec(prec).SetUint64(ringQ.Modulus[level]))
+		c.Add(c, new(big.Float).SetFloat64(0.5))
+		c.Int(constant)
+
+		for i := range tmp.Value() {
+			ringQ.MulScalarBigintLvl(level, tmp.Value()[i], constant, tmp.Value()[i])
+		}
+
+		for i := range tmp.Value() {
+			ringQ.DivRoundByLastModulusNTT(tmp.Value()[i])
+		}
+
+		level--
+	}
+
+	if ctOut.Level() < level {
+		panic("cannot MulRelinBalanced: receiver Ciphertext level is too low")
+	}
+
+	if ctOut.Level() > level {
+		eval.DropLevel(ctOut, ctOut.Level()-level)
+	}
+
+	eval.MulRelin(tmp, ct1, evakey, ctOut)
+
+	for i := range ctOut.Value() {
+		ringQ.DivRoundByLastModulusNTT(ctOut.Value()[i])
+	}
+
+	ctOut.SetScale(eval.scale)
+}
+
 // RelinearizeNew applies the relinearization procedure on ct0 and returns the result in a newly
 // created Ciphertext. The input Ciphertext must be of degree two.
 func (eval *evaluator) RelinearizeNew(ct0 *Ciphertext, evakey *EvaluationKey) (ctOut *Ciphertext) {
@@ -1427,23 +1769,24 @@ func (eval *evaluator) RelinearizeNew(ct0 *Ciphertext, evakey *EvaluationKey) (c
 	return
 }
 
-// Relinearize applies the relinearization procedure on ct0 and returns the result in ctOut. The input Ciphertext must be of degree two.
+// Relinearize applies the relinearization procedure on ct0 and returns the result in ctOut. The input Ciphertext must be of degree
+// two, or of degree at most evakey.MaxDegree() for keys generated with GenRelinKeys.
 func (eval *evaluator) Relinearize(ct0 *Ciphertext, evakey *EvaluationKey, ctOut *Ciphertext) {
-	if ct0.Degree() != 2 {
-		panic("cannot Relinearize: input Ciphertext is not of degree 2")
+	if ct0.Degree() < 2 || ct0.Degree() > evakey.MaxDegree() {
+		panic("cannot Relinearize: input Ciphertext must be of degree between 2 and the maximum degree of the evaluation key")
 	}
 
+	checkKeyBinding(ct0.Element, evakey.evakey)
+
 	if ctOut != ct0 {
 		ctOut.SetScale(ct0.Scale())
 	}
 
 	level := utils.MinUint64(ct0.Level(), ctOut.Level())
-	ringQ := eval.ringQ
 
-	eval.switchKeysInPlace(level, ct0.value[2], evakey.evakey, eval.poolQ[1], eval.poolQ[2])
+	eval.relinearize(level, ct0.value[0], ct0.value[1], ct0.value[2:], evakey, ctOut.value[0], ctOut.value[1])
 
-	ringQ.AddLvl(level, ct0.value[0], eval.poolQ[1], ctOut.value[0])
-	ringQ.AddLvl(level, ct0.value[1], eval.poolQ[2], ctOut.value[1])
+	ctOut.keyFingerprint = ct0.keyFingerprint
 
 	ctOut.Resize(eval.params, 1)
 }
@@ -1466,13 +1809,25 @@ func (eval *evaluator) SwitchKeys(ct0 *Ciphertext, switchingKey *SwitchingKey, c
 		panic("cannot SwitchKeys: input and output Ciphertext must be of degree 1")
 	}
 
+	checkKeyBinding(ct0.Element, switchingKey)
+
+	// The result is bound to the output key of the switching key, if it is known
+	var binding *KeyFingerprint
+	if ct0.keyFingerprint != nil {
+		binding = switchingKey.bindingOut
+	}
+
 	level := utils.MinUint64(ct0.Level(), ctOut.Level())
 	ringQ := eval.ringQ
 
+	eval.growPools(level)
+
 	eval.switchKeysInPlace(level, ct0.value[1], switchingKey, eval.poolQ[1], eval.poolQ[2])
 
 	ringQ.AddLvl(level, ct0.value[0], eval.poolQ[1], ctOut.value[0])
 	ringQ.CopyLvl(level, eval.poolQ[2], ctOut.value[1])
+
+	ctOut.keyFingerprint = binding
 }
 
 // RotateColumnsNew rotates the columns of ct0 by k positions to the left, and returns the result in a newly created element.
@@ -1485,6 +1840,8 @@ func (eval *evaluator) RotateColumnsNew(ct0 *Ciphertext, k uint64, evakey *Rotat
 
 // RotateColumns rotates the columns of ct0 by k positions to the left and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
+// If this key is missing, the rotation is decomposed into the pow2 rotations if all of them are available, and otherwise into the
+// shortest sequence of available rotations, with a warning (see CanRotate). It panics if no such decomposition exists.
 func (eval *evaluator) RotateColumns(ct0 *Ciphertext, k uint64, evakey *RotationKeys, ctOut *Ciphertext) {
 
 	if ct0.Degree() != 1 || ctOut.Degree() != 1 {
@@ -1502,23 +1859,15 @@ func (eval *evaluator) RotateColumns(ct0 *Ciphertext, k uint64, evakey *Rotation
 		ctOut.SetScale(ct0.Scale())
 
 		// It checks in the RotationKeys if the corresponding rotation has been generated
-		if evakey.evakeyRotColLeft[k] != nil {
+		if index, swk := evakey.rotationKey(k); swk != nil {
 
-			eval.permuteNTT(ct0, evakey.permuteNTTLeftIndex[k], evakey.evakeyRotColLeft[k], ctOut)
+			eval.permuteNTT(ct0, index, swk, ctOut)
 
 		} else {
 
-			// If not, it checks if the left and right pow2 rotations have been generated
-			hasPow2Rotations := true
-			for i := uint64(1); i < eval.ringQ.N>>1; i <<= 1 {
-				if evakey.evakeyRotColLeft[i] == nil || evakey.evakeyRotColRight[i] == nil {
-					hasPow2Rotations = false
-					break
-				}
-			}
-
+			// If not, it checks if the left and right pow2 rotations have been generated.
 			// If yes, it computes the least amount of rotation between left and right required to apply the demanded rotation
-			if hasPow2Rotations {
+			if hasPow2Rotations(eval.ringQ.N>>1, evakey) {
 
 				if utils.HammingWeight64(k) <= utils.HammingWeight64((eval.ringQ.N>>1)-k) {
 					eval.rotateColumnsLPow2(ct0, k, evakey, ctOut)
@@ -1526,9 +1875,18 @@ func (eval *evaluator) RotateColumns(ct0 *Ciphertext, k uint64, evakey *Rotation
 					eval.rotateColumnsRPow2(ct0, (eval.ringQ.N>>1)-k, evakey, ctOut)
 				}
 
-				// Otherwise, it returns an error indicating that the keys have not been generated
+				// Otherwise, it composes the available rotations if possible
 			} else {
-				panic("cannot RotateColumns: specific rotation and pow2 rotations have not been generated")
+
+				plan := findRotationPlan(eval.ringQ.N>>1, k, evakey)
+
+				if plan == nil {
+					panic("cannot RotateColumns: specific rotation has not been generated and cannot be composed from the available rotations")
+				}
+
+				eval.logger.Warn("ckks: rotation key is missing, composing the available rotations", "k", k, "plan", plan)
+
+				eval.rotateColumnsWithPlan(ct0, plan, evakey, ctOut)
 			}
 		}
 	}
@@ -1582,19 +1940,89 @@ func (eval *evaluator) Conjugate(ct0 *Ciphertext, evakey *RotationKeys, ctOut *C
 		panic("cannot Conjugate: input and output Ciphertext must be of degree 1")
 	}
 
-	if evakey.evakeyConjugate == nil {
+	index, swk := evakey.conjugationKey()
+	if swk == nil {
 		panic("cannot Conjugate: rows rotation key not generated")
 	}
 
 	ctOut.SetScale(ct0.Scale())
 
-	eval.permuteNTT(ct0, evakey.permuteNTTConjugateIndex, evakey.evakeyConjugate, ctOut)
+	eval.permuteNTT(ct0, index, swk, ctOut)
+}
+
+// SplitComplex splits ct0 into two newly created ciphertexts ctRe and ctIm encrypting respectively the real and the
+// imaginary parts of its slots, both on the real part of their slots. The conjugation of ct0 is computed once and shared
+// by the two outputs, which requires the rotation key of the conjugation. The division by two is done by doubling the scale,
+// hence ctRe and ctIm are at the level of ct0 with twice its scale.
+func (eval *evaluator) SplitComplex(ct0 *Ciphertext, evakey *RotationKeys) (ctRe, ctIm *Ciphertext) {
+
+	conj := eval.ConjugateNew(ct0, evakey)
+
+	// Re(z) = (z + conj(z))/2
+	ctRe = eval.AddNew(ct0, conj)
+	ctRe.SetScale(2 * ct0.Scale())
+
+	// Im(z) = (z - conj(z))/2i
+	ctIm = eval.SubNew(ct0, conj)
+	eval.DivByi(ctIm, ctIm)
+	ctIm.SetScale(2 * ct0.Scale())
+
+	return
+}
+
+// MergeComplex returns a newly created ciphertext encrypting ctRe + i*ctIm, which is the inverse of SplitComplex if
+// ctRe and ctIm encrypt real values. It does not require any key and does not consume any level.
+func (eval *evaluator) MergeComplex(ctRe, ctIm *Ciphertext) (ctOut *Ciphertext) {
+	ctOut = eval.MultByiNew(ctIm)
+	eval.Add(ctOut, ctRe, ctOut)
+	return
+}
+
+// AutomorphismNew applies the automorphism X -> X^galEl on ct0 and returns the result in a newly created element.
+// The key of the automorphism must have been generated with KeyGenerator.GenAutomorphismKey, or be the key of the
+// corresponding rotation to the left or conjugation.
+func (eval *evaluator) AutomorphismNew(ct0 *Ciphertext, galEl uint64, evakey *RotationKeys) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, ct0.Degree(), ct0.Level(), ct0.Scale())
+	eval.Automorphism(ct0, galEl, evakey, ctOut)
+	return
+}
+
+// Automorphism applies the automorphism X -> X^galEl on ct0 and returns the result in ctOut.
+// The key of the automorphism must have been generated with KeyGenerator.GenAutomorphismKey, or be the key of the
+// corresponding rotation to the left or conjugation.
+func (eval *evaluator) Automorphism(ct0 *Ciphertext, galEl uint64, evakey *RotationKeys, ctOut *Ciphertext) {
+
+	if ct0.Degree() != 1 || ctOut.Degree() != 1 {
+		panic("cannot Automorphism: input and output Ciphertext must be of degree 1")
+	}
+
+	galEl &= (eval.ringQ.N << 1) - 1
+
+	if galEl == 1 {
+		if ct0 != ctOut {
+			ctOut.Copy(ct0.El())
+		}
+		return
+	}
+
+	index, swk := evakey.automorphismKey(eval.params, galEl)
+	if swk == nil {
+		panic("cannot Automorphism: key not generated")
+	}
+
+	ctOut.SetScale(ct0.Scale())
+
+	eval.permuteNTT(ct0, index, swk, ctOut)
 }
 
 func (eval *evaluator) permuteNTT(ct0 *Ciphertext, index []uint64, rotKeys *SwitchingKey, ctOut *Ciphertext) {
 
+	checkKeyBinding(ct0.Element, rotKeys)
+
 	level := utils.MinUint64(ct0.Level(), ctOut.Level())
 
+	eval.growPools(level)
+
 	pool2Q := eval.poolQ[1]
 	pool3Q := eval.poolQ[2]
 
@@ -1604,72 +2032,54 @@ func (eval *evaluator) permuteNTT(ct0 *Ciphertext, index []uint64, rotKeys *Swit
 
 	ring.PermuteNTTWithIndexLvl(level, pool2Q, index, ctOut.value[0])
 	ring.PermuteNTTWithIndexLvl(level, pool3Q, index, ctOut.value[1])
+
+	ctOut.keyFingerprint = ct0.keyFingerprint
 }
 
 func (eval *evaluator) switchKeysInPlaceNoModDown(level uint64, cx *ring.Poly, evakey *SwitchingKey, pool2Q, pool2P, pool3Q, pool3P *ring.Poly) {
-	var reduce uint64
 
 	ringQ := eval.ringQ
 	ringP := eval.ringP
 
-	// Pointers allocation
-	c2QiQ := eval.poolQ[0]
-	c2QiP := eval.poolP[0]
+	eval.growPools(level)
 
 	c2 := eval.poolQ[3]
 
-	evakey0Q := new(ring.Poly)
-	evakey1Q := new(ring.Poly)
-	evakey0P := new(ring.Poly)
-	evakey1P := new(ring.Poly)
-
 	// We switch the element on which the switching key operation will be conducted out of the NTT domain
-
 	ringQ.InvNTTLvl(level, cx, c2)
 
-	reduce = 0
+	beta := eval.params.BetaLvl(level)
 
-	alpha := eval.params.Alpha()
-	beta := uint64(math.Ceil(float64(level+1) / float64(alpha)))
+	c2QiQDecomp := make([]*ring.Poly, beta)
+	c2QiPDecomp := make([]*ring.Poly, beta)
 
-	// Key switching with CRT decomposition for the Qi
+	// The whole CRT decomposition is computed first, so that the products with the key are a single inner product
+	// evaluated by the ring.Backend
 	for i := uint64(0); i < beta; i++ {
+		c2QiQDecomp[i] = ringQ.GetPolyLvl(level)
+		c2QiPDecomp[i] = ringP.GetPoly()
+		eval.decomposeAndSplitNTT(level, i, cx, c2, c2QiQDecomp[i], c2QiPDecomp[i])
+	}
 
-		eval.decomposeAndSplitNTT(level, i, cx, c2, c2QiQ, c2QiP)
+	eval.keyswitchHoistedNoModDown(level, c2QiQDecomp, c2QiPDecomp, evakey, pool2Q, pool3Q, pool2P, pool3P)
 
-		evakey0Q.Coeffs = evakey.evakey[i][0].Coeffs[:level+1]
-		evakey1Q.Coeffs = evakey.evakey[i][1].Coeffs[:level+1]
-		evakey0P.Coeffs = evakey.evakey[i][0].Coeffs[len(ringQ.Modulus):]
-		evakey1P.Coeffs = evakey.evakey[i][1].Coeffs[len(ringQ.Modulus):]
+	for i := range c2QiQDecomp {
+		ringQ.PutPoly(c2QiQDecomp[i])
+		ringP.PutPoly(c2QiPDecomp[i])
+	}
+}
 
-		if i == 0 {
-			ringQ.MulCoeffsMontgomeryLvl(level, evakey0Q, c2QiQ, pool2Q)
-			ringQ.MulCoeffsMontgomeryLvl(level, evakey1Q, c2QiQ, pool3Q)
-			ringP.MulCoeffsMontgomery(evakey0P, c2QiP, pool2P)
-			ringP.MulCoeffsMontgomery(evakey1P, c2QiP, pool3P)
-		} else {
-			ringQ.MulCoeffsMontgomeryAndAddNoModLvl(level, evakey0Q, c2QiQ, pool2Q)
-			ringQ.MulCoeffsMontgomeryAndAddNoModLvl(level, evakey1Q, c2QiQ, pool3Q)
-			ringP.MulCoeffsMontgomeryAndAddNoMod(evakey0P, c2QiP, pool2P)
-			ringP.MulCoeffsMontgomeryAndAddNoMod(evakey1P, c2QiP, pool3P)
-		}
+// checkSwitchingKeyLevel checks that the switching key covers the moduli up to the given level and returns the number
+// of moduli qi of the key above this level, which must be skipped to access the moduli pi of the key.
+func (eval *evaluator) checkSwitchingKeyLevel(level uint64, evakey *SwitchingKey) (gap uint64) {
 
-		if reduce&7 == 1 {
-			ringQ.ReduceLvl(level, pool2Q, pool2Q)
-			ringQ.ReduceLvl(level, pool3Q, pool3Q)
-			ringP.Reduce(pool2P, pool2P)
-			ringP.Reduce(pool3P, pool3P)
-		}
+	keyLevel := uint64(len(evakey.evakey[0][0].Coeffs)) - eval.params.PiCount() - 1
 
-		reduce++
+	if keyLevel < level || uint64(len(evakey.evakey)) < eval.params.BetaLvl(level) {
+		panic("cannot switch keys: switching key does not cover the level of the Ciphertext")
 	}
 
-	if (reduce-1)&7 != 1 {
-		ringQ.ReduceLvl(level, pool2Q, pool2Q)
-		ringQ.ReduceLvl(level, pool3Q, pool3Q)
-		ringP.Reduce(pool2P, pool2P)
-		ringP.Reduce(pool3P, pool3P)
-	}
+	return keyLevel - level
 }
 
 // switchKeysInPlace applies the general key-switching procedure of the form [c0 + cx*evakey[0], c1 + cx*evakey[1]]
@@ -1677,8 +2087,8 @@ func (eval *evaluator) switchKeysInPlace(level uint64, cx *ring.Poly, evakey *Sw
 
 	eval.switchKeysInPlaceNoModDown(level, cx, evakey, p0, eval.poolP[1], p1, eval.poolP[2])
 
-	eval.baseconverter.ModDownSplitNTTPQ(level, p0, eval.poolP[1], p0)
-	eval.baseconverter.ModDownSplitNTTPQ(level, p1, eval.poolP[2], p1)
+	eval.baseconverter.ModDownSplitQPtoQNTT(level, p0, eval.poolP[1], p0)
+	eval.baseconverter.ModDownSplitQPtoQNTT(level, p1, eval.poolP[2], p1)
 }
 
 // decomposeAndSplitNTT decomposes the input polynomial into the target CRT basis.
@@ -1689,8 +2099,7 @@ func (eval *evaluator) decomposeAndSplitNTT(level, beta uint64, c2NTT, c2InvNTT,
 
 	eval.decomposer.DecomposeAndSplit(level, beta, c2InvNTT, c2QiQ, c2QiP)
 
-	p0idxst := beta * eval.params.Alpha()
-	p0idxed := p0idxst + eval.decomposer.Xalpha()[beta]
+	p0idxst, p0idxed := eval.params.DecompositionDigit(beta)
 
 	// c2_qi = cx mod qi mod qi
 	for x := uint64(0); x < level+1; x++ {
@@ -1723,21 +2132,29 @@ func (eval *evaluator) RotateHoisted(ct0 *Ciphertext, rotations []uint64, rotkey
 	ringP := eval.ringP
 
 	c2NTT := ct0.value[1]
-	c2InvNTT := ringQ.NewPoly()
+	c2InvNTT := ringQ.GetPolyLvl(ct0.Level())
 	ringQ.InvNTTLvl(ct0.Level(), c2NTT, c2InvNTT)
 
-	alpha := eval.params.Alpha()
-	beta := uint64(math.Ceil(float64(ct0.Level()+1) / float64(alpha)))
+	beta := eval.params.BetaLvl(ct0.Level())
 
 	c2QiQDecomp := make([]*ring.Poly, beta)
 	c2QiPDecomp := make([]*ring.Poly, beta)
 
 	for i := uint64(0); i < beta; i++ {
-		c2QiQDecomp[i] = ringQ.NewPoly()
-		c2QiPDecomp[i] = ringP.NewPoly()
+		c2QiQDecomp[i] = ringQ.GetPolyLvl(ct0.Level())
+		c2QiPDecomp[i] = ringP.GetPoly()
 		eval.decomposeAndSplitNTT(ct0.Level(), i, c2NTT, c2InvNTT, c2QiQDecomp[i], c2QiPDecomp[i])
 	}
 
+	ringQ.PutPoly(c2InvNTT)
+
+	defer func() {
+		for i := range c2QiQDecomp {
+			ringQ.PutPoly(c2QiQDecomp[i])
+			ringP.PutPoly(c2QiPDecomp[i])
+		}
+	}()
+
 	cOut = make(map[uint64]*Ciphertext)
 
 	for _, i := range rotations {
@@ -1763,12 +2180,17 @@ func (eval *evaluator) permuteNTTHoisted(ct0 *Ciphertext, c2QiQDecomp, c2QiPDeco
 
 	k &= 2*eval.ringQ.N - 1
 
-	if rotKeys.permuteNTTLeftIndex[k] == nil {
+	index, swk := rotKeys.rotationKey(k)
+	if index == nil {
 		panic("cannot switchKeyHoisted: specific rotation has not been generated")
 	}
 
+	checkKeyBinding(ct0.Element, swk)
+
 	ctOut.SetScale(ct0.Scale())
 
+	eval.growPools(ct0.Level())
+
 	pool2Q := eval.poolQ[0]
 	pool3Q := eval.poolQ[1]
 
@@ -1777,12 +2199,14 @@ func (eval *evaluator) permuteNTTHoisted(ct0 *Ciphertext, c2QiQDecomp, c2QiPDeco
 
 	level := ctOut.Level()
 
-	eval.keyswitchHoisted(level, c2QiQDecomp, c2QiPDecomp, rotKeys.evakeyRotColLeft[k], pool2Q, pool3Q, pool2P, pool3P)
+	eval.keyswitchHoisted(level, c2QiQDecomp, c2QiPDecomp, swk, pool2Q, pool3Q, pool2P, pool3P)
 
 	eval.ringQ.AddLvl(level, pool2Q, ct0.value[0], pool2Q)
 
-	ring.PermuteNTTWithIndexLvl(level, pool2Q, rotKeys.permuteNTTLeftIndex[k], ctOut.value[0])
-	ring.PermuteNTTWithIndexLvl(level, pool3Q, rotKeys.permuteNTTLeftIndex[k], ctOut.value[1])
+	ring.PermuteNTTWithIndexLvl(level, pool2Q, index, ctOut.value[0])
+	ring.PermuteNTTWithIndexLvl(level, pool3Q, index, ctOut.value[1])
+
+	ctOut.keyFingerprint = ct0.keyFingerprint
 }
 
 func (eval *evaluator) keyswitchHoisted(level uint64, c2QiQDecomp, c2QiPDecomp []*ring.Poly, evakey *SwitchingKey, pool2Q, pool3Q, pool2P, pool3P *ring.Poly) {
@@ -1790,8 +2214,8 @@ func (eval *evaluator) keyswitchHoisted(level uint64, c2QiQDecomp, c2QiPDecomp [
 	eval.keyswitchHoistedNoModDown(level, c2QiQDecomp, c2QiPDecomp, evakey, pool2Q, pool3Q, pool2P, pool3P)
 
 	// Computes pool2Q = pool2Q/pool2P and pool3Q = pool3Q/pool3P
-	eval.baseconverter.ModDownSplitNTTPQ(level, pool2Q, pool2P, pool2Q)
-	eval.baseconverter.ModDownSplitNTTPQ(level, pool3Q, pool3P, pool3Q)
+	eval.baseconverter.ModDownSplitQPtoQNTT(level, pool2Q, pool2P, pool2Q)
+	eval.baseconverter.ModDownSplitQPtoQNTT(level, pool3Q, pool3P, pool3Q)
 }
 
 func (eval *evaluator) keyswitchHoistedNoModDown(level uint64, c2QiQDecomp, c2QiPDecomp []*ring.Poly, evakey *SwitchingKey, pool2Q, pool3Q, pool2P, pool3P *ring.Poly) {
@@ -1799,49 +2223,26 @@ func (eval *evaluator) keyswitchHoistedNoModDown(level uint64, c2QiQDecomp, c2Qi
 	ringQ := eval.ringQ
 	ringP := eval.ringP
 
-	alpha := eval.params.Alpha()
-	beta := uint64(math.Ceil(float64(level+1) / float64(alpha)))
-
-	evakey0Q := new(ring.Poly)
-	evakey1Q := new(ring.Poly)
-	evakey0P := new(ring.Poly)
-	evakey1P := new(ring.Poly)
-
-	// Key switching with CRT decomposition for the Qi
-	var reduce uint64
-	for i := uint64(0); i < beta; i++ {
-
-		evakey0Q.Coeffs = evakey.evakey[i][0].Coeffs[:level+1]
-		evakey1Q.Coeffs = evakey.evakey[i][1].Coeffs[:level+1]
-		evakey0P.Coeffs = evakey.evakey[i][0].Coeffs[len(ringQ.Modulus):]
-		evakey1P.Coeffs = evakey.evakey[i][1].Coeffs[len(ringQ.Modulus):]
+	beta := eval.params.BetaLvl(level)
 
-		if i == 0 {
-			ringQ.MulCoeffsMontgomeryLvl(level, evakey0Q, c2QiQDecomp[i], pool2Q)
-			ringQ.MulCoeffsMontgomeryLvl(level, evakey1Q, c2QiQDecomp[i], pool3Q)
-			ringP.MulCoeffsMontgomery(evakey0P, c2QiPDecomp[i], pool2P)
-			ringP.MulCoeffsMontgomery(evakey1P, c2QiPDecomp[i], pool3P)
-		} else {
-			ringQ.MulCoeffsMontgomeryAndAddNoModLvl(level, evakey0Q, c2QiQDecomp[i], pool2Q)
-			ringQ.MulCoeffsMontgomeryAndAddNoModLvl(level, evakey1Q, c2QiQDecomp[i], pool3Q)
-			ringP.MulCoeffsMontgomeryAndAddNoMod(evakey0P, c2QiPDecomp[i], pool2P)
-			ringP.MulCoeffsMontgomeryAndAddNoMod(evakey1P, c2QiPDecomp[i], pool3P)
-		}
+	keyLevelGap := eval.checkSwitchingKeyLevel(level, evakey)
 
-		if reduce&7 == 1 {
-			ringQ.ReduceLvl(level, pool2Q, pool2Q)
-			ringQ.ReduceLvl(level, pool3Q, pool3Q)
-			ringP.Reduce(pool2P, pool2P)
-			ringP.Reduce(pool3P, pool3P)
-		}
+	evakey0Q := make([]*ring.Poly, beta)
+	evakey1Q := make([]*ring.Poly, beta)
+	evakey0P := make([]*ring.Poly, beta)
+	evakey1P := make([]*ring.Poly, beta)
 
-		reduce++
+	for i := uint64(0); i < beta; i++ {
+		evakey0Q[i] = &ring.Poly{Coeffs: evakey.evakey[i][0].Coeffs[:level+1]}
+		evakey1Q[i] = &ring.Poly{Coeffs: evakey.evakey[i][1].Coeffs[:level+1]}
+		evakey0P[i] = &ring.Poly{Coeffs: evakey.evakey[i][0].Coeffs[level+1+keyLevelGap:]}
+		evakey1P[i] = &ring.Poly{Coeffs: evakey.evakey[i][1].Coeffs[level+1+keyLevelGap:]}
 	}
 
-	if (reduce-1)&7 != 1 {
-		ringQ.ReduceLvl(level, pool2Q, pool2Q)
-		ringQ.ReduceLvl(level, pool3Q, pool3Q)
-		ringP.Reduce(pool2P, pool2P)
-		ringP.Reduce(pool3P, pool3P)
-	}
+	// Key switching with CRT decomposition for the Qi
+	levelP := uint64(len(ringP.Modulus) - 1)
+	ringQ.InnerProductMontgomeryLvl(level, evakey0Q, c2QiQDecomp[:beta], pool2Q)
+	ringQ.InnerProductMontgomeryLvl(level, evakey1Q, c2QiQDecomp[:beta], pool3Q)
+	ringP.InnerProductMontgomeryLvl(levelP, evakey0P, c2QiPDecomp[:beta], pool2P)
+	ringP.InnerProductMontgomeryLvl(levelP, evakey1P, c2QiPDecomp[:beta], pool3P)
 }
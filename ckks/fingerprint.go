@@ -0,0 +1,153 @@
+package ckks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// KeyFingerprint is a stable identifier of a key, computed as the SHA-256 digest of the hash of the parameters
+// and of the binary representation of the key. Two keys have the same fingerprint if and only if they are equal
+// and used with the same parameters (up to collisions of the hash function).
+//
+// The fingerprint of a SecretKey is a digest of the secret and does not reveal it, but since it allows to test
+// whether a candidate key is the secret, it should not be published if the secret is of low entropy.
+type KeyFingerprint [32]byte
+
+// String returns the hexadecimal representation of the first 8 bytes of the fingerprint.
+func (fp KeyFingerprint) String() string {
+	return hex.EncodeToString(fp[:8])
+}
+
+// Domain separation of the fingerprints of the different kinds of keys
+const (
+	fingerprintSecretKey = iota
+	fingerprintPublicKey
+	fingerprintEvaluationKey
+	fingerprintSwitchingKey
+	fingerprintRotationKeys
+)
+
+func newKeyFingerprint(params *Parameters, kind byte, data []byte, err error) (fp KeyFingerprint) {
+
+	if err != nil {
+		panic(err)
+	}
+
+	paramsHash := params.Hash()
+
+	hash := sha256.New()
+	hash.Write([]byte{kind})
+	hash.Write(paramsHash[:])
+	hash.Write(data)
+	copy(fp[:], hash.Sum(nil))
+
+	return
+}
+
+// Fingerprint returns the fingerprint of the SecretKey for the given parameters.
+func (sk *SecretKey) Fingerprint(params *Parameters) KeyFingerprint {
+	data, err := sk.MarshalBinary()
+	return newKeyFingerprint(params, fingerprintSecretKey, data, err)
+}
+
+// Fingerprint returns the fingerprint of the PublicKey for the given parameters.
+func (pk *PublicKey) Fingerprint(params *Parameters) KeyFingerprint {
+	data, err := pk.MarshalBinary()
+	return newKeyFingerprint(params, fingerprintPublicKey, data, err)
+}
+
+// Fingerprint returns the fingerprint of the EvaluationKey for the given parameters.
+func (evaluationkey *EvaluationKey) Fingerprint(params *Parameters) KeyFingerprint {
+	data, err := evaluationkey.MarshalBinary()
+	return newKeyFingerprint(params, fingerprintEvaluationKey, data, err)
+}
+
+// Fingerprint returns the fingerprint of the SwitchingKey for the given parameters.
+func (switchkey *SwitchingKey) Fingerprint(params *Parameters) KeyFingerprint {
+	data, err := switchkey.MarshalBinary()
+	return newKeyFingerprint(params, fingerprintSwitchingKey, data, err)
+}
+
+// Fingerprint returns the fingerprint of the RotationKeys for the given parameters.
+func (rotationkey *RotationKeys) Fingerprint(params *Parameters) KeyFingerprint {
+	data, err := rotationkey.MarshalBinary()
+	return newKeyFingerprint(params, fingerprintRotationKeys, data, err)
+}
+
+// SecretFingerprint returns the fingerprint of the secret-key of the PublicKey, and whether it is known. It is known for
+// the public-keys generated by a KeyGenerator, and lost by the serialization.
+func (pk *PublicKey) SecretFingerprint() (fp KeyFingerprint, known bool) {
+	if pk.secret == nil {
+		return fp, false
+	}
+	return *pk.secret, true
+}
+
+// bindingFingerprint returns the fingerprint to which the Ciphertexts encrypted with the PublicKey are bound: the one
+// of its secret-key if it is known, else the one of the PublicKey.
+func (pk *PublicKey) bindingFingerprint(params *Parameters) KeyFingerprint {
+	if fp, known := pk.SecretFingerprint(); known {
+		return fp
+	}
+	return pk.Fingerprint(params)
+}
+
+// bind records the fingerprints of the secret-keys of the Ciphertexts switched by the key and of the results.
+func (swk *SwitchingKey) bind(in, out *KeyFingerprint) {
+	swk.bindingIn, swk.bindingOut = in, out
+}
+
+// bindRotation records the fingerprint of the secret-key of a rotation key, which is the key of its inputs and outputs.
+func (swk *SwitchingKey) bindRotation(params *Parameters, sk *SecretKey) {
+	fp := sk.Fingerprint(params)
+	swk.bind(&fp, &fp)
+}
+
+// KeyFingerprint returns the fingerprint of the key to which the element is bound, and whether it is bound to a key.
+// A Ciphertext is bound to the key that encrypted it if its Encryptor was created with key binding enabled: to the
+// secret-key for an Encryptor created with the secret-key or with a public-key generated by a KeyGenerator, else to the
+// public-key. The binding is propagated by the Evaluator to the results of the operations on this Ciphertext, and
+// checked against the secret-keys of the switching keys generated by a KeyGenerator. The binding is not serialized.
+func (el *Element) KeyFingerprint() (fp KeyFingerprint, bound bool) {
+	if el.keyFingerprint == nil {
+		return fp, false
+	}
+	return *el.keyFingerprint, true
+}
+
+// SetKeyFingerprint binds the element to the key with the given fingerprint.
+func (el *Element) SetKeyFingerprint(fp KeyFingerprint) {
+	el.keyFingerprint = &fp
+}
+
+// Unbind removes the binding of the element to a key.
+func (el *Element) Unbind() {
+	el.keyFingerprint = nil
+}
+
+// checkKeyBinding panics if the element is bound to another key than the one of the Ciphertexts switched by swk.
+// Unbound elements and switching keys whose secret-keys are unknown are not checked.
+func checkKeyBinding(el *Element, swk *SwitchingKey) {
+
+	if el.keyFingerprint == nil || swk == nil || swk.bindingIn == nil {
+		return
+	}
+
+	if *el.keyFingerprint != *swk.bindingIn {
+		panic("cannot evaluate: operand is bound to the key " + el.keyFingerprint.String() + " but the switching key is for the key " + swk.bindingIn.String())
+	}
+}
+
+// bindingOf returns the binding of the result of an operation on el0 and el1, and panics if they are bound to different keys.
+func bindingOf(el0, el1 *Element) *KeyFingerprint {
+
+	if el0.keyFingerprint == nil {
+		return el1.keyFingerprint
+	}
+
+	if el1.keyFingerprint != nil && *el0.keyFingerprint != *el1.keyFingerprint {
+		panic("cannot evaluate: operands are bound to different keys (" + el0.keyFingerprint.String() + " and " + el1.keyFingerprint.String() + ")")
+	}
+
+	return el0.keyFingerprint
+}
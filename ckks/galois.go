@@ -0,0 +1,157 @@
+package ckks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// GaloisElementForColumnRotation returns the Galois element GaloisGen^k mod 2N of the automorphism rotating
+// the slots by k positions to the left. A negative k corresponds to a rotation to the right.
+func (p *Parameters) GaloisElementForColumnRotation(k int) uint64 {
+
+	slots := int(p.N() >> 1)
+
+	k %= slots
+	if k < 0 {
+		k += slots
+	}
+
+	return ring.ModExp(GaloisGen, uint64(k), p.N()<<1)
+}
+
+// GaloisElementForRowRotation returns the Galois element 2N-1 of the automorphism conjugating the slots.
+func (p *Parameters) GaloisElementForRowRotation() uint64 {
+	return (p.N() << 1) - 1
+}
+
+// GaloisElementForRotation returns the Galois element of the automorphism applied by a rotation of the given type
+// and amount, as given to KeyGenerator.GenRotationKey.
+func (p *Parameters) GaloisElementForRotation(rotType Rotation, k uint64) uint64 {
+	switch rotType {
+	case RotationLeft:
+		return p.GaloisElementForColumnRotation(int(k & ((p.N() >> 1) - 1)))
+	case RotationRight:
+		return p.GaloisElementForColumnRotation(-int(k & ((p.N() >> 1) - 1)))
+	case Conjugate:
+		return p.GaloisElementForRowRotation()
+	}
+	panic("cannot GaloisElementForRotation: invalid rotation type")
+}
+
+// GaloisElementsForRotations returns the sorted list of distinct Galois elements of the keys needed to rotate the slots
+// by each of the given amounts to the left (negative amounts being rotations to the right), and to conjugate the slots
+// if conjugate is true. Rotations by zero positions do not need a key.
+func (p *Parameters) GaloisElementsForRotations(rotations []int, conjugate bool) (galEls []uint64) {
+
+	seen := make(map[uint64]bool)
+
+	for _, k := range rotations {
+		if galEl := p.GaloisElementForColumnRotation(k); galEl != 1 && !seen[galEl] {
+			seen[galEl] = true
+			galEls = append(galEls, galEl)
+		}
+	}
+
+	if conjugate {
+		galEls = append(galEls, p.GaloisElementForRowRotation())
+	}
+
+	sort.Slice(galEls, func(i, j int) bool { return galEls[i] < galEls[j] })
+
+	return
+}
+
+// InverseGaloisElement returns the Galois element of the inverse automorphism, that is galEl^-1 mod 2N.
+func (p *Parameters) InverseGaloisElement(galEl uint64) uint64 {
+	// The multiplicative group of Z_2N has order N, hence galEl^-1 = galEl^(N-1)
+	return ring.ModExp(galEl, p.N()-1, p.N()<<1)
+}
+
+// RotationForGaloisElement returns the rotation of the slots applied by the automorphism of the given Galois element:
+// either RotationLeft by k positions, with 0 <= k < N/2, or Conjugate. It returns an error if the automorphism is
+// not one of those, for example a rotation composed with a conjugation.
+func (p *Parameters) RotationForGaloisElement(galEl uint64) (rotType Rotation, k uint64, err error) {
+
+	M := p.N() << 1
+
+	galEl &= M - 1
+
+	if galEl == p.GaloisElementForRowRotation() {
+		return Conjugate, 0, nil
+	}
+
+	// Discrete logarithm of galEl in base GaloisGen, by exhaustive search over the N/2 powers
+	pow := uint64(1)
+	for k = 0; k < p.N()>>1; k++ {
+		if pow == galEl {
+			return RotationLeft, k, nil
+		}
+		pow = (pow * GaloisGen) & (M - 1)
+	}
+
+	return 0, 0, fmt.Errorf("%d is not the Galois element of a rotation or of the conjugation", galEl)
+}
+
+// automorphismKey returns the permutation index and the SwitchingKey of the automorphism of Galois element galEl
+// stored in rotKey, either as an automorphism key or as the key of a rotation to the left or of the conjugation.
+// It returns a nil SwitchingKey if rotKey does not contain the key.
+func (rotKey *RotationKeys) automorphismKey(params *Parameters, galEl uint64) (index []uint64, swk *SwitchingKey) {
+
+	if swk = rotKey.evakeyAutomorphism[galEl]; swk != nil {
+		return rotKey.permuteNTTAutomorphismIndex[galEl], swk
+	}
+
+	rotType, k, err := params.RotationForGaloisElement(galEl)
+	if err != nil {
+		return nil, nil
+	}
+
+	if rotType == Conjugate {
+		return rotKey.conjugationKey()
+	}
+
+	return rotKey.rotationKey(k)
+}
+
+// rotationKey returns the permutation index and the SwitchingKey of the rotation by k positions to the left stored in
+// rotKey, reading them from the key stream of rotKey on their first use. It returns a nil SwitchingKey if rotKey does
+// not contain the key.
+func (rotKey *RotationKeys) rotationKey(k uint64) (index []uint64, swk *SwitchingKey) {
+
+	if swk = rotKey.evakeyRotColLeft[k]; swk != nil || rotKey.stream == nil {
+		return rotKey.permuteNTTLeftIndex[k], swk
+	}
+
+	return rotKey.stream.load(rotKey.stream.params.GaloisElementForColumnRotation(int(k)))
+}
+
+// conjugationKey returns the permutation index and the SwitchingKey of the conjugation stored in rotKey, reading them
+// from the key stream of rotKey on their first use. It returns a nil SwitchingKey if rotKey does not contain the key.
+func (rotKey *RotationKeys) conjugationKey() (index []uint64, swk *SwitchingKey) {
+
+	if swk = rotKey.evakeyConjugate; swk != nil || rotKey.stream == nil {
+		return rotKey.permuteNTTConjugateIndex, swk
+	}
+
+	return rotKey.stream.load(rotKey.stream.params.GaloisElementForRowRotation())
+}
+
+// hasRotationKey returns whether rotKey contains the key of the rotation by k positions to the left, without reading
+// it from the key stream of rotKey.
+func (rotKey *RotationKeys) hasRotationKey(k uint64) bool {
+	if rotKey.evakeyRotColLeft[k] != nil && rotKey.permuteNTTLeftIndex[k] != nil {
+		return true
+	}
+	return rotKey.stream != nil && rotKey.stream.has(rotKey.stream.params.GaloisElementForColumnRotation(int(k)))
+}
+
+// hasConjugationKey returns whether rotKey contains the key of the conjugation, without reading it from the key stream
+// of rotKey.
+func (rotKey *RotationKeys) hasConjugationKey() bool {
+	if rotKey.evakeyConjugate != nil {
+		return true
+	}
+	return rotKey.stream != nil && rotKey.stream.has(rotKey.stream.params.GaloisElementForRowRotation())
+}
@@ -1,6 +1,7 @@
 package ckks
 
 import (
+	"io"
 	"math"
 	"math/big"
 
@@ -18,10 +19,18 @@ type KeyGenerator interface {
 	GenKeyPair() (sk *SecretKey, pk *PublicKey)
 	GenKeyPairSparse(hw uint64) (sk *SecretKey, pk *PublicKey)
 	GenRelinKey(sk *SecretKey) (evakey *EvaluationKey)
+	GenRelinKeys(sk *SecretKey, maxDegree uint64) (evakey *EvaluationKey)
 	GenSwitchingKey(skInput, skOutput *SecretKey) (newevakey *SwitchingKey)
+	GenSwitchingKeyLvl(level uint64, skInput, skOutput *SecretKey) (newevakey *SwitchingKey)
 	GenRotationKey(rotType Rotation, sk *SecretKey, k uint64, rotKey *RotationKeys)
+	GenRotationKeyLvl(level uint64, rotType Rotation, sk *SecretKey, k uint64, rotKey *RotationKeys)
 	GenRotationKeysPow2(skOutput *SecretKey) (rotKey *RotationKeys)
+	GenAutomorphismKey(galEl uint64, sk *SecretKey, rotKey *RotationKeys)
 	GenBootstrappingKey(logSlots uint64, btpParams *BootstrappParams, sk *SecretKey) (btpKey *BootstrappingKey)
+	GenBootstrappingRotationKeys(logSlots uint64, btpParams *BootstrappParams, sk *SecretKey, rotKey *RotationKeys)
+	GenBootstrappingKeyEncapsulated(logSlots uint64, btpParams *BootstrappParams, skDense, skSparse *SecretKey) (btpKey *BootstrappingKey)
+	GenSparseEncapsulationKeys(skDense, skSparse *SecretKey) (swkDenseToSparse, swkSparseToDense *SwitchingKey)
+	GenBootstrappingKeyStream(w io.Writer, logSlots uint64, btpParams *BootstrappParams, sk *SecretKey, seeded bool) (err error)
 }
 
 // KeyGenerator is a structure that stores the elements required to create new keys,
@@ -31,8 +40,8 @@ type keyGenerator struct {
 	ringQP          *ring.Ring
 	pBigInt         *big.Int
 	polypool        [2]*ring.Poly
-	gaussianSampler *ring.GaussianSampler
-	uniformSampler  *ring.UniformSampler
+	gaussianSampler ring.Sampler
+	uniformSampler  ring.Sampler
 }
 
 // SecretKey is a structure that stores the SecretKey
@@ -43,6 +52,8 @@ type SecretKey struct {
 // PublicKey is a structure that stores the PublicKey
 type PublicKey struct {
 	pk [2]*ring.Poly
+
+	secret *KeyFingerprint // fingerprint of the secret-key of the public-key, nil if unknown
 }
 
 // Rotation is a type used to represent the rotations types.
@@ -64,16 +75,26 @@ type RotationKeys struct {
 	evakeyRotColLeft  map[uint64]*SwitchingKey
 	evakeyRotColRight map[uint64]*SwitchingKey
 	evakeyConjugate   *SwitchingKey
+
+	permuteNTTAutomorphismIndex map[uint64][]uint64
+	evakeyAutomorphism          map[uint64]*SwitchingKey
+
+	// Rotation and conjugation keys read on their first use from a bootstrapping key stream, nil if none
+	stream *keyStreamIndex
 }
 
 // EvaluationKey is a structure that stores the switching-keys required during the relinearization.
 type EvaluationKey struct {
-	evakey *SwitchingKey
+	evakey    *SwitchingKey   // Switching key from s^2 to s
+	evakeyPow []*SwitchingKey // Switching keys from s^3, s^4, ... to s (GenRelinKeys only)
 }
 
 // SwitchingKey is a structure that stores the switching-keys required during the key-switching.
 type SwitchingKey struct {
 	evakey [][2]*ring.Poly
+
+	// Fingerprints of the secret-keys of the Ciphertexts switched by the key and of the results, nil if unknown
+	bindingIn, bindingOut *KeyFingerprint
 }
 
 // Get returns the switching key backing slice
@@ -85,6 +106,27 @@ func (swk *SwitchingKey) Get() [][2]*ring.Poly {
 type BootstrappingKey struct {
 	relinkey *EvaluationKey // Relinearization key
 	rotkeys  *RotationKeys  // Rotation and conjugation keys
+
+	swkDenseToSparse *SwitchingKey // Switching key from the dense to the sparse secret (sparse secret encapsulation only)
+	swkSparseToDense *SwitchingKey // Switching key from the sparse to the dense secret (sparse secret encapsulation only)
+}
+
+// NewBootstrappingKey creates a new BootstrappingKey referencing the given relinearization and rotation keys, which can
+// thus be shared with the Evaluator of the application instead of being duplicated. The rotation keys can be completed
+// with the ones required by the bootstrapping with KeyGenerator.GenBootstrappingRotationKeys.
+func NewBootstrappingKey(relinKey *EvaluationKey, rotKey *RotationKeys) (btpKey *BootstrappingKey) {
+	return &BootstrappingKey{relinkey: relinKey, rotkeys: rotKey}
+}
+
+// RelinKey returns the relinearization key referenced by the BootstrappingKey.
+func (btpKey *BootstrappingKey) RelinKey() *EvaluationKey {
+	return btpKey.relinkey
+}
+
+// RotationKeys returns the rotation keys referenced by the BootstrappingKey. They include all the rotations and the
+// conjugation required by the bootstrapping, and can be used by an Evaluator.
+func (btpKey *BootstrappingKey) RotationKeys() *RotationKeys {
+	return btpKey.rotkeys
 }
 
 // NewKeyGenerator creates a new KeyGenerator, from which the secret and public keys, as well as the evaluation,
@@ -116,7 +158,7 @@ func NewKeyGenerator(params *Parameters) KeyGenerator {
 		pBigInt:         pBigInt,
 		polypool:        [2]*ring.Poly{qp.NewPoly(), qp.NewPoly()},
 		gaussianSampler: ring.NewGaussianSampler(prng, qp, params.sigma, uint64(6*params.sigma)),
-		uniformSampler:  ring.NewUniformSampler(prng, qp),
+		uniformSampler:  ring.NewUniformSamplerWithDomain(prng, qp, true, true),
 	}
 }
 
@@ -195,6 +237,9 @@ func (keygen *keyGenerator) GenPublicKey(sk *SecretKey) (pk *PublicKey) {
 	ringQP.MulCoeffsMontgomeryAndAdd(sk.sk, pk.pk[1], pk.pk[0])
 	ringQP.Neg(pk.pk[0], pk.pk[0])
 
+	fp := sk.Fingerprint(keygen.params)
+	pk.secret = &fp
+
 	return pk
 }
 
@@ -218,6 +263,7 @@ func (pk *PublicKey) Get() [2]*ring.Poly {
 func (pk *PublicKey) Set(poly [2]*ring.Poly) {
 	pk.pk[0] = poly[0].CopyNew()
 	pk.pk[1] = poly[1].CopyNew()
+	pk.secret = nil
 }
 
 // GenKeyPair generates a new SecretKey with distribution [1/3, 1/3, 1/3] and a corresponding public key.
@@ -241,8 +287,49 @@ func (keygen *keyGenerator) GenRelinKey(sk *SecretKey) (evakey *EvaluationKey) {
 
 	evakey = new(EvaluationKey)
 	keygen.ringQP.MulCoeffsMontgomery(sk.Get(), sk.Get(), keygen.polypool[0])
-	evakey.evakey = keygen.newSwitchingKey(keygen.polypool[0], sk.Get())
+	evakey.evakey = keygen.newSwitchingKey(keygen.params.MaxLevel(), keygen.polypool[0], sk.Get())
+	keygen.polypool[0].Zero()
+
+	fp := sk.Fingerprint(keygen.params)
+	evakey.evakey.bind(&fp, &fp)
+
+	return
+}
+
+// GenRelinKeys generates a new EvaluationKey that relinearizes Ciphertexts of degree up to maxDegree, i.e. that stores a
+// switching key from s^i to s for 2 <= i <= maxDegree. With such a key, the products of Ciphertexts can be left unrelinearized
+// until their degree reaches maxDegree, and then be relinearized in one pass with Relinearize.
+func (keygen *keyGenerator) GenRelinKeys(sk *SecretKey, maxDegree uint64) (evakey *EvaluationKey) {
+
+	if len(keygen.params.pi) == 0 {
+		panic("Cannot GenRelinKeys: modulus P is empty")
+	}
+
+	if maxDegree < 2 {
+		panic("Cannot GenRelinKeys: maxDegree must be at least 2")
+	}
+
+	ringQP := keygen.ringQP
+
+	evakey = new(EvaluationKey)
+
+	// polypool[0] is used by newSwitchingKey, so the powers of s are computed in polypool[1]
+	skPow := keygen.polypool[1]
+	ringQP.MulCoeffsMontgomery(sk.Get(), sk.Get(), skPow)
+	evakey.evakey = keygen.newSwitchingKey(keygen.params.MaxLevel(), skPow, sk.Get())
+
+	for i := uint64(3); i <= maxDegree; i++ {
+		ringQP.MulCoeffsMontgomery(skPow, sk.Get(), skPow)
+		evakey.evakeyPow = append(evakey.evakeyPow, keygen.newSwitchingKey(keygen.params.MaxLevel(), skPow, sk.Get()))
+	}
+
 	keygen.polypool[0].Zero()
+	keygen.polypool[1].Zero()
+
+	fp := sk.Fingerprint(keygen.params)
+	for degree := uint64(2); degree <= maxDegree; degree++ {
+		evakey.keyOfDegree(degree).bind(&fp, &fp)
+	}
 
 	return
 }
@@ -269,6 +356,19 @@ func (evk *EvaluationKey) Get() *SwitchingKey {
 	return evk.evakey
 }
 
+// MaxDegree returns the maximum degree of the Ciphertexts that can be relinearized with the evaluation-key.
+func (evk *EvaluationKey) MaxDegree() uint64 {
+	return 2 + uint64(len(evk.evakeyPow))
+}
+
+// keyOfDegree returns the switching key from s^degree to s, for 2 <= degree <= MaxDegree().
+func (evk *EvaluationKey) keyOfDegree(degree uint64) *SwitchingKey {
+	if degree == 2 {
+		return evk.evakey
+	}
+	return evk.evakeyPow[degree-3]
+}
+
 // Set sets the target Evaluation key with the input polynomials.
 func (evk *EvaluationKey) Set(rlk [][2]*ring.Poly) {
 
@@ -282,14 +382,28 @@ func (evk *EvaluationKey) Set(rlk [][2]*ring.Poly) {
 
 // GenSwitchingKey generates a new key-switching key, that will re-encrypt a Ciphertext encrypted under the input key into the output key.
 func (keygen *keyGenerator) GenSwitchingKey(skInput, skOutput *SecretKey) (newevakey *SwitchingKey) {
+	return keygen.GenSwitchingKeyLvl(keygen.params.MaxLevel(), skInput, skOutput)
+}
+
+// GenSwitchingKeyLvl generates a new key-switching key that only covers the moduli up to the given level. The key can
+// only be used on Ciphertexts at this level or below, but is smaller than a key generated with GenSwitchingKey.
+func (keygen *keyGenerator) GenSwitchingKeyLvl(level uint64, skInput, skOutput *SecretKey) (newevakey *SwitchingKey) {
 
 	if len(keygen.params.pi) == 0 {
 		panic("Cannot GenSwitchingKey: modulus P is empty")
 	}
 
+	if level > keygen.params.MaxLevel() {
+		panic("Cannot GenSwitchingKey: level is larger than the maximum level of the parameters")
+	}
+
 	keygen.ringQP.Copy(skInput.Get(), keygen.polypool[0])
-	newevakey = keygen.newSwitchingKey(keygen.polypool[0], skOutput.Get())
+	newevakey = keygen.newSwitchingKey(level, keygen.polypool[0], skOutput.Get())
 	keygen.polypool[0].Zero()
+
+	fpIn, fpOut := skInput.Fingerprint(keygen.params), skOutput.Fingerprint(keygen.params)
+	newevakey.bind(&fpIn, &fpOut)
+
 	return
 }
 
@@ -318,11 +432,21 @@ func NewRotationKeys() (rotKey *RotationKeys) {
 
 // GenRot populates the input RotationKeys with a SwitchingKey for the given rotation type and amount.
 func (keygen *keyGenerator) GenRotationKey(rotType Rotation, sk *SecretKey, k uint64, rotKey *RotationKeys) {
+	keygen.GenRotationKeyLvl(keygen.params.MaxLevel(), rotType, sk, k, rotKey)
+}
+
+// GenRotationKeyLvl populates the input RotationKeys with a SwitchingKey for the given rotation type and amount, that only
+// covers the moduli up to the given level. The rotation can then only be applied on Ciphertexts at this level or below.
+func (keygen *keyGenerator) GenRotationKeyLvl(level uint64, rotType Rotation, sk *SecretKey, k uint64, rotKey *RotationKeys) {
 
 	if len(keygen.params.pi) == 0 {
 		panic("Cannot GenRot: modulus P is empty")
 	}
 
+	if level > keygen.params.MaxLevel() {
+		panic("Cannot GenRot: level is larger than the maximum level of the parameters")
+	}
+
 	ringQP := keygen.ringQP
 
 	if rotType != Conjugate {
@@ -336,11 +460,11 @@ func (keygen *keyGenerator) GenRotationKey(rotType Rotation, sk *SecretKey, k ui
 		}
 
 		if _, inMap := rotKey.permuteNTTLeftIndex[k]; !inMap {
-			rotKey.permuteNTTLeftIndex[k] = ring.PermuteNTTIndex(GaloisGen, k, ringQP.N)
+			rotKey.permuteNTTLeftIndex[k] = ring.PermuteNTTIndexShared(GaloisGen, k, ringQP.N)
 		}
 
 		if _, inMap := rotKey.permuteNTTRightIndex[k]; !inMap {
-			rotKey.permuteNTTRightIndex[k] = ring.PermuteNTTIndex(GaloisGen, 2*ringQP.N-k, ringQP.N)
+			rotKey.permuteNTTRightIndex[k] = ring.PermuteNTTIndexShared(GaloisGen, 2*ringQP.N-k, ringQP.N)
 		}
 	}
 
@@ -352,7 +476,8 @@ func (keygen *keyGenerator) GenRotationKey(rotType Rotation, sk *SecretKey, k ui
 		}
 
 		if rotKey.evakeyRotColLeft[k] == nil && k != 0 {
-			rotKey.evakeyRotColLeft[k] = keygen.genrotKey(sk.Get(), rotKey.permuteNTTRightIndex[k])
+			rotKey.evakeyRotColLeft[k] = keygen.genrotKey(level, sk.Get(), rotKey.permuteNTTRightIndex[k])
+			rotKey.evakeyRotColLeft[k].bindRotation(keygen.params, sk)
 		}
 
 	case RotationRight:
@@ -362,12 +487,14 @@ func (keygen *keyGenerator) GenRotationKey(rotType Rotation, sk *SecretKey, k ui
 		}
 
 		if rotKey.evakeyRotColRight[k] == nil && k != 0 {
-			rotKey.evakeyRotColRight[k] = keygen.genrotKey(sk.Get(), rotKey.permuteNTTLeftIndex[k])
+			rotKey.evakeyRotColRight[k] = keygen.genrotKey(level, sk.Get(), rotKey.permuteNTTLeftIndex[k])
+			rotKey.evakeyRotColRight[k].bindRotation(keygen.params, sk)
 		}
 
 	case Conjugate:
-		rotKey.permuteNTTConjugateIndex = ring.PermuteNTTIndex(2*ringQP.N-1, 1, ringQP.N)
-		rotKey.evakeyConjugate = keygen.genrotKey(sk.Get(), rotKey.permuteNTTConjugateIndex)
+		rotKey.permuteNTTConjugateIndex = ring.PermuteNTTIndexShared(2*ringQP.N-1, 1, ringQP.N)
+		rotKey.evakeyConjugate = keygen.genrotKey(level, sk.Get(), rotKey.permuteNTTConjugateIndex)
+		rotKey.evakeyConjugate.bindRotation(keygen.params, sk)
 	}
 }
 
@@ -390,6 +517,78 @@ func (keygen *keyGenerator) GenRotationKeysPow2(skOutput *SecretKey) (rotKey *Ro
 	return
 }
 
+// GenAutomorphismKey populates the input RotationKeys with the SwitchingKey of the automorphism X -> X^galEl, for any
+// galEl coprime with 2N, that is any odd galEl. The automorphism is then applied with Evaluator.Automorphism.
+// Unlike the keys of GenRotationKey, those keys are indexed by their Galois element and cover all the automorphisms
+// of the ring, including the compositions of rotations and conjugation used by trace-based slot manipulations.
+func (keygen *keyGenerator) GenAutomorphismKey(galEl uint64, sk *SecretKey, rotKey *RotationKeys) {
+
+	if len(keygen.params.pi) == 0 {
+		panic("cannot GenAutomorphismKey: modulus P is empty")
+	}
+
+	if galEl&1 == 0 {
+		panic("cannot GenAutomorphismKey: Galois element must be odd")
+	}
+
+	N := keygen.ringQP.N
+
+	galEl &= (N << 1) - 1
+
+	if galEl == 1 {
+		return
+	}
+
+	if rotKey.permuteNTTAutomorphismIndex == nil {
+		rotKey.permuteNTTAutomorphismIndex = make(map[uint64][]uint64)
+	}
+
+	if rotKey.evakeyAutomorphism == nil {
+		rotKey.evakeyAutomorphism = make(map[uint64]*SwitchingKey)
+	}
+
+	if rotKey.evakeyAutomorphism[galEl] == nil {
+		rotKey.permuteNTTAutomorphismIndex[galEl] = ring.PermuteNTTIndexShared(galEl, 1, N)
+		rotKey.evakeyAutomorphism[galEl] = keygen.genrotKey(keygen.params.MaxLevel(), sk.Get(), ring.PermuteNTTIndex(keygen.params.InverseGaloisElement(galEl), 1, N))
+		rotKey.evakeyAutomorphism[galEl].bindRotation(keygen.params, sk)
+	}
+}
+
+// SetAutomorphismKey sets the target RotationKeys' SwitchingKey for the automorphism X -> X^galEl with the input
+// polynomials, as GenAutomorphismKey does for a key generated from a secret key. galEl must be odd.
+func (rotKey *RotationKeys) SetAutomorphismKey(params *Parameters, evakey [][2]*ring.Poly, galEl uint64) {
+
+	if galEl&1 == 0 {
+		panic("cannot SetAutomorphismKey: Galois element must be odd")
+	}
+
+	galEl &= (params.N() << 1) - 1
+
+	if galEl == 1 {
+		return
+	}
+
+	if rotKey.permuteNTTAutomorphismIndex == nil {
+		rotKey.permuteNTTAutomorphismIndex = make(map[uint64][]uint64)
+	}
+
+	if rotKey.evakeyAutomorphism == nil {
+		rotKey.evakeyAutomorphism = make(map[uint64]*SwitchingKey)
+	}
+
+	if rotKey.evakeyAutomorphism[galEl] == nil {
+
+		rotKey.permuteNTTAutomorphismIndex[galEl] = ring.PermuteNTTIndexShared(galEl, 1, params.N())
+
+		rotKey.evakeyAutomorphism[galEl] = new(SwitchingKey)
+		rotKey.evakeyAutomorphism[galEl].evakey = make([][2]*ring.Poly, len(evakey))
+		for j := range evakey {
+			rotKey.evakeyAutomorphism[galEl].evakey[j][0] = evakey[j][0].CopyNew()
+			rotKey.evakeyAutomorphism[galEl].evakey[j][1] = evakey[j][1].CopyNew()
+		}
+	}
+}
+
 // SetRotKey sets the target RotationKeys' SwitchingKey for the specified rotation type and amount with the input polynomials.
 func (rotKey *RotationKeys) SetRotKey(params *Parameters, evakey [][2]*ring.Poly, rotType Rotation, k uint64) {
 
@@ -406,7 +605,7 @@ func (rotKey *RotationKeys) SetRotKey(params *Parameters, evakey [][2]*ring.Poly
 
 		if rotKey.evakeyRotColLeft[k] == nil && k != 0 {
 
-			rotKey.permuteNTTLeftIndex[k] = ring.PermuteNTTIndex(GaloisGen, k, params.N())
+			rotKey.permuteNTTLeftIndex[k] = ring.PermuteNTTIndexShared(GaloisGen, k, params.N())
 
 			rotKey.evakeyRotColLeft[k] = new(SwitchingKey)
 			rotKey.evakeyRotColLeft[k].evakey = make([][2]*ring.Poly, len(evakey))
@@ -428,7 +627,7 @@ func (rotKey *RotationKeys) SetRotKey(params *Parameters, evakey [][2]*ring.Poly
 
 		if rotKey.evakeyRotColRight[k] == nil && k != 0 {
 
-			rotKey.permuteNTTRightIndex[k] = ring.PermuteNTTIndex(GaloisGen, 2*params.N()-1-k, params.N())
+			rotKey.permuteNTTRightIndex[k] = ring.PermuteNTTIndexShared(GaloisGen, 2*params.N()-1-k, params.N())
 
 			rotKey.evakeyRotColRight[k] = new(SwitchingKey)
 			rotKey.evakeyRotColRight[k].evakey = make([][2]*ring.Poly, len(evakey))
@@ -442,7 +641,7 @@ func (rotKey *RotationKeys) SetRotKey(params *Parameters, evakey [][2]*ring.Poly
 
 		if rotKey.evakeyConjugate == nil {
 
-			rotKey.permuteNTTConjugateIndex = ring.PermuteNTTIndex(2*params.N()-1, 1, params.N())
+			rotKey.permuteNTTConjugateIndex = ring.PermuteNTTIndexShared(2*params.N()-1, 1, params.N())
 
 			rotKey.evakeyConjugate = new(SwitchingKey)
 			rotKey.evakeyConjugate.evakey = make([][2]*ring.Poly, len(evakey))
@@ -454,14 +653,19 @@ func (rotKey *RotationKeys) SetRotKey(params *Parameters, evakey [][2]*ring.Poly
 	}
 }
 
-func (keygen *keyGenerator) genrotKey(sk *ring.Poly, index []uint64) (switchingkey *SwitchingKey) {
+func (keygen *keyGenerator) genrotKey(level uint64, sk *ring.Poly, index []uint64) (switchingkey *SwitchingKey) {
+	return keygen.genrotKeyWithSampler(level, sk, index, keygen.uniformSampler)
+}
+
+// genrotKeyWithSampler is genrotKey with the uniform elements of the switching key read from the given sampler.
+func (keygen *keyGenerator) genrotKeyWithSampler(level uint64, sk *ring.Poly, index []uint64, uniformSampler ring.Sampler) (switchingkey *SwitchingKey) {
 
 	skIn := sk
 	skOut := keygen.polypool[1]
 
 	ring.PermuteNTTWithIndexLvl(keygen.params.QPiCount()-1, skIn, index, skOut)
 
-	switchingkey = keygen.newSwitchingKey(skIn, skOut)
+	switchingkey = keygen.newSwitchingKeyWithSampler(level, skIn, skOut, uniformSampler)
 
 	keygen.polypool[0].Zero()
 	keygen.polypool[1].Zero()
@@ -469,7 +673,15 @@ func (keygen *keyGenerator) genrotKey(sk *ring.Poly, index []uint64) (switchingk
 	return
 }
 
-func (keygen *keyGenerator) newSwitchingKey(skIn, skOut *ring.Poly) (switchingkey *SwitchingKey) {
+// newSwitchingKey generates a switching key from skIn to skOut. If level is smaller than the maximum level, only the
+// decomposition elements needed at this level are generated, and the moduli qi above level are dropped.
+func (keygen *keyGenerator) newSwitchingKey(level uint64, skIn, skOut *ring.Poly) (switchingkey *SwitchingKey) {
+	return keygen.newSwitchingKeyWithSampler(level, skIn, skOut, keygen.uniformSampler)
+}
+
+// newSwitchingKeyWithSampler is newSwitchingKey with the uniform elements a of the switching key read from the given
+// sampler, one polynomial of QP per decomposition element, so that they can be regenerated from the state of its PRNG.
+func (keygen *keyGenerator) newSwitchingKeyWithSampler(level uint64, skIn, skOut *ring.Poly, uniformSampler ring.Sampler) (switchingkey *SwitchingKey) {
 
 	switchingkey = new(SwitchingKey)
 
@@ -478,10 +690,7 @@ func (keygen *keyGenerator) newSwitchingKey(skIn, skOut *ring.Poly) (switchingke
 	// Computes P * skIn
 	ringQP.MulScalarBigint(skIn, keygen.pBigInt, keygen.polypool[0])
 
-	alpha := keygen.params.Alpha()
-	beta := keygen.params.Beta()
-
-	var index uint64
+	beta := keygen.params.BetaLvl(level)
 
 	switchingkey.evakey = make([][2]*ring.Poly, beta)
 
@@ -489,22 +698,20 @@ func (keygen *keyGenerator) newSwitchingKey(skIn, skOut *ring.Poly) (switchingke
 
 		// e
 		switchingkey.evakey[i][0] = keygen.gaussianSampler.ReadNew()
-		ringQP.NTT(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
-		ringQP.MForm(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
+		ringQP.NTTMForm(switchingkey.evakey[i][0], switchingkey.evakey[i][0])
 
 		// a (since a is uniform, we consider we already sample it in the NTT and Montgomery domain)
-		switchingkey.evakey[i][1] = keygen.uniformSampler.ReadNew()
+		switchingkey.evakey[i][1] = uniformSampler.ReadNew()
 
 		// e + (skIn * P) * (q_star * q_tild) mod QP
 		//
-		// q_prod = prod(q[i*alpha+j])
+		// q_prod = prod(q[j]) for the moduli q[j] of the i-th element of the decomposition
 		// q_star = Q/qprod
 		// q_tild = q_star^-1 mod q_prod
 		//
-		// Therefore : (skIn * P) * (q_star * q_tild) = sk*P mod q[i*alpha+j], else 0
-		for j := uint64(0); j < alpha; j++ {
-
-			index = i*alpha + j
+		// Therefore : (skIn * P) * (q_star * q_tild) = sk*P mod q[j], else 0
+		start, end := keygen.params.DecompositionDigit(i)
+		for index := start; index < end; index++ {
 
 			qi := ringQP.Modulus[index]
 			p0tmp := keygen.polypool[0].Coeffs[index]
@@ -513,43 +720,69 @@ func (keygen *keyGenerator) newSwitchingKey(skIn, skOut *ring.Poly) (switchingke
 			for w := uint64(0); w < ringQP.N; w++ {
 				p1tmp[w] = ring.CRed(p1tmp[w]+p0tmp[w], qi)
 			}
-
-			// It handles the case where nb pj does not divide nb qi
-			if index >= keygen.params.QiCount() {
-				break
-			}
 		}
 
 		// (skIn * P) * (q_star * q_tild) - a * skOut + e mod QP
 		ringQP.MulCoeffsMontgomeryAndSub(switchingkey.evakey[i][1], skOut, switchingkey.evakey[i][0])
+
+		if level < keygen.params.MaxLevel() {
+			for j := range switchingkey.evakey[i] {
+				switchingkey.evakey[i][j].Coeffs = append(switchingkey.evakey[i][j].Coeffs[:level+1], switchingkey.evakey[i][j].Coeffs[keygen.params.QiCount():]...)
+			}
+		}
 	}
 
 	return
 }
 
-// GenKeys generates the bootstrapping keys
+// GenBootstrappingKey generates a new BootstrappingKey with a new relinearization key and the rotation keys required by
+// the bootstrapping. To share the rotation keys of the application, use NewBootstrappingKey and
+// GenBootstrappingRotationKeys instead.
 func (keygen *keyGenerator) GenBootstrappingKey(logSlots uint64, btpParams *BootstrappParams, sk *SecretKey) (btpKey *BootstrappingKey) {
+	btpKey = NewBootstrappingKey(keygen.GenRelinKey(sk), NewRotationKeys())
+	keygen.GenBootstrappingRotationKeys(logSlots, btpParams, sk, btpKey.rotkeys)
+	return
+}
 
-	btpKey = &BootstrappingKey{
-		relinkey: keygen.GenRelinKey(sk),
-		rotkeys:  NewRotationKeys(),
-	}
+// GenBootstrappingRotationKeys populates rotKey with the rotation and conjugation keys required by the bootstrapping that
+// it does not already contain. The keys already present are kept and shared, except those generated for a level lower
+// than the maximum level, which are replaced by keys covering all the moduli.
+func (keygen *keyGenerator) GenBootstrappingRotationKeys(logSlots uint64, btpParams *BootstrappParams, sk *SecretKey, rotKey *RotationKeys) {
 
-	rotKeyIndex := computeBootstrappingDFTRotationList(keygen.params.logN, logSlots, btpParams)
+	// The keys generated for a lower level have fewer moduli, and not always fewer decomposition elements
+	fullLevel := func(swk *SwitchingKey) bool {
+		return uint64(len(swk.evakey[0][0].Coeffs)) == keygen.params.QPiCount()
+	}
 
-	/*
-		nbKeys := uint64(len(rotKeyIndex)) + 2 //rot keys + conj key + relin key
-		nbPoly := keygen.params.Beta()
-		nbCoefficients := 2 * keygen.params.N() * keygen.params.QPiCount()
-		bytesPerCoeff := uint64(8)
-		log.Println("Switching-Keys size (GB) :", float64(nbKeys*nbPoly*nbCoefficients*bytesPerCoeff)/float64(1000000000), "(", nbKeys, "keys)")
-	*/
+	if rotKey.evakeyConjugate == nil || !fullLevel(rotKey.evakeyConjugate) {
+		keygen.GenRotationKey(Conjugate, sk, 0, rotKey)
+	}
 
-	keygen.GenRotationKey(Conjugate, sk, 0, btpKey.rotkeys)
-	for _, i := range rotKeyIndex {
-		keygen.GenRotationKey(RotationLeft, sk, uint64(i), btpKey.rotkeys)
+	for _, k := range computeBootstrappingDFTRotationList(keygen.params.logN, logSlots, btpParams) {
+		if swk := rotKey.evakeyRotColLeft[k]; swk != nil && !fullLevel(swk) {
+			delete(rotKey.evakeyRotColLeft, k)
+		}
+		keygen.GenRotationKey(RotationLeft, sk, k, rotKey)
 	}
+}
+
+// GenBootstrappingKeyEncapsulated generates the bootstrapping keys for the sparse secret encapsulation technique: the
+// Ciphertexts are encrypted under the dense secret skDense, and are only switched to the sparse secret skSparse, whose
+// Hamming weight should be btpParams.H, around the ModRaise step, which is the only step whose error depends on the
+// weight of the secret. The relinearization and rotation keys of the bootstrapping are therefore generated for skDense.
+func (keygen *keyGenerator) GenBootstrappingKeyEncapsulated(logSlots uint64, btpParams *BootstrappParams, skDense, skSparse *SecretKey) (btpKey *BootstrappingKey) {
+	btpKey = keygen.GenBootstrappingKey(logSlots, btpParams, skDense)
+	btpKey.swkDenseToSparse, btpKey.swkSparseToDense = keygen.GenSparseEncapsulationKeys(skDense, skSparse)
+	return
+}
 
+// GenSparseEncapsulationKeys generates the switching keys between the dense secret skDense and the sparse secret skSparse
+// used by the sparse secret encapsulation. Since the bootstrapping switches to the sparse secret at level 0, right
+// before the ModRaise, the key from skDense to skSparse only covers the first modulus, while the key back to skDense
+// covers all the moduli.
+func (keygen *keyGenerator) GenSparseEncapsulationKeys(skDense, skSparse *SecretKey) (swkDenseToSparse, swkSparseToDense *SwitchingKey) {
+	swkDenseToSparse = keygen.GenSwitchingKeyLvl(0, skDense, skSparse)
+	swkSparseToDense = keygen.GenSwitchingKey(skSparse, skDense)
 	return
 }
 
@@ -0,0 +1,314 @@
+package ckks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeyWrapper is an interface for the encryption at rest of serialized secret keys,
+// for example a client of an external key management service (KMS).
+type KeyWrapper interface {
+	Wrap(plaintext []byte) (ciphertext []byte, err error)
+	Unwrap(ciphertext []byte) (plaintext []byte, err error)
+}
+
+// KeyStore is an interface for the persistence of the keys of the CKKS scheme.
+// Keys are indexed by an identifier, and keys of different types can share the
+// same identifier.
+type KeyStore interface {
+	PutSecretKey(id string, sk *SecretKey) (err error)
+	GetSecretKey(id string) (sk *SecretKey, err error)
+	PutPublicKey(id string, pk *PublicKey) (err error)
+	GetPublicKey(id string) (pk *PublicKey, err error)
+	PutEvaluationKey(id string, evk *EvaluationKey) (err error)
+	GetEvaluationKey(id string) (evk *EvaluationKey, err error)
+	PutRotationKeys(id string, rtk *RotationKeys) (err error)
+	GetRotationKeys(id string) (rtk *RotationKeys, err error)
+	Delete(id string) (err error)
+}
+
+// ErrKeyNotFound is returned by a KeyStore when no key of the requested type is stored under the given identifier.
+var ErrKeyNotFound = errors.New("key not found")
+
+const (
+	keyStoreExtSecretKey     = ".sk"
+	keyStoreExtPublicKey     = ".pk"
+	keyStoreExtEvaluationKey = ".evk"
+	keyStoreExtRotationKeys  = ".rtk"
+)
+
+var keyStoreExtensions = []string{keyStoreExtSecretKey, keyStoreExtPublicKey, keyStoreExtEvaluationKey, keyStoreExtRotationKeys}
+
+type fileKeyStore struct {
+	dir     string
+	wrapper KeyWrapper
+}
+
+// NewFileKeyStore creates a new KeyStore storing each key in its own file in the directory dir.
+// Secret keys are always wrapped with the provided KeyWrapper before being written to disk;
+// if wrapper is nil, the returned KeyStore refuses to store or load secret keys.
+func NewFileKeyStore(dir string, wrapper KeyWrapper) (KeyStore, error) {
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cannot create file KeyStore: %s is not a directory", dir)
+	}
+
+	return &fileKeyStore{dir: dir, wrapper: wrapper}, nil
+}
+
+func (ks *fileKeyStore) path(id, ext string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid key identifier %q", id)
+	}
+	return filepath.Join(ks.dir, id+ext), nil
+}
+
+// PutSecretKey wraps and stores the SecretKey under the identifier id.
+func (ks *fileKeyStore) PutSecretKey(id string, sk *SecretKey) (err error) {
+
+	if ks.wrapper == nil {
+		return errors.New("cannot store secret key: KeyStore has no KeyWrapper")
+	}
+
+	var data []byte
+	if data, err = sk.MarshalBinary(); err != nil {
+		return err
+	}
+
+	if data, err = ks.wrapper.Wrap(data); err != nil {
+		return err
+	}
+
+	return ks.put(id, keyStoreExtSecretKey, rawKey(data))
+}
+
+// GetSecretKey loads and unwraps the SecretKey stored under the identifier id.
+func (ks *fileKeyStore) GetSecretKey(id string) (sk *SecretKey, err error) {
+
+	if ks.wrapper == nil {
+		return nil, errors.New("cannot load secret key: KeyStore has no KeyWrapper")
+	}
+
+	var data rawKey
+	if err = ks.get(id, keyStoreExtSecretKey, &data); err != nil {
+		return nil, err
+	}
+
+	if data, err = ks.wrapper.Unwrap(data); err != nil {
+		return nil, err
+	}
+
+	sk = new(SecretKey)
+	if err = sk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return sk, nil
+}
+
+// PutPublicKey stores the PublicKey under the identifier id.
+func (ks *fileKeyStore) PutPublicKey(id string, pk *PublicKey) (err error) {
+	return ks.put(id, keyStoreExtPublicKey, pk)
+}
+
+// GetPublicKey loads the PublicKey stored under the identifier id.
+func (ks *fileKeyStore) GetPublicKey(id string) (pk *PublicKey, err error) {
+	pk = new(PublicKey)
+	if err = ks.get(id, keyStoreExtPublicKey, pk); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// PutEvaluationKey stores the EvaluationKey under the identifier id.
+func (ks *fileKeyStore) PutEvaluationKey(id string, evk *EvaluationKey) (err error) {
+	return ks.put(id, keyStoreExtEvaluationKey, evk)
+}
+
+// GetEvaluationKey loads the EvaluationKey stored under the identifier id.
+func (ks *fileKeyStore) GetEvaluationKey(id string) (evk *EvaluationKey, err error) {
+	evk = new(EvaluationKey)
+	if err = ks.get(id, keyStoreExtEvaluationKey, evk); err != nil {
+		return nil, err
+	}
+	return evk, nil
+}
+
+// PutRotationKeys stores the RotationKeys under the identifier id.
+func (ks *fileKeyStore) PutRotationKeys(id string, rtk *RotationKeys) (err error) {
+	return ks.put(id, keyStoreExtRotationKeys, rtk)
+}
+
+// GetRotationKeys loads the RotationKeys stored under the identifier id.
+func (ks *fileKeyStore) GetRotationKeys(id string) (rtk *RotationKeys, err error) {
+	rtk = new(RotationKeys)
+	if err = ks.get(id, keyStoreExtRotationKeys, rtk); err != nil {
+		return nil, err
+	}
+	return rtk, nil
+}
+
+// Delete removes all the keys stored under the identifier id.
+func (ks *fileKeyStore) Delete(id string) (err error) {
+
+	found := false
+	for _, ext := range keyStoreExtensions {
+
+		var path string
+		if path, err = ks.path(id, ext); err != nil {
+			return err
+		}
+
+		if err = os.Remove(path); err == nil {
+			found = true
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if !found {
+		return ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// put writes the key in a temporary file which is then renamed, so that a
+// concurrent reader never observes a partially written key.
+func (ks *fileKeyStore) put(id, ext string, key encoding.BinaryMarshaler) (err error) {
+
+	var path string
+	if path, err = ks.path(id, ext); err != nil {
+		return err
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile(ks.dir, "."+id+ext+".tmp"); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err = WriteKey(w, key); err != nil {
+		return err
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (ks *fileKeyStore) get(id, ext string, key encoding.BinaryUnmarshaler) (err error) {
+
+	var path string
+	if path, err = ks.path(id, ext); err != nil {
+		return err
+	}
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	defer f.Close()
+
+	return ReadKey(bufio.NewReader(f), key)
+}
+
+// WriteKey writes the serialization of key on w, prefixed by its length in bytes.
+func WriteKey(w io.Writer, key encoding.BinaryMarshaler) (err error) {
+	return writeLengthPrefixed(w, key)
+}
+
+// ReadKey reads from r a key written by WriteKey and deserializes it on key.
+func ReadKey(r io.Reader, key encoding.BinaryUnmarshaler) (err error) {
+	return readLengthPrefixed(r, key)
+}
+
+func writeLengthPrefixed(w io.Writer, obj encoding.BinaryMarshaler) (err error) {
+
+	var data []byte
+	if data, err = obj.MarshalBinary(); err != nil {
+		return err
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], uint64(len(data)))
+
+	if _, err = w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+func readLengthPrefixed(r io.Reader, obj encoding.BinaryUnmarshaler) (err error) {
+
+	var header [8]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+
+	dataLen := binary.BigEndian.Uint64(header[:])
+
+	// Reads incrementally so that a corrupted header cannot trigger a huge allocation.
+	buf := new(bytes.Buffer)
+	if _, err = io.CopyN(buf, r, int64(dataLen)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	return obj.UnmarshalBinary(buf.Bytes())
+}
+
+// rawKey is an already serialized key.
+type rawKey []byte
+
+func (k rawKey) MarshalBinary() ([]byte, error) {
+	return k, nil
+}
+
+func (k *rawKey) UnmarshalBinary(data []byte) error {
+	*k = data
+	return nil
+}
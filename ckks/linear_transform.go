@@ -0,0 +1,150 @@
+package ckks
+
+import (
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// PtDiagMatrix is a plaintext matrix of size slots x slots stored by its non-zero diagonals, as used by
+// Evaluator.LinearTransform. The diagonal k is the vector (M[i][(i+k) mod slots])_i, so that the product of
+// M by a vector v is the sum over k of diag_k * rot_k(v), where rot_k is the rotation by k positions to the left.
+type PtDiagMatrix struct {
+	LogSlots uint64                // log2 of the number of slots of the matrix
+	Level    uint64                // level at which the diagonals are encoded
+	Scale    float64               // scale at which the diagonals are encoded
+	Vec      map[uint64]*ring.Poly // non-zero diagonals, indexed by rotation, in the NTT and Montgomery domain
+}
+
+// Rotations returns the rotations to the left for which the keys are needed to evaluate the matrix.
+func (matrix *PtDiagMatrix) Rotations() (rotations []uint64) {
+	for k := range matrix.Vec {
+		if k != 0 {
+			rotations = append(rotations, k)
+		}
+	}
+	return
+}
+
+// EncodeDiagMatrixAtLvl encodes the diagonals of a matrix of size 2^logSlots x 2^logSlots at the given level and scale.
+// The map diagonals associates to each non-zero diagonal its index, a negative index k being the diagonal 2^logSlots + k.
+func (encoder *encoderComplex128) EncodeDiagMatrixAtLvl(level uint64, diagonals map[int][]complex128, scale float64, logSlots uint64) (matrix *PtDiagMatrix) {
+
+	slots := uint64(1) << logSlots
+
+	matrix = &PtDiagMatrix{LogSlots: logSlots, Level: level, Scale: scale, Vec: make(map[uint64]*ring.Poly)}
+
+	for i, diagonal := range diagonals {
+
+		k := uint64(i) & (slots - 1)
+
+		if _, ok := matrix.Vec[k]; ok {
+			panic("cannot EncodeDiagMatrixAtLvl: two diagonals have the same index modulo the number of slots")
+		}
+
+		pt := NewPlaintext(encoder.params, level, scale)
+		encoder.EncodeNTT(pt, diagonal, slots)
+		encoder.ringQ.MFormLvl(level, pt.value, pt.value)
+
+		matrix.Vec[k] = pt.value
+	}
+
+	return
+}
+
+// LinearTransformNew evaluates the matrix on ct0 and returns the result in a newly created element, see LinearTransform.
+func (eval *evaluator) LinearTransformNew(ct0 *Ciphertext, matrix *PtDiagMatrix, rotkeys *RotationKeys, bias Operand) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, 1, utils.MinUint64(ct0.Level(), matrix.Level), ct0.Scale()*matrix.Scale)
+	eval.LinearTransform(ct0, matrix, rotkeys, bias, ctOut)
+	return
+}
+
+// LinearTransform evaluates the product of the matrix by the slots of ct0 and returns the result in ctOut, at the scale
+// ct0.Scale() * matrix.Scale and without rescaling. The rotations of ct0 are hoisted, and rotkeys must contain the keys
+// of matrix.Rotations().
+//
+// If bias is not nil, it is added to the result during the accumulation. The bias can be a Plaintext or a Ciphertext
+// in the NTT domain; it is scaled by the integer closest below the ratio between the scale of the result and its own
+// scale, so it should be encoded at the scale of the result, or at a scale dividing it, to avoid any error.
+func (eval *evaluator) LinearTransform(ct0 *Ciphertext, matrix *PtDiagMatrix, rotkeys *RotationKeys, bias Operand, ctOut *Ciphertext) {
+
+	if ct0.Degree() != 1 || ctOut.Degree() != 1 {
+		panic("cannot LinearTransform: input and output Ciphertext must be of degree 1")
+	}
+
+	ringQ := eval.ringQ
+
+	level := utils.MinUint64(utils.MinUint64(ct0.Level(), matrix.Level), ctOut.Level())
+
+	var biasEl *Element
+	if bias != nil {
+		if biasEl = bias.El(); biasEl.Degree() > 1 || biasEl.Level() < level {
+			panic("cannot LinearTransform: bias must be of degree 0 or 1 and at a level larger than or equal to the one of the result")
+		}
+	}
+
+	rotations := matrix.Rotations()
+	for _, k := range rotations {
+		if rotkeys == nil || !rotkeys.hasRotationKey(k) {
+			panic("cannot LinearTransform: missing rotation key")
+		}
+	}
+
+	ctRot := eval.RotateHoisted(ct0, rotations, rotkeys)
+	ctRot[0] = ct0
+
+	// The accumulation is done in the evaluator pool, as ctOut can be ct0
+	eval.growPools(level)
+	acc0, acc1 := eval.poolQMul[0], eval.poolQMul[1]
+
+	first := true
+	for k, diagonal := range matrix.Vec {
+		if first {
+			ringQ.MulCoeffsMontgomeryLvl(level, diagonal, ctRot[k].value[0], acc0)
+			ringQ.MulCoeffsMontgomeryLvl(level, diagonal, ctRot[k].value[1], acc1)
+			first = false
+		} else {
+			ringQ.MulCoeffsMontgomeryAndAddLvl(level, diagonal, ctRot[k].value[0], acc0)
+			ringQ.MulCoeffsMontgomeryAndAddLvl(level, diagonal, ctRot[k].value[1], acc1)
+		}
+	}
+
+	if first {
+		acc0.Zero()
+		acc1.Zero()
+	}
+
+	scale := ct0.Scale() * matrix.Scale
+
+	if biasEl != nil {
+
+		// Integer factor bringing the bias to the scale of the result, as done by Add
+		factor := uint64(1)
+		if scale > biasEl.Scale() {
+			factor = uint64(scale / biasEl.Scale())
+		}
+
+		if factor == 1 {
+			ringQ.AddLvl(level, acc0, biasEl.value[0], acc0)
+		} else {
+			ringQ.MulScalarLvl(level, biasEl.value[0], factor, eval.poolQMul[2])
+			ringQ.AddLvl(level, acc0, eval.poolQMul[2], acc0)
+		}
+
+		if biasEl.Degree() == 1 {
+			if factor == 1 {
+				ringQ.AddLvl(level, acc1, biasEl.value[1], acc1)
+			} else {
+				ringQ.MulScalarLvl(level, biasEl.value[1], factor, eval.poolQMul[2])
+				ringQ.AddLvl(level, acc1, eval.poolQMul[2], acc1)
+			}
+		}
+	}
+
+	ctOut.value[0].Coeffs = ctOut.value[0].Coeffs[:level+1]
+	ctOut.value[1].Coeffs = ctOut.value[1].Coeffs[:level+1]
+
+	ringQ.CopyLvl(level, acc0, ctOut.value[0])
+	ringQ.CopyLvl(level, acc1, ctOut.value[1])
+
+	ctOut.SetScale(scale)
+}
@@ -41,7 +41,7 @@ func (ciphertext *Ciphertext) MarshalBinary() (data []byte, err error) {
 
 	for _, el := range ciphertext.value {
 
-		if inc, err = el.WriteTo(data[pointer:]); err != nil {
+		if inc, err = el.Encode(data[pointer:]); err != nil {
 			return nil, err
 		}
 
@@ -100,7 +100,7 @@ func (sk *SecretKey) MarshalBinary() (data []byte, err error) {
 
 	data = make([]byte, sk.GetDataLen(true))
 
-	if _, err = sk.sk.WriteTo(data); err != nil {
+	if _, err = sk.sk.Encode(data); err != nil {
 		return nil, err
 	}
 
@@ -138,11 +138,11 @@ func (pk *PublicKey) MarshalBinary() (data []byte, err error) {
 
 	var pointer, inc uint64
 
-	if inc, err = pk.pk[0].WriteTo(data[pointer:]); err != nil {
+	if inc, err = pk.pk[0].Encode(data[pointer:]); err != nil {
 		return nil, err
 	}
 
-	if _, err = pk.pk[1].WriteTo(data[pointer+inc:]); err != nil {
+	if _, err = pk.pk[1].Encode(data[pointer+inc:]); err != nil {
 		return nil, err
 	}
 
@@ -171,31 +171,63 @@ func (pk *PublicKey) UnmarshalBinary(data []byte) (err error) {
 
 // GetDataLen returns the length in bytes of the target EvaluationKey.
 func (evaluationkey *EvaluationKey) GetDataLen(WithMetaData bool) (dataLen uint64) {
-	return evaluationkey.evakey.GetDataLen(WithMetaData)
+	dataLen = evaluationkey.evakey.GetDataLen(WithMetaData)
+	for _, swk := range evaluationkey.evakeyPow {
+		dataLen += swk.GetDataLen(WithMetaData)
+	}
+	return
 }
 
-// MarshalBinary encodes an evaluation key in a byte slice.
+// MarshalBinary encodes an evaluation key in a byte slice. The switching keys of the higher powers of the secret,
+// if any, are encoded after the one of s^2.
 func (evaluationkey *EvaluationKey) MarshalBinary() (data []byte, err error) {
 
-	var pointer uint64
-
-	dataLen := evaluationkey.evakey.GetDataLen(true)
-
-	data = make([]byte, dataLen)
+	data = make([]byte, evaluationkey.GetDataLen(true))
 
-	if _, err = evaluationkey.evakey.encode(pointer, data); err != nil {
+	if _, err = evaluationkey.encode(0, data); err != nil {
 		return nil, err
 	}
 
 	return data, nil
 }
 
+func (evaluationkey *EvaluationKey) encode(pointer uint64, data []byte) (uint64, error) {
+
+	var err error
+
+	if pointer, err = evaluationkey.evakey.encode(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	for _, swk := range evaluationkey.evakeyPow {
+		if pointer, err = swk.encode(pointer, data); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
 // UnmarshalBinary decodes a previously marshaled evaluation-key in the target evaluation-key.
 func (evaluationkey *EvaluationKey) UnmarshalBinary(data []byte) (err error) {
+
+	var pointer, inc uint64
+
 	evaluationkey.evakey = new(SwitchingKey)
-	if _, err = evaluationkey.evakey.decode(data); err != nil {
+	if pointer, err = evaluationkey.evakey.decode(data); err != nil {
 		return err
 	}
+
+	evaluationkey.evakeyPow = nil
+	for pointer < uint64(len(data)) {
+		swk := new(SwitchingKey)
+		if inc, err = swk.decode(data[pointer:]); err != nil {
+			return err
+		}
+		pointer += inc
+		evaluationkey.evakeyPow = append(evaluationkey.evakeyPow, swk)
+	}
+
 	return nil
 }
 
@@ -248,13 +280,13 @@ func (switchkey *SwitchingKey) encode(pointer uint64, data []byte) (uint64, erro
 
 	for j := uint64(0); j < uint64(len(switchkey.evakey)); j++ {
 
-		if inc, err = switchkey.evakey[j][0].WriteTo(data[pointer:]); err != nil {
+		if inc, err = switchkey.evakey[j][0].Encode(data[pointer:]); err != nil {
 			return pointer, err
 		}
 
 		pointer += inc
 
-		if inc, err = switchkey.evakey[j][1].WriteTo(data[pointer:]); err != nil {
+		if inc, err = switchkey.evakey[j][1].Encode(data[pointer:]); err != nil {
 			return pointer, err
 		}
 
@@ -316,14 +348,34 @@ func (rotationkey *RotationKeys) GetDataLen(WithMetaData bool) (dataLen uint64)
 		dataLen += rotationkey.evakeyConjugate.GetDataLen(WithMetaData)
 	}
 
+	for i := range rotationkey.evakeyAutomorphism {
+		if WithMetaData {
+			dataLen += 4
+		}
+		dataLen += rotationkey.evakeyAutomorphism[i].GetDataLen(WithMetaData)
+	}
+
 	return
 }
 
+// automorphismKeyType is the type of the keys generated by KeyGenerator.GenAutomorphismKey in a marshaled RotationKeys,
+// following the rotation types RotationRight, RotationLeft and Conjugate. Those keys are indexed by their Galois element.
+const automorphismKeyType = Conjugate + 1
+
 // MarshalBinary encodes a RotationKeys structure in a byte slice.
 func (rotationkey *RotationKeys) MarshalBinary() (data []byte, err error) {
 
 	data = make([]byte, rotationkey.GetDataLen(true))
 
+	if _, err = rotationkey.encode(0, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (rotationkey *RotationKeys) encode(pointer uint64, data []byte) (uint64, error) {
+
 	mappingColL := []uint64{}
 	mappingColR := []uint64{}
 
@@ -335,8 +387,6 @@ func (rotationkey *RotationKeys) MarshalBinary() (data []byte, err error) {
 		mappingColR = append(mappingColR, i)
 	}
 
-	var pointer uint64
-
 	for _, i := range mappingColL {
 
 		binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(i))
@@ -360,10 +410,19 @@ func (rotationkey *RotationKeys) MarshalBinary() (data []byte, err error) {
 		data[pointer] = uint8(Conjugate)
 		pointer += 4
 
-		_, _ = rotationkey.evakeyConjugate.encode(pointer, data)
+		pointer, _ = rotationkey.evakeyConjugate.encode(pointer, data)
 	}
 
-	return data, nil
+	for i := range rotationkey.evakeyAutomorphism {
+
+		binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(i))
+		data[pointer] = automorphismKeyType
+		pointer += 4
+
+		pointer, _ = rotationkey.evakeyAutomorphism[i].encode(pointer, data)
+	}
+
+	return pointer, nil
 }
 
 // UnmarshalBinary decodes a previously marshaled RotationKeys in the target RotationKeys.
@@ -401,7 +460,7 @@ func (rotationkey *RotationKeys) UnmarshalBinary(data []byte) (err error) {
 
 			N := uint64(len(rotationkey.evakeyRotColLeft[rotationNumber].evakey[0][0].Coeffs[0]))
 
-			rotationkey.permuteNTTLeftIndex[rotationNumber] = ring.PermuteNTTIndex(GaloisGen, rotationNumber, N)
+			rotationkey.permuteNTTLeftIndex[rotationNumber] = ring.PermuteNTTIndexShared(GaloisGen, rotationNumber, N)
 
 		} else if rotationType == RotationRight {
 
@@ -420,7 +479,7 @@ func (rotationkey *RotationKeys) UnmarshalBinary(data []byte) (err error) {
 
 			N := uint64(len(rotationkey.evakeyRotColRight[rotationNumber].evakey[0][0].Coeffs[0]))
 
-			rotationkey.permuteNTTRightIndex[rotationNumber] = ring.PermuteNTTIndex(GaloisGen, (2*N)-rotationNumber, N)
+			rotationkey.permuteNTTRightIndex[rotationNumber] = ring.PermuteNTTIndexShared(GaloisGen, (2*N)-rotationNumber, N)
 
 		} else if rotationType == Conjugate {
 
@@ -431,7 +490,26 @@ func (rotationkey *RotationKeys) UnmarshalBinary(data []byte) (err error) {
 
 			N := uint64(len(rotationkey.evakeyConjugate.evakey[0][0].Coeffs[0]))
 
-			rotationkey.permuteNTTConjugateIndex = ring.PermuteNTTIndex((2*N)-1, 1, N)
+			rotationkey.permuteNTTConjugateIndex = ring.PermuteNTTIndexShared((2*N)-1, 1, N)
+
+		} else if rotationType == automorphismKeyType {
+
+			if rotationkey.evakeyAutomorphism == nil {
+				rotationkey.evakeyAutomorphism = make(map[uint64]*SwitchingKey)
+			}
+
+			if rotationkey.permuteNTTAutomorphismIndex == nil {
+				rotationkey.permuteNTTAutomorphismIndex = make(map[uint64][]uint64)
+			}
+
+			rotationkey.evakeyAutomorphism[rotationNumber] = new(SwitchingKey)
+			if inc, err = rotationkey.evakeyAutomorphism[rotationNumber].decode(data[pointer:]); err != nil {
+				return err
+			}
+
+			N := uint64(len(rotationkey.evakeyAutomorphism[rotationNumber].evakey[0][0].Coeffs[0]))
+
+			rotationkey.permuteNTTAutomorphismIndex[rotationNumber] = ring.PermuteNTTIndexShared(rotationNumber, 1, N)
 
 		} else {
 
@@ -445,3 +523,192 @@ func (rotationkey *RotationKeys) UnmarshalBinary(data []byte) (err error) {
 
 	return nil
 }
+
+// GetDataLen returns the length in bytes of the target SeededCiphertext.
+func (sc *SeededCiphertext) GetDataLen(WithMetaData bool) (dataLen uint64) {
+	if WithMetaData {
+		dataLen += 9
+	}
+
+	return dataLen + SeedSize + sc.value.GetDataLen(WithMetaData)
+}
+
+// MarshalBinary encodes a SeededCiphertext on a byte slice. Only the element c0
+// and the seed of the element c1 are stored.
+func (sc *SeededCiphertext) MarshalBinary() (data []byte, err error) {
+
+	data = make([]byte, sc.GetDataLen(true))
+
+	binary.LittleEndian.PutUint64(data[0:8], math.Float64bits(sc.scale))
+
+	if sc.isNTT {
+		data[8] = 1
+	}
+
+	copy(data[9:9+SeedSize], sc.seed)
+
+	if _, err = sc.value.Encode(data[9+SeedSize:]); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled SeededCiphertext on the target SeededCiphertext.
+func (sc *SeededCiphertext) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) < 9+SeedSize {
+		return errors.New("too small bytearray")
+	}
+
+	sc.scale = math.Float64frombits(binary.LittleEndian.Uint64(data[0:8]))
+	sc.isNTT = data[8] == 1
+
+	sc.seed = make([]byte, SeedSize)
+	copy(sc.seed, data[9:9+SeedSize])
+
+	sc.value = new(ring.Poly)
+
+	var inc uint64
+	if inc, err = sc.value.DecodePolyNew(data[9+SeedSize:]); err != nil {
+		return err
+	}
+
+	if 9+SeedSize+inc != uint64(len(data)) {
+		return errors.New("remaining unparsed data")
+	}
+
+	return nil
+}
+
+// GetDataLen returns the length in bytes of the target BootstrappingKey.
+func (btpKey *BootstrappingKey) GetDataLen(WithMetaData bool) (dataLen uint64) {
+
+	if btpKey.relinkey != nil {
+		dataLen += btpKey.relinkey.GetDataLen(WithMetaData)
+	}
+
+	if btpKey.rotkeys != nil {
+		dataLen += btpKey.rotkeys.GetDataLen(WithMetaData)
+	}
+
+	if btpKey.swkDenseToSparse != nil {
+		dataLen += btpKey.swkDenseToSparse.GetDataLen(WithMetaData)
+	}
+
+	if btpKey.swkSparseToDense != nil {
+		dataLen += btpKey.swkSparseToDense.GetDataLen(WithMetaData)
+	}
+
+	if WithMetaData {
+		dataLen += 4 << 3
+	}
+
+	return
+}
+
+// MarshalBinary encodes a BootstrappingKey in a byte slice. The relinearization key, the rotation keys and the
+// switching keys of the sparse secret encapsulation are encoded one after the other, each preceded by its length,
+// which is zero for a missing key. The keys are encoded in place, so that the encoding does not need more memory
+// than its output.
+func (btpKey *BootstrappingKey) MarshalBinary() (data []byte, err error) {
+
+	data = make([]byte, btpKey.GetDataLen(true))
+
+	var pointer uint64
+
+	writeLen := func(dataLen uint64) {
+		binary.BigEndian.PutUint64(data[pointer:pointer+8], dataLen)
+		pointer += 8
+	}
+
+	if btpKey.relinkey != nil {
+		writeLen(btpKey.relinkey.GetDataLen(true))
+		if pointer, err = btpKey.relinkey.encode(pointer, data); err != nil {
+			return nil, err
+		}
+	} else {
+		writeLen(0)
+	}
+
+	if btpKey.rotkeys != nil {
+		writeLen(btpKey.rotkeys.GetDataLen(true))
+		if pointer, err = btpKey.rotkeys.encode(pointer, data); err != nil {
+			return nil, err
+		}
+	} else {
+		writeLen(0)
+	}
+
+	for _, swk := range []*SwitchingKey{btpKey.swkDenseToSparse, btpKey.swkSparseToDense} {
+		if swk != nil {
+			writeLen(swk.GetDataLen(true))
+			if pointer, err = swk.encode(pointer, data); err != nil {
+				return nil, err
+			}
+		} else {
+			writeLen(0)
+		}
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled BootstrappingKey in the target BootstrappingKey. The decoded keys
+// are not checked against the scheme parameters, see BootstrappingKey.Validate.
+func (btpKey *BootstrappingKey) UnmarshalBinary(data []byte) (err error) {
+
+	var keysData [4][]byte
+
+	for i := range keysData {
+
+		if len(data) < 8 {
+			return errors.New("invalid BootstrappingKey encoding: too short")
+		}
+
+		dataLen := binary.BigEndian.Uint64(data[:8])
+		data = data[8:]
+
+		if uint64(len(data)) < dataLen {
+			return errors.New("invalid BootstrappingKey encoding: too short")
+		}
+
+		keysData[i], data = data[:dataLen], data[dataLen:]
+	}
+
+	if len(data) != 0 {
+		return errors.New("invalid BootstrappingKey encoding: invalid length")
+	}
+
+	*btpKey = BootstrappingKey{}
+
+	if len(keysData[0]) != 0 {
+		btpKey.relinkey = new(EvaluationKey)
+		if err = btpKey.relinkey.UnmarshalBinary(keysData[0]); err != nil {
+			return err
+		}
+	}
+
+	if len(keysData[1]) != 0 {
+		btpKey.rotkeys = new(RotationKeys)
+		if err = btpKey.rotkeys.UnmarshalBinary(keysData[1]); err != nil {
+			return err
+		}
+	}
+
+	if len(keysData[2]) != 0 {
+		btpKey.swkDenseToSparse = new(SwitchingKey)
+		if err = btpKey.swkDenseToSparse.UnmarshalBinary(keysData[2]); err != nil {
+			return err
+		}
+	}
+
+	if len(keysData[3]) != 0 {
+		btpKey.swkSparseToDense = new(SwitchingKey)
+		if err = btpKey.swkSparseToDense.UnmarshalBinary(keysData[3]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package ckks
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// maxAddressableMemory32 is a conservative bound on the memory a process can allocate on a 32-bit platform.
+const maxAddressableMemory32 = 1 << 31
+
+// PolySize returns the size in bytes of a polynomial over QP.
+func (p *Parameters) PolySize() uint64 {
+	return p.N() * p.QPiCount() << 3
+}
+
+// CiphertextSize returns the size in bytes of a Ciphertext of degree one at the given level.
+func (p *Parameters) CiphertextSize(level uint64) uint64 {
+	return 2 * p.N() * (level + 1) << 3
+}
+
+// SwitchingKeySize returns the size in bytes of a SwitchingKey, that is of the relinearization key
+// or of a single rotation key. It is proportional to Beta, the number of elements of the decomposition.
+func (p *Parameters) SwitchingKeySize() uint64 {
+	return 2 * p.Beta() * p.PolySize()
+}
+
+// KeysSize returns the size in bytes of a secret key, a public key, a relinearization key and the given number of rotation keys.
+func (p *Parameters) KeysSize(rotations uint64) uint64 {
+	return 3*p.PolySize() + (1+rotations)*p.SwitchingKeySize()
+}
+
+// CheckPlatform returns an error if the parameters cannot be used on the current platform, that is if their moduli do not fit
+// the modular arithmetic of the ring package or if the keys returned by KeysSize(rotations) do not fit in maxMemory bytes.
+// If maxMemory is zero, the keys are only required to fit in the memory addressable by the platform, which is the limiting
+// factor on 32-bit platforms. The arithmetic itself does not depend on the platform, as the 64-bit operations are emulated
+// on 32-bit platforms.
+func (p *Parameters) CheckPlatform(rotations, maxMemory uint64) (err error) {
+
+	if err = checkModuli(&Moduli{p.qi, p.pi}, p.logN); err != nil {
+		return err
+	}
+
+	if maxMemory == 0 {
+		if bits.UintSize == 32 {
+			maxMemory = maxAddressableMemory32
+		} else {
+			return nil
+		}
+	}
+
+	if size := p.KeysSize(rotations); size > maxMemory {
+		return fmt.Errorf("keys with %d rotation keys need %d MB, more than the available %d MB", rotations, size>>20, maxMemory>>20)
+	}
+
+	return nil
+}
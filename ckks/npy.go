@@ -0,0 +1,409 @@
+package ckks
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Float64Array is an interface for read-only arrays of float64, which is satisfied
+// among others by the Float64 arrays of Apache Arrow (array.Float64).
+type Float64Array interface {
+	Len() int
+	Value(i int) float64
+}
+
+// EncodeFloat64Array encodes the values of arr on plaintexts of the given level and scale, each of them
+// packing at most params.Slots() values, and calls f on each plaintext. The plaintext given to f is
+// reused for the next batch and must therefore be copied if it needs to be kept.
+func EncodeFloat64Array(params *Parameters, encoder Encoder, arr Float64Array, level uint64, scale float64, f func(plaintext *Plaintext) error) (err error) {
+
+	slots := params.Slots()
+	values := make([]complex128, slots)
+	plaintext := NewPlaintext(params, level, scale)
+
+	n := uint64(arr.Len())
+
+	for start := uint64(0); start < n; start += slots {
+
+		end := start + slots
+		if end > n {
+			end = n
+		}
+
+		for i := start; i < end; i++ {
+			values[i-start] = complex(arr.Value(int(i)), 0)
+		}
+
+		for i := end - start; i < slots; i++ {
+			values[i] = 0
+		}
+
+		encoder.EncodeNTT(plaintext, values, slots)
+
+		if err = f(plaintext); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeToFloat64 decodes the plaintexts and returns the real part of their first n values
+// in a single slice, which can be used to build an Apache Arrow Float64 array.
+func DecodeToFloat64(params *Parameters, encoder Encoder, plaintexts []*Plaintext, n uint64) (res []float64) {
+
+	slots := params.Slots()
+
+	res = make([]float64, 0, n)
+
+	for _, plaintext := range plaintexts {
+
+		values := encoder.Decode(plaintext, slots)
+
+		for i := uint64(0); i < slots && uint64(len(res)) < n; i++ {
+			res = append(res, real(values[i]))
+		}
+	}
+
+	return
+}
+
+// NpyReader reads one-dimensional NumPy arrays (.npy files) of
+// little-endian float64 ('<f8') or complex128 ('<c16') by batches.
+type NpyReader struct {
+	r         *bufio.Reader
+	isComplex bool
+	len       uint64
+	read      uint64
+	buff      []byte
+}
+
+// NewNpyReader reads the header of the .npy stream r and returns a new NpyReader positioned on its first value.
+func NewNpyReader(r io.Reader) (npy *NpyReader, err error) {
+
+	npy = &NpyReader{r: bufio.NewReader(r)}
+
+	var preamble [8]byte
+	if _, err = io.ReadFull(npy.r, preamble[:]); err != nil {
+		return nil, err
+	}
+
+	if string(preamble[:6]) != "\x93NUMPY" {
+		return nil, errors.New("cannot read npy: invalid magic string")
+	}
+
+	var headerLen uint64
+	switch preamble[6] {
+	case 1:
+		var tmp [2]byte
+		if _, err = io.ReadFull(npy.r, tmp[:]); err != nil {
+			return nil, err
+		}
+		headerLen = uint64(binary.LittleEndian.Uint16(tmp[:]))
+	case 2, 3:
+		var tmp [4]byte
+		if _, err = io.ReadFull(npy.r, tmp[:]); err != nil {
+			return nil, err
+		}
+		headerLen = uint64(binary.LittleEndian.Uint32(tmp[:]))
+	default:
+		return nil, fmt.Errorf("cannot read npy: unsupported format version %d.%d", preamble[6], preamble[7])
+	}
+
+	header := make([]byte, headerLen)
+	if _, err = io.ReadFull(npy.r, header); err != nil {
+		return nil, err
+	}
+
+	if err = npy.parseHeader(string(header)); err != nil {
+		return nil, err
+	}
+
+	if npy.isComplex {
+		npy.buff = make([]byte, 16)
+	} else {
+		npy.buff = make([]byte, 8)
+	}
+
+	return npy, nil
+}
+
+func (npy *NpyReader) parseHeader(header string) (err error) {
+
+	descr, err := npyHeaderValue(header, "descr")
+	if err != nil {
+		return err
+	}
+
+	switch strings.Trim(descr, "'\"") {
+	case "<f8":
+		npy.isComplex = false
+	case "<c16":
+		npy.isComplex = true
+	default:
+		return fmt.Errorf("cannot read npy: unsupported dtype %s, only <f8 and <c16 are supported", descr)
+	}
+
+	fortran, err := npyHeaderValue(header, "fortran_order")
+	if err != nil {
+		return err
+	}
+
+	if fortran != "False" {
+		return errors.New("cannot read npy: fortran order is not supported")
+	}
+
+	shape, err := npyHeaderValue(header, "shape")
+	if err != nil {
+		return err
+	}
+
+	dims := strings.Split(strings.Trim(shape, "()"), ",")
+	if len(dims) > 2 || (len(dims) == 2 && strings.TrimSpace(dims[1]) != "") {
+		return fmt.Errorf("cannot read npy: only one-dimensional arrays are supported, shape is %s", shape)
+	}
+
+	if npy.len, err = strconv.ParseUint(strings.TrimSpace(dims[0]), 10, 64); err != nil {
+		return fmt.Errorf("cannot read npy: invalid shape %s", shape)
+	}
+
+	return nil
+}
+
+// npyHeaderValue returns the raw value associated to key in the Python dictionary literal of a .npy header.
+func npyHeaderValue(header, key string) (value string, err error) {
+
+	idx := strings.Index(header, "'"+key+"'")
+	if idx < 0 {
+		return "", fmt.Errorf("cannot read npy: missing %s in header", key)
+	}
+
+	value = strings.TrimSpace(header[idx+len(key)+2:])
+	if !strings.HasPrefix(value, ":") {
+		return "", fmt.Errorf("cannot read npy: malformed header")
+	}
+
+	value = strings.TrimSpace(value[1:])
+
+	var end int
+	if strings.HasPrefix(value, "(") {
+		end = strings.Index(value, ")") + 1
+	} else {
+		end = strings.IndexAny(value, ",}")
+	}
+
+	if end <= 0 {
+		return "", fmt.Errorf("cannot read npy: malformed header")
+	}
+
+	return strings.TrimSpace(value[:end]), nil
+}
+
+// Len returns the total number of values of the array.
+func (npy *NpyReader) Len() uint64 {
+	return npy.len
+}
+
+// IsComplex returns true if the values of the array are complex128 and false if they are float64.
+func (npy *NpyReader) IsComplex() bool {
+	return npy.isComplex
+}
+
+// Read reads up to len(values) values of the array on values and returns the number of values read.
+// It returns io.EOF once all the values of the array have been read.
+func (npy *NpyReader) Read(values []complex128) (n int, err error) {
+
+	if npy.read == npy.len {
+		return 0, io.EOF
+	}
+
+	for n = range values {
+
+		if npy.read == npy.len {
+			return n, nil
+		}
+
+		if _, err = io.ReadFull(npy.r, npy.buff); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+
+		if npy.isComplex {
+			values[n] = complex(math.Float64frombits(binary.LittleEndian.Uint64(npy.buff[:8])), math.Float64frombits(binary.LittleEndian.Uint64(npy.buff[8:])))
+		} else {
+			values[n] = complex(math.Float64frombits(binary.LittleEndian.Uint64(npy.buff)), 0)
+		}
+
+		npy.read++
+	}
+
+	return len(values), nil
+}
+
+// EncodeNpy reads the one-dimensional .npy array from r by batches of params.Slots() values, encodes each batch on a
+// plaintext of the given level and scale and calls f on it. The plaintext given to f is reused for the next batch
+// and must therefore be copied if it needs to be kept. It returns the total number of values of the array.
+func EncodeNpy(params *Parameters, encoder Encoder, r io.Reader, level uint64, scale float64, f func(plaintext *Plaintext) error) (n uint64, err error) {
+
+	var npy *NpyReader
+	if npy, err = NewNpyReader(r); err != nil {
+		return 0, err
+	}
+
+	slots := params.Slots()
+	values := make([]complex128, slots)
+	plaintext := NewPlaintext(params, level, scale)
+
+	var read int
+	for {
+
+		if read, err = npy.Read(values); err != nil {
+			if err == io.EOF {
+				return npy.Len(), nil
+			}
+			return 0, err
+		}
+
+		for i := read; i < len(values); i++ {
+			values[i] = 0
+		}
+
+		encoder.EncodeNTT(plaintext, values, slots)
+
+		if err = f(plaintext); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// NpyWriter writes one-dimensional NumPy arrays (.npy files) of
+// little-endian float64 ('<f8') or complex128 ('<c16') by batches.
+type NpyWriter struct {
+	w         *bufio.Writer
+	isComplex bool
+	len       uint64
+	written   uint64
+	buff      []byte
+}
+
+// NewNpyWriter writes on w the header of a .npy array of n values, of dtype complex128 if isComplex is
+// true and float64 otherwise, and returns a new NpyWriter on which the values can then be written.
+func NewNpyWriter(w io.Writer, n uint64, isComplex bool) (npy *NpyWriter, err error) {
+
+	npy = &NpyWriter{w: bufio.NewWriter(w), isComplex: isComplex, len: n}
+
+	descr := "<f8"
+	npy.buff = make([]byte, 8)
+	if isComplex {
+		descr = "<c16"
+		npy.buff = make([]byte, 16)
+	}
+
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d,), }", descr, n)
+
+	// The total size of the preamble and header must be a multiple of 64 and the header ends with a newline.
+	pad := 64 - (10+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+
+	if len(header) > math.MaxUint16 {
+		return nil, errors.New("cannot write npy: header too large")
+	}
+
+	var preamble [10]byte
+	copy(preamble[:], "\x93NUMPY\x01\x00")
+	binary.LittleEndian.PutUint16(preamble[8:], uint16(len(header)))
+
+	if _, err = npy.w.Write(preamble[:]); err != nil {
+		return nil, err
+	}
+
+	if _, err = npy.w.WriteString(header); err != nil {
+		return nil, err
+	}
+
+	return npy, nil
+}
+
+// Write writes the values on the array. The imaginary part of the values is discarded if the array is of dtype float64.
+func (npy *NpyWriter) Write(values []complex128) (err error) {
+
+	if npy.written+uint64(len(values)) > npy.len {
+		return errors.New("cannot write npy: too many values")
+	}
+
+	for _, v := range values {
+
+		binary.LittleEndian.PutUint64(npy.buff, math.Float64bits(real(v)))
+
+		if npy.isComplex {
+			binary.LittleEndian.PutUint64(npy.buff[8:], math.Float64bits(imag(v)))
+		}
+
+		if _, err = npy.w.Write(npy.buff); err != nil {
+			return err
+		}
+	}
+
+	npy.written += uint64(len(values))
+
+	return nil
+}
+
+// Close flushes the written values and checks that the array is complete.
+func (npy *NpyWriter) Close() (err error) {
+
+	if err = npy.w.Flush(); err != nil {
+		return err
+	}
+
+	if npy.written != npy.len {
+		return fmt.Errorf("cannot write npy: %d values written but %d expected", npy.written, npy.len)
+	}
+
+	return nil
+}
+
+// DecodeNpy decodes the plaintexts and writes their first n values on w as a one-dimensional .npy array
+// of dtype complex128 if isComplex is true and float64 otherwise.
+func DecodeNpy(params *Parameters, encoder Encoder, plaintexts []*Plaintext, n uint64, isComplex bool, w io.Writer) (err error) {
+
+	var npy *NpyWriter
+	if npy, err = NewNpyWriter(w, n, isComplex); err != nil {
+		return err
+	}
+
+	slots := params.Slots()
+
+	remaining := n
+	for _, plaintext := range plaintexts {
+
+		if remaining == 0 {
+			break
+		}
+
+		values := encoder.Decode(plaintext, slots)
+
+		if remaining < slots {
+			values = values[:remaining]
+		}
+
+		if err = npy.Write(values); err != nil {
+			return err
+		}
+
+		remaining -= uint64(len(values))
+	}
+
+	return npy.Close()
+}
@@ -19,6 +19,8 @@ type Element struct {
 	value []*ring.Poly
 	scale float64
 	isNTT bool
+
+	keyFingerprint *KeyFingerprint // key to which the element is bound, nil if unbound
 }
 
 // NewElement returns a new Element with zero values.
@@ -151,6 +153,7 @@ func (el *Element) Copy(ctxCopy *Element) (err error) {
 func (el *Element) CopyParams(Element *Element) {
 	el.SetScale(Element.Scale())
 	el.SetIsNTT(Element.IsNTT())
+	el.keyFingerprint = Element.keyFingerprint
 }
 
 // El sets the target element type to Element.
@@ -167,3 +170,29 @@ func (el *Element) Ciphertext() *Ciphertext {
 func (el *Element) Plaintext() *Plaintext {
 	return &Plaintext{el, el.value[0]}
 }
+
+// PrecomputedOperand is an Operand storing, along with a Plaintext or a Ciphertext, the Montgomery form of its
+// polynomials, so that the evaluator does not recompute it each time the operand is multiplied. It is created with
+// Evaluator.Precompute. Since the moduli Qi of the lower levels are a prefix of the ones of the upper levels, the
+// cached representation is valid at any level up to the level of the operand.
+//
+// The cache is not updated if the underlying Plaintext or Ciphertext is modified after the call to Precompute.
+type PrecomputedOperand struct {
+	*Element
+	valueMForm []*ring.Poly
+}
+
+// ValueMForm returns the Montgomery form of the polynomials of the operand.
+func (op *PrecomputedOperand) ValueMForm() []*ring.Poly {
+	return op.valueMForm
+}
+
+// valueMFormLvl returns the Montgomery form of the i-th polynomial of el at the given level, either read from the cache
+// of op if op is a PrecomputedOperand, or computed in pool otherwise.
+func valueMFormLvl(ringQ *ring.Ring, level uint64, op Operand, el *Element, i int, pool *ring.Poly) *ring.Poly {
+	if pre, ok := op.(*PrecomputedOperand); ok && pre.Element == el {
+		return pre.valueMForm[i]
+	}
+	ringQ.MFormLvl(level, el.value[i], pool)
+	return pool
+}
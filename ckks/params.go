@@ -1,6 +1,7 @@
 package ckks
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"math"
@@ -12,13 +13,14 @@ import (
 )
 
 // MaxLogN is the log2 of the largest supported polynomial modulus degree.
-const MaxLogN = 16
+const MaxLogN = 17
 
-// MaxModuliCount is the largest supported number of moduli in the RNS representation.
-const MaxModuliCount = 34
+// MaxModuliCount is the largest supported number of moduli in the RNS representation, counting both the moduli Qi
+// and the moduli Pi, which is bounded by the serialization of the polynomials.
+const MaxModuliCount = ring.MaxModuliCount
 
 // MaxModuliSize is the largest bit-length supported for the moduli in the RNS representation.
-const MaxModuliSize = 60
+const MaxModuliSize = ring.MaxModulusSize
 
 // DefaultSigma is the default error distribution standard deviation
 const DefaultSigma = 3.2
@@ -46,6 +48,9 @@ const (
 	PN15QP827pq
 	// PN16QP1654pq is the index in DefaultParams for logQP = 1654 (post quantum)
 	PN16QP1654pq
+
+	// PN17QP2015 is the index in DefaultParams for logQP = 2015
+	PN17QP2015
 )
 
 // DefaultParams is a set of default CKKS parameters ensuring 128 bit security.
@@ -174,6 +179,65 @@ var DefaultParams = []*Parameters{
 		scale: 1 << 45,
 		sigma: DefaultSigma,
 	},
+
+	//LogQiP = 2015
+	// The depth is not bounded by the security, which would allow a logQP up to 3524.
+	{logN: 17,
+		logSlots: 16,
+		qi: []uint64{0xffffffffffc0001, // 60 + 33 x 50
+			0x3ffffffb80001, 0x4000000800001, 0x4000001340001, 0x3fffffec80001,
+			0x3fffffea40001, 0x3fffffe940001, 0x4000001700001, 0x4000001bc0001,
+			0x4000002100001, 0x3fffffdd40001, 0x3fffffd900001, 0x3fffffd540001,
+			0x3fffffd500001, 0x3fffffcc40001, 0x3fffffcb40001, 0x4000003680001,
+			0x3fffffc600001, 0x3fffffc4c0001, 0x3fffffc3c0001, 0x3fffffc240001,
+			0x3fffffc0c0001, 0x4000004280001, 0x4000004400001, 0x3fffffbb00001,
+			0x3fffffbac0001, 0x3fffffb800001, 0x3fffffb7c0001, 0x3fffffb580001,
+			0x4000004e80001, 0x3fffffafc0001, 0x3fffffaf80001, 0x3fffffaf00001,
+			0x3fffffac00001},
+		pi: []uint64{0x1fffffffffe00001, 0x1fffffffffc80001, 0x1fffffffffb40001, // 5 x 61
+			0x1fffffffff500001, 0x1fffffffff380001},
+		scale: 1 << 50,
+		sigma: DefaultSigma,
+	},
+}
+
+// Name of the low-memory parameter sets
+const (
+	// PN13QP218lm is the index in LowMemoryParams for logQP = 218
+	PN13QP218lm = iota
+	// PN14QP438lm is the index in LowMemoryParams for logQP = 438
+	PN14QP438lm
+)
+
+// LowMemoryParams is a set of CKKS parameters ensuring 128 bit security for memory-constrained devices.
+// Compared to the DefaultParams of the same ring degree, they use more special primes Pi, which reduces
+// the number of elements of the switching keys (see SwitchingKeySize) at the cost of a smaller depth.
+var LowMemoryParams = []*Parameters{
+
+	//LogQiP = 218
+	{logN: 13,
+		logSlots: 12,
+		qi: []uint64{0x1fffec001, // 33 + 4 x 30
+			0x3fff4001,
+			0x3ffe8001,
+			0x40020001,
+			0x40038001},
+		pi:    []uint64{0xfff88001, 0xfff00001}, // 32, 32
+		scale: 1 << 30,
+		sigma: DefaultSigma,
+	},
+
+	//LogQiP = 438
+	{logN: 14,
+		logSlots: 13,
+		qi: []uint64{0x1ffffff18001, 0x10000048001, // 45 + 7 x 40
+			0x10000140001, 0xffffe80001,
+			0x10000290001, 0x100002b8001,
+			0xffffca8001, 0xffffc40001},
+		pi:    []uint64{0x1ffffe0001, 0x1ffffc0001, 0x1fffee8001}, // 3 x 37
+		scale: 1 << 40,
+		sigma: DefaultSigma,
+	},
 }
 
 // Moduli stores the NTT primes of the RNS representation.
@@ -232,7 +296,8 @@ type Parameters struct {
 	logN     uint64 // Ring degree (power of 2)
 	logSlots uint64
 	scale    float64
-	sigma    float64 // Gaussian sampling variance
+	sigma    float64  // Gaussian sampling variance
+	xalpha   []uint64 // Number of moduli of Q of each element of the key-switching decomposition, nil for the default one
 }
 
 // NewParametersFromModuli creates a new Parameters struct and returns a pointer to it.
@@ -271,6 +336,56 @@ func NewParametersFromLogModuli(logN uint64, lm *LogModuli) (p *Parameters, err
 	return NewParametersFromModuli(logN, genModuli(lm, logN))
 }
 
+// logQ0Headroom is the number of bits by which the first modulus exceeds the scale, so that
+// messages of magnitude up to 2^logQ0Headroom can be decrypted at the last level.
+const logQ0Headroom = 10
+
+// GenModuliForDepthAndPrecision returns the LogModuli of a moduli chain supporting depth rescalings of
+// a scale of logScale bits, which can then be instantiated with NewParametersFromLogModuli.
+// The middle moduli are of logScale bits, so that the scale stays close to 2^logScale after each rescaling.
+// The first modulus is larger, to leave room for the message at the last level, and the special modulus
+// is of logSpecial bits, which must be at least the size of the first modulus to keep the key-switching
+// error below the rescaling error.
+func GenModuliForDepthAndPrecision(logN, depth, logScale, logSpecial uint64) (lm *LogModuli, err error) {
+
+	if logN > MaxLogN {
+		return nil, fmt.Errorf("logN is larger than %d", MaxLogN)
+	}
+
+	if logScale == 0 || logScale > MaxModuliSize {
+		return nil, fmt.Errorf("logScale must be between 1 and %d", MaxModuliSize)
+	}
+
+	if depth+2 > MaxModuliCount {
+		return nil, fmt.Errorf("depth is larger than %d", MaxModuliCount-2)
+	}
+
+	logQ0 := logScale + logQ0Headroom
+	if logQ0 > MaxModuliSize {
+		logQ0 = MaxModuliSize
+	}
+
+	if logSpecial < logQ0 {
+		return nil, fmt.Errorf("logSpecial must be at least %d", logQ0)
+	}
+
+	lm = &LogModuli{
+		LogQi: make([]uint64, depth+1),
+		LogPi: []uint64{logSpecial},
+	}
+
+	lm.LogQi[0] = logQ0
+	for i := uint64(1); i < depth+1; i++ {
+		lm.LogQi[i] = logScale
+	}
+
+	if err = checkLogModuli(lm); err != nil {
+		return nil, err
+	}
+
+	return lm, nil
+}
+
 // NewPolyQ returns a new empty polynomial of degree 2^LogN in basis Qi.
 func (p *Parameters) NewPolyQ() *ring.Poly {
 	return ring.NewPoly(p.N(), p.QiCount())
@@ -469,23 +584,17 @@ func (p *Parameters) LogP() uint64 {
 // the key-switching wont be negligible.
 func (p *Parameters) LogQAlpha() uint64 {
 
-	alpha := p.PiCount()
-
-	if alpha == 0 {
+	if p.PiCount() == 0 {
 		return 0
 	}
 
 	res := ring.NewUint(0)
-	var j uint64
-	for i := uint64(0); i < p.QiCount(); i = i + alpha {
+	for i := uint64(0); i < p.Beta(); i++ {
 
-		j = i + alpha
-		if j > p.QiCount() {
-			j = p.QiCount()
-		}
+		start, end := p.DecompositionDigit(i)
 
 		tmp := ring.NewUint(1)
-		for _, qi := range p.pi[i:j] {
+		for _, qi := range p.qi[start:end] {
 			tmp.Mul(tmp, ring.NewUint(qi))
 		}
 
@@ -495,13 +604,19 @@ func (p *Parameters) LogQAlpha() uint64 {
 	return uint64(res.BitLen())
 }
 
-// Alpha returns the number of moduli in in P
+// Alpha returns the number of moduli in in P, which is the number of moduli of Q of the elements
+// of the default decomposition.
 func (p *Parameters) Alpha() uint64 {
 	return p.PiCount()
 }
 
-// Beta returns the number of element in the RNS decomposition basis: Ceil(lenQi / lenPi)
+// Beta returns the number of element in the RNS decomposition basis, which is Ceil(lenQi / lenPi)
+// for the default decomposition.
 func (p *Parameters) Beta() uint64 {
+	if p.xalpha != nil {
+		return uint64(len(p.xalpha))
+	}
+
 	if p.Alpha() != 0 {
 		return uint64(math.Ceil(float64(p.QiCount()) / float64(p.Alpha())))
 	}
@@ -509,6 +624,87 @@ func (p *Parameters) Beta() uint64 {
 	return 0
 }
 
+// BetaLvl returns the number of elements of the RNS decomposition basis needed to decompose a
+// polynomial at the given level, which is the number of elements of a switching key at this level.
+func (p *Parameters) BetaLvl(level uint64) (beta uint64) {
+	for beta < p.Beta() {
+		if start, _ := p.DecompositionDigit(beta); start > level {
+			break
+		}
+		beta++
+	}
+	return
+}
+
+// Decomposition returns the number of moduli of Q of each element of the RNS decomposition basis
+// used by the key-switching.
+func (p *Parameters) Decomposition() (xalpha []uint64) {
+
+	if p.xalpha != nil {
+		return append([]uint64{}, p.xalpha...)
+	}
+
+	xalpha = make([]uint64, p.Beta())
+	for i := range xalpha {
+		start, end := p.DecompositionDigit(uint64(i))
+		xalpha[i] = end - start
+	}
+
+	return
+}
+
+// DecompositionDigit returns the range [start, end) of the indexes of the moduli of Q of the i-th
+// element of the RNS decomposition basis.
+func (p *Parameters) DecompositionDigit(i uint64) (start, end uint64) {
+
+	if p.xalpha == nil {
+		start = i * p.Alpha()
+		return start, utils.MinUint64(start+p.Alpha(), p.QiCount())
+	}
+
+	for _, alphai := range p.xalpha[:i] {
+		start += alphai
+	}
+
+	return start, start + p.xalpha[i]
+}
+
+// SetDecomposition sets the RNS decomposition basis used by the key-switching: its i-th element is
+// the product of xalpha[i] moduli of Q, following the moduli of the previous elements. Elements with
+// more moduli than P give smaller switching keys, as they have fewer elements, but a larger
+// key-switching error (see LogQAlpha). Giving more moduli to the last elements therefore reduces
+// the size of the keys at the top levels. A nil xalpha restores the default decomposition, whose
+// elements have #Pi moduli.
+//
+// The keys must be generated, and the Evaluator created, with the same decomposition.
+func (p *Parameters) SetDecomposition(xalpha []uint64) (err error) {
+
+	if xalpha == nil {
+		p.xalpha = nil
+		return nil
+	}
+
+	if p.PiCount() == 0 {
+		return errors.New("invalid decomposition: parameters have no modulus P")
+	}
+
+	var sum uint64
+	for _, alphai := range xalpha {
+		if alphai == 0 {
+			return errors.New("invalid decomposition: elements must have at least one modulus")
+		}
+		sum += alphai
+	}
+
+	if sum != p.QiCount() {
+		return fmt.Errorf("invalid decomposition: elements have %d moduli but Q has %d", sum, p.QiCount())
+	}
+
+	p.xalpha = append([]uint64{}, xalpha...)
+
+	return nil
+}
+
 // Copy creates a copy of the target parameters.
 func (p *Parameters) Copy() (paramsCopy *Parameters) {
 
@@ -521,6 +717,9 @@ func (p *Parameters) Copy() (paramsCopy *Parameters) {
 	copy(paramsCopy.qi, p.qi)
 	paramsCopy.pi = make([]uint64, len(p.pi), len(p.pi))
 	copy(paramsCopy.pi, p.pi)
+	if p.xalpha != nil {
+		paramsCopy.xalpha = append([]uint64{}, p.xalpha...)
+	}
 	return
 }
 
@@ -537,9 +736,17 @@ func (p *Parameters) Equals(other *Parameters) (res bool) {
 	res = res && (p.sigma == other.sigma)
 	res = res && utils.EqualSliceUint64(p.qi, other.qi)
 	res = res && utils.EqualSliceUint64(p.pi, other.pi)
+	res = res && utils.EqualSliceUint64(p.Decomposition(), other.Decomposition())
 	return
 }
 
+// Hash returns the SHA-256 digest of the binary representation of the parameter set, which identifies
+// the parameters in serialized objects that do not embed them.
+func (p *Parameters) Hash() (digest [32]byte) {
+	data, _ := p.MarshalBinary()
+	return sha256.Sum256(data)
+}
+
 // MarshalBinary returns a []byte representation of the parameter set.
 func (p *Parameters) MarshalBinary() ([]byte, error) {
 	if p.logN == 0 { // if N is 0, then p is the zero value
@@ -557,6 +764,14 @@ func (p *Parameters) MarshalBinary() ([]byte, error) {
 	b.WriteUint64Slice(p.qi)
 	b.WriteUint64Slice(p.pi)
 
+	// The decomposition is only written if it is not the default one
+	if p.xalpha != nil {
+		b.WriteUint8(uint8(len(p.xalpha)))
+		for _, alphai := range p.xalpha {
+			b.WriteUint8(uint8(alphai))
+		}
+	}
+
 	return b.Bytes(), nil
 }
 
@@ -597,27 +812,41 @@ func (p *Parameters) UnmarshalBinary(data []byte) (err error) {
 		return err
 	}
 
+	p.xalpha = nil
+
+	if data = b.Bytes(); len(data) != 0 {
+
+		if len(data) != int(data[0])+1 {
+			return errors.New("invalid parameters encoding")
+		}
+
+		xalpha := make([]uint64, data[0])
+		for i := range xalpha {
+			xalpha[i] = uint64(data[i+1])
+		}
+
+		if err = p.SetDecomposition(xalpha); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func checkModuli(m *Moduli, logN uint64) error {
 
-	if len(m.Qi) > MaxModuliCount {
-		return fmt.Errorf("#Qi is larger than %d", MaxModuliCount)
-	}
-
-	if len(m.Pi) > MaxModuliCount {
-		return fmt.Errorf("#Pi is larger than %d", MaxModuliCount)
+	if len(m.Qi)+len(m.Pi) > MaxModuliCount {
+		return fmt.Errorf("#Qi + #Pi is larger than %d", MaxModuliCount)
 	}
 
 	for i, qi := range m.Qi {
-		if uint64(bits.Len64(qi)-1) > MaxModuliSize+1 {
+		if uint64(bits.Len64(qi)) > MaxModuliSize {
 			return fmt.Errorf("Qi bit-size (i=%d) is larger than %d", i, MaxModuliSize)
 		}
 	}
 
 	for i, pi := range m.Pi {
-		if uint64(bits.Len64(pi)-1) > MaxModuliSize+2 {
+		if uint64(bits.Len64(pi)) > MaxModuliSize {
 			return fmt.Errorf("Pi bit-size (i=%d) is larger than %d", i, MaxModuliSize)
 		}
 	}
@@ -641,12 +870,8 @@ func checkModuli(m *Moduli, logN uint64) error {
 
 func checkLogModuli(m *LogModuli) error {
 
-	if len(m.LogQi) > MaxModuliCount {
-		return fmt.Errorf("#LogQi is larger than %d", MaxModuliCount)
-	}
-
-	if len(m.LogPi) > MaxModuliCount {
-		return fmt.Errorf("#LogPi is larger than %d", MaxModuliCount)
+	if len(m.LogQi)+len(m.LogPi) > MaxModuliCount {
+		return fmt.Errorf("#LogQi + #LogPi is larger than %d", MaxModuliCount)
 	}
 
 	for i, qi := range m.LogQi {
@@ -656,7 +881,7 @@ func checkLogModuli(m *LogModuli) error {
 	}
 
 	for i, pi := range m.LogPi {
-		if pi > MaxModuliSize+1 {
+		if pi > MaxModuliSize {
 			return fmt.Errorf("LogPi (i=%d) is larger than %d", i, MaxModuliSize)
 		}
 	}
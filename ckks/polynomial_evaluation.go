@@ -94,7 +94,7 @@ func (eval *evaluator) EvaluateCheby(op *Ciphertext, cheby *ChebyshevInterpolati
 
 	eval.MultByConst(C[1], 2/(cheby.b-cheby.a), C[1])
 	eval.AddConst(C[1], (-cheby.a-cheby.b)/(cheby.b-cheby.a), C[1])
-	eval.Rescale(C[1], eval.scale, C[1])
+	eval.Rescale(C[1], eval.rescaleThreshold(), C[1])
 
 	return eval.evalCheby(cheby, C, evakey)
 }
@@ -109,7 +109,7 @@ func (eval *evaluator) EvaluateChebySpecial(ct *Ciphertext, n complex128, cheby
 
 	eval.MultByConst(C[1], 2/((cheby.b-cheby.a)*n), C[1])
 	eval.AddConst(C[1], (-cheby.a-cheby.b)/(cheby.b-cheby.a), C[1])
-	eval.Rescale(C[1], eval.scale, C[1])
+	eval.Rescale(C[1], eval.rescaleThreshold(), C[1])
 
 	return eval.evalCheby(cheby, C, evakey)
 }
@@ -149,7 +149,7 @@ func computePowerBasis(n uint64, C map[uint64]*Ciphertext, evaluator *evaluator,
 		// Computes C[n] = C[a]*C[b]
 		C[n] = evaluator.MulRelinNew(C[a], C[b], evakey)
 
-		evaluator.Rescale(C[n], evaluator.scale, C[n])
+		evaluator.Rescale(C[n], evaluator.rescaleThreshold(), C[n])
 	}
 }
 
@@ -182,7 +182,7 @@ func computePowerBasisCheby(n uint64, C map[uint64]*Ciphertext, evaluator *evalu
 		// Computes C[n] = C[a]*C[b]
 		//fmt.Println("Mul", C[a].Level(), C[b].Level())
 		C[n] = evaluator.MulRelinNew(C[a], C[b], evakey)
-		evaluator.Rescale(C[n], evaluator.scale, C[n])
+		evaluator.Rescale(C[n], evaluator.rescaleThreshold(), C[n])
 
 		// Computes C[n] = 2*C[a]*C[b]
 		evaluator.Add(C[n], C[n], C[n])
@@ -310,13 +310,13 @@ func recurse(targetScale float64, logSplit, logDegree uint64, coeffs *Poly, C ma
 	evaluator.MulRelin(res, C[nextPower], evakey, res)
 
 	if res.Level() > tmp.Level() {
-		evaluator.Rescale(res, evaluator.scale, res)
+		evaluator.Rescale(res, evaluator.rescaleThreshold(), res)
 		//fmt.Printf("%f = %d) + (%d %f) = ", res.Scale(), res.Level(), tmp.Level(), tmp.Scale())
 		evaluator.Add(res, tmp, res)
 		//fmt.Printf("(%d %f) %f\n", res.Level(), res.Scale(), res.Scale()-tmp.Scale())
 	} else {
 		evaluator.Add(res, tmp, res)
-		evaluator.Rescale(res, evaluator.scale, res)
+		evaluator.Rescale(res, evaluator.rescaleThreshold(), res)
 	}
 
 	tmp = nil
@@ -374,13 +374,13 @@ func recurseCheby(targetScale float64, logSplit, logDegree uint64, coeffs *Poly,
 	evaluator.MulRelin(res, C[nextPower], evakey, res)
 
 	if res.Level() > tmp.Level() {
-		evaluator.Rescale(res, evaluator.scale, res)
+		evaluator.Rescale(res, evaluator.rescaleThreshold(), res)
 		//fmt.Printf("%f = %d) + (%d %f) = ", res.Scale(), res.Level(), tmp.Level(), tmp.Scale())
 		evaluator.Add(res, tmp, res)
 		//fmt.Printf("(%d %f) %f\n", res.Level(), res.Scale(), res.Scale()-tmp.Scale())
 	} else {
 		evaluator.Add(res, tmp, res)
-		evaluator.Rescale(res, evaluator.scale, res)
+		evaluator.Rescale(res, evaluator.rescaleThreshold(), res)
 	}
 
 	tmp = nil
@@ -430,7 +430,7 @@ func evaluatePolyFromPowerBasis(targetScale float64, coeffs *Poly, C map[uint64]
 		}
 	}
 
-	evaluator.Rescale(res, evaluator.scale, res)
+	evaluator.Rescale(res, evaluator.rescaleThreshold(), res)
 
 	return
 }
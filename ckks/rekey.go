@@ -0,0 +1,94 @@
+package ckks
+
+import (
+	"io"
+)
+
+// Rekeyer is a struct storing the switching key and the evaluator needed to
+// re-encrypt ciphertexts from an old secret key to a new one, for example to
+// rotate the secret key of a deployment.
+type Rekeyer struct {
+	params       *Parameters
+	evaluator    Evaluator
+	switchingKey *SwitchingKey
+}
+
+// NewRekeyer creates a new Rekeyer re-encrypting ciphertexts from skOld to skNew.
+func NewRekeyer(params *Parameters, skOld, skNew *SecretKey) *Rekeyer {
+	return NewRekeyerFromSwitchingKey(params, NewKeyGenerator(params).GenSwitchingKey(skOld, skNew))
+}
+
+// NewRekeyerFromSwitchingKey creates a new Rekeyer from an already generated switching key,
+// so that the party performing the re-encryption does not need access to the secret keys.
+func NewRekeyerFromSwitchingKey(params *Parameters, switchingKey *SwitchingKey) *Rekeyer {
+	return &Rekeyer{
+		params:       params.Copy(),
+		evaluator:    NewEvaluator(params),
+		switchingKey: switchingKey,
+	}
+}
+
+// SwitchingKey returns the switching key used by the Rekeyer.
+func (rk *Rekeyer) SwitchingKey() *SwitchingKey {
+	return rk.switchingKey
+}
+
+// RekeyNew re-encrypts ct under the new secret key and returns the result in a newly created Ciphertext.
+func (rk *Rekeyer) RekeyNew(ct *Ciphertext) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(rk.params, 1, ct.Level(), ct.Scale())
+	rk.Rekey(ct, ctOut)
+	return
+}
+
+// Rekey re-encrypts ct under the new secret key and returns the result in ctOut.
+func (rk *Rekeyer) Rekey(ct *Ciphertext, ctOut *Ciphertext) {
+	rk.evaluator.SwitchKeys(ct, rk.switchingKey, ctOut)
+}
+
+// RekeyBatch re-encrypts in place each Ciphertext of the collection under the new secret key.
+func (rk *Rekeyer) RekeyBatch(cts []*Ciphertext) {
+	for _, ct := range cts {
+		rk.Rekey(ct, ct)
+	}
+}
+
+// RekeyStream reads a sequence of ciphertexts from r, re-encrypts them under the new secret key
+// and writes them on w, until r is exhausted. Ciphertexts are read and written one at a time, each
+// prefixed by its length in bytes on 8 bytes, so that archives larger than the available memory can
+// be processed. It returns the number of ciphertexts that were re-encrypted.
+func (rk *Rekeyer) RekeyStream(r io.Reader, w io.Writer) (n uint64, err error) {
+
+	ct := new(Ciphertext)
+
+	for {
+
+		if err = readLengthPrefixed(r, ct); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		rk.Rekey(ct, ct)
+
+		if err = writeLengthPrefixed(w, ct); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+}
+
+// WriteCiphertext writes ct on w, prefixed by its length in bytes, in the format expected by RekeyStream.
+func WriteCiphertext(w io.Writer, ct *Ciphertext) (err error) {
+	return writeLengthPrefixed(w, ct)
+}
+
+// ReadCiphertext reads from r a Ciphertext written by WriteCiphertext.
+func ReadCiphertext(r io.Reader) (ct *Ciphertext, err error) {
+	ct = new(Ciphertext)
+	if err = readLengthPrefixed(r, ct); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
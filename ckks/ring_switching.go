@@ -0,0 +1,244 @@
+package ckks
+
+import (
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// RingDegreeSwitcher is a struct storing the switching keys and the evaluator needed to move
+// ciphertexts between a ring of degree N and a ring of degree N/2 sharing the same moduli Qi.
+//
+// A Ciphertext of degree N is first re-encrypted under the secret key of degree N/2 embedded
+// in the large ring as s(X^2), after which its even coefficients form a valid encryption in the
+// ring of degree N/2. Only the messages encoded on at most N/4 slots, whose odd coefficients are
+// zero, are therefore preserved by ToSmallRing.
+type RingDegreeSwitcher struct {
+	paramsLarge *Parameters
+	paramsSmall *Parameters
+
+	ringQLarge *ring.Ring
+	ringQSmall *ring.Ring
+
+	evaluator Evaluator
+
+	swkLargeToSmall *SwitchingKey
+	swkSmallToLarge *SwitchingKey
+
+	ctPool   *Ciphertext
+	polyPool *ring.Poly
+}
+
+// GenRingDegreeSwitchingKeys generates the switching keys from skLarge to skSmall and from skSmall to skLarge, where
+// skLarge is a secret key of paramsLarge and skSmall a secret key of paramsSmall. paramsSmall must be of degree N/2 and
+// its moduli Qi must be the first moduli Qi of paramsLarge. The keys only cover the levels of paramsSmall.
+func GenRingDegreeSwitchingKeys(paramsLarge, paramsSmall *Parameters, skLarge, skSmall *SecretKey) (swkLargeToSmall, swkSmallToLarge *SwitchingKey) {
+
+	checkRingDegreeSwitchingParameters(paramsLarge, paramsSmall)
+
+	skEmbedded := embedSecretKey(paramsLarge, paramsSmall, skSmall)
+
+	kgen := NewKeyGenerator(paramsLarge)
+
+	level := paramsSmall.MaxLevel()
+
+	swkLargeToSmall = kgen.GenSwitchingKeyLvl(level, skLarge, skEmbedded)
+	swkSmallToLarge = kgen.GenSwitchingKeyLvl(level, skEmbedded, skLarge)
+
+	return
+}
+
+// NewRingDegreeSwitcher creates a new RingDegreeSwitcher between paramsLarge and paramsSmall, generating
+// the switching keys from the secret keys skLarge and skSmall.
+func NewRingDegreeSwitcher(paramsLarge, paramsSmall *Parameters, skLarge, skSmall *SecretKey) *RingDegreeSwitcher {
+	swkLargeToSmall, swkSmallToLarge := GenRingDegreeSwitchingKeys(paramsLarge, paramsSmall, skLarge, skSmall)
+	return NewRingDegreeSwitcherFromKeys(paramsLarge, paramsSmall, swkLargeToSmall, swkSmallToLarge)
+}
+
+// NewRingDegreeSwitcherFromKeys creates a new RingDegreeSwitcher from switching keys generated with
+// GenRingDegreeSwitchingKeys. Either key can be nil if the corresponding direction is not needed.
+func NewRingDegreeSwitcherFromKeys(paramsLarge, paramsSmall *Parameters, swkLargeToSmall, swkSmallToLarge *SwitchingKey) *RingDegreeSwitcher {
+
+	checkRingDegreeSwitchingParameters(paramsLarge, paramsSmall)
+
+	ringQLarge, err := ring.NewRing(paramsLarge.N(), paramsLarge.qi)
+	if err != nil {
+		panic(err)
+	}
+
+	ringQSmall, err := ring.NewRing(paramsSmall.N(), paramsSmall.qi)
+	if err != nil {
+		panic(err)
+	}
+
+	return &RingDegreeSwitcher{
+		paramsLarge:     paramsLarge.Copy(),
+		paramsSmall:     paramsSmall.Copy(),
+		ringQLarge:      ringQLarge,
+		ringQSmall:      ringQSmall,
+		evaluator:       NewEvaluator(paramsLarge),
+		swkLargeToSmall: swkLargeToSmall,
+		swkSmallToLarge: swkSmallToLarge,
+		ctPool:          NewCiphertext(paramsLarge, 1, paramsSmall.MaxLevel(), 0),
+		polyPool:        ringQSmall.NewPoly(),
+	}
+}
+
+// ToSmallRingNew switches ctIn from the ring of degree N to the ring of degree N/2 and returns the result in a newly created Ciphertext.
+func (rds *RingDegreeSwitcher) ToSmallRingNew(ctIn *Ciphertext) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(rds.paramsSmall, 1, utils.MinUint64(ctIn.Level(), rds.paramsSmall.MaxLevel()), ctIn.Scale())
+	rds.ToSmallRing(ctIn, ctOut)
+	return
+}
+
+// ToSmallRing switches ctIn, a Ciphertext of degree N, to the ring of degree N/2 and returns the result in ctOut.
+// The output level is the minimum between the levels of ctIn and ctOut.
+func (rds *RingDegreeSwitcher) ToSmallRing(ctIn *Ciphertext, ctOut *Ciphertext) {
+
+	if rds.swkLargeToSmall == nil {
+		panic("cannot ToSmallRing: RingDegreeSwitcher has no switching key to the small ring")
+	}
+
+	if ctIn.Degree() != 1 || ctOut.Degree() != 1 {
+		panic("cannot ToSmallRing: input and output Ciphertext must be of degree 1")
+	}
+
+	if uint64(len(ctIn.Value()[0].Coeffs[0])) != rds.paramsLarge.N() || uint64(len(ctOut.Value()[0].Coeffs[0])) != rds.paramsSmall.N() {
+		panic("cannot ToSmallRing: input Ciphertext must be of degree N and output Ciphertext of degree N/2")
+	}
+
+	level := utils.MinUint64(ctIn.Level(), ctOut.Level())
+
+	ctTmp := rds.ctPool
+	ctTmp.Value()[0].Coeffs = ctTmp.Value()[0].Coeffs[:level+1]
+	ctTmp.Value()[1].Coeffs = ctTmp.Value()[1].Coeffs[:level+1]
+
+	rds.evaluator.SwitchKeys(ctIn, rds.swkLargeToSmall, ctTmp)
+
+	for i := range ctTmp.Value() {
+
+		rds.ringQLarge.InvNTTLvl(level, ctTmp.Value()[i], ctTmp.Value()[i])
+
+		for j := uint64(0); j < level+1; j++ {
+			tmpLarge, tmpSmall := ctTmp.Value()[i].Coeffs[j], ctOut.Value()[i].Coeffs[j]
+			for k := range tmpSmall {
+				tmpSmall[k] = tmpLarge[2*k]
+			}
+		}
+
+		rds.ringQSmall.NTTLvl(level, ctOut.Value()[i], ctOut.Value()[i])
+	}
+
+	ctOut.Value()[0].Coeffs = ctOut.Value()[0].Coeffs[:level+1]
+	ctOut.Value()[1].Coeffs = ctOut.Value()[1].Coeffs[:level+1]
+
+	ctOut.SetScale(ctIn.Scale())
+}
+
+// ToLargeRingNew switches ctIn from the ring of degree N/2 to the ring of degree N and returns the result in a newly created Ciphertext.
+func (rds *RingDegreeSwitcher) ToLargeRingNew(ctIn *Ciphertext) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(rds.paramsLarge, 1, ctIn.Level(), ctIn.Scale())
+	rds.ToLargeRing(ctIn, ctOut)
+	return
+}
+
+// ToLargeRing switches ctIn, a Ciphertext of degree N/2, to the ring of degree N and returns the result in ctOut.
+// The output level is the minimum between the levels of ctIn and ctOut.
+func (rds *RingDegreeSwitcher) ToLargeRing(ctIn *Ciphertext, ctOut *Ciphertext) {
+
+	if rds.swkSmallToLarge == nil {
+		panic("cannot ToLargeRing: RingDegreeSwitcher has no switching key to the large ring")
+	}
+
+	if ctIn.Degree() != 1 || ctOut.Degree() != 1 {
+		panic("cannot ToLargeRing: input and output Ciphertext must be of degree 1")
+	}
+
+	if uint64(len(ctIn.Value()[0].Coeffs[0])) != rds.paramsSmall.N() || uint64(len(ctOut.Value()[0].Coeffs[0])) != rds.paramsLarge.N() {
+		panic("cannot ToLargeRing: input Ciphertext must be of degree N/2 and output Ciphertext of degree N")
+	}
+
+	level := utils.MinUint64(ctIn.Level(), ctOut.Level())
+
+	ctTmp := rds.ctPool
+	ctTmp.Value()[0].Coeffs = ctTmp.Value()[0].Coeffs[:level+1]
+	ctTmp.Value()[1].Coeffs = ctTmp.Value()[1].Coeffs[:level+1]
+
+	// c(Y) -> c(X^2), which is an encryption of m(X^2) under s(X^2)
+	for i := range ctIn.Value() {
+
+		rds.ringQSmall.InvNTTLvl(level, ctIn.Value()[i], rds.polyPool)
+
+		for j := uint64(0); j < level+1; j++ {
+			tmpSmall, tmpLarge := rds.polyPool.Coeffs[j], ctTmp.Value()[i].Coeffs[j]
+			for k := range tmpSmall {
+				tmpLarge[2*k] = tmpSmall[k]
+				tmpLarge[2*k+1] = 0
+			}
+		}
+
+		rds.ringQLarge.NTTLvl(level, ctTmp.Value()[i], ctTmp.Value()[i])
+	}
+
+	ctTmp.SetScale(ctIn.Scale())
+
+	rds.evaluator.SwitchKeys(ctTmp, rds.swkSmallToLarge, ctOut)
+
+	ctOut.Value()[0].Coeffs = ctOut.Value()[0].Coeffs[:level+1]
+	ctOut.Value()[1].Coeffs = ctOut.Value()[1].Coeffs[:level+1]
+
+	ctOut.SetScale(ctIn.Scale())
+}
+
+func checkRingDegreeSwitchingParameters(paramsLarge, paramsSmall *Parameters) {
+
+	if paramsLarge.LogN() != paramsSmall.LogN()+1 {
+		panic("cannot switch ring degree: the degree of the small parameters must be half the degree of the large parameters")
+	}
+
+	if paramsSmall.QiCount() > paramsLarge.QiCount() {
+		panic("cannot switch ring degree: the small parameters have more moduli Qi than the large parameters")
+	}
+
+	for i, qi := range paramsSmall.qi {
+		if paramsLarge.qi[i] != qi {
+			panic("cannot switch ring degree: the moduli Qi of the small parameters must be the first moduli Qi of the large parameters")
+		}
+	}
+}
+
+// embedSecretKey returns the secret key s(X^2) of paramsLarge, where s(Y) is the secret key skSmall of paramsSmall.
+func embedSecretKey(paramsLarge, paramsSmall *Parameters, skSmall *SecretKey) (skEmbedded *SecretKey) {
+
+	ringQPSmall, err := ring.NewRing(paramsSmall.N(), append(paramsSmall.Qi(), paramsSmall.Pi()...))
+	if err != nil {
+		panic(err)
+	}
+
+	ringQPLarge, err := ring.NewRing(paramsLarge.N(), append(paramsLarge.Qi(), paramsLarge.Pi()...))
+	if err != nil {
+		panic(err)
+	}
+
+	tmp := skSmall.Get().CopyNew()
+	ringQPSmall.InvNTT(tmp, tmp)
+	ringQPSmall.InvMForm(tmp, tmp)
+
+	skEmbedded = NewSecretKey(paramsLarge)
+
+	// The coefficients of the secret are small, so they can be read centered modulo the first modulus
+	q0 := ringQPSmall.Modulus[0]
+	for k, c := range tmp.Coeffs[0] {
+		for j, qj := range ringQPLarge.Modulus {
+			if c > q0>>1 {
+				skEmbedded.sk.Coeffs[j][2*k] = (qj - (q0-c)%qj) % qj
+			} else {
+				skEmbedded.sk.Coeffs[j][2*k] = c % qj
+			}
+		}
+	}
+
+	ringQPLarge.MForm(skEmbedded.sk, skEmbedded.sk)
+	ringQPLarge.NTT(skEmbedded.sk, skEmbedded.sk)
+
+	return
+}
@@ -0,0 +1,152 @@
+package ckks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// RotationPlan is a decomposition of a rotation of the columns into a sequence of rotations for which keys are available.
+type RotationPlan struct {
+	// Rotation is the requested rotation to the left.
+	Rotation uint64
+	// Steps are the rotations applied in order, a positive value being a rotation to the left
+	// and a negative value a rotation to the right. Each step costs one key-switching.
+	Steps []int
+}
+
+// KeySwitches returns the number of key-switching operations needed to apply the plan.
+func (plan *RotationPlan) KeySwitches() int {
+	return len(plan.Steps)
+}
+
+// String returns a human readable representation of the plan.
+func (plan *RotationPlan) String() string {
+	steps := make([]string, len(plan.Steps))
+	for i, s := range plan.Steps {
+		steps[i] = fmt.Sprintf("%+d", s)
+	}
+	return fmt.Sprintf("rotation by %d = %s (%d key-switches)", plan.Rotation, strings.Join(steps, " "), plan.KeySwitches())
+}
+
+// CanRotate returns true if RotateColumns can rotate the columns by k positions to the left with the given rotation keys,
+// along with the plan it would follow. The plan has a single step if the key for k is available. Otherwise, it is the
+// decomposition into pow2 rotations if all of them are available, or else the shortest sequence of available left and
+// right rotations whose sum is k modulo the number of slots.
+func (eval *evaluator) CanRotate(k uint64, evakey *RotationKeys) (ok bool, plan *RotationPlan) {
+
+	slots := eval.ringQ.N >> 1
+
+	k &= slots - 1
+
+	if k == 0 {
+		return true, &RotationPlan{}
+	}
+
+	if evakey == nil {
+		return false, nil
+	}
+
+	if evakey.hasRotationKey(k) {
+		return true, &RotationPlan{Rotation: k, Steps: []int{int(k)}}
+	}
+
+	if hasPow2Rotations(slots, evakey) {
+
+		plan = &RotationPlan{Rotation: k}
+
+		sign, r := 1, k
+		if utils.HammingWeight64(k) > utils.HammingWeight64(slots-k) {
+			sign, r = -1, slots-k
+		}
+
+		for i := uint64(1); r != 0; i, r = i<<1, r>>1 {
+			if r&1 == 1 {
+				plan.Steps = append(plan.Steps, sign*int(i))
+			}
+		}
+
+		return true, plan
+	}
+
+	if plan = findRotationPlan(slots, k, evakey); plan == nil {
+		return false, nil
+	}
+
+	return true, plan
+}
+
+// hasPow2Rotations returns true if evakey contains the keys of all the left and right rotations by a power of two.
+func hasPow2Rotations(slots uint64, evakey *RotationKeys) bool {
+	for i := uint64(1); i < slots; i <<= 1 {
+		if evakey.evakeyRotColLeft[i] == nil || evakey.evakeyRotColRight[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// findRotationPlan searches, by a breadth-first search over the rotations modulo slots, the shortest sequence
+// of rotations with an available key that rotates by k positions to the left. It returns nil if there is none.
+func findRotationPlan(slots, k uint64, evakey *RotationKeys) (plan *RotationPlan) {
+
+	var steps []int
+	for i := range evakey.evakeyRotColLeft {
+		steps = append(steps, int(i))
+	}
+	for i := range evakey.evakeyRotColRight {
+		steps = append(steps, -int(i))
+	}
+
+	// Deterministic plans regardless of the iteration order of the maps
+	sort.Ints(steps)
+
+	// prev[r] is the last step of the shortest sequence reaching r, visited[r] whether r has been reached
+	prev := make([]int, slots)
+	visited := make([]bool, slots)
+	visited[0] = true
+
+	queue := []uint64{0}
+
+	for len(queue) != 0 && !visited[k] {
+
+		r := queue[0]
+		queue = queue[1:]
+
+		for _, s := range steps {
+			next := (r + uint64(s)) & (slots - 1)
+			if !visited[next] {
+				visited[next] = true
+				prev[next] = s
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	if !visited[k] {
+		return nil
+	}
+
+	plan = &RotationPlan{Rotation: k}
+	for r := k; r != 0; r = (r - uint64(prev[r])) & (slots - 1) {
+		plan.Steps = append([]int{prev[r]}, plan.Steps...)
+	}
+
+	return
+}
+
+// rotateColumnsWithPlan applies the rotations of plan to ct0 and returns the result in ctOut.
+func (eval *evaluator) rotateColumnsWithPlan(ct0 *Ciphertext, plan *RotationPlan, evakey *RotationKeys, ctOut *Ciphertext) {
+
+	ctIn := ct0
+	for _, s := range plan.Steps {
+		if s > 0 {
+			eval.permuteNTT(ctIn, evakey.permuteNTTLeftIndex[uint64(s)], evakey.evakeyRotColLeft[uint64(s)], ctOut)
+		} else {
+			eval.permuteNTT(ctIn, evakey.permuteNTTRightIndex[uint64(-s)], evakey.evakeyRotColRight[uint64(-s)], ctOut)
+		}
+		ctIn = ctOut
+	}
+}
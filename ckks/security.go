@@ -0,0 +1,132 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+)
+
+// SecretDistribution is the distribution of the secret key considered by the security estimator.
+type SecretDistribution int
+
+// Secret distributions supported by the security estimator.
+const (
+	// SecretTernary is a uniform secret in {-1, 0, 1}, as generated by KeyGenerator.GenSecretKey.
+	SecretTernary SecretDistribution = iota
+	// SecretGaussian is a secret following the error distribution.
+	SecretGaussian
+	// SecretUniform is a secret uniform modulo QP.
+	SecretUniform
+)
+
+// securityLevels are the security levels of the lookup tables, in bits.
+var securityLevels = [3]float64{128, 192, 256}
+
+// securityTables gives, for each secret distribution, the largest logQP achieving each of the securityLevels for logN = 10 to 17.
+// The values up to logN = 15 are the ones of the Homomorphic Encryption Security Standard (classical attacks, sigma = 3.2);
+// the values for logN = 16 and 17 are extrapolated, the maximum logQP being roughly linear in N.
+var securityTables = map[SecretDistribution][8][3]float64{
+	SecretTernary: {
+		{27, 19, 14},
+		{54, 37, 29},
+		{109, 75, 58},
+		{218, 152, 118},
+		{438, 305, 237},
+		{881, 611, 476},
+		{1761, 1221, 951},
+		{3524, 2443, 1903},
+	},
+	SecretGaussian: {
+		{29, 21, 16},
+		{56, 39, 31},
+		{111, 77, 60},
+		{220, 153, 120},
+		{440, 304, 239},
+		{883, 610, 477},
+		{1765, 1219, 953},
+		{3530, 2438, 1906},
+	},
+	SecretUniform: {
+		{29, 21, 16},
+		{56, 39, 31},
+		{111, 77, 60},
+		{220, 153, 120},
+		{440, 300, 239},
+		{883, 606, 478},
+		{1765, 1211, 955},
+		{3530, 2422, 1910},
+	},
+}
+
+// MinSecurityLevel is the default threshold, in bits, used by CheckSecurityLevel.
+var MinSecurityLevel = 128.0
+
+// SecurityLevel returns a conservative estimate of the security of the parameters in bits, assuming a ternary secret.
+// See SecurityLevelWithSecret.
+func (p *Parameters) SecurityLevel() float64 {
+	return p.SecurityLevelWithSecret(SecretTernary)
+}
+
+// SecurityLevelWithSecret returns a conservative estimate of the security of the parameters in bits against classical
+// attacks, for a secret of the given distribution. The estimate interpolates the tables of the Homomorphic Encryption
+// Security Standard, the security being taken as linear in 1/logQP between the tabulated levels. It returns 0 for
+// logN < 10, for which no estimate is available. Sparse secrets, such as the ones used for the bootstrapping, provide
+// less security than estimated for ternary secrets.
+func (p *Parameters) SecurityLevelWithSecret(dist SecretDistribution) float64 {
+
+	table, ok := securityTables[dist]
+	if !ok {
+		panic("cannot SecurityLevel: unknown secret distribution")
+	}
+
+	if p.logN < 10 || p.logN-10 >= uint64(len(table)) {
+		return 0
+	}
+
+	logQP := p.logQPFloat()
+
+	row := table[p.logN-10]
+
+	// Beyond the table, extrapolates with the closest tabulated level
+	if logQP >= row[0] {
+		return securityLevels[0] * row[0] / logQP
+	}
+
+	if logQP <= row[len(row)-1] {
+		return securityLevels[len(row)-1] * row[len(row)-1] / logQP
+	}
+
+	for i := 1; i < len(row); i++ {
+		if logQP >= row[i] {
+			t := (1/logQP - 1/row[i-1]) / (1/row[i] - 1/row[i-1])
+			return securityLevels[i-1] + t*(securityLevels[i]-securityLevels[i-1])
+		}
+	}
+
+	return securityLevels[len(row)-1]
+}
+
+// CheckSecurityLevel returns an error if the estimated security of the parameters, assuming a ternary secret,
+// is below minLevel bits. If minLevel is zero, MinSecurityLevel is used instead.
+func (p *Parameters) CheckSecurityLevel(minLevel float64) (err error) {
+
+	if minLevel == 0 {
+		minLevel = MinSecurityLevel
+	}
+
+	if level := p.SecurityLevel(); level < minLevel {
+		return fmt.Errorf("estimated security of %.1f bits is below the required %.1f bits", level, minLevel)
+	}
+
+	return nil
+}
+
+// logQPFloat returns the size of the extended modulus QP in bits, without rounding.
+func (p *Parameters) logQPFloat() (logQP float64) {
+	for _, qi := range p.qi {
+		logQP += math.Log2(float64(qi))
+	}
+	for _, pi := range p.pi {
+		logQP += math.Log2(float64(pi))
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package ckks
+
+import (
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// SeedSize is the size in bytes of the seed of a SeededCiphertext.
+const SeedSize = 32
+
+// SeededCiphertext is a Ciphertext of degree 1 encrypted with the secret key, whose uniform
+// element c1 is not stored but generated from a seed. It is about half the size of the
+// corresponding Ciphertext and is intended to be sent by clients to a server, which
+// recovers the Ciphertext with Expand.
+type SeededCiphertext struct {
+	value *ring.Poly
+	seed  []byte
+	scale float64
+	isNTT bool
+}
+
+// NewSeededCiphertext creates a new SeededCiphertext of the given level and scale with zero values.
+func NewSeededCiphertext(params *Parameters, level uint64, scale float64) *SeededCiphertext {
+	return &SeededCiphertext{
+		value: ring.NewPoly(params.N(), level+1),
+		seed:  make([]byte, SeedSize),
+		scale: scale,
+		isNTT: true,
+	}
+}
+
+// Level returns the level of the SeededCiphertext.
+func (sc *SeededCiphertext) Level() uint64 {
+	return uint64(len(sc.value.Coeffs) - 1)
+}
+
+// Scale returns the scale of the SeededCiphertext.
+func (sc *SeededCiphertext) Scale() float64 {
+	return sc.scale
+}
+
+// Seed returns the seed from which the element c1 of the SeededCiphertext is generated.
+func (sc *SeededCiphertext) Seed() []byte {
+	return sc.seed
+}
+
+// Expand returns the Ciphertext represented by the SeededCiphertext, generating its element c1 from the seed.
+func (sc *SeededCiphertext) Expand(params *Parameters) (ct *Ciphertext) {
+
+	if uint64(sc.value.GetDegree()) != params.N() {
+		panic("cannot Expand: SeededCiphertext ring degree does not match params ring degree")
+	}
+
+	ct = NewCiphertext(params, 1, sc.Level(), sc.scale)
+	ct.value[0].Copy(sc.value)
+	ct.isNTT = sc.isNTT
+
+	ringQ, err := ring.NewRing(params.N(), params.qi)
+	if err != nil {
+		panic(err)
+	}
+
+	sampleSeededCRP(ringQ, sc.seed, sc.Level(), ct.value[1])
+
+	return
+}
+
+// sampleSeededCRP samples the uniform polynomial p up to the given level from the seed, in the NTT domain.
+func sampleSeededCRP(ringQ *ring.Ring, seed []byte, level uint64, p *ring.Poly) {
+
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		panic(err)
+	}
+
+	ring.NewUniformSamplerWithDomain(prng, ringQ, true, false).ReadLvl(level, p)
+}
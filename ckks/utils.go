@@ -156,58 +156,46 @@ func scaleDown(coeff *big.Int, n float64) (x float64) {
 	return
 }
 
-func genBigIntChain(Q []uint64) (bigintChain []*big.Int) {
+// scaleDownBigFloat divides coeff by scale with the precision of scale and returns the closest float64.
+func scaleDownBigFloat(coeff *big.Int, scale *big.Float) (x float64) {
 
-	bigintChain = make([]*big.Int, len(Q))
-	bigintChain[0] = ring.NewUint(Q[0])
-	for i := 1; i < len(Q); i++ {
-		bigintChain[i] = ring.NewUint(Q[i])
-		bigintChain[i].Mul(bigintChain[i], bigintChain[i-1])
+	prec := scale.Prec()
+	if prec < 53 {
+		prec = 53
 	}
-	return
-}
 
-// GenSwitchkeysRescalingParams generates the parameters for rescaling the switching keys
-func GenSwitchkeysRescalingParams(Q, P []uint64) (params []uint64) {
+	xFlo := new(big.Float).SetPrec(prec + uint(coeff.BitLen()))
+	xFlo.SetInt(coeff)
+	xFlo.Quo(xFlo, scale)
 
-	params = make([]uint64, len(Q))
+	x, _ = xFlo.Float64()
 
-	PBig := ring.NewUint(1)
-	for _, pj := range P {
-		PBig.Mul(PBig, ring.NewUint(pj))
-	}
+	return
+}
 
-	tmp := ring.NewUint(0)
+// centerUint64 returns the representative of coeff mod Q in [-Q/2, Q/2) as a *big.Int.
+func centerUint64(coeff, Q uint64) *big.Int {
+	if coeff >= Q>>1 {
+		return new(big.Int).Neg(ring.NewUint(Q - coeff))
+	}
+	return ring.NewUint(coeff)
+}
 
-	for i := 0; i < len(Q); i++ {
+func genBigIntChain(Q []uint64) (bigintChain []*big.Int) {
 
-		params[i] = tmp.Mod(PBig, ring.NewUint(Q[i])).Uint64()
-		params[i] = ring.ModExp(params[i], Q[i]-2, Q[i])
-		params[i] = ring.MForm(params[i], Q[i], ring.BRedParams(Q[i]))
+	bigintChain = make([]*big.Int, len(Q))
+	bigintChain[0] = ring.NewUint(Q[0])
+	for i := 1; i < len(Q); i++ {
+		bigintChain[i] = ring.NewUint(Q[i])
+		bigintChain[i].Mul(bigintChain[i], bigintChain[i-1])
 	}
-
 	return
 }
 
-func sliceBitReverseInPlaceComplex128(slice []complex128, N uint64) {
-
-	var bit, j uint64
-
-	for i := uint64(1); i < N; i++ {
-
-		bit = N >> 1
-
-		for j >= bit {
-			j -= bit
-			bit >>= 1
-		}
-
-		j += bit
-
-		if i < j {
-			slice[i], slice[j] = slice[j], slice[i]
-		}
-	}
+// GenSwitchkeysRescalingParams generates the parameters for rescaling the switching keys.
+// It is equivalent to ring.GenModDownParams.
+func GenSwitchkeysRescalingParams(Q, P []uint64) (params []uint64) {
+	return ring.GenModDownParams(Q, P)
 }
 
 func sliceBitReverseInPlaceRingComplex(slice []*ring.Complex, N uint64) {
@@ -59,12 +59,13 @@ func newDbfvContext(params *bfv.Parameters) *dbfvContext {
 	}
 }
 
-// NewCRPGenerator creates a new deterministic random polynomial generator.
+// NewCRPGenerator creates a new deterministic random polynomial generator. The sampled polynomials are common
+// reference polynomials of QP in the NTT and Montgomery domain.
 func NewCRPGenerator(params *bfv.Parameters, key []byte) *ring.UniformSampler {
 	ctx := newDbfvContext(params)
 	prng, err := utils.NewKeyedPRNG(key)
 	if err != nil {
 		panic(err)
 	}
-	return ring.NewUniformSampler(prng, ctx.ringQP)
+	return ring.NewUniformSamplerWithDomain(prng, ctx.ringQP, true, true)
 }
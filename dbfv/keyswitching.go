@@ -17,7 +17,7 @@ type CKSProtocol struct {
 	hP       *ring.Poly
 
 	baseconverter   *ring.FastBasisExtender
-	gaussianSampler *ring.GaussianSampler
+	gaussianSampler ring.Sampler
 }
 
 // CKSShare is a type for the CKS protocol shares.
@@ -104,7 +104,7 @@ func (cks *CKSProtocol) genShareDelta(skDelta *ring.Poly, ct *bfv.Ciphertext, sh
 		}
 	}
 
-	cks.baseconverter.ModDownSplitPQ(level, shareOut.Poly, cks.hP, shareOut.Poly)
+	cks.baseconverter.ModDownSplitQPtoQ(level, shareOut.Poly, cks.hP, shareOut.Poly)
 
 	cks.tmpNtt.Zero()
 	cks.hP.Zero()
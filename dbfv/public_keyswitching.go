@@ -17,8 +17,8 @@ type PCKSProtocol struct {
 	share1tmp *ring.Poly
 
 	baseconverter            *ring.FastBasisExtender
-	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
 // PCKSShare is a type for the PCKS protocol shares.
@@ -36,12 +36,12 @@ func (share *PCKSShare) MarshalBinary() ([]byte, error) {
 	lenR2 := share[1].GetDataLen(true)
 
 	data := make([]byte, lenR1+lenR2)
-	_, err := share[0].WriteTo(data[0:lenR1])
+	_, err := share[0].Encode(data[0:lenR1])
 	if err != nil {
 		return []byte{}, err
 	}
 
-	_, err = share[1].WriteTo(data[lenR1 : lenR1+lenR2])
+	_, err = share[1].Encode(data[lenR1 : lenR1+lenR2])
 	if err != nil {
 		return []byte{}, err
 	}
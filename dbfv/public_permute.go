@@ -17,8 +17,8 @@ type PermuteProtocol struct {
 	hP              *ring.Poly
 	baseconverter   *ring.FastBasisExtender
 	scaler          ring.Scaler
-	gaussianSampler *ring.GaussianSampler
-	uniformSampler  *ring.UniformSampler
+	gaussianSampler ring.Sampler
+	uniformSampler  ring.Sampler
 }
 
 // NewPermuteProtocol creates a new instance of the PermuteProtocol.
@@ -76,7 +76,8 @@ func (pp *PermuteProtocol) AllocateShares() RefreshShare {
 		pp.context.ringQ.NewPoly()}
 }
 
-// GenShares generates the shares of the PermuteProtocol
+// GenShares generates the shares of the PermuteProtocol. The common reference polynomial crs of QP is read in the
+// NTT domain, as sampled by a CRPGenerator.
 func (pp *PermuteProtocol) GenShares(sk *ring.Poly, ciphertext *bfv.Ciphertext, crs *ring.Poly, permutation []uint64, share RefreshShare) {
 
 	level := uint64(len(ciphertext.Value()[1].Coeffs) - 1)
@@ -107,11 +108,10 @@ func (pp *PermuteProtocol) GenShares(sk *ring.Poly, ciphertext *bfv.Ciphertext,
 	}
 
 	// h0 = (s*ct[1]*P + e)/P
-	pp.baseconverter.ModDownSplitPQ(level, share.RefreshShareDecrypt, pp.hP, share.RefreshShareDecrypt)
+	pp.baseconverter.ModDownSplitQPtoQ(level, share.RefreshShareDecrypt, pp.hP, share.RefreshShareDecrypt)
 
-	// h1 = -s*a
-	ringQP.NTT(crs, pp.tmp1)
-	ringQP.MulCoeffsMontgomery(sk, pp.tmp1, pp.tmp2)
+	// h1 = -s*a, the crs being in the NTT domain
+	ringQP.MulCoeffsMontgomery(sk, crs, pp.tmp2)
 	ringQP.Neg(pp.tmp2, pp.tmp2)
 	ringQP.InvNTT(pp.tmp2, pp.tmp2)
 
@@ -182,7 +182,9 @@ func (pp *PermuteProtocol) Recrypt(sharePlaintext *ring.Poly, crs *ring.Poly, sh
 	pp.context.ringQ.Add(sharePlaintext, shareRecrypt, ciphertextOut.Value()[0])
 
 	// ciphertext[1] = crs/P
-	pp.baseconverter.ModDownPQ(uint64(len(ciphertextOut.Value()[1].Coeffs)-1), crs, ciphertextOut.Value()[1])
+	level := uint64(len(ciphertextOut.Value()[1].Coeffs) - 1)
+	pp.baseconverter.ModDownQPtoQNTT(level, crs, ciphertextOut.Value()[1])
+	pp.context.ringQ.InvNTTLvl(level, ciphertextOut.Value()[1], ciphertextOut.Value()[1])
 
 }
 
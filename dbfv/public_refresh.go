@@ -17,8 +17,8 @@ type RefreshProtocol struct {
 	hP              *ring.Poly
 	baseconverter   *ring.FastBasisExtender
 	scaler          ring.Scaler
-	gaussianSampler *ring.GaussianSampler
-	uniformSampler  *ring.UniformSampler
+	gaussianSampler ring.Sampler
+	uniformSampler  ring.Sampler
 }
 
 // RefreshShareDecrypt is a struct storing the decrpytion share.
@@ -43,13 +43,13 @@ func (share *RefreshShare) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint64(data[8:16], lenRecrypt)
 
 	ptr := uint64(16)
-	tmp, err := (*share.RefreshShareDecrypt).WriteTo(data[ptr : ptr+lenDecrypt])
+	tmp, err := (*share.RefreshShareDecrypt).Encode(data[ptr : ptr+lenDecrypt])
 	if err != nil {
 		return []byte{}, err
 	}
 
 	ptr += tmp
-	tmp, err = (*share.RefreshShareRecrypt).WriteTo(data[ptr : ptr+lenRecrypt])
+	tmp, err = (*share.RefreshShareRecrypt).Encode(data[ptr : ptr+lenRecrypt])
 	if err != nil {
 		return []byte{}, err
 	}
@@ -110,7 +110,8 @@ func (rfp *RefreshProtocol) AllocateShares() RefreshShare {
 		rfp.context.ringQ.NewPoly()}
 }
 
-// GenShares generates a share for the Refresh protocol.
+// GenShares generates a share for the Refresh protocol. The common reference polynomial crs of QP is read in the
+// NTT domain, as sampled by a CRPGenerator.
 func (rfp *RefreshProtocol) GenShares(sk *ring.Poly, ciphertext *bfv.Ciphertext, crs *ring.Poly, share RefreshShare) {
 
 	level := uint64(len(ciphertext.Value()[1].Coeffs) - 1)
@@ -140,11 +141,10 @@ func (rfp *RefreshProtocol) GenShares(sk *ring.Poly, ciphertext *bfv.Ciphertext,
 	}
 
 	// h0 = (s*ct[1]*P + e)/P
-	rfp.baseconverter.ModDownSplitPQ(level, share.RefreshShareDecrypt, rfp.hP, share.RefreshShareDecrypt)
+	rfp.baseconverter.ModDownSplitQPtoQ(level, share.RefreshShareDecrypt, rfp.hP, share.RefreshShareDecrypt)
 
-	// h1 = -s*a
-	ringQP.NTT(crs, rfp.tmp1)
-	ringQP.MulCoeffsMontgomery(sk, rfp.tmp1, rfp.tmp2)
+	// h1 = -s*a, the crs being in the NTT domain
+	ringQP.MulCoeffsMontgomery(sk, crs, rfp.tmp2)
 	ringQP.Neg(rfp.tmp2, rfp.tmp2)
 	ringQP.InvNTT(rfp.tmp2, rfp.tmp2)
 
@@ -189,7 +189,9 @@ func (rfp *RefreshProtocol) Recrypt(sharePlaintext *ring.Poly, crs *ring.Poly, s
 	rfp.context.ringQ.Add(sharePlaintext, shareRecrypt, ciphertextOut.Value()[0])
 
 	// ciphertext[1] = crs/P
-	rfp.baseconverter.ModDownPQ(uint64(len(ciphertextOut.Value()[1].Coeffs)-1), crs, ciphertextOut.Value()[1])
+	level := uint64(len(ciphertextOut.Value()[1].Coeffs) - 1)
+	rfp.baseconverter.ModDownQPtoQNTT(level, crs, ciphertextOut.Value()[1])
+	rfp.context.ringQ.InvNTTLvl(level, ciphertextOut.Value()[1], ciphertextOut.Value()[1])
 
 }
 
@@ -11,7 +11,7 @@ import (
 type CKGProtocol struct {
 	ringQP          *ring.Ring
 	sigma           float64
-	gaussianSampler *ring.GaussianSampler
+	gaussianSampler ring.Sampler
 }
 
 // CKGShare is a struct holding a CKG share.
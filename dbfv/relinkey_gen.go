@@ -15,8 +15,8 @@ type RKGProtocol struct {
 	tmpPoly1                 *ring.Poly
 	tmpPoly2                 *ring.Poly
 	polypool                 *ring.Poly
-	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
 // RKGShare is a share of the RKGProtocol
@@ -36,12 +36,12 @@ func (share *RKGShare) MarshalBinary() ([]byte, error) {
 	//write all the polys
 	ptr := uint64(1)
 	for _, elem := range *share {
-		_, err := elem[0].WriteTo(data[ptr : ptr+rLength])
+		_, err := elem[0].Encode(data[ptr : ptr+rLength])
 		if err != nil {
 			return []byte{}, err
 		}
 		ptr += rLength
-		_, err = elem[1].WriteTo(data[ptr : ptr+rLength])
+		_, err = elem[1].Encode(data[ptr : ptr+rLength])
 		if err != nil {
 			return []byte{}, err
 		}
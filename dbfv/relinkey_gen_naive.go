@@ -10,8 +10,8 @@ import (
 type RKGProtocolNaive struct {
 	context                  *dbfvContext
 	polypool                 *ring.Poly
-	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
 // NewRKGProtocolNaive creates a new RKGProtocolNaive object that will be used to generate a collective evaluation-key
@@ -18,7 +18,7 @@ type RTGProtocol struct {
 
 	tmpSwitchKey    [][2]*ring.Poly
 	tmpPoly         [2]*ring.Poly
-	gaussianSampler *ring.GaussianSampler
+	gaussianSampler ring.Sampler
 }
 
 // RTGShare is the structure storing the shares of the RTG protocol
@@ -37,7 +37,7 @@ func (share *RTGShare) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint64(data[16:24], lenRing)
 	ptr := uint64(24)
 	for _, val := range share.Value {
-		cnt, err := val.WriteTo(data[ptr : ptr+lenRing])
+		cnt, err := val.Encode(data[ptr : ptr+lenRing])
 		if err != nil {
 			return []byte{}, err
 		}
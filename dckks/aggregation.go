@@ -0,0 +1,140 @@
+package dckks
+
+import (
+	"context"
+	"fmt"
+)
+
+// With many parties, the aggregation of the shares by a single party costs it the memory and bandwidth of all the
+// shares. The parties can instead be arranged in an AggregationTree: each party aggregates the shares of its children
+// with its own share and forwards the result to its parent, so that the root obtains the aggregate of all the shares
+// while each party only handles the shares of its children.
+
+// AggregationTree is a tree of parties of given arity for the hierarchical aggregation of shares, in which the party
+// at the index i of the list of parties has as children the parties at the indexes arity*i+1 up to arity*i+arity.
+// The first party of the list is the root, which obtains the aggregate of all the shares.
+type AggregationTree struct {
+	parties []uint64
+	index   map[uint64]int
+	arity   int
+}
+
+// NewAggregationTree creates a new AggregationTree of the given arity on the list of distinct parties.
+func NewAggregationTree(parties []uint64, arity int) *AggregationTree {
+
+	if len(parties) == 0 {
+		panic("cannot NewAggregationTree: no parties")
+	}
+
+	if arity < 1 {
+		panic("cannot NewAggregationTree: arity must be at least 1")
+	}
+
+	tree := new(AggregationTree)
+	tree.parties = append([]uint64{}, parties...)
+	tree.index = make(map[uint64]int, len(parties))
+	tree.arity = arity
+
+	for i, party := range parties {
+		if _, ok := tree.index[party]; ok {
+			panic(fmt.Sprintf("cannot NewAggregationTree: party %d is not unique", party))
+		}
+		tree.index[party] = i
+	}
+
+	return tree
+}
+
+// Root returns the party at the root of the tree.
+func (tree *AggregationTree) Root() uint64 {
+	return tree.parties[0]
+}
+
+// Parent returns the parent of the party, and false if the party is the root.
+func (tree *AggregationTree) Parent(party uint64) (parent uint64, ok bool) {
+
+	i := tree.indexOf(party)
+
+	if i == 0 {
+		return 0, false
+	}
+
+	return tree.parties[(i-1)/tree.arity], true
+}
+
+// Children returns the children of the party, from which it receives shares.
+func (tree *AggregationTree) Children(party uint64) (children []uint64) {
+
+	i := tree.indexOf(party)
+
+	for j := tree.arity*i + 1; j <= tree.arity*i+tree.arity && j < len(tree.parties); j++ {
+		children = append(children, tree.parties[j])
+	}
+
+	return
+}
+
+func (tree *AggregationTree) indexOf(party uint64) int {
+	i, ok := tree.index[party]
+	if !ok {
+		panic(fmt.Sprintf("party %d is not in the AggregationTree", party))
+	}
+	return i
+}
+
+// AggregateMany adds all the shares on the first one and returns it. It allocates no memory, and the shares other than
+// the first one are left unchanged.
+func (cks *CKSProtocol) AggregateMany(shares []CKSShare) CKSShare {
+
+	if len(shares) == 0 {
+		panic("cannot AggregateMany: no shares")
+	}
+
+	for i := 1; i < len(shares); i++ {
+		cks.AggregateShares(shares[i], shares[0], shares[0])
+	}
+
+	return shares[0]
+}
+
+// AggregateTree runs the aggregation of the local party of tr in the tree for the round: it receives the aggregated
+// shares of its children, validates them against the level of share, adds them to share and, unless it is the root,
+// forwards the result to its parent. On return, the share of the root is the aggregate of the shares of all the
+// parties. The shares are received with the Party of the child which sent them, and forwarded with the Party of the
+// local party.
+func (cks *CKSProtocol) AggregateTree(ctx context.Context, tr Transport, tree *AggregationTree, round RoundTag, share CKSShare) (err error) {
+
+	party := tr.Party()
+	level := uint64(len(share.Poly.Coeffs)) - 1
+
+	if children := tree.Children(party); len(children) > 0 {
+
+		received, err := tr.ReceiveShares(ctx, round, children)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+
+			childShare := new(CKSShare)
+			if err = childShare.UnmarshalBinary(received[child]); err != nil {
+				return fmt.Errorf("cannot AggregateTree: invalid share of party %d: %s", child, err)
+			}
+
+			if childShare.Party != child {
+				return fmt.Errorf("cannot AggregateTree: share of party %d received from party %d", childShare.Party, child)
+			}
+
+			if err = cks.ValidateAndAggregate(level, *childShare, share); err != nil {
+				return fmt.Errorf("cannot AggregateTree: invalid share of party %d: %s", child, err)
+			}
+		}
+	}
+
+	if parent, ok := tree.Parent(party); ok {
+		share.Party = party
+		return tr.SendShare(ctx, parent, round, &share)
+	}
+
+	return nil
+}
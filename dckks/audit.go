@@ -0,0 +1,358 @@
+package dckks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// DecryptionAudit is an audit record of a collective decryption (or key-switching) with the CKSProtocol. It is a
+// commitment log, and not a proof of correct decryption. It binds together the parameters, the input ciphertext, a
+// commitment to the share of each party and to their aggregate, and seals them with a transcript hash signed by each
+// party. The record can be stored alongside the decryption output so that, given the ciphertext and the broadcast
+// shares, it can later be checked that these are the shares the parties signed for. Nothing in the record shows that
+// a share was correctly generated from the secret key of its party, nor that the decryption output is correct.
+//
+// Each party checks with Open that the record commits to its share before signing it with Sign, so that a record
+// carrying the signatures of all the parties cannot be forged for other shares without their private keys. The
+// commitments are binding but not hiding: the salt of each commitment is stored next to its digest, so anyone holding
+// the record can test a guess of a share. The record must not be published if the shares are to be kept secret.
+type DecryptionAudit struct {
+	ParamsHash          [32]byte
+	CiphertextHash      [32]byte
+	ShareCommitments    []ShareCommitment
+	AggregateCommitment ShareCommitment
+	TranscriptHash      [32]byte
+
+	// Signatures holds the ed25519 signature of the transcript hash by each party, in the order of the shares, or nil
+	// for the parties which did not sign yet.
+	Signatures [][]byte
+}
+
+// ShareCommitment is a commitment to a CKSShare: the SHA-256 digest of the share salted with 32 random bytes, which
+// are needed to open it. The salt is part of the commitment, hence whoever holds the commitment can test a guess of
+// the share: the commitment binds to the share, but does not hide it.
+type ShareCommitment struct {
+	Salt   [32]byte
+	Digest [32]byte
+}
+
+// CommitShare returns a commitment, with a fresh random salt, to a CKSShare generated on a ciphertext at the given
+// level.
+func CommitShare(level uint64, share CKSShare) (commitment ShareCommitment, err error) {
+	if _, err = rand.Read(commitment.Salt[:]); err != nil {
+		return commitment, err
+	}
+	commitment.Digest = saltedShareDigest("dckks/CKS/share", commitment.Salt, level, share.Poly)
+	return commitment, nil
+}
+
+// Open returns true if the commitment is a commitment to the share at the given level.
+func (commitment ShareCommitment) Open(level uint64, share CKSShare) bool {
+	return commitment.open(saltedShareDigest("dckks/CKS/share", commitment.Salt, level, share.Poly))
+}
+
+func (commitment ShareCommitment) open(digest [32]byte) bool {
+	return subtle.ConstantTimeCompare(digest[:], commitment.Digest[:]) == 1
+}
+
+func saltedShareDigest(domain string, salt [32]byte, level uint64, polys ...*ring.Poly) (digest [32]byte) {
+	h := sha256.New()
+	h.Write([]byte(domain))
+	h.Write(salt[:])
+	for _, pol := range polys {
+		hashPolyLvl(h, level, pol)
+	}
+	copy(digest[:], h.Sum(nil))
+	return
+}
+
+// GenAudit generates the audit record of the collective decryption of ct with the given shares, in the order of
+// the parties, and their aggregate combined. The record is not signed yet. It returns an error if combined is not the
+// sum of the shares.
+func (cks *CKSProtocol) GenAudit(ct *ckks.Ciphertext, shares []CKSShare, combined CKSShare) (audit *DecryptionAudit, err error) {
+
+	if len(shares) == 0 {
+		return nil, errors.New("cannot GenAudit: no shares")
+	}
+
+	if !cks.isAggregate(ct.Level(), shares, combined) {
+		return nil, errors.New("cannot GenAudit: combined share is not the aggregate of the shares")
+	}
+
+	if audit, err = newDecryptionAudit(cks.dckksContext.params, ct); err != nil {
+		return nil, err
+	}
+
+	audit.ShareCommitments = make([]ShareCommitment, len(shares))
+	for i := range shares {
+		if audit.ShareCommitments[i], err = CommitShare(ct.Level(), shares[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if audit.AggregateCommitment, err = CommitShare(ct.Level(), combined); err != nil {
+		return nil, err
+	}
+
+	audit.TranscriptHash = audit.transcriptHash()
+	audit.Signatures = make([][]byte, len(shares))
+
+	return audit, nil
+}
+
+// VerifyAudit checks that the audit record is signed by all the parties, whose ed25519 public keys are given in the
+// order of the shares, and is consistent with the parameters of the protocol, the ciphertext ct, the shares and their
+// aggregate combined. It returns nil if the record is consistent, and an error describing the first inconsistency
+// otherwise. It only re-hashes the given shares against the record: it does not check that the shares are correct
+// decryption shares, hence does not prove that the decryption is correct.
+func (cks *CKSProtocol) VerifyAudit(audit *DecryptionAudit, ct *ckks.Ciphertext, shares []CKSShare, combined CKSShare, keys []ed25519.PublicKey) (err error) {
+
+	if err = audit.VerifySignatures(keys); err != nil {
+		return err
+	}
+
+	ref, err := newDecryptionAudit(cks.dckksContext.params, ct)
+	if err != nil {
+		return err
+	}
+
+	if audit.ParamsHash != ref.ParamsHash {
+		return errors.New("invalid audit: parameters mismatch")
+	}
+
+	if audit.CiphertextHash != ref.CiphertextHash {
+		return errors.New("invalid audit: ciphertext mismatch")
+	}
+
+	if len(shares) != len(audit.ShareCommitments) {
+		return fmt.Errorf("invalid audit: %d shares for %d commitments", len(shares), len(audit.ShareCommitments))
+	}
+
+	for i := range shares {
+		if !audit.ShareCommitments[i].Open(ct.Level(), shares[i]) {
+			return fmt.Errorf("invalid audit: share %d does not match its commitment", i)
+		}
+	}
+
+	if !audit.AggregateCommitment.Open(ct.Level(), combined) {
+		return errors.New("invalid audit: combined share does not match its commitment")
+	}
+
+	if !cks.isAggregate(ct.Level(), shares, combined) {
+		return errors.New("invalid audit: combined share is not the aggregate of the shares")
+	}
+
+	return nil
+}
+
+// VerifyTranscript checks that the transcript hash of the audit record matches its content. It does not require
+// the shares, and detects the accidental corruption of a stored record. A deliberate modification, after which the
+// transcript hash can be computed again, is detected by VerifySignatures.
+func (audit *DecryptionAudit) VerifyTranscript() error {
+	if audit.transcriptHash() != audit.TranscriptHash {
+		return errors.New("invalid audit: transcript hash mismatch")
+	}
+	return nil
+}
+
+// Sign signs the transcript hash of the audit record with the ed25519 private key of the i-th party in the order of
+// the shares. The party must first check with Open that the i-th commitment of the record is a commitment to its
+// share.
+func (audit *DecryptionAudit) Sign(i int, key ed25519.PrivateKey) {
+
+	if i < 0 || i >= len(audit.ShareCommitments) {
+		panic(fmt.Sprintf("cannot Sign: no share %d in a record of %d shares", i, len(audit.ShareCommitments)))
+	}
+
+	if audit.Signatures == nil {
+		audit.Signatures = make([][]byte, len(audit.ShareCommitments))
+	}
+
+	audit.Signatures[i] = ed25519.Sign(key, audit.TranscriptHash[:])
+}
+
+// VerifySignatures checks that the transcript hash of the audit record matches its content and is signed by all the
+// parties, whose ed25519 public keys are given in the order of the shares.
+func (audit *DecryptionAudit) VerifySignatures(keys []ed25519.PublicKey) error {
+
+	if err := audit.VerifyTranscript(); err != nil {
+		return err
+	}
+
+	if len(keys) != len(audit.ShareCommitments) || len(audit.Signatures) != len(audit.ShareCommitments) {
+		return fmt.Errorf("invalid audit: %d keys and %d signatures for %d shares", len(keys), len(audit.Signatures), len(audit.ShareCommitments))
+	}
+
+	for i := range keys {
+		if audit.Signatures[i] == nil {
+			return fmt.Errorf("invalid audit: share %d is not signed", i)
+		}
+		if len(keys[i]) != ed25519.PublicKeySize || !ed25519.Verify(keys[i], audit.TranscriptHash[:], audit.Signatures[i]) {
+			return fmt.Errorf("invalid audit: invalid signature of share %d", i)
+		}
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the audit record on a slice of bytes. A missing signature is encoded as zero bytes.
+func (audit *DecryptionAudit) MarshalBinary() (data []byte, err error) {
+
+	nShares := len(audit.ShareCommitments)
+
+	if audit.Signatures != nil && len(audit.Signatures) != nShares {
+		return nil, errors.New("cannot marshal DecryptionAudit: number of signatures and shares mismatch")
+	}
+
+	data = make([]byte, 0, auditLen(uint64(nShares)))
+
+	data = append(data, audit.ParamsHash[:]...)
+	data = append(data, audit.CiphertextHash[:]...)
+
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], uint32(nShares))
+	data = append(data, tmp[:]...)
+
+	for i := range audit.ShareCommitments {
+		data = append(data, audit.ShareCommitments[i].Salt[:]...)
+		data = append(data, audit.ShareCommitments[i].Digest[:]...)
+	}
+
+	data = append(data, audit.AggregateCommitment.Salt[:]...)
+	data = append(data, audit.AggregateCommitment.Digest[:]...)
+	data = append(data, audit.TranscriptHash[:]...)
+
+	var noSignature [ed25519.SignatureSize]byte
+	for i := 0; i < nShares; i++ {
+		if audit.Signatures == nil || audit.Signatures[i] == nil {
+			data = append(data, noSignature[:]...)
+			continue
+		}
+		if len(audit.Signatures[i]) != ed25519.SignatureSize {
+			return nil, fmt.Errorf("cannot marshal DecryptionAudit: invalid signature %d", i)
+		}
+		data = append(data, audit.Signatures[i]...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the target audit record.
+func (audit *DecryptionAudit) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) < 68 {
+		return errors.New("invalid DecryptionAudit encoding: too short")
+	}
+
+	nShares := uint64(binary.BigEndian.Uint32(data[64:68]))
+
+	if uint64(len(data)) != auditLen(nShares) {
+		return errors.New("invalid DecryptionAudit encoding: invalid length")
+	}
+
+	copy(audit.ParamsHash[:], data[0:32])
+	copy(audit.CiphertextHash[:], data[32:64])
+	data = data[68:]
+
+	audit.ShareCommitments = make([]ShareCommitment, nShares)
+	for i := range audit.ShareCommitments {
+		copy(audit.ShareCommitments[i].Salt[:], data[0:32])
+		copy(audit.ShareCommitments[i].Digest[:], data[32:64])
+		data = data[64:]
+	}
+
+	copy(audit.AggregateCommitment.Salt[:], data[0:32])
+	copy(audit.AggregateCommitment.Digest[:], data[32:64])
+	copy(audit.TranscriptHash[:], data[64:96])
+	data = data[96:]
+
+	var noSignature [ed25519.SignatureSize]byte
+	audit.Signatures = make([][]byte, nShares)
+	for i := range audit.Signatures {
+		if signature := data[:ed25519.SignatureSize]; subtle.ConstantTimeCompare(signature, noSignature[:]) == 0 {
+			audit.Signatures[i] = append([]byte{}, signature...)
+		}
+		data = data[ed25519.SignatureSize:]
+	}
+
+	return nil
+}
+
+// auditLen returns the length of a marshaled DecryptionAudit of nShares shares: the hashes of the parameters and
+// ciphertext, the number of shares, the commitments, the transcript hash and the signatures.
+func auditLen(nShares uint64) uint64 {
+	return 68 + 64*(nShares+1) + 32 + ed25519.SignatureSize*nShares
+}
+
+func newDecryptionAudit(params *ckks.Parameters, ct *ckks.Ciphertext) (audit *DecryptionAudit, err error) {
+
+	audit = new(DecryptionAudit)
+	audit.ParamsHash = params.Hash()
+
+	data, err := ct.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	audit.CiphertextHash = sha256.Sum256(data)
+
+	return audit, nil
+}
+
+func (audit *DecryptionAudit) transcriptHash() (digest [32]byte) {
+	h := sha256.New()
+	h.Write([]byte("dckks/CKS/audit"))
+	h.Write(audit.ParamsHash[:])
+	h.Write(audit.CiphertextHash[:])
+	for i := range audit.ShareCommitments {
+		h.Write(audit.ShareCommitments[i].Salt[:])
+		h.Write(audit.ShareCommitments[i].Digest[:])
+	}
+	h.Write(audit.AggregateCommitment.Salt[:])
+	h.Write(audit.AggregateCommitment.Digest[:])
+	copy(digest[:], h.Sum(nil))
+	return
+}
+
+// isAggregate returns true if combined is equal to the sum of the shares up to the given level.
+func (cks *CKSProtocol) isAggregate(level uint64, shares []CKSShare, combined CKSShare) bool {
+
+	ringQ := cks.dckksContext.ringQ
+
+	sum := ringQ.GetPolyLvl(level)
+	defer ringQ.PutPoly(sum)
+
+	sum.Zero()
+	for i := range shares {
+		ringQ.AddLvl(level, sum, shares[i].Poly, sum)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		for j := uint64(0); j < ringQ.N; j++ {
+			if sum.Coeffs[i][j] != combined.Coeffs[i][j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func hashPolyLvl(h hash.Hash, level uint64, pol *ring.Poly) {
+	var tmp [8]byte
+	for i := uint64(0); i < level+1; i++ {
+		for _, c := range pol.Coeffs[i] {
+			binary.BigEndian.PutUint64(tmp[:], c)
+			h.Write(tmp[:])
+		}
+	}
+}
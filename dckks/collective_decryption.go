@@ -0,0 +1,119 @@
+package dckks
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// CollectiveDecryptionProtocol is the decryption of a ciphertext, encrypted under the collective secret key, for a
+// designated receiver among the parties. The other parties send to the receiver a share of the decryption flooded
+// with a noise 2^lambda times larger than the noise of the ciphertext, for a statistical security parameter lambda,
+// and the receiver completes the decryption with its own secret key share, which it does not publish: the plaintext
+// is only known to the receiver, and does not reveal the secret key shares of the other parties.
+//
+// The ckks package does not track the noise of the ciphertexts: the noise estimate given by the parties is the
+// standard deviation of the error of the ciphertext, e.g. bounded from the circuit that produced it or measured with
+// ring.MeanAndVariance on test decryptions. The flooding noise adds to the error of the plaintext, so the precision of
+// the plaintext is log2(scale) - lambda - log2(noiseStd) bits at most.
+type CollectiveDecryptionProtocol struct {
+	cks               *CKSProtocol
+	securityParameter uint64
+	zero              *ring.Poly
+	tmp               *ring.Poly
+}
+
+// maxFloodingSigma is the largest standard deviation of the flooding noise, whose samples are bounded by 6*sigma in
+// absolute value and must fit the ConstantTimeGaussianSampler.
+const maxFloodingSigma = float64(uint64(1)<<61) / 6
+
+// NewCollectiveDecryptionProtocol creates a new CollectiveDecryptionProtocol with the statistical security parameter
+// lambda, in bits.
+func NewCollectiveDecryptionProtocol(params *ckks.Parameters, lambda uint64) *CollectiveDecryptionProtocol {
+
+	cdp := new(CollectiveDecryptionProtocol)
+	cdp.cks = NewCKSProtocol(params, 0)
+	cdp.securityParameter = lambda
+	cdp.zero = cdp.cks.dckksContext.ringQP.NewPoly()
+	cdp.tmp = cdp.cks.dckksContext.ringQ.NewPoly()
+
+	return cdp
+}
+
+// FloodingSigma returns the standard deviation 2^lambda * noiseStd of the flooding noise for a ciphertext whose error
+// has standard deviation noiseStd. It returns an error if it exceeds the largest supported standard deviation, of about
+// 2^58.4.
+func (cdp *CollectiveDecryptionProtocol) FloodingSigma(noiseStd float64) (sigma float64, err error) {
+
+	if noiseStd <= 0 {
+		return 0, fmt.Errorf("invalid noise estimate %f: must be positive", noiseStd)
+	}
+
+	sigma = math.Ldexp(noiseStd, int(cdp.securityParameter))
+
+	if sigma > maxFloodingSigma {
+		return 0, fmt.Errorf("flooding noise of standard deviation 2^%.1f for lambda = %d is larger than 2^%.1f", math.Log2(sigma), cdp.securityParameter, math.Log2(maxFloodingSigma))
+	}
+
+	return sigma, nil
+}
+
+// AllocateShare allocates a share of the protocol.
+func (cdp *CollectiveDecryptionProtocol) AllocateShare() CKSShare {
+	return cdp.cks.AllocateShare()
+}
+
+// GenShare computes the share s_i*c_1 + e_i of a party other than the receiver for the decryption of ct, whose error
+// has standard deviation noiseStd, e_i being the flooding noise. It returns an error if the flooding noise is too large,
+// see FloodingSigma, or too large for ct: if its standard deviation is not smaller than the scale of ct, which would
+// leave no precision to the plaintext, or if its bound 6*sigma is not smaller than half the modulus at the level of ct,
+// such that the noise would wrap around it. The noise can be larger than each of the moduli.
+func (cdp *CollectiveDecryptionProtocol) GenShare(sk *ring.Poly, ct *ckks.Ciphertext, noiseStd float64, shareOut CKSShare) error {
+
+	sigma, err := cdp.FloodingSigma(noiseStd)
+	if err != nil {
+		return fmt.Errorf("cannot GenShare: %s", err)
+	}
+
+	if sigma >= ct.Scale() {
+		return fmt.Errorf("cannot GenShare: flooding noise of standard deviation 2^%.1f for lambda = %d is not smaller than the scale 2^%.1f of the ciphertext", math.Log2(sigma), cdp.securityParameter, math.Log2(ct.Scale()))
+	}
+
+	var logQ float64
+	for _, qi := range cdp.cks.dckksContext.ringQ.Modulus[:ct.Level()+1] {
+		logQ += math.Log2(float64(qi))
+	}
+
+	if math.Log2(6*sigma)+1 >= logQ {
+		return fmt.Errorf("cannot GenShare: flooding noise of bound 2^%.1f for lambda = %d is not smaller than half the modulus 2^%.1f at level %d", math.Log2(6*sigma), cdp.securityParameter, logQ, ct.Level())
+	}
+
+	cdp.cks.GenShareWithSigma(sigma, sk, cdp.zero, ct, shareOut)
+
+	return nil
+}
+
+// AggregateShares adds share1 and share2 on shareOut.
+func (cdp *CollectiveDecryptionProtocol) AggregateShares(share1, share2, shareOut CKSShare) {
+	cdp.cks.AggregateShares(share1, share2, shareOut)
+}
+
+// Decrypt is run by the receiver, of secret key share skReceiver, on the aggregate combined of the shares of all the
+// other parties, and returns the plaintext c_0 + combined + skReceiver*c_1 of ct.
+func (cdp *CollectiveDecryptionProtocol) Decrypt(skReceiver *ring.Poly, ct *ckks.Ciphertext, combined CKSShare) (pt *ckks.Plaintext) {
+
+	ringQ := cdp.cks.dckksContext.ringQ
+	level := ct.Level()
+
+	pt = ckks.NewPlaintext(cdp.cks.dckksContext.params, level, ct.Scale())
+
+	ringQ.MulCoeffsMontgomeryLvl(level, ct.Value()[1], skReceiver, cdp.tmp)
+	ringQ.AddLvl(level, cdp.tmp, ct.Value()[0], pt.Value()[0])
+	ringQ.AddLvl(level, pt.Value()[0], combined.Poly, pt.Value()[0])
+
+	cdp.tmp.Zero()
+
+	return pt
+}
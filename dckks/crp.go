@@ -0,0 +1,99 @@
+package dckks
+
+import (
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// CRPGenerator derives all the common reference polynomials of a session of protocols from a public seed shared by the
+// parties. Each polynomial is sampled from a fork of the KeyedPRNG of the seed labelled with the protocol, the round
+// of the protocol in the session and the number of parties, so that the parties obtain the same polynomials whatever
+// the order in which they request them, and that two protocols, two rounds or two sessions with a different number of
+// parties never share a polynomial. Requesting the same polynomials twice returns copies of the same polynomials.
+//
+// The polynomials are in the NTT and Montgomery domain, as the ones of NewCRPGenerator.
+type CRPGenerator struct {
+	dckksContext *dckksContext
+	prng         utils.PRNG
+	nParties     uint64
+}
+
+// NewSessionCRPGenerator creates a new CRPGenerator for a session between nParties parties, from the public seed of
+// the session.
+func NewSessionCRPGenerator(params *ckks.Parameters, seed []byte, nParties uint64) *CRPGenerator {
+
+	if nParties == 0 {
+		panic("cannot NewSessionCRPGenerator: nParties must be at least 1")
+	}
+
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		panic(err)
+	}
+
+	crpg := new(CRPGenerator)
+	crpg.dckksContext = newDckksContext(params)
+	crpg.prng = prng
+	crpg.nParties = nParties
+
+	return crpg
+}
+
+// sampler returns the UniformSampler on r of the polynomials of the given label.
+func (crpg *CRPGenerator) sampler(r *ring.Ring, label string) *ring.UniformSampler {
+	fork := crpg.prng.Fork([]byte(fmt.Sprintf("dckks/CRP/%s/parties=%d", label, crpg.nParties)))
+	return ring.NewUniformSamplerWithDomain(fork, r, true, true)
+}
+
+// readDecomposition returns the beta polynomials of QP of the given label, one per decomposition digit.
+func (crpg *CRPGenerator) readDecomposition(label string) (crp []*ring.Poly) {
+	sampler := crpg.sampler(crpg.dckksContext.ringQP, label)
+	crp = make([]*ring.Poly, crpg.dckksContext.beta)
+	for i := range crp {
+		crp[i] = sampler.ReadNew()
+	}
+	return
+}
+
+// CKG returns the polynomial of QP of the collective public key generation.
+func (crpg *CRPGenerator) CKG() *ring.Poly {
+	return crpg.sampler(crpg.dckksContext.ringQP, "CKG").ReadNew()
+}
+
+// RKG returns the polynomials of QP of the relinearization key generation, shared by its two rounds.
+func (crpg *CRPGenerator) RKG() []*ring.Poly {
+	return crpg.readDecomposition("RKG")
+}
+
+// RKGOneRound returns the two vectors of polynomials of QP (a, a') of the one-round relinearization key generation.
+func (crpg *CRPGenerator) RKGOneRound() [2][]*ring.Poly {
+	return [2][]*ring.Poly{crpg.readDecomposition("RKGOneRound/a"), crpg.readDecomposition("RKGOneRound/a'")}
+}
+
+// RTG returns the polynomials of QP of the generation of the automorphism key of the Galois element galEl.
+func (crpg *CRPGenerator) RTG(galEl uint64) []*ring.Poly {
+	return crpg.readDecomposition(fmt.Sprintf("RTG/galEl=%d", galEl))
+}
+
+// Refresh returns the polynomial of Q of the round-th run of the refresh protocol in the session.
+func (crpg *CRPGenerator) Refresh(round uint64) *ring.Poly {
+	return crpg.sampler(crpg.dckksContext.ringQ, fmt.Sprintf("Refresh/round=%d", round)).ReadNew()
+}
+
+// Permute returns the polynomial of Q of the round-th run of the refresh and permute protocol in the session.
+func (crpg *CRPGenerator) Permute(round uint64) *ring.Poly {
+	return crpg.sampler(crpg.dckksContext.ringQ, fmt.Sprintf("Permute/round=%d", round)).ReadNew()
+}
+
+// RTGBatch returns the polynomials of the generation of the automorphism keys of the Galois elements galEls, in the
+// order of the list, as expected by RTGProtocol.GenBatchShare.
+func (crpg *CRPGenerator) RTGBatch(galEls []uint64) (crp [][]*ring.Poly) {
+	crp = make([][]*ring.Poly, len(galEls))
+	for i, galEl := range galEls {
+		crp[i] = crpg.RTG(galEl)
+	}
+	return
+}
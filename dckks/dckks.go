@@ -15,8 +15,9 @@ type dckksContext struct {
 	ringP  *ring.Ring
 	ringQP *ring.Ring
 
-	alpha uint64
-	beta  uint64
+	beta uint64
+
+	logger utils.Logger
 }
 
 func newDckksContext(params *ckks.Parameters) (context *dckksContext) {
@@ -27,9 +28,10 @@ func newDckksContext(params *ckks.Parameters) (context *dckksContext) {
 
 	context.n = params.N()
 
-	context.alpha = params.Alpha()
 	context.beta = params.Beta()
 
+	context.logger = utils.NopLogger{}
+
 	var err error
 	if context.ringQ, err = ring.NewRing(params.N(), params.Qi()); err != nil {
 		panic(err)
@@ -46,12 +48,42 @@ func newDckksContext(params *ckks.Parameters) (context *dckksContext) {
 	return
 }
 
-// NewCRPGenerator creates a new deterministic random polynomial generator.
+// setLogger sets the logger of the context, a nil logger restoring the default NopLogger.
+func (context *dckksContext) setLogger(logger utils.Logger) {
+	if logger == nil {
+		logger = utils.NopLogger{}
+	}
+	context.logger = logger
+}
+
+// addSmudgingNoiseLvl adds on pol, in the NTT domain and for the moduli of Q up to the given level, a smudging noise
+// sampled with the ConstantTimeGaussianSampler of ringQ from the discrete Gaussian of standard deviation sigma truncated
+// at 6*sigma, so that the time to generate a share does not leak the noise. The noise is sampled on a polynomial of
+// the pool of ringQ for the level, which is zeroed before it is given back. A sigma of zero adds no noise.
+func (context *dckksContext) addSmudgingNoiseLvl(level uint64, sampler *ring.ConstantTimeGaussianSampler, sigma float64, pol *ring.Poly) {
+
+	if sigma == 0 {
+		return
+	}
+
+	ringQ := context.ringQ
+
+	tmp := ringQ.GetPolyLvl(level)
+	sampler.ReadLvlWithParameters(level, sigma, uint64(6*sigma), tmp)
+	ringQ.NTTLvl(level, tmp, tmp)
+	ringQ.AddLvl(level, pol, tmp, pol)
+	tmp.Zero()
+	ringQ.PutPoly(tmp)
+}
+
+// NewCRPGenerator creates a new deterministic random polynomial generator. The sampled polynomials are common
+// reference polynomials of QP in the NTT and Montgomery domain. The parties must read them in the same order: a
+// CRPGenerator derives the polynomials of a whole session independently of the order.
 func NewCRPGenerator(params *ckks.Parameters, key []byte) *ring.UniformSampler {
 	ctx := newDckksContext(params)
 	prng, err := utils.NewKeyedPRNG(key)
 	if err != nil {
 		panic(err)
 	}
-	return ring.NewUniformSampler(prng, ctx.ringQP)
+	return ring.NewUniformSamplerWithDomain(prng, ctx.ringQP, true, true)
 }
@@ -199,6 +199,15 @@ func benchKeySwitching(testCtx *testContext, b *testing.B) {
 		}
 	})
 
+	ciphertextLvl0 := ckks.NewCiphertextRandom(testCtx.prng, testCtx.params, 1, 0, testCtx.params.Scale())
+
+	b.Run(testString("KeySwitching/GenLvl0/", parties, testCtx.params), func(b *testing.B) {
+
+		for i := 0; i < b.N; i++ {
+			p.GenShare(p.s0, p.s1, ciphertextLvl0, p.share)
+		}
+	})
+
 	b.Run(testString("KeySwitching/Agg/", parties, testCtx.params), func(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
@@ -239,6 +248,16 @@ func benchPublicKeySwitching(testCtx *testContext, b *testing.B) {
 		}
 	})
 
+	ciphertextLvl0 := ckks.NewCiphertextRandom(testCtx.prng, testCtx.params, 1, 0, testCtx.params.Scale())
+	shareLvl0 := p.AllocateShares(0)
+
+	b.Run(testString("PublicKeySwitching/GenLvl0/", parties, testCtx.params), func(b *testing.B) {
+
+		for i := 0; i < b.N; i++ {
+			p.GenShare(p.s, pk1, ciphertextLvl0, shareLvl0)
+		}
+	})
+
 	b.Run(testString("PublicKeySwitching/Agg/", parties, testCtx.params), func(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
@@ -340,7 +359,8 @@ func benchRefresh(testCtx *testContext, b *testing.B) {
 	b.Run(testString("Refresh/Agg/", parties, testCtx.params), func(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
-			p.Aggregate(p.share1, p.share1, p.share1)
+			p.AggregateDecrypt(p.share1, p.share1, p.share1)
+			p.AggregateRecrypt(p.share2, p.share2, p.share2)
 		}
 	})
 
@@ -393,8 +413,8 @@ func benchRefreshAndPermute(testCtx *testContext, b *testing.B) {
 
 	ciphertext := ckks.NewCiphertextRandom(testCtx.prng, testCtx.params, 1, levelStart, testCtx.params.Scale())
 
-	crpGenerator.Readlvl(levelStart, ciphertext.Value()[0])
-	crpGenerator.Readlvl(levelStart, ciphertext.Value()[1])
+	crpGenerator.ReadLvl(levelStart, ciphertext.Value()[0])
+	crpGenerator.ReadLvl(levelStart, ciphertext.Value()[1])
 
 	permutation := make([]uint64, testCtx.params.Slots())
 
@@ -412,7 +432,8 @@ func benchRefreshAndPermute(testCtx *testContext, b *testing.B) {
 	b.Run(testString("RefreshAndPermute/Agg/", parties, testCtx.params), func(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
-			p.Aggregate(p.share1, p.share1, p.share1)
+			p.AggregateDecrypt(p.share1, p.share1, p.share1)
+			p.AggregateRecrypt(p.share2, p.share2, p.share2)
 		}
 	})
 
@@ -1,11 +1,14 @@
 package dckks
 
 import (
+	"context"
+	"crypto/ed25519"
 	"flag"
 	"fmt"
 	"math"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -73,14 +76,34 @@ func TestDCKKS(t *testing.T) {
 		}
 
 		testPublicKeyGen(testCtx, t)
+		testCRPGenerator(testCtx, t)
 		testRelinKeyGen(testCtx, t)
+		testRelinKeyGenSession(testCtx, t)
 		testRelinKeyGenNaive(testCtx, t)
+		testRelinKeyGenOneRound(testCtx, t)
 		testKeyswitching(testCtx, t)
+		testDecryptionAudit(testCtx, t)
 		testPublicKeySwitching(testCtx, t)
+		testKeyswitchingLevels(testCtx, t)
+		testKeyswitchingWithSigma(testCtx, t)
+		testReencryption(testCtx, t)
+		testCollectiveDecryption(testCtx, t)
+		testTransport(testCtx, t)
+		testAggregationTree(testCtx, t)
+		testTranscript(testCtx, t)
+		testValidateShares(testCtx, t)
+		testThreshold(testCtx, t)
 		testRotKeyGenConjugate(testCtx, t)
 		testRotKeyGenCols(testCtx, t)
+		testRotKeyGenGaloisElements(testCtx, t)
+		testRotKeyGenBatch(testCtx, t)
 		testRefresh(testCtx, t)
+		testRefreshWithScale(testCtx, t)
 		testRefreshAndPermute(testCtx, t)
+		testRefreshAndTransform(testCtx, t)
+		testSecretPermute(testCtx, t)
+		testSimulator(testCtx, t)
+		testMarshalShares(testCtx, t)
 	}
 }
 
@@ -179,6 +202,61 @@ func testPublicKeyGen(testCtx *testContext, t *testing.T) {
 
 }
 
+func testCRPGenerator(testCtx *testContext, t *testing.T) {
+
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+	ringQ := testCtx.dckksContext.ringQ
+	ringQP := testCtx.dckksContext.ringQP
+
+	seed := []byte("session seed")
+
+	t.Run(testString("CRPGenerator/Derivation/", parties, testCtx.params), func(t *testing.T) {
+
+		crpg0 := NewSessionCRPGenerator(testCtx.params, seed, parties)
+		crpg1 := NewSessionCRPGenerator(testCtx.params, seed, parties)
+
+		// Same polynomials in any order
+		rkg := crpg0.RKG()
+		require.True(t, ringQ.Equal(crpg0.Refresh(1), crpg1.Refresh(1)))
+		require.True(t, ringQP.Equal(crpg0.CKG(), crpg1.CKG()))
+		for i, crp := range crpg1.RKG() {
+			require.True(t, ringQP.Equal(rkg[i], crp))
+		}
+
+		// Distinct polynomials for distinct protocols, rounds, Galois elements and sessions
+		require.False(t, ringQP.Equal(crpg0.CKG(), rkg[0]))
+		require.False(t, ringQ.Equal(crpg0.Refresh(0), crpg0.Refresh(1)))
+		require.False(t, ringQ.Equal(crpg0.Refresh(0), crpg0.Permute(0)))
+		require.False(t, ringQP.Equal(crpg0.RTG(3)[0], crpg0.RTG(5)[0]))
+		require.False(t, ringQP.Equal(crpg0.CKG(), NewSessionCRPGenerator(testCtx.params, seed, parties+1).CKG()))
+		require.False(t, ringQP.Equal(crpg0.CKG(), NewSessionCRPGenerator(testCtx.params, []byte("other seed"), parties).CKG()))
+	})
+
+	t.Run(testString("CRPGenerator/PublicKeyGen/", parties, testCtx.params), func(t *testing.T) {
+
+		ckg := NewCKGProtocol(testCtx.params)
+
+		shares := make([]CKGShare, parties)
+		for i := range shares {
+			// Each party derives the polynomial on its side
+			crp := NewSessionCRPGenerator(testCtx.params, seed, parties).CKG()
+			shares[i] = ckg.AllocateShares()
+			ckg.GenShare(sk0Shards[i].Get(), crp, shares[i])
+			if i > 0 {
+				ckg.AggregateShares(shares[i], shares[0], shares[0])
+			}
+		}
+
+		pk := &ckks.PublicKey{}
+		ckg.GenPublicKey(shares[0], NewSessionCRPGenerator(testCtx.params, seed, parties).CKG(), pk)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, ckks.NewEncryptorFromPk(testCtx.params, pk), 1, t)
+
+		verifyTestVectors(testCtx, decryptorSk0, coeffs, ciphertext, t)
+	})
+}
+
 func testRelinKeyGen(testCtx *testContext, t *testing.T) {
 
 	evaluator := testCtx.evaluator
@@ -253,6 +331,92 @@ func testRelinKeyGen(testCtx *testContext, t *testing.T) {
 
 }
 
+func testRelinKeyGenSession(testCtx *testContext, t *testing.T) {
+
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("RelinKeyGenSession/", parties, testCtx.params), func(t *testing.T) {
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, testCtx.dckksContext.ringQP)
+		crp := make([]*ring.Poly, testCtx.params.Beta())
+		for i := range crp {
+			crp[i] = crpGenerator.ReadNew()
+		}
+
+		rkg := NewEkgProtocol(testCtx.params)
+
+		sessions := make([]*RKGSession, parties)
+		for i := range sessions {
+			sessions[i] = rkg.NewSession(uint64(i), parties, crp)
+		}
+
+		_, err := sessions[0].GenShare(sk0Shards[0].Get())
+		require.NoError(t, err)
+		_, err = sessions[0].GenShare(sk0Shards[0].Get())
+		require.Error(t, err)
+		require.Error(t, sessions[0].RelinearizationKey(ckks.NewRelinKey(testCtx.params)))
+		sessions[0] = rkg.NewSession(0, parties, crp)
+
+		for _, phase := range []RKGPhase{RKGPhaseRoundOne, RKGPhaseRoundTwo} {
+
+			shares := make([]RKGShare, parties)
+			for i, session := range sessions {
+				require.Equal(t, phase, session.Phase())
+				shares[i], err = session.GenShare(sk0Shards[i].Get())
+				require.NoError(t, err)
+			}
+
+			// The parties store their state while waiting for the shares of the others
+			for i, session := range sessions {
+				data, err := session.MarshalBinary()
+				require.NoError(t, err)
+				sessions[i], err = rkg.LoadSession(crp, data)
+				require.NoError(t, err)
+			}
+
+			// A share of the other round is rejected
+			other := shares[1]
+			other.Round = RKGPhaseRoundTwo - phase
+			require.Error(t, sessions[0].AddShare(other))
+
+			require.Error(t, sessions[0].AddShare(shares[0]))
+			require.NoError(t, sessions[0].AddShare(shares[1]))
+			require.Error(t, sessions[0].AddShare(shares[1]))
+
+			for i, session := range sessions {
+				for j := range shares {
+					if i != j && !(i == 0 && j == 1) {
+						require.NoError(t, session.AddShare(shares[j]))
+					}
+				}
+			}
+		}
+
+		require.Error(t, sessions[0].AddShare(RKGShare{Party: 1}))
+
+		evk := ckks.NewRelinKey(testCtx.params)
+		for _, session := range sessions {
+			require.Equal(t, RKGPhaseDone, session.Phase())
+			require.NoError(t, session.RelinearizationKey(evk))
+		}
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		for i := range coeffs {
+			coeffs[i] *= coeffs[i]
+		}
+
+		evaluator.MulRelin(ciphertext, ciphertext, evk, ciphertext)
+
+		evaluator.Rescale(ciphertext, testCtx.params.Scale(), ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk0, coeffs, ciphertext, t)
+	})
+}
+
 func testRelinKeyGenNaive(testCtx *testContext, t *testing.T) {
 
 	evaluator := testCtx.evaluator
@@ -318,6 +482,68 @@ func testRelinKeyGenNaive(testCtx *testContext, t *testing.T) {
 	})
 }
 
+func testRelinKeyGenOneRound(testCtx *testContext, t *testing.T) {
+
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+	ringQP := testCtx.dckksContext.ringQP
+
+	t.Run(testString("RelinKeyGenOneRound/", parties, testCtx.params), func(t *testing.T) {
+
+		crp := NewSessionCRPGenerator(testCtx.params, []byte("session seed"), parties).RKGOneRound()
+
+		rkg := NewRKGProtocolOneRound(testCtx.params)
+		aggregate := rkg.AllocateShare()
+
+		// Each party sends a single share, which depends only on its secret key share and on the crp
+		for i := range sk0Shards {
+			p := NewRKGProtocolOneRound(testCtx.params)
+			share := p.AllocateShare()
+			p.GenShare(sk0Shards[i].Get(), crp, share)
+			share.Party = uint64(i)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+			require.LessOrEqual(t, uint64(len(data)), MaxMarshaledShareLen(testCtx.params, 0))
+
+			received := new(RKGOneRoundShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.Len(t, received.Value, len(share.Value))
+			for j := range share.Value {
+				for k := range share.Value[j] {
+					require.True(t, ringQP.Equal(share.Value[j][k], received.Value[j][k]))
+				}
+			}
+			require.Error(t, new(RKGShare).UnmarshalBinary(data))
+
+			rkg.AggregateShares(*received, aggregate, aggregate)
+		}
+
+		rlk := NewOneRoundRelinearizationKey(testCtx.params)
+		rkg.GenRelinearizationKey(aggregate, crp, rlk)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		for i := range coeffs {
+			coeffs[i] *= coeffs[i]
+		}
+
+		ciphertext2 := ckks.NewCiphertext(testCtx.params, 2, ciphertext.Level(), ciphertext.Scale())
+		evaluator.MulRelin(ciphertext, ciphertext, nil, ciphertext2)
+		require.Equal(t, uint64(2), ciphertext2.Degree())
+
+		NewOneRoundRelinearizer(testCtx.params).Relinearize(ciphertext2, rlk, ciphertext)
+		require.Equal(t, uint64(1), ciphertext.Degree())
+
+		evaluator.Rescale(ciphertext, testCtx.params.Scale(), ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk0, coeffs, ciphertext, t)
+	})
+}
+
 func testKeyswitching(testCtx *testContext, t *testing.T) {
 
 	encryptorPk0 := testCtx.encryptorPk0
@@ -368,6 +594,81 @@ func testKeyswitching(testCtx *testContext, t *testing.T) {
 	})
 }
 
+func testDecryptionAudit(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("DecryptionAudit/", parties, testCtx.params), func(t *testing.T) {
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+		zero := testCtx.dckksContext.ringQP.NewPoly()
+
+		_, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		shares := make([]CKSShare, parties)
+		combined := cks.AllocateShare()
+		for i := range shares {
+			shares[i] = cks.AllocateShare()
+			cks.GenShare(sk0Shards[i].Get(), zero, ciphertext, shares[i])
+			cks.AggregateShares(shares[i], combined, combined)
+		}
+
+		audit, err := cks.GenAudit(ciphertext, shares, combined)
+		require.NoError(t, err)
+
+		keys := make([]ed25519.PublicKey, parties)
+		for i := range shares {
+
+			publicKey, privateKey, err := ed25519.GenerateKey(nil)
+			require.NoError(t, err)
+			keys[i] = publicKey
+
+			// A record missing a signature is rejected
+			require.Error(t, cks.VerifyAudit(audit, ciphertext, shares, combined, keys))
+
+			// Each party checks the commitment to its share before signing
+			require.True(t, audit.ShareCommitments[i].Open(ciphertext.Level(), shares[i]))
+			audit.Sign(i, privateKey)
+		}
+
+		require.NoError(t, cks.VerifyAudit(audit, ciphertext, shares, combined, keys))
+
+		// The commitments are salted: two commitments to the same share differ
+		commitment, err := CommitShare(ciphertext.Level(), shares[0])
+		require.NoError(t, err)
+		require.True(t, commitment.Open(ciphertext.Level(), shares[0]))
+		require.NotEqual(t, audit.ShareCommitments[0], commitment)
+		require.False(t, commitment.Open(ciphertext.Level(), shares[1]))
+
+		data, err := audit.MarshalBinary()
+		require.NoError(t, err)
+		auditNew := new(DecryptionAudit)
+		require.NoError(t, auditNew.UnmarshalBinary(data))
+		require.Equal(t, audit, auditNew)
+
+		// Tampered record
+		auditNew.ShareCommitments[0].Digest[0] ^= 1
+		require.Error(t, auditNew.VerifyTranscript())
+
+		// A tampered record with a recomputed transcript hash is rejected by the signatures
+		auditNew.TranscriptHash = auditNew.transcriptHash()
+		require.NoError(t, auditNew.VerifyTranscript())
+		require.Error(t, auditNew.VerifySignatures(keys))
+
+		// Signatures by other keys
+		keys[0], keys[1] = keys[1], keys[0]
+		require.Error(t, audit.VerifySignatures(keys))
+		keys[0], keys[1] = keys[1], keys[0]
+
+		// Tampered share
+		shares[1].Coeffs[0][0]++
+		require.Error(t, cks.VerifyAudit(audit, ciphertext, shares, combined, keys))
+		_, err = cks.GenAudit(ciphertext, shares, combined)
+		require.Error(t, err)
+	})
+}
+
 func testPublicKeySwitching(testCtx *testContext, t *testing.T) {
 
 	encryptorPk0 := testCtx.encryptorPk0
@@ -412,122 +713,951 @@ func testPublicKeySwitching(testCtx *testContext, t *testing.T) {
 	})
 }
 
-func testRotKeyGenConjugate(testCtx *testContext, t *testing.T) {
+func testKeyswitchingLevels(testCtx *testContext, t *testing.T) {
 
-	ringQP := testCtx.dckksContext.ringQP
-	evaluator := testCtx.evaluator
 	encryptorPk0 := testCtx.encryptorPk0
-	decryptorSk0 := testCtx.decryptorSk0
+	decryptorSk1 := testCtx.decryptorSk1
 	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+	pk1 := testCtx.pk1
 
-	t.Run(testString("RotKeyGenConjugate/", parties, testCtx.params), func(t *testing.T) {
+	t.Run(testString("KeyswitchingLevels/", parties, testCtx.params), func(t *testing.T) {
 
 		type Party struct {
-			*RTGProtocol
-			s     *ring.Poly
-			share RTGShare
+			cks    *CKSProtocol
+			pcks   *PCKSProtocol
+			s0, s1 *ring.Poly
 		}
 
-		pcksParties := make([]*Party, parties)
-		for i := uint64(0); i < parties; i++ {
-			p := new(Party)
-			p.RTGProtocol = NewRotKGProtocol(testCtx.params)
-			p.s = sk0Shards[i].Get()
-			p.share = p.AllocateShare()
-			pcksParties[i] = p
+		ksParties := make([]*Party, parties)
+		for i := range ksParties {
+			ksParties[i] = &Party{
+				cks:  NewCKSProtocol(testCtx.params, 6.36),
+				pcks: NewPCKSProtocol(testCtx.params, 6.36),
+				s0:   sk0Shards[i].Get(),
+				s1:   sk1Shards[i].Get(),
+			}
 		}
-		P0 := pcksParties[0]
+		P0 := ksParties[0]
+
+		// The same instances generate the shares of ciphertexts of decreasing and then increasing levels
+		maxLevel := testCtx.params.MaxLevel()
+		for _, level := range []uint64{maxLevel, 0, maxLevel / 2, maxLevel} {
+
+			coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+			testCtx.evaluator.DropLevel(ciphertext, maxLevel-level)
+
+			cksShares := make([]CKSShare, parties)
+			pcksShares := make([]PCKSShare, parties)
+			for i, p := range ksParties {
+				cksShares[i] = p.cks.AllocateShare()
+				p.cks.GenShare(p.s0, p.s1, ciphertext, cksShares[i])
+				pcksShares[i] = p.pcks.AllocateShares(level)
+				p.pcks.GenShare(p.s0, pk1, ciphertext, pcksShares[i])
+				if i > 0 {
+					P0.cks.AggregateShares(cksShares[i], cksShares[0], cksShares[0])
+					P0.pcks.AggregateShares(pcksShares[i], pcksShares[0], pcksShares[0])
+				}
+			}
 
-		crpGenerator := ring.NewUniformSampler(testCtx.prng, testCtx.dckksContext.ringQP)
-		crp := make([]*ring.Poly, testCtx.params.Beta())
+			ksCiphertext := ckks.NewCiphertext(testCtx.params, 1, level, ciphertext.Scale())
+			P0.cks.KeySwitch(cksShares[0], ciphertext, ksCiphertext)
+			verifyTestVectors(testCtx, decryptorSk1, coeffs, ksCiphertext, t)
 
-		for i := uint64(0); i < testCtx.params.Beta(); i++ {
-			crp[i] = crpGenerator.ReadNew()
+			P0.pcks.KeySwitch(pcksShares[0], ciphertext, ksCiphertext)
+			verifyTestVectors(testCtx, decryptorSk1, coeffs, ksCiphertext, t)
 		}
+	})
+}
 
-		for i, p := range pcksParties {
-			p.GenShare(ckks.Conjugate, 0, p.s, crp, &p.share)
+func testKeyswitchingWithSigma(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+	pk1 := testCtx.pk1
+	ringQ := testCtx.dckksContext.ringQ
+
+	sigma := float64(1 << 7)
+
+	t.Run(testString("KeyswitchingWithSigma/SmudgingNoise/", parties, testCtx.params), func(t *testing.T) {
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+
+		_, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		// With skInput = skOutput the share is the noise only
+		share := cks.AllocateShare()
+		cks.GenShareWithSigma(sigma, sk0Shards[0].Get(), sk0Shards[0].Get(), ciphertext, share)
+		ringQ.InvNTTLvl(ciphertext.Level(), share.Poly, share.Poly)
+
+		_, variance := ringQ.MeanAndVarianceLvl(ciphertext.Level(), share.Poly)
+		require.InDelta(t, sigma, math.Sqrt(variance), sigma/8)
+		require.True(t, ringQ.NormLvl(ciphertext.Level(), share.Poly).Uint64() <= uint64(6*sigma)+2)
+	})
+
+	t.Run(testString("KeyswitchingWithSigma/CKS/", parties, testCtx.params), func(t *testing.T) {
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		shares := make([]CKSShare, parties)
+		for i := range shares {
+			shares[i] = cks.AllocateShare()
+			cks.GenShareWithSigma(sigma, sk0Shards[i].Get(), sk1Shards[i].Get(), ciphertext, shares[i])
 			if i > 0 {
-				P0.Aggregate(p.share, P0.share, P0.share)
+				cks.AggregateShares(shares[i], shares[0], shares[0])
 			}
 		}
 
-		rotkey := ckks.NewRotationKeys()
-		P0.Finalize(testCtx.params, P0.share, crp, rotkey)
+		cks.KeySwitch(shares[0], ciphertext, ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertext, t)
+	})
+
+	t.Run(testString("KeyswitchingWithSigma/PCKS/", parties, testCtx.params), func(t *testing.T) {
+
+		pcks := NewPCKSProtocol(testCtx.params, 6.36)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		shares := make([]PCKSShare, parties)
+		for i := range shares {
+			shares[i] = pcks.AllocateShares(ciphertext.Level())
+			pcks.GenShareWithSigma(sigma, sk0Shards[i].Get(), pk1, ciphertext, shares[i])
+			if i > 0 {
+				pcks.AggregateShares(shares[i], shares[0], shares[0])
+			}
+		}
+
+		ciphertextSwitched := ckks.NewCiphertext(testCtx.params, 1, ciphertext.Level(), ciphertext.Scale())
+		pcks.KeySwitch(shares[0], ciphertext, ciphertextSwitched)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertextSwitched, t)
+	})
+}
+
+func testReencryption(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	pk1 := testCtx.pk1
+
+	t.Run(testString("Reencryption/", parties, testCtx.params), func(t *testing.T) {
+
+		rep := NewReencryptionProtocol(testCtx.params, 1<<7)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		shares := make([]PCKSShare, parties)
+		commitments := make(map[uint64]ShareCommitment)
+		for i := range shares {
+			shares[i] = rep.AllocateShares(ciphertext.Level())
+			shares[i].Party = uint64(i)
+			rep.GenShare(sk0Shards[i].Get(), pk1, ciphertext, shares[i])
+			commitment, err := CommitPCKSShare(ciphertext.Level(), shares[i])
+			require.NoError(t, err)
+			commitments[shares[i].Party] = commitment
+		}
+
+		// Commitments are salted: two commitments to the same share differ
+		commitment, err := CommitPCKSShare(ciphertext.Level(), shares[0])
+		require.NoError(t, err)
+		require.NotEqual(t, commitments[shares[0].Party], commitment)
+		require.True(t, commitment.OpenPCKSShare(ciphertext.Level(), shares[0]))
+
+		rep.AddShareVerifier(NewCommitmentVerifier(commitments))
+
+		combined := rep.AllocateShares(ciphertext.Level())
+		for i := range shares {
+			require.NoError(t, rep.VerifyAndAggregate(ciphertext, shares[i], combined))
+		}
+
+		ciphertextSwitched := ckks.NewCiphertext(testCtx.params, 1, ciphertext.Level(), ciphertext.Scale())
+		rep.Reencrypt(combined, ciphertext, ciphertextSwitched)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertextSwitched, t)
+
+		// Rejected shares leave the aggregate unchanged
+		combinedCopy := PCKSShare{Value: [2]*ring.Poly{combined.Value[0].CopyNew(), combined.Value[1].CopyNew()}}
+
+		shares[1].Value[0].Coeffs[0][0] = (shares[1].Value[0].Coeffs[0][0] + 1) % testCtx.params.Qi()[0]
+		require.Error(t, rep.VerifyAndAggregate(ciphertext, shares[1], combined))
+
+		shares[2].Value[1].Coeffs[0][0] = testCtx.params.Qi()[0]
+		require.Error(t, rep.VerifyAndAggregate(ciphertext, shares[2], combined))
+
+		require.Error(t, rep.VerifyAndAggregate(ciphertext, rep.AllocateShares(ciphertext.Level()-1), combined))
+
+		shares[0].Party = parties
+		require.Error(t, rep.VerifyAndAggregate(ciphertext, shares[0], combined))
+
+		require.True(t, testCtx.dckksContext.ringQ.Equal(combined.Value[0], combinedCopy.Value[0]))
+		require.True(t, testCtx.dckksContext.ringQ.Equal(combined.Value[1], combinedCopy.Value[1]))
+	})
+}
+
+func testCollectiveDecryption(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("CollectiveDecryption/", parties, testCtx.params), func(t *testing.T) {
+
+		cdp := NewCollectiveDecryptionProtocol(testCtx.params, 4)
+
+		sigma, err := cdp.FloodingSigma(8)
+		require.NoError(t, err)
+		require.Equal(t, float64(1<<7), sigma)
+
+		_, err = cdp.FloodingSigma(0)
+		require.Error(t, err)
+		_, err = NewCollectiveDecryptionProtocol(testCtx.params, 80).FloodingSigma(8)
+		require.Error(t, err)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		// The party 0 is the receiver
+		combined := cdp.AllocateShare()
+		for i := uint64(1); i < parties; i++ {
+			share := cdp.AllocateShare()
+			require.NoError(t, cdp.GenShare(sk0Shards[i].Get(), ciphertext, 8, share))
+			cdp.AggregateShares(share, combined, combined)
+		}
+
+		require.Error(t, cdp.GenShare(sk0Shards[1].Get(), ciphertext, -1, combined))
+
+		pt := cdp.Decrypt(sk0Shards[0].Get(), ciphertext, combined)
+
+		verifyTestVectors(testCtx, nil, coeffs, pt, t)
+	})
+
+	t.Run(testString("CollectiveDecryption/Lambda=40/", parties, testCtx.params), func(t *testing.T) {
+
+		params := testCtx.params
+
+		cdp := NewCollectiveDecryptionProtocol(params, 40)
+		share := cdp.AllocateShare()
+
+		// At the default scale, the flooding noise would leave no precision
+		_, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+		require.Error(t, cdp.GenShare(sk0Shards[1].Get(), ciphertext, 1<<12, share))
+
+		// It is also rejected if it wraps around the modulus, even with a larger scale
+		testCtx.evaluator.DropLevel(ciphertext, ciphertext.Level())
+		ciphertext.SetScale(math.Exp2(80))
+		require.Error(t, cdp.GenShare(sk0Shards[1].Get(), ciphertext, 1<<12, share))
+
+		// With a scale of 2^60, lambda = 40 leaves about 20 - log2(noiseStd) bits to the plaintext, minus the growth
+		// of the noise in the decoding, of log2(N)/2 bits. The flooding noise is wider than the moduli of 30 to 34 bits.
+		noiseStd := 1.0
+
+		values := make([]complex128, params.Slots())
+		for i := range values {
+			values[i] = randomComplex(testCtx.prng, 1)
+		}
+
+		plaintext := ckks.NewPlaintext(params, params.MaxLevel(), math.Exp2(60))
+		testCtx.encoder.Encode(plaintext, values, params.Slots())
+		ciphertext = encryptorPk0.EncryptNew(plaintext)
+
+		combined := cdp.AllocateShare()
+		for i := uint64(1); i < parties; i++ {
+			require.NoError(t, cdp.GenShare(sk0Shards[i].Get(), ciphertext, noiseStd, share))
+			cdp.AggregateShares(share, combined, combined)
+		}
+
+		pt := cdp.Decrypt(sk0Shards[0].Get(), ciphertext, combined)
+
+		precStats := ckks.GetPrecisionStats(params, testCtx.encoder, nil, values, pt)
+
+		expected := 60 - 40 - math.Log2(noiseStd) - float64(params.LogN())/2
+		require.GreaterOrEqual(t, real(precStats.MeanPrecision), expected-1)
+		require.GreaterOrEqual(t, imag(precStats.MeanPrecision), expected-1)
+	})
+}
+
+func testTransport(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+
+	t.Run(testString("Transport/", parties, testCtx.params), func(t *testing.T) {
+
+		ids := make([]uint64, parties)
+		for i := range ids {
+			ids[i] = uint64(i)
+		}
+
+		network := NewMemoryNetwork(ids)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		ctx := context.Background()
+		round := RoundTag("cks")
+
+		// Each party sends its share to the party 0, which aggregates them
+		errs := make(chan error, parties)
+		for i := uint64(1); i < parties; i++ {
+			go func(i uint64) {
+				cks := NewCKSProtocol(testCtx.params, 6.36)
+				share := cks.AllocateShare()
+				share.Party = i
+				cks.GenShare(sk0Shards[i].Get(), sk1Shards[i].Get(), ciphertext, share)
+				errs <- network.Transport(i).SendShare(ctx, 0, round, &share)
+			}(i)
+		}
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+		combined := cks.AllocateShare()
+		cks.GenShare(sk0Shards[0].Get(), sk1Shards[0].Get(), ciphertext, combined)
+
+		received, err := network.Transport(0).ReceiveShares(ctx, round, ids[1:])
+		require.NoError(t, err)
+
+		for i := uint64(1); i < parties; i++ {
+			require.NoError(t, <-errs)
+			share := new(CKSShare)
+			require.NoError(t, share.UnmarshalBinary(received[i]))
+			require.Equal(t, i, share.Party)
+			cks.AggregateShares(*share, combined, combined)
+		}
+
+		cks.KeySwitch(combined, ciphertext, ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertext, t)
+
+		// The round is complete, hence forgotten by the network
+		require.Empty(t, network.mailboxes[0].shares)
+		require.Empty(t, network.mailboxes[0].delivered)
+
+		// A share replayed after its delivery is rejected until the round is complete
+		replayed := new(CKSShare)
+		require.NoError(t, replayed.UnmarshalBinary(received[1]))
+		require.NoError(t, network.Transport(1).SendShare(ctx, 0, "partial", replayed))
+		require.NoError(t, network.Transport(2).SendShare(ctx, 0, "partial", replayed))
+		_, err = network.Transport(0).ReceiveShares(ctx, "partial", ids[1:2])
+		require.NoError(t, err)
+		require.Error(t, network.Transport(1).SendShare(ctx, 0, "partial", replayed))
+		_, err = network.Transport(0).ReceiveShares(ctx, "partial", ids[2:3])
+		require.NoError(t, err)
+		require.Empty(t, network.mailboxes[0].shares)
+		require.Empty(t, network.mailboxes[0].delivered)
+
+		// Duplicate share, unknown receiver and missing share
+		share := cks.AllocateShare()
+		require.NoError(t, network.Transport(1).SendShare(ctx, 0, "dup", &share))
+		require.Error(t, network.Transport(1).SendShare(ctx, 0, "dup", &share))
+		require.Error(t, network.Transport(1).SendShare(ctx, parties, "dup", &share))
+
+		ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = network.Transport(0).ReceiveShares(ctxTimeout, "dup", ids[1:])
+		require.Error(t, err)
+	})
+}
+
+func testAggregationTree(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+
+	t.Run(testString("AggregationTree/Structure/", parties, testCtx.params), func(t *testing.T) {
+
+		tree := NewAggregationTree([]uint64{10, 11, 12, 13, 14, 15, 16}, 2)
+
+		require.Equal(t, uint64(10), tree.Root())
+		require.Equal(t, []uint64{11, 12}, tree.Children(10))
+		require.Equal(t, []uint64{15, 16}, tree.Children(12))
+		require.Empty(t, tree.Children(13))
+
+		_, ok := tree.Parent(10)
+		require.False(t, ok)
+		parent, ok := tree.Parent(14)
+		require.True(t, ok)
+		require.Equal(t, uint64(11), parent)
+
+		require.Panics(t, func() { NewAggregationTree([]uint64{1, 1}, 2) })
+		require.Panics(t, func() { tree.Children(1) })
+	})
+
+	t.Run(testString("AggregationTree/AggregateMany/", parties, testCtx.params), func(t *testing.T) {
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		shares := make([]CKSShare, parties)
+		for i := range shares {
+			shares[i] = cks.AllocateShare()
+			cks.GenShare(sk0Shards[i].Get(), sk1Shards[i].Get(), ciphertext, shares[i])
+		}
+
+		cks.KeySwitch(cks.AggregateMany(shares), ciphertext, ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertext, t)
+	})
+
+	for _, arity := range []int{1, 2} {
+
+		t.Run(testString(fmt.Sprintf("AggregationTree/arity=%d/", arity), parties, testCtx.params), func(t *testing.T) {
+
+			ids := make([]uint64, parties)
+			for i := range ids {
+				ids[i] = uint64(i)
+			}
+
+			network := NewMemoryNetwork(ids)
+			tree := NewAggregationTree(ids, arity)
+
+			coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+			shares := make([]CKSShare, parties)
+			errs := make(chan error, parties)
+			for i := range ids {
+				go func(i uint64) {
+					cks := NewCKSProtocol(testCtx.params, 6.36)
+					shares[i] = cks.AllocateShare()
+					cks.GenShare(sk0Shards[i].Get(), sk1Shards[i].Get(), ciphertext, shares[i])
+					errs <- cks.AggregateTree(context.Background(), network.Transport(i), tree, "cks", shares[i])
+				}(uint64(i))
+			}
+
+			for range ids {
+				require.NoError(t, <-errs)
+			}
+
+			cks := NewCKSProtocol(testCtx.params, 6.36)
+			cks.KeySwitch(shares[tree.Root()], ciphertext, ciphertext)
+
+			verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertext, t)
+		})
+	}
+
+	t.Run(testString("AggregationTree/InvalidShare/", parties, testCtx.params), func(t *testing.T) {
+
+		network := NewMemoryNetwork([]uint64{0, 1})
+		tree := NewAggregationTree([]uint64{0, 1}, 1)
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+		share := cks.AllocateShare()
+
+		// A share of a child at another level than the local share is rejected
+		childShare := CKSShare{Poly: testCtx.dckksContext.ringQ.NewPolyLvl(0), Party: 1}
+		require.NoError(t, network.Transport(1).SendShare(context.Background(), 0, "cks", &childShare))
+		require.Error(t, cks.AggregateTree(context.Background(), network.Transport(0), tree, "cks", share))
+	})
+}
+
+func testTranscript(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+
+	t.Run(testString("Transcript/", parties, testCtx.params), func(t *testing.T) {
+
+		session := NewSessionID(testCtx.params, parties, []byte("run 1"))
+		require.NotEqual(t, session, NewSessionID(testCtx.params, parties+1, []byte("run 1")))
+		require.NotEqual(t, session, NewSessionID(testCtx.params, parties, []byte("run 2")))
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+
+		// Each party seals its share and opens the shares of the others
+		transcripts := make([]*Transcript, parties)
+		sealed := make([][]byte, parties)
+		for i := range transcripts {
+			transcripts[i] = NewTranscript(session)
+			share := cks.AllocateShare()
+			share.Party = uint64(i)
+			cks.GenShare(sk0Shards[i].Get(), sk1Shards[i].Get(), ciphertext, share)
+			var err error
+			sealed[i], err = transcripts[i].Seal("cks", &share)
+			require.NoError(t, err)
+		}
+
+		combined := cks.AllocateShare()
+		for i := range transcripts {
+			for j := range sealed {
+				share := new(CKSShare)
+				if i != j {
+					require.NoError(t, transcripts[i].Open("cks", sealed[j], share))
+				} else {
+					require.NoError(t, NewTranscript(session).Open("cks", sealed[j], share))
+				}
+				if i == 0 {
+					cks.AggregateShares(*share, combined, combined)
+				}
+			}
+		}
+
+		for i := 1; i < len(transcripts); i++ {
+			require.NoError(t, transcripts[0].Verify(transcripts[i].Digest()))
+		}
+
+		cks.KeySwitch(combined, ciphertext, ciphertext)
+
+		verifyTestVectors(testCtx, decryptorSk1, coeffs, ciphertext, t)
+
+		share := new(CKSShare)
+
+		// Replayed share, share of another round and share of another session
+		require.Error(t, transcripts[0].Open("cks", sealed[1], share))
+		require.Error(t, NewTranscript(session).Open("rkg", sealed[1], share))
+		require.Error(t, NewTranscript(NewSessionID(testCtx.params, parties, []byte("run 2"))).Open("cks", sealed[1], share))
+
+		// Transcript with a missing share
+		partial := NewTranscript(session)
+		require.NoError(t, partial.Open("cks", sealed[0], share))
+		require.Error(t, transcripts[0].Verify(partial.Digest()))
+	})
+}
+
+func testValidateShares(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+	pk1 := testCtx.pk1
+	ringQ := testCtx.dckksContext.ringQ
+	ringQP := testCtx.dckksContext.ringQP
+
+	t.Run(testString("ValidateShares/CKS/", parties, testCtx.params), func(t *testing.T) {
+
+		cks := NewCKSProtocol(testCtx.params, 6.36)
+
+		_, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+		level := ciphertext.Level()
+
+		share, aggregate := cks.AllocateShare(), cks.AllocateShare()
+		cks.GenShare(sk0Shards[0].Get(), sk1Shards[0].Get(), ciphertext, share)
+		require.NoError(t, cks.ValidateAndAggregate(level, share, aggregate))
+		require.True(t, ringQ.EqualLvl(level, share.Poly, aggregate.Poly))
+
+		c := share.Coeffs[level][0]
+		share.Coeffs[level][0] = ringQ.Modulus[level]
+		require.Error(t, cks.ValidateAndAggregate(level, share, aggregate))
+		require.Error(t, cks.ValidateShare(level, CKSShare{Poly: ring.NewPoly(ringQ.N, level)}))
+		require.Error(t, cks.ValidateShare(level, CKSShare{Poly: ring.NewPoly(ringQ.N>>1, level+1)}))
+		require.Error(t, cks.ValidateShare(level, CKSShare{}))
+
+		share.Coeffs[level][0] = c
+		require.True(t, ringQ.EqualLvl(level, share.Poly, aggregate.Poly))
+	})
+
+	t.Run(testString("ValidateShares/PCKS/", parties, testCtx.params), func(t *testing.T) {
+
+		pcks := NewPCKSProtocol(testCtx.params, 6.36)
+
+		_, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+		level := ciphertext.Level()
+
+		share, aggregate := pcks.AllocateShares(level), pcks.AllocateShares(level)
+		pcks.GenShare(sk0Shards[0].Get(), pk1, ciphertext, share)
+		require.NoError(t, pcks.ValidateAndAggregate(level, share, aggregate))
+
+		share.Value[1].Coeffs[0][0] = ringQ.Modulus[0]
+		require.Error(t, pcks.ValidateAndAggregate(level, share, aggregate))
+		require.Error(t, pcks.ValidateShare(level, pcks.AllocateShares(level-1)))
+	})
+
+	t.Run(testString("ValidateShares/RKG/", parties, testCtx.params), func(t *testing.T) {
+
+		rkg := NewEkgProtocol(testCtx.params)
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, ringQP)
+		crp := make([]*ring.Poly, testCtx.params.Beta())
+		for i := range crp {
+			crp[i] = crpGenerator.ReadNew()
+		}
+
+		share, aggregate := rkg.AllocateShares()
+		rkg.GenShareRoundOne(rkg.NewEphemeralKey(), sk0Shards[0].Get(), crp, share)
+		require.NoError(t, rkg.ValidateAndAggregateRoundOne(share, aggregate))
+		require.NoError(t, rkg.ValidateAndAggregateRoundTwo(share, aggregate))
+
+		share.Value[0][1].Coeffs[0][0] = ringQP.Modulus[0]
+		require.Error(t, rkg.ValidateAndAggregateRoundOne(share, aggregate))
+		require.Error(t, rkg.ValidateShare(RKGShare{Value: share.Value[1:]}))
+	})
+}
+
+func testThreshold(testCtx *testContext, t *testing.T) {
+
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk1 := testCtx.decryptorSk1
+	sk0Shards := testCtx.sk0Shards
+	sk1Shards := testCtx.sk1Shards
+	ringQP := testCtx.dckksContext.ringQP
+
+	threshold := uint64(2)
+
+	t.Run(testString(fmt.Sprintf("Threshold/t=%d/", threshold), parties, testCtx.params), func(t *testing.T) {
+
+		type Party struct {
+			*Thresholdizer
+			point        ShamirPublicPoint
+			shamirShare0 ShamirSecretShare
+			shamirShare1 ShamirSecretShare
+		}
+
+		thrParties := make([]*Party, parties)
+		for i := range thrParties {
+			p := new(Party)
+			p.Thresholdizer = NewThresholdizer(testCtx.params)
+			p.point = ShamirPublicPoint(i + 1)
+			p.shamirShare0 = p.AllocateShamirShare()
+			p.shamirShare1 = p.AllocateShamirShare()
+			thrParties[i] = p
+		}
+
+		// Each party sends the evaluation of its Shamir polynomials at the point of each party
+		share := thrParties[0].AllocateShamirShare()
+		for i, p := range thrParties {
+			poly0 := p.GenShamirPolynomial(threshold, sk0Shards[i].Get())
+			poly1 := p.GenShamirPolynomial(threshold, sk1Shards[i].Get())
+			for _, q := range thrParties {
+				p.GenShamirShare(q.point, poly0, share)
+				q.AggregateShares(q.shamirShare0, share, q.shamirShare0)
+				p.GenShamirShare(q.point, poly1, share)
+				q.AggregateShares(q.shamirShare1, share, q.shamirShare1)
+			}
+		}
+
+		// Only the first and last parties are online
+		online := []*Party{thrParties[0], thrParties[parties-1]}
+		activePoints := []ShamirPublicPoint{online[0].point, online[1].point}
+
+		combiner := NewCombiner(testCtx.params, threshold)
+
+		t.Run("AdditiveShares", func(t *testing.T) {
+			sk := ringQP.NewPoly()
+			skShare := ringQP.NewPoly()
+			for _, p := range online {
+				combiner.GenAdditiveShare(activePoints, p.point, p.shamirShare0, skShare)
+				ringQP.Add(sk, skShare, sk)
+			}
+			require.True(t, ringQP.Equal(sk, testCtx.sk0.Get()))
+		})
+
+		t.Run("Keyswitching", func(t *testing.T) {
+
+			coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+			cks := NewCKSProtocol(testCtx.params, 6.36)
+			combined := cks.AllocateShare()
+			cksShare := cks.AllocateShare()
+			for _, p := range online {
+				cks.GenShareThreshold(combiner, activePoints, p.point, p.shamirShare0, p.shamirShare1, ciphertext, cksShare)
+				cks.AggregateShares(combined, cksShare, combined)
+			}
+
+			ksCiphertext := ckks.NewCiphertext(testCtx.params, 1, ciphertext.Level(), ciphertext.Scale())
+			cks.KeySwitch(combined, ciphertext, ksCiphertext)
+
+			verifyTestVectors(testCtx, decryptorSk1, coeffs, ksCiphertext, t)
+		})
+
+		t.Run("PublicKeySwitching", func(t *testing.T) {
+
+			coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+			pcks := NewPCKSProtocol(testCtx.params, 6.36)
+			combined := pcks.AllocateShares(ciphertext.Level())
+			pcksShare := pcks.AllocateShares(ciphertext.Level())
+			for _, p := range online {
+				pcks.GenShareThreshold(combiner, activePoints, p.point, p.shamirShare0, testCtx.pk1, ciphertext, pcksShare)
+				pcks.AggregateShares(combined, pcksShare, combined)
+			}
+
+			ksCiphertext := ckks.NewCiphertext(testCtx.params, 1, ciphertext.Level(), ciphertext.Scale())
+			pcks.KeySwitch(combined, ciphertext, ksCiphertext)
+
+			verifyTestVectors(testCtx, decryptorSk1, coeffs, ksCiphertext, t)
+		})
+	})
+}
+
+func testRotKeyGenConjugate(testCtx *testContext, t *testing.T) {
+
+	ringQP := testCtx.dckksContext.ringQP
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("RotKeyGenConjugate/", parties, testCtx.params), func(t *testing.T) {
+
+		type Party struct {
+			*RTGProtocol
+			s     *ring.Poly
+			share RTGShare
+		}
+
+		pcksParties := make([]*Party, parties)
+		for i := uint64(0); i < parties; i++ {
+			p := new(Party)
+			p.RTGProtocol = NewRotKGProtocol(testCtx.params)
+			p.s = sk0Shards[i].Get()
+			p.share = p.AllocateShare()
+			pcksParties[i] = p
+		}
+		P0 := pcksParties[0]
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, testCtx.dckksContext.ringQP)
+		crp := make([]*ring.Poly, testCtx.params.Beta())
+
+		for i := uint64(0); i < testCtx.params.Beta(); i++ {
+			crp[i] = crpGenerator.ReadNew()
+		}
+
+		for i, p := range pcksParties {
+			p.GenShare(ckks.Conjugate, 0, p.s, crp, &p.share)
+			if i > 0 {
+				P0.Aggregate(p.share, P0.share, P0.share)
+			}
+		}
+
+		rotkey := ckks.NewRotationKeys()
+		P0.Finalize(testCtx.params, P0.share, crp, rotkey)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		evaluator.Conjugate(ciphertext, rotkey, ciphertext)
+
+		coeffsWant := make([]complex128, ringQP.N>>1)
+
+		for i := uint64(0); i < ringQP.N>>1; i++ {
+			coeffsWant[i] = complex(real(coeffs[i]), -imag(coeffs[i]))
+		}
+
+		verifyTestVectors(testCtx, decryptorSk0, coeffsWant, ciphertext, t)
+
+	})
+}
+
+func testRotKeyGenCols(testCtx *testContext, t *testing.T) {
+
+	ringQP := testCtx.dckksContext.ringQP
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("RotKeyGenCols/", parties, testCtx.params), func(t *testing.T) {
+
+		type Party struct {
+			*RTGProtocol
+			s     *ring.Poly
+			share RTGShare
+		}
+
+		pcksParties := make([]*Party, parties)
+		for i := uint64(0); i < parties; i++ {
+			p := new(Party)
+			p.RTGProtocol = NewRotKGProtocol(testCtx.params)
+			p.s = sk0Shards[i].Get()
+			p.share = p.AllocateShare()
+			pcksParties[i] = p
+		}
+
+		P0 := pcksParties[0]
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, ringQP)
+		crp := make([]*ring.Poly, testCtx.params.Beta())
+
+		for i := uint64(0); i < testCtx.params.Beta(); i++ {
+			crp[i] = crpGenerator.ReadNew()
+		}
+
+		mask := (ringQP.N >> 1) - 1
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
+
+		receiver := ckks.NewCiphertext(testCtx.params, ciphertext.Degree(), ciphertext.Level(), ciphertext.Scale())
+
+		for k := uint64(1); k < ringQP.N>>1; k <<= 1 {
+
+			for i, p := range pcksParties {
+				p.GenShare(ckks.RotationLeft, k, p.s, crp, &p.share)
+				if i > 0 {
+					P0.Aggregate(p.share, P0.share, P0.share)
+				}
+			}
+
+			rotkey := ckks.NewRotationKeys()
+			P0.Finalize(testCtx.params, P0.share, crp, rotkey)
+
+			evaluator.RotateColumns(ciphertext, k, rotkey, receiver)
+
+			coeffsWant := make([]complex128, ringQP.N>>1)
+
+			for i := uint64(0); i < ringQP.N>>1; i++ {
+				coeffsWant[i] = coeffs[(i+k)&mask]
+			}
+
+			verifyTestVectors(testCtx, decryptorSk0, coeffsWant, receiver, t)
+		}
+	})
+}
+
+func testRotKeyGenGaloisElements(testCtx *testContext, t *testing.T) {
+
+	ringQP := testCtx.dckksContext.ringQP
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("RotKeyGenGaloisElements/", parties, testCtx.params), func(t *testing.T) {
+
+		type Party struct {
+			*RTGProtocol
+			s     *ring.Poly
+			share RTGShare
+		}
+
+		rtgParties := make([]*Party, parties)
+		for i := uint64(0); i < parties; i++ {
+			p := new(Party)
+			p.RTGProtocol = NewRotKGProtocol(testCtx.params)
+			p.s = sk0Shards[i].Get()
+			p.share = p.AllocateShare()
+			rtgParties[i] = p
+		}
+
+		P0 := rtgParties[0]
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, ringQP)
+
+		slots := ringQP.N >> 1
+		mask := slots - 1
+
+		// Rotations to the left and to the right, the conjugation, and a rotation composed with the conjugation
+		rotations := []int{1, 5, -3}
+		galEls := testCtx.params.GaloisElementsForRotations(rotations, true)
+		galElRotConj := (testCtx.params.GaloisElementForColumnRotation(2) * testCtx.params.GaloisElementForRowRotation()) & ((ringQP.N << 1) - 1)
+		galEls = append(galEls, galElRotConj)
+
+		rotkey := ckks.NewRotationKeys()
+
+		for _, galEl := range galEls {
+
+			crp := make([]*ring.Poly, testCtx.params.Beta())
+			for i := range crp {
+				crp[i] = crpGenerator.ReadNew()
+			}
+
+			for i, p := range rtgParties {
+				p.GenShareGaloisElement(galEl, p.s, crp, &p.share)
+				if i > 0 {
+					P0.Aggregate(p.share, P0.share, P0.share)
+				}
+			}
+
+			P0.Finalize(testCtx.params, P0.share, crp, rotkey)
+		}
 
 		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
 
-		evaluator.Conjugate(ciphertext, rotkey, ciphertext)
+		receiver := ckks.NewCiphertext(testCtx.params, ciphertext.Degree(), ciphertext.Level(), ciphertext.Scale())
 
-		coeffsWant := make([]complex128, ringQP.N>>1)
+		for _, k := range rotations {
 
-		for i := uint64(0); i < ringQP.N>>1; i++ {
+			evaluator.Automorphism(ciphertext, testCtx.params.GaloisElementForColumnRotation(k), rotkey, receiver)
+
+			coeffsWant := make([]complex128, slots)
+			for i := uint64(0); i < slots; i++ {
+				coeffsWant[i] = coeffs[(i+uint64(k))&mask]
+			}
+
+			verifyTestVectors(testCtx, decryptorSk0, coeffsWant, receiver, t)
+		}
+
+		evaluator.Automorphism(ciphertext, testCtx.params.GaloisElementForRowRotation(), rotkey, receiver)
+
+		coeffsWant := make([]complex128, slots)
+		for i := uint64(0); i < slots; i++ {
 			coeffsWant[i] = complex(real(coeffs[i]), -imag(coeffs[i]))
 		}
 
-		verifyTestVectors(testCtx, decryptorSk0, coeffsWant, ciphertext, t)
+		verifyTestVectors(testCtx, decryptorSk0, coeffsWant, receiver, t)
+
+		evaluator.Automorphism(ciphertext, galElRotConj, rotkey, receiver)
+
+		for i := uint64(0); i < slots; i++ {
+			c := coeffs[(i+2)&mask]
+			coeffsWant[i] = complex(real(c), -imag(c))
+		}
 
+		verifyTestVectors(testCtx, decryptorSk0, coeffsWant, receiver, t)
 	})
 }
 
-func testRotKeyGenCols(testCtx *testContext, t *testing.T) {
+func testRotKeyGenBatch(testCtx *testContext, t *testing.T) {
 
-	ringQP := testCtx.dckksContext.ringQP
 	evaluator := testCtx.evaluator
 	encryptorPk0 := testCtx.encryptorPk0
 	decryptorSk0 := testCtx.decryptorSk0
 	sk0Shards := testCtx.sk0Shards
 
-	t.Run(testString("RotKeyGenCols/", parties, testCtx.params), func(t *testing.T) {
+	t.Run(testString("RotKeyGenBatch/", parties, testCtx.params), func(t *testing.T) {
 
-		type Party struct {
-			*RTGProtocol
-			s     *ring.Poly
-			share RTGShare
-		}
+		slots := testCtx.params.Slots()
+		mask := slots - 1
 
-		pcksParties := make([]*Party, parties)
-		for i := uint64(0); i < parties; i++ {
-			p := new(Party)
-			p.RTGProtocol = NewRotKGProtocol(testCtx.params)
-			p.s = sk0Shards[i].Get()
-			p.share = p.AllocateShare()
-			pcksParties[i] = p
-		}
+		rotations := []int{1, 2, 5, -3}
+		galEls := testCtx.params.GaloisElementsForRotations(rotations, false)
 
-		P0 := pcksParties[0]
+		crp := NewSessionCRPGenerator(testCtx.params, []byte("session seed"), parties).RTGBatch(galEls)
 
-		crpGenerator := ring.NewUniformSampler(testCtx.prng, ringQP)
-		crp := make([]*ring.Poly, testCtx.params.Beta())
+		rtg := NewRotKGProtocol(testCtx.params)
 
-		for i := uint64(0); i < testCtx.params.Beta(); i++ {
-			crp[i] = crpGenerator.ReadNew()
+		combined := rtg.AllocateBatchShare(len(galEls))
+		for i := uint64(0); i < parties; i++ {
+
+			share := rtg.AllocateBatchShare(len(galEls))
+			share.Party = i
+			rtg.GenBatchShare(galEls, sk0Shards[i].Get(), crp, &share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+			received := new(RTGBatchShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, i, received.Party)
+			require.Error(t, new(RTGBatchShare).UnmarshalBinary(data[:len(data)-1]))
+
+			if i == 0 {
+				combined = *received
+			} else {
+				rtg.AggregateBatch(*received, combined, combined)
+			}
 		}
 
-		mask := (ringQP.N >> 1) - 1
+		rotkey := ckks.NewRotationKeys()
+		rtg.FinalizeBatch(testCtx.params, combined, crp, rotkey)
 
 		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1, t)
 
 		receiver := ckks.NewCiphertext(testCtx.params, ciphertext.Degree(), ciphertext.Level(), ciphertext.Scale())
 
-		for k := uint64(1); k < ringQP.N>>1; k <<= 1 {
-
-			for i, p := range pcksParties {
-				p.GenShare(ckks.RotationLeft, k, p.s, crp, &p.share)
-				if i > 0 {
-					P0.Aggregate(p.share, P0.share, P0.share)
-				}
-			}
-
-			rotkey := ckks.NewRotationKeys()
-			P0.Finalize(testCtx.params, P0.share, crp, rotkey)
-
-			evaluator.RotateColumns(ciphertext, k, rotkey, receiver)
+		for _, k := range rotations {
 
-			coeffsWant := make([]complex128, ringQP.N>>1)
+			evaluator.Automorphism(ciphertext, testCtx.params.GaloisElementForColumnRotation(k), rotkey, receiver)
 
-			for i := uint64(0); i < ringQP.N>>1; i++ {
-				coeffsWant[i] = coeffs[(i+k)&mask]
+			coeffsWant := make([]complex128, slots)
+			for i := uint64(0); i < slots; i++ {
+				coeffsWant[i] = coeffs[(i+uint64(k))&mask]
 			}
 
 			verifyTestVectors(testCtx, decryptorSk0, coeffsWant, receiver, t)
@@ -580,8 +1710,8 @@ func testRefresh(testCtx *testContext, t *testing.T) {
 		for i, p := range RefreshParties {
 			p.GenShares(p.s, levelStart, parties, ciphertext, crp, p.share1, p.share2)
 			if i > 0 {
-				P0.Aggregate(p.share1, P0.share1, P0.share1)
-				P0.Aggregate(p.share2, P0.share2, P0.share2)
+				P0.AggregateDecrypt(p.share1, P0.share1, P0.share1)
+				P0.AggregateRecrypt(p.share2, P0.share2, P0.share2)
 			}
 		}
 
@@ -597,6 +1727,74 @@ func testRefresh(testCtx *testContext, t *testing.T) {
 	})
 }
 
+func testRefreshWithScale(testCtx *testContext, t *testing.T) {
+
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+
+	levelStart := uint64(3)
+
+	t.Run(testString("RefreshWithScale/", parties, testCtx.params), func(t *testing.T) {
+
+		if testCtx.params.MaxLevel() < 3 {
+			t.Skip()
+		}
+
+		type Party struct {
+			*RefreshProtocol
+			s      *ring.Poly
+			share1 RefreshShareDecrypt
+			share2 RefreshShareRecrypt
+		}
+
+		RefreshParties := make([]*Party, parties)
+		for i := uint64(0); i < parties; i++ {
+			p := new(Party)
+			p.RefreshProtocol = NewRefreshProtocol(testCtx.params)
+			p.s = sk0Shards[i].Get()
+			p.share1, p.share2 = p.AllocateShares(levelStart)
+			RefreshParties[i] = p
+		}
+
+		P0 := RefreshParties[0]
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, testCtx.dckksContext.ringQ)
+		crp := crpGenerator.ReadNew()
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1.0, t)
+
+		for ciphertext.Level() != levelStart {
+			evaluator.DropLevel(ciphertext, 1)
+		}
+
+		// Scale of twice the default one on values multiplied by 1.5, as after an imprecise rescaling
+		evaluator.ScaleUp(ciphertext, 3, ciphertext)
+		ciphertext.DivScale(1.5)
+		for i := range coeffs {
+			coeffs[i] *= 1.5
+		}
+
+		targetScale := testCtx.params.Scale()
+
+		for i, p := range RefreshParties {
+			p.GenSharesWithScale(p.s, levelStart, parties, ciphertext, targetScale, crp, p.share1, p.share2)
+			if i > 0 {
+				P0.AggregateDecrypt(p.share1, P0.share1, P0.share1)
+				P0.AggregateRecrypt(p.share2, P0.share2, P0.share2)
+			}
+		}
+
+		P0.Finalize(ciphertext, targetScale, crp, P0.share1, P0.share2)
+
+		require.Equal(t, ciphertext.Level(), testCtx.params.MaxLevel())
+		require.Equal(t, ciphertext.Scale(), targetScale)
+
+		verifyTestVectors(testCtx, decryptorSk0, coeffs, ciphertext, t)
+	})
+}
+
 func testRefreshAndPermute(testCtx *testContext, t *testing.T) {
 
 	evaluator := testCtx.evaluator
@@ -648,8 +1846,8 @@ func testRefreshAndPermute(testCtx *testContext, t *testing.T) {
 		for i, p := range RefreshParties {
 			p.GenShares(p.s, levelStart, parties, ciphertext, crp, testCtx.params.Slots(), permutation, p.share1, p.share2)
 			if i > 0 {
-				P0.Aggregate(p.share1, P0.share1, P0.share1)
-				P0.Aggregate(p.share2, P0.share2, P0.share2)
+				P0.AggregateDecrypt(p.share1, P0.share1, P0.share1)
+				P0.AggregateRecrypt(p.share2, P0.share2, P0.share2)
 			}
 		}
 
@@ -671,6 +1869,197 @@ func testRefreshAndPermute(testCtx *testContext, t *testing.T) {
 	})
 }
 
+func testRefreshAndTransform(testCtx *testContext, t *testing.T) {
+
+	evaluator := testCtx.evaluator
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+	slots := testCtx.params.Slots()
+
+	levelStart := uint64(3)
+
+	t.Run(testString("SlotTransform/", parties, testCtx.params), func(t *testing.T) {
+
+		seeds := [][]byte{{'a'}, {'b'}, {'c'}}
+
+		transform := NewJointSlotTransform(seeds, slots, true)
+		require.Equal(t, transform, NewJointSlotTransform(seeds, slots, true))
+
+		values := make([]complex128, slots)
+		for i := range values {
+			values[i] = randomComplex(testCtx.prng, 1)
+		}
+
+		// Composition in the order of the seeds
+		composed := NewSlotTransform(seeds[0], slots, true).Apply(values)
+		composed = NewSlotTransform(seeds[1], slots, true).Apply(composed)
+		composed = NewSlotTransform(seeds[2], slots, true).Apply(composed)
+		require.Equal(t, composed, transform.Apply(values))
+
+		require.Equal(t, values, transform.Inverse().Apply(transform.Apply(values)))
+
+		// The permutation is a bijection
+		seen := make([]bool, slots)
+		for _, j := range transform.Permutation {
+			require.False(t, seen[j])
+			seen[j] = true
+		}
+
+		require.Nil(t, NewSlotTransform(seeds[0], slots, false).Phases)
+	})
+
+	t.Run(testString("RefreshAndTransform/", parties, testCtx.params), func(t *testing.T) {
+
+		if testCtx.params.MaxLevel() < 3 {
+			t.Skip()
+		}
+
+		type Party struct {
+			*PermuteProtocol
+			s      *ring.Poly
+			seed   []byte
+			share1 RefreshShareDecrypt
+			share2 RefreshShareRecrypt
+		}
+
+		RefreshParties := make([]*Party, parties)
+		seeds := make([][]byte, parties)
+		for i := uint64(0); i < parties; i++ {
+			p := new(Party)
+			p.PermuteProtocol = NewPermuteProtocol(testCtx.params)
+			p.s = sk0Shards[i].Get()
+			p.seed = []byte{'s', 'e', 'e', 'd', byte(i)}
+			p.share1, p.share2 = p.AllocateShares(levelStart)
+			RefreshParties[i] = p
+			seeds[i] = p.seed
+		}
+
+		P0 := RefreshParties[0]
+
+		crp := NewSessionCRPGenerator(testCtx.params, []byte{'c', 'r', 'p'}, parties).Permute(0)
+
+		coeffs, _, ciphertext := newTestVectors(testCtx, encryptorPk0, 1.0, t)
+
+		for ciphertext.Level() != levelStart {
+			evaluator.DropLevel(ciphertext, 1)
+		}
+
+		// The parties exchange their seeds
+		transform := NewJointSlotTransform(seeds, slots, true)
+
+		for i, p := range RefreshParties {
+			p.GenSharesWithTransform(p.s, levelStart, parties, ciphertext, crp, slots, transform, p.share1, p.share2)
+			if i > 0 {
+				P0.AggregateDecrypt(p.share1, P0.share1, P0.share1)
+				P0.AggregateRecrypt(p.share2, P0.share2, P0.share2)
+			}
+		}
+
+		P0.Decrypt(ciphertext, P0.share1)
+		P0.Transform(ciphertext, transform, slots)
+		P0.Recrypt(ciphertext, crp, P0.share2)
+
+		require.Equal(t, ciphertext.Level(), testCtx.params.MaxLevel())
+
+		verifyTestVectors(testCtx, decryptorSk0, transform.Apply(coeffs), ciphertext, t)
+	})
+}
+
+func testSecretPermute(testCtx *testContext, t *testing.T) {
+
+	encoder := testCtx.encoder
+	encryptorPk0 := testCtx.encryptorPk0
+	decryptorSk0 := testCtx.decryptorSk0
+	sk0Shards := testCtx.sk0Shards
+	params := testCtx.params
+
+	// Few slots, as the parties need the keys of all the rotations
+	slots := uint64(16)
+
+	t.Run(testString("SecretPermute/", parties, params), func(t *testing.T) {
+
+		if params.MaxLevel() < 3 {
+			t.Skip()
+		}
+
+		type Party struct {
+			*SecretPermuteProtocol
+			*RefreshProtocol
+			s *ring.Poly
+		}
+
+		seeds := make([][]byte, parties)
+		permuteParties := make([]*Party, parties)
+		for i := range permuteParties {
+			seeds[i] = []byte{'s', 'e', 'e', 'd', byte(i)}
+			p := new(Party)
+			p.SecretPermuteProtocol = NewSecretPermuteProtocol(params, seeds[i], slots, true, 8*params.Sigma())
+			p.RefreshProtocol = NewRefreshProtocol(params)
+			p.s = sk0Shards[i].Get()
+			permuteParties[i] = p
+		}
+
+		// The collective rotation keys, generated with the ideal secret key
+		kgen := ckks.NewKeyGenerator(params)
+		rotkeys := ckks.NewRotationKeys()
+		for _, k := range SecretPermuteRotations(slots) {
+			kgen.GenRotationKey(ckks.RotationLeft, testCtx.sk0, k, rotkeys)
+		}
+
+		values := make([]complex128, slots)
+		for i := range values {
+			values[i] = randomComplex(testCtx.prng, 1)
+		}
+
+		ciphertext := encryptorPk0.EncryptNew(encoder.EncodeNTTNew(values, slots))
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, testCtx.dckksContext.ringQ)
+
+		// Each party applies its transform alone, then all the parties refresh its output
+		for _, pi := range permuteParties {
+
+			ciphertext = pi.Permute(ciphertext, rotkeys)
+
+			levelStart := ciphertext.Level()
+			crp := crpGenerator.ReadNew()
+
+			P0 := permuteParties[0]
+			share1, share2 := P0.RefreshProtocol.AllocateShares(levelStart)
+			for i, p := range permuteParties {
+				pShare1, pShare2 := share1, share2
+				if i > 0 {
+					pShare1, pShare2 = p.RefreshProtocol.AllocateShares(levelStart)
+				}
+				p.RefreshProtocol.GenShares(p.s, levelStart, parties, ciphertext, crp, pShare1, pShare2)
+				if i > 0 {
+					P0.AggregateDecrypt(pShare1, share1, share1)
+					P0.AggregateRecrypt(pShare2, share2, share2)
+				}
+			}
+
+			P0.RefreshProtocol.Finalize(ciphertext, ciphertext.Scale(), crp, share1, share2)
+			require.Equal(t, params.MaxLevel(), ciphertext.Level())
+		}
+
+		// The reference composition, that only the test knows
+		composed := NewJointSlotTransform(seeds, slots, true)
+
+		// No party holds the composed permutation: each one only knows its own transform
+		for _, p := range permuteParties {
+			require.NotEqual(t, composed.Permutation, p.transform.Permutation)
+		}
+
+		valuesWant := composed.Apply(values)
+		valuesTest := encoder.Decode(decryptorSk0.DecryptNew(ciphertext), slots)
+
+		for i := range valuesWant {
+			require.GreaterOrEqual(t, math.Log2(1/math.Abs(real(valuesTest[i])-real(valuesWant[i]))), minPrec)
+			require.GreaterOrEqual(t, math.Log2(1/math.Abs(imag(valuesTest[i])-imag(valuesWant[i]))), minPrec)
+		}
+	})
+}
+
 func newTestVectors(testCtx *testContext, encryptor ckks.Encryptor, a float64, t *testing.T) (values []complex128, plaintext *ckks.Plaintext, ciphertext *ckks.Ciphertext) {
 
 	slots := testCtx.params.Slots()
@@ -803,3 +2192,250 @@ func calcmedian(values []complex128) (median complex128) {
 
 	return (values[index] + values[index+1]) / 2
 }
+
+func testSimulator(testCtx *testContext, t *testing.T) {
+
+	t.Run(testString("Simulator/", parties, testCtx.params), func(t *testing.T) {
+
+		circuit := Circuit{
+			PublicKey:          true,
+			RelinearizationKey: true,
+			Rotations:          4,
+			KeySwitches:        2,
+			PublicKeySwitches:  2,
+			Refreshes:          1,
+			Level:              testCtx.params.MaxLevel() / 2,
+		}
+
+		sim := NewSimulator(testCtx.params, parties)
+
+		_, err := sim.Estimate(circuit)
+		require.Error(t, err)
+
+		sim.Calibrate(circuit, 1)
+
+		est, err := sim.Estimate(circuit)
+		require.NoError(t, err)
+
+		// CKG, RKG (two rounds), RTG, CKS, PCKS and Refresh
+		require.Len(t, est.Rounds, 7)
+
+		for _, r := range est.Rounds {
+			require.Greater(t, int64(r.PartyTime), int64(0))
+			require.Equal(t, parties*r.PartyBytes, r.TotalBytes)
+		}
+
+		ckgShare := NewCKGProtocol(testCtx.params).AllocateShares()
+		data, err := ckgShare.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(data)), est.Rounds[0].ShareBytes)
+
+		rkgShare, _ := NewEkgProtocol(testCtx.params).AllocateShares()
+		data, err = rkgShare.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(data)), est.Rounds[1].ShareBytes)
+
+		require.Equal(t, circuit.Rotations*est.Rounds[3].ShareBytes, est.Rounds[3].PartyBytes)
+	})
+}
+
+func testMarshalShares(testCtx *testContext, t *testing.T) {
+
+	ringQP := testCtx.dckksContext.ringQP
+	sk0Shards := testCtx.sk0Shards
+
+	t.Run(testString("MarshalShares/", parties, testCtx.params), func(t *testing.T) {
+
+		crpGenerator := ring.NewUniformSampler(testCtx.prng, ringQP)
+		crp := make([]*ring.Poly, testCtx.params.Beta())
+		for i := range crp {
+			crp[i] = crpGenerator.ReadNew()
+		}
+
+		_, _, ciphertext := newTestVectors(testCtx, testCtx.encryptorPk0, 1, t)
+		testCtx.evaluator.DropLevel(ciphertext, 1)
+
+		t.Run("CKGShare", func(t *testing.T) {
+			ckg := NewCKGProtocol(testCtx.params)
+			share := ckg.AllocateShares()
+			share.Party = 2
+			ckg.GenShare(sk0Shards[0].Get(), crp[0], share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(CKGShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.True(t, ringQP.Equal(share.Poly, received.Poly))
+
+			// A share of another protocol, a truncated share and a share with an invalid level are rejected
+			require.Error(t, new(CKSShare).UnmarshalBinary(data))
+			require.Error(t, new(RTGBatchShare).UnmarshalBinary(data[:len(data)-1]))
+			data[9]--
+			require.Error(t, received.UnmarshalBinary(data))
+		})
+
+		t.Run("RKGShare", func(t *testing.T) {
+			rkg := NewEkgProtocol(testCtx.params)
+			share, _ := rkg.AllocateShares()
+			share.Party = 1
+			rkg.GenShareRoundOne(rkg.NewEphemeralKey(), sk0Shards[0].Get(), crp, share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(RKGShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.Equal(t, RKGPhaseRoundOne, received.Round)
+			require.Len(t, received.Value, len(share.Value))
+			for i := range share.Value {
+				require.True(t, ringQP.Equal(share.Value[i][0], received.Value[i][0]))
+				require.True(t, ringQP.Equal(share.Value[i][1], received.Value[i][1]))
+			}
+
+			// The round is part of the share
+			data[shareHeaderLen] = uint8(RKGPhaseRoundTwo)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, RKGPhaseRoundTwo, received.Round)
+			data[shareHeaderLen] = uint8(RKGPhaseDone)
+			require.Error(t, received.UnmarshalBinary(data))
+		})
+
+		t.Run("ShamirSecretShare", func(t *testing.T) {
+			thr := NewThresholdizer(testCtx.params)
+			share := thr.AllocateShamirShare()
+			share.Party = 2
+			thr.GenShamirShare(3, thr.GenShamirPolynomial(2, sk0Shards[0].Get()), share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(ShamirSecretShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.True(t, ringQP.Equal(share.Poly, received.Poly))
+		})
+
+		t.Run("RTGShare", func(t *testing.T) {
+			rtg := NewRotKGProtocol(testCtx.params)
+			share := rtg.AllocateShare()
+			share.Party = 3
+			rtg.GenShare(ckks.RotationLeft, 5, sk0Shards[0].Get(), crp, &share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(RTGShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.Equal(t, share.Type, received.Type)
+			require.Equal(t, share.K, received.K)
+			require.Equal(t, share.GaloisElement, received.GaloisElement)
+			for i := range share.Value {
+				require.True(t, ringQP.Equal(share.Value[i], received.Value[i]))
+			}
+		})
+
+		t.Run("CKSShare", func(t *testing.T) {
+			cks := NewCKSProtocol(testCtx.params, 6.36)
+			share := cks.AllocateShare()
+			share.Party = 2
+			cks.GenShare(sk0Shards[0].Get(), testCtx.sk1Shards[0].Get(), ciphertext, share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(CKSShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.True(t, testCtx.dckksContext.ringQ.EqualLvl(ciphertext.Level(), share.Poly, received.Poly))
+		})
+
+		t.Run("PCKSShare", func(t *testing.T) {
+			pcks := NewPCKSProtocol(testCtx.params, 6.36)
+			share := pcks.AllocateShares(ciphertext.Level())
+			share.Party = 2
+			pcks.GenShare(sk0Shards[0].Get(), testCtx.pk1, ciphertext, share)
+
+			data, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			received := new(PCKSShare)
+			require.NoError(t, received.UnmarshalBinary(data))
+			require.Equal(t, share.Party, received.Party)
+			require.Equal(t, uint64(len(received.Value[0].Coeffs)), ciphertext.Level()+1)
+			require.True(t, testCtx.dckksContext.ringQ.EqualLvl(ciphertext.Level(), share.Value[0], received.Value[0]))
+			require.True(t, testCtx.dckksContext.ringQ.EqualLvl(ciphertext.Level(), share.Value[1], received.Value[1]))
+		})
+
+		t.Run("RefreshShares", func(t *testing.T) {
+			refresh := NewRefreshProtocol(testCtx.params)
+			shareDecrypt, shareRecrypt := refresh.AllocateShares(ciphertext.Level())
+			shareDecrypt.Party, shareRecrypt.Party = 1, 1
+			refresh.GenShares(sk0Shards[0].Get(), ciphertext.Level(), parties, ciphertext, crp[0], shareDecrypt, shareRecrypt)
+
+			data, err := shareDecrypt.MarshalBinary()
+			require.NoError(t, err)
+
+			receivedDecrypt := new(RefreshShareDecrypt)
+			require.NoError(t, receivedDecrypt.UnmarshalBinary(data))
+			require.Equal(t, shareDecrypt.Party, receivedDecrypt.Party)
+			require.True(t, testCtx.dckksContext.ringQ.EqualLvl(ciphertext.Level(), shareDecrypt.Poly, receivedDecrypt.Poly))
+			require.Error(t, new(RefreshShareRecrypt).UnmarshalBinary(data))
+
+			data, err = shareRecrypt.MarshalBinary()
+			require.NoError(t, err)
+
+			receivedRecrypt := new(RefreshShareRecrypt)
+			require.NoError(t, receivedRecrypt.UnmarshalBinary(data))
+			require.Equal(t, shareRecrypt.Party, receivedRecrypt.Party)
+			require.True(t, testCtx.dckksContext.ringQ.Equal(shareRecrypt.Poly, receivedRecrypt.Poly))
+		})
+
+		t.Run("Malformed", func(t *testing.T) {
+
+			// A CKSShare of 2^61 coefficients at level 7, whose length would be 13 bytes in 64-bit arithmetic
+			data := make([]byte, shareHeaderLen+2)
+			data[0], data[9], data[10] = uint8(shareTypeCKS), 7, 1
+			data[shareHeaderLen], data[shareHeaderLen+1] = 61, 8
+			require.Error(t, new(CKSShare).UnmarshalBinary(data))
+			require.Error(t, new(ring.Poly).UnmarshalBinary(data[shareHeaderLen:]))
+
+			batch := make([]byte, 12+len(data))
+			batch[3], batch[11] = 1, uint8(len(data))
+			copy(batch[12:], data)
+			require.Error(t, new(RTGBatchShare).UnmarshalBinary(batch))
+
+			// Every value of the level, number of polynomials, degree and number of moduli of a well-formed share is
+			// rejected or decoded without panic
+			cks := NewCKSProtocol(testCtx.params, 6.36)
+			share := cks.AllocateShare()
+			cks.GenShare(sk0Shards[0].Get(), testCtx.sk1Shards[0].Get(), ciphertext, share)
+			valid, err := share.MarshalBinary()
+			require.NoError(t, err)
+
+			rkg := NewEkgProtocol(testCtx.params)
+			session, err := rkg.NewSession(0, parties, crp).MarshalBinary()
+			require.NoError(t, err)
+
+			// The polynomials of the session follow its metadata, with no received share
+			for _, encoded := range []struct {
+				data       []byte
+				polyHeader int
+			}{{valid, shareHeaderLen}, {session, shareHeaderLen + rkgSessionMetaLen}} {
+				for _, pos := range []int{9, 10, encoded.polyHeader, encoded.polyHeader + 1} {
+					for v := 0; v < 256; v++ {
+						data := append([]byte{}, encoded.data...)
+						data[pos] = uint8(v)
+						require.NotPanics(t, func() {
+							_ = new(CKSShare).UnmarshalBinary(data)
+							_, _ = rkg.LoadSession(crp, data)
+						})
+					}
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,295 @@
+// +build grpc
+
+// Package grpctransport is a reference implementation of the dckks.Transport interface over gRPC. It is only built
+// with the grpc build tag, e.g. go build -tags grpc, so that the builds of the other packages do not depend on gRPC.
+//
+// Each party runs a gRPC server on which its Transport is registered, and sends its shares to the servers of the other
+// parties with unary calls. The shares are carried as raw bytes by a codec of the package, so that no protobuf
+// definition is needed. The index of the sender is the one it claims in the call: the application must authenticate
+// the parties, e.g. with mutual TLS passed in the options of the server and of the Transport, and should reject the
+// calls of a peer claiming another index with an interceptor.
+//
+// gRPC rejects the messages larger than 4 MiB by default, which the shares exceed from logN = 15 with a few tens of
+// moduli. The Transports and the gRPC servers of the parties must therefore be given the size of the largest message
+// for the parameters, returned by MaxMessageSize, with NewTransport and ServerOptions.
+package grpctransport
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/dckks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcencoding "google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// codecName is the content-subtype of the calls of the Transport.
+const codecName = "lattigo-dckks-share"
+
+// maxRoundTagLen is the length of the longest round tag accounted for by MaxMessageSize.
+const maxRoundTagLen = 1 << 10
+
+func init() {
+	grpcencoding.RegisterCodec(codec{})
+}
+
+// Transport is a dckks.Transport sending the shares of the local party to the gRPC servers of the other parties and
+// receiving theirs on the gRPC server of the local party, on which it must be registered with Register. The received
+// shares are stored until the local party reads them with ReceiveShares.
+type Transport struct {
+	party       uint64
+	peers       map[uint64]string
+	options     []grpc.DialOption
+	callOptions []grpc.CallOption
+
+	// The received shares are delivered to the local party through a network of all the parties in memory, in which
+	// they are sent by the Transports of their senders
+	inbox *dckks.MemoryNetwork
+
+	mu    sync.Mutex
+	conns map[uint64]*grpc.ClientConn
+}
+
+// MaxMessageSize returns the size of the largest message of a Transport for the parameters, carrying a share of at most
+// dckks.MaxMarshaledShareLen(params, batchSize) bytes with a round tag of at most 1 KiB.
+func MaxMessageSize(params *ckks.Parameters, batchSize int) int {
+	return 12 + maxRoundTagLen + int(dckks.MaxMarshaledShareLen(params, batchSize))
+}
+
+// ServerOptions returns the options of the gRPC server of the local party raising its limits on the size of the
+// messages to maxMessageSize, to be given to grpc.NewServer along with the credentials.
+func ServerOptions(maxMessageSize int) []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize)}
+}
+
+// NewTransport creates a new Transport for the local party, with the addresses of the gRPC servers of the other
+// parties indexed by party. The connections to the other parties are created on the first share sent to them, with
+// the given options, which must include the transport credentials. The calls of the Transport are limited to messages
+// of maxMessageSize bytes, see MaxMessageSize.
+func NewTransport(party uint64, peers map[uint64]string, maxMessageSize int, options ...grpc.DialOption) *Transport {
+
+	parties := []uint64{party}
+	for peer := range peers {
+		if peer == party {
+			panic(fmt.Sprintf("cannot NewTransport: the local party %d is one of the peers", party))
+		}
+		parties = append(parties, peer)
+	}
+
+	return &Transport{
+		party:   party,
+		peers:   peers,
+		options: options,
+		callOptions: []grpc.CallOption{
+			grpc.CallContentSubtype(codecName),
+			grpc.MaxCallSendMsgSize(maxMessageSize),
+			grpc.MaxCallRecvMsgSize(maxMessageSize),
+		},
+		inbox: dckks.NewMemoryNetwork(parties),
+		conns: make(map[uint64]*grpc.ClientConn),
+	}
+}
+
+// Register registers the Transport on the gRPC server of the local party, which receives the shares of the others.
+func (tr *Transport) Register(server *grpc.Server) {
+	server.RegisterService(&serviceDesc, tr)
+}
+
+// Close closes the connections of the Transport to the other parties.
+func (tr *Transport) Close() (err error) {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for peer, conn := range tr.conns {
+		if errClose := conn.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+		delete(tr.conns, peer)
+	}
+
+	return err
+}
+
+// Party returns the index of the local party.
+func (tr *Transport) Party() uint64 {
+	return tr.party
+}
+
+// SendShare sends the share of the local party for the round to the party to.
+func (tr *Transport) SendShare(ctx context.Context, to uint64, round dckks.RoundTag, share encoding.BinaryMarshaler) (err error) {
+
+	if len(round) > maxRoundTagLen {
+		return fmt.Errorf("cannot SendShare: round tag longer than %d bytes", maxRoundTagLen)
+	}
+
+	conn, err := tr.conn(to)
+	if err != nil {
+		return err
+	}
+
+	data, err := share.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	msg := &shareMessage{from: tr.party, round: round, data: data}
+
+	if err = conn.Invoke(ctx, sendMethod, msg, new(emptyMessage), tr.callOptions...); err != nil {
+		return fmt.Errorf("cannot SendShare: %w", err)
+	}
+
+	return nil
+}
+
+// ReceiveShares blocks until the local party received the shares of all the parties from for the round, and returns
+// them indexed by party. It returns an error if ctx is done before.
+func (tr *Transport) ReceiveShares(ctx context.Context, round dckks.RoundTag, from []uint64) (map[uint64][]byte, error) {
+	return tr.inbox.Transport(tr.party).ReceiveShares(ctx, round, from)
+}
+
+// conn returns the connection to the party to, which is created on the first call.
+func (tr *Transport) conn(to uint64) (*grpc.ClientConn, error) {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if conn, ok := tr.conns[to]; ok {
+		return conn, nil
+	}
+
+	addr, ok := tr.peers[to]
+	if !ok {
+		return nil, fmt.Errorf("cannot SendShare: party %d is not a peer", to)
+	}
+
+	conn, err := grpc.Dial(addr, tr.options...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot SendShare: %w", err)
+	}
+
+	tr.conns[to] = conn
+
+	return conn, nil
+}
+
+// receive stores the share of a call to the gRPC server of the local party.
+func (tr *Transport) receive(ctx context.Context, msg *shareMessage) (*emptyMessage, error) {
+
+	if _, ok := tr.peers[msg.from]; !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "party %d is not a peer", msg.from)
+	}
+
+	if err := tr.inbox.Transport(msg.from).SendShare(ctx, tr.party, msg.round, rawShare(msg.data)); err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	return new(emptyMessage), nil
+}
+
+// shareReceiver is the interface of the handler of the service, implemented by Transport.
+type shareReceiver interface {
+	receive(ctx context.Context, msg *shareMessage) (*emptyMessage, error)
+}
+
+const sendMethod = "/lattigo.dckks.ShareExchange/Send"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "lattigo.dckks.ShareExchange",
+	HandlerType: (*shareReceiver)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Send",
+			Handler:    sendHandler,
+		},
+	},
+	Metadata: "dckks/grpctransport",
+}
+
+func sendHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+
+	msg := new(shareMessage)
+	if err := dec(msg); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(shareReceiver).receive(ctx, msg)
+	}
+
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: sendMethod}
+
+	return interceptor(ctx, msg, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(shareReceiver).receive(ctx, req.(*shareMessage))
+	})
+}
+
+// rawShare is a share already marshaled.
+type rawShare []byte
+
+func (share rawShare) MarshalBinary() ([]byte, error) {
+	return share, nil
+}
+
+// shareMessage is the request of a call: the index of the sender, the round and the marshaled share.
+type shareMessage struct {
+	from  uint64
+	round dckks.RoundTag
+	data  []byte
+}
+
+// emptyMessage is the response of a call.
+type emptyMessage struct{}
+
+// codec encodes the messages of the calls, a shareMessage as the index of the sender on 8 bytes, the length of the
+// round on 4 bytes, the round and the share.
+type codec struct{}
+
+func (codec) Name() string {
+	return codecName
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+
+	switch msg := v.(type) {
+	case *shareMessage:
+		data := make([]byte, 12+len(msg.round)+len(msg.data))
+		binary.BigEndian.PutUint64(data[0:8], msg.from)
+		binary.BigEndian.PutUint32(data[8:12], uint32(len(msg.round)))
+		copy(data[12+copy(data[12:], msg.round):], msg.data)
+		return data, nil
+	case *emptyMessage:
+		return []byte{}, nil
+	default:
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+
+	switch msg := v.(type) {
+	case *shareMessage:
+		if len(data) < 12 || uint64(binary.BigEndian.Uint32(data[8:12])) > uint64(len(data)-12) {
+			return errors.New("cannot unmarshal share message: data is too short")
+		}
+		roundLen := 12 + int(binary.BigEndian.Uint32(data[8:12]))
+		msg.from = binary.BigEndian.Uint64(data[0:8])
+		msg.round = dckks.RoundTag(data[12:roundLen])
+		msg.data = append([]byte{}, data[roundLen:]...)
+		return nil
+	case *emptyMessage:
+		if len(data) != 0 {
+			return errors.New("cannot unmarshal empty message: data is not empty")
+		}
+		return nil
+	default:
+		return fmt.Errorf("cannot unmarshal %T", v)
+	}
+}
@@ -0,0 +1,222 @@
+// +build grpc
+
+package grpctransport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/dckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// startParties starts a gRPC server on the loopback interface for each party and returns their Transports, along with
+// a function stopping the servers and closing the Transports.
+func startParties(t *testing.T, parties []uint64, maxMessageSize int) (transports []*Transport, stop func()) {
+
+	listeners := make([]net.Listener, len(parties))
+	addrs := make(map[uint64]string, len(parties))
+	for i, party := range parties {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listeners[i] = lis
+		addrs[party] = lis.Addr().String()
+	}
+
+	servers := make([]*grpc.Server, len(parties))
+	transports = make([]*Transport, len(parties))
+	for i, party := range parties {
+
+		peers := make(map[uint64]string, len(parties)-1)
+		for peer, addr := range addrs {
+			if peer != party {
+				peers[peer] = addr
+			}
+		}
+
+		transports[i] = NewTransport(party, peers, maxMessageSize, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+		servers[i] = grpc.NewServer(ServerOptions(maxMessageSize)...)
+		transports[i].Register(servers[i])
+		go servers[i].Serve(listeners[i])
+	}
+
+	return transports, func() {
+		for i := range parties {
+			servers[i].Stop()
+			transports[i].Close()
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+
+	params := ckks.DefaultParams[ckks.PN12QP109]
+	parties := []uint64{0, 1, 2}
+
+	t.Run("CKG", func(t *testing.T) {
+
+		transports, stop := startParties(t, parties, MaxMessageSize(params, 0))
+		defer stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		kgen := ckks.NewKeyGenerator(params)
+		crp := dckks.NewSessionCRPGenerator(params, []byte("grpctransport"), uint64(len(parties))).CKG()
+
+		// Each party generates its share and sends it to party 0, which aggregates them with the AggregationTree of
+		// arity len(parties)
+		tree := dckks.NewAggregationTree(parties, len(parties))
+		sks := make([]*ckks.SecretKey, len(parties))
+		shares := make([]dckks.CKGShare, len(parties))
+		errs := make(chan error, len(parties))
+
+		for i, tr := range transports {
+			go func(i int, tr *Transport) {
+				ckg := dckks.NewCKGProtocol(params)
+				sks[i] = kgen.GenSecretKey()
+				shares[i] = ckg.AllocateShares()
+				shares[i].Party = tr.Party()
+				ckg.GenShare(sks[i].Get(), crp, shares[i])
+
+				if parent, ok := tree.Parent(tr.Party()); ok {
+					errs <- tr.SendShare(ctx, parent, "ckg", &shares[i])
+					return
+				}
+
+				received, err := tr.ReceiveShares(ctx, "ckg", tree.Children(tr.Party()))
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				for party, data := range received {
+					share := new(dckks.CKGShare)
+					if err = share.UnmarshalBinary(data); err != nil {
+						errs <- err
+						return
+					}
+					if share.Party != party {
+						errs <- fmt.Errorf("share of party %d received from party %d", share.Party, party)
+						return
+					}
+					ckg.AggregateShares(*share, shares[i], shares[i])
+				}
+
+				errs <- nil
+			}(i, tr)
+		}
+
+		for range transports {
+			require.NoError(t, <-errs)
+		}
+
+		pk := ckks.NewPublicKey(params)
+		dckks.NewCKGProtocol(params).GenPublicKey(shares[tree.Root()], crp, pk)
+
+		// The public key encrypts under the sum of the secret keys
+		ringQP, err := ring.NewRing(params.N(), append(params.Qi(), params.Pi()...))
+		require.NoError(t, err)
+
+		skIdeal := ckks.NewSecretKey(params)
+		for _, sk := range sks {
+			ringQP.Add(skIdeal.Get(), sk.Get(), skIdeal.Get())
+		}
+
+		encoder := ckks.NewEncoder(params)
+		values := make([]complex128, params.Slots())
+		for i := range values {
+			values[i] = complex(float64(i)/float64(len(values)), 0)
+		}
+
+		ciphertext := ckks.NewEncryptorFromPk(params, pk).EncryptNew(encoder.EncodeNew(values, params.Slots()))
+		decoded := encoder.Decode(ckks.NewDecryptor(params, skIdeal).DecryptNew(ciphertext), params.Slots())
+
+		for i := range values {
+			require.InDelta(t, real(values[i]), real(decoded[i]), 1e-3)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+
+		transports, stop := startParties(t, parties[:2], MaxMessageSize(params, 0))
+		defer stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		share := rawShare("share")
+
+		// A share is delivered once per round and sender
+		require.NoError(t, transports[1].SendShare(ctx, 0, "round", share))
+		require.Error(t, transports[1].SendShare(ctx, 0, "round", share))
+		require.NoError(t, transports[1].SendShare(ctx, 0, "other round", share))
+
+		received, err := transports[0].ReceiveShares(ctx, "round", []uint64{1})
+		require.NoError(t, err)
+		require.Equal(t, []byte(share), received[1])
+
+		// The round is complete once its shares are delivered, after which its tag is free again
+		require.NoError(t, transports[1].SendShare(ctx, 0, "round", share))
+		received, err = transports[0].ReceiveShares(ctx, "round", []uint64{1})
+		require.NoError(t, err)
+		require.Equal(t, []byte(share), received[1])
+
+		require.Error(t, transports[1].SendShare(ctx, 0, dckks.RoundTag(make([]byte, maxRoundTagLen+1)), share))
+
+		// Parties which are not peers are rejected on both sides
+		require.Error(t, transports[1].SendShare(ctx, 2, "round", share))
+
+		stranger := NewTransport(2, map[uint64]string{0: transports[1].peers[0]}, MaxMessageSize(params, 0), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		defer stranger.Close()
+		require.Error(t, stranger.SendShare(ctx, 0, "round", share))
+
+		// ReceiveShares returns when the context is done
+		ctxShort, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancelShort()
+		_, err = transports[0].ReceiveShares(ctxShort, "missing", []uint64{1})
+		require.Equal(t, context.DeadlineExceeded, err)
+	})
+	t.Run("LargeShare", func(t *testing.T) {
+
+		// A CKS share at logN = 16 is larger than the 4 MiB default limit of gRPC on the received messages
+		paramsLarge := ckks.DefaultParams[ckks.PN16QP1761]
+
+		transports, stop := startParties(t, parties[:2], MaxMessageSize(paramsLarge, 0))
+		defer stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		share := dckks.CKSShare{Poly: ring.NewPoly(paramsLarge.N(), uint64(len(paramsLarge.Qi()))), Party: 1}
+		for i := range share.Coeffs {
+			for j := range share.Coeffs[i] {
+				share.Coeffs[i][j] = uint64(i + j)
+			}
+		}
+
+		data, err := share.MarshalBinary()
+		require.NoError(t, err)
+		require.Greater(t, len(data), 4<<20)
+		require.LessOrEqual(t, uint64(len(data)), dckks.MaxMarshaledShareLen(paramsLarge, 0))
+
+		require.NoError(t, transports[1].SendShare(ctx, 0, "cks", &share))
+
+		received, err := transports[0].ReceiveShares(ctx, "cks", []uint64{1})
+		require.NoError(t, err)
+		require.Equal(t, data, received[1])
+
+		// The servers and calls keeping the default limits reject it
+		transportsDefault, stopDefault := startParties(t, parties[:2], 4<<20)
+		defer stopDefault()
+		require.Error(t, transportsDefault[1].SendShare(ctx, 0, "cks", &share))
+	})
+}
@@ -12,20 +12,28 @@ type CKSProtocol struct {
 
 	sigmaSmudging float64
 
-	tmp      *ring.Poly
 	tmpDelta *ring.Poly
 	hP       *ring.Poly
+	noisePQ  *ring.Poly // hP followed by the moduli of the share up to the level of the ciphertext, of ringPQ
+
+	// ringPQ has the moduli of P followed by the ones of Q, such that the moduli of P and the ones of Q up to a level
+	// are the moduli of ringPQ up to a level.
+	ringPQ *ring.Ring
 
 	baseconverter   *ring.FastBasisExtender
 	gaussianSampler *ring.GaussianSampler
+	smudgingSampler *ring.ConstantTimeGaussianSampler
 }
 
 // CKSShare is a struct holding a share of the CKS protocol.
-type CKSShare *ring.Poly
+type CKSShare struct {
+	*ring.Poly
+	Party uint64
+}
 
 // NewCKSProtocol creates a new CKSProtocol that will be used to operate a collective key-switching on a ciphertext encrypted under a collective public-key, whose
 // secret-shares are distributed among j parties, re-encrypting the ciphertext under another public-key, whose secret-shares are also known to the
-// parties.
+// parties. sigmaSmudging is the standard deviation of the smudging noise of the shares of GenShare.
 func NewCKSProtocol(params *ckks.Parameters, sigmaSmudging float64) (cks *CKSProtocol) {
 
 	cks = new(CKSProtocol)
@@ -34,23 +42,37 @@ func NewCKSProtocol(params *ckks.Parameters, sigmaSmudging float64) (cks *CKSPro
 
 	cks.dckksContext = dckksContext
 
-	cks.tmp = dckksContext.ringQP.NewPoly()
+	cks.sigmaSmudging = sigmaSmudging
+
 	cks.tmpDelta = dckksContext.ringQ.NewPoly()
 	cks.hP = dckksContext.ringP.NewPoly()
 
+	var err error
+	if cks.ringPQ, err = ring.NewRing(params.N(), append(params.Pi(), params.Qi()...)); err != nil {
+		panic(err)
+	}
+
+	cks.noisePQ = &ring.Poly{Coeffs: make([][]uint64, 0, len(cks.ringPQ.Modulus))}
+
 	cks.baseconverter = ring.NewFastBasisExtender(dckksContext.ringQ, dckksContext.ringP)
 	prng, err := utils.NewPRNG()
 	if err != nil {
 		panic(err)
 	}
-	cks.gaussianSampler = ring.NewGaussianSampler(prng, dckksContext.ringQP, params.Sigma(), uint64(6*params.Sigma()))
+	cks.gaussianSampler = ring.NewGaussianSampler(prng, cks.ringPQ, params.Sigma(), uint64(6*params.Sigma()))
+	cks.smudgingSampler = ring.NewConstantTimeGaussianSampler(prng, dckksContext.ringQ, params.Sigma(), uint64(6*params.Sigma()))
 
 	return cks
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (cks *CKSProtocol) SetLogger(logger utils.Logger) {
+	cks.dckksContext.setLogger(logger)
+}
+
 // AllocateShare allocates the share of the CKS protocol.
 func (cks *CKSProtocol) AllocateShare() CKSShare {
-	return cks.dckksContext.ringQ.NewPoly()
+	return CKSShare{Poly: cks.dckksContext.ringQ.NewPoly()}
 }
 
 // GenShare is the first and unique round of the CKSProtocol protocol. Each party holding a ciphertext ctx encrypted under a collective publick-key must
@@ -58,53 +80,54 @@ func (cks *CKSProtocol) AllocateShare() CKSShare {
 //
 // [(skInput_i - skOutput_i) * ctx[0] + e_i]
 //
-// Each party then broadcasts the result of this computation to the other j-1 parties.
+// Each party then broadcasts the result of this computation to the other j-1 parties. The noise e_i is a smudging noise of
+// standard deviation the sigmaSmudging of the protocol.
 func (cks *CKSProtocol) GenShare(skInput, skOutput *ring.Poly, ct *ckks.Ciphertext, shareOut CKSShare) {
+	cks.GenShareWithSigma(cks.sigmaSmudging, skInput, skOutput, ct, shareOut)
+}
+
+// GenShareWithSigma is GenShare with a smudging noise of standard deviation sigmaSmudging instead of the one of the
+// protocol, e.g. a larger one for a ciphertext whose decryption is released. The noise is sampled in constant time.
+func (cks *CKSProtocol) GenShareWithSigma(sigmaSmudging float64, skInput, skOutput *ring.Poly, ct *ckks.Ciphertext, shareOut CKSShare) {
 
-	cks.dckksContext.ringQ.Sub(skInput, skOutput, cks.tmpDelta)
+	cks.dckksContext.logger.Debug("dckks: CKS share generation", "level", ct.Level(), "sigmaSmudging", sigmaSmudging)
 
-	cks.genShareDelta(cks.tmpDelta, ct, shareOut)
+	cks.dckksContext.ringQ.SubLvl(ct.Level(), skInput, skOutput, cks.tmpDelta)
+
+	cks.genShareDelta(sigmaSmudging, cks.tmpDelta, ct, shareOut)
 }
 
-func (cks *CKSProtocol) genShareDelta(skDelta *ring.Poly, ct *ckks.Ciphertext, shareOut CKSShare) {
+func (cks *CKSProtocol) genShareDelta(sigmaSmudging float64, skDelta *ring.Poly, ct *ckks.Ciphertext, shareOut CKSShare) {
 
 	ringQ := cks.dckksContext.ringQ
 	ringP := cks.dckksContext.ringP
 
-	ringQ.MulCoeffsMontgomeryLvl(ct.Level(), ct.Value()[1], skDelta, shareOut)
-
-	ringQ.MulScalarBigintLvl(ct.Level(), shareOut, ringP.ModulusBigint, shareOut)
+	ringQ.MulCoeffsMontgomeryLvl(ct.Level(), ct.Value()[1], skDelta, shareOut.Poly)
 
-	// TODO : improve by only computing the NTT for the required primes
-	cks.gaussianSampler.Read(cks.tmp)
-	cks.dckksContext.ringQP.NTT(cks.tmp, cks.tmp)
+	ringQ.MulScalarBigintLvl(ct.Level(), shareOut.Poly, ringP.ModulusBigint, shareOut.Poly)
 
-	ringQ.AddLvl(ct.Level(), shareOut, cks.tmp, shareOut)
+	// Adds the noise on hP and on shareOut, seen as a single polynomial of ringPQ
+	cks.noisePQ.Coeffs = append(append(cks.noisePQ.Coeffs[:0], cks.hP.Coeffs...), shareOut.Coeffs[:ct.Level()+1]...)
+	cks.gaussianSampler.ReadAndAddNTTLvl(uint64(len(ringP.Modulus))+ct.Level(), cks.noisePQ)
 
-	for x, i := 0, uint64(len(ringQ.Modulus)); i < uint64(len(cks.dckksContext.ringQP.Modulus)); x, i = x+1, i+1 {
-		tmp0 := cks.tmp.Coeffs[i]
-		tmp1 := cks.hP.Coeffs[x]
-		for j := uint64(0); j < ringQ.N; j++ {
-			tmp1[j] += tmp0[j]
-		}
-	}
+	cks.baseconverter.ModDownSplitQPtoQNTT(ct.Level(), shareOut.Poly, cks.hP, shareOut.Poly)
 
-	cks.baseconverter.ModDownSplitNTTPQ(ct.Level(), shareOut, cks.hP, shareOut)
+	// The noise added before the division by P is only a rounding noise, the smudging noise is added after it
+	cks.dckksContext.addSmudgingNoiseLvl(ct.Level(), cks.smudgingSampler, sigmaSmudging, shareOut.Poly)
 
 	cks.hP.Zero()
-	cks.tmp.Zero()
 }
 
 // AggregateShares is the second part of the unique round of the CKSProtocol protocol. Upon receiving the j-1 elements each party computes :
 //
 // [ctx[0] + sum((skInput_i - skOutput_i) * ctx[0] + e_i), ctx[1]]
 func (cks *CKSProtocol) AggregateShares(share1, share2, shareOut CKSShare) {
-	cks.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1, share2, shareOut)
+	cks.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1.Poly, share2.Poly, shareOut.Poly)
 }
 
 // KeySwitch performs the actual keyswitching operation on a ciphertext ct and put the result in ctOut
 func (cks *CKSProtocol) KeySwitch(combined CKSShare, ct *ckks.Ciphertext, ctOut *ckks.Ciphertext) {
 	ctOut.SetScale(ct.Scale())
-	cks.dckksContext.ringQ.AddLvl(ct.Level(), ct.Value()[0], combined, ctOut.Value()[0])
+	cks.dckksContext.ringQ.AddLvl(ct.Level(), ct.Value()[0], combined.Poly, ctOut.Value()[0])
 	cks.dckksContext.ringQ.CopyLvl(ct.Level(), ct.Value()[1], ctOut.Value()[1])
 }
@@ -0,0 +1,381 @@
+package dckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// The shares of the protocols are marshaled with a header made of the type of the share, the index of the party which
+// sent it and the level and number of its polynomials, followed by the metadata specific to the type of the share and
+// by the marshaled polynomials. The header lets the receiver of a share check that it is the share it expects before
+// aggregating it, rather than aggregating polynomials of the wrong protocol or level. The Party field of the shares is
+// not used by the protocols: it is set by the application to the index of the party which sent the share.
+
+// shareType identifies the type of a marshaled share.
+type shareType uint8
+
+const (
+	shareTypeCKG shareType = iota + 1
+	shareTypeRKG
+	shareTypeRTG
+	shareTypeCKS
+	shareTypePCKS
+	shareTypeRefreshDecrypt
+	shareTypeRefreshRecrypt
+	shareTypeShamir
+	shareTypeRKGNaiveRoundOne
+	shareTypeRKGNaiveRoundTwo
+	shareTypeRKGSession
+	shareTypeRKGOneRound
+)
+
+var shareTypeNames = map[shareType]string{
+	shareTypeCKG:              "CKGShare",
+	shareTypeRKG:              "RKGShare",
+	shareTypeRTG:              "RTGShare",
+	shareTypeCKS:              "CKSShare",
+	shareTypePCKS:             "PCKSShare",
+	shareTypeRefreshDecrypt:   "RefreshShareDecrypt",
+	shareTypeRefreshRecrypt:   "RefreshShareRecrypt",
+	shareTypeShamir:           "ShamirSecretShare",
+	shareTypeRKGNaiveRoundOne: "RKGNaiveShareRoundOne",
+	shareTypeRKGNaiveRoundTwo: "RKGNaiveShareRoundTwo",
+	shareTypeRKGSession:       "RKGSession",
+	shareTypeRKGOneRound:      "RKGOneRoundShare",
+}
+
+func (t shareType) String() string {
+	if name, ok := shareTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("shareType(%d)", uint8(t))
+}
+
+// shareHeaderLen is the length of the header of a marshaled share: type, party, level and number of polynomials.
+const shareHeaderLen = 1 + 8 + 1 + 1
+
+// marshaledShareLen returns the length of a marshaled share with metaLen bytes of metadata and count polynomials of N
+// coefficients at the given level. It does not overflow for count and level+1 of at most 255 and N of at most
+// 2^ring.MaxLogN.
+func marshaledShareLen(metaLen, count, N, level uint64) uint64 {
+	return shareHeaderLen + metaLen + count*(2+8*N*(level+1))
+}
+
+// MaxMarshaledShareLen returns the length of the largest marshaled share of the protocols for the parameters, with
+// RTGBatchShares of at most batchSize keys. It bounds the size of the messages of a Transport.
+func MaxMarshaledShareLen(params *ckks.Parameters, batchSize int) uint64 {
+
+	beta := params.Beta()
+	levelQP := uint64(len(params.Qi())+len(params.Pi())) - 1
+
+	// The shares of a single key are at most beta triplets of polynomials in QP, for the one-round relinearization key
+	maxLen := marshaledShareLen(rtgShareMetaLen, 3*beta, params.N(), levelQP)
+
+	batchLen := 4 + uint64(batchSize)*(8+marshaledShareLen(rtgShareMetaLen, beta, params.N(), levelQP))
+	if batchLen > maxLen {
+		maxLen = batchLen
+	}
+
+	return maxLen
+}
+
+// marshalShare marshals a share of type t sent by the given party, with its metadata meta and its polynomials, which
+// must have the same degree and level.
+func marshalShare(t shareType, party uint64, meta []byte, polys ...*ring.Poly) (data []byte, err error) {
+
+	if len(polys) == 0 || len(polys) > 0xFF {
+		return nil, fmt.Errorf("cannot marshal %s: invalid number of polynomials %d", t, len(polys))
+	}
+
+	N := uint64(polys[0].GetDegree())
+	level := uint64(polys[0].GetLenModuli() - 1)
+
+	if level+1 > ring.MaxModuliCount {
+		return nil, fmt.Errorf("cannot marshal %s: more than %d moduli", t, ring.MaxModuliCount)
+	}
+
+	for _, p := range polys {
+		if uint64(p.GetDegree()) != N || uint64(p.GetLenModuli()-1) != level {
+			return nil, fmt.Errorf("cannot marshal %s: polynomials of different degrees or levels", t)
+		}
+	}
+
+	data = make([]byte, marshaledShareLen(uint64(len(meta)), uint64(len(polys)), N, level))
+
+	data[0] = uint8(t)
+	binary.BigEndian.PutUint64(data[1:9], party)
+	data[9] = uint8(level)
+	data[10] = uint8(len(polys))
+
+	ptr := uint64(shareHeaderLen)
+	ptr += uint64(copy(data[ptr:], meta))
+
+	for _, p := range polys {
+		if _, err = p.Encode(data[ptr:]); err != nil {
+			return nil, err
+		}
+		ptr += p.GetDataLen(true)
+	}
+
+	return data, nil
+}
+
+// unmarshalShare unmarshals a share of type t with metaLen bytes of metadata, and returns the party which sent it, its
+// metadata and its polynomials. It returns an error if the data is not a well-formed share of type t.
+func unmarshalShare(t shareType, metaLen uint64, data []byte) (party uint64, meta []byte, polys []*ring.Poly, err error) {
+
+	if uint64(len(data)) < shareHeaderLen+metaLen {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: data is too short", t)
+	}
+
+	if got := shareType(data[0]); got != t {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: data is a %s", t, got)
+	}
+
+	party = binary.BigEndian.Uint64(data[1:9])
+	level := uint64(data[9])
+	count := uint64(data[10])
+
+	if count == 0 {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: no polynomials", t)
+	}
+
+	ptr := shareHeaderLen + metaLen
+	meta = data[shareHeaderLen:ptr]
+
+	if ptr+2 > uint64(len(data)) || data[ptr] > ring.MaxLogN {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: invalid polynomial degree", t)
+	}
+
+	N := uint64(1) << data[ptr]
+
+	if uint64(len(data)) != marshaledShareLen(metaLen, count, N, level) {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: invalid length", t)
+	}
+
+	polys = make([]*ring.Poly, count)
+	polyLen := 2 + 8*N*(level+1)
+
+	for i := range polys {
+
+		if uint64(1)<<data[ptr] != N || uint64(data[ptr+1]) != level+1 {
+			return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: polynomials of different degrees or levels", t)
+		}
+
+		polys[i] = new(ring.Poly)
+		if err = polys[i].UnmarshalBinary(data[ptr : ptr+polyLen]); err != nil {
+			return 0, nil, nil, err
+		}
+		ptr += polyLen
+	}
+
+	return party, meta, polys, nil
+}
+
+// unmarshalSinglePolyShare is unmarshalShare for the shares of type t made of a single polynomial and no metadata.
+func unmarshalSinglePolyShare(t shareType, data []byte) (party uint64, poly *ring.Poly, err error) {
+	var polys []*ring.Poly
+	if party, _, polys, err = unmarshalShare(t, 0, data); err != nil {
+		return 0, nil, err
+	}
+	if len(polys) != 1 {
+		return 0, nil, fmt.Errorf("cannot unmarshal %s: invalid number of polynomials", t)
+	}
+	return party, polys[0], nil
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *CKGShare) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypeCKG, share.Party, nil, share.Poly)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *CKGShare) UnmarshalBinary(data []byte) (err error) {
+	share.Party, share.Poly, err = unmarshalSinglePolyShare(shareTypeCKG, data)
+	return
+}
+
+// marshalPairsShare is marshalShare for the shares of type t made of pairs of polynomials.
+func marshalPairsShare(t shareType, party uint64, meta []byte, value [][2]*ring.Poly) ([]byte, error) {
+	polys := make([]*ring.Poly, 0, 2*len(value))
+	for i := range value {
+		polys = append(polys, value[i][0], value[i][1])
+	}
+	return marshalShare(t, party, meta, polys...)
+}
+
+// unmarshalPairsShare is unmarshalShare for the shares of type t made of pairs of polynomials.
+func unmarshalPairsShare(t shareType, metaLen uint64, data []byte) (party uint64, meta []byte, value [][2]*ring.Poly, err error) {
+	var polys []*ring.Poly
+	if party, meta, polys, err = unmarshalShare(t, metaLen, data); err != nil {
+		return 0, nil, nil, err
+	}
+	if len(polys)&1 == 1 {
+		return 0, nil, nil, fmt.Errorf("cannot unmarshal %s: invalid number of polynomials", t)
+	}
+	value = make([][2]*ring.Poly, len(polys)>>1)
+	for i := range value {
+		value[i] = [2]*ring.Poly{polys[2*i], polys[2*i+1]}
+	}
+	return party, meta, value, nil
+}
+
+// rkgShareMetaLen is the length of the metadata of a marshaled RKGShare: round.
+const rkgShareMetaLen = 1
+
+// MarshalBinary encodes the share on a slice of bytes, with its round as metadata.
+func (share *RKGShare) MarshalBinary() ([]byte, error) {
+	return marshalPairsShare(shareTypeRKG, share.Party, []byte{uint8(share.Round)}, share.Value)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RKGShare) UnmarshalBinary(data []byte) (err error) {
+
+	var meta []byte
+	if share.Party, meta, share.Value, err = unmarshalPairsShare(shareTypeRKG, rkgShareMetaLen, data); err != nil {
+		return
+	}
+
+	if share.Round = RKGPhase(meta[0]); share.Round > RKGPhaseRoundTwo {
+		return fmt.Errorf("cannot unmarshal %s: invalid round %s", shareTypeRKG, share.Round)
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RKGNaiveShareRoundOne) MarshalBinary() ([]byte, error) {
+	return marshalPairsShare(shareTypeRKGNaiveRoundOne, share.Party, nil, share.Value)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RKGNaiveShareRoundOne) UnmarshalBinary(data []byte) (err error) {
+	share.Party, _, share.Value, err = unmarshalPairsShare(shareTypeRKGNaiveRoundOne, 0, data)
+	return
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RKGNaiveShareRoundTwo) MarshalBinary() ([]byte, error) {
+	return marshalPairsShare(shareTypeRKGNaiveRoundTwo, share.Party, nil, share.Value)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RKGNaiveShareRoundTwo) UnmarshalBinary(data []byte) (err error) {
+	share.Party, _, share.Value, err = unmarshalPairsShare(shareTypeRKGNaiveRoundTwo, 0, data)
+	return
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RKGOneRoundShare) MarshalBinary() ([]byte, error) {
+	polys := make([]*ring.Poly, 0, 3*len(share.Value))
+	for i := range share.Value {
+		polys = append(polys, share.Value[i][:]...)
+	}
+	return marshalShare(shareTypeRKGOneRound, share.Party, nil, polys...)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RKGOneRoundShare) UnmarshalBinary(data []byte) (err error) {
+	var polys []*ring.Poly
+	if share.Party, _, polys, err = unmarshalShare(shareTypeRKGOneRound, 0, data); err != nil {
+		return err
+	}
+	if len(polys)%3 != 0 {
+		return fmt.Errorf("cannot unmarshal %s: invalid number of polynomials", shareTypeRKGOneRound)
+	}
+	share.Value = make([][3]*ring.Poly, len(polys)/3)
+	for i := range share.Value {
+		share.Value[i] = [3]*ring.Poly{polys[3*i], polys[3*i+1], polys[3*i+2]}
+	}
+	return nil
+}
+
+// rtgShareMetaLen is the length of the metadata of a marshaled RTGShare: type, K and Galois element.
+const rtgShareMetaLen = 1 + 8 + 8
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RTGShare) MarshalBinary() ([]byte, error) {
+	meta := make([]byte, rtgShareMetaLen)
+	meta[0] = uint8(share.Type)
+	binary.BigEndian.PutUint64(meta[1:9], share.K)
+	binary.BigEndian.PutUint64(meta[9:17], share.GaloisElement)
+	return marshalShare(shareTypeRTG, share.Party, meta, share.Value...)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RTGShare) UnmarshalBinary(data []byte) (err error) {
+	var meta []byte
+	if share.Party, meta, share.Value, err = unmarshalShare(shareTypeRTG, rtgShareMetaLen, data); err != nil {
+		return err
+	}
+	share.Type = ckks.Rotation(meta[0])
+	share.K = binary.BigEndian.Uint64(meta[1:9])
+	share.GaloisElement = binary.BigEndian.Uint64(meta[9:17])
+	return nil
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *CKSShare) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypeCKS, share.Party, nil, share.Poly)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *CKSShare) UnmarshalBinary(data []byte) (err error) {
+	share.Party, share.Poly, err = unmarshalSinglePolyShare(shareTypeCKS, data)
+	return
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *PCKSShare) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypePCKS, share.Party, nil, share.Value[0], share.Value[1])
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *PCKSShare) UnmarshalBinary(data []byte) (err error) {
+	var polys []*ring.Poly
+	if share.Party, _, polys, err = unmarshalShare(shareTypePCKS, 0, data); err != nil {
+		return err
+	}
+	if len(polys) != 2 {
+		return errors.New("cannot unmarshal PCKSShare: invalid number of polynomials")
+	}
+	share.Value = [2]*ring.Poly{polys[0], polys[1]}
+	return nil
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RefreshShareDecrypt) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypeRefreshDecrypt, share.Party, nil, share.Poly)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RefreshShareDecrypt) UnmarshalBinary(data []byte) (err error) {
+	share.Party, share.Poly, err = unmarshalSinglePolyShare(shareTypeRefreshDecrypt, data)
+	return
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *RefreshShareRecrypt) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypeRefreshRecrypt, share.Party, nil, share.Poly)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *RefreshShareRecrypt) UnmarshalBinary(data []byte) (err error) {
+	share.Party, share.Poly, err = unmarshalSinglePolyShare(shareTypeRefreshRecrypt, data)
+	return
+}
+
+// MarshalBinary encodes the share on a slice of bytes.
+func (share *ShamirSecretShare) MarshalBinary() ([]byte, error) {
+	return marshalShare(shareTypeShamir, share.Party, nil, share.Poly)
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the share.
+func (share *ShamirSecretShare) UnmarshalBinary(data []byte) (err error) {
+	share.Party, share.Poly, err = unmarshalSinglePolyShare(shareTypeShamir, data)
+	return
+}
@@ -12,21 +12,31 @@ type PCKSProtocol struct {
 
 	sigmaSmudging float64
 
-	tmp *ring.Poly
+	// ringPQ has the moduli of P followed by the ones of Q, as the one of the CKSProtocol, so that the temporary
+	// polynomials of the share generation, taken from its pools, only have the moduli of P and the moduli of Q up to
+	// the level of the ciphertext.
+	ringPQ *ring.Ring
 
-	share0tmp *ring.Poly
-	share1tmp *ring.Poly
+	pkPQ  [2]*ring.Poly // Moduli of the public key of GenShare, in the order of ringPQ
+	viewQ *ring.Poly    // Moduli of Q of a temporary polynomial of ringPQ
+	viewP *ring.Poly    // Moduli of P of a temporary polynomial of ringPQ
+
+	skTmp *ring.Poly // Additive share of the secret key of GenShareThreshold
 
 	baseconverter            *ring.FastBasisExtender
 	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	smudgingSampler          *ring.ConstantTimeGaussianSampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
 // PCKSShare is a struct storing the share of the PCKS protocol.
-type PCKSShare [2]*ring.Poly
+type PCKSShare struct {
+	Value [2]*ring.Poly
+	Party uint64
+}
 
 // NewPCKSProtocol creates a new PCKSProtocol object and will be used to re-encrypt a ciphertext ctx encrypted under a secret-shared key mong j parties under a new
-// collective public-key.
+// collective public-key. sigmaSmudging is the standard deviation of the smudging noise of the shares of GenShare.
 func NewPCKSProtocol(params *ckks.Parameters, sigmaSmudging float64) *PCKSProtocol {
 
 	pcks := new(PCKSProtocol)
@@ -35,25 +45,41 @@ func NewPCKSProtocol(params *ckks.Parameters, sigmaSmudging float64) *PCKSProtoc
 
 	pcks.dckksContext = dckksContext
 
-	pcks.tmp = dckksContext.ringQP.NewPoly()
-	pcks.share0tmp = dckksContext.ringQP.NewPoly()
-	pcks.share1tmp = dckksContext.ringQP.NewPoly()
+	pcks.sigmaSmudging = sigmaSmudging
+
+	var err error
+	if pcks.ringPQ, err = ring.NewRing(params.N(), append(params.Pi(), params.Qi()...)); err != nil {
+		panic(err)
+	}
+
+	for i := range pcks.pkPQ {
+		pcks.pkPQ[i] = &ring.Poly{Coeffs: make([][]uint64, len(pcks.ringPQ.Modulus))}
+	}
+	pcks.viewQ = new(ring.Poly)
+	pcks.viewP = new(ring.Poly)
+	pcks.skTmp = dckksContext.ringQP.NewPoly()
 
 	pcks.baseconverter = ring.NewFastBasisExtender(dckksContext.ringQ, dckksContext.ringP)
 	prng, err := utils.NewPRNG()
 	if err != nil {
 		panic(err)
 	}
-	pcks.gaussianSampler = ring.NewGaussianSampler(prng, dckksContext.ringQP, params.Sigma(), uint64(6*params.Sigma()))
-	pcks.ternarySamplerMontgomery = ring.NewTernarySampler(prng, dckksContext.ringQP, 0.5, true)
+	pcks.gaussianSampler = ring.NewGaussianSampler(prng, pcks.ringPQ, params.Sigma(), uint64(6*params.Sigma()))
+	pcks.smudgingSampler = ring.NewConstantTimeGaussianSampler(prng, dckksContext.ringQ, params.Sigma(), uint64(6*params.Sigma()))
+	pcks.ternarySamplerMontgomery = ring.NewTernarySampler(prng, pcks.ringPQ, 0.5, true)
 
 	return pcks
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (pcks *PCKSProtocol) SetLogger(logger utils.Logger) {
+	pcks.dckksContext.setLogger(logger)
+}
+
 // AllocateShares allocates the share of the PCKS protocol.
 func (pcks *PCKSProtocol) AllocateShares(level uint64) (s PCKSShare) {
-	s[0] = pcks.dckksContext.ringQ.NewPolyLvl(level)
-	s[1] = pcks.dckksContext.ringQ.NewPolyLvl(level)
+	s.Value[0] = pcks.dckksContext.ringQ.NewPolyLvl(level)
+	s.Value[1] = pcks.dckksContext.ringQ.NewPolyLvl(level)
 	return
 }
 
@@ -61,40 +87,70 @@ func (pcks *PCKSProtocol) AllocateShares(level uint64) (s PCKSShare) {
 //
 // [s_i * ctx[0] + u_i * pk[0] + e_0i, u_i * pk[1] + e_1i]
 //
-// and broadcasts the result to the other j-1 parties.
+// and broadcasts the result to the other j-1 parties. The noise e_0i includes a smudging noise of standard deviation the
+// sigmaSmudging of the protocol.
 func (pcks *PCKSProtocol) GenShare(sk *ring.Poly, pk *ckks.PublicKey, ct *ckks.Ciphertext, shareOut PCKSShare) {
+	pcks.GenShareWithSigma(pcks.sigmaSmudging, sk, pk, ct, shareOut)
+}
+
+// GenShareWithSigma is GenShare with a smudging noise of standard deviation sigmaSmudging instead of the one of the
+// protocol, e.g. a larger one for a ciphertext whose decryption is released. The noise is sampled in constant time.
+func (pcks *PCKSProtocol) GenShareWithSigma(sigmaSmudging float64, sk *ring.Poly, pk *ckks.PublicKey, ct *ckks.Ciphertext, shareOut PCKSShare) {
+
+	pcks.dckksContext.logger.Debug("dckks: PCKS share generation", "level", ct.Level(), "sigmaSmudging", sigmaSmudging)
 
 	ringQ := pcks.dckksContext.ringQ
-	ringQP := pcks.dckksContext.ringQP
+	ringPQ := pcks.ringPQ
+
+	// The moduli of Q above the level of the ciphertext are dropped by the division by P: u_i, its products with
+	// the public key and the noise are only computed for the moduli of P and the moduli of Q up to this level
+	level := ct.Level()
+	nP := uint64(len(pcks.dckksContext.ringP.Modulus))
+	levelPQ := nP + level
 
-	pcks.ternarySamplerMontgomery.Read(pcks.tmp)
-	ringQP.NTT(pcks.tmp, pcks.tmp)
+	for i := range pcks.pkPQ {
+		copy(pcks.pkPQ[i].Coeffs, pk.Get()[i].Coeffs[len(ringQ.Modulus):])
+		copy(pcks.pkPQ[i].Coeffs[nP:], pk.Get()[i].Coeffs)
+	}
+
+	u := ringPQ.GetPolyLvl(levelPQ)
+	h0 := ringPQ.GetPolyLvl(levelPQ)
+	h1 := ringPQ.GetPolyLvl(levelPQ)
+
+	pcks.ternarySamplerMontgomery.ReadLvl(levelPQ, u)
+	ringPQ.NTTLvl(levelPQ, u, u)
 
 	// h_0 = u_i * pk_0
-	ringQP.MulCoeffsMontgomery(pcks.tmp, pk.Get()[0], pcks.share0tmp)
+	ringPQ.MulCoeffsMontgomeryLvl(levelPQ, u, pcks.pkPQ[0], h0)
 	// h_1 = u_i * pk_1
-	ringQP.MulCoeffsMontgomery(pcks.tmp, pk.Get()[1], pcks.share1tmp)
+	ringPQ.MulCoeffsMontgomeryLvl(levelPQ, u, pcks.pkPQ[1], h1)
 
 	// h_0 = u_i * pk_0 + e0
-	pcks.gaussianSampler.Read(pcks.tmp)
-	ringQP.NTT(pcks.tmp, pcks.tmp)
-	ringQP.Add(pcks.share0tmp, pcks.tmp, pcks.share0tmp)
+	pcks.gaussianSampler.ReadAndAddNTTLvl(levelPQ, h0)
 	// h_1 = u_i * pk_1 + e1
-	pcks.gaussianSampler.Read(pcks.tmp)
-	ringQP.NTT(pcks.tmp, pcks.tmp)
-	ringQP.Add(pcks.share1tmp, pcks.tmp, pcks.share1tmp)
+	pcks.gaussianSampler.ReadAndAddNTTLvl(levelPQ, h1)
 
 	// h_0 = (u_i * pk_0 + e0)/P
-	pcks.baseconverter.ModDownNTTPQ(ct.Level(), pcks.share0tmp, shareOut[0])
+	pcks.viewQ.Coeffs, pcks.viewP.Coeffs = h0.Coeffs[nP:], h0.Coeffs[:nP]
+	pcks.baseconverter.ModDownSplitQPtoQNTT(level, pcks.viewQ, pcks.viewP, shareOut.Value[0])
 
 	// h_1 = (u_i * pk_1 + e1)/P
 	// Cound be moved to the keyswitch part of the protocol, but the second element of the shares will be larger.
-	pcks.baseconverter.ModDownNTTPQ(ct.Level(), pcks.share1tmp, shareOut[1])
+	pcks.viewQ.Coeffs, pcks.viewP.Coeffs = h1.Coeffs[nP:], h1.Coeffs[:nP]
+	pcks.baseconverter.ModDownSplitQPtoQNTT(level, pcks.viewQ, pcks.viewP, shareOut.Value[1])
+
+	// The pooled buffers hold the secret u_i and the noise e_i: they are wiped before they are given back
+	pcks.viewQ.Coeffs, pcks.viewP.Coeffs = nil, nil
+	for _, pol := range []*ring.Poly{u, h0, h1} {
+		pol.Zero()
+		ringPQ.PutPoly(pol)
+	}
 
 	// h_0 = s_i*c_1 + (u_i * pk_0 + e0)/P
-	ringQ.MulCoeffsMontgomeryAndAddLvl(ct.Level(), ct.Value()[1], sk, shareOut[0])
+	ringQ.MulCoeffsMontgomeryAndAddLvl(level, ct.Value()[1], sk, shareOut.Value[0])
 
-	pcks.tmp.Zero()
+	// h_0 = s_i*c_1 + (u_i * pk_0 + e0)/P + e_smudging
+	pcks.dckksContext.addSmudgingNoiseLvl(level, pcks.smudgingSampler, sigmaSmudging, shareOut.Value[0])
 }
 
 // AggregateShares is the second part of the first and unique round of the PCKSProtocol protocol. Each party uppon receiving the j-1 elements from the
@@ -103,9 +159,9 @@ func (pcks *PCKSProtocol) GenShare(sk *ring.Poly, pk *ckks.PublicKey, ct *ckks.C
 // [ctx[0] + sum(s_i * ctx[0] + u_i * pk[0] + e_0i), sum(u_i * pk[1] + e_1i)]
 func (pcks *PCKSProtocol) AggregateShares(share1, share2, shareOut PCKSShare) {
 
-	level := uint64(len(share1[0].Coeffs)) - 1
-	pcks.dckksContext.ringQ.AddLvl(level, share1[0], share2[0], shareOut[0])
-	pcks.dckksContext.ringQ.AddLvl(level, share1[1], share2[1], shareOut[1])
+	level := uint64(len(share1.Value[0].Coeffs)) - 1
+	pcks.dckksContext.ringQ.AddLvl(level, share1.Value[0], share2.Value[0], shareOut.Value[0])
+	pcks.dckksContext.ringQ.AddLvl(level, share1.Value[1], share2.Value[1], shareOut.Value[1])
 }
 
 // KeySwitch performs the actual keyswitching operation on a ciphertext ct and put the result in ctOut
@@ -113,6 +169,6 @@ func (pcks *PCKSProtocol) KeySwitch(combined PCKSShare, ct, ctOut *ckks.Cipherte
 
 	ctOut.SetScale(ct.Scale())
 
-	pcks.dckksContext.ringQ.AddLvl(ct.Level(), ct.Value()[0], combined[0], ctOut.Value()[0])
-	pcks.dckksContext.ringQ.CopyLvl(ct.Level(), combined[1], ctOut.Value()[1])
+	pcks.dckksContext.ringQ.AddLvl(ct.Level(), ct.Value()[0], combined.Value[0], ctOut.Value()[0])
+	pcks.dckksContext.ringQ.CopyLvl(ct.Level(), combined.Value[1], ctOut.Value()[1])
 }
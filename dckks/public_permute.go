@@ -12,7 +12,6 @@ import (
 type PermuteProtocol struct {
 	dckksContext    *dckksContext
 	encoder         ckks.EncoderBigComplex
-	tmp             *ring.Poly
 	maskBigint      []*big.Int
 	maskFloat       []*big.Float
 	maskComplex     []*ring.Complex
@@ -28,7 +27,6 @@ func NewPermuteProtocol(params *ckks.Parameters) (pp *PermuteProtocol) {
 	pp.encoder = ckks.NewEncoderBigComplex(params, prec)
 	dckksContext := newDckksContext(params)
 	pp.dckksContext = dckksContext
-	pp.tmp = dckksContext.ringQ.NewPoly()
 	pp.maskBigint = make([]*big.Int, dckksContext.n)
 	pp.maskFloat = make([]*big.Float, dckksContext.n)
 	pp.maskComplex = make([]*ring.Complex, dckksContext.n>>1)
@@ -52,25 +50,45 @@ func NewPermuteProtocol(params *ckks.Parameters) (pp *PermuteProtocol) {
 	return
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (pp *PermuteProtocol) SetLogger(logger utils.Logger) {
+	pp.dckksContext.setLogger(logger)
+}
+
 // AllocateShares allocates the shares of the Refresh protocol.
 func (pp *PermuteProtocol) AllocateShares(levelStart uint64) (RefreshShareDecrypt, RefreshShareRecrypt) {
-	return pp.dckksContext.ringQ.NewPolyLvl(levelStart), pp.dckksContext.ringQ.NewPoly()
+	return RefreshShareDecrypt{Poly: pp.dckksContext.ringQ.NewPolyLvl(levelStart)}, RefreshShareRecrypt{Poly: pp.dckksContext.ringQ.NewPoly()}
 }
 
-func (pp *PermuteProtocol) permuteWithIndex(permutation []uint64, values []*ring.Complex) {
-	tmp := make([]*ring.Complex, len(values))
+// applyTransform applies the SlotTransform to the first len(transform.Permutation) values.
+func (pp *PermuteProtocol) applyTransform(transform *SlotTransform, values []*ring.Complex) {
+
+	tmp := make([]*ring.Complex, len(transform.Permutation))
 
-	for i := range values {
-		tmp[i] = values[permutation[i]].Copy()
+	for i := range tmp {
+		tmp[i] = values[transform.Permutation[i]].Copy()
+		if transform.Phases != nil {
+			mulByUnit(tmp[i], transform.Phases[i])
+		}
 	}
 
-	for i := range values {
+	for i := range tmp {
 		values[i] = tmp[i]
 	}
 }
 
 // GenShares generates the decryption and recryption shares of the Refresh protocol.
 func (pp *PermuteProtocol) GenShares(sk *ring.Poly, levelStart, nParties uint64, ciphertext *ckks.Ciphertext, crs *ring.Poly, slots uint64, permutation []uint64, shareDecrypt RefreshShareDecrypt, shareRecrypt RefreshShareRecrypt) {
+	pp.GenSharesWithTransform(sk, levelStart, nParties, ciphertext, crs, slots, &SlotTransform{Permutation: permutation}, shareDecrypt, shareRecrypt)
+}
+
+// GenSharesWithTransform is GenShares for a SlotTransform, e.g. a joint secret permutation and masking of the slots
+// returned by NewJointSlotTransform, applied with Transform instead of Permute.
+func (pp *PermuteProtocol) GenSharesWithTransform(sk *ring.Poly, levelStart, nParties uint64, ciphertext *ckks.Ciphertext, crs *ring.Poly, slots uint64, transform *SlotTransform, shareDecrypt RefreshShareDecrypt, shareRecrypt RefreshShareRecrypt) {
+
+	pp.dckksContext.logger.Debug("dckks: permute shares generation", "levelStart", levelStart, "parties", nParties, "slots", slots)
+
+	transform.check(slots)
 
 	ringQ := pp.dckksContext.ringQ
 
@@ -98,14 +116,12 @@ func (pp *PermuteProtocol) GenShares(sk *ring.Poly, levelStart, nParties uint64,
 	}
 
 	// h0 = mask (at level min)
-	ringQ.SetCoefficientsBigintLvl(levelStart, pp.maskBigint, shareDecrypt)
-	ringQ.NTTLvl(levelStart, shareDecrypt, shareDecrypt)
+	ringQ.SetCoefficientsBigintLvl(levelStart, pp.maskBigint, shareDecrypt.Poly)
+	ringQ.NTTLvl(levelStart, shareDecrypt.Poly, shareDecrypt.Poly)
 	// h0 = sk*c1 + mask
-	ringQ.MulCoeffsMontgomeryAndAddLvl(levelStart, sk, ciphertext.Value()[1], shareDecrypt)
+	ringQ.MulCoeffsMontgomeryAndAddLvl(levelStart, sk, ciphertext.Value()[1], shareDecrypt.Poly)
 	// h0 = sk*c1 + mask + e0
-	pp.gaussianSampler.Read(pp.tmp)
-	ringQ.NTT(pp.tmp, pp.tmp)
-	ringQ.AddLvl(levelStart, shareDecrypt, pp.tmp, shareDecrypt)
+	pp.gaussianSampler.ReadAndAddNTTLvl(levelStart, shareDecrypt.Poly)
 
 	// Permutes only the (sparse) plaintext coefficients of h1
 	for i, jdx, idx := uint64(0), maxSlots, uint64(0); i < slots; i, jdx, idx = i+1, jdx+gap, idx+gap {
@@ -117,7 +133,7 @@ func (pp *PermuteProtocol) GenShares(sk *ring.Poly, levelStart, nParties uint64,
 
 	// h1 = pi(mask) (at level max)
 	pp.encoder.FFT(pp.maskComplex, slots)
-	pp.permuteWithIndex(permutation, pp.maskComplex)
+	pp.applyTransform(transform, pp.maskComplex)
 	pp.encoder.InvFFT(pp.maskComplex, slots)
 
 	for i, jdx, idx := uint64(0), maxSlots, uint64(0); i < slots; i, jdx, idx = i+1, jdx+gap, idx+gap {
@@ -125,75 +141,66 @@ func (pp *PermuteProtocol) GenShares(sk *ring.Poly, levelStart, nParties uint64,
 		pp.maskComplex[i].Imag().Int(pp.maskBigint[jdx])
 	}
 
-	ringQ.SetCoefficientsBigint(pp.maskBigint, shareRecrypt)
+	ringQ.SetCoefficientsBigint(pp.maskBigint, shareRecrypt.Poly)
 
-	ringQ.NTT(shareRecrypt, shareRecrypt)
+	ringQ.NTT(shareRecrypt.Poly, shareRecrypt.Poly)
 
 	// h1 = sk*a + mask
-	ringQ.MulCoeffsMontgomeryAndAdd(sk, crs, shareRecrypt)
+	ringQ.MulCoeffsMontgomeryAndAdd(sk, crs, shareRecrypt.Poly)
 
 	// h1 = sk*a + mask + e1
-	pp.gaussianSampler.Read(pp.tmp)
-	ringQ.NTT(pp.tmp, pp.tmp)
-	ringQ.Add(shareRecrypt, pp.tmp, shareRecrypt)
+	pp.gaussianSampler.ReadAndAddNTT(shareRecrypt.Poly)
 
 	// h1 = -sk*c1 - mask - e1
-	ringQ.Neg(shareRecrypt, shareRecrypt)
+	ringQ.Neg(shareRecrypt.Poly, shareRecrypt.Poly)
+}
 
-	pp.tmp.Zero()
+// AggregateDecrypt adds the decryption shares share1 and share2 on shareOut.
+func (pp *PermuteProtocol) AggregateDecrypt(share1, share2, shareOut RefreshShareDecrypt) {
+	pp.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1.Poly, share2.Poly, shareOut.Poly)
 }
 
-// Aggregate adds share1 with share2 on shareOut.
-func (pp *PermuteProtocol) Aggregate(share1, share2, shareOut *ring.Poly) {
-	pp.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1, share2, shareOut)
+// AggregateRecrypt adds the re-encryption shares share1 and share2 on shareOut.
+func (pp *PermuteProtocol) AggregateRecrypt(share1, share2, shareOut RefreshShareRecrypt) {
+	pp.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1.Poly, share2.Poly, shareOut.Poly)
 }
 
 // Decrypt operates a masked decryption on the ciphertext with the given decryption share.
 func (pp *PermuteProtocol) Decrypt(ciphertext *ckks.Ciphertext, shareDecrypt RefreshShareDecrypt) {
-	pp.dckksContext.ringQ.AddLvl(ciphertext.Level(), ciphertext.Value()[0], shareDecrypt, ciphertext.Value()[0])
+	pp.dckksContext.ringQ.AddLvl(ciphertext.Level(), ciphertext.Value()[0], shareDecrypt.Poly, ciphertext.Value()[0])
 }
 
 // Permute takes a masked decrypted ciphertext at modulus Q_0 and returns the same masked decrypted ciphertext at modulus Q_L, with Q_0 << Q_L.
 // Operates a permutation of the plaintext slots.
 func (pp *PermuteProtocol) Permute(ciphertext *ckks.Ciphertext, permutation []uint64, slots uint64) {
+	pp.Transform(ciphertext, &SlotTransform{Permutation: permutation}, slots)
+}
+
+// Transform is Permute for the SlotTransform of GenSharesWithTransform.
+func (pp *PermuteProtocol) Transform(ciphertext *ckks.Ciphertext, transform *SlotTransform, slots uint64) {
+
+	transform.check(slots)
+
 	dckksContext := pp.dckksContext
 	ringQ := pp.dckksContext.ringQ
 
 	ringQ.InvNTTLvl(ciphertext.Level(), ciphertext.Value()[0], ciphertext.Value()[0])
 
-	ringQ.PolyToBigint(ciphertext.Value()[0], pp.maskBigint)
-
-	QStart := ring.NewUint(ringQ.Modulus[0])
-	for i := uint64(1); i < ciphertext.Level()+1; i++ {
-		QStart.Mul(QStart, ring.NewUint(ringQ.Modulus[i]))
-	}
-	QHalf := new(big.Int).Rsh(QStart, 1)
+	// Centers the values around the current modulus
+	ringQ.PolyToBigintCenteredLvl(ciphertext.Level(), ciphertext.Value()[0], 1, pp.maskBigint)
 
 	maxSlots := pp.dckksContext.n >> 1
 	gap := maxSlots / slots
 
-	var sign int
 	for i, idx := uint64(0), uint64(0); i < slots; i, idx = i+1, idx+gap {
 
-		// Centers the value around the current modulus
-		sign = pp.maskBigint[idx].Cmp(QHalf)
-		if sign == 1 || sign == 0 {
-			pp.maskBigint[idx].Sub(pp.maskBigint[idx], QStart)
-		}
-
-		// Centers the value around the current modulus
-		sign = pp.maskBigint[idx+maxSlots].Cmp(QHalf)
-		if sign == 1 || sign == 0 {
-			pp.maskBigint[idx+maxSlots].Sub(pp.maskBigint[idx+maxSlots], QStart)
-		}
-
 		pp.maskComplex[i].Real().SetInt(pp.maskBigint[idx])
 		pp.maskComplex[i].Imag().SetInt(pp.maskBigint[idx+maxSlots])
 	}
 
 	pp.encoder.FFT(pp.maskComplex, slots)
 
-	pp.permuteWithIndex(permutation, pp.maskComplex)
+	pp.applyTransform(transform, pp.maskComplex)
 
 	pp.encoder.InvFFT(pp.maskComplex, slots)
 
@@ -215,7 +222,7 @@ func (pp *PermuteProtocol) Permute(ciphertext *ckks.Ciphertext, permutation []ui
 // Recrypt operates a masked recryption on the masked decrypted ciphertext.
 func (pp *PermuteProtocol) Recrypt(ciphertext *ckks.Ciphertext, crs *ring.Poly, shareRecrypt RefreshShareRecrypt) {
 
-	pp.dckksContext.ringQ.Add(ciphertext.Value()[0], shareRecrypt, ciphertext.Value()[0])
+	pp.dckksContext.ringQ.Add(ciphertext.Value()[0], shareRecrypt.Poly, ciphertext.Value()[0])
 
 	ciphertext.Value()[1] = crs.CopyNew()
 }
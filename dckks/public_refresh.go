@@ -11,16 +11,21 @@ import (
 // RefreshProtocol is a struct storing the parameters for the Refresh protocol.
 type RefreshProtocol struct {
 	dckksContext    *dckksContext
-	tmp             *ring.Poly
 	maskBigint      []*big.Int
 	gaussianSampler *ring.GaussianSampler
 }
 
 // RefreshShareDecrypt is a struct storing the masked decryption share.
-type RefreshShareDecrypt *ring.Poly
+type RefreshShareDecrypt struct {
+	*ring.Poly
+	Party uint64
+}
 
 // RefreshShareRecrypt is a struct storing the masked recryption share.
-type RefreshShareRecrypt *ring.Poly
+type RefreshShareRecrypt struct {
+	*ring.Poly
+	Party uint64
+}
 
 // NewRefreshProtocol creates a new instance of the Refresh protocol.
 func NewRefreshProtocol(params *ckks.Parameters) (refreshProtocol *RefreshProtocol) {
@@ -28,7 +33,6 @@ func NewRefreshProtocol(params *ckks.Parameters) (refreshProtocol *RefreshProtoc
 	refreshProtocol = new(RefreshProtocol)
 	dckksContext := newDckksContext(params)
 	refreshProtocol.dckksContext = dckksContext
-	refreshProtocol.tmp = dckksContext.ringQ.NewPoly()
 	refreshProtocol.maskBigint = make([]*big.Int, dckksContext.n)
 	prng, err := utils.NewPRNG()
 	if err != nil {
@@ -39,13 +43,29 @@ func NewRefreshProtocol(params *ckks.Parameters) (refreshProtocol *RefreshProtoc
 	return
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (refreshProtocol *RefreshProtocol) SetLogger(logger utils.Logger) {
+	refreshProtocol.dckksContext.setLogger(logger)
+}
+
 // AllocateShares allocates the shares of the Refresh protocol.
 func (refreshProtocol *RefreshProtocol) AllocateShares(levelStart uint64) (RefreshShareDecrypt, RefreshShareRecrypt) {
-	return refreshProtocol.dckksContext.ringQ.NewPolyLvl(levelStart), refreshProtocol.dckksContext.ringQ.NewPoly()
+	return RefreshShareDecrypt{Poly: refreshProtocol.dckksContext.ringQ.NewPolyLvl(levelStart)}, RefreshShareRecrypt{Poly: refreshProtocol.dckksContext.ringQ.NewPoly()}
 }
 
 // GenShares generates the decryption and recryption shares of the Refresh protocol.
 func (refreshProtocol *RefreshProtocol) GenShares(sk *ring.Poly, levelStart, nParties uint64, ciphertext *ckks.Ciphertext, crs *ring.Poly, shareDecrypt RefreshShareDecrypt, shareRecrypt RefreshShareRecrypt) {
+	refreshProtocol.GenSharesWithScale(sk, levelStart, nParties, ciphertext, ciphertext.Scale(), crs, shareDecrypt, shareRecrypt)
+}
+
+// GenSharesWithScale generates the decryption and recryption shares of the Refresh protocol for a refreshed ciphertext
+// of scale targetScale, e.g. the default scale of the parameters to restore the scale of a ciphertext that drifted
+// with the rescalings. All the parties must use the same targetScale, which must then be given to RecodeWithScale.
+// The mask of the recryption share is the mask of the decryption share multiplied by targetScale/ciphertext.Scale(),
+// so that it cancels the mask of the recoded ciphertext up to a rounding error of at most one per party.
+func (refreshProtocol *RefreshProtocol) GenSharesWithScale(sk *ring.Poly, levelStart, nParties uint64, ciphertext *ckks.Ciphertext, targetScale float64, crs *ring.Poly, shareDecrypt RefreshShareDecrypt, shareRecrypt RefreshShareRecrypt) {
+
+	refreshProtocol.dckksContext.logger.Debug("dckks: refresh shares generation", "levelStart", levelStart, "parties", nParties, "targetScale", targetScale)
 
 	ringQ := refreshProtocol.dckksContext.ringQ
 
@@ -67,78 +87,73 @@ func (refreshProtocol *RefreshProtocol) GenShares(sk *ring.Poly, levelStart, nPa
 	}
 
 	// h0 = mask (at level min)
-	ringQ.SetCoefficientsBigintLvl(levelStart, refreshProtocol.maskBigint, shareDecrypt)
-	// h1 = mask (at level max)
-	ringQ.SetCoefficientsBigint(refreshProtocol.maskBigint, shareRecrypt)
+	ringQ.SetCoefficientsBigintLvl(levelStart, refreshProtocol.maskBigint, shareDecrypt.Poly)
+	// h1 = mask * targetScale/scale (at level max)
+	scaleBigintRound(refreshProtocol.maskBigint, refreshScaleRatio(ciphertext.Scale(), targetScale))
+	ringQ.SetCoefficientsBigint(refreshProtocol.maskBigint, shareRecrypt.Poly)
 
 	for i := range refreshProtocol.maskBigint {
 		refreshProtocol.maskBigint[i].SetUint64(0)
 	}
 
-	ringQ.NTTLvl(levelStart, shareDecrypt, shareDecrypt)
-	ringQ.NTT(shareRecrypt, shareRecrypt)
+	ringQ.NTTLvl(levelStart, shareDecrypt.Poly, shareDecrypt.Poly)
+	ringQ.NTT(shareRecrypt.Poly, shareRecrypt.Poly)
 
 	// h0 = sk*c1 + mask
-	ringQ.MulCoeffsMontgomeryAndAddLvl(levelStart, sk, ciphertext.Value()[1], shareDecrypt)
+	ringQ.MulCoeffsMontgomeryAndAddLvl(levelStart, sk, ciphertext.Value()[1], shareDecrypt.Poly)
 
 	// h1 = sk*a + mask
-	ringQ.MulCoeffsMontgomeryAndAdd(sk, crs, shareRecrypt)
+	ringQ.MulCoeffsMontgomeryAndAdd(sk, crs, shareRecrypt.Poly)
 
-	// h0 = sk*c1 + mask + e0
-	refreshProtocol.gaussianSampler.Read(refreshProtocol.tmp)
-	ringQ.NTT(refreshProtocol.tmp, refreshProtocol.tmp)
-	ringQ.AddLvl(levelStart, shareDecrypt, refreshProtocol.tmp, shareDecrypt)
+	// h0 = sk*c1 + mask + e0, the noise being only transformed for the moduli up to levelStart
+	refreshProtocol.gaussianSampler.ReadAndAddNTTLvl(levelStart, shareDecrypt.Poly)
 
 	// h1 = sk*a + mask + e1
-	refreshProtocol.gaussianSampler.Read(refreshProtocol.tmp)
-	ringQ.NTT(refreshProtocol.tmp, refreshProtocol.tmp)
-	ringQ.Add(shareRecrypt, refreshProtocol.tmp, shareRecrypt)
+	refreshProtocol.gaussianSampler.ReadAndAddNTT(shareRecrypt.Poly)
 
 	// h1 = -sk*c1 - mask - e0
-	ringQ.Neg(shareRecrypt, shareRecrypt)
+	ringQ.Neg(shareRecrypt.Poly, shareRecrypt.Poly)
+}
 
-	refreshProtocol.tmp.Zero()
+// AggregateDecrypt adds the decryption shares share1 and share2 on shareOut.
+func (refreshProtocol *RefreshProtocol) AggregateDecrypt(share1, share2, shareOut RefreshShareDecrypt) {
+	refreshProtocol.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1.Poly, share2.Poly, shareOut.Poly)
 }
 
-// Aggregate adds share1 with share2 on shareOut.
-func (refreshProtocol *RefreshProtocol) Aggregate(share1, share2, shareOut *ring.Poly) {
-	refreshProtocol.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1, share2, shareOut)
+// AggregateRecrypt adds the re-encryption shares share1 and share2 on shareOut.
+func (refreshProtocol *RefreshProtocol) AggregateRecrypt(share1, share2, shareOut RefreshShareRecrypt) {
+	refreshProtocol.dckksContext.ringQ.AddLvl(uint64(len(share1.Coeffs)-1), share1.Poly, share2.Poly, shareOut.Poly)
 }
 
 // Decrypt operates a masked decryption on the ciphertext with the given decryption share.
 func (refreshProtocol *RefreshProtocol) Decrypt(ciphertext *ckks.Ciphertext, shareDecrypt RefreshShareDecrypt) {
-	refreshProtocol.dckksContext.ringQ.AddLvl(ciphertext.Level(), ciphertext.Value()[0], shareDecrypt, ciphertext.Value()[0])
+	refreshProtocol.dckksContext.ringQ.AddLvl(ciphertext.Level(), ciphertext.Value()[0], shareDecrypt.Poly, ciphertext.Value()[0])
 }
 
 // Recode takes a masked decrypted ciphertext at modulus Q_0 and returns the same masked decrypted ciphertext at modulus Q_L, with Q_0 << Q_L.
 func (refreshProtocol *RefreshProtocol) Recode(ciphertext *ckks.Ciphertext) {
+	refreshProtocol.RecodeWithScale(ciphertext, ciphertext.Scale())
+}
+
+// RecodeWithScale is Recode for the shares generated by GenSharesWithScale: the masked decrypted ciphertext is
+// multiplied by targetScale/ciphertext.Scale() before being lifted to Q_L, and its scale is set to targetScale.
+func (refreshProtocol *RefreshProtocol) RecodeWithScale(ciphertext *ckks.Ciphertext, targetScale float64) {
 	dckksContext := refreshProtocol.dckksContext
 	ringQ := refreshProtocol.dckksContext.ringQ
 
 	ringQ.InvNTTLvl(ciphertext.Level(), ciphertext.Value()[0], ciphertext.Value()[0])
 
-	ringQ.PolyToBigint(ciphertext.Value()[0], refreshProtocol.maskBigint)
-
-	QStart := ring.NewUint(ringQ.Modulus[0])
-	for i := uint64(1); i < ciphertext.Level()+1; i++ {
-		QStart.Mul(QStart, ring.NewUint(ringQ.Modulus[i]))
-	}
+	// Centers the mask around Q_0 before lifting it to Q_L
+	ringQ.PolyToBigintCenteredLvl(ciphertext.Level(), ciphertext.Value()[0], 1, refreshProtocol.maskBigint)
 
-	QHalf := new(big.Int).Rsh(QStart, 1)
+	scaleBigintRound(refreshProtocol.maskBigint, refreshScaleRatio(ciphertext.Scale(), targetScale))
+	ciphertext.SetScale(targetScale)
 
 	for ciphertext.Level() != dckksContext.params.MaxLevel() {
 		ciphertext.Value()[0].Coeffs = append(ciphertext.Value()[0].Coeffs, make([][]uint64, 1)...)
 		ciphertext.Value()[0].Coeffs[ciphertext.Level()] = make([]uint64, dckksContext.n)
 	}
 
-	var sign int
-	for i := uint64(0); i < dckksContext.n; i++ {
-		sign = refreshProtocol.maskBigint[i].Cmp(QHalf)
-		if sign == 1 || sign == 0 {
-			refreshProtocol.maskBigint[i].Sub(refreshProtocol.maskBigint[i], QStart)
-		}
-	}
-
 	ringQ.SetCoefficientsBigintLvl(ciphertext.Level(), refreshProtocol.maskBigint, ciphertext.Value()[0])
 
 	ringQ.NTTLvl(ciphertext.Level(), ciphertext.Value()[0], ciphertext.Value()[0])
@@ -147,7 +162,51 @@ func (refreshProtocol *RefreshProtocol) Recode(ciphertext *ckks.Ciphertext) {
 // Recrypt operates a masked recryption on the masked decrypted ciphertext.
 func (refreshProtocol *RefreshProtocol) Recrypt(ciphertext *ckks.Ciphertext, crs *ring.Poly, shareRecrypt RefreshShareRecrypt) {
 
-	refreshProtocol.dckksContext.ringQ.Add(ciphertext.Value()[0], shareRecrypt, ciphertext.Value()[0])
+	refreshProtocol.dckksContext.ringQ.Add(ciphertext.Value()[0], shareRecrypt.Poly, ciphertext.Value()[0])
 	crs.Coeffs = crs.Coeffs[:ciphertext.Level()+1]
 	ciphertext.Value()[1] = crs.CopyNew()
 }
+
+// Finalize refreshes the ciphertext with the aggregated shares of GenSharesWithScale, that is operates the masked
+// decryption, the recoding to the scale targetScale and the masked recryption. The refreshed ciphertext is at the
+// maximum level of the parameters.
+func (refreshProtocol *RefreshProtocol) Finalize(ciphertext *ckks.Ciphertext, targetScale float64, crs *ring.Poly, shareDecrypt RefreshShareDecrypt, shareRecrypt RefreshShareRecrypt) {
+	refreshProtocol.Decrypt(ciphertext, shareDecrypt)
+	refreshProtocol.RecodeWithScale(ciphertext, targetScale)
+	refreshProtocol.Recrypt(ciphertext, crs, shareRecrypt)
+}
+
+// refreshScaleRatio returns targetScale/scale as an exact rational, or nil if the scales are equal. The ratio is
+// computed in float64 and then converted exactly, so that all the parties scale their masks by the same value.
+func refreshScaleRatio(scale, targetScale float64) *big.Rat {
+	if scale == targetScale {
+		return nil
+	}
+	return new(big.Rat).SetFloat64(targetScale / scale)
+}
+
+// scaleBigintRound replaces each value by its product with ratio rounded to the nearest integer, ties away from zero.
+// A nil ratio leaves the values unchanged.
+func scaleBigintRound(values []*big.Int, ratio *big.Rat) {
+
+	if ratio == nil {
+		return
+	}
+
+	num, den := ratio.Num(), ratio.Denom()
+	halfDen := new(big.Int).Rsh(den, 1)
+	rem := new(big.Int)
+
+	for _, v := range values {
+		v.Mul(v, num)
+		v.QuoRem(v, den, rem)
+		// QuoRem truncates towards zero and rem has the sign of the dividend
+		if rem.Sign() != 0 && rem.CmpAbs(halfDen) >= 0 {
+			if rem.Sign() < 0 {
+				v.Sub(v, ring.NewUint(1))
+			} else {
+				v.Add(v, ring.NewUint(1))
+			}
+		}
+	}
+}
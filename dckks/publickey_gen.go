@@ -10,11 +10,14 @@ import (
 // CKGProtocol is the structure storing the parameters and state for a party in the collective key generation protocol.
 type CKGProtocol struct {
 	dckksContext    *dckksContext
-	gaussianSampler *ring.GaussianSampler
+	gaussianSampler ring.Sampler
 }
 
 // CKGShare is a struct storing the CKG protocol's share.
-type CKGShare *ring.Poly
+type CKGShare struct {
+	*ring.Poly
+	Party uint64
+}
 
 // NewCKGProtocol creates a new CKGProtocol instance
 func NewCKGProtocol(params *ckks.Parameters) *CKGProtocol {
@@ -29,9 +32,14 @@ func NewCKGProtocol(params *ckks.Parameters) *CKGProtocol {
 	return ckg
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (ckg *CKGProtocol) SetLogger(logger utils.Logger) {
+	ckg.dckksContext.setLogger(logger)
+}
+
 // AllocateShares allocates the share of the CKG protocol.
 func (ckg *CKGProtocol) AllocateShares() CKGShare {
-	return ckg.dckksContext.ringQP.NewPoly()
+	return CKGShare{Poly: ckg.dckksContext.ringQP.NewPoly()}
 }
 
 // GenShare generates the party's public key share from its secret key as:
@@ -40,19 +48,21 @@ func (ckg *CKGProtocol) AllocateShares() CKGShare {
 //
 // for the receiver protocol. Has no effect is the share was already generated.
 func (ckg *CKGProtocol) GenShare(sk *ring.Poly, crs *ring.Poly, shareOut CKGShare) {
+	ckg.dckksContext.logger.Debug("dckks: CKG share generation")
+
 	ringQP := ckg.dckksContext.ringQP
 
-	ckg.gaussianSampler.Read(shareOut)
-	ringQP.NTT(shareOut, shareOut)
-	ringQP.MulCoeffsMontgomeryAndSub(sk, crs, shareOut)
+	ckg.gaussianSampler.Read(shareOut.Poly)
+	ringQP.NTT(shareOut.Poly, shareOut.Poly)
+	ringQP.MulCoeffsMontgomeryAndSub(sk, crs, shareOut.Poly)
 }
 
 // AggregateShares aggregates a new share to the aggregate key
 func (ckg *CKGProtocol) AggregateShares(share1, share2, shareOut CKGShare) {
-	ckg.dckksContext.ringQP.Add(share1, share2, shareOut)
+	ckg.dckksContext.ringQP.Add(share1.Poly, share2.Poly, shareOut.Poly)
 }
 
 // GenPublicKey return the current aggregation of the received shares as a bfv.PublicKey.
 func (ckg *CKGProtocol) GenPublicKey(roundShare CKGShare, crs *ring.Poly, pubkey *ckks.PublicKey) {
-	pubkey.Set([2]*ring.Poly{roundShare, crs})
+	pubkey.Set([2]*ring.Poly{roundShare.Poly, crs})
 }
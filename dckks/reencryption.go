@@ -0,0 +1,128 @@
+package dckks
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// ReencryptionProtocol is the collective re-encryption of a ciphertext under the public key of a third party, e.g. a
+// client that was not part of the key generation, so that only this third party can decrypt the result. It is the
+// PCKSProtocol with a flooding noise added by each party to its share, which hides the secret key shares from the
+// receiver of the result, and with verification hooks run on the received shares before their aggregation.
+//
+// The flooding noise must be large compared to the noise of the ciphertext for statistical security, and it is added
+// to the result: sigmaFlooding must therefore be chosen according to the scale of the ciphertext and the precision the
+// receiver needs.
+type ReencryptionProtocol struct {
+	*PCKSProtocol
+
+	sigmaFlooding float64
+	verifiers     []ShareVerifier
+}
+
+// ShareVerifier is a hook checking a PCKSShare generated by a party on the ciphertext ct before its aggregation. It
+// returns a non-nil error to reject the share.
+type ShareVerifier func(ct *ckks.Ciphertext, share PCKSShare) error
+
+// NewReencryptionProtocol creates a new ReencryptionProtocol whose shares have a flooding noise of standard deviation
+// sigmaFlooding.
+func NewReencryptionProtocol(params *ckks.Parameters, sigmaFlooding float64) *ReencryptionProtocol {
+
+	if sigmaFlooding < 0 {
+		panic("cannot NewReencryptionProtocol: sigmaFlooding must be non-negative")
+	}
+
+	rep := new(ReencryptionProtocol)
+	rep.PCKSProtocol = NewPCKSProtocol(params, sigmaFlooding)
+	rep.sigmaFlooding = sigmaFlooding
+
+	return rep
+}
+
+// AddShareVerifier adds a hook to the verifications of VerifyShare, which runs the hooks in the order they were added.
+func (rep *ReencryptionProtocol) AddShareVerifier(verifier ShareVerifier) {
+	rep.verifiers = append(rep.verifiers, verifier)
+}
+
+// GenShare computes the share of the party of secret key share sk for the re-encryption of ct under the public key
+// pk of the receiver, with the flooding noise of the protocol.
+func (rep *ReencryptionProtocol) GenShare(sk *ring.Poly, pk *ckks.PublicKey, ct *ckks.Ciphertext, shareOut PCKSShare) {
+	rep.GenShareWithSigma(rep.sigmaFlooding, sk, pk, ct, shareOut)
+}
+
+// VerifyShare checks that the share is well formed for the ciphertext ct with ValidateShare, and then runs the hooks
+// of the protocol on it. It returns the first error encountered, prefixed with the party of the share.
+func (rep *ReencryptionProtocol) VerifyShare(ct *ckks.Ciphertext, share PCKSShare) (err error) {
+
+	if err = rep.ValidateShare(ct.Level(), share); err != nil {
+		return err
+	}
+
+	for _, verifier := range rep.verifiers {
+		if err = verifier(ct, share); err != nil {
+			return fmt.Errorf("invalid share of party %d: %s", share.Party, err)
+		}
+	}
+
+	return nil
+}
+
+// VerifyAndAggregate verifies the share with VerifyShare and, if it is valid, adds it to aggregate. aggregate is left
+// unchanged if the share is rejected.
+func (rep *ReencryptionProtocol) VerifyAndAggregate(ct *ckks.Ciphertext, share, aggregate PCKSShare) (err error) {
+
+	if err = rep.VerifyShare(ct, share); err != nil {
+		return err
+	}
+
+	rep.AggregateShares(share, aggregate, aggregate)
+
+	return nil
+}
+
+// Reencrypt writes on ctOut the re-encryption of ct under the public key of the receiver from the aggregate of the
+// shares of all the parties.
+func (rep *ReencryptionProtocol) Reencrypt(combined PCKSShare, ct, ctOut *ckks.Ciphertext) {
+	rep.KeySwitch(combined, ct, ctOut)
+}
+
+// CommitPCKSShare returns a commitment, with a fresh random salt, to a PCKSShare generated on a ciphertext at the
+// given level. As for the commitments of CommitShare, the salt is part of the commitment: the commitment binds the
+// party to its share before the shares are revealed, but does not hide it, as whoever holds the commitment can test a
+// guess of the share.
+func CommitPCKSShare(level uint64, share PCKSShare) (commitment ShareCommitment, err error) {
+	if _, err = rand.Read(commitment.Salt[:]); err != nil {
+		return commitment, err
+	}
+	commitment.Digest = saltedShareDigest("dckks/PCKS/share", commitment.Salt, level, share.Value[0], share.Value[1])
+	return commitment, nil
+}
+
+// OpenPCKSShare returns true if the commitment is a commitment, returned by CommitPCKSShare, to the PCKSShare at the
+// given level.
+func (commitment ShareCommitment) OpenPCKSShare(level uint64, share PCKSShare) bool {
+	return commitment.open(saltedShareDigest("dckks/PCKS/share", commitment.Salt, level, share.Value[0], share.Value[1]))
+}
+
+// NewCommitmentVerifier returns a ShareVerifier checking the shares against the commitments published by the parties
+// beforehand with CommitPCKSShare, indexed by the Party of the shares. It rejects the shares of the parties without
+// commitment.
+func NewCommitmentVerifier(commitments map[uint64]ShareCommitment) ShareVerifier {
+	return func(ct *ckks.Ciphertext, share PCKSShare) error {
+
+		commitment, ok := commitments[share.Party]
+		if !ok {
+			return errors.New("no commitment")
+		}
+
+		if !commitment.OpenPCKSShare(ct.Level(), share) {
+			return errors.New("commitment mismatch")
+		}
+
+		return nil
+	}
+}
@@ -1,8 +1,6 @@
 package dckks
 
 import (
-	"errors"
-
 	"github.com/ldsec/lattigo/v2/ckks"
 	"github.com/ldsec/lattigo/v2/ring"
 	"github.com/ldsec/lattigo/v2/utils"
@@ -15,83 +13,28 @@ type RKGProtocol struct {
 	tmpPoly1                 *ring.Poly
 	tmpPoly2                 *ring.Poly
 	polypool                 *ring.Poly
-	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
-// RKGShare is type for the RKGProtocol shares
-type RKGShare [][2]*ring.Poly
-
-// MarshalBinary encodes the target element on a slice of bytes.
-func (share *RKGShare) MarshalBinary() ([]byte, error) {
-	//we have modulus * bitLog * Len of 1 ring rings
-	rLength := ((*share)[0])[0].GetDataLen(true)
-	data := make([]byte, 1+2*rLength*uint64(len(*share)))
-	if len(*share) > 0xFF {
-		return []byte{}, errors.New("RKGShare : uint8 overflow on length")
-	}
-	data[0] = uint8(len(*share))
-
-	//write all of our rings in the data.
-	//write all the polys
-	ptr := uint64(1)
-	for _, elem := range *share {
-		_, err := elem[0].WriteTo(data[ptr : ptr+rLength])
-		if err != nil {
-			return []byte{}, err
-		}
-		ptr += rLength
-		_, err = elem[1].WriteTo(data[ptr : ptr+rLength])
-		if err != nil {
-			return []byte{}, err
-		}
-		ptr += rLength
-	}
-
-	return data, nil
-
-}
-
-// UnmarshalBinary decodes a slice of bytes on the target element.
-func (share *RKGShare) UnmarshalBinary(data []byte) error {
-	lenShare := data[0]
-	rLength := (len(data) - 1) / (2 * int(lenShare))
-
-	if *share == nil {
-		*share = make([][2]*ring.Poly, lenShare)
-	}
-	ptr := (1)
-	for i := (0); i < int(lenShare); i++ {
-		if (*share)[i][0] == nil || (*share)[i][1] == nil {
-			(*share)[i][0] = new(ring.Poly)
-			(*share)[i][1] = new(ring.Poly)
-		}
-
-		err := (*share)[i][0].UnmarshalBinary(data[ptr : ptr+rLength])
-		if err != nil {
-			return err
-		}
-		ptr += rLength
-		err = (*share)[i][1].UnmarshalBinary(data[ptr : ptr+rLength])
-		if err != nil {
-			return err
-		}
-		ptr += rLength
-
-	}
-
-	return nil
+// RKGShare is type for the RKGProtocol shares. Round is the round of the protocol the share is allocated for, which
+// is marshaled with the share so that an RKGSession rejects the shares of another round.
+type RKGShare struct {
+	Value [][2]*ring.Poly
+	Party uint64
+	Round RKGPhase
 }
 
-// AllocateShares allocates the shares of the EKG protocol.
+// AllocateShares allocates the shares of the EKG protocol, of round RKGPhaseRoundOne and RKGPhaseRoundTwo.
 func (ekg *RKGProtocol) AllocateShares() (r1 RKGShare, r2 RKGShare) {
-	r1 = make([][2]*ring.Poly, ekg.context.params.Beta())
-	r2 = make([][2]*ring.Poly, ekg.context.params.Beta())
+	r1.Round, r2.Round = RKGPhaseRoundOne, RKGPhaseRoundTwo
+	r1.Value = make([][2]*ring.Poly, ekg.context.params.Beta())
+	r2.Value = make([][2]*ring.Poly, ekg.context.params.Beta())
 	for i := uint64(0); i < ekg.context.params.Beta(); i++ {
-		r1[i][0] = ekg.context.ringQP.NewPoly()
-		r1[i][1] = ekg.context.ringQP.NewPoly()
-		r2[i][0] = ekg.context.ringQP.NewPoly()
-		r2[i][1] = ekg.context.ringQP.NewPoly()
+		r1.Value[i][0] = ekg.context.ringQP.NewPoly()
+		r1.Value[i][1] = ekg.context.ringQP.NewPoly()
+		r2.Value[i][0] = ekg.context.ringQP.NewPoly()
+		r2.Value[i][1] = ekg.context.ringQP.NewPoly()
 	}
 	return
 }
@@ -118,6 +61,11 @@ func NewEkgProtocol(params *ckks.Parameters) *RKGProtocol {
 	return ekg
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (ekg *RKGProtocol) SetLogger(logger utils.Logger) {
+	ekg.context.setLogger(logger)
+}
+
 // NewEphemeralKey generates a new Ephemeral Key u_i (needs to be stored for the 3 first rounds).
 // Each party is required to pre-compute a secret additional ephemeral key in addition to its share
 // of the collective secret-key.
@@ -132,7 +80,8 @@ func (ekg *RKGProtocol) NewEphemeralKey() (ephemeralKey *ring.Poly) {
 // j-1 parties.
 func (ekg *RKGProtocol) GenShareRoundOne(u, sk *ring.Poly, crp []*ring.Poly, shareOut RKGShare) {
 
-	var index uint64
+	ekg.context.logger.Debug("dckks: RKG round one share generation")
+
 	// Given a base decomposition w_i (here the CRT decomposition)
 	// computes [-u*a_i + P*s_i + e_i]
 	// where a_i = crp_i
@@ -147,35 +96,29 @@ func (ekg *RKGProtocol) GenShareRoundOne(u, sk *ring.Poly, crp []*ring.Poly, sha
 
 	for i := uint64(0); i < ekg.context.params.Beta(); i++ {
 		// h = e
-		ekg.gaussianSampler.Read(shareOut[i][0])
-		ringQP.NTT(shareOut[i][0], shareOut[i][0])
+		ekg.gaussianSampler.Read(shareOut.Value[i][0])
+		ringQP.NTT(shareOut.Value[i][0], shareOut.Value[i][0])
 
 		// h = sk*CrtBaseDecompQi + e
-		for j := uint64(0); j < ekg.context.params.Alpha(); j++ {
-
-			index = i*ekg.context.params.Alpha() + j
+		start, end := ekg.context.params.DecompositionDigit(i)
+		for index := start; index < end; index++ {
 			qi := ringQP.Modulus[index]
 			tmp0 := ekg.polypool.Coeffs[index]
-			tmp1 := shareOut[i][0].Coeffs[index]
+			tmp1 := shareOut.Value[i][0].Coeffs[index]
 
 			for w := uint64(0); w < ekg.context.ringQP.N; w++ {
 				tmp1[w] = ring.CRed(tmp1[w]+tmp0[w], qi)
 			}
-
-			// Handles the case where nb pj does not divides nb qi
-			if index == ekg.context.params.QiCount() {
-				break
-			}
 		}
 		// h = sk*CrtBaseDecompQi + -u*a + e
-		ekg.context.ringQP.MulCoeffsMontgomeryAndSub(u, crp[i], shareOut[i][0])
+		ekg.context.ringQP.MulCoeffsMontgomeryAndSub(u, crp[i], shareOut.Value[i][0])
 
 		// Second Element
 		// e_2i
-		ekg.gaussianSampler.Read(shareOut[i][1])
-		ringQP.NTT(shareOut[i][1], shareOut[i][1])
+		ekg.gaussianSampler.Read(shareOut.Value[i][1])
+		ringQP.NTT(shareOut.Value[i][1], shareOut.Value[i][1])
 		// s*a + e_2i
-		ringQP.MulCoeffsMontgomeryAndAdd(sk, crp[i], shareOut[i][1])
+		ringQP.MulCoeffsMontgomeryAndAdd(sk, crp[i], shareOut.Value[i][1])
 	}
 
 	ekg.polypool.Zero() // TODO: check if we can remove this one
@@ -187,8 +130,8 @@ func (ekg *RKGProtocol) GenShareRoundOne(u, sk *ring.Poly, crp []*ring.Poly, sha
 func (ekg *RKGProtocol) AggregateShareRoundOne(share1, share2, shareOut RKGShare) {
 
 	for i := uint64(0); i < ekg.context.params.Beta(); i++ {
-		ekg.context.ringQP.Add(share1[i][0], share2[i][0], shareOut[i][0])
-		ekg.context.ringQP.Add(share1[i][1], share2[i][1], shareOut[i][1])
+		ekg.context.ringQP.Add(share1.Value[i][0], share2.Value[i][0], shareOut.Value[i][0])
+		ekg.context.ringQP.Add(share1.Value[i][1], share2.Value[i][1], shareOut.Value[i][1])
 	}
 
 }
@@ -202,6 +145,8 @@ func (ekg *RKGProtocol) AggregateShareRoundOne(share1, share2, shareOut RKGShare
 // and broadcasts both values to the other j-1 parties.
 func (ekg *RKGProtocol) GenShareRoundTwo(round1 RKGShare, u, sk *ring.Poly, crp []*ring.Poly, shareOut RKGShare) {
 
+	ekg.context.logger.Debug("dckks: RKG round two share generation")
+
 	ringQP := ekg.context.ringQP
 
 	// (u_i - s_i)
@@ -214,18 +159,18 @@ func (ekg *RKGProtocol) GenShareRoundTwo(round1 RKGShare, u, sk *ring.Poly, crp
 		// Computes [(sum samples)*sk + e_1i, sk*a + e_2i]
 
 		// (AggregateShareRoundTwo samples) * sk
-		ringQP.MulCoeffsMontgomery(round1[i][0], sk, shareOut[i][0])
+		ringQP.MulCoeffsMontgomery(round1.Value[i][0], sk, shareOut.Value[i][0])
 
 		// (AggregateShareRoundTwo samples) * sk + e_1i
 		ekg.gaussianSampler.Read(ekg.tmpPoly2)
 		ringQP.NTT(ekg.tmpPoly2, ekg.tmpPoly2)
-		ringQP.Add(shareOut[i][0], ekg.tmpPoly2, shareOut[i][0])
+		ringQP.Add(shareOut.Value[i][0], ekg.tmpPoly2, shareOut.Value[i][0])
 
 		// second part
 		// (u - s) * (sum [x][s*a_i + e_2i]) + e3i
-		ekg.gaussianSampler.Read(shareOut[i][1])
-		ringQP.NTT(shareOut[i][1], shareOut[i][1])
-		ringQP.MulCoeffsMontgomeryAndAdd(ekg.tmpPoly1, round1[i][1], shareOut[i][1])
+		ekg.gaussianSampler.Read(shareOut.Value[i][1])
+		ringQP.NTT(shareOut.Value[i][1], shareOut.Value[i][1])
+		ringQP.MulCoeffsMontgomeryAndAdd(ekg.tmpPoly1, round1.Value[i][1], shareOut.Value[i][1])
 	}
 
 }
@@ -239,8 +184,8 @@ func (ekg *RKGProtocol) GenShareRoundTwo(round1 RKGShare, u, sk *ring.Poly, crp
 func (ekg *RKGProtocol) AggregateShareRoundTwo(share1, share2, shareOut RKGShare) {
 
 	for i := uint64(0); i < ekg.context.params.Beta(); i++ {
-		ekg.context.ringQP.Add(share1[i][0], share2[i][0], shareOut[i][0])
-		ekg.context.ringQP.Add(share1[i][1], share2[i][1], shareOut[i][1])
+		ekg.context.ringQP.Add(share1.Value[i][0], share2.Value[i][0], shareOut.Value[i][0])
+		ekg.context.ringQP.Add(share1.Value[i][1], share2.Value[i][1], shareOut.Value[i][1])
 	}
 }
 
@@ -252,8 +197,8 @@ func (ekg *RKGProtocol) GenRelinearizationKey(round1 RKGShare, round2 RKGShare,
 	key := evalKeyOut.Get().Get()
 	for i := uint64(0); i < ekg.context.params.Beta(); i++ {
 
-		ringQP.Add(round2[i][0], round2[i][1], key[i][0])
-		key[i][1].Copy(round1[i][1])
+		ringQP.Add(round2.Value[i][0], round2.Value[i][1], key[i][0])
+		key[i][1].Copy(round1.Value[i][1])
 
 		ringQP.MForm(key[i][0], key[i][0])
 		ringQP.MForm(key[i][1], key[i][1])
@@ -10,8 +10,8 @@ import (
 type RKGProtocolNaive struct {
 	dckksContext             *dckksContext
 	polypool                 *ring.Poly
-	gaussianSampler          *ring.GaussianSampler
-	ternarySamplerMontgomery *ring.TernarySampler
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
 }
 
 // NewRKGProtocolNaive creates a new RKGProtocolNaive object that will be used to generate a collective evaluation-key
@@ -32,25 +32,36 @@ func NewRKGProtocolNaive(params *ckks.Parameters) (rkg *RKGProtocolNaive) {
 	return
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (rkg *RKGProtocolNaive) SetLogger(logger utils.Logger) {
+	rkg.dckksContext.setLogger(logger)
+}
+
 // RKGNaiveShareRoundOne is a struct storing the round one share of the RKG naive protocol.
-type RKGNaiveShareRoundOne [][2]*ring.Poly
+type RKGNaiveShareRoundOne struct {
+	Value [][2]*ring.Poly
+	Party uint64
+}
 
 // RKGNaiveShareRoundTwo is a struct storing the round two share of the RKG naive protocol.
-type RKGNaiveShareRoundTwo [][2]*ring.Poly
+type RKGNaiveShareRoundTwo struct {
+	Value [][2]*ring.Poly
+	Party uint64
+}
 
 // AllocateShares allocates the share of the RKG naive protocol.
 func (rkg *RKGProtocolNaive) AllocateShares() (r1 RKGNaiveShareRoundOne, r2 RKGNaiveShareRoundTwo) {
 	ringQP := rkg.dckksContext.ringQP
 
-	r1 = make([][2]*ring.Poly, rkg.dckksContext.beta)
-	r2 = make([][2]*ring.Poly, rkg.dckksContext.beta)
+	r1.Value = make([][2]*ring.Poly, rkg.dckksContext.beta)
+	r2.Value = make([][2]*ring.Poly, rkg.dckksContext.beta)
 
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
-		r1[i][0] = ringQP.NewPoly()
-		r1[i][1] = ringQP.NewPoly()
+		r1.Value[i][0] = ringQP.NewPoly()
+		r1.Value[i][1] = ringQP.NewPoly()
 
-		r2[i][0] = ringQP.NewPoly()
-		r2[i][1] = ringQP.NewPoly()
+		r2.Value[i][0] = ringQP.NewPoly()
+		r2.Value[i][1] = ringQP.NewPoly()
 	}
 
 	return
@@ -64,6 +75,8 @@ func (rkg *RKGProtocolNaive) AllocateShares() (r1 RKGNaiveShareRoundOne, r2 RKGN
 // and broadcasts it to all other j-1 parties.
 func (rkg *RKGProtocolNaive) GenShareRoundOne(sk *ring.Poly, pk [2]*ring.Poly, shareOut RKGNaiveShareRoundOne) {
 
+	rkg.dckksContext.logger.Debug("dckks: naive RKG round one share generation")
+
 	ringQP := rkg.dckksContext.ringQP
 
 	rkg.polypool.Copy(sk)
@@ -72,36 +85,28 @@ func (rkg *RKGProtocolNaive) GenShareRoundOne(sk *ring.Poly, pk [2]*ring.Poly, s
 
 	ringQP.InvMForm(rkg.polypool, rkg.polypool)
 
-	var index uint64
-
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
 
 		// h_0 = e0
-		rkg.gaussianSampler.Read(shareOut[i][0])
-		ringQP.NTT(shareOut[i][0], shareOut[i][0])
+		rkg.gaussianSampler.Read(shareOut.Value[i][0])
+		ringQP.NTT(shareOut.Value[i][0], shareOut.Value[i][0])
 
 		// h_1 = e1
-		rkg.gaussianSampler.Read(shareOut[i][1])
-		ringQP.NTT(shareOut[i][1], shareOut[i][1])
+		rkg.gaussianSampler.Read(shareOut.Value[i][1])
+		ringQP.NTT(shareOut.Value[i][1], shareOut.Value[i][1])
 
 		// h_0 = e0 + [sk*P*(qiBarre*qiStar)%qi = sk*P, else 0]
 
-		for j := uint64(0); j < rkg.dckksContext.alpha; j++ {
-
-			index = i*rkg.dckksContext.alpha + j
+		start, end := rkg.dckksContext.params.DecompositionDigit(i)
+		for index := start; index < end; index++ {
 
 			qi := ringQP.Modulus[index]
 			tmp0 := rkg.polypool.Coeffs[index]
-			tmp1 := shareOut[i][0].Coeffs[index]
+			tmp1 := shareOut.Value[i][0].Coeffs[index]
 
 			for w := uint64(0); w < ringQP.N; w++ {
 				tmp1[w] = ring.CRed(tmp1[w]+tmp0[w], qi)
 			}
-
-			// Handles the case where nb pj does not divides nb qi
-			if index >= rkg.dckksContext.params.QiCount() {
-				break
-			}
 		}
 	}
 
@@ -110,9 +115,9 @@ func (rkg *RKGProtocolNaive) GenShareRoundOne(sk *ring.Poly, pk [2]*ring.Poly, s
 		rkg.ternarySamplerMontgomery.Read(rkg.polypool)
 		ringQP.NTT(rkg.polypool, rkg.polypool)
 		// h_0 = pk_0 * u + e0 + P * sk * (qiBarre*qiStar)%qi
-		ringQP.MulCoeffsMontgomeryAndAdd(pk[0], rkg.polypool, shareOut[i][0])
+		ringQP.MulCoeffsMontgomeryAndAdd(pk[0], rkg.polypool, shareOut.Value[i][0])
 		// h_1 = pk_1 * u + e1 + P * sk * (qiBarre*qiStar)%qi
-		ringQP.MulCoeffsMontgomeryAndAdd(pk[1], rkg.polypool, shareOut[i][1])
+		ringQP.MulCoeffsMontgomeryAndAdd(pk[1], rkg.polypool, shareOut.Value[i][1])
 	}
 
 	rkg.polypool.Zero()
@@ -128,8 +133,8 @@ func (rkg *RKGProtocolNaive) AggregateShareRoundOne(share1, share2, shareOut RKG
 	ringQP := rkg.dckksContext.ringQP
 
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
-		ringQP.Add(share1[i][0], share2[i][0], shareOut[i][0])
-		ringQP.Add(share1[i][1], share2[i][1], shareOut[i][1])
+		ringQP.Add(share1.Value[i][0], share2.Value[i][0], shareOut.Value[i][0])
+		ringQP.Add(share1.Value[i][1], share2.Value[i][1], shareOut.Value[i][1])
 	}
 
 }
@@ -143,34 +148,36 @@ func (rkg *RKGProtocolNaive) AggregateShareRoundOne(share1, share2, shareOut RKG
 // And each party broadcasts this last result to the other j-1 parties.
 func (rkg *RKGProtocolNaive) GenShareRoundTwo(round1 RKGNaiveShareRoundOne, sk *ring.Poly, pk [2]*ring.Poly, shareOut RKGNaiveShareRoundTwo) {
 
+	rkg.dckksContext.logger.Debug("dckks: naive RKG round two share generation")
+
 	ringQP := rkg.dckksContext.ringQP
 
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
 
 		// h_0 = sum(samples[0]) * sk
 		// h_1 = sum(samples[1]) * sk
-		ringQP.MulCoeffsMontgomery(round1[i][0], sk, shareOut[i][0])
-		ringQP.MulCoeffsMontgomery(round1[i][1], sk, shareOut[i][1])
+		ringQP.MulCoeffsMontgomery(round1.Value[i][0], sk, shareOut.Value[i][0])
+		ringQP.MulCoeffsMontgomery(round1.Value[i][1], sk, shareOut.Value[i][1])
 
 		// v
 		rkg.ternarySamplerMontgomery.Read(rkg.polypool)
 		ringQP.NTT(rkg.polypool, rkg.polypool)
 
 		// h_0 = sum(samples[0]) * sk + pk0 * v
-		ringQP.MulCoeffsMontgomeryAndAdd(pk[0], rkg.polypool, shareOut[i][0])
+		ringQP.MulCoeffsMontgomeryAndAdd(pk[0], rkg.polypool, shareOut.Value[i][0])
 
 		// h_1 = sum(samples[1]) * sk + pk1 * v
-		ringQP.MulCoeffsMontgomeryAndAdd(pk[1], rkg.polypool, shareOut[i][1])
+		ringQP.MulCoeffsMontgomeryAndAdd(pk[1], rkg.polypool, shareOut.Value[i][1])
 
 		// h_0 = sum(samples[0]) * sk + pk0 * v + e2
 		rkg.gaussianSampler.Read(rkg.polypool)
 		ringQP.NTT(rkg.polypool, rkg.polypool)
-		ringQP.Add(shareOut[i][0], rkg.polypool, shareOut[i][0])
+		ringQP.Add(shareOut.Value[i][0], rkg.polypool, shareOut.Value[i][0])
 
 		// h_1 = sum(samples[1]) * sk + pk1 * v + e3
 		rkg.gaussianSampler.Read(rkg.polypool)
 		ringQP.NTT(rkg.polypool, rkg.polypool)
-		ringQP.Add(shareOut[i][1], rkg.polypool, shareOut[i][1])
+		ringQP.Add(shareOut.Value[i][1], rkg.polypool, shareOut.Value[i][1])
 
 	}
 
@@ -190,8 +197,8 @@ func (rkg *RKGProtocolNaive) AggregateShareRoundTwo(share1, share2, shareOut RKG
 	ringQP := rkg.dckksContext.ringQP
 
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
-		ringQP.Add(share1[i][0], share2[i][0], shareOut[i][0])
-		ringQP.Add(share1[i][1], share2[i][1], shareOut[i][1])
+		ringQP.Add(share1.Value[i][0], share2.Value[i][0], shareOut.Value[i][0])
+		ringQP.Add(share1.Value[i][1], share2.Value[i][1], shareOut.Value[i][1])
 	}
 }
 
@@ -203,8 +210,8 @@ func (rkg *RKGProtocolNaive) GenRelinearizationKey(round2 RKGNaiveShareRoundTwo,
 	key := evalKeyOut.Get().Get()
 	for i := uint64(0); i < rkg.dckksContext.beta; i++ {
 
-		key[i][0].Copy(round2[i][0])
-		key[i][1].Copy(round2[i][1])
+		key[i][0].Copy(round2.Value[i][0])
+		key[i][1].Copy(round2.Value[i][1])
 
 		ringQP.MForm(key[i][0], key[i][0])
 		ringQP.MForm(key[i][1], key[i][1])
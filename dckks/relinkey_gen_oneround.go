@@ -0,0 +1,220 @@
+package dckks
+
+import (
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// RKGProtocolOneRound is the structure storing the parameters and state for a party in the one-round relinearization
+// key generation protocol. Once the parties agree on the common reference polynomials, each of them sends a single
+// share, computed from its secret key share alone, and the aggregate of the shares is the relinearization key: no
+// share depends on the shares of the other parties.
+//
+// The key it generates is not a ckks.EvaluationKey: it encrypts s^2 as the pair of switching keys of a
+// OneRoundRelinearizationKey, with which a OneRoundRelinearizer relinearizes at the cost of two key-switchings
+// instead of one. Applications which relinearize often should rather run the two rounds of the RKGProtocol.
+type RKGProtocolOneRound struct {
+	context                  *dckksContext
+	polypool                 *ring.Poly
+	gaussianSampler          ring.Sampler
+	ternarySamplerMontgomery ring.Sampler
+}
+
+// RKGOneRoundShare is the share of a party in the RKGProtocolOneRound, made of three polynomials of QP per
+// decomposition digit w_i:
+//
+// [-s_i*a + e_0i, -s_i*a' + P*w_i*r_i + e_1i, r_i*a + P*w_i*s_i + e_2i]
+//
+// where r_i is a fresh ternary polynomial of the party and (a, a') are the common reference polynomials.
+type RKGOneRoundShare struct {
+	Value [][3]*ring.Poly
+	Party uint64
+}
+
+// OneRoundRelinearizationKey is the relinearization key output by the RKGProtocolOneRound. For r the sum of the
+// ephemeral keys of the parties, its first switching key turns c2 into a pair (c', v) decrypting to c2*s^2 under
+// (r, s), and its second one switches c' from r to s.
+type OneRoundRelinearizationKey struct {
+	keys [2]*ckks.SwitchingKey
+}
+
+// NewOneRoundRelinearizationKey returns a new OneRoundRelinearizationKey with zero values.
+func NewOneRoundRelinearizationKey(params *ckks.Parameters) *OneRoundRelinearizationKey {
+	return &OneRoundRelinearizationKey{keys: [2]*ckks.SwitchingKey{ckks.NewSwitchingKey(params), ckks.NewSwitchingKey(params)}}
+}
+
+// NewRKGProtocolOneRound creates a new RKGProtocolOneRound object that will be used to generate a collective
+// relinearization key among j parties in a single round.
+func NewRKGProtocolOneRound(params *ckks.Parameters) (rkg *RKGProtocolOneRound) {
+
+	rkg = new(RKGProtocolOneRound)
+	rkg.context = newDckksContext(params)
+	rkg.polypool = rkg.context.ringQP.NewPoly()
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+	rkg.gaussianSampler = ring.NewGaussianSampler(prng, rkg.context.ringQP, params.Sigma(), uint64(6*params.Sigma()))
+	rkg.ternarySamplerMontgomery = ring.NewTernarySampler(prng, rkg.context.ringQP, 0.5, true)
+
+	return
+}
+
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (rkg *RKGProtocolOneRound) SetLogger(logger utils.Logger) {
+	rkg.context.setLogger(logger)
+}
+
+// AllocateShare allocates the share of the RKGProtocolOneRound.
+func (rkg *RKGProtocolOneRound) AllocateShare() (share RKGOneRoundShare) {
+	share.Value = make([][3]*ring.Poly, rkg.context.beta)
+	for i := range share.Value {
+		for j := range share.Value[i] {
+			share.Value[i][j] = rkg.context.ringQP.NewPoly()
+		}
+	}
+	return
+}
+
+// GenShare is the single round of the RKGProtocolOneRound. Each party samples a fresh ephemeral key r_i and
+// broadcasts to the other j-1 parties the share
+//
+// [-s_i*a + e_0i, -s_i*a' + P*w_i*r_i + e_1i, r_i*a + P*w_i*s_i + e_2i]
+//
+// on the common reference polynomials crp = (a, a').
+func (rkg *RKGProtocolOneRound) GenShare(sk *ring.Poly, crp [2][]*ring.Poly, shareOut RKGOneRoundShare) {
+
+	rkg.context.logger.Debug("dckks: one-round RKG share generation")
+
+	ringQP := rkg.context.ringQP
+
+	r := rkg.ternarySamplerMontgomery.ReadNew()
+	ringQP.NTT(r, r)
+
+	for i := uint64(0); i < rkg.context.beta; i++ {
+		for j := range shareOut.Value[i] {
+			rkg.gaussianSampler.Read(shareOut.Value[i][j])
+			ringQP.NTT(shareOut.Value[i][j], shareOut.Value[i][j])
+		}
+	}
+
+	// e_1i + P*w_i*r_i and e_2i + P*w_i*s_i
+	rkg.addGadgetProduct(r, shareOut, 1)
+	rkg.addGadgetProduct(sk, shareOut, 2)
+
+	for i := uint64(0); i < rkg.context.beta; i++ {
+		// -s_i*a + e_0i
+		ringQP.MulCoeffsMontgomeryAndSub(sk, crp[0][i], shareOut.Value[i][0])
+		// -s_i*a' + P*w_i*r_i + e_1i
+		ringQP.MulCoeffsMontgomeryAndSub(sk, crp[1][i], shareOut.Value[i][1])
+		// r_i*a + P*w_i*s_i + e_2i
+		ringQP.MulCoeffsMontgomeryAndAdd(r, crp[0][i], shareOut.Value[i][2])
+	}
+
+	rkg.polypool.Zero()
+}
+
+// addGadgetProduct adds P*w_i*p to the polynomial of index j of each digit i of shareOut, for p in the Montgomery form.
+func (rkg *RKGProtocolOneRound) addGadgetProduct(p *ring.Poly, shareOut RKGOneRoundShare, j int) {
+
+	ringQP := rkg.context.ringQP
+
+	ringQP.MulScalarBigint(p, rkg.context.ringP.ModulusBigint, rkg.polypool)
+	ringQP.InvMForm(rkg.polypool, rkg.polypool)
+
+	for i := uint64(0); i < rkg.context.beta; i++ {
+		start, end := rkg.context.params.DecompositionDigit(i)
+		for index := start; index < end; index++ {
+			qi := ringQP.Modulus[index]
+			tmp0 := rkg.polypool.Coeffs[index]
+			tmp1 := shareOut.Value[i][j].Coeffs[index]
+
+			for w := uint64(0); w < ringQP.N; w++ {
+				tmp1[w] = ring.CRed(tmp1[w]+tmp0[w], qi)
+			}
+		}
+	}
+}
+
+// AggregateShares adds share1 and share2 on shareOut.
+func (rkg *RKGProtocolOneRound) AggregateShares(share1, share2, shareOut RKGOneRoundShare) {
+	for i := uint64(0); i < rkg.context.beta; i++ {
+		for j := range shareOut.Value[i] {
+			rkg.context.ringQP.Add(share1.Value[i][j], share2.Value[i][j], shareOut.Value[i][j])
+		}
+	}
+}
+
+// GenRelinearizationKey finalizes the protocol from the aggregate of the shares of all the parties and the common
+// reference polynomials, and returns the collective relinearization key in keyOut.
+func (rkg *RKGProtocolOneRound) GenRelinearizationKey(aggregate RKGOneRoundShare, crp [2][]*ring.Poly, keyOut *OneRoundRelinearizationKey) {
+
+	ringQP := rkg.context.ringQP
+
+	key0, key1 := keyOut.keys[0].Get(), keyOut.keys[1].Get()
+	for i := uint64(0); i < rkg.context.beta; i++ {
+
+		// [-s*a + e_0, r*a + P*w_i*s + e_2]
+		ringQP.MForm(aggregate.Value[i][0], key0[i][0])
+		ringQP.MForm(aggregate.Value[i][2], key0[i][1])
+
+		// [-s*a' + P*w_i*r + e_1, a']
+		ringQP.MForm(aggregate.Value[i][1], key1[i][0])
+		ringQP.MForm(crp[1][i], key1[i][1])
+	}
+}
+
+// OneRoundRelinearizer relinearizes the ciphertexts of degree two with a OneRoundRelinearizationKey.
+type OneRoundRelinearizer struct {
+	params    *ckks.Parameters
+	ringQ     *ring.Ring
+	evaluator ckks.Evaluator
+}
+
+// NewOneRoundRelinearizer creates a new OneRoundRelinearizer.
+func NewOneRoundRelinearizer(params *ckks.Parameters) *OneRoundRelinearizer {
+	return &OneRoundRelinearizer{
+		params:    params.Copy(),
+		ringQ:     newDckksContext(params).ringQ,
+		evaluator: ckks.NewEvaluator(params),
+	}
+}
+
+// Relinearize relinearizes the ciphertext of degree two ct0 with the key and returns the result in ctOut. For
+// ct0 = (c0, c1, c2), it first switches c2 to (c', v) with v*s + c'*r = c2*s^2, and then c' from r to s.
+func (rl *OneRoundRelinearizer) Relinearize(ct0 *ckks.Ciphertext, key *OneRoundRelinearizationKey, ctOut *ckks.Ciphertext) {
+
+	if ct0.Degree() != 2 {
+		panic("cannot Relinearize: input Ciphertext must be of degree 2")
+	}
+
+	level := utils.MinUint64(ct0.Level(), ctOut.Level())
+	ringQ := rl.ringQ
+
+	ctTmp := ckks.NewCiphertext(rl.params, 1, level, ct0.Scale())
+	ctU := ckks.NewCiphertext(rl.params, 1, level, ct0.Scale())
+	ctV := ckks.NewCiphertext(rl.params, 1, level, ct0.Scale())
+
+	// (c', v) = SwitchKeys((0, c2), key_0)
+	ringQ.CopyLvl(level, ct0.Value()[2], ctTmp.Value()[1])
+	rl.evaluator.SwitchKeys(ctTmp, key.keys[0], ctV)
+
+	// (u_0, u_1) = SwitchKeys((0, c'), key_1)
+	ringQ.CopyLvl(level, ctV.Value()[0], ctTmp.Value()[1])
+	rl.evaluator.SwitchKeys(ctTmp, key.keys[1], ctU)
+
+	ringQ.AddLvl(level, ct0.Value()[0], ctU.Value()[0], ctOut.Value()[0])
+	ringQ.AddLvl(level, ct0.Value()[1], ctU.Value()[1], ctOut.Value()[1])
+	ringQ.AddLvl(level, ctOut.Value()[1], ctV.Value()[1], ctOut.Value()[1])
+
+	if fp, bound := ct0.KeyFingerprint(); bound {
+		ctOut.SetKeyFingerprint(fp)
+	} else {
+		ctOut.Unbind()
+	}
+
+	ctOut.SetScale(ct0.Scale())
+	ctOut.Resize(rl.params, 1)
+}
@@ -0,0 +1,273 @@
+package dckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// The RKGProtocol already is the compressed variant of the relinearization key generation: its first round combines
+// the pseudo-encryption of s_i under u_i and the share of s*a on the same common reference polynomials, so that the
+// protocol has two rounds instead of three. It cannot have a single round since the shares of the second round are
+// computed on the aggregate of the first one: the RKGProtocolOneRound has a single round, but generates a key of
+// another form, which costs two key-switchings per relinearization. An RKGSession runs the two rounds for one party
+// as a state machine whose state can be marshaled between the rounds, e.g. to be stored while the party waits for the
+// shares of the others in an asynchronous deployment.
+
+// RKGPhase is the phase of an RKGSession.
+type RKGPhase uint8
+
+const (
+	// RKGPhaseRoundOne is the phase in which the session generates and aggregates the shares of the first round.
+	RKGPhaseRoundOne RKGPhase = iota
+	// RKGPhaseRoundTwo is the phase in which the session generates and aggregates the shares of the second round.
+	RKGPhaseRoundTwo
+	// RKGPhaseDone is the phase in which the session has aggregated the shares of both rounds and can output the
+	// relinearization key.
+	RKGPhaseDone
+)
+
+func (phase RKGPhase) String() string {
+	switch phase {
+	case RKGPhaseRoundOne:
+		return "RoundOne"
+	case RKGPhaseRoundTwo:
+		return "RoundTwo"
+	case RKGPhaseDone:
+		return "Done"
+	default:
+		return fmt.Sprintf("RKGPhase(%d)", uint8(phase))
+	}
+}
+
+// RKGSession is the state of a party in a run of the RKGProtocol between nParties parties: its ephemeral key, the
+// aggregates of the shares of the current and past rounds and the parties whose share of the current round it has
+// aggregated, identified by the Party field of the shares. The secret key share of the party is not part of the state.
+type RKGSession struct {
+	ekg *RKGProtocol
+	crp []*ring.Poly
+
+	party     uint64
+	nParties  uint64
+	phase     RKGPhase
+	generated bool
+	received  map[uint64]bool
+
+	u      *ring.Poly
+	round1 RKGShare
+	round2 RKGShare
+}
+
+// NewSession creates a new RKGSession for the party among nParties parties, with the common reference polynomials crp.
+func (ekg *RKGProtocol) NewSession(party, nParties uint64, crp []*ring.Poly) *RKGSession {
+
+	if nParties == 0 {
+		panic("cannot NewSession: nParties must be at least 1")
+	}
+
+	session := ekg.newSession(crp)
+	session.party = party
+	session.nParties = nParties
+	session.u = ekg.NewEphemeralKey()
+
+	return session
+}
+
+func (ekg *RKGProtocol) newSession(crp []*ring.Poly) *RKGSession {
+
+	if uint64(len(crp)) != ekg.context.params.Beta() {
+		panic(fmt.Sprintf("cannot create RKGSession: %d crp for %d decomposition digits", len(crp), ekg.context.params.Beta()))
+	}
+
+	session := new(RKGSession)
+	session.ekg = ekg
+	session.crp = crp
+	session.received = make(map[uint64]bool)
+	session.round1, session.round2 = ekg.AllocateShares()
+
+	return session
+}
+
+// Phase returns the phase of the session.
+func (session *RKGSession) Phase() RKGPhase {
+	return session.phase
+}
+
+// GenShare generates the share of the party for the round of the current phase, aggregates it in the session and
+// returns it to be sent to the other parties. It returns an error if the party already generated its share for the
+// round or if the session is done.
+func (session *RKGSession) GenShare(sk *ring.Poly) (share RKGShare, err error) {
+
+	if session.phase == RKGPhaseDone {
+		return share, errors.New("cannot GenShare: the session is done")
+	}
+
+	if session.generated {
+		return share, fmt.Errorf("cannot GenShare: share already generated for phase %s", session.phase)
+	}
+
+	shareRoundOne, shareRoundTwo := session.ekg.AllocateShares()
+
+	if session.phase == RKGPhaseRoundOne {
+		share = shareRoundOne
+		session.ekg.GenShareRoundOne(session.u, sk, session.crp, share)
+	} else {
+		share = shareRoundTwo
+		session.ekg.GenShareRoundTwo(session.round1, session.u, sk, session.crp, share)
+	}
+
+	share.Party = session.party
+
+	session.generated = true
+
+	return share, session.aggregate(share)
+}
+
+// AddShare aggregates in the session the share of another party for the round of the current phase, and moves to
+// the next phase once the shares of all the parties are aggregated. It returns an error if the share is of another
+// round, if the session already aggregated a share of this party for the round or if the session is done: a share
+// of the second round received before the end of the first one must be kept by the caller until then.
+func (session *RKGSession) AddShare(share RKGShare) (err error) {
+
+	if session.phase == RKGPhaseDone {
+		return errors.New("cannot AddShare: the session is done")
+	}
+
+	if share.Round != session.phase {
+		return fmt.Errorf("cannot AddShare: share of round %s in phase %s", share.Round, session.phase)
+	}
+
+	if share.Party == session.party {
+		return errors.New("cannot AddShare: the share of the party is added by GenShare")
+	}
+
+	if err = session.ekg.ValidateShare(share); err != nil {
+		return fmt.Errorf("cannot AddShare: %s", err)
+	}
+
+	return session.aggregate(share)
+}
+
+func (session *RKGSession) aggregate(share RKGShare) error {
+
+	if session.received[share.Party] {
+		return fmt.Errorf("share of party %d already aggregated for phase %s", share.Party, session.phase)
+	}
+
+	if uint64(len(session.received)) == session.nParties-1 && !session.received[session.party] && share.Party != session.party {
+		return fmt.Errorf("share of party %d exceeds the %d parties of phase %s", share.Party, session.nParties, session.phase)
+	}
+
+	if session.phase == RKGPhaseRoundOne {
+		session.ekg.AggregateShareRoundOne(share, session.round1, session.round1)
+	} else {
+		session.ekg.AggregateShareRoundTwo(share, session.round2, session.round2)
+	}
+
+	session.received[share.Party] = true
+
+	if uint64(len(session.received)) == session.nParties {
+		session.phase++
+		session.generated = false
+		session.received = make(map[uint64]bool)
+	}
+
+	return nil
+}
+
+// RelinearizationKey writes the relinearization key on evalKeyOut. It returns an error if the session is not done.
+func (session *RKGSession) RelinearizationKey(evalKeyOut *ckks.EvaluationKey) error {
+
+	if session.phase != RKGPhaseDone {
+		return fmt.Errorf("cannot RelinearizationKey: the session is in phase %s", session.phase)
+	}
+
+	session.ekg.GenRelinearizationKey(session.round1, session.round2, evalKeyOut)
+
+	return nil
+}
+
+// rkgSessionMetaLen is the length of the fixed part of the metadata of a marshaled RKGSession: number of parties,
+// phase, generated flag and number of received shares, followed by the parties of the received shares.
+const rkgSessionMetaLen = 8 + 1 + 1 + 8
+
+// MarshalBinary encodes the state of the session on a slice of bytes, with the format of the shares. The encoding
+// contains the ephemeral key of the party and must be stored as securely as its secret key share.
+func (session *RKGSession) MarshalBinary() (data []byte, err error) {
+
+	meta := make([]byte, rkgSessionMetaLen+8*len(session.received))
+	binary.BigEndian.PutUint64(meta[0:8], session.nParties)
+	meta[8] = uint8(session.phase)
+	if session.generated {
+		meta[9] = 1
+	}
+	binary.BigEndian.PutUint64(meta[10:18], uint64(len(session.received)))
+
+	received := make([]uint64, 0, len(session.received))
+	for party := range session.received {
+		received = append(received, party)
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i] < received[j] })
+
+	for i, party := range received {
+		binary.BigEndian.PutUint64(meta[rkgSessionMetaLen+8*i:], party)
+	}
+
+	polys := []*ring.Poly{session.u}
+	for i := range session.round1.Value {
+		polys = append(polys, session.round1.Value[i][0], session.round1.Value[i][1])
+		polys = append(polys, session.round2.Value[i][0], session.round2.Value[i][1])
+	}
+
+	return marshalShare(shareTypeRKGSession, session.party, meta, polys...)
+}
+
+// LoadSession decodes a slice of bytes generated by RKGSession.MarshalBinary into a new RKGSession of the protocol,
+// with the common reference polynomials crp of the run.
+func (ekg *RKGProtocol) LoadSession(crp []*ring.Poly, data []byte) (session *RKGSession, err error) {
+
+	if uint64(len(data)) < shareHeaderLen+rkgSessionMetaLen {
+		return nil, errors.New("cannot LoadSession: data is too short")
+	}
+
+	nReceived := binary.BigEndian.Uint64(data[shareHeaderLen+10 : shareHeaderLen+18])
+	if nReceived > uint64(len(data))/8 {
+		return nil, errors.New("cannot LoadSession: invalid number of received shares")
+	}
+
+	party, meta, polys, err := unmarshalShare(shareTypeRKGSession, rkgSessionMetaLen+8*nReceived, data)
+	if err != nil {
+		return nil, err
+	}
+
+	beta := ekg.context.params.Beta()
+	if uint64(len(polys)) != 1+4*beta || validatePolyLvl(ekg.context.ringQP, uint64(len(ekg.context.ringQP.Modulus)-1), polys[0]) != nil {
+		return nil, errors.New("cannot LoadSession: invalid polynomials")
+	}
+
+	session = ekg.newSession(crp)
+	session.party = party
+	session.nParties = binary.BigEndian.Uint64(meta[0:8])
+	session.phase = RKGPhase(meta[8])
+	session.generated = meta[9] == 1
+
+	if session.nParties == 0 || session.phase > RKGPhaseDone || nReceived >= session.nParties {
+		return nil, errors.New("cannot LoadSession: invalid state")
+	}
+
+	for i := uint64(0); i < nReceived; i++ {
+		session.received[binary.BigEndian.Uint64(meta[rkgSessionMetaLen+8*i:])] = true
+	}
+
+	session.u = polys[0]
+	for i := uint64(0); i < beta; i++ {
+		session.round1.Value[i] = [2]*ring.Poly{polys[1+4*i], polys[2+4*i]}
+		session.round2.Value[i] = [2]*ring.Poly{polys[3+4*i], polys[4+4*i]}
+	}
+
+	return session, nil
+}
@@ -0,0 +1,160 @@
+package dckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// RTGBatchShare is the share of a party for the keys of a list of Galois elements, generated in a single round of the
+// RTG protocol, e.g. the rotation keys of a bootstrapping. It holds one RTGShare per Galois element, in the order of
+// the list.
+type RTGBatchShare struct {
+	Shares []RTGShare
+	Party  uint64
+}
+
+// AllocateBatchShare allocates the share of the RTG protocol for a batch of nKeys Galois elements.
+func (rtg *RTGProtocol) AllocateBatchShare(nKeys int) (share RTGBatchShare) {
+	share.Shares = make([]RTGShare, nKeys)
+	for i := range share.Shares {
+		share.Shares[i] = rtg.AllocateShare()
+	}
+	return
+}
+
+// GenBatchShare generates the shares of the keys of the automorphisms X -> X^galEl for all the galEl of galEls in a
+// single round, crp[i] being the common reference polynomials of galEls[i], e.g. as returned by CRPGenerator.RTGBatch.
+// The product of the secret key share by P is computed once for all the keys. The collective keys are finalized with
+// FinalizeBatch.
+func (rtg *RTGProtocol) GenBatchShare(galEls []uint64, sk *ring.Poly, crp [][]*ring.Poly, shareOut *RTGBatchShare) {
+
+	rtg.dckksContext.logger.Debug("dckks: RTG batch share generation", "keys", len(galEls))
+
+	if len(crp) != len(galEls) || len(shareOut.Shares) != len(galEls) {
+		panic("cannot GenBatchShare: galEls, crp and shareOut must have the same length")
+	}
+
+	mask := (rtg.dckksContext.n << 1) - 1
+
+	for _, galEl := range galEls {
+		if galEl&1 == 0 {
+			panic("cannot GenBatchShare: Galois elements must be odd")
+		}
+	}
+
+	rtg.dckksContext.ringQP.MulScalarBigint(sk, rtg.dckksContext.ringP.ModulusBigint, rtg.tmpPoly[0])
+
+	for i, galEl := range galEls {
+		share := &shareOut.Shares[i]
+		share.Type = 0
+		share.K = 0
+		share.GaloisElement = galEl & mask
+		share.Party = shareOut.Party
+		rtg.genShareScaled(sk, rtg.dckksContext.params.InverseGaloisElement(galEl&mask), crp[i], share.Value)
+	}
+
+	rtg.tmpPoly[0].Zero()
+}
+
+// AggregateBatch adds the shares of share1 and share2 key by key on shareOut. The batches must be for the same list of
+// Galois elements.
+func (rtg *RTGProtocol) AggregateBatch(share1, share2, shareOut RTGBatchShare) {
+
+	if len(share1.Shares) != len(share2.Shares) || len(share1.Shares) != len(shareOut.Shares) {
+		panic("cannot AggregateBatch: batches of different lengths")
+	}
+
+	for i := range share1.Shares {
+		rtg.Aggregate(share1.Shares[i], share2.Shares[i], shareOut.Shares[i])
+	}
+}
+
+// FinalizeBatch finalizes the keys of the aggregated batch share into the automorphism keys of rotKey, crp being the
+// common reference polynomials of GenBatchShare.
+func (rtg *RTGProtocol) FinalizeBatch(params *ckks.Parameters, share RTGBatchShare, crp [][]*ring.Poly, rotKey *ckks.RotationKeys) {
+
+	if len(crp) != len(share.Shares) {
+		panic("cannot FinalizeBatch: share and crp must have the same length")
+	}
+
+	for i := range share.Shares {
+		rtg.Finalize(params, share.Shares[i], crp[i], rotKey)
+	}
+}
+
+// MarshalBinary encodes the batch share on a slice of bytes, as the number of keys followed by the length and the
+// encoding of the RTGShare of each key with the Party of the batch.
+func (share *RTGBatchShare) MarshalBinary() (data []byte, err error) {
+
+	data = make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(len(share.Shares)))
+
+	var tmp [8]byte
+
+	for i := range share.Shares {
+
+		keyShare := share.Shares[i]
+		keyShare.Party = share.Party
+
+		keyData, err := keyShare.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		binary.BigEndian.PutUint64(tmp[:], uint64(len(keyData)))
+		data = append(data, tmp[:]...)
+		data = append(data, keyData...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes generated by MarshalBinary on the batch share.
+func (share *RTGBatchShare) UnmarshalBinary(data []byte) (err error) {
+
+	if len(data) < 4 {
+		return errors.New("cannot unmarshal RTGBatchShare: data is too short")
+	}
+
+	nKeys := uint64(binary.BigEndian.Uint32(data))
+	data = data[4:]
+
+	if nKeys > uint64(len(data))/8 {
+		return errors.New("cannot unmarshal RTGBatchShare: invalid number of keys")
+	}
+
+	share.Shares = make([]RTGShare, nKeys)
+
+	for i := range share.Shares {
+
+		if len(data) < 8 || binary.BigEndian.Uint64(data) > uint64(len(data)-8) {
+			return errors.New("cannot unmarshal RTGBatchShare: data is too short")
+		}
+
+		keyLen := binary.BigEndian.Uint64(data)
+
+		if err = share.Shares[i].UnmarshalBinary(data[8 : 8+keyLen]); err != nil {
+			return fmt.Errorf("cannot unmarshal RTGBatchShare: key %d: %s", i, err)
+		}
+
+		if i > 0 && share.Shares[i].Party != share.Shares[0].Party {
+			return errors.New("cannot unmarshal RTGBatchShare: keys of different parties")
+		}
+
+		data = data[8+keyLen:]
+	}
+
+	if len(data) != 0 {
+		return errors.New("cannot unmarshal RTGBatchShare: invalid length")
+	}
+
+	if nKeys > 0 {
+		share.Party = share.Shares[0].Party
+	}
+
+	return nil
+}
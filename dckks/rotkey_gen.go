@@ -15,14 +15,18 @@ type RTGProtocol struct {
 
 	tmpSwitchKey    [][2]*ring.Poly
 	tmpPoly         [2]*ring.Poly
-	gaussianSampler *ring.GaussianSampler
+	gaussianSampler ring.Sampler
 }
 
-// RTGShare is a struct storing the share of the RTG protocol.
+// RTGShare is a struct storing the share of the RTG protocol. The share of the key of a rotation or of the conjugation
+// is identified by its Type and K, and the share of the key of an arbitrary automorphism, whose Type is zero, by its
+// GaloisElement.
 type RTGShare struct {
-	Type  ckks.Rotation
-	K     uint64
-	Value []*ring.Poly
+	Type          ckks.Rotation
+	K             uint64
+	GaloisElement uint64
+	Value         []*ring.Poly
+	Party         uint64
 }
 
 // AllocateShare allocates the share the the RTG protocol.
@@ -76,6 +80,11 @@ func NewRotKGProtocol(params *ckks.Parameters) (rtg *RTGProtocol) {
 	return rtg
 }
 
+// SetLogger sets the Logger on which the protocol reports its events. The protocol logs nothing by default.
+func (rtg *RTGProtocol) SetLogger(logger utils.Logger) {
+	rtg.dckksContext.setLogger(logger)
+}
+
 // GenShare is the first and unique round of the rotkg protocol. Each party, using its secret share of the collective secret-key
 // and a collective random polynomial, a public share of the rotation-key by computing :
 //
@@ -83,8 +92,11 @@ func NewRotKGProtocol(params *ckks.Parameters) (rtg *RTGProtocol) {
 //
 // and broadcasts it to the other j-1 parties. The protocol must be repeated for each desired rotation.
 func (rtg *RTGProtocol) GenShare(rotType ckks.Rotation, k uint64, sk *ring.Poly, crp []*ring.Poly, shareOut *RTGShare) {
+	rtg.dckksContext.logger.Debug("dckks: RTG share generation", "type", rotType, "k", k)
+
 	shareOut.Type = rotType
 	shareOut.K = k
+	shareOut.GaloisElement = 0
 	switch rotType {
 	case ckks.RotationRight:
 		rtg.genShare(sk, rtg.galElRotCol[ckks.RotationLeft][k&((rtg.dckksContext.n>>1)-1)], crp, shareOut.Value)
@@ -98,16 +110,44 @@ func (rtg *RTGProtocol) GenShare(rotType ckks.Rotation, k uint64, sk *ring.Poly,
 	}
 }
 
+// GenShareGaloisElement is the first and unique round of the rotkg protocol for the key of the automorphism
+// X -> X^galEl, for any odd galEl, such as the Galois elements returned by ckks.Parameters.GaloisElementsForRotations.
+// Each party computes its public share as in GenShare, and the collective key is finalized with Finalize into the
+// automorphism keys of the RotationKeys, which are applied with ckks.Evaluator.Automorphism. The protocol must be
+// repeated, with a new crp, for each Galois element of the list, or run once for the whole list with GenBatchShare.
+func (rtg *RTGProtocol) GenShareGaloisElement(galEl uint64, sk *ring.Poly, crp []*ring.Poly, shareOut *RTGShare) {
+	rtg.dckksContext.logger.Debug("dckks: RTG share generation", "galEl", galEl)
+
+	if galEl&1 == 0 {
+		panic("cannot GenShareGaloisElement: Galois element must be odd")
+	}
+
+	galEl &= (rtg.dckksContext.n << 1) - 1
+
+	shareOut.Type = 0
+	shareOut.K = 0
+	shareOut.GaloisElement = galEl
+
+	// The key switches from pi^-1(s) to s, as the automorphism is applied to the ciphertext before the key-switching
+	rtg.genShare(sk, rtg.dckksContext.params.InverseGaloisElement(galEl), crp, shareOut.Value)
+}
+
 // genswitchkey is a generic method to generate the public-share of the collective rotation-key.
 func (rtg *RTGProtocol) genShare(sk *ring.Poly, galEl uint64, crp []*ring.Poly, evakey []*ring.Poly) {
 
-	ringQP := rtg.dckksContext.ringQP
+	rtg.dckksContext.ringQP.MulScalarBigint(sk, rtg.dckksContext.ringP.ModulusBigint, rtg.tmpPoly[0])
 
-	ring.PermuteNTT(sk, galEl, rtg.tmpPoly[1])
+	rtg.genShareScaled(sk, galEl, crp, evakey)
 
-	ringQP.MulScalarBigint(sk, rtg.dckksContext.ringP.ModulusBigint, rtg.tmpPoly[0])
+	rtg.tmpPoly[0].Zero()
+}
 
-	var index uint64
+// genShareScaled is genShare for a tmpPoly[0] already equal to P*sk, which is shared by the keys of a batch.
+func (rtg *RTGProtocol) genShareScaled(sk *ring.Poly, galEl uint64, crp []*ring.Poly, evakey []*ring.Poly) {
+
+	ringQP := rtg.dckksContext.ringQP
+
+	ring.PermuteNTT(sk, galEl, rtg.tmpPoly[1])
 
 	for i := uint64(0); i < rtg.dckksContext.beta; i++ {
 
@@ -120,9 +160,8 @@ func (rtg *RTGProtocol) genShare(sk *ring.Poly, galEl uint64, crp []*ring.Poly,
 
 		// e + sk_in * (qiBarre*qiStar) * 2^w
 		// (qiBarre*qiStar)%qi = 1, else 0
-		for j := uint64(0); j < rtg.dckksContext.alpha; j++ {
-
-			index = i*rtg.dckksContext.alpha + j
+		start, end := rtg.dckksContext.params.DecompositionDigit(i)
+		for index := start; index < end; index++ {
 
 			qi := ringQP.Modulus[index]
 			tmp0 := rtg.tmpPoly[0].Coeffs[index]
@@ -131,21 +170,13 @@ func (rtg *RTGProtocol) genShare(sk *ring.Poly, galEl uint64, crp []*ring.Poly,
 			for w := uint64(0); w < ringQP.N; w++ {
 				tmp1[w] = ring.CRed(tmp1[w]+tmp0[w], qi)
 			}
-
-			// Handles the case where nb pj does not divides nb qi
-			if index >= rtg.dckksContext.params.QiCount() {
-				break
-			}
 		}
 
 		// sk_in * (qiBarre*qiStar) * 2^w - a*sk + e
 		ringQP.MulCoeffsMontgomeryAndSub(crp[i], rtg.tmpPoly[1], evakey[i])
 	}
 
-	rtg.tmpPoly[0].Zero()
 	rtg.tmpPoly[1].Zero()
-
-	return
 }
 
 // Aggregate is the second part of the unique round of the rotkg protocol. Uppon receiving the j-1 public shares,
@@ -155,18 +186,20 @@ func (rtg *RTGProtocol) genShare(sk *ring.Poly, galEl uint64, crp []*ring.Poly,
 func (rtg *RTGProtocol) Aggregate(share1, share2, shareOut RTGShare) {
 	ringQP := rtg.dckksContext.ringQP
 
-	if share1.Type != share2.Type || share1.K != share2.K {
+	if share1.Type != share2.Type || share1.K != share2.K || share1.GaloisElement != share2.GaloisElement {
 		panic("cannot aggregate shares of different types")
 	}
 
 	shareOut.Type = share1.Type
 	shareOut.K = share1.K
+	shareOut.GaloisElement = share1.GaloisElement
 	for i := uint64(0); i < rtg.dckksContext.beta; i++ {
 		ringQP.Add(share1.Value[i], share2.Value[i], shareOut.Value[i])
 	}
 }
 
-// Finalize finalizes the RTG protocol and populates the input RotationKey with the computed collective SwitchingKey.
+// Finalize finalizes the RTG protocol and populates the input RotationKey with the computed collective SwitchingKey,
+// as a rotation key for the shares of GenShare and as an automorphism key for the shares of GenShareGaloisElement.
 func (rtg *RTGProtocol) Finalize(params *ckks.Parameters, share RTGShare, crp []*ring.Poly, rotKey *ckks.RotationKeys) {
 
 	ringQP := rtg.dckksContext.ringQP
@@ -178,5 +211,10 @@ func (rtg *RTGProtocol) Finalize(params *ckks.Parameters, share RTGShare, crp []
 		ringQP.Copy(crp[i], rtg.tmpSwitchKey[i][1])
 	}
 
+	if share.Type == 0 {
+		rotKey.SetAutomorphismKey(params, rtg.tmpSwitchKey, share.GaloisElement)
+		return
+	}
+
 	rotKey.SetRotKey(params, rtg.tmpSwitchKey, share.Type, k)
 }
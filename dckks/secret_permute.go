@@ -0,0 +1,92 @@
+package dckks
+
+import (
+	"math/bits"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// SecretPermuteProtocol is the step of one party in a chained secret transform of the slots. In its step, the party
+// applies the SlotTransform derived from its own seed on the ciphertext, homomorphically and alone, and all the
+// parties then refresh the result with the RefreshProtocol, which replaces the ciphertext by a fresh one. Chaining the
+// steps of all the parties applies the composition of their transforms in the order of the chain. The seed of a party
+// never leaves it, so no party, and no coalition missing one of the parties of the chain, knows the composed
+// permutation.
+//
+// The masks of a SlotTransform are powers of the imaginary unit: they hide the phase of each slot, but not its modulus,
+// so whoever sees both the input and the output values can still link slots of distinct moduli.
+type SecretPermuteProtocol struct {
+	params          *ckks.Parameters
+	slots           uint64
+	transform       *SlotTransform
+	diagonals       map[int][]complex128
+	encoder         ckks.Encoder
+	evaluator       ckks.Evaluator
+	smudgingSampler *ring.GaussianSampler
+}
+
+// NewSecretPermuteProtocol creates the step of a party for the transform derived from its secret seed on the given
+// number of slots. The party adds to its output a Gaussian noise of standard deviation smudgingSigma, which hides the
+// noise of the input permuted by its transform from the parties downstream.
+func NewSecretPermuteProtocol(params *ckks.Parameters, seed []byte, slots uint64, masking bool, smudgingSigma float64) (sp *SecretPermuteProtocol) {
+
+	if slots == 0 || slots&(slots-1) != 0 || slots > params.Slots() {
+		panic("cannot NewSecretPermuteProtocol: slots must be a power of two smaller than or equal to params.Slots()")
+	}
+
+	sp = new(SecretPermuteProtocol)
+	sp.params = params.Copy()
+	sp.slots = slots
+	sp.transform = NewSlotTransform(seed, slots, masking)
+	sp.diagonals = sp.transform.diagonals()
+	sp.encoder = ckks.NewEncoder(params)
+	sp.evaluator = ckks.NewEvaluator(params)
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	ringQ := newDckksContext(params).ringQ
+	sp.smudgingSampler = ring.NewGaussianSampler(prng, ringQ, smudgingSigma, uint64(6*smudgingSigma))
+
+	return
+}
+
+// SecretPermuteRotations returns the rotations for which the parties must generate the collective rotation keys
+// before a chained secret transform on the given number of slots: all of them, so that the keys do not depend on the
+// transforms.
+func SecretPermuteRotations(slots uint64) (rotations []uint64) {
+	rotations = make([]uint64, slots-1)
+	for i := range rotations {
+		rotations[i] = uint64(i + 1)
+	}
+	return
+}
+
+// Permute applies the transform of the party on the ciphertext and returns the result, one level below and at the same
+// scale. The result must then be refreshed by all the parties before it is given to the next party of the chain.
+func (sp *SecretPermuteProtocol) Permute(ciphertext *ckks.Ciphertext, rotkeys *ckks.RotationKeys) (ctOut *ckks.Ciphertext) {
+
+	level := ciphertext.Level()
+
+	if level == 0 {
+		panic("cannot Permute: input Ciphertext at level 0")
+	}
+
+	// The scale of the matrix is the modulus dropped by the rescaling, which restores the scale of the input exactly
+	scale := float64(sp.params.Qi()[level])
+	matrix := sp.encoder.EncodeDiagMatrixAtLvl(level, sp.diagonals, scale, uint64(bits.Len64(sp.slots)-1))
+
+	ctOut = sp.evaluator.LinearTransformNew(ciphertext, matrix, rotkeys, nil)
+
+	if err := sp.evaluator.Rescale(ctOut, ciphertext.Scale(), ctOut); err != nil {
+		panic(err)
+	}
+
+	sp.smudgingSampler.ReadAndAddNTTLvl(ctOut.Level(), ctOut.Value()[0])
+
+	return
+}
@@ -0,0 +1,389 @@
+package dckks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// Protocol identifies a protocol of the dckks package in a cost estimation.
+type Protocol int
+
+// Protocols of the dckks package whose cost can be estimated by a Simulator.
+const (
+	ProtocolCKG Protocol = iota
+	ProtocolRKG
+	ProtocolRTG
+	ProtocolCKS
+	ProtocolPCKS
+	ProtocolRefresh
+)
+
+var protocolNames = map[Protocol]string{
+	ProtocolCKG:     "CKG",
+	ProtocolRKG:     "RKG",
+	ProtocolRTG:     "RTG",
+	ProtocolCKS:     "CKS",
+	ProtocolPCKS:    "PCKS",
+	ProtocolRefresh: "Refresh",
+}
+
+func (p Protocol) String() string {
+	if name, ok := protocolNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Protocol(%d)", int(p))
+}
+
+// protocolRounds is the number of rounds of each protocol.
+var protocolRounds = map[Protocol]int{
+	ProtocolCKG:     1,
+	ProtocolRKG:     2,
+	ProtocolRTG:     1,
+	ProtocolCKS:     1,
+	ProtocolPCKS:    1,
+	ProtocolRefresh: 1,
+}
+
+// Circuit describes the collective operations of a deployment, by the number of
+// times each protocol is executed.
+type Circuit struct {
+	// PublicKey and RelinearizationKey indicate if the collective public key and
+	// relinearization key are generated.
+	PublicKey          bool
+	RelinearizationKey bool
+
+	// Rotations is the number of collective rotation keys generated.
+	Rotations uint64
+
+	// KeySwitches, PublicKeySwitches and Refreshes are the number of ciphertexts
+	// on which the CKS, PCKS and Refresh protocols are executed.
+	KeySwitches       uint64
+	PublicKeySwitches uint64
+	Refreshes         uint64
+
+	// Level is the level of the ciphertexts given to the CKS, PCKS and Refresh protocols.
+	Level uint64
+}
+
+// instances returns the number of executions of the protocol in the circuit.
+func (c *Circuit) instances(protocol Protocol) uint64 {
+	switch protocol {
+	case ProtocolCKG:
+		if c.PublicKey {
+			return 1
+		}
+	case ProtocolRKG:
+		if c.RelinearizationKey {
+			return 1
+		}
+	case ProtocolRTG:
+		return c.Rotations
+	case ProtocolCKS:
+		return c.KeySwitches
+	case ProtocolPCKS:
+		return c.PublicKeySwitches
+	case ProtocolRefresh:
+		return c.Refreshes
+	}
+	return 0
+}
+
+// RoundTiming stores the measured durations of the operations of one round of a protocol.
+type RoundTiming struct {
+	// GenShare is the time for a party to generate its share.
+	GenShare time.Duration
+	// Aggregate is the time to aggregate two shares.
+	Aggregate time.Duration
+	// Finalize is the time to compute the output of the protocol from the aggregated share.
+	Finalize time.Duration
+}
+
+// RoundCost is the estimated cost of one round of a protocol, for all its executions in a circuit.
+type RoundCost struct {
+	Protocol  Protocol
+	Round     int
+	Instances uint64
+
+	// PartyTime is the computation time of each party and AggregationTime the computation
+	// time of the party aggregating the shares and computing the output of the round.
+	PartyTime       time.Duration
+	AggregationTime time.Duration
+
+	// ShareBytes is the size of a single share, PartyBytes the number of bytes sent by each
+	// party to the aggregator and TotalBytes the number of bytes sent by all the parties.
+	ShareBytes uint64
+	PartyBytes uint64
+	TotalBytes uint64
+}
+
+// CostEstimate is the estimated cost of a circuit, round by round.
+type CostEstimate struct {
+	Parties uint64
+	Rounds  []RoundCost
+}
+
+// PartyTime returns the total computation time of each party.
+func (est *CostEstimate) PartyTime() (d time.Duration) {
+	for _, r := range est.Rounds {
+		d += r.PartyTime
+	}
+	return
+}
+
+// AggregationTime returns the total computation time of the aggregator.
+func (est *CostEstimate) AggregationTime() (d time.Duration) {
+	for _, r := range est.Rounds {
+		d += r.AggregationTime
+	}
+	return
+}
+
+// TotalBytes returns the total number of bytes sent by all the parties.
+func (est *CostEstimate) TotalBytes() (n uint64) {
+	for _, r := range est.Rounds {
+		n += r.TotalBytes
+	}
+	return
+}
+
+func (est *CostEstimate) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-8s %5s %9s %14s %14s %14s %14s\n", "Protocol", "Round", "Instances", "PartyTime", "AggTime", "PartyBytes", "TotalBytes")
+	for _, r := range est.Rounds {
+		fmt.Fprintf(&sb, "%-8s %5d %9d %14s %14s %14d %14d\n", r.Protocol, r.Round, r.Instances, r.PartyTime, r.AggregationTime, r.PartyBytes, r.TotalBytes)
+	}
+	fmt.Fprintf(&sb, "%-8s %5s %9s %14s %14s %14s %14d\n", "Total", "", "", est.PartyTime(), est.AggregationTime(), "", est.TotalBytes())
+	return sb.String()
+}
+
+// Simulator estimates the computation time and the communication of the dckks protocols for a given
+// number of parties, without running them between actual parties. The parties are assumed to be
+// connected to a single aggregator, to which each party sends its share at each round.
+type Simulator struct {
+	params  *ckks.Parameters
+	parties uint64
+	timings map[Protocol][]RoundTiming
+}
+
+// NewSimulator creates a new Simulator for the given parameters and number of parties.
+func NewSimulator(params *ckks.Parameters, parties uint64) *Simulator {
+
+	if parties == 0 {
+		panic("cannot NewSimulator: the number of parties must be at least 1")
+	}
+
+	return &Simulator{
+		params:  params.Copy(),
+		parties: parties,
+		timings: make(map[Protocol][]RoundTiming),
+	}
+}
+
+// SetTimings sets the timings of each round of the protocol, for example with values measured
+// by Calibrate on the hardware of the parties.
+func (sim *Simulator) SetTimings(protocol Protocol, timings []RoundTiming) {
+
+	if len(timings) != protocolRounds[protocol] {
+		panic(fmt.Sprintf("cannot SetTimings: %s has %d rounds", protocol, protocolRounds[protocol]))
+	}
+
+	sim.timings[protocol] = append([]RoundTiming{}, timings...)
+}
+
+// Timings returns the timings of each round of the protocol, or nil if they have not been measured or set.
+func (sim *Simulator) Timings(protocol Protocol) []RoundTiming {
+	return sim.timings[protocol]
+}
+
+// Calibrate measures on the current machine the timings of the protocols used by the circuit, averaged over the given
+// number of repetitions. The timings of the CKS, PCKS and Refresh protocols are measured at the level of the circuit.
+func (sim *Simulator) Calibrate(circuit Circuit, repetitions int) {
+
+	if repetitions < 1 {
+		repetitions = 1
+	}
+
+	params := sim.params
+	level := circuit.Level
+	context := newDckksContext(params)
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	kgen := ckks.NewKeyGenerator(params)
+	sk, pk := kgen.GenKeyPair()
+	skOut := kgen.GenSecretKey()
+
+	crpGenerator := ring.NewUniformSampler(prng, context.ringQP)
+	crp := make([]*ring.Poly, params.Beta())
+	for i := range crp {
+		crp[i] = crpGenerator.ReadNew()
+	}
+
+	ciphertext := ckks.NewCiphertextRandom(prng, params, 1, level, params.Scale())
+
+	if circuit.instances(ProtocolCKG) > 0 {
+		ckg := NewCKGProtocol(params)
+		share := ckg.AllocateShares()
+		pkOut := ckks.NewPublicKey(params)
+		sim.timings[ProtocolCKG] = []RoundTiming{{
+			GenShare:  measure(repetitions, func() { ckg.GenShare(sk.Get(), crp[0], share) }),
+			Aggregate: measure(repetitions, func() { ckg.AggregateShares(share, share, share) }),
+			Finalize:  measure(repetitions, func() { ckg.GenPublicKey(share, crp[0], pkOut) }),
+		}}
+	}
+
+	if circuit.instances(ProtocolRKG) > 0 {
+		rkg := NewEkgProtocol(params)
+		u := rkg.NewEphemeralKey()
+		share1, share2 := rkg.AllocateShares()
+		rlk := ckks.NewRelinKey(params)
+		sim.timings[ProtocolRKG] = []RoundTiming{
+			{
+				GenShare:  measure(repetitions, func() { rkg.GenShareRoundOne(u, sk.Get(), crp, share1) }),
+				Aggregate: measure(repetitions, func() { rkg.AggregateShareRoundOne(share1, share1, share1) }),
+			},
+			{
+				GenShare:  measure(repetitions, func() { rkg.GenShareRoundTwo(share1, u, sk.Get(), crp, share2) }),
+				Aggregate: measure(repetitions, func() { rkg.AggregateShareRoundTwo(share2, share2, share2) }),
+				Finalize:  measure(repetitions, func() { rkg.GenRelinearizationKey(share1, share2, rlk) }),
+			},
+		}
+	}
+
+	if circuit.instances(ProtocolRTG) > 0 {
+		rtg := NewRotKGProtocol(params)
+		share := rtg.AllocateShare()
+		rotKeys := ckks.NewRotationKeys()
+		sim.timings[ProtocolRTG] = []RoundTiming{{
+			GenShare:  measure(repetitions, func() { rtg.GenShare(ckks.RotationLeft, 1, sk.Get(), crp, &share) }),
+			Aggregate: measure(repetitions, func() { rtg.Aggregate(share, share, share) }),
+			Finalize:  measure(repetitions, func() { rtg.Finalize(params, share, crp, rotKeys) }),
+		}}
+	}
+
+	if circuit.instances(ProtocolCKS) > 0 {
+		cks := NewCKSProtocol(params, 3.19)
+		share := cks.AllocateShare()
+		ctOut := ckks.NewCiphertext(params, 1, level, params.Scale())
+		sim.timings[ProtocolCKS] = []RoundTiming{{
+			GenShare:  measure(repetitions, func() { cks.GenShare(sk.Get(), skOut.Get(), ciphertext, share) }),
+			Aggregate: measure(repetitions, func() { cks.AggregateShares(share, share, share) }),
+			Finalize:  measure(repetitions, func() { cks.KeySwitch(share, ciphertext, ctOut) }),
+		}}
+	}
+
+	if circuit.instances(ProtocolPCKS) > 0 {
+		pcks := NewPCKSProtocol(params, 3.19)
+		share := pcks.AllocateShares(level)
+		ctOut := ckks.NewCiphertext(params, 1, level, params.Scale())
+		sim.timings[ProtocolPCKS] = []RoundTiming{{
+			GenShare:  measure(repetitions, func() { pcks.GenShare(sk.Get(), pk, ciphertext, share) }),
+			Aggregate: measure(repetitions, func() { pcks.AggregateShares(share, share, share) }),
+			Finalize:  measure(repetitions, func() { pcks.KeySwitch(share, ciphertext, ctOut) }),
+		}}
+	}
+
+	if circuit.instances(ProtocolRefresh) > 0 {
+		refresh := NewRefreshProtocol(params)
+		shareDecrypt, shareRecrypt := refresh.AllocateShares(level)
+		crs := ring.NewUniformSampler(prng, context.ringQ).ReadNew()
+		timing := RoundTiming{
+			GenShare:  measure(repetitions, func() { refresh.GenShares(sk.Get(), level, sim.parties, ciphertext, crs, shareDecrypt, shareRecrypt) }),
+			Aggregate: measure(repetitions, func() { refresh.AggregateDecrypt(shareDecrypt, shareDecrypt, shareDecrypt) }),
+		}
+		timing.Aggregate += measure(repetitions, func() { refresh.AggregateRecrypt(shareRecrypt, shareRecrypt, shareRecrypt) })
+		// Decrypt, Recode and Recrypt modify the ciphertext, so each repetition works on a fresh copy
+		for i := 0; i < repetitions; i++ {
+			ct := ciphertext.CopyNew().Ciphertext()
+			start := time.Now()
+			refresh.Decrypt(ct, shareDecrypt)
+			refresh.Recode(ct)
+			refresh.Recrypt(ct, crs, shareRecrypt)
+			timing.Finalize += time.Since(start)
+		}
+		timing.Finalize /= time.Duration(repetitions)
+		sim.timings[ProtocolRefresh] = []RoundTiming{timing}
+	}
+}
+
+// Estimate returns the estimated cost of the circuit. It returns an error if the timings of a
+// protocol used by the circuit have neither been measured by Calibrate nor set by SetTimings.
+func (sim *Simulator) Estimate(circuit Circuit) (est *CostEstimate, err error) {
+
+	est = &CostEstimate{Parties: sim.parties}
+
+	for _, protocol := range []Protocol{ProtocolCKG, ProtocolRKG, ProtocolRTG, ProtocolCKS, ProtocolPCKS, ProtocolRefresh} {
+
+		instances := circuit.instances(protocol)
+		if instances == 0 {
+			continue
+		}
+
+		timings, ok := sim.timings[protocol]
+		if !ok {
+			return nil, fmt.Errorf("cannot Estimate: no timings for %s", protocol)
+		}
+
+		for round, timing := range timings {
+
+			shareBytes := sim.shareBytes(protocol, circuit.Level)
+
+			aggregation := time.Duration(sim.parties-1)*timing.Aggregate + timing.Finalize
+
+			est.Rounds = append(est.Rounds, RoundCost{
+				Protocol:        protocol,
+				Round:           round + 1,
+				Instances:       instances,
+				PartyTime:       time.Duration(instances) * timing.GenShare,
+				AggregationTime: time.Duration(instances) * aggregation,
+				ShareBytes:      shareBytes,
+				PartyBytes:      instances * shareBytes,
+				TotalBytes:      sim.parties * instances * shareBytes,
+			})
+		}
+	}
+
+	return est, nil
+}
+
+// shareBytes returns the size in bytes of a marshaled share of the protocol.
+func (sim *Simulator) shareBytes(protocol Protocol, level uint64) uint64 {
+
+	params := sim.params
+
+	N, levelQP := params.N(), params.QPiCount()-1
+
+	switch protocol {
+	case ProtocolCKG:
+		return marshaledShareLen(0, 1, N, levelQP)
+	case ProtocolRKG:
+		return marshaledShareLen(rkgShareMetaLen, 2*params.Beta(), N, levelQP)
+	case ProtocolRTG:
+		return marshaledShareLen(rtgShareMetaLen, params.Beta(), N, levelQP)
+	case ProtocolCKS:
+		return marshaledShareLen(0, 1, N, level)
+	case ProtocolPCKS:
+		return marshaledShareLen(0, 2, N, level)
+	case ProtocolRefresh:
+		return marshaledShareLen(0, 1, N, level) + marshaledShareLen(0, 1, N, params.MaxLevel())
+	}
+
+	return 0
+}
+
+// measure returns the average duration of f over the given number of repetitions.
+func measure(repetitions int, f func()) time.Duration {
+	start := time.Now()
+	for i := 0; i < repetitions; i++ {
+		f()
+	}
+	return time.Since(start) / time.Duration(repetitions)
+}
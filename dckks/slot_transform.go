@@ -0,0 +1,191 @@
+package dckks
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// SlotTransform is a permutation of the slots followed by a masking of each slot by a root of unity of order four:
+// the i-th slot of the result is i^Phases[i] times the Permutation[i]-th slot of the input, where i is the imaginary
+// unit. A nil Phases is a permutation without masking. The masks are applied exactly, so the transform is inverted
+// exactly by the owner of the output, but they leave the modulus of each slot unchanged: the masking hides the phase
+// of the slots, not their magnitude.
+type SlotTransform struct {
+	Permutation []uint64
+	Phases      []uint8
+}
+
+// NewSlotTransform derives the SlotTransform of a party on the given number of slots from its secret seed: a uniform
+// permutation and, if masking is true, uniform masks.
+func NewSlotTransform(seed []byte, slots uint64, masking bool) *SlotTransform {
+
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		panic(err)
+	}
+
+	transform := &SlotTransform{Permutation: make([]uint64, slots)}
+
+	for i := range transform.Permutation {
+		transform.Permutation[i] = uint64(i)
+	}
+
+	// Fisher-Yates shuffle
+	permPRNG := prng.Fork([]byte("dckks/SlotTransform/permutation"))
+	for i := slots - 1; i > 0 && i < slots; i-- {
+		j := ring.RandUniform(permPRNG, i+1, (uint64(1)<<bits.Len64(i))-1)
+		transform.Permutation[i], transform.Permutation[j] = transform.Permutation[j], transform.Permutation[i]
+	}
+
+	if masking {
+		phasesPRNG := prng.Fork([]byte("dckks/SlotTransform/phases"))
+		transform.Phases = make([]uint8, slots)
+		for i := range transform.Phases {
+			transform.Phases[i] = uint8(ring.RandUniform(phasesPRNG, 4, 3))
+		}
+	}
+
+	return transform
+}
+
+// NewJointSlotTransform returns the composition of the SlotTransforms derived from the seeds of the parties, applied
+// in the order of the seeds. All the parties of a run of the PermuteProtocol apply the same transform in their
+// shares, so the joint transform is known to every party that received the seeds: it is only hidden from the owner
+// of the ciphertext and from outsiders. The SecretPermuteProtocol hides it from the parties as well.
+func NewJointSlotTransform(seeds [][]byte, slots uint64, masking bool) *SlotTransform {
+
+	if len(seeds) == 0 {
+		panic("cannot NewJointSlotTransform: no seed")
+	}
+
+	transform := NewSlotTransform(seeds[0], slots, masking)
+	for _, seed := range seeds[1:] {
+		transform = transform.Compose(NewSlotTransform(seed, slots, masking))
+	}
+
+	return transform
+}
+
+// Compose returns the SlotTransform applying transform then next.
+func (transform *SlotTransform) Compose(next *SlotTransform) *SlotTransform {
+
+	if len(transform.Permutation) != len(next.Permutation) {
+		panic("cannot Compose: transforms on different numbers of slots")
+	}
+
+	out := &SlotTransform{Permutation: make([]uint64, len(next.Permutation))}
+
+	for i, j := range next.Permutation {
+		out.Permutation[i] = transform.Permutation[j]
+	}
+
+	if transform.Phases != nil || next.Phases != nil {
+		out.Phases = make([]uint8, len(next.Permutation))
+		for i, j := range next.Permutation {
+			if transform.Phases != nil {
+				out.Phases[i] += transform.Phases[j]
+			}
+			if next.Phases != nil {
+				out.Phases[i] += next.Phases[i]
+			}
+			out.Phases[i] &= 3
+		}
+	}
+
+	return out
+}
+
+// Inverse returns the SlotTransform undoing transform.
+func (transform *SlotTransform) Inverse() *SlotTransform {
+
+	out := &SlotTransform{Permutation: make([]uint64, len(transform.Permutation))}
+
+	for i, j := range transform.Permutation {
+		out.Permutation[j] = uint64(i)
+	}
+
+	if transform.Phases != nil {
+		out.Phases = make([]uint8, len(transform.Phases))
+		for i, j := range transform.Permutation {
+			out.Phases[j] = (4 - transform.Phases[i]) & 3
+		}
+	}
+
+	return out
+}
+
+// Apply applies the transform on the values, as a reference for the transform operated on the slots by the
+// PermuteProtocol.
+func (transform *SlotTransform) Apply(values []complex128) []complex128 {
+
+	transform.check(uint64(len(values)))
+
+	units := [4]complex128{1, 1i, -1, -1i}
+
+	out := make([]complex128, len(values))
+	for i, j := range transform.Permutation {
+		out[i] = values[j]
+		if transform.Phases != nil {
+			out[i] *= units[transform.Phases[i]&3]
+		}
+	}
+
+	return out
+}
+
+// diagonals returns the non-zero diagonals of the matrix of the transform, indexed by their rotation as expected by
+// ckks.Encoder.EncodeDiagMatrixAtLvl.
+func (transform *SlotTransform) diagonals() (diagonals map[int][]complex128) {
+
+	slots := uint64(len(transform.Permutation))
+
+	units := [4]complex128{1, 1i, -1, -1i}
+
+	diagonals = make(map[int][]complex128)
+	for i, j := range transform.Permutation {
+
+		// The i-th slot of the output is read from the i-th slot of the input rotated by j-i
+		k := int((j + slots - uint64(i)) % slots)
+
+		if diagonals[k] == nil {
+			diagonals[k] = make([]complex128, slots)
+		}
+
+		diagonals[k][i] = 1
+		if transform.Phases != nil {
+			diagonals[k][i] = units[transform.Phases[i]&3]
+		}
+	}
+
+	return
+}
+
+// check panics if the transform is not a transform on the given number of slots.
+func (transform *SlotTransform) check(slots uint64) {
+
+	if uint64(len(transform.Permutation)) != slots {
+		panic(fmt.Sprintf("invalid SlotTransform: permutation of %d slots instead of %d", len(transform.Permutation), slots))
+	}
+
+	if transform.Phases != nil && uint64(len(transform.Phases)) != slots {
+		panic(fmt.Sprintf("invalid SlotTransform: %d masks for %d slots", len(transform.Phases), slots))
+	}
+}
+
+// mulByUnit multiplies c by i^phase in place.
+func mulByUnit(c *ring.Complex, phase uint8) {
+	switch phase & 3 {
+	case 1:
+		c[0], c[1] = c[1], c[0]
+		c[0].Neg(c[0])
+	case 2:
+		c[0].Neg(c[0])
+		c[1].Neg(c[1])
+	case 3:
+		c[0], c[1] = c[1], c[0]
+		c[1].Neg(c[1])
+	}
+}
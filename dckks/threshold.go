@@ -0,0 +1,220 @@
+package dckks
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// The t-out-of-n threshold setting replaces the n additive shares s_i of the ideal secret key s = sum(s_i) by Shamir
+// shares f(x_j) of a polynomial f of degree t-1 such that f(0) = s, so that any t parties can run the protocols:
+//
+//  1. each party i samples with GenShamirPolynomial a polynomial f_i of degree t-1 with f_i(0) = s_i, and sends
+//     f_i(x_j), computed with GenShamirShare, to the party j of public point x_j;
+//  2. each party j aggregates the n shares it received into its Shamir share f(x_j) = sum(f_i(x_j)) of s;
+//  3. for each protocol, the t online parties multiply their Shamir share by their Lagrange coefficient for the set
+//     of online parties with Combiner.GenAdditiveShare, which gives t additive shares of s. The GenShareThreshold
+//     variants of the CKS and PCKS protocols do it before generating their share.
+//
+// The Shamir polynomials are computed coefficient-wise over the moduli of QP, so the shares have the same domain as
+// the secret keys, i.e. the NTT and Montgomery domain.
+
+// ShamirPublicPoint is the public point x_j of a party in the t-out-of-n threshold setting, at which the Shamir
+// polynomials are evaluated to compute its share. The points of the parties must be distinct, non-zero and smaller
+// than the moduli of QP.
+type ShamirPublicPoint uint64
+
+// ShamirPolynomial is a polynomial of degree t-1 whose coefficients are polynomials of QP, the constant coefficient
+// being the additive share of the secret key of its owner.
+type ShamirPolynomial struct {
+	Coeffs []*ring.Poly
+}
+
+// ShamirSecretShare is the share of a party in the t-out-of-n threshold setting, that is the evaluation of a Shamir
+// polynomial, or of the sum of the Shamir polynomials of the parties, at its public point.
+type ShamirSecretShare struct {
+	*ring.Poly
+	Party uint64
+}
+
+// Thresholdizer is the structure storing the parameters for the generation of the Shamir shares of the secret key.
+type Thresholdizer struct {
+	dckksContext   *dckksContext
+	uniformSampler *ring.UniformSampler
+}
+
+// NewThresholdizer creates a new Thresholdizer.
+func NewThresholdizer(params *ckks.Parameters) *Thresholdizer {
+
+	thr := new(Thresholdizer)
+	thr.dckksContext = newDckksContext(params)
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+	thr.uniformSampler = ring.NewUniformSampler(prng, thr.dckksContext.ringQP)
+
+	return thr
+}
+
+// AllocateShamirShare allocates a ShamirSecretShare.
+func (thr *Thresholdizer) AllocateShamirShare() ShamirSecretShare {
+	return ShamirSecretShare{Poly: thr.dckksContext.ringQP.NewPoly()}
+}
+
+// GenShamirPolynomial returns a random Shamir polynomial of degree threshold-1 whose constant coefficient is a copy
+// of the additive share sk of the secret key.
+func (thr *Thresholdizer) GenShamirPolynomial(threshold uint64, sk *ring.Poly) (poly *ShamirPolynomial) {
+
+	if threshold == 0 {
+		panic("cannot GenShamirPolynomial: threshold must be at least 1")
+	}
+
+	poly = new(ShamirPolynomial)
+	poly.Coeffs = make([]*ring.Poly, threshold)
+	poly.Coeffs[0] = sk.CopyNew()
+	for i := uint64(1); i < threshold; i++ {
+		poly.Coeffs[i] = thr.uniformSampler.ReadNew()
+	}
+
+	return
+}
+
+// GenShamirShare evaluates the Shamir polynomial at the public point of a party and writes the result on shareOut,
+// to be sent to this party.
+func (thr *Thresholdizer) GenShamirShare(point ShamirPublicPoint, poly *ShamirPolynomial, shareOut ShamirSecretShare) {
+
+	ringQP := thr.dckksContext.ringQP
+
+	checkShamirPoint(ringQP, point)
+
+	// Horner evaluation
+	ringQP.Copy(poly.Coeffs[len(poly.Coeffs)-1], shareOut.Poly)
+	for i := len(poly.Coeffs) - 2; i >= 0; i-- {
+		ringQP.MulScalar(shareOut.Poly, uint64(point), shareOut.Poly)
+		ringQP.Add(shareOut.Poly, poly.Coeffs[i], shareOut.Poly)
+	}
+}
+
+// AggregateShares adds share1 with share2 on shareOut. A party aggregates the shares sent by all the parties into its
+// Shamir share of the secret key.
+func (thr *Thresholdizer) AggregateShares(share1, share2, shareOut ShamirSecretShare) {
+	thr.dckksContext.ringQP.Add(share1.Poly, share2.Poly, shareOut.Poly)
+}
+
+// Combiner is the structure storing the parameters for the conversion of the Shamir shares of t online parties into
+// t additive shares of the secret key.
+type Combiner struct {
+	dckksContext *dckksContext
+	threshold    uint64
+}
+
+// NewCombiner creates a new Combiner for the given threshold t.
+func NewCombiner(params *ckks.Parameters, threshold uint64) *Combiner {
+
+	if threshold == 0 {
+		panic("cannot NewCombiner: threshold must be at least 1")
+	}
+
+	cmb := new(Combiner)
+	cmb.dckksContext = newDckksContext(params)
+	cmb.threshold = threshold
+	return cmb
+}
+
+// GenAdditiveShare multiplies the Shamir share of the party of public point ownPoint by its Lagrange coefficient for
+// the set activePoints of public points of the online parties, and writes the result on skOut. The skOut of the online
+// parties are additive shares of the secret key, which can be used by any protocol of the package. activePoints must
+// have at least threshold distinct points and contain ownPoint.
+func (cmb *Combiner) GenAdditiveShare(activePoints []ShamirPublicPoint, ownPoint ShamirPublicPoint, ownShare ShamirSecretShare, skOut *ring.Poly) {
+	cmb.dckksContext.ringQP.MulScalarBigint(ownShare.Poly, cmb.LagrangeCoefficient(activePoints, ownPoint), skOut)
+}
+
+// LagrangeCoefficient returns the Lagrange coefficient modulo QP of the party of public point ownPoint for the set
+// activePoints, that is the product of x/(x-ownPoint) for the points x of activePoints other than ownPoint.
+func (cmb *Combiner) LagrangeCoefficient(activePoints []ShamirPublicPoint, ownPoint ShamirPublicPoint) *big.Int {
+
+	ringQP := cmb.dckksContext.ringQP
+
+	if uint64(len(activePoints)) < cmb.threshold {
+		panic(fmt.Sprintf("cannot LagrangeCoefficient: %d active parties for a threshold of %d", len(activePoints), cmb.threshold))
+	}
+
+	num, den := ring.NewUint(1), ring.NewUint(1)
+	found := false
+	seen := make(map[ShamirPublicPoint]bool)
+
+	for _, x := range activePoints {
+
+		checkShamirPoint(ringQP, x)
+
+		if seen[x] {
+			panic("cannot LagrangeCoefficient: public points must be distinct")
+		}
+		seen[x] = true
+
+		if x == ownPoint {
+			found = true
+			continue
+		}
+
+		num.Mul(num, ring.NewUint(uint64(x)))
+		den.Mul(den, new(big.Int).Sub(ring.NewUint(uint64(x)), ring.NewUint(uint64(ownPoint))))
+	}
+
+	if !found {
+		panic("cannot LagrangeCoefficient: ownPoint is not an active point")
+	}
+
+	// den is invertible modulo QP since the points are distinct and smaller than its moduli
+	den.Mod(den, ringQP.ModulusBigint)
+	den.ModInverse(den, ringQP.ModulusBigint)
+
+	return num.Mod(num.Mul(num, den), ringQP.ModulusBigint)
+}
+
+// GenShareThreshold is GenShare for the t-out-of-n threshold setting: the additive shares of skInput and skOutput
+// are computed from the Shamir shares of the party with its Lagrange coefficient for the online parties activePoints.
+// An skOutputShare with a nil Poly stands for the zero secret key, i.e. for a collective decryption.
+func (cks *CKSProtocol) GenShareThreshold(combiner *Combiner, activePoints []ShamirPublicPoint, ownPoint ShamirPublicPoint, skInputShare, skOutputShare ShamirSecretShare, ct *ckks.Ciphertext, shareOut CKSShare) {
+
+	cks.dckksContext.logger.Debug("dckks: CKS threshold share generation", "level", ct.Level(), "parties", len(activePoints))
+
+	ringQ := cks.dckksContext.ringQ
+
+	lambda := combiner.LagrangeCoefficient(activePoints, ownPoint)
+
+	// lambda * (skInput - skOutput)
+	if skOutputShare.Poly != nil {
+		ringQ.SubLvl(ct.Level(), skInputShare.Poly, skOutputShare.Poly, cks.tmpDelta)
+		ringQ.MulScalarBigintLvl(ct.Level(), cks.tmpDelta, lambda, cks.tmpDelta)
+	} else {
+		ringQ.MulScalarBigintLvl(ct.Level(), skInputShare.Poly, lambda, cks.tmpDelta)
+	}
+
+	cks.genShareDelta(cks.sigmaSmudging, cks.tmpDelta, ct, shareOut)
+}
+
+// GenShareThreshold is GenShare for the t-out-of-n threshold setting: the additive share of the secret key is computed
+// from the Shamir share of the party with its Lagrange coefficient for the online parties activePoints.
+func (pcks *PCKSProtocol) GenShareThreshold(combiner *Combiner, activePoints []ShamirPublicPoint, ownPoint ShamirPublicPoint, skShare ShamirSecretShare, pk *ckks.PublicKey, ct *ckks.Ciphertext, shareOut PCKSShare) {
+
+	combiner.GenAdditiveShare(activePoints, ownPoint, skShare, pcks.skTmp)
+
+	pcks.GenShare(pcks.skTmp, pk, ct, shareOut)
+
+	pcks.skTmp.Zero()
+}
+
+// checkShamirPoint panics if the public point is zero or not smaller than the moduli of the ring.
+func checkShamirPoint(r *ring.Ring, point ShamirPublicPoint) {
+	for _, qi := range r.Modulus {
+		if point == 0 || uint64(point) >= qi {
+			panic(fmt.Sprintf("invalid public point %d: must be non-zero and smaller than the moduli", point))
+		}
+	}
+}
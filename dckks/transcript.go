@@ -0,0 +1,181 @@
+package dckks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+)
+
+// SessionID identifies a run of the protocols between a set of parties. It is derived from the parameters, the number
+// of parties and a nonce agreed on by the parties, so that the parties of a run with different parameters do not share
+// the same SessionID.
+type SessionID [32]byte
+
+// NewSessionID returns the SessionID of a run between nParties parties with the given parameters and nonce, which
+// must be unique to the run, e.g. a counter or random bytes chosen by one party.
+func NewSessionID(params *ckks.Parameters, nParties uint64, nonce []byte) (session SessionID) {
+
+	paramsHash := params.Hash()
+
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], nParties)
+
+	h := sha256.New()
+	h.Write([]byte("dckks/session"))
+	h.Write(paramsHash[:])
+	h.Write(tmp[:])
+	h.Write(nonce)
+	copy(session[:], h.Sum(nil))
+
+	return
+}
+
+// Transcript binds the shares exchanged in a session to its SessionID and accumulates a hash over them. The shares are
+// sent sealed with Seal, which prefixes them with the SessionID and the round, and received with Open, which rejects
+// the shares of other sessions or rounds and the shares of a party already received for the round. Before finalizing
+// the protocol, the parties compare the Digest of their transcripts with Verify, which detects that they did not
+// aggregate the same shares.
+type Transcript struct {
+	session SessionID
+	shares  map[transcriptEntry][32]byte
+}
+
+// transcriptEntry indexes the shares of a Transcript by round and party.
+type transcriptEntry struct {
+	round RoundTag
+	party uint64
+}
+
+// NewTranscript creates a new empty Transcript for the session.
+func NewTranscript(session SessionID) *Transcript {
+	return &Transcript{session: session, shares: make(map[transcriptEntry][32]byte)}
+}
+
+// Seal marshals the share of the local party for the round, prefixed with the SessionID and the round, and adds it
+// to the transcript.
+func (tr *Transcript) Seal(round RoundTag, share encoding.BinaryMarshaler) (data []byte, err error) {
+
+	if len(round) > 0xFF {
+		return nil, errors.New("cannot Seal: round tag is too long")
+	}
+
+	shareData, err := share.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := tr.newEntry(round, shareData)
+	if err != nil {
+		return nil, err
+	}
+
+	tr.shares[entry] = sha256.Sum256(shareData)
+
+	data = make([]byte, 0, len(tr.session)+1+len(round)+len(shareData))
+	data = append(data, tr.session[:]...)
+	data = append(data, uint8(len(round)))
+	data = append(data, round...)
+	data = append(data, shareData...)
+
+	return data, nil
+}
+
+// Open checks that the sealed data is a share of the session for the round, unmarshals it on share and adds it to
+// the transcript. It returns an error if the share was sealed for another session or round, or if the transcript
+// already has a share of the same party for the round.
+func (tr *Transcript) Open(round RoundTag, data []byte, share encoding.BinaryUnmarshaler) (err error) {
+
+	ptr := len(tr.session)
+
+	if len(data) < ptr+1 || len(data) < ptr+1+int(data[ptr]) {
+		return errors.New("cannot Open: data is too short")
+	}
+
+	if !bytes.Equal(data[:ptr], tr.session[:]) {
+		return errors.New("cannot Open: share of another session")
+	}
+
+	if RoundTag(data[ptr+1:ptr+1+int(data[ptr])]) != round {
+		return fmt.Errorf("cannot Open: share of round %q instead of %q", data[ptr+1:ptr+1+int(data[ptr])], round)
+	}
+
+	shareData := data[ptr+1+int(data[ptr]):]
+
+	entry, err := tr.newEntry(round, shareData)
+	if err != nil {
+		return err
+	}
+
+	if err = share.UnmarshalBinary(shareData); err != nil {
+		return err
+	}
+
+	tr.shares[entry] = sha256.Sum256(shareData)
+
+	return nil
+}
+
+// newEntry returns the entry of the marshaled share for the round, the party being read from the header of the share.
+// It returns an error if the transcript already has a share of this party for the round.
+func (tr *Transcript) newEntry(round RoundTag, shareData []byte) (entry transcriptEntry, err error) {
+
+	if len(shareData) < shareHeaderLen {
+		return entry, errors.New("invalid share: data is too short")
+	}
+
+	entry = transcriptEntry{round: round, party: binary.BigEndian.Uint64(shareData[1:9])}
+
+	if _, ok := tr.shares[entry]; ok {
+		return entry, fmt.Errorf("share of party %d already in the transcript for round %q", entry.party, round)
+	}
+
+	return entry, nil
+}
+
+// Digest returns the hash of the SessionID and of the shares of the transcript, which does not depend on the order in
+// which the shares were added.
+func (tr *Transcript) Digest() (digest [32]byte) {
+
+	entries := make([]transcriptEntry, 0, len(tr.shares))
+	for entry := range tr.shares {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].round != entries[j].round {
+			return entries[i].round < entries[j].round
+		}
+		return entries[i].party < entries[j].party
+	})
+
+	var tmp [8]byte
+
+	h := sha256.New()
+	h.Write([]byte("dckks/transcript"))
+	h.Write(tr.session[:])
+	for _, entry := range entries {
+		h.Write([]byte{uint8(len(entry.round))})
+		h.Write([]byte(entry.round))
+		binary.BigEndian.PutUint64(tmp[:], entry.party)
+		h.Write(tmp[:])
+		shareHash := tr.shares[entry]
+		h.Write(shareHash[:])
+	}
+	copy(digest[:], h.Sum(nil))
+
+	return
+}
+
+// Verify checks that the digest of the transcript of another party matches the Digest of the transcript.
+func (tr *Transcript) Verify(digest [32]byte) error {
+	if tr.Digest() != digest {
+		return errors.New("invalid transcript: digest mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package dckks
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"sync"
+)
+
+// RoundTag identifies a round of an instance of a protocol, e.g. "rkg/1" and "rkg/2" for the two rounds of a
+// relinearization key generation, so that the shares of concurrent rounds sent between the same parties are not mixed.
+type RoundTag string
+
+// Transport is the network layer of a party, which sends its shares to the other parties and receives theirs, the
+// parties being identified by the same indexes as the Party field of the shares. The shares are sent marshaled, and
+// the receiver unmarshals them with the UnmarshalBinary method of the share it expects. The package
+// github.com/ldsec/lattigo/v2/dckks/grpctransport, built with the grpc build tag, implements it over gRPC.
+type Transport interface {
+
+	// Party returns the index of the local party.
+	Party() uint64
+
+	// SendShare sends the share of the local party for the round to the party to.
+	SendShare(ctx context.Context, to uint64, round RoundTag, share encoding.BinaryMarshaler) error
+
+	// ReceiveShares blocks until the local party received the shares of all the parties from for the round, and
+	// returns them indexed by party. It returns an error if ctx is done before.
+	ReceiveShares(ctx context.Context, round RoundTag, from []uint64) (map[uint64][]byte, error)
+}
+
+// MemoryNetwork is a network of parties in the same process, whose Transports exchange the shares through memory, e.g.
+// to test or simulate the protocols without networking. A party sends at most one share per round to each party: a
+// second one is rejected, even after the first was delivered, until the round is complete. A round is complete for the
+// receiver once all the shares it received for the round were delivered, after which the network forgets the round.
+type MemoryNetwork struct {
+	mailboxes map[uint64]*mailbox
+}
+
+// mailbox stores the shares received by a party, indexed by round and sender, until they are delivered, and the senders
+// of the shares already delivered for each round, so that a share replayed after its delivery is rejected as well. Both
+// are dropped once the round is complete. notify is closed and replaced on each reception to wake up the receivers.
+type mailbox struct {
+	sync.Mutex
+	shares    map[RoundTag]map[uint64][]byte
+	delivered map[RoundTag]map[uint64]bool
+	notify    chan struct{}
+}
+
+// NewMemoryNetwork creates a new MemoryNetwork between the given parties.
+func NewMemoryNetwork(parties []uint64) *MemoryNetwork {
+
+	network := new(MemoryNetwork)
+	network.mailboxes = make(map[uint64]*mailbox, len(parties))
+
+	for _, party := range parties {
+		network.mailboxes[party] = &mailbox{
+			shares:    make(map[RoundTag]map[uint64][]byte),
+			delivered: make(map[RoundTag]map[uint64]bool),
+			notify:    make(chan struct{}),
+		}
+	}
+
+	return network
+}
+
+// Transport returns the Transport of the given party on the network.
+func (network *MemoryNetwork) Transport(party uint64) Transport {
+
+	if _, ok := network.mailboxes[party]; !ok {
+		panic(fmt.Sprintf("cannot Transport: party %d is not on the network", party))
+	}
+
+	return &memoryTransport{network: network, party: party}
+}
+
+type memoryTransport struct {
+	network *MemoryNetwork
+	party   uint64
+}
+
+func (tr *memoryTransport) Party() uint64 {
+	return tr.party
+}
+
+func (tr *memoryTransport) SendShare(ctx context.Context, to uint64, round RoundTag, share encoding.BinaryMarshaler) (err error) {
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	box, ok := tr.network.mailboxes[to]
+	if !ok {
+		return fmt.Errorf("cannot SendShare: party %d is not on the network", to)
+	}
+
+	data, err := share.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	box.Lock()
+	defer box.Unlock()
+
+	if box.shares[round] == nil {
+		box.shares[round] = make(map[uint64][]byte)
+	}
+
+	if _, ok := box.shares[round][tr.party]; ok || box.delivered[round][tr.party] {
+		return fmt.Errorf("cannot SendShare: party %d already sent a share to party %d for round %q", tr.party, to, round)
+	}
+
+	box.shares[round][tr.party] = data
+
+	close(box.notify)
+	box.notify = make(chan struct{})
+
+	return nil
+}
+
+func (tr *memoryTransport) ReceiveShares(ctx context.Context, round RoundTag, from []uint64) (shares map[uint64][]byte, err error) {
+
+	box := tr.network.mailboxes[tr.party]
+
+	for {
+
+		box.Lock()
+
+		received := box.shares[round]
+		complete := true
+		for _, party := range from {
+			if _, ok := received[party]; !ok {
+				complete = false
+				break
+			}
+		}
+
+		if complete {
+
+			shares = make(map[uint64][]byte, len(from))
+			for _, party := range from {
+				shares[party] = received[party]
+				delete(received, party)
+			}
+
+			// The round is complete if no other share is pending, else its senders are recorded to reject their replays
+			if len(received) == 0 {
+				delete(box.shares, round)
+				delete(box.delivered, round)
+			} else {
+				if box.delivered[round] == nil {
+					box.delivered[round] = make(map[uint64]bool)
+				}
+				for _, party := range from {
+					box.delivered[round][party] = true
+				}
+			}
+
+			box.Unlock()
+
+			return shares, nil
+		}
+
+		notify := box.notify
+
+		box.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package dckks
+
+import (
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// The aggregation methods of the protocols do not check their inputs, and a single share with too few moduli,
+// the wrong degree or unreduced coefficients, e.g. after a faulty transmission, silently corrupts the aggregate. The
+// ValidateShare methods check the received shares before their aggregation. As the shares are pseudo-random modulo
+// the moduli, the coefficients of a valid share are only bounded by their modulus: the checks detect malformed
+// shares, but not well-formed shares generated with wrong inputs.
+
+// validatePolyLvl returns an error if p does not have the degree of r, has less than level+1 moduli or has a
+// coefficient not reduced modulo its modulus for the moduli from q_0 up to q_level.
+func validatePolyLvl(r *ring.Ring, level uint64, p *ring.Poly) error {
+
+	if p == nil {
+		return fmt.Errorf("missing polynomial")
+	}
+
+	if uint64(p.GetLenModuli()) < level+1 || level+1 > uint64(len(r.Modulus)) {
+		return fmt.Errorf("%d moduli for level %d", p.GetLenModuli(), level)
+	}
+
+	for i, qi := range r.Modulus[:level+1] {
+
+		if uint64(len(p.Coeffs[i])) != r.N {
+			return fmt.Errorf("degree %d instead of %d", len(p.Coeffs[i]), r.N)
+		}
+
+		for _, c := range p.Coeffs[i] {
+			if c >= qi {
+				return fmt.Errorf("coefficient not reduced modulo q_%d", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateShare returns an error if the share is not a well-formed share for a ciphertext at the given level.
+func (cks *CKSProtocol) ValidateShare(level uint64, share CKSShare) error {
+	if err := validatePolyLvl(cks.dckksContext.ringQ, level, share.Poly); err != nil {
+		return fmt.Errorf("invalid CKSShare of party %d: %s", share.Party, err)
+	}
+	return nil
+}
+
+// ValidateAndAggregate validates the share with ValidateShare and, if it is well formed, adds it to aggregate for the
+// moduli up to the given level. aggregate is left unchanged if the share is rejected.
+func (cks *CKSProtocol) ValidateAndAggregate(level uint64, share, aggregate CKSShare) error {
+
+	if err := cks.ValidateShare(level, share); err != nil {
+		return err
+	}
+
+	cks.dckksContext.ringQ.AddLvl(level, share.Poly, aggregate.Poly, aggregate.Poly)
+
+	return nil
+}
+
+// ValidateShare returns an error if the share is not a well-formed share for a ciphertext at the given level.
+func (pcks *PCKSProtocol) ValidateShare(level uint64, share PCKSShare) error {
+	for _, p := range share.Value {
+		if err := validatePolyLvl(pcks.dckksContext.ringQ, level, p); err != nil {
+			return fmt.Errorf("invalid PCKSShare of party %d: %s", share.Party, err)
+		}
+	}
+	return nil
+}
+
+// ValidateAndAggregate validates the share with ValidateShare and, if it is well formed, adds it to aggregate for the
+// moduli up to the given level. aggregate is left unchanged if the share is rejected.
+func (pcks *PCKSProtocol) ValidateAndAggregate(level uint64, share, aggregate PCKSShare) error {
+
+	if err := pcks.ValidateShare(level, share); err != nil {
+		return err
+	}
+
+	pcks.dckksContext.ringQ.AddLvl(level, share.Value[0], aggregate.Value[0], aggregate.Value[0])
+	pcks.dckksContext.ringQ.AddLvl(level, share.Value[1], aggregate.Value[1], aggregate.Value[1])
+
+	return nil
+}
+
+// ValidateShare returns an error if the share is not a well-formed share of either round of the protocol, i.e. if it
+// does not have one pair of polynomials of QP per decomposition digit with reduced coefficients.
+func (ekg *RKGProtocol) ValidateShare(share RKGShare) error {
+
+	ringQP := ekg.context.ringQP
+
+	if uint64(len(share.Value)) != ekg.context.params.Beta() {
+		return fmt.Errorf("invalid RKGShare of party %d: %d decomposition digits instead of %d", share.Party, len(share.Value), ekg.context.params.Beta())
+	}
+
+	for i := range share.Value {
+		for _, p := range share.Value[i] {
+			if err := validatePolyLvl(ringQP, uint64(len(ringQP.Modulus)-1), p); err != nil {
+				return fmt.Errorf("invalid RKGShare of party %d: %s", share.Party, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateAndAggregateRoundOne validates the share of the first round with ValidateShare and, if it is well formed,
+// adds it to aggregate. aggregate is left unchanged if the share is rejected.
+func (ekg *RKGProtocol) ValidateAndAggregateRoundOne(share, aggregate RKGShare) error {
+
+	if err := ekg.ValidateShare(share); err != nil {
+		return err
+	}
+
+	ekg.AggregateShareRoundOne(share, aggregate, aggregate)
+
+	return nil
+}
+
+// ValidateAndAggregateRoundTwo validates the share of the second round with ValidateShare and, if it is well formed,
+// adds it to aggregate. aggregate is left unchanged if the share is rejected.
+func (ekg *RKGProtocol) ValidateAndAggregateRoundTwo(share, aggregate RKGShare) error {
+
+	if err := ekg.ValidateShare(share); err != nil {
+		return err
+	}
+
+	ekg.AggregateShareRoundTwo(share, aggregate, aggregate)
+
+	return nil
+}
@@ -81,7 +81,7 @@ func main() {
 	ringQP, _ := ring.NewRing(1<<params.LogN(), append(params.Qi(), params.Pi()...))
 
 	// Common reference polynomial generator that uses the PRNG
-	crsGen := ring.NewUniformSampler(lattigoPRNG, ringQP)
+	crsGen := ring.NewUniformSamplerWithDomain(lattigoPRNG, ringQP, true, true)
 	crs := crsGen.ReadNew()                  // for the public-key
 	crp := make([]*ring.Poly, params.Beta()) // for the relinearization keys
 	for i := uint64(0); i < params.Beta(); i++ {
@@ -5,4 +5,6 @@ go 1.13
 require (
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f
+	google.golang.org/grpc v1.34.0
 )
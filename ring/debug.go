@@ -0,0 +1,71 @@
+package ring
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// The checks below are only called by the operations if debugChecks is set, i.e. if the package is built with the
+// ringdebug build tag, e.g. go test -tags ringdebug, in which case the operations panic on operands whose levels do
+// not match or whose coefficients alias in a way the operation does not support. They are meant to locate silent
+// errors in the code calling the ring operations and are too costly for production builds.
+
+// checkLevel panics if level is not a level of the Ring r, or if one of the polynomials does not have the moduli
+// from q_0 up to q_level or has less than N coefficients.
+func checkLevel(op string, r *Ring, level uint64, polys ...*Poly) {
+
+	if level >= uint64(len(r.Modulus)) {
+		panic(fmt.Sprintf("cannot %s: level %d is larger than the maximum level %d of the ring", op, level, len(r.Modulus)-1))
+	}
+
+	checkPolyLevel(op, r.N, level, polys...)
+}
+
+// checkPolyLevel panics if one of the polynomials does not have the moduli from q_0 up to q_level or has less than N
+// coefficients.
+func checkPolyLevel(op string, N, level uint64, polys ...*Poly) {
+	for i, p := range polys {
+
+		if uint64(len(p.Coeffs)) < level+1 {
+			panic(fmt.Sprintf("cannot %s: operand %d has %d moduli but the operation is at level %d", op, i, len(p.Coeffs), level))
+		}
+
+		for j := uint64(0); j < level+1; j++ {
+			if uint64(len(p.Coeffs[j])) < N {
+				panic(fmt.Sprintf("cannot %s: operand %d has %d coefficients for modulus %d but the degree is %d", op, i, len(p.Coeffs[j]), j, N))
+			}
+		}
+	}
+}
+
+// checkAliasing panics if the first N coefficients of the moduli from q_0 up to q_level of out overlap the ones of one
+// of the inputs, except if elementWise is true and the moduli of out and of the input are the same slices, as an
+// element-wise operation can be computed in place.
+func checkAliasing(op string, N, level uint64, elementWise bool, out *Poly, in ...*Poly) {
+	for k, p := range in {
+		for i := uint64(0); i < level+1; i++ {
+			for j := uint64(0); j < level+1; j++ {
+
+				if !overlaps(out.Coeffs[i][:N], p.Coeffs[j][:N]) {
+					continue
+				}
+
+				if elementWise && i == j && &out.Coeffs[i][0] == &p.Coeffs[j][0] {
+					continue
+				}
+
+				if elementWise {
+					panic(fmt.Sprintf("cannot %s: modulus %d of the output overlaps modulus %d of operand %d without being equal to it", op, i, j, k))
+				}
+
+				panic(fmt.Sprintf("cannot %s: modulus %d of the output overlaps modulus %d of operand %d, the operation cannot be in place", op, i, j, k))
+			}
+		}
+	}
+}
+
+// overlaps returns true if the non-empty slices a and b share some of their elements.
+func overlaps(a, b []uint64) bool {
+	pa, pb := uintptr(unsafe.Pointer(&a[0])), uintptr(unsafe.Pointer(&b[0]))
+	return pa < pb+uintptr(len(b))*8 && pb < pa+uintptr(len(a))*8
+}
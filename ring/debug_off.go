@@ -0,0 +1,7 @@
+// +build !ringdebug
+
+package ring
+
+// debugChecks enables the runtime checks of the levels and of the aliasing of the operands of the operations. They
+// are compiled out unless the package is built with the ringdebug build tag.
+const debugChecks = false
@@ -0,0 +1,7 @@
+// +build ringdebug
+
+package ring
+
+// debugChecks enables the runtime checks of the levels and of the aliasing of the operands of the operations, see
+// checkLevel and checkAliasing.
+const debugChecks = true
@@ -0,0 +1,282 @@
+package ring
+
+import (
+	"unsafe"
+)
+
+// The coefficient-wise kernels below are the inner loops of the arithmetic of the ring on the coefficients of a single
+// modulus. They have an assembly implementation on the architectures that support it, selected at runtime according
+// to the features of the CPU, and the generic implementations below as fallback, which are also used with the purego
+// build tag. The length of the slices must be a multiple of 8, and of 16 for the coefficients of the butterflies of
+// span 1, 2 and 4, which take one root of psi per group of butterflies.
+
+// addVecGeneric writes p1 + p2 mod q on p3, for p1 and p2 in [0, q).
+func addVecGeneric(p1, p2, p3 []uint64, q uint64) {
+	for j := 0; j < len(p3); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		y := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p3[j]))
+
+		z[0] = CRed(x[0]+y[0], q)
+		z[1] = CRed(x[1]+y[1], q)
+		z[2] = CRed(x[2]+y[2], q)
+		z[3] = CRed(x[3]+y[3], q)
+		z[4] = CRed(x[4]+y[4], q)
+		z[5] = CRed(x[5]+y[5], q)
+		z[6] = CRed(x[6]+y[6], q)
+		z[7] = CRed(x[7]+y[7], q)
+	}
+}
+
+// subVecGeneric writes p1 - p2 mod q on p3, for p1 and p2 in [0, q).
+func subVecGeneric(p1, p2, p3 []uint64, q uint64) {
+	for j := 0; j < len(p3); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		y := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p3[j]))
+
+		z[0] = CRed((x[0]+q)-y[0], q)
+		z[1] = CRed((x[1]+q)-y[1], q)
+		z[2] = CRed((x[2]+q)-y[2], q)
+		z[3] = CRed((x[3]+q)-y[3], q)
+		z[4] = CRed((x[4]+q)-y[4], q)
+		z[5] = CRed((x[5]+q)-y[5], q)
+		z[6] = CRed((x[6]+q)-y[6], q)
+		z[7] = CRed((x[7]+q)-y[7], q)
+	}
+}
+
+// mulCoeffsMontgomeryVecGeneric writes p1 * p2 * 2^-64 mod q on p3.
+func mulCoeffsMontgomeryVecGeneric(p1, p2, p3 []uint64, q, qInv uint64) {
+	for j := 0; j < len(p3); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		y := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p3[j]))
+
+		z[0] = MRed(x[0], y[0], q, qInv)
+		z[1] = MRed(x[1], y[1], q, qInv)
+		z[2] = MRed(x[2], y[2], q, qInv)
+		z[3] = MRed(x[3], y[3], q, qInv)
+		z[4] = MRed(x[4], y[4], q, qInv)
+		z[5] = MRed(x[5], y[5], q, qInv)
+		z[6] = MRed(x[6], y[6], q, qInv)
+		z[7] = MRed(x[7], y[7], q, qInv)
+	}
+}
+
+// butterflyVecGeneric computes the lazy butterflies of the NTT of xIn and yIn with the root psi in the Montgomery form,
+// see butterfly, and writes the results on xOut and yOut. The inputs must be in [0, 4q) and the outputs are in [0, 4q).
+func butterflyVecGeneric(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	for j := 0; j < len(xOut); j = j + 8 {
+
+		xin := (*[8]uint64)(unsafe.Pointer(&xIn[j]))
+		yin := (*[8]uint64)(unsafe.Pointer(&yIn[j]))
+
+		xout := (*[8]uint64)(unsafe.Pointer(&xOut[j]))
+		yout := (*[8]uint64)(unsafe.Pointer(&yOut[j]))
+
+		xout[0], yout[0] = butterfly(xin[0], yin[0], psi, q, qInv)
+		xout[1], yout[1] = butterfly(xin[1], yin[1], psi, q, qInv)
+		xout[2], yout[2] = butterfly(xin[2], yin[2], psi, q, qInv)
+		xout[3], yout[3] = butterfly(xin[3], yin[3], psi, q, qInv)
+		xout[4], yout[4] = butterfly(xin[4], yin[4], psi, q, qInv)
+		xout[5], yout[5] = butterfly(xin[5], yin[5], psi, q, qInv)
+		xout[6], yout[6] = butterfly(xin[6], yin[6], psi, q, qInv)
+		xout[7], yout[7] = butterfly(xin[7], yin[7], psi, q, qInv)
+	}
+}
+
+// mFormVecGeneric writes p1 * 2^64 mod q on p2, for p1 in [0, q), where u are the Barrett parameters of q.
+func mFormVecGeneric(p1, p2 []uint64, q uint64, u []uint64) {
+
+	u0, u1 := u[0], u[1]
+
+	for j := 0; j < len(p2); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+
+		z[0] = mForm(x[0], q, u0, u1)
+		z[1] = mForm(x[1], q, u0, u1)
+		z[2] = mForm(x[2], q, u0, u1)
+		z[3] = mForm(x[3], q, u0, u1)
+		z[4] = mForm(x[4], q, u0, u1)
+		z[5] = mForm(x[5], q, u0, u1)
+		z[6] = mForm(x[6], q, u0, u1)
+		z[7] = mForm(x[7], q, u0, u1)
+	}
+}
+
+// invMFormVecGeneric writes p1 * 2^-64 mod q on p2.
+func invMFormVecGeneric(p1, p2 []uint64, q, qInv uint64) {
+	for j := 0; j < len(p2); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+
+		z[0] = InvMForm(x[0], q, qInv)
+		z[1] = InvMForm(x[1], q, qInv)
+		z[2] = InvMForm(x[2], q, qInv)
+		z[3] = InvMForm(x[3], q, qInv)
+		z[4] = InvMForm(x[4], q, qInv)
+		z[5] = InvMForm(x[5], q, qInv)
+		z[6] = InvMForm(x[6], q, qInv)
+		z[7] = InvMForm(x[7], q, qInv)
+	}
+}
+
+// butterfly4VecGeneric computes the lazy butterflies of span 4 of the NTT on coeffs, see butterflyVecGeneric, where each
+// group of 8 coefficients uses the next root of psi. The inputs must be in [0, 4q) and the outputs are in [0, 4q).
+func butterfly4VecGeneric(coeffs, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffs); i, j = i+2, j+16 {
+
+		w := (*[2]uint64)(unsafe.Pointer(&psi[i]))
+		x := (*[16]uint64)(unsafe.Pointer(&coeffs[j]))
+
+		x[0], x[4] = butterfly(x[0], x[4], w[0], q, qInv)
+		x[1], x[5] = butterfly(x[1], x[5], w[0], q, qInv)
+		x[2], x[6] = butterfly(x[2], x[6], w[0], q, qInv)
+		x[3], x[7] = butterfly(x[3], x[7], w[0], q, qInv)
+		x[8], x[12] = butterfly(x[8], x[12], w[1], q, qInv)
+		x[9], x[13] = butterfly(x[9], x[13], w[1], q, qInv)
+		x[10], x[14] = butterfly(x[10], x[14], w[1], q, qInv)
+		x[11], x[15] = butterfly(x[11], x[15], w[1], q, qInv)
+	}
+}
+
+// butterfly2VecGeneric computes the lazy butterflies of span 2 of the NTT on coeffs, see butterfly4VecGeneric.
+func butterfly2VecGeneric(coeffs, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffs); i, j = i+4, j+16 {
+
+		w := (*[4]uint64)(unsafe.Pointer(&psi[i]))
+		x := (*[16]uint64)(unsafe.Pointer(&coeffs[j]))
+
+		x[0], x[2] = butterfly(x[0], x[2], w[0], q, qInv)
+		x[1], x[3] = butterfly(x[1], x[3], w[0], q, qInv)
+		x[4], x[6] = butterfly(x[4], x[6], w[1], q, qInv)
+		x[5], x[7] = butterfly(x[5], x[7], w[1], q, qInv)
+		x[8], x[10] = butterfly(x[8], x[10], w[2], q, qInv)
+		x[9], x[11] = butterfly(x[9], x[11], w[2], q, qInv)
+		x[12], x[14] = butterfly(x[12], x[14], w[3], q, qInv)
+		x[13], x[15] = butterfly(x[13], x[15], w[3], q, qInv)
+	}
+}
+
+// butterfly1VecGeneric computes the lazy butterflies of span 1 of the NTT on coeffs, see butterfly4VecGeneric.
+func butterfly1VecGeneric(coeffs, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffs); i, j = i+8, j+16 {
+
+		w := (*[8]uint64)(unsafe.Pointer(&psi[i]))
+		x := (*[16]uint64)(unsafe.Pointer(&coeffs[j]))
+
+		x[0], x[1] = butterfly(x[0], x[1], w[0], q, qInv)
+		x[2], x[3] = butterfly(x[2], x[3], w[1], q, qInv)
+		x[4], x[5] = butterfly(x[4], x[5], w[2], q, qInv)
+		x[6], x[7] = butterfly(x[6], x[7], w[3], q, qInv)
+		x[8], x[9] = butterfly(x[8], x[9], w[4], q, qInv)
+		x[10], x[11] = butterfly(x[10], x[11], w[5], q, qInv)
+		x[12], x[13] = butterfly(x[12], x[13], w[6], q, qInv)
+		x[14], x[15] = butterfly(x[14], x[15], w[7], q, qInv)
+	}
+}
+
+// invButterflyVecGeneric computes the lazy butterflies of the InvNTT of xIn and yIn with the root psi in the Montgomery
+// form, see invbutterfly, and writes the results on xOut and yOut. The inputs must be in [0, 2q) and the outputs are
+// in [0, 2q].
+func invButterflyVecGeneric(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	for j := 0; j < len(xOut); j = j + 8 {
+
+		xin := (*[8]uint64)(unsafe.Pointer(&xIn[j]))
+		yin := (*[8]uint64)(unsafe.Pointer(&yIn[j]))
+
+		xout := (*[8]uint64)(unsafe.Pointer(&xOut[j]))
+		yout := (*[8]uint64)(unsafe.Pointer(&yOut[j]))
+
+		xout[0], yout[0] = invbutterfly(xin[0], yin[0], psi, q, qInv)
+		xout[1], yout[1] = invbutterfly(xin[1], yin[1], psi, q, qInv)
+		xout[2], yout[2] = invbutterfly(xin[2], yin[2], psi, q, qInv)
+		xout[3], yout[3] = invbutterfly(xin[3], yin[3], psi, q, qInv)
+		xout[4], yout[4] = invbutterfly(xin[4], yin[4], psi, q, qInv)
+		xout[5], yout[5] = invbutterfly(xin[5], yin[5], psi, q, qInv)
+		xout[6], yout[6] = invbutterfly(xin[6], yin[6], psi, q, qInv)
+		xout[7], yout[7] = invbutterfly(xin[7], yin[7], psi, q, qInv)
+	}
+}
+
+// invButterfly4VecGeneric computes the lazy butterflies of span 4 of the InvNTT on coeffs, see invButterflyVecGeneric,
+// where each group of 8 coefficients uses the next root of psi.
+func invButterfly4VecGeneric(coeffs, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffs); i, j = i+2, j+16 {
+
+		w := (*[2]uint64)(unsafe.Pointer(&psi[i]))
+		x := (*[16]uint64)(unsafe.Pointer(&coeffs[j]))
+
+		x[0], x[4] = invbutterfly(x[0], x[4], w[0], q, qInv)
+		x[1], x[5] = invbutterfly(x[1], x[5], w[0], q, qInv)
+		x[2], x[6] = invbutterfly(x[2], x[6], w[0], q, qInv)
+		x[3], x[7] = invbutterfly(x[3], x[7], w[0], q, qInv)
+		x[8], x[12] = invbutterfly(x[8], x[12], w[1], q, qInv)
+		x[9], x[13] = invbutterfly(x[9], x[13], w[1], q, qInv)
+		x[10], x[14] = invbutterfly(x[10], x[14], w[1], q, qInv)
+		x[11], x[15] = invbutterfly(x[11], x[15], w[1], q, qInv)
+	}
+}
+
+// invButterfly2VecGeneric computes the lazy butterflies of span 2 of the InvNTT on coeffs, see invButterfly4VecGeneric.
+func invButterfly2VecGeneric(coeffs, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffs); i, j = i+4, j+16 {
+
+		w := (*[4]uint64)(unsafe.Pointer(&psi[i]))
+		x := (*[16]uint64)(unsafe.Pointer(&coeffs[j]))
+
+		x[0], x[2] = invbutterfly(x[0], x[2], w[0], q, qInv)
+		x[1], x[3] = invbutterfly(x[1], x[3], w[0], q, qInv)
+		x[4], x[6] = invbutterfly(x[4], x[6], w[1], q, qInv)
+		x[5], x[7] = invbutterfly(x[5], x[7], w[1], q, qInv)
+		x[8], x[10] = invbutterfly(x[8], x[10], w[2], q, qInv)
+		x[9], x[11] = invbutterfly(x[9], x[11], w[2], q, qInv)
+		x[12], x[14] = invbutterfly(x[12], x[14], w[3], q, qInv)
+		x[13], x[15] = invbutterfly(x[13], x[15], w[3], q, qInv)
+	}
+}
+
+// invButterfly1VecGeneric computes the lazy butterflies of span 1 of the InvNTT of coeffsIn, see
+// invButterfly4VecGeneric, and writes the results on coeffsOut.
+func invButterfly1VecGeneric(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64) {
+	for i, j := 0, 0; j < len(coeffsOut); i, j = i+8, j+16 {
+
+		w := (*[8]uint64)(unsafe.Pointer(&psi[i]))
+		xin := (*[16]uint64)(unsafe.Pointer(&coeffsIn[j]))
+		xout := (*[16]uint64)(unsafe.Pointer(&coeffsOut[j]))
+
+		xout[0], xout[1] = invbutterfly(xin[0], xin[1], w[0], q, qInv)
+		xout[2], xout[3] = invbutterfly(xin[2], xin[3], w[1], q, qInv)
+		xout[4], xout[5] = invbutterfly(xin[4], xin[5], w[2], q, qInv)
+		xout[6], xout[7] = invbutterfly(xin[6], xin[7], w[3], q, qInv)
+		xout[8], xout[9] = invbutterfly(xin[8], xin[9], w[4], q, qInv)
+		xout[10], xout[11] = invbutterfly(xin[10], xin[11], w[5], q, qInv)
+		xout[12], xout[13] = invbutterfly(xin[12], xin[13], w[6], q, qInv)
+		xout[14], xout[15] = invbutterfly(xin[14], xin[15], w[7], q, qInv)
+	}
+}
+
+// mulScalarMontgomeryVecGeneric writes p1 * scalar * 2^-64 mod q on p2, for scalar in [0, q).
+func mulScalarMontgomeryVecGeneric(p1, p2 []uint64, scalar, q, qInv uint64) {
+	for j := 0; j < len(p2); j = j + 8 {
+
+		x := (*[8]uint64)(unsafe.Pointer(&p1[j]))
+		z := (*[8]uint64)(unsafe.Pointer(&p2[j]))
+
+		z[0] = MRed(x[0], scalar, q, qInv)
+		z[1] = MRed(x[1], scalar, q, qInv)
+		z[2] = MRed(x[2], scalar, q, qInv)
+		z[3] = MRed(x[3], scalar, q, qInv)
+		z[4] = MRed(x[4], scalar, q, qInv)
+		z[5] = MRed(x[5], scalar, q, qInv)
+		z[6] = MRed(x[6], scalar, q, qInv)
+		z[7] = MRed(x[7], scalar, q, qInv)
+	}
+}
@@ -0,0 +1,232 @@
+// +build !purego
+
+package ring
+
+import (
+	"golang.org/x/sys/cpu"
+)
+
+// useAVX2 selects the AVX2 implementations of the kernels. The AVX2 kernels reduce their values with signed
+// comparisons, which is correct for values in [0, 4q) for all the supported moduli.
+var useAVX2 = cpu.X86.HasAVX2
+
+// useAVX512 selects the AVX-512 implementations of the kernels that have one, before the AVX2 ones. Their Montgomery
+// products take the low halves of the 64-bit products with VPMULLQ, hence they also require AVX512DQ. It is false with
+// the noavx512 build tag, see kernels_noavx512_amd64.go.
+var useAVX512 = cpu.X86.HasAVX512F && cpu.X86.HasAVX512DQ
+
+//go:noescape
+func addVecAVX2(p1, p2, p3 []uint64, q uint64)
+
+//go:noescape
+func subVecAVX2(p1, p2, p3 []uint64, q uint64)
+
+//go:noescape
+func butterflyVecAVX2(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func butterfly4VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly2VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly1VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterflyVecAVX2(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func invButterfly4VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly2VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly1VecAVX2(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func mulCoeffsMontgomeryVecAVX512(p1, p2, p3 []uint64, q, qInv uint64)
+
+//go:noescape
+func mulScalarMontgomeryVecAVX512(p1, p2 []uint64, scalar, q, qInv uint64)
+
+//go:noescape
+func mFormVecAVX512(p1, p2 []uint64, q, u0, u1 uint64)
+
+//go:noescape
+func invMFormVecAVX512(p1, p2 []uint64, q, qInv uint64)
+
+//go:noescape
+func butterflyVecAVX512(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func butterfly4VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly2VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly1VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterflyVecAVX512(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func invButterfly4VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly2VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly1VecAVX512(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+
+func addVec(p1, p2, p3 []uint64, q uint64) {
+	if useAVX2 {
+		addVecAVX2(p1, p2, p3, q)
+		return
+	}
+	addVecGeneric(p1, p2, p3, q)
+}
+
+func subVec(p1, p2, p3 []uint64, q uint64) {
+	if useAVX2 {
+		subVecAVX2(p1, p2, p3, q)
+		return
+	}
+	subVecGeneric(p1, p2, p3, q)
+}
+
+// The Montgomery products have no AVX2 implementation: without a 64-bit multiplication, each of their three 64-bit
+// products costs three or four VPMULUDQ on four lanes, which is no faster than the generic kernels, and the AVX2
+// kernels of the NTT are only about as fast as the generic ones. On eight lanes, the AVX-512 kernels of the NTT, of
+// MForm and of InvMForm are about twice as fast as the generic ones, which makes the NTT and the InvNTT 2.5 to 3 times
+// faster for N = 2^14. The Montgomery product of two vectors needs one more 64-bit product than the one by a root, and
+// is only 1.2 to 1.5 times faster, see BenchmarkKernels.
+func mulCoeffsMontgomeryVec(p1, p2, p3 []uint64, q, qInv uint64) {
+	if useAVX512 {
+		mulCoeffsMontgomeryVecAVX512(p1, p2, p3, q, qInv)
+		return
+	}
+	mulCoeffsMontgomeryVecGeneric(p1, p2, p3, q, qInv)
+}
+
+func butterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	if useAVX512 {
+		butterflyVecAVX512(xIn, yIn, xOut, yOut, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		butterflyVecAVX2(xIn, yIn, xOut, yOut, psi, q, qInv)
+		return
+	}
+	butterflyVecGeneric(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func butterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		butterfly4VecAVX512(coeffs, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		butterfly4VecAVX2(coeffs, psi, q, qInv)
+		return
+	}
+	butterfly4VecGeneric(coeffs, psi, q, qInv)
+}
+
+func butterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		butterfly2VecAVX512(coeffs, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		butterfly2VecAVX2(coeffs, psi, q, qInv)
+		return
+	}
+	butterfly2VecGeneric(coeffs, psi, q, qInv)
+}
+
+func butterfly1Vec(coeffs, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		butterfly1VecAVX512(coeffs, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		butterfly1VecAVX2(coeffs, psi, q, qInv)
+		return
+	}
+	butterfly1VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	if useAVX512 {
+		invButterflyVecAVX512(xIn, yIn, xOut, yOut, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		invButterflyVecAVX2(xIn, yIn, xOut, yOut, psi, q, qInv)
+		return
+	}
+	invButterflyVecGeneric(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func invButterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		invButterfly4VecAVX512(coeffs, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		invButterfly4VecAVX2(coeffs, psi, q, qInv)
+		return
+	}
+	invButterfly4VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		invButterfly2VecAVX512(coeffs, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		invButterfly2VecAVX2(coeffs, psi, q, qInv)
+		return
+	}
+	invButterfly2VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterfly1Vec(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64) {
+	if useAVX512 {
+		invButterfly1VecAVX512(coeffsIn, coeffsOut, psi, q, qInv)
+		return
+	}
+	if useAVX2 {
+		invButterfly1VecAVX2(coeffsIn, coeffsOut, psi, q, qInv)
+		return
+	}
+	invButterfly1VecGeneric(coeffsIn, coeffsOut, psi, q, qInv)
+}
+
+func mulScalarMontgomeryVec(p1, p2 []uint64, scalar, q, qInv uint64) {
+	if useAVX512 {
+		mulScalarMontgomeryVecAVX512(p1, p2, scalar, q, qInv)
+		return
+	}
+	mulScalarMontgomeryVecGeneric(p1, p2, scalar, q, qInv)
+}
+
+func mFormVec(p1, p2 []uint64, q uint64, u []uint64) {
+	if useAVX512 {
+		mFormVecAVX512(p1, p2, q, u[0], u[1])
+		return
+	}
+	mFormVecGeneric(p1, p2, q, u)
+}
+
+func invMFormVec(p1, p2 []uint64, q, qInv uint64) {
+	if useAVX512 {
+		invMFormVecAVX512(p1, p2, q, qInv)
+		return
+	}
+	invMFormVecGeneric(p1, p2, q, qInv)
+}
@@ -0,0 +1,497 @@
+// +build !purego
+
+#include "textflag.h"
+
+// The kernels process four coefficients per iteration, one per 64-bit lane of a YMM register. The comparisons are
+// signed, which is correct as long as the compared values are smaller than 2^63.
+
+// func addVecAVX2(p1, p2, p3 []uint64, q uint64)
+TEXT ·addVecAVX2(SB), NOSPLIT, $0-80
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DI
+	MOVQ p3_base+48(FP), DX
+	MOVQ p3_len+56(FP), CX
+	SHRQ $2, CX
+
+	VPBROADCASTQ q+72(FP), Y15
+
+	// Y14 = q - 1
+	VPCMPEQQ Y13, Y13, Y13
+	VPADDQ   Y13, Y15, Y14
+
+	TESTQ CX, CX
+	JZ    addDone
+
+addLoop:
+	VMOVDQU (SI), Y0
+	VMOVDQU (DI), Y1
+	VPADDQ  Y1, Y0, Y0
+
+	// z = x + y - q if x + y > q - 1
+	VPCMPGTQ Y14, Y0, Y1
+	VPAND    Y15, Y1, Y1
+	VPSUBQ   Y1, Y0, Y0
+	VMOVDQU  Y0, (DX)
+
+	ADDQ $32, SI
+	ADDQ $32, DI
+	ADDQ $32, DX
+	DECQ CX
+	JNZ  addLoop
+
+addDone:
+	VZEROUPPER
+	RET
+
+// func subVecAVX2(p1, p2, p3 []uint64, q uint64)
+TEXT ·subVecAVX2(SB), NOSPLIT, $0-80
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DI
+	MOVQ p3_base+48(FP), DX
+	MOVQ p3_len+56(FP), CX
+	SHRQ $2, CX
+
+	VPBROADCASTQ q+72(FP), Y15
+
+	// Y14 = q - 1
+	VPCMPEQQ Y13, Y13, Y13
+	VPADDQ   Y13, Y15, Y14
+
+	TESTQ CX, CX
+	JZ    subDone
+
+subLoop:
+	VMOVDQU (SI), Y0
+	VMOVDQU (DI), Y1
+	VPADDQ  Y15, Y0, Y0
+	VPSUBQ  Y1, Y0, Y0
+
+	// z = x + q - y - q if x + q - y > q - 1
+	VPCMPGTQ Y14, Y0, Y1
+	VPAND    Y15, Y1, Y1
+	VPSUBQ   Y1, Y0, Y0
+	VMOVDQU  Y0, (DX)
+
+	ADDQ $32, SI
+	ADDQ $32, DI
+	ADDQ $32, DX
+	DECQ CX
+	JNZ  subLoop
+
+subDone:
+	VZEROUPPER
+	RET
+
+// The NTT kernels below multiply by the roots with a Montgomery product computed from the 32-bit halves of the operands
+// with VPMULUDQ, as AVX2 has no 64-bit multiplication. The root being a constant, psi * qInv is computed once per root,
+// so that lo(V * psi) * qInv is the low product of V and psi * qInv and the low half of V * psi is never needed. The
+// lazy values are reduced by comparing their difference with 2q to zero, which is correct for values in [0, 4q) as
+// long as 2q < 2^63, that is for all the supported moduli.
+//
+// The kernels use the following registers:
+//   Y15, Y14: q and q >> 32
+//   Y13, Y12: the roots psi and psi >> 32
+//   Y11, Y10: psi * qInv mod 2^64 and (psi * qInv mod 2^64) >> 32
+//   Y9: 2^32 - 1
+//   Y8: 2q
+//   Y7: the first input of the butterflies
+//   Y6: zero
+//   Y0 to Y5: temporaries
+
+// NTTCONSTANTS loads q, q >> 32, 2^32 - 1, 2q and zero in each lane of Y15, Y14, Y9, Y8 and Y6.
+#define NTTCONSTANTS(q) \
+	VPBROADCASTQ q, Y15;        \
+	VPSRLQ       $32, Y15, Y14; \
+	VPCMPEQQ     Y9, Y9, Y9;    \
+	VPSRLQ       $32, Y9, Y9;   \
+	VPADDQ       Y15, Y15, Y8;  \
+	VPXOR        Y6, Y6, Y6
+
+// BROADCASTROOT loads the root psi and psi * qInv mod 2^64 in each lane of Y13 and Y11, and their high halves in Y12
+// and Y10, given psi and qInv in general purpose registers. psi is clobbered.
+#define BROADCASTROOT(psi, qInv) \
+	VMOVQ        psi, X13;      \
+	VPBROADCASTQ X13, Y13;      \
+	VPSRLQ       $32, Y13, Y12; \
+	IMULQ        qInv, psi;     \
+	VMOVQ        psi, X11;      \
+	VPBROADCASTQ X11, Y11;      \
+	VPSRLQ       $32, Y11, Y10
+
+// LANEROOTS computes psi * qInv mod 2^64 for the roots in the lanes of Y13 on Y11, and the high halves of both on Y12
+// and Y10. Y0, Y1 and Y7 are clobbered.
+#define LANEROOTS(qInv) \
+	VPSRLQ       $32, Y13, Y12; \
+	VPBROADCASTQ qInv, Y7;      \
+	VPSRLQ       $32, Y7, Y6;   \
+	VPMULUDQ     Y7, Y13, Y11;  \
+	VPMULUDQ     Y6, Y13, Y0;   \
+	VPMULUDQ     Y7, Y12, Y1;   \
+	VPADDQ       Y1, Y0, Y0;    \
+	VPSLLQ       $32, Y0, Y0;   \
+	VPADDQ       Y0, Y11, Y11;  \
+	VPSRLQ       $32, Y11, Y10; \
+	VPXOR        Y6, Y6, Y6
+
+// MREDCONSTANT computes the Montgomery products V * psi * 2^-64 mod q in [0, 2q) of the lanes of V and of the roots in
+// Y13, see MRedConstant, given the high halves Vh of V, and writes them on r. It computes
+// r = hi(V * psi) - hi(lo(V * psi * qInv) * q) + q. V, Vh, t0, t1 and t2 are clobbered.
+#define MREDCONSTANT(V, Vh, r, t0, t1, t2) \
+	VPMULUDQ Y13, V, t0;  \
+	VPMULUDQ Y12, V, t1;  \
+	VPMULUDQ Y13, Vh, t2; \
+	VPMULUDQ Y12, Vh, r;  \
+	VPSRLQ   $32, t0, t0; \
+	VPADDQ   t2, t0, t0;  \
+	VPAND    Y9, t0, t2;  \
+	VPADDQ   t1, t2, t2;  \
+	VPSRLQ   $32, t0, t0; \
+	VPADDQ   t0, r, r;    \
+	VPSRLQ   $32, t2, t2; \
+	VPADDQ   t2, r, r;    \
+	VPMULUDQ Y11, V, t0;  \
+	VPMULUDQ Y10, V, t1;  \
+	VPMULUDQ Y11, Vh, t2; \
+	VPADDQ   t2, t1, t1;  \
+	VPSLLQ   $32, t1, t1; \
+	VPADDQ   t1, t0, t0;  \
+	VPSRLQ   $32, t0, Vh; \
+	VPMULUDQ Y15, t0, t1; \
+	VPMULUDQ Y14, t0, t2; \
+	VPMULUDQ Y15, Vh, V;  \
+	VPMULUDQ Y14, Vh, t0; \
+	VPSRLQ   $32, t1, t1; \
+	VPADDQ   V, t1, t1;   \
+	VPAND    Y9, t1, V;   \
+	VPADDQ   t2, V, V;    \
+	VPSRLQ   $32, t1, t1; \
+	VPADDQ   t1, t0, t0;  \
+	VPSRLQ   $32, V, V;   \
+	VPADDQ   V, t0, t0;   \
+	VPSUBQ   t0, r, r;    \
+	VPADDQ   Y15, r, r
+
+// REDUCE2Q subtracts 2q from the lanes of x greater than 2q. t is clobbered.
+#define REDUCE2Q(x, t) \
+	VPSUBQ   Y8, x, t; \
+	VPCMPGTQ Y6, t, t; \
+	VPAND    Y8, t, t; \
+	VPSUBQ   t, x, x
+
+// BUTTERFLY computes the butterflies of the NTT of U in Y7 and V in Y0, see butterfly, and writes U + V * psi and
+// U - V * psi on Y0 and Y1. Y2 to Y5 and Y7 are clobbered.
+#define BUTTERFLY \
+	VPSRLQ $32, Y0, Y1;                      \
+	MREDCONSTANT(Y0, Y1, Y2, Y3, Y4, Y5);    \
+	REDUCE2Q(Y7, Y3);                        \
+	VPADDQ Y2, Y7, Y0;                       \
+	VPADDQ Y8, Y7, Y7;                       \
+	VPSUBQ Y2, Y7, Y1
+
+// INVBUTTERFLY computes the butterflies of the InvNTT of U in Y7 and V in Y0, see invbutterfly, and writes U + V and
+// (U - V) * psi on Y7 and Y2. Y0, Y1 and Y3 to Y5 are clobbered.
+#define INVBUTTERFLY \
+	VPADDQ Y8, Y7, Y1;                       \
+	VPSUBQ Y0, Y1, Y1;                       \
+	VPADDQ Y0, Y7, Y7;                       \
+	REDUCE2Q(Y7, Y3);                        \
+	VPSRLQ $32, Y1, Y0;                      \
+	MREDCONSTANT(Y1, Y0, Y2, Y3, Y4, Y5)
+
+// func butterflyVecAVX2(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·butterflyVecAVX2(SB), NOSPLIT, $0-120
+	MOVQ xIn_base+0(FP), SI
+	MOVQ yIn_base+24(FP), DI
+	MOVQ xOut_base+48(FP), DX
+	MOVQ xOut_len+56(FP), CX
+	MOVQ yOut_base+72(FP), R8
+	MOVQ psi+96(FP), AX
+	MOVQ qInv+112(FP), BX
+	SHRQ $2, CX
+
+	NTTCONSTANTS(q+104(FP))
+	BROADCASTROOT(AX, BX)
+
+	TESTQ CX, CX
+	JZ    butterflyDone
+
+butterflyLoop:
+	VMOVDQU (SI), Y7
+	VMOVDQU (DI), Y0
+
+	BUTTERFLY
+
+	VMOVDQU Y0, (DX)
+	VMOVDQU Y1, (R8)
+
+	ADDQ $32, SI
+	ADDQ $32, DI
+	ADDQ $32, DX
+	ADDQ $32, R8
+	DECQ CX
+	JNZ  butterflyLoop
+
+butterflyDone:
+	VZEROUPPER
+	RET
+
+// func butterfly4VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly4VecAVX2(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	MOVQ qInv+56(FP), BX
+
+	NTTCONSTANTS(q+48(FP))
+
+	TESTQ CX, CX
+	JZ    butterfly4Done
+
+butterfly4Loop:
+	// One group [x0 x1 x2 x3 y0 y1 y2 y3] per iteration
+	MOVQ (DI), AX
+	BROADCASTROOT(AX, BX)
+
+	VMOVDQU (SI), Y7
+	VMOVDQU 32(SI), Y0
+
+	BUTTERFLY
+
+	VMOVDQU Y0, (SI)
+	VMOVDQU Y1, 32(SI)
+
+	ADDQ $8, DI
+	ADDQ $64, SI
+	DECQ CX
+	JNZ  butterfly4Loop
+
+butterfly4Done:
+	VZEROUPPER
+	RET
+
+// func butterfly2VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly2VecAVX2(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $1, CX
+
+	NTTCONSTANTS(q+48(FP))
+
+	TESTQ CX, CX
+	JZ    butterfly2Done
+
+butterfly2Loop:
+	// Two groups [x0 x1 y0 y1] [x2 x3 y2 y3] per iteration, with the roots [p0 p0 p1 p1]
+	VBROADCASTI128 (DI), Y13
+	VPERMQ         $0x50, Y13, Y13
+	LANEROOTS(qInv+56(FP))
+
+	VMOVDQU    (SI), Y2
+	VMOVDQU    32(SI), Y3
+	VPERM2I128 $0x20, Y3, Y2, Y7
+	VPERM2I128 $0x31, Y3, Y2, Y0
+
+	BUTTERFLY
+
+	VPERM2I128 $0x20, Y1, Y0, Y2
+	VPERM2I128 $0x31, Y1, Y0, Y3
+	VMOVDQU    Y2, (SI)
+	VMOVDQU    Y3, 32(SI)
+
+	ADDQ $16, DI
+	ADDQ $64, SI
+	DECQ CX
+	JNZ  butterfly2Loop
+
+butterfly2Done:
+	VZEROUPPER
+	RET
+
+// func butterfly1VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly1VecAVX2(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $2, CX
+
+	NTTCONSTANTS(q+48(FP))
+
+	TESTQ CX, CX
+	JZ    butterfly1Done
+
+butterfly1Loop:
+	// Four groups [x0 y0 x1 y1] [x2 y2 x3 y3] per iteration, unpacked in [x0 x2 x1 x3] and [y0 y2 y1 y3], with the
+	// roots [p0 p2 p1 p3]
+	VMOVDQU (DI), Y13
+	VPERMQ  $0xD8, Y13, Y13
+	LANEROOTS(qInv+56(FP))
+
+	VMOVDQU     (SI), Y2
+	VMOVDQU     32(SI), Y3
+	VPUNPCKLQDQ Y3, Y2, Y7
+	VPUNPCKHQDQ Y3, Y2, Y0
+
+	BUTTERFLY
+
+	VPUNPCKLQDQ Y1, Y0, Y2
+	VPUNPCKHQDQ Y1, Y0, Y3
+	VMOVDQU     Y2, (SI)
+	VMOVDQU     Y3, 32(SI)
+
+	ADDQ $32, DI
+	ADDQ $64, SI
+	DECQ CX
+	JNZ  butterfly1Loop
+
+butterfly1Done:
+	VZEROUPPER
+	RET
+
+// func invButterflyVecAVX2(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·invButterflyVecAVX2(SB), NOSPLIT, $0-120
+	MOVQ xIn_base+0(FP), SI
+	MOVQ yIn_base+24(FP), DI
+	MOVQ xOut_base+48(FP), DX
+	MOVQ xOut_len+56(FP), CX
+	MOVQ yOut_base+72(FP), R8
+	MOVQ psi+96(FP), AX
+	MOVQ qInv+112(FP), BX
+	SHRQ $2, CX
+
+	NTTCONSTANTS(q+104(FP))
+	BROADCASTROOT(AX, BX)
+
+	TESTQ CX, CX
+	JZ    invButterflyDone
+
+invButterflyLoop:
+	VMOVDQU (SI), Y7
+	VMOVDQU (DI), Y0
+
+	INVBUTTERFLY
+
+	VMOVDQU Y7, (DX)
+	VMOVDQU Y2, (R8)
+
+	ADDQ $32, SI
+	ADDQ $32, DI
+	ADDQ $32, DX
+	ADDQ $32, R8
+	DECQ CX
+	JNZ  invButterflyLoop
+
+invButterflyDone:
+	VZEROUPPER
+	RET
+
+// func invButterfly4VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly4VecAVX2(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	MOVQ qInv+56(FP), BX
+
+	NTTCONSTANTS(q+48(FP))
+
+	TESTQ CX, CX
+	JZ    invButterfly4Done
+
+invButterfly4Loop:
+	MOVQ (DI), AX
+	BROADCASTROOT(AX, BX)
+
+	VMOVDQU (SI), Y7
+	VMOVDQU 32(SI), Y0
+
+	INVBUTTERFLY
+
+	VMOVDQU Y7, (SI)
+	VMOVDQU Y2, 32(SI)
+
+	ADDQ $8, DI
+	ADDQ $64, SI
+	DECQ CX
+	JNZ  invButterfly4Loop
+
+invButterfly4Done:
+	VZEROUPPER
+	RET
+
+// func invButterfly2VecAVX2(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly2VecAVX2(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $1, CX
+
+	NTTCONSTANTS(q+48(FP))
+
+	TESTQ CX, CX
+	JZ    invButterfly2Done
+
+invButterfly2Loop:
+	VBROADCASTI128 (DI), Y13
+	VPERMQ         $0x50, Y13, Y13
+	LANEROOTS(qInv+56(FP))
+
+	VMOVDQU    (SI), Y2
+	VMOVDQU    32(SI), Y3
+	VPERM2I128 $0x20, Y3, Y2, Y7
+	VPERM2I128 $0x31, Y3, Y2, Y0
+
+	INVBUTTERFLY
+
+	VPERM2I128 $0x20, Y2, Y7, Y0
+	VPERM2I128 $0x31, Y2, Y7, Y1
+	VMOVDQU    Y0, (SI)
+	VMOVDQU    Y1, 32(SI)
+
+	ADDQ $16, DI
+	ADDQ $64, SI
+	DECQ CX
+	JNZ  invButterfly2Loop
+
+invButterfly2Done:
+	VZEROUPPER
+	RET
+
+// func invButterfly1VecAVX2(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly1VecAVX2(SB), NOSPLIT, $0-88
+	MOVQ coeffsIn_base+0(FP), SI
+	MOVQ coeffsOut_base+24(FP), DX
+	MOVQ psi_base+48(FP), DI
+	MOVQ psi_len+56(FP), CX
+	SHRQ $2, CX
+
+	NTTCONSTANTS(q+72(FP))
+
+	TESTQ CX, CX
+	JZ    invButterfly1Done
+
+invButterfly1Loop:
+	VMOVDQU (DI), Y13
+	VPERMQ  $0xD8, Y13, Y13
+	LANEROOTS(qInv+80(FP))
+
+	VMOVDQU     (SI), Y2
+	VMOVDQU     32(SI), Y3
+	VPUNPCKLQDQ Y3, Y2, Y7
+	VPUNPCKHQDQ Y3, Y2, Y0
+
+	INVBUTTERFLY
+
+	VPUNPCKLQDQ Y2, Y7, Y0
+	VPUNPCKHQDQ Y2, Y7, Y1
+	VMOVDQU     Y0, (DX)
+	VMOVDQU     Y1, 32(DX)
+
+	ADDQ $32, DI
+	ADDQ $64, SI
+	ADDQ $64, DX
+	DECQ CX
+	JNZ  invButterfly1Loop
+
+invButterfly1Done:
+	VZEROUPPER
+	RET
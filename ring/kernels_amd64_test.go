@@ -0,0 +1,204 @@
+// +build !purego
+
+package ring
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelsAVX2(t *testing.T) {
+
+	if !useAVX2 {
+		t.Skip("AVX2 not supported")
+	}
+
+	n := uint64(1 << 10)
+
+	for _, q := range []uint64{0x3fffffffef8001, 0x1fffffffffe00001, 0x3fffffffffc00001} {
+
+		qInv := MRedParams(q)
+
+		t.Run(fmt.Sprintf("logQ=%d", bits.Len64(q)), func(t *testing.T) {
+
+			p1, p2 := randomVec(n, q), randomVec(n, q)
+			want, have := make([]uint64, n), make([]uint64, n)
+
+			addVecGeneric(p1, p2, want, q)
+			addVecAVX2(p1, p2, have, q)
+			assert.Equal(t, want, have, "addVecAVX2")
+
+			subVecGeneric(p1, p2, want, q)
+			subVecAVX2(p1, p2, have, q)
+			assert.Equal(t, want, have, "subVecAVX2")
+
+			// The lazy butterflies of the NTT take their inputs in [0, 4q) and those of the InvNTT in [0, 2q)
+			x, y := randomVec(n, 4*q), randomVec(n, 4*q)
+			psi := MForm(rand.Uint64()%q, q, BRedParams(q))
+			wantY, haveY := make([]uint64, n), make([]uint64, n)
+
+			butterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			butterflyVecAVX2(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "butterflyVecAVX2")
+			assert.Equal(t, wantY, haveY, "butterflyVecAVX2")
+
+			x, y = randomVec(n, 2*q), randomVec(n, 2*q)
+
+			invButterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			invButterflyVecAVX2(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "invButterflyVecAVX2")
+			assert.Equal(t, wantY, haveY, "invButterflyVecAVX2")
+		})
+	}
+}
+
+func TestKernelsAVX512(t *testing.T) {
+
+	if !useAVX512 {
+		t.Skip("AVX-512 not supported")
+	}
+
+	n := uint64(1 << 10)
+
+	for _, q := range []uint64{0x3fffffffef8001, 0x1fffffffffe00001, 0x3fffffffffc00001} {
+
+		qInv := MRedParams(q)
+		u := BRedParams(q)
+
+		t.Run(fmt.Sprintf("logQ=%d", bits.Len64(q)), func(t *testing.T) {
+
+			p1, p2 := randomVec(n, q), randomVec(n, q)
+			want, have := make([]uint64, n), make([]uint64, n)
+
+			mulCoeffsMontgomeryVecGeneric(p1, p2, want, q, qInv)
+			mulCoeffsMontgomeryVecAVX512(p1, p2, have, q, qInv)
+			assert.Equal(t, want, have, "mulCoeffsMontgomeryVecAVX512")
+
+			mFormVecGeneric(p1, want, q, u)
+			mFormVecAVX512(p1, have, q, u[0], u[1])
+			assert.Equal(t, want, have, "mFormVecAVX512")
+
+			invMFormVecGeneric(p1, want, q, qInv)
+			invMFormVecAVX512(p1, have, q, qInv)
+			assert.Equal(t, want, have, "invMFormVecAVX512")
+
+			// The butterflies take their inputs up to 2^62, as the NTT of unreduced inputs, for which the generic
+			// kernels subtract 2q once; the equality with 2q is checked on purpose
+			x, y := randomVec(n, 1<<62), randomVec(n, 1<<62)
+			x[0], y[1] = 2*q, 2*q
+			psi := MForm(rand.Uint64()%q, q, u)
+			wantY, haveY := make([]uint64, n), make([]uint64, n)
+
+			butterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			butterflyVecAVX512(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "butterflyVecAVX512")
+			assert.Equal(t, wantY, haveY, "butterflyVecAVX512")
+
+			invButterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			invButterflyVecAVX512(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "invButterflyVecAVX512")
+			assert.Equal(t, wantY, haveY, "invButterflyVecAVX512")
+
+			for _, k := range []struct {
+				span                  uint64
+				generic, avx512       func(coeffs, psi []uint64, q, qInv uint64)
+				invGeneric, invAVX512 func(coeffs, psi []uint64, q, qInv uint64)
+			}{
+				{4, butterfly4VecGeneric, butterfly4VecAVX512, invButterfly4VecGeneric, invButterfly4VecAVX512},
+				{2, butterfly2VecGeneric, butterfly2VecAVX512, invButterfly2VecGeneric, invButterfly2VecAVX512},
+				{1, butterfly1VecGeneric, butterfly1VecAVX512, nil, nil},
+			} {
+
+				roots := make([]uint64, n/(2*k.span))
+				for i := range roots {
+					roots[i] = MForm(rand.Uint64()%q, q, u)
+				}
+
+				for _, inverse := range []bool{false, true} {
+
+					copy(want, x)
+					copy(have, x)
+
+					switch {
+					case !inverse:
+						k.generic(want, roots, q, qInv)
+						k.avx512(have, roots, q, qInv)
+					case k.invAVX512 == nil:
+						invButterfly1VecGeneric(x, want, roots, q, qInv)
+						invButterfly1VecAVX512(x, have, roots, q, qInv)
+					default:
+						k.invGeneric(want, roots, q, qInv)
+						k.invAVX512(have, roots, q, qInv)
+					}
+
+					assert.Equal(t, want, have, fmt.Sprintf("span=%d/inverse=%t", k.span, inverse))
+				}
+			}
+
+			mulScalarMontgomeryVecGeneric(x, want, psi, q, qInv)
+			mulScalarMontgomeryVecAVX512(x, have, psi, q, qInv)
+			assert.Equal(t, want, have, "mulScalarMontgomeryVecAVX512")
+		})
+	}
+}
+
+func BenchmarkKernels(b *testing.B) {
+
+	n := uint64(1 << 14)
+	q := uint64(0x1fffffffffe00001)
+
+	qInv := MRedParams(q)
+	psi := MForm(rand.Uint64()%q, q, BRedParams(q))
+
+	p1, p2, p3 := randomVec(n, q), randomVec(n, q), make([]uint64, n)
+	roots := randomVec(n/2, q)
+
+	u := BRedParams(q)
+
+	// The kernels without an AVX2 or AVX-512 implementation have a nil function
+	kernels := []struct {
+		name    string
+		generic func()
+		avx2    func()
+		avx512  func()
+	}{
+		{"Add", func() { addVecGeneric(p1, p2, p3, q) }, func() { addVecAVX2(p1, p2, p3, q) }, nil},
+		{"Sub", func() { subVecGeneric(p1, p2, p3, q) }, func() { subVecAVX2(p1, p2, p3, q) }, nil},
+		{"MulCoeffsMontgomery", func() { mulCoeffsMontgomeryVecGeneric(p1, p2, p3, q, qInv) }, nil, func() { mulCoeffsMontgomeryVecAVX512(p1, p2, p3, q, qInv) }},
+		{"MulScalarMontgomery", func() { mulScalarMontgomeryVecGeneric(p1, p3, psi, q, qInv) }, nil, func() { mulScalarMontgomeryVecAVX512(p1, p3, psi, q, qInv) }},
+		{"MForm", func() { mFormVecGeneric(p1, p3, q, u) }, nil, func() { mFormVecAVX512(p1, p3, q, u[0], u[1]) }},
+		{"InvMForm", func() { invMFormVecGeneric(p1, p3, q, qInv) }, nil, func() { invMFormVecAVX512(p1, p3, q, qInv) }},
+		{"Butterfly", func() { butterflyVecGeneric(p1, p2, p1, p2, psi, q, qInv) }, func() { butterflyVecAVX2(p1, p2, p1, p2, psi, q, qInv) }, func() { butterflyVecAVX512(p1, p2, p1, p2, psi, q, qInv) }},
+		{"Butterfly1", func() { butterfly1VecGeneric(p1, roots, q, qInv) }, func() { butterfly1VecAVX2(p1, roots, q, qInv) }, func() { butterfly1VecAVX512(p1, roots, q, qInv) }},
+		{"InvButterfly", func() { invButterflyVecGeneric(p1, p2, p1, p2, psi, q, qInv) }, func() { invButterflyVecAVX2(p1, p2, p1, p2, psi, q, qInv) }, func() { invButterflyVecAVX512(p1, p2, p1, p2, psi, q, qInv) }},
+	}
+
+	for _, k := range kernels {
+
+		b.Run(fmt.Sprintf("%s/Generic/N=%d", k.name, n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k.generic()
+			}
+		})
+
+		if useAVX2 && k.avx2 != nil {
+			b.Run(fmt.Sprintf("%s/AVX2/N=%d", k.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					k.avx2()
+				}
+			})
+		}
+
+		if useAVX512 && k.avx512 != nil {
+			b.Run(fmt.Sprintf("%s/AVX512/N=%d", k.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					k.avx512()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// +build neon,!purego
+
+package ring
+
+// The NEON kernels are only built with the neon build tag, and the generic kernels are used on arm64 without it. They
+// do not need to be selected at runtime, as Advanced SIMD is mandatory on arm64. The conversions to and from the
+// Montgomery form use the generic kernels.
+
+//go:noescape
+func addVecNEON(p1, p2, p3 []uint64, q uint64)
+
+//go:noescape
+func subVecNEON(p1, p2, p3 []uint64, q uint64)
+
+//go:noescape
+func mulCoeffsMontgomeryVecNEON(p1, p2, p3 []uint64, q, qInv uint64)
+
+//go:noescape
+func mulScalarMontgomeryVecNEON(p1, p2 []uint64, scalar, q, qInv uint64)
+
+//go:noescape
+func butterflyVecNEON(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func butterfly4VecNEON(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly2VecNEON(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func butterfly1VecNEON(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterflyVecNEON(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+
+//go:noescape
+func invButterfly4VecNEON(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly2VecNEON(coeffs, psi []uint64, q, qInv uint64)
+
+//go:noescape
+func invButterfly1VecNEON(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+
+func addVec(p1, p2, p3 []uint64, q uint64) {
+	addVecNEON(p1, p2, p3, q)
+}
+
+func subVec(p1, p2, p3 []uint64, q uint64) {
+	subVecNEON(p1, p2, p3, q)
+}
+
+func mulCoeffsMontgomeryVec(p1, p2, p3 []uint64, q, qInv uint64) {
+	mulCoeffsMontgomeryVecNEON(p1, p2, p3, q, qInv)
+}
+
+func butterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	butterflyVecNEON(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func butterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly4VecNEON(coeffs, psi, q, qInv)
+}
+
+func butterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly2VecNEON(coeffs, psi, q, qInv)
+}
+
+func butterfly1Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly1VecNEON(coeffs, psi, q, qInv)
+}
+
+func invButterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	invButterflyVecNEON(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func invButterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	invButterfly4VecNEON(coeffs, psi, q, qInv)
+}
+
+func invButterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	invButterfly2VecNEON(coeffs, psi, q, qInv)
+}
+
+func invButterfly1Vec(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64) {
+	invButterfly1VecNEON(coeffsIn, coeffsOut, psi, q, qInv)
+}
+
+func mulScalarMontgomeryVec(p1, p2 []uint64, scalar, q, qInv uint64) {
+	mulScalarMontgomeryVecNEON(p1, p2, scalar, q, qInv)
+}
+
+func mFormVec(p1, p2 []uint64, q uint64, u []uint64) {
+	mFormVecGeneric(p1, p2, q, u)
+}
+
+func invMFormVec(p1, p2 []uint64, q, qInv uint64) {
+	invMFormVecGeneric(p1, p2, q, qInv)
+}
@@ -0,0 +1,554 @@
+// +build neon,!purego
+
+#include "textflag.h"
+
+// The NEON kernels process four coefficients per step, two per 128-bit register. They compute the same values as the
+// generic kernels, also for unreduced inputs, as the comparisons are unsigned.
+//
+// As NEON has no 64-bit multiplication, the Montgomery products are computed from the 32-bit halves of the operands:
+// the low and high halves of the four coefficients of a step are gathered with UZP1 and UZP2 in the four lanes of
+// two registers, the products of the halves of the lanes 0 and 1 are taken with UMULL and UMLAL, and those of the
+// lanes 2 and 3 with UMULL2 and UMLAL2. The roots are also held per lane, such that the butterflies of span 4, 2 and
+// 1 only differ from the others by the loading of their coefficients and of their roots.
+//
+// The kernels use the following registers:
+//   V31, V30: q and 2q
+//   V29, V28: the low and high halves of q in each lane
+//   V27, V26: the low and high halves of qInv in each lane
+//   V25: 2^32 - 1
+//   V24, V23: the low and high halves of the roots psi of the lanes
+//   V22, V21: the low and high halves of psi * qInv mod 2^64
+//   V0 to V20: the coefficients and temporaries
+//
+// As the assemblers of the Go versions supported by the module do not know most of the NEON instructions used by the
+// kernels, they are encoded with WORD by the macros below, which take the numbers of the registers as operands, in the
+// same order as the Go assembler: VADD_D2(m, n, d) computes Vd.D2 = Vn.D2 + Vm.D2. The first operand of VDUP_D2 and of
+// the loads and stores with post-increment is the number of a general purpose register Rn.
+
+#define VADD_D2(m, n, d) WORD $(0x4ee08400 | (m)<<16 | (n)<<5 | (d))
+#define VSUB_D2(m, n, d) WORD $(0x6ee08400 | (m)<<16 | (n)<<5 | (d))
+#define VAND_B16(m, n, d) WORD $(0x4e201c00 | (m)<<16 | (n)<<5 | (d))
+#define VCMEQ_D2(m, n, d) WORD $(0x6ee08c00 | (m)<<16 | (n)<<5 | (d))
+#define VCMHI_D2(m, n, d) WORD $(0x6ee03400 | (m)<<16 | (n)<<5 | (d))
+#define VCMHS_D2(m, n, d) WORD $(0x6ee03c00 | (m)<<16 | (n)<<5 | (d))
+#define VUZP1_S4(m, n, d) WORD $(0x4e801800 | (m)<<16 | (n)<<5 | (d))
+#define VUZP2_S4(m, n, d) WORD $(0x4e805800 | (m)<<16 | (n)<<5 | (d))
+#define VMLA_S4(m, n, d) WORD $(0x4ea09400 | (m)<<16 | (n)<<5 | (d))
+#define VUMULL_S2(m, n, d) WORD $(0x2ea0c000 | (m)<<16 | (n)<<5 | (d))
+#define VUMULL2_S4(m, n, d) WORD $(0x6ea0c000 | (m)<<16 | (n)<<5 | (d))
+#define VUMLAL_S2(m, n, d) WORD $(0x2ea08000 | (m)<<16 | (n)<<5 | (d))
+#define VUMLAL2_S4(m, n, d) WORD $(0x6ea08000 | (m)<<16 | (n)<<5 | (d))
+#define VDUP_D2(n, d) WORD $(0x4e080c00 | (n)<<5 | (d))
+#define VUSHR_D2(shift, n, d) WORD $(0x6f000400 | (128-(shift))<<16 | (n)<<5 | (d))
+#define VUSRA_D2(shift, n, d) WORD $(0x6f001400 | (128-(shift))<<16 | (n)<<5 | (d))
+
+// VLD1R_P_D2 loads the uint64 at Rn in the two lanes of Vt and increments Rn by 8.
+#define VLD1R_P_D2(n, t) WORD $(0x4ddfcc00 | (n)<<5 | (t))
+
+// VLD2_P_D2 loads the four uint64 at Rn, the even ones in Vt and the odd ones in Vt+1, and increments Rn by 32.
+#define VLD2_P_D2(n, t) WORD $(0x4cdf8c00 | (n)<<5 | (t))
+
+// VST2_P_D2 stores the lanes of Vt and Vt+1 interleaved at Rn and increments Rn by 32.
+#define VST2_P_D2(n, t) WORD $(0x4c9f8c00 | (n)<<5 | (t))
+
+// CONSTANTSNEON loads q, 2q, the halves of q and qInv and 2^32 - 1 in V31 to V25, given the numbers of the general
+// purpose registers holding q and qInv.
+#define CONSTANTSNEON(q, qInv) \
+	VDUP_D2(q, 31);       \
+	VADD_D2(31, 31, 30);  \
+	VUZP1_S4(31, 31, 29); \
+	VUZP2_S4(31, 31, 28); \
+	VDUP_D2(qInv, 26);    \
+	VUZP1_S4(26, 26, 27); \
+	VUZP2_S4(26, 26, 26); \
+	VCMEQ_D2(25, 25, 25); \
+	VUSHR_D2(32, 25, 25)
+
+// MULLONEON computes the halves rl and rh of the low halves of the products of the lanes of a and b, given the halves al,
+// ah, bl and bh of their lanes. t0 and t1 are clobbered.
+#define MULLONEON(al, ah, bl, bh, rl, rh, t0, t1) \
+	VUMULL_S2(bl, al, t0);  \
+	VUMULL2_S4(bl, al, t1); \
+	VUZP1_S4(t1, t0, rl);   \
+	VUZP2_S4(t1, t0, rh);   \
+	VMLA_S4(bh, al, rh);    \
+	VMLA_S4(bl, ah, rh)
+
+// MULHIHALFNEON computes the high halves of the products of the lanes 0 and 1 of a and b, with mul = VUMULL_S2 and
+// mla = VUMLAL_S2, or of the lanes 2 and 3, with mul = VUMULL2_S4 and mla = VUMLAL2_S4, given the halves al, ah, bl and
+// bh of their lanes, and writes them on r. V19 and V20 are clobbered.
+#define MULHIHALFNEON(mul, mla, al, ah, bl, bh, r) \
+	mul(bl, al, 19);      \
+	VUSHR_D2(32, 19, 19); \
+	mla(bl, ah, 19);      \
+	VAND_B16(25, 19, 20); \
+	VUSHR_D2(32, 19, r);  \
+	mla(bh, al, 20);      \
+	mla(bh, ah, r);       \
+	VUSRA_D2(32, 20, r)
+
+// MULHINEON computes the high halves of the products of the lanes of a and b, given the halves al, ah, bl and bh of
+// their lanes, and writes those of the lanes 0 and 1 on r0 and those of the lanes 2 and 3 on r1. V19 and V20 are
+// clobbered.
+#define MULHINEON(al, ah, bl, bh, r0, r1) \
+	MULHIHALFNEON(VUMULL_S2, VUMLAL_S2, al, ah, bl, bh, r0); \
+	MULHIHALFNEON(VUMULL2_S4, VUMLAL2_S4, al, ah, bl, bh, r1)
+
+// LANEROOTSNEON computes the halves of the roots psi of the lanes, given in r0 for the lanes 0 and 1 and in r1 for
+// the lanes 2 and 3, and of psi * qInv mod 2^64 on V24 to V21. V14 and V15 are clobbered.
+#define LANEROOTSNEON(r0, r1) \
+	VUZP1_S4(r1, r0, 24); \
+	VUZP2_S4(r1, r0, 23); \
+	MULLONEON(24, 23, 27, 26, 22, 21, 14, 15)
+
+// MREDCONSTANTNEON computes the Montgomery products V * psi * 2^-64 mod q in [0, 2q) of the coefficients in v0 and v1
+// and of the roots of their lanes, see MRedConstant, and writes them on r0 and r1. It computes
+// r = hi(V * psi) - hi(lo(V * psi * qInv) * q) + q. V4 to V15, V19 and V20 are clobbered.
+#define MREDCONSTANTNEON(v0, v1, r0, r1) \
+	VUZP1_S4(v1, v0, 4);                   \
+	VUZP2_S4(v1, v0, 5);                   \
+	MULHINEON(4, 5, 24, 23, 6, 7);         \
+	MULLONEON(4, 5, 22, 21, 8, 9, 14, 15); \
+	MULHINEON(8, 9, 29, 28, 10, 11);       \
+	VSUB_D2(10, 6, r0);                    \
+	VSUB_D2(11, 7, r1);                    \
+	VADD_D2(31, r0, r0);                   \
+	VADD_D2(31, r1, r1)
+
+// REDUCENEON subtracts q from the lanes of r greater or equal to q. t is clobbered.
+#define REDUCENEON(r, t) \
+	VCMHS_D2(31, r, t); \
+	VAND_B16(31, t, t); \
+	VSUB_D2(t, r, r)
+
+// BUTTERFLYNEON computes the butterflies of the NTT of U in u0 and u1 and V in v0 and v1, see butterfly, and writes
+// U + V * psi on u0 and u1 and U - V * psi on v0 and v1. V4 to V20 are clobbered.
+#define BUTTERFLYNEON(u0, u1, v0, v1) \
+	MREDCONSTANTNEON(v0, v1, v0, v1); \
+	VCMHI_D2(30, u0, 16);             \
+	VCMHI_D2(30, u1, 17);             \
+	VAND_B16(30, 16, 16);             \
+	VAND_B16(30, 17, 17);             \
+	VSUB_D2(16, u0, u0);              \
+	VSUB_D2(17, u1, u1);              \
+	VADD_D2(30, u0, 16);              \
+	VADD_D2(30, u1, 17);              \
+	VADD_D2(v0, u0, u0);              \
+	VADD_D2(v1, u1, u1);              \
+	VSUB_D2(v0, 16, v0);              \
+	VSUB_D2(v1, 17, v1)
+
+// INVBUTTERFLYNEON computes the butterflies of the InvNTT of U in u0 and u1 and V in v0 and v1, see invbutterfly, and
+// writes U + V on u0 and u1 and (U - V) * psi on v0 and v1. V4 to V20 are clobbered.
+#define INVBUTTERFLYNEON(u0, u1, v0, v1) \
+	VADD_D2(30, u0, 16);  \
+	VADD_D2(30, u1, 17);  \
+	VSUB_D2(v0, 16, 16);  \
+	VSUB_D2(v1, 17, 17);  \
+	VADD_D2(v0, u0, u0);  \
+	VADD_D2(v1, u1, u1);  \
+	VCMHI_D2(30, u0, v0); \
+	VCMHI_D2(30, u1, v1); \
+	VAND_B16(30, v0, v0); \
+	VAND_B16(30, v1, v1); \
+	VSUB_D2(v0, u0, u0);  \
+	VSUB_D2(v1, u1, u1);  \
+	MREDCONSTANTNEON(16, 17, v0, v1)
+
+// func addVecNEON(p1, p2, p3 []uint64, q uint64)
+TEXT ·addVecNEON(SB), NOSPLIT, $0-80
+	MOVD p1_base+0(FP), R0
+	MOVD p2_base+24(FP), R1
+	MOVD p3_base+48(FP), R2
+	MOVD p3_len+56(FP), R3
+	MOVD q+72(FP), R4
+	LSR  $2, R3
+
+	VDUP_D2(4, 31)
+
+	CBZ R3, addDone
+
+addLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+	VLD1.P 32(R1), [V2.D2, V3.D2]
+	VADD_D2(2, 0, 0)
+	VADD_D2(3, 1, 1)
+
+	// z = x + y - q if x + y >= q
+	REDUCENEON(0, 4)
+	REDUCENEON(1, 5)
+
+	VST1.P [V0.D2, V1.D2], 32(R2)
+
+	SUBS $1, R3, R3
+	BNE  addLoop
+
+addDone:
+	RET
+
+// func subVecNEON(p1, p2, p3 []uint64, q uint64)
+TEXT ·subVecNEON(SB), NOSPLIT, $0-80
+	MOVD p1_base+0(FP), R0
+	MOVD p2_base+24(FP), R1
+	MOVD p3_base+48(FP), R2
+	MOVD p3_len+56(FP), R3
+	MOVD q+72(FP), R4
+	LSR  $2, R3
+
+	VDUP_D2(4, 31)
+
+	CBZ R3, subDone
+
+subLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+	VLD1.P 32(R1), [V2.D2, V3.D2]
+	VADD_D2(31, 0, 0)
+	VADD_D2(31, 1, 1)
+	VSUB_D2(2, 0, 0)
+	VSUB_D2(3, 1, 1)
+
+	// z = x + q - y - q if x + q - y >= q
+	REDUCENEON(0, 4)
+	REDUCENEON(1, 5)
+
+	VST1.P [V0.D2, V1.D2], 32(R2)
+
+	SUBS $1, R3, R3
+	BNE  subLoop
+
+subDone:
+	RET
+
+// func mulCoeffsMontgomeryVecNEON(p1, p2, p3 []uint64, q, qInv uint64)
+TEXT ·mulCoeffsMontgomeryVecNEON(SB), NOSPLIT, $0-88
+	MOVD p1_base+0(FP), R0
+	MOVD p2_base+24(FP), R1
+	MOVD p3_base+48(FP), R2
+	MOVD p3_len+56(FP), R3
+	MOVD q+72(FP), R4
+	MOVD qInv+80(FP), R5
+	LSR  $2, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, mulCoeffsDone
+
+mulCoeffsLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+	VLD1.P 32(R1), [V2.D2, V3.D2]
+
+	// The halves of x and y
+	VUZP1_S4(1, 0, 4)
+	VUZP2_S4(1, 0, 5)
+	VUZP1_S4(3, 2, 16)
+	VUZP2_S4(3, 2, 17)
+
+	// r = hi(x * y) - hi(lo(lo(x * y) * qInv) * q) + q
+	MULHINEON(4, 5, 16, 17, 6, 7)
+	MULLONEON(4, 5, 16, 17, 8, 9, 14, 15)
+	MULLONEON(8, 9, 27, 26, 12, 13, 14, 15)
+	MULHINEON(12, 13, 29, 28, 10, 11)
+	VSUB_D2(10, 6, 0)
+	VSUB_D2(11, 7, 1)
+	VADD_D2(31, 0, 0)
+	VADD_D2(31, 1, 1)
+
+	REDUCENEON(0, 2)
+	REDUCENEON(1, 3)
+
+	VST1.P [V0.D2, V1.D2], 32(R2)
+
+	SUBS $1, R3, R3
+	BNE  mulCoeffsLoop
+
+mulCoeffsDone:
+	RET
+
+// func mulScalarMontgomeryVecNEON(p1, p2 []uint64, scalar, q, qInv uint64)
+TEXT ·mulScalarMontgomeryVecNEON(SB), NOSPLIT, $0-72
+	MOVD p1_base+0(FP), R0
+	MOVD p2_base+24(FP), R1
+	MOVD p2_len+32(FP), R3
+	MOVD scalar+48(FP), R6
+	MOVD q+56(FP), R4
+	MOVD qInv+64(FP), R5
+	LSR  $2, R3
+
+	CONSTANTSNEON(4, 5)
+
+	// The scalar is used as the root of each lane
+	VDUP_D2(6, 16)
+	LANEROOTSNEON(16, 16)
+
+	CBZ R3, mulScalarDone
+
+mulScalarLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+
+	MREDCONSTANTNEON(0, 1, 0, 1)
+	REDUCENEON(0, 2)
+	REDUCENEON(1, 3)
+
+	VST1.P [V0.D2, V1.D2], 32(R1)
+
+	SUBS $1, R3, R3
+	BNE  mulScalarLoop
+
+mulScalarDone:
+	RET
+
+// func butterflyVecNEON(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·butterflyVecNEON(SB), NOSPLIT, $0-120
+	MOVD xIn_base+0(FP), R0
+	MOVD yIn_base+24(FP), R1
+	MOVD xOut_base+48(FP), R2
+	MOVD xOut_len+56(FP), R3
+	MOVD yOut_base+72(FP), R6
+	MOVD psi+96(FP), R7
+	MOVD q+104(FP), R4
+	MOVD qInv+112(FP), R5
+	LSR  $2, R3
+
+	CONSTANTSNEON(4, 5)
+
+	VDUP_D2(7, 16)
+	LANEROOTSNEON(16, 16)
+
+	CBZ R3, butterflyDone
+
+butterflyLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+	VLD1.P 32(R1), [V2.D2, V3.D2]
+
+	BUTTERFLYNEON(0, 1, 2, 3)
+
+	VST1.P [V0.D2, V1.D2], 32(R2)
+	VST1.P [V2.D2, V3.D2], 32(R6)
+
+	SUBS $1, R3, R3
+	BNE  butterflyLoop
+
+butterflyDone:
+	RET
+
+// The butterflies of span 4, 2 and 1 take eight coefficients per step. For the span 4, the first inputs of the
+// butterflies of a step are its four first coefficients, and use the same root. For the span 2, they are the
+// coefficients 0, 1, 4 and 5, and the coefficients 0 and 1 use the first root of the step and 4 and 5 the second.
+// For the span 1, they are the even coefficients, which are separated from the odd ones with VLD2, and each uses its
+// own root.
+
+// func butterfly4VecNEON(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly4VecNEON(SB), NOSPLIT, $0-64
+	MOVD coeffs_base+0(FP), R0
+	MOVD coeffs_len+8(FP), R3
+	MOVD psi_base+24(FP), R1
+	MOVD q+48(FP), R4
+	MOVD qInv+56(FP), R5
+	LSR  $3, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, butterfly4Done
+
+butterfly4Loop:
+	VLD1R_P_D2(1, 16)
+	LANEROOTSNEON(16, 16)
+
+	VLD1 (R0), [V0.D2, V1.D2, V2.D2, V3.D2]
+
+	BUTTERFLYNEON(0, 1, 2, 3)
+
+	VST1.P [V0.D2, V1.D2, V2.D2, V3.D2], 64(R0)
+
+	SUBS $1, R3, R3
+	BNE  butterfly4Loop
+
+butterfly4Done:
+	RET
+
+// func butterfly2VecNEON(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly2VecNEON(SB), NOSPLIT, $0-64
+	MOVD coeffs_base+0(FP), R0
+	MOVD coeffs_len+8(FP), R3
+	MOVD psi_base+24(FP), R1
+	MOVD q+48(FP), R4
+	MOVD qInv+56(FP), R5
+	LSR  $3, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, butterfly2Done
+
+butterfly2Loop:
+	VLD1R_P_D2(1, 16)
+	VLD1R_P_D2(1, 17)
+	LANEROOTSNEON(16, 17)
+
+	VLD1 (R0), [V0.D2, V1.D2, V2.D2, V3.D2]
+
+	BUTTERFLYNEON(0, 2, 1, 3)
+
+	VST1.P [V0.D2, V1.D2, V2.D2, V3.D2], 64(R0)
+
+	SUBS $1, R3, R3
+	BNE  butterfly2Loop
+
+butterfly2Done:
+	RET
+
+// func butterfly1VecNEON(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly1VecNEON(SB), NOSPLIT, $0-64
+	MOVD coeffs_base+0(FP), R0
+	MOVD coeffs_len+8(FP), R3
+	MOVD psi_base+24(FP), R1
+	MOVD q+48(FP), R4
+	MOVD qInv+56(FP), R5
+	LSR  $3, R3
+	MOVD R0, R2
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, butterfly1Done
+
+butterfly1Loop:
+	VLD1.P 32(R1), [V16.D2, V17.D2]
+	LANEROOTSNEON(16, 17)
+
+	VLD2_P_D2(0, 0)
+	VLD2_P_D2(0, 2)
+
+	BUTTERFLYNEON(0, 2, 1, 3)
+
+	VST2_P_D2(2, 0)
+	VST2_P_D2(2, 2)
+
+	SUBS $1, R3, R3
+	BNE  butterfly1Loop
+
+butterfly1Done:
+	RET
+
+// func invButterflyVecNEON(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·invButterflyVecNEON(SB), NOSPLIT, $0-120
+	MOVD xIn_base+0(FP), R0
+	MOVD yIn_base+24(FP), R1
+	MOVD xOut_base+48(FP), R2
+	MOVD xOut_len+56(FP), R3
+	MOVD yOut_base+72(FP), R6
+	MOVD psi+96(FP), R7
+	MOVD q+104(FP), R4
+	MOVD qInv+112(FP), R5
+	LSR  $2, R3
+
+	CONSTANTSNEON(4, 5)
+
+	VDUP_D2(7, 16)
+	LANEROOTSNEON(16, 16)
+
+	CBZ R3, invButterflyDone
+
+invButterflyLoop:
+	VLD1.P 32(R0), [V0.D2, V1.D2]
+	VLD1.P 32(R1), [V2.D2, V3.D2]
+
+	INVBUTTERFLYNEON(0, 1, 2, 3)
+
+	VST1.P [V0.D2, V1.D2], 32(R2)
+	VST1.P [V2.D2, V3.D2], 32(R6)
+
+	SUBS $1, R3, R3
+	BNE  invButterflyLoop
+
+invButterflyDone:
+	RET
+
+// func invButterfly4VecNEON(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly4VecNEON(SB), NOSPLIT, $0-64
+	MOVD coeffs_base+0(FP), R0
+	MOVD coeffs_len+8(FP), R3
+	MOVD psi_base+24(FP), R1
+	MOVD q+48(FP), R4
+	MOVD qInv+56(FP), R5
+	LSR  $3, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, invButterfly4Done
+
+invButterfly4Loop:
+	VLD1R_P_D2(1, 16)
+	LANEROOTSNEON(16, 16)
+
+	VLD1 (R0), [V0.D2, V1.D2, V2.D2, V3.D2]
+
+	INVBUTTERFLYNEON(0, 1, 2, 3)
+
+	VST1.P [V0.D2, V1.D2, V2.D2, V3.D2], 64(R0)
+
+	SUBS $1, R3, R3
+	BNE  invButterfly4Loop
+
+invButterfly4Done:
+	RET
+
+// func invButterfly2VecNEON(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly2VecNEON(SB), NOSPLIT, $0-64
+	MOVD coeffs_base+0(FP), R0
+	MOVD coeffs_len+8(FP), R3
+	MOVD psi_base+24(FP), R1
+	MOVD q+48(FP), R4
+	MOVD qInv+56(FP), R5
+	LSR  $3, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, invButterfly2Done
+
+invButterfly2Loop:
+	VLD1R_P_D2(1, 16)
+	VLD1R_P_D2(1, 17)
+	LANEROOTSNEON(16, 17)
+
+	VLD1 (R0), [V0.D2, V1.D2, V2.D2, V3.D2]
+
+	INVBUTTERFLYNEON(0, 2, 1, 3)
+
+	VST1.P [V0.D2, V1.D2, V2.D2, V3.D2], 64(R0)
+
+	SUBS $1, R3, R3
+	BNE  invButterfly2Loop
+
+invButterfly2Done:
+	RET
+
+// func invButterfly1VecNEON(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly1VecNEON(SB), NOSPLIT, $0-88
+	MOVD coeffsIn_base+0(FP), R0
+	MOVD coeffsOut_base+24(FP), R2
+	MOVD coeffsOut_len+32(FP), R3
+	MOVD psi_base+48(FP), R1
+	MOVD q+72(FP), R4
+	MOVD qInv+80(FP), R5
+	LSR  $3, R3
+
+	CONSTANTSNEON(4, 5)
+
+	CBZ R3, invButterfly1Done
+
+invButterfly1Loop:
+	VLD1.P 32(R1), [V16.D2, V17.D2]
+	LANEROOTSNEON(16, 17)
+
+	VLD2_P_D2(0, 0)
+	VLD2_P_D2(0, 2)
+
+	INVBUTTERFLYNEON(0, 2, 1, 3)
+
+	VST2_P_D2(2, 0)
+	VST2_P_D2(2, 2)
+
+	SUBS $1, R3, R3
+	BNE  invButterfly1Loop
+
+invButterfly1Done:
+	RET
@@ -0,0 +1,49 @@
+// +build neon,!purego
+
+package ring
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func BenchmarkKernels(b *testing.B) {
+
+	n := uint64(1 << 14)
+	q := uint64(0x1fffffffffe00001)
+
+	qInv := MRedParams(q)
+	psi := MForm(rand.Uint64()%q, q, BRedParams(q))
+
+	p1, p2, p3 := randomVec(n, q), randomVec(n, q), make([]uint64, n)
+	roots := randomVec(n/2, q)
+
+	kernels := []struct {
+		name          string
+		generic, neon func()
+	}{
+		{"Add", func() { addVecGeneric(p1, p2, p3, q) }, func() { addVecNEON(p1, p2, p3, q) }},
+		{"Sub", func() { subVecGeneric(p1, p2, p3, q) }, func() { subVecNEON(p1, p2, p3, q) }},
+		{"MulCoeffsMontgomery", func() { mulCoeffsMontgomeryVecGeneric(p1, p2, p3, q, qInv) }, func() { mulCoeffsMontgomeryVecNEON(p1, p2, p3, q, qInv) }},
+		{"MulScalarMontgomery", func() { mulScalarMontgomeryVecGeneric(p1, p3, psi, q, qInv) }, func() { mulScalarMontgomeryVecNEON(p1, p3, psi, q, qInv) }},
+		{"Butterfly", func() { butterflyVecGeneric(p1, p2, p1, p2, psi, q, qInv) }, func() { butterflyVecNEON(p1, p2, p1, p2, psi, q, qInv) }},
+		{"Butterfly1", func() { butterfly1VecGeneric(p1, roots, q, qInv) }, func() { butterfly1VecNEON(p1, roots, q, qInv) }},
+		{"InvButterfly", func() { invButterflyVecGeneric(p1, p2, p1, p2, psi, q, qInv) }, func() { invButterflyVecNEON(p1, p2, p1, p2, psi, q, qInv) }},
+	}
+
+	for _, k := range kernels {
+
+		b.Run(fmt.Sprintf("%s/Generic/N=%d", k.name, n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k.generic()
+			}
+		})
+
+		b.Run(fmt.Sprintf("%s/NEON/N=%d", k.name, n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k.neon()
+			}
+		})
+	}
+}
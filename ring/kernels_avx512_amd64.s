@@ -0,0 +1,602 @@
+// +build !purego
+
+#include "textflag.h"
+
+// The AVX-512 kernels process eight coefficients per iteration, one per 64-bit lane of a ZMM register, and sixteen for
+// the butterflies of span 4, 2 and 1. They compute the same values as the generic kernels, also for unreduced inputs:
+// the conditional subtractions use unsigned comparisons, with a mask where the generic kernels compare strictly and
+// with VPMINUQ where they subtract q from values greater or equal to q.
+//
+// As AVX-512 has no high product of 64-bit integers, the high halves of the products are computed from the 32-bit
+// halves of the operands with VPMULUDQ, and the low halves with VPMULLQ, which requires AVX512DQ.
+//
+// The kernels use the following registers:
+//   Z15, Z14: q and q >> 32
+//   Z13, Z12: the roots psi and psi >> 32
+//   Z11: psi * qInv mod 2^64
+//   Z10: qInv
+//   Z9: 2^32 - 1
+//   Z8: 2q
+//   Z16: the permutation of the roots of the butterflies of span 4, 2 and 1
+//   Z0 to Z7: temporaries
+
+// The permutations of the roots of the butterflies of span 4, 2 and 1 on the lanes of the first inputs of the
+// butterflies, see the kernels.
+DATA rootsSpan4<>+0(SB)/8, $0
+DATA rootsSpan4<>+8(SB)/8, $0
+DATA rootsSpan4<>+16(SB)/8, $0
+DATA rootsSpan4<>+24(SB)/8, $0
+DATA rootsSpan4<>+32(SB)/8, $1
+DATA rootsSpan4<>+40(SB)/8, $1
+DATA rootsSpan4<>+48(SB)/8, $1
+DATA rootsSpan4<>+56(SB)/8, $1
+GLOBL rootsSpan4<>(SB), RODATA|NOPTR, $64
+
+DATA rootsSpan2<>+0(SB)/8, $0
+DATA rootsSpan2<>+8(SB)/8, $0
+DATA rootsSpan2<>+16(SB)/8, $1
+DATA rootsSpan2<>+24(SB)/8, $1
+DATA rootsSpan2<>+32(SB)/8, $2
+DATA rootsSpan2<>+40(SB)/8, $2
+DATA rootsSpan2<>+48(SB)/8, $3
+DATA rootsSpan2<>+56(SB)/8, $3
+GLOBL rootsSpan2<>(SB), RODATA|NOPTR, $64
+
+DATA rootsSpan1<>+0(SB)/8, $0
+DATA rootsSpan1<>+8(SB)/8, $4
+DATA rootsSpan1<>+16(SB)/8, $1
+DATA rootsSpan1<>+24(SB)/8, $5
+DATA rootsSpan1<>+32(SB)/8, $2
+DATA rootsSpan1<>+40(SB)/8, $6
+DATA rootsSpan1<>+48(SB)/8, $3
+DATA rootsSpan1<>+56(SB)/8, $7
+GLOBL rootsSpan1<>(SB), RODATA|NOPTR, $64
+
+// CONSTANTS512 loads q, q >> 32, qInv, 2^32 - 1 and 2q in each lane of Z15, Z14, Z10, Z9 and Z8.
+#define CONSTANTS512(q, qInv) \
+	VPBROADCASTQ q, Z15;          \
+	VPSRLQ       $32, Z15, Z14;   \
+	VPBROADCASTQ qInv, Z10;       \
+	VPTERNLOGQ   $0xff, Z9, Z9, Z9; \
+	VPSRLQ       $32, Z9, Z9;     \
+	VPADDQ       Z15, Z15, Z8
+
+// BROADCASTROOT512 loads the root psi in each lane of Z13, and computes Z12 and Z11, given psi in a general purpose
+// register.
+#define BROADCASTROOT512(psi) \
+	VPBROADCASTQ psi, Z13;    \
+	LANEROOTS512
+
+// LANEROOTS512 computes psi >> 32 and psi * qInv mod 2^64 for the roots in the lanes of Z13 on Z12 and Z11.
+#define LANEROOTS512 \
+	VPSRLQ  $32, Z13, Z12; \
+	VPMULLQ Z10, Z13, Z11
+
+// MULHI512 computes the high halves of the products of the lanes of a and b, given their high halves ah and bh, and
+// writes them on r. t0, t1 and t2 are clobbered.
+#define MULHI512(a, ah, b, bh, r, t0, t1, t2) \
+	VPMULUDQ b, a, t0;    \
+	VPMULUDQ bh, a, t1;   \
+	VPMULUDQ b, ah, t2;   \
+	VPMULUDQ bh, ah, r;   \
+	VPSRLQ   $32, t0, t0; \
+	VPADDQ   t2, t0, t0;  \
+	VPANDQ   Z9, t0, t2;  \
+	VPADDQ   t1, t2, t2;  \
+	VPSRLQ   $32, t0, t0; \
+	VPADDQ   t0, r, r;    \
+	VPSRLQ   $32, t2, t2; \
+	VPADDQ   t2, r, r
+
+// MREDCONSTANT512 computes the Montgomery products V * psi * 2^-64 mod q in [0, 2q) of the lanes of V and of the roots
+// in Z13, see MRedConstant, given the high halves Vh of V, and writes them on r. It computes
+// r = hi(V * psi) - hi(lo(V * psi * qInv) * q) + q. V, Vh and t0 to t3 are clobbered.
+#define MREDCONSTANT512(V, Vh, r, t0, t1, t2, t3) \
+	MULHI512(V, Vh, Z13, Z12, r, t0, t1, t2); \
+	VPMULLQ Z11, V, t3;                       \
+	VPSRLQ  $32, t3, Vh;                      \
+	MULHI512(t3, Vh, Z15, Z14, t0, V, t1, t2); \
+	VPSUBQ  t0, r, r;                         \
+	VPADDQ  Z15, r, r
+
+// BUTTERFLY512 computes the butterflies of the NTT of U in Z7 and V in Z0, see butterfly, and writes U + V * psi and
+// U - V * psi on Z0 and Z1. Z2 to Z7 and K1 are clobbered.
+#define BUTTERFLY512 \
+	VPSRLQ  $32, Z0, Z1;                         \
+	MREDCONSTANT512(Z0, Z1, Z2, Z3, Z4, Z5, Z6); \
+	VPCMPUQ $6, Z8, Z7, K1;                      \
+	VPSUBQ  Z8, Z7, K1, Z7;                      \
+	VPADDQ  Z2, Z7, Z0;                          \
+	VPADDQ  Z8, Z7, Z7;                          \
+	VPSUBQ  Z2, Z7, Z1
+
+// INVBUTTERFLY512 computes the butterflies of the InvNTT of U in Z7 and V in Z0, see invbutterfly, and writes U + V
+// and (U - V) * psi on Z7 and Z2. Z0, Z1, Z3 to Z6 and K1 are clobbered.
+#define INVBUTTERFLY512 \
+	VPADDQ  Z8, Z7, Z1;                          \
+	VPSUBQ  Z0, Z1, Z1;                          \
+	VPADDQ  Z0, Z7, Z7;                          \
+	VPCMPUQ $6, Z8, Z7, K1;                      \
+	VPSUBQ  Z8, Z7, K1, Z7;                      \
+	VPSRLQ  $32, Z1, Z0;                         \
+	MREDCONSTANT512(Z1, Z0, Z2, Z3, Z4, Z5, Z6)
+
+// func mulCoeffsMontgomeryVecAVX512(p1, p2, p3 []uint64, q, qInv uint64)
+TEXT ·mulCoeffsMontgomeryVecAVX512(SB), NOSPLIT, $0-88
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DI
+	MOVQ p3_base+48(FP), DX
+	MOVQ p3_len+56(FP), CX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+72(FP), qInv+80(FP))
+
+	TESTQ CX, CX
+	JZ    mulCoeffsDone
+
+mulCoeffsLoop:
+	VMOVDQU64 (SI), Z0
+	VMOVDQU64 (DI), Z1
+	VPSRLQ    $32, Z0, Z2
+	VPSRLQ    $32, Z1, Z3
+
+	// hi(x * y) on Z4, from the products of the halves of x and y
+	VPMULUDQ Z1, Z0, Z5
+	VPMULUDQ Z3, Z0, Z6
+	VPMULUDQ Z1, Z2, Z7
+	VPMULUDQ Z3, Z2, Z4
+
+	// m = lo(x * y) * qInv on Z0
+	VPADDQ  Z7, Z6, Z0
+	VPSLLQ  $32, Z0, Z0
+	VPADDQ  Z5, Z0, Z0
+	VPMULLQ Z10, Z0, Z0
+
+	VPSRLQ $32, Z5, Z5
+	VPADDQ Z7, Z5, Z5
+	VPANDQ Z9, Z5, Z7
+	VPADDQ Z6, Z7, Z7
+	VPSRLQ $32, Z5, Z5
+	VPADDQ Z5, Z4, Z4
+	VPSRLQ $32, Z7, Z7
+	VPADDQ Z7, Z4, Z4
+
+	// r = hi(x * y) - hi(m * q) + q, minus q if r >= q
+	VPSRLQ   $32, Z0, Z1
+	MULHI512(Z0, Z1, Z15, Z14, Z2, Z3, Z5, Z6)
+	VPSUBQ   Z2, Z4, Z4
+	VPADDQ   Z15, Z4, Z4
+	VPSUBQ   Z15, Z4, Z5
+	VPMINUQ  Z5, Z4, Z4
+	VMOVDQU64 Z4, (DX)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	ADDQ $64, DX
+	DECQ CX
+	JNZ  mulCoeffsLoop
+
+mulCoeffsDone:
+	VZEROUPPER
+	RET
+
+// func mulScalarMontgomeryVecAVX512(p1, p2 []uint64, scalar, q, qInv uint64)
+TEXT ·mulScalarMontgomeryVecAVX512(SB), NOSPLIT, $0-72
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DX
+	MOVQ p2_len+32(FP), CX
+	MOVQ scalar+48(FP), AX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+56(FP), qInv+64(FP))
+	BROADCASTROOT512(AX)
+
+	TESTQ CX, CX
+	JZ    mulScalarDone
+
+mulScalarLoop:
+	VMOVDQU64 (SI), Z0
+	VPSRLQ    $32, Z0, Z1
+
+	MREDCONSTANT512(Z0, Z1, Z2, Z3, Z4, Z5, Z6)
+
+	// r = r - q if r >= q
+	VPSUBQ    Z15, Z2, Z3
+	VPMINUQ   Z3, Z2, Z2
+	VMOVDQU64 Z2, (DX)
+
+	ADDQ $64, SI
+	ADDQ $64, DX
+	DECQ CX
+	JNZ  mulScalarLoop
+
+mulScalarDone:
+	VZEROUPPER
+	RET
+
+// func mFormVecAVX512(p1, p2 []uint64, q, u0, u1 uint64)
+TEXT ·mFormVecAVX512(SB), NOSPLIT, $0-72
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DX
+	MOVQ p2_len+32(FP), CX
+	SHRQ $3, CX
+
+	// The Barrett parameters u0 and u1 take the place of qInv and of the roots
+	CONSTANTS512(q+48(FP), u0+56(FP))
+	VPBROADCASTQ u1+64(FP), Z13
+	VPSRLQ       $32, Z13, Z12
+	VPXORQ       Z11, Z11, Z11
+
+	TESTQ CX, CX
+	JZ    mFormDone
+
+mFormLoop:
+	VMOVDQU64 (SI), Z0
+	VPSRLQ    $32, Z0, Z1
+
+	// r = -(x * u0 + hi(x * u1)) * q, minus q if r >= q
+	MULHI512(Z0, Z1, Z13, Z12, Z2, Z3, Z4, Z5)
+	VPMULLQ   Z10, Z0, Z0
+	VPADDQ    Z2, Z0, Z0
+	VPMULLQ   Z15, Z0, Z0
+	VPSUBQ    Z0, Z11, Z0
+	VPSUBQ    Z15, Z0, Z1
+	VPMINUQ   Z1, Z0, Z0
+	VMOVDQU64 Z0, (DX)
+
+	ADDQ $64, SI
+	ADDQ $64, DX
+	DECQ CX
+	JNZ  mFormLoop
+
+mFormDone:
+	VZEROUPPER
+	RET
+
+// func invMFormVecAVX512(p1, p2 []uint64, q, qInv uint64)
+TEXT ·invMFormVecAVX512(SB), NOSPLIT, $0-64
+	MOVQ p1_base+0(FP), SI
+	MOVQ p2_base+24(FP), DX
+	MOVQ p2_len+32(FP), CX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+
+	TESTQ CX, CX
+	JZ    invMFormDone
+
+invMFormLoop:
+	// r = q - hi(x * qInv * q), minus q if r >= q
+	VMOVDQU64 (SI), Z0
+	VPMULLQ   Z10, Z0, Z0
+	VPSRLQ    $32, Z0, Z1
+	MULHI512(Z0, Z1, Z15, Z14, Z2, Z3, Z4, Z5)
+	VPSUBQ    Z2, Z15, Z0
+	VPSUBQ    Z15, Z0, Z1
+	VPMINUQ   Z1, Z0, Z0
+	VMOVDQU64 Z0, (DX)
+
+	ADDQ $64, SI
+	ADDQ $64, DX
+	DECQ CX
+	JNZ  invMFormLoop
+
+invMFormDone:
+	VZEROUPPER
+	RET
+
+// func butterflyVecAVX512(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·butterflyVecAVX512(SB), NOSPLIT, $0-120
+	MOVQ xIn_base+0(FP), SI
+	MOVQ yIn_base+24(FP), DI
+	MOVQ xOut_base+48(FP), DX
+	MOVQ xOut_len+56(FP), CX
+	MOVQ yOut_base+72(FP), R8
+	MOVQ psi+96(FP), AX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+104(FP), qInv+112(FP))
+	BROADCASTROOT512(AX)
+
+	TESTQ CX, CX
+	JZ    butterflyDone
+
+butterflyLoop:
+	VMOVDQU64 (SI), Z7
+	VMOVDQU64 (DI), Z0
+
+	BUTTERFLY512
+
+	VMOVDQU64 Z0, (DX)
+	VMOVDQU64 Z1, (R8)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	ADDQ $64, DX
+	ADDQ $64, R8
+	DECQ CX
+	JNZ  butterflyLoop
+
+butterflyDone:
+	VZEROUPPER
+	RET
+
+// func butterfly4VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly4VecAVX512(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $1, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+	VMOVDQU64 rootsSpan4<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    butterfly4Done
+
+butterfly4Loop:
+	// Two groups [x0 x1 x2 x3 y0 y1 y2 y3] [x4 x5 x6 x7 y4 y5 y6 y7] per iteration, unpacked in [x0 ... x7] and
+	// [y0 ... y7], with the roots [p0 p0 p0 p0 p1 p1 p1 p1]
+	VMOVDQU (DI), X13
+	VPERMQ  Z13, Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64  (SI), Z2
+	VMOVDQU64  64(SI), Z3
+	VSHUFI64X2 $0x44, Z3, Z2, Z7
+	VSHUFI64X2 $0xEE, Z3, Z2, Z0
+
+	BUTTERFLY512
+
+	VSHUFI64X2 $0x44, Z1, Z0, Z2
+	VSHUFI64X2 $0xEE, Z1, Z0, Z3
+	VMOVDQU64  Z2, (SI)
+	VMOVDQU64  Z3, 64(SI)
+
+	ADDQ $16, DI
+	ADDQ $128, SI
+	DECQ CX
+	JNZ  butterfly4Loop
+
+butterfly4Done:
+	VZEROUPPER
+	RET
+
+// func butterfly2VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly2VecAVX512(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $2, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+	VMOVDQU64 rootsSpan2<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    butterfly2Done
+
+butterfly2Loop:
+	// Four groups [x0 x1 y0 y1] ... [x6 x7 y6 y7] per iteration, unpacked in [x0 ... x7] and [y0 ... y7], with the
+	// roots [p0 p0 p1 p1 p2 p2 p3 p3]
+	VMOVDQU (DI), Y13
+	VPERMQ  Z13, Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64  (SI), Z2
+	VMOVDQU64  64(SI), Z3
+	VSHUFI64X2 $0x88, Z3, Z2, Z7
+	VSHUFI64X2 $0xDD, Z3, Z2, Z0
+
+	BUTTERFLY512
+
+	VSHUFI64X2 $0x44, Z1, Z0, Z2
+	VSHUFI64X2 $0xEE, Z1, Z0, Z3
+	VSHUFI64X2 $0xD8, Z2, Z2, Z2
+	VSHUFI64X2 $0xD8, Z3, Z3, Z3
+	VMOVDQU64  Z2, (SI)
+	VMOVDQU64  Z3, 64(SI)
+
+	ADDQ $32, DI
+	ADDQ $128, SI
+	DECQ CX
+	JNZ  butterfly2Loop
+
+butterfly2Done:
+	VZEROUPPER
+	RET
+
+// func butterfly1VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·butterfly1VecAVX512(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+	VMOVDQU64 rootsSpan1<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    butterfly1Done
+
+butterfly1Loop:
+	// Eight groups [x0 y0 x1 y1 ...] [x4 y4 x5 y5 ...] per iteration, unpacked in [x0 x4 x1 x5 ...] and
+	// [y0 y4 y1 y5 ...], with the roots [p0 p4 p1 p5 p2 p6 p3 p7]
+	VPERMQ (DI), Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64   (SI), Z2
+	VMOVDQU64   64(SI), Z3
+	VPUNPCKLQDQ Z3, Z2, Z7
+	VPUNPCKHQDQ Z3, Z2, Z0
+
+	BUTTERFLY512
+
+	VPUNPCKLQDQ Z1, Z0, Z2
+	VPUNPCKHQDQ Z1, Z0, Z3
+	VMOVDQU64   Z2, (SI)
+	VMOVDQU64   Z3, 64(SI)
+
+	ADDQ $64, DI
+	ADDQ $128, SI
+	DECQ CX
+	JNZ  butterfly1Loop
+
+butterfly1Done:
+	VZEROUPPER
+	RET
+
+// func invButterflyVecAVX512(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64)
+TEXT ·invButterflyVecAVX512(SB), NOSPLIT, $0-120
+	MOVQ xIn_base+0(FP), SI
+	MOVQ yIn_base+24(FP), DI
+	MOVQ xOut_base+48(FP), DX
+	MOVQ xOut_len+56(FP), CX
+	MOVQ yOut_base+72(FP), R8
+	MOVQ psi+96(FP), AX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+104(FP), qInv+112(FP))
+	BROADCASTROOT512(AX)
+
+	TESTQ CX, CX
+	JZ    invButterflyDone
+
+invButterflyLoop:
+	VMOVDQU64 (SI), Z7
+	VMOVDQU64 (DI), Z0
+
+	INVBUTTERFLY512
+
+	VMOVDQU64 Z7, (DX)
+	VMOVDQU64 Z2, (R8)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	ADDQ $64, DX
+	ADDQ $64, R8
+	DECQ CX
+	JNZ  invButterflyLoop
+
+invButterflyDone:
+	VZEROUPPER
+	RET
+
+// func invButterfly4VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly4VecAVX512(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $1, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+	VMOVDQU64 rootsSpan4<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    invButterfly4Done
+
+invButterfly4Loop:
+	VMOVDQU (DI), X13
+	VPERMQ  Z13, Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64  (SI), Z2
+	VMOVDQU64  64(SI), Z3
+	VSHUFI64X2 $0x44, Z3, Z2, Z7
+	VSHUFI64X2 $0xEE, Z3, Z2, Z0
+
+	INVBUTTERFLY512
+
+	VSHUFI64X2 $0x44, Z2, Z7, Z0
+	VSHUFI64X2 $0xEE, Z2, Z7, Z1
+	VMOVDQU64  Z0, (SI)
+	VMOVDQU64  Z1, 64(SI)
+
+	ADDQ $16, DI
+	ADDQ $128, SI
+	DECQ CX
+	JNZ  invButterfly4Loop
+
+invButterfly4Done:
+	VZEROUPPER
+	RET
+
+// func invButterfly2VecAVX512(coeffs, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly2VecAVX512(SB), NOSPLIT, $0-64
+	MOVQ coeffs_base+0(FP), SI
+	MOVQ psi_base+24(FP), DI
+	MOVQ psi_len+32(FP), CX
+	SHRQ $2, CX
+
+	CONSTANTS512(q+48(FP), qInv+56(FP))
+	VMOVDQU64 rootsSpan2<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    invButterfly2Done
+
+invButterfly2Loop:
+	VMOVDQU (DI), Y13
+	VPERMQ  Z13, Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64  (SI), Z2
+	VMOVDQU64  64(SI), Z3
+	VSHUFI64X2 $0x88, Z3, Z2, Z7
+	VSHUFI64X2 $0xDD, Z3, Z2, Z0
+
+	INVBUTTERFLY512
+
+	VSHUFI64X2 $0x44, Z2, Z7, Z0
+	VSHUFI64X2 $0xEE, Z2, Z7, Z1
+	VSHUFI64X2 $0xD8, Z0, Z0, Z0
+	VSHUFI64X2 $0xD8, Z1, Z1, Z1
+	VMOVDQU64  Z0, (SI)
+	VMOVDQU64  Z1, 64(SI)
+
+	ADDQ $32, DI
+	ADDQ $128, SI
+	DECQ CX
+	JNZ  invButterfly2Loop
+
+invButterfly2Done:
+	VZEROUPPER
+	RET
+
+// func invButterfly1VecAVX512(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64)
+TEXT ·invButterfly1VecAVX512(SB), NOSPLIT, $0-88
+	MOVQ coeffsIn_base+0(FP), SI
+	MOVQ coeffsOut_base+24(FP), DX
+	MOVQ psi_base+48(FP), DI
+	MOVQ psi_len+56(FP), CX
+	SHRQ $3, CX
+
+	CONSTANTS512(q+72(FP), qInv+80(FP))
+	VMOVDQU64 rootsSpan1<>(SB), Z16
+
+	TESTQ CX, CX
+	JZ    invButterfly1Done
+
+invButterfly1Loop:
+	VPERMQ (DI), Z16, Z13
+	LANEROOTS512
+
+	VMOVDQU64   (SI), Z2
+	VMOVDQU64   64(SI), Z3
+	VPUNPCKLQDQ Z3, Z2, Z7
+	VPUNPCKHQDQ Z3, Z2, Z0
+
+	INVBUTTERFLY512
+
+	VPUNPCKLQDQ Z2, Z7, Z0
+	VPUNPCKHQDQ Z2, Z7, Z1
+	VMOVDQU64   Z0, (DX)
+	VMOVDQU64   Z1, 64(DX)
+
+	ADDQ $64, DI
+	ADDQ $128, SI
+	ADDQ $128, DX
+	DECQ CX
+	JNZ  invButterfly1Loop
+
+invButterfly1Done:
+	VZEROUPPER
+	RET
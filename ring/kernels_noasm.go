@@ -0,0 +1,59 @@
+// +build !amd64,!arm64 arm64,!neon purego
+
+package ring
+
+func addVec(p1, p2, p3 []uint64, q uint64) {
+	addVecGeneric(p1, p2, p3, q)
+}
+
+func subVec(p1, p2, p3 []uint64, q uint64) {
+	subVecGeneric(p1, p2, p3, q)
+}
+
+func mulCoeffsMontgomeryVec(p1, p2, p3 []uint64, q, qInv uint64) {
+	mulCoeffsMontgomeryVecGeneric(p1, p2, p3, q, qInv)
+}
+
+func butterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	butterflyVecGeneric(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func mFormVec(p1, p2 []uint64, q uint64, u []uint64) {
+	mFormVecGeneric(p1, p2, q, u)
+}
+
+func invMFormVec(p1, p2 []uint64, q, qInv uint64) {
+	invMFormVecGeneric(p1, p2, q, qInv)
+}
+
+func butterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly4VecGeneric(coeffs, psi, q, qInv)
+}
+
+func butterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly2VecGeneric(coeffs, psi, q, qInv)
+}
+
+func butterfly1Vec(coeffs, psi []uint64, q, qInv uint64) {
+	butterfly1VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterflyVec(xIn, yIn, xOut, yOut []uint64, psi, q, qInv uint64) {
+	invButterflyVecGeneric(xIn, yIn, xOut, yOut, psi, q, qInv)
+}
+
+func invButterfly4Vec(coeffs, psi []uint64, q, qInv uint64) {
+	invButterfly4VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterfly2Vec(coeffs, psi []uint64, q, qInv uint64) {
+	invButterfly2VecGeneric(coeffs, psi, q, qInv)
+}
+
+func invButterfly1Vec(coeffsIn, coeffsOut, psi []uint64, q, qInv uint64) {
+	invButterfly1VecGeneric(coeffsIn, coeffsOut, psi, q, qInv)
+}
+
+func mulScalarMontgomeryVec(p1, p2 []uint64, scalar, q, qInv uint64) {
+	mulScalarMontgomeryVecGeneric(p1, p2, scalar, q, qInv)
+}
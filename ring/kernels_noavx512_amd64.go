@@ -0,0 +1,10 @@
+// +build noavx512,!purego
+
+package ring
+
+// The noavx512 build tag disables the AVX-512 kernels, which are then replaced by the AVX2 and the generic ones, e.g. on
+// processors lowering their frequency when running AVX-512 instructions. They can also be disabled at runtime with the
+// environment variable GODEBUG=cpu.avx512f=off.
+func init() {
+	useAVX512 = false
+}
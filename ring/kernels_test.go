@@ -0,0 +1,119 @@
+package ring
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomVec(n, bound uint64) (v []uint64) {
+	v = make([]uint64, n)
+	for i := range v {
+		v[i] = rand.Uint64() % bound
+	}
+	return
+}
+
+// TestKernels checks the kernels selected for the architecture against the generic ones.
+func TestKernels(t *testing.T) {
+
+	n := uint64(1 << 10)
+
+	for _, q := range []uint64{0x3fffffffef8001, 0x1fffffffffe00001, 0x3fffffffffc00001} {
+
+		qInv := MRedParams(q)
+
+		t.Run(fmt.Sprintf("logQ=%d", bits.Len64(q)), func(t *testing.T) {
+
+			p1, p2 := randomVec(n, q), randomVec(n, q)
+			want, have := make([]uint64, n), make([]uint64, n)
+
+			addVecGeneric(p1, p2, want, q)
+			addVec(p1, p2, have, q)
+			assert.Equal(t, want, have, "addVec")
+
+			subVecGeneric(p1, p2, want, q)
+			subVec(p1, p2, have, q)
+			assert.Equal(t, want, have, "subVec")
+
+			mulCoeffsMontgomeryVecGeneric(p1, p2, want, q, qInv)
+			mulCoeffsMontgomeryVec(p1, p2, have, q, qInv)
+			assert.Equal(t, want, have, "mulCoeffsMontgomeryVec")
+
+			mFormVecGeneric(p1, want, q, BRedParams(q))
+			mFormVec(p1, have, q, BRedParams(q))
+			assert.Equal(t, want, have, "mFormVec")
+
+			invMFormVec(have, have, q, qInv)
+			assert.Equal(t, p1, have, "invMFormVec")
+
+			x, y := randomVec(n, 4*q), randomVec(n, 4*q)
+			psi := MForm(rand.Uint64()%q, q, BRedParams(q))
+			wantY, haveY := make([]uint64, n), make([]uint64, n)
+
+			butterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			butterflyVec(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "butterflyVec")
+			assert.Equal(t, wantY, haveY, "butterflyVec")
+
+			// The inverse butterflies take their inputs in [0, 2q)
+			x, y = randomVec(n, 2*q), randomVec(n, 2*q)
+
+			invButterflyVecGeneric(x, y, want, wantY, psi, q, qInv)
+			invButterflyVec(x, y, have, haveY, psi, q, qInv)
+			assert.Equal(t, want, have, "invButterflyVec")
+			assert.Equal(t, wantY, haveY, "invButterflyVec")
+
+			// The butterflies of span 4, 2 and 1 take one root per group of 2 * span coefficients
+			for _, k := range []struct {
+				span               uint64
+				generic, vec       func(coeffs, psi []uint64, q, qInv uint64)
+				invGeneric, invVec func(coeffs, psi []uint64, q, qInv uint64)
+			}{
+				{4, butterfly4VecGeneric, butterfly4Vec, invButterfly4VecGeneric, invButterfly4Vec},
+				{2, butterfly2VecGeneric, butterfly2Vec, invButterfly2VecGeneric, invButterfly2Vec},
+				{1, butterfly1VecGeneric, butterfly1Vec, nil, nil},
+			} {
+
+				roots := make([]uint64, n/(2*k.span))
+				for i := range roots {
+					roots[i] = MForm(rand.Uint64()%q, q, BRedParams(q))
+				}
+
+				x = randomVec(n, 4*q)
+				copy(want, x)
+				copy(have, x)
+
+				k.generic(want, roots, q, qInv)
+				k.vec(have, roots, q, qInv)
+				assert.Equal(t, want, have, fmt.Sprintf("butterfly%dVec", k.span))
+
+				x = randomVec(n, 2*q)
+
+				if k.invVec == nil {
+					// The first stage of the InvNTT is not in place
+					invButterfly1VecGeneric(x, want, roots, q, qInv)
+					invButterfly1Vec(x, have, roots, q, qInv)
+				} else {
+					copy(want, x)
+					copy(have, x)
+
+					k.invGeneric(want, roots, q, qInv)
+					k.invVec(have, roots, q, qInv)
+				}
+				assert.Equal(t, want, have, fmt.Sprintf("invButterfly%dVec", k.span))
+			}
+
+			// The Montgomery product by a scalar takes any input, as the outputs of the NTT of unreduced inputs
+			x = randomVec(n, 1<<63)
+			scalar := rand.Uint64() % q
+
+			mulScalarMontgomeryVecGeneric(x, want, scalar, q, qInv)
+			mulScalarMontgomeryVec(x, have, scalar, q, qInv)
+			assert.Equal(t, want, have, "mulScalarMontgomeryVec")
+		})
+	}
+}
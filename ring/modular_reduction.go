@@ -8,8 +8,13 @@ import (
 // MForm switches a to the Montgomery domain by computing
 // a*2^64 mod q.
 func MForm(a, q uint64, u []uint64) (r uint64) {
-	mhi, _ := bits.Mul64(a, u[1])
-	r = -(a*u[0] + mhi) * q
+	return mForm(a, q, u[0], u[1])
+}
+
+// mForm is MForm with the Barrett parameters u0 and u1 passed by value, which lets the kernels keep them in registers.
+func mForm(a, q, u0, u1 uint64) (r uint64) {
+	mhi, _ := bits.Mul64(a, u1)
+	r = -(a*u0 + mhi) * q
 	if r >= q {
 		r -= q
 	}
@@ -1,17 +1,40 @@
 // Package ring implements RNS-accelerated modular arithmetic operations for polynomials, including:
 // RNS basis extension; RNS rescaling; number theoretic transform (NTT); uniform, Gaussian and ternary sampling.
+//
+// The operations suffixed by NoMod are lazy: they skip the final modular reduction of their result, whose range is
+// documented as a function of the ranges of their inputs, e.g. AddNoMod returns values in [0, 2q) for inputs in
+// [0, q). A sequence of lazy operations must keep the coefficients below 2^64, and its result is brought back to
+// [0, q) by Reduce or, in constant time, by ReduceConstant. LazyReductionPeriod gives the number of lazy additions
+// of values in [0, q) that can be accumulated between two reductions.
+//
+// Building with the ringdebug tag, e.g. go test -tags ringdebug, makes the operations check at runtime that their
+// operands have the moduli of the level of the operation and that the output aliases the inputs only in the ways the
+// operation supports, panicking with the offending operands otherwise.
 package ring
 
 import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"math/big"
 	"math/bits"
+	"sync"
 
 	"github.com/ldsec/lattigo/v2/utils"
 )
 
+// MaxModulusSize is the largest bit-length of the moduli of a Ring allowing the NTT. The lazy butterflies of the NTT
+// keep their values in [0, 4q), which must fit in 64 bits.
+const MaxModulusSize = 62
+
+// MaxModuliCount is the largest number of moduli of a Poly that can be serialized, as it is written on one byte.
+const MaxModuliCount = 255
+
+// MaxLogN is the log2 of the largest degree of a Poly that can be deserialized. It bounds the memory allocated for the
+// coefficients of a polynomial read from untrusted data before its length is checked.
+const MaxLogN = 30
+
 // Ring is a structure that keeps all the variables required to operate on a polynomial represented in this ring.
 type Ring struct {
 
@@ -43,6 +66,18 @@ type Ring struct {
 	NttPsi    [][]uint64 //powers of the inverse of the 2N-th primitive root in Montgomery form (in bit-reversed order)
 	NttPsiInv [][]uint64 //powers of the inverse of the 2N-th primitive root in Montgomery form (in bit-reversed order)
 	NttNInv   []uint64   //[N^-1] mod Qi in Montgomery form
+
+	// Size of the blocks of coefficients on which the NTT tiles its butterflies (0 if not blocked)
+	nttBlockSize uint64
+
+	// Implementation of the NTT, the Montgomery products and the key-switching inner product (CPUBackend if nil)
+	backend Backend
+
+	// Pool on which the NTT is computed in parallel across the moduli (sequential if nil)
+	workerPool *WorkerPool
+
+	// Pools of temporary polynomials, one per level (see GetPoly)
+	polyPools []sync.Pool
 }
 
 // NewRing creates a new Ring with the given parameters. It checks that N is a power of 2 and that the moduli are NTT friendly.
@@ -52,6 +87,52 @@ func NewRing(N uint64, Moduli []uint64) (r *Ring, err error) {
 	return r, r.genNTTParams()
 }
 
+// Level returns the level of the Ring, i.e. its number of moduli minus one.
+func (r *Ring) Level() uint64 {
+	return uint64(len(r.Modulus) - 1)
+}
+
+// AtLevel returns a view of r restricted to the moduli from q_0 up to q_level, such that the operations of the view
+// without a level argument operate on these moduli only, and its new polynomials have level+1 moduli. The view shares
+// the precomputed values, Backend, WorkerPool and pools of polynomials that r has when it is called, and is therefore
+// cheap to create, while setting them on the view does not affect r. Scheme
+// code can take a view at the level of its operands instead of passing the level to each operation. The polynomials
+// given to the view can have more moduli than the view, in which case the moduli above q_level are left untouched,
+// except by the operations which take the level of their operand, such as DivRoundByLastModulusNTT.
+func (r *Ring) AtLevel(level uint64) *Ring {
+
+	if level > r.Level() {
+		panic(fmt.Sprintf("cannot AtLevel: level %d is larger than the maximum level %d of the ring", level, r.Level()))
+	}
+
+	if level == r.Level() {
+		return r
+	}
+
+	view := *r
+
+	view.Modulus = r.Modulus[:level+1]
+	view.Mask = r.Mask[:level+1]
+	view.BredParams = r.BredParams[:level+1]
+	view.MredParams = r.MredParams[:level+1]
+
+	view.ModulusBigint = NewUint(1)
+	for _, qi := range view.Modulus {
+		view.ModulusBigint.Mul(view.ModulusBigint, NewUint(qi))
+	}
+
+	if r.allowsNTT {
+		view.RescaleParams = r.RescaleParams[:level]
+		view.PsiMont = r.PsiMont[:level+1]
+		view.PsiInvMont = r.PsiInvMont[:level+1]
+		view.NttPsi = r.NttPsi[:level+1]
+		view.NttPsiInv = r.NttPsiInv[:level+1]
+		view.NttNInv = r.NttNInv[:level+1]
+	}
+
+	return &view
+}
+
 // setParameters initializes a *Ring by setting the required precomputed values (except for the NTT-related values, which are set by the
 // genNTTParams function).
 func (r *Ring) setParameters(N uint64, Modulus []uint64) {
@@ -95,6 +176,7 @@ func (r *Ring) setParameters(N uint64, Modulus []uint64) {
 		}
 	}
 
+	r.polyPools = r.newPolyPools()
 }
 
 // genNTTParams checks that N has been correctly initialized, and checks that each modulus is a prime congruent to 1 mod 2N (i.e. NTT-friendly).
@@ -110,25 +192,15 @@ func (r *Ring) genNTTParams() error {
 		panic("error : invalid r parameters (missing)")
 	}
 
-	// Check if each qi is prime and if qi = 1 mod 2n
+	// Check if each qi is a prime of at most MaxModulusSize bits and if qi = 1 mod 2n
 	for _, qi := range r.Modulus {
-		if IsPrime(qi) == false || qi&((r.N<<1)-1) != 1 {
+		if bits.Len64(qi) > MaxModulusSize || IsPrime(qi) == false || qi&((r.N<<1)-1) != 1 {
 			r.allowsNTT = false
 			return errors.New("warning : provided modulus does not allow NTT")
 		}
 	}
 
-	r.RescaleParams = make([][]uint64, len(r.Modulus)-1, len(r.Modulus)-1)
-
-	for j := len(r.Modulus) - 1; j > 0; j-- {
-
-		r.RescaleParams[j-1] = make([]uint64, j)
-
-		for i := 0; i < j; i++ {
-
-			r.RescaleParams[j-1][i] = MForm(ModExp(r.Modulus[j], r.Modulus[i]-2, r.Modulus[i]), r.Modulus[i], r.BredParams[i])
-		}
-	}
+	r.RescaleParams = GenRescaleParams(r.Modulus)
 
 	r.PsiMont = make([]uint64, len(r.Modulus))
 	r.PsiInvMont = make([]uint64, len(r.Modulus))
@@ -178,9 +250,31 @@ func (r *Ring) genNTTParams() error {
 
 	r.allowsNTT = true
 
+	if r.N >= MinNTTBlockedN {
+		r.nttBlockSize = tuneNTTBlockSize(r.N, r.Modulus[0], r.MredParams[0], r.BredParams[0], r.NttPsi[0], r.NttPsiInv[0], r.NttNInv[0])
+	}
+
 	return nil
 }
 
+// LazyReductionPeriod returns the largest power of two n, at most 8, such that n values in [0, q) can be added to a
+// value in [0, q) without overflow for all the moduli q of the Ring, i.e. such that (n+1)*q <= 2^64. Lazy
+// accumulations, such as MulCoeffsMontgomeryAndAddNoMod, must be reduced at least every n additions: the period is 8
+// for moduli up to 60 bits, but shorter for 61 and 62-bit moduli.
+func (r *Ring) LazyReductionPeriod() uint64 {
+	return lazyReductionPeriod(r.Modulus)
+}
+
+func lazyReductionPeriod(moduli []uint64) (n uint64) {
+	n = 8
+	for _, qi := range moduli {
+		for n > 1 && qi > 0xffffffffffffffff/(n+1) {
+			n >>= 1
+		}
+	}
+	return
+}
+
 // Minimal required information to recover the full ring. Used to import and export the ring.
 type ringParams struct {
 	N       uint64
@@ -222,6 +316,23 @@ func (r *Ring) AllowsNTT() bool {
 	return r.allowsNTT
 }
 
+// NTTBlockSize returns the size of the blocks of coefficients on which the NTT of the Ring tiles its butterflies,
+// or 0 if the NTT is not blocked. It is autotuned when the Ring is created if N >= MinNTTBlockedN.
+func (r *Ring) NTTBlockSize() uint64 {
+	return r.nttBlockSize
+}
+
+// SetNTTBlockSize sets the size of the blocks of coefficients on which the NTT of the Ring tiles its butterflies,
+// overriding the autotuned value. blockSize must be 0, which disables the blocking, or a power of two greater or
+// equal to 16.
+func (r *Ring) SetNTTBlockSize(blockSize uint64) error {
+	if blockSize != 0 && (blockSize < 16 || blockSize&(blockSize-1) != 0) {
+		return errors.New("invalid NTT block size (must be 0 or a power of 2 >= 16)")
+	}
+	r.nttBlockSize = blockSize
+	return nil
+}
+
 // GetBredParams returns the Barret reduction parameters of the Ring.
 func (r *Ring) GetBredParams() [][]uint64 {
 	return r.BredParams
@@ -269,7 +380,7 @@ func (r *Ring) NewPoly() *Poly {
 	return p
 }
 
-// NewPolyLvl creates a new polynomial with all coefficients set to 0.
+// NewPolyLvl creates a new polynomial with all coefficients set to 0, for the moduli from q_0 up to q_level only.
 func (r *Ring) NewPolyLvl(level uint64) *Poly {
 	p := new(Poly)
 
@@ -281,6 +392,15 @@ func (r *Ring) NewPolyLvl(level uint64) *Poly {
 	return p
 }
 
+// GrowLvl allocates the moduli of p up to q_level that it does not have yet, with their coefficients set to 0. The
+// existing moduli are left unchanged, and p is never shrunk, so that a polynomial used as a memory pool only grows to
+// the largest level at which it is used.
+func (r *Ring) GrowLvl(level uint64, p *Poly) {
+	for i := uint64(len(p.Coeffs)); i < level+1; i++ {
+		p.Coeffs = append(p.Coeffs, make([]uint64, r.N))
+	}
+}
+
 // SetCoefficientsInt64 sets the coefficients of p1 from an int64 array.
 func (r *Ring) SetCoefficientsInt64(coeffs []int64, p1 *Poly) {
 	for i, coeff := range coeffs {
@@ -312,7 +432,8 @@ func (r *Ring) SetCoefficientsString(coeffs []string, p1 *Poly) {
 	}
 }
 
-// SetCoefficientsBigint sets the coefficients of p1 from an array of Int variables.
+// SetCoefficientsBigint sets the coefficients of p1 from an array of Int variables, which can be negative, e.g. as
+// returned by PolyToBigintCentered.
 func (r *Ring) SetCoefficientsBigint(coeffs []*big.Int, p1 *Poly) {
 	QiBigint := new(big.Int)
 	coeffTmp := new(big.Int)
@@ -325,7 +446,9 @@ func (r *Ring) SetCoefficientsBigint(coeffs []*big.Int, p1 *Poly) {
 	}
 }
 
-// SetCoefficientsBigintLvl sets the coefficients of p1 from an array of Int variables.
+// SetCoefficientsBigintLvl sets the coefficients of p1 from an array of Int variables, which can be negative, for the
+// moduli from q_0 up to q_level. It is the inverse of PolyToBigintLvl and PolyToBigintCenteredLvl (with a gap of 1),
+// and the coefficients can be reduced modulo a level larger than the one they were reconstructed from.
 func (r *Ring) SetCoefficientsBigintLvl(level uint64, coeffs []*big.Int, p1 *Poly) {
 
 	QiBigint := new(big.Int)
@@ -356,9 +479,18 @@ func (r *Ring) PolyToString(p1 *Poly) []string {
 // PolyToBigint reconstructs p1 and returns the result in an array of Int.
 func (r *Ring) PolyToBigint(p1 *Poly, coeffsBigint []*big.Int) {
 
-	var qi, level uint64
+	for x := uint64(0); x < r.N; x++ {
+		coeffsBigint[x] = new(big.Int)
+	}
 
-	level = uint64(len(p1.Coeffs) - 1)
+	r.PolyToBigintLvl(uint64(len(p1.Coeffs)-1), p1, 1, coeffsBigint)
+}
+
+// PolyToBigintLvl reconstructs every gap-th coefficient of p1 from the moduli q_0 up to q_level, and writes the
+// coefficient of index i*gap on coeffsBigint[i], in [0, q_0 * ... * q_level). coeffsBigint must have at least N/gap
+// elements, which are allocated if they are nil, so that only the required coefficients are reconstructed, e.g. for
+// the extraction of LWE samples.
+func (r *Ring) PolyToBigintLvl(level uint64, p1 *Poly, gap uint64, coeffsBigint []*big.Int) {
 
 	crtReconstruction := make([]*big.Int, level+1)
 
@@ -368,8 +500,7 @@ func (r *Ring) PolyToBigint(p1 *Poly, coeffsBigint []*big.Int) {
 
 	for i := uint64(0); i < level+1; i++ {
 
-		qi = r.Modulus[i]
-		QiB.SetUint64(qi)
+		QiB.SetUint64(r.Modulus[i])
 
 		modulusBigint.Mul(modulusBigint, QiB)
 
@@ -380,19 +511,49 @@ func (r *Ring) PolyToBigint(p1 *Poly, coeffsBigint []*big.Int) {
 		crtReconstruction[i].Mul(crtReconstruction[i], tmp)
 	}
 
-	for x := uint64(0); x < r.N; x++ {
+	for x, i := uint64(0), uint64(0); i < r.N; x, i = x+1, i+gap {
 
-		tmp.SetUint64(0)
-		coeffsBigint[x] = new(big.Int)
+		if coeffsBigint[x] == nil {
+			coeffsBigint[x] = new(big.Int)
+		}
 
-		for i := uint64(0); i < level+1; i++ {
-			coeffsBigint[x].Add(coeffsBigint[x], tmp.Mul(NewUint(p1.Coeffs[i][x]), crtReconstruction[i]))
+		coeffsBigint[x].SetUint64(0)
+
+		for j := uint64(0); j < level+1; j++ {
+			coeffsBigint[x].Add(coeffsBigint[x], tmp.Mul(QiB.SetUint64(p1.Coeffs[j][i]), crtReconstruction[j]))
 		}
 
 		coeffsBigint[x].Mod(coeffsBigint[x], modulusBigint)
 	}
 }
 
+// PolyToBigintCentered reconstructs p1 and returns the result in an array of Int, centered in (-Q/2, Q/2] for Q the
+// product of the moduli of p1. SetCoefficientsBigint is its inverse.
+func (r *Ring) PolyToBigintCentered(p1 *Poly, coeffsBigint []*big.Int) {
+	r.PolyToBigintCenteredLvl(uint64(len(p1.Coeffs)-1), p1, 1, coeffsBigint)
+}
+
+// PolyToBigintCenteredLvl reconstructs every gap-th coefficient of p1 from the moduli q_0 up to q_level as
+// PolyToBigintLvl, but centered in (-Q/2, Q/2] for Q = q_0 * ... * q_level, e.g. to lift a small polynomial modulo Q
+// to a larger modulus with SetCoefficientsBigintLvl, or to measure a noise.
+func (r *Ring) PolyToBigintCenteredLvl(level uint64, p1 *Poly, gap uint64, coeffsBigint []*big.Int) {
+
+	r.PolyToBigintLvl(level, p1, gap, coeffsBigint)
+
+	Q := NewUint(1)
+	for _, qi := range r.Modulus[:level+1] {
+		Q.Mul(Q, NewUint(qi))
+	}
+
+	QHalf := new(big.Int).Rsh(Q, 1)
+
+	for x, i := uint64(0), uint64(0); i < r.N; x, i = x+1, i+gap {
+		if coeffsBigint[x].Cmp(QHalf) > 0 {
+			coeffsBigint[x].Sub(coeffsBigint[x], Q)
+		}
+	}
+}
+
 // PolyToBigintNoAlloc reconstructs p1 and returns the result in an pre-allocated array of Int.
 func (r *Ring) PolyToBigintNoAlloc(p1 *Poly, coeffsBigint []*big.Int) {
 
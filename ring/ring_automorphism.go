@@ -2,6 +2,7 @@ package ring
 
 import (
 	"math/bits"
+	"sync"
 	"unsafe"
 
 	"github.com/ldsec/lattigo/v2/utils"
@@ -51,11 +52,53 @@ func PermuteNTTIndex(gen, power, N uint64) (index []uint64) {
 	return
 }
 
+// permuteNTTIndexKey identifies an index table of the cache of PermuteNTTIndexShared.
+type permuteNTTIndexKey struct {
+	N, galEl uint64
+}
+
+// permuteNTTIndexCache stores the index tables returned by PermuteNTTIndexShared, so that they are computed and stored
+// once per process whatever the number of keys, evaluators and protocols using them.
+var permuteNTTIndexCache sync.Map
+
+// PermuteNTTIndexShared returns the index table for PermuteNTT of the Galois element gen^power mod 2N, as
+// PermuteNTTIndex, but from a process-wide cache keyed by the degree and the Galois element. The table is shared by
+// all its callers and must therefore not be modified. The tables stay in the cache until ClearPermuteNTTIndexCache.
+func PermuteNTTIndexShared(gen, power, N uint64) (index []uint64) {
+
+	key := permuteNTTIndexKey{N, ModExp(gen, power, 2*N)}
+
+	if index, ok := permuteNTTIndexCache.Load(key); ok {
+		return index.([]uint64)
+	}
+
+	// Concurrent callers may compute the same table, but all of them get the one which is stored first
+	cached, _ := permuteNTTIndexCache.LoadOrStore(key, PermuteNTTIndex(key.galEl, 1, N))
+
+	return cached.([]uint64)
+}
+
+// ClearPermuteNTTIndexCache empties the cache of PermuteNTTIndexShared. The tables which were returned before remain
+// valid for their holders.
+func ClearPermuteNTTIndexCache() {
+	permuteNTTIndexCache.Range(func(key, _ interface{}) bool {
+		permuteNTTIndexCache.Delete(key)
+		return true
+	})
+}
+
 // PermuteNTT applies the Galois transform on a polynomial in the NTT domain.
 // It maps the coefficients x^i to x^(gen*i)
 // It must be noted that the result cannot be in-place.
 func PermuteNTT(polIn *Poly, gen uint64, polOut *Poly) {
 
+	if debugChecks {
+		N := uint64(len(polIn.Coeffs[0]))
+		level := uint64(len(polIn.Coeffs) - 1)
+		checkPolyLevel("PermuteNTT", N, level, polIn, polOut)
+		checkAliasing("PermuteNTT", N, level, false, polOut, polIn)
+	}
+
 	var N, tmp, mask, logN, tmp1, tmp2 uint64
 
 	N = uint64(len(polIn.Coeffs[0]))
@@ -90,6 +133,12 @@ func PermuteNTT(polIn *Poly, gen uint64, polOut *Poly) {
 // It must be noted that the result cannot be in-place.
 func PermuteNTTLvl(level uint64, polIn *Poly, gen uint64, polOut *Poly) {
 
+	if debugChecks {
+		N := uint64(len(polIn.Coeffs[0]))
+		checkPolyLevel("PermuteNTTLvl", N, level, polIn, polOut)
+		checkAliasing("PermuteNTTLvl", N, level, false, polOut, polIn)
+	}
+
 	var N, tmp, mask, logN, tmp1, tmp2 uint64
 
 	N = uint64(len(polIn.Coeffs[0]))
@@ -124,6 +173,12 @@ func PermuteNTTLvl(level uint64, polIn *Poly, gen uint64, polOut *Poly) {
 // It must be noted that the result cannot be in-place.
 func PermuteNTTWithIndexLvl(level uint64, polIn *Poly, index []uint64, polOut *Poly) {
 
+	if debugChecks {
+		N := uint64(len(polIn.Coeffs[0]))
+		checkPolyLevel("PermuteNTTWithIndexLvl", N, level, polIn, polOut)
+		checkAliasing("PermuteNTTWithIndexLvl", N, level, false, polOut, polIn)
+	}
+
 	for j := uint64(0); j < uint64(len(polIn.Coeffs[0])); j = j + 8 {
 
 		x := (*[8]uint64)(unsafe.Pointer(&index[j]))
@@ -151,6 +206,12 @@ func PermuteNTTWithIndexLvl(level uint64, polIn *Poly, index []uint64, polOut *P
 // It must be noted that the result cannot be in-place.
 func PermuteNTTWithIndexAndAddNoModLvl(level uint64, polIn *Poly, index []uint64, polOut *Poly) {
 
+	if debugChecks {
+		N := uint64(len(polIn.Coeffs[0]))
+		checkPolyLevel("PermuteNTTWithIndexAndAddNoModLvl", N, level, polIn, polOut)
+		checkAliasing("PermuteNTTWithIndexAndAddNoModLvl", N, level, false, polOut, polIn)
+	}
+
 	for j := uint64(0); j < uint64(len(polIn.Coeffs[0])); j = j + 8 {
 
 		x := (*[8]uint64)(unsafe.Pointer(&index[j]))
@@ -177,6 +238,12 @@ func PermuteNTTWithIndexAndAddNoModLvl(level uint64, polIn *Poly, index []uint64
 // It must be noted that the result cannot be in-place.
 func (r *Ring) Permute(polIn *Poly, gen uint64, polOut *Poly) {
 
+	if debugChecks {
+		level := uint64(len(r.Modulus) - 1)
+		checkLevel("Permute", r, level, polIn, polOut)
+		checkAliasing("Permute", r.N, level, false, polOut, polIn)
+	}
+
 	var mask, index, indexRaw, logN, tmp uint64
 
 	mask = r.N - 1
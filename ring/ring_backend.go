@@ -0,0 +1,155 @@
+package ring
+
+import (
+	"fmt"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// Backend is the interface of the implementations of the operations of a Ring that dominate the cost of the
+// homomorphic evaluation: the NTT and its inverse, also merged with the conversions to and from the Montgomery form, the
+// coefficient-wise Montgomery products and the inner product of the key-switching. A Backend offloading them, for example to a GPU, is plugged into a Ring with SetBackend.
+//
+// The methods take the Ring on which they operate and have the same semantic as the homonymous methods of the Ring:
+// the inputs and outputs are in [0, q_i) for each modulus q_i from q_0 up to q_level, and the outputs may alias the
+// inputs. A Backend can embed CPUBackend to fall back to the CPU for the operations it does not implement. CheckBackend
+// tests the conformance of a Backend with the CPUBackend.
+type Backend interface {
+	NTTLvl(r *Ring, level uint64, p1, p2 *Poly)
+	InvNTTLvl(r *Ring, level uint64, p1, p2 *Poly)
+	NTTMFormLvl(r *Ring, level uint64, p1, p2 *Poly)
+	InvNTTInvMFormLvl(r *Ring, level uint64, p1, p2 *Poly)
+	MulCoeffsMontgomeryLvl(r *Ring, level uint64, p1, p2, p3 *Poly)
+	InnerProductMontgomeryLvl(r *Ring, level uint64, p1, p2 []*Poly, p3 *Poly)
+}
+
+// CPUBackend is the reference Backend, which computes the operations on the CPU. It is the Backend of the Rings unless
+// another one is set with SetBackend.
+type CPUBackend struct{}
+
+// NTTLvl computes the NTT of p1 and returns the result on p2, see Ring.NTTLvl.
+func (CPUBackend) NTTLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	r.nttLvl(level, p1, p2)
+}
+
+// InvNTTLvl computes the inverse-NTT of p1 and returns the result on p2, see Ring.InvNTTLvl.
+func (CPUBackend) InvNTTLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	r.invNTTLvl(level, p1, p2)
+}
+
+// NTTMFormLvl computes the NTT of p1 and returns the result on p2 in the Montgomery form, see Ring.NTTMFormLvl.
+func (CPUBackend) NTTMFormLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	r.nttMFormLvl(level, p1, p2)
+}
+
+// InvNTTInvMFormLvl computes the inverse-NTT of p1, which is in the Montgomery form, and returns the result on p2 out
+// of the Montgomery form, see Ring.InvNTTInvMFormLvl.
+func (CPUBackend) InvNTTInvMFormLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	r.invNTTInvMFormLvl(level, p1, p2)
+}
+
+// MulCoeffsMontgomeryLvl multiplies p1 by p2 coefficient-wise with a Montgomery modular reduction and returns the
+// result on p3, see Ring.MulCoeffsMontgomeryLvl.
+func (CPUBackend) MulCoeffsMontgomeryLvl(r *Ring, level uint64, p1, p2, p3 *Poly) {
+	r.mulCoeffsMontgomeryLvl(level, p1, p2, p3)
+}
+
+// InnerProductMontgomeryLvl computes the inner product of p1 and p2 with a Montgomery modular reduction and returns
+// the result on p3, see Ring.InnerProductMontgomeryLvl.
+func (CPUBackend) InnerProductMontgomeryLvl(r *Ring, level uint64, p1, p2 []*Poly, p3 *Poly) {
+	r.innerProductMontgomeryLvl(level, p1, p2, p3)
+}
+
+// Backend returns the Backend of the Ring.
+func (r *Ring) Backend() Backend {
+	if r.backend == nil {
+		return CPUBackend{}
+	}
+	return r.backend
+}
+
+// SetBackend sets the Backend of the Ring. A nil backend restores the CPUBackend.
+func (r *Ring) SetBackend(backend Backend) {
+	r.backend = backend
+}
+
+// InnerProductMontgomeryLvl computes sum_i p1[i] * p2[i] coefficient-wise with a Montgomery modular reduction for the
+// moduli from q_0 up to q_level and returns the result on p3. It is the inner product of the key-switching, between
+// the decomposition of a polynomial and the switching key. p1 and p2 must have the same length, and p3 must not alias
+// any of their elements.
+func (r *Ring) InnerProductMontgomeryLvl(level uint64, p1, p2 []*Poly, p3 *Poly) {
+	if len(p1) != len(p2) || len(p1) == 0 {
+		panic("cannot InnerProductMontgomeryLvl: p1 and p2 must have the same non-zero length")
+	}
+	if debugChecks {
+		checkLevel("InnerProductMontgomeryLvl", r, level, append(append([]*Poly{p3}, p1...), p2...)...)
+		checkAliasing("InnerProductMontgomeryLvl", r.N, level, false, p3, append(append([]*Poly{}, p1...), p2...)...)
+	}
+	r.Backend().InnerProductMontgomeryLvl(r, level, p1, p2, p3)
+}
+
+// innerProductMontgomeryLvl is the implementation of InnerProductMontgomeryLvl of the CPUBackend. The products are
+// accumulated without modular reduction, which is done once every LazyReductionPeriod products.
+func (r *Ring) innerProductMontgomeryLvl(level uint64, p1, p2 []*Poly, p3 *Poly) {
+
+	mask := lazyReductionPeriod(r.Modulus[:level+1]) - 1
+
+	r.mulCoeffsMontgomeryLvl(level, p1[0], p2[0], p3)
+
+	for i := uint64(1); i < uint64(len(p1)); i++ {
+
+		r.MulCoeffsMontgomeryAndAddNoModLvl(level, p1[i], p2[i], p3)
+
+		if i&mask == mask {
+			r.ReduceLvl(level, p3, p3)
+		}
+	}
+
+	if uint64(len(p1))&mask != 0 {
+		r.ReduceLvl(level, p3, p3)
+	}
+}
+
+// CheckBackend tests the conformance of backend with the CPUBackend on the Ring r: it evaluates each operation of
+// the Backend on random inputs, at the maximum level and at level 0, and returns an error if a result differs from
+// the one of the CPUBackend.
+func CheckBackend(r *Ring, backend Backend, prng utils.PRNG) (err error) {
+
+	sampler := NewUniformSampler(prng, r)
+
+	p1 := make([]*Poly, 10)
+	p2 := make([]*Poly, 10)
+	for i := range p1 {
+		p1[i] = sampler.ReadNew()
+		p2[i] = sampler.ReadNew()
+	}
+
+	want, have := r.NewPoly(), r.NewPoly()
+
+	for _, level := range []uint64{uint64(len(r.Modulus) - 1), 0} {
+
+		checks := []struct {
+			name string
+			eval func(b Backend, pOut *Poly)
+		}{
+			{"NTTLvl", func(b Backend, pOut *Poly) { b.NTTLvl(r, level, p1[0], pOut) }},
+			{"InvNTTLvl", func(b Backend, pOut *Poly) { b.InvNTTLvl(r, level, p1[0], pOut) }},
+			{"NTTMFormLvl", func(b Backend, pOut *Poly) { b.NTTMFormLvl(r, level, p1[0], pOut) }},
+			{"InvNTTInvMFormLvl", func(b Backend, pOut *Poly) { b.InvNTTInvMFormLvl(r, level, p1[0], pOut) }},
+			{"MulCoeffsMontgomeryLvl", func(b Backend, pOut *Poly) { b.MulCoeffsMontgomeryLvl(r, level, p1[0], p2[0], pOut) }},
+			{"InnerProductMontgomeryLvl", func(b Backend, pOut *Poly) { b.InnerProductMontgomeryLvl(r, level, p1, p2, pOut) }},
+		}
+
+		for _, check := range checks {
+
+			check.eval(CPUBackend{}, want)
+			check.eval(backend, have)
+
+			if !r.EqualLvl(level, want, have) {
+				return fmt.Errorf("backend does not conform: %s differs from the CPUBackend at level %d", check.name, level)
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package ring
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"unsafe"
@@ -13,6 +15,7 @@ type FastBasisExtender struct {
 	ringP           *Ring
 	paramsQP        *modupParams
 	paramsPQ        *modupParams
+	paramsQPLvl     []*modupParams // Basis extensions from Q up to a level to P, see paramsQtoPLvl
 	modDownParamsPQ []uint64
 	modDownParamsQP []uint64
 	polypoolQ       *Poly
@@ -36,22 +39,9 @@ type modupParams struct {
 
 	bredParamsP [][]uint64
 	mredParamsP []uint64
-}
-
-func genModDownParams(ringP, ringQ *Ring) (params []uint64) {
-
-	params = make([]uint64, len(ringP.Modulus))
-
-	bredParams := ringP.GetBredParams()
-	tmp := new(big.Int)
-	for i, Qi := range ringP.Modulus {
-
-		params[i] = tmp.Mod(ringQ.ModulusBigint, NewUint(Qi)).Uint64()
-		params[i] = ModExp(params[i], Qi-2, Qi)
-		params[i] = MForm(params[i], Qi, bredParams[i])
-	}
 
-	return
+	// The accumulations modulo the Pj are reduced every lazyMaskP+1 additions, see lazyReductionPeriod
+	lazyMaskP uint64
 }
 
 // NewFastBasisExtender creates a new FastBasisExtender, enabling RNS basis extension from Q to P and P to Q.
@@ -65,8 +55,8 @@ func NewFastBasisExtender(ringQ, ringP *Ring) *FastBasisExtender {
 	newParams.paramsQP = basisextenderparameters(ringQ.Modulus, ringP.Modulus)
 	newParams.paramsPQ = basisextenderparameters(ringP.Modulus, ringQ.Modulus)
 
-	newParams.modDownParamsPQ = genModDownParams(ringQ, ringP)
-	newParams.modDownParamsQP = genModDownParams(ringP, ringQ)
+	newParams.modDownParamsPQ = GenModDownParams(ringQ.Modulus, ringP.Modulus)
+	newParams.modDownParamsQP = GenModDownParams(ringP.Modulus, ringQ.Modulus)
 
 	newParams.polypoolQ = ringQ.NewPoly()
 	newParams.polypoolP = ringP.NewPoly()
@@ -96,6 +86,8 @@ func basisextenderparameters(Q, P []uint64) (params *modupParams) {
 		params.mredParamsP[i] = MRedParams(pj)
 	}
 
+	params.lazyMaskP = lazyReductionPeriod(P) - 1
+
 	tmp := new(big.Int)
 	QiB := new(big.Int)
 	QiStar := new(big.Int)
@@ -143,100 +135,105 @@ func basisextenderparameters(Q, P []uint64) (params *modupParams) {
 	return
 }
 
-// ModUpSplitQP extends the RNS basis of a polynomial from Q to QP.
-// Given a polynomial with coefficients in basis {Q0,Q1....Qlevel},
-// it extends its basis from {Q0,Q1....Qlevel} to {Q0,Q1....Qlevel,P0,P1...Pj}
-func (basisextender *FastBasisExtender) ModUpSplitQP(level uint64, p1, p2 *Poly) {
-	modUpExact(p1.Coeffs[:level+1], p2.Coeffs[:uint64(len(basisextender.paramsQP.P))], basisextender.paramsQP)
+// The basis conversions below are between the moduli {q0, ..., qlevelQ} of Q up to a level and all the moduli of P.
+// They are exact, except if the correction term of a coefficient, estimated in floating point, is within about 2^-50
+// of an integer, in which case the converted coefficient can be off by a multiple of the product of the source moduli.
+//
+// A polynomial of QP is either split into a polynomial of Q and a polynomial of P, or contiguous: a single polynomial
+// whose moduli are the ones of Q followed by the ones of P, as the polynomials of the Ring of moduli append(Q, P).
+// The scratch polynomials of the FastBasisExtender are shared by its methods, which must therefore not be called
+// concurrently.
+
+// ModUpQtoP extends the RNS basis of p1 from the moduli {q0, ..., qlevelQ} of Q to the moduli of P and writes the
+// result on p2, such that p2 = p1 mod P. p1 and p2 are outside of the NTT domain.
+func (basisextender *FastBasisExtender) ModUpQtoP(levelQ uint64, p1, p2 *Poly) {
+	params := basisextender.paramsQtoPLvl(levelQ)
+	modUpExact(p1.Coeffs[:levelQ+1], p2.Coeffs[:len(params.P)], params)
 }
 
-// ModUpSplitPQ extends the RNS basis of a polynomial from P to PQ.
-// Given a polynomial with coefficients in basis {P0,P1....Plevel},
-// it extends its basis from {P0,P1....Plevel} to {Q0,Q1...Qj}
-func (basisextender *FastBasisExtender) ModUpSplitPQ(level uint64, p1, p2 *Poly) {
-	modUpExact(p1.Coeffs[:level+1], p2.Coeffs[:uint64(len(basisextender.paramsPQ.P))], basisextender.paramsPQ)
+// ModUpQtoPNTT is ModUpQtoP for p1 and p2 in the NTT domain. p1 is not modified.
+func (basisextender *FastBasisExtender) ModUpQtoPNTT(levelQ uint64, p1, p2 *Poly) {
+	polypool := basisextender.polypoolQ
+	basisextender.ringQ.InvNTTLvl(levelQ, p1, polypool)
+	basisextender.ModUpQtoP(levelQ, polypool, p2)
+	basisextender.ringP.NTT(p2, p2)
 }
 
-// ModDownNTTPQ reduces the basis RNS of a polynomial in the NTT domain
-// from QP to Q and divides its coefficients by P.
-// Given a polynomial with coefficients in basis {Q0,Q1....Qlevel,P0,P1...Pj},
-// it reduces its basis from {Q0,Q1....Qlevel,P0,P1...Pj} to {Q0,Q1....Qlevel}
-// and performs a rounded integer division of the result by P.
-// Inputs must be in the NTT domain.
-func (basisextender *FastBasisExtender) ModDownNTTPQ(level uint64, p1, p2 *Poly) {
+// ModUpPtoQ extends the RNS basis of p1 from the moduli of P to the moduli {q0, ..., qlevelQ} of Q and writes the
+// result on p2, such that p2 = p1 mod Q. p1 and p2 are outside of the NTT domain.
+func (basisextender *FastBasisExtender) ModUpPtoQ(levelQ uint64, p1, p2 *Poly) {
+	modUpExact(p1.Coeffs[:len(basisextender.paramsPQ.Q)], p2.Coeffs[:levelQ+1], basisextender.paramsPQ)
+}
 
-	ringQ := basisextender.ringQ
-	ringP := basisextender.ringP
-	modDownParams := basisextender.modDownParamsPQ
-	polypool := basisextender.polypoolQ
-	nQi := len(ringQ.Modulus)
-	nPj := len(ringP.Modulus)
+// ModUpPtoQNTT is ModUpPtoQ for p1 and p2 in the NTT domain. p1 is not modified.
+func (basisextender *FastBasisExtender) ModUpPtoQNTT(levelQ uint64, p1, p2 *Poly) {
+	polypool := basisextender.polypoolP
+	basisextender.ringP.InvNTT(p1, polypool)
+	basisextender.ModUpPtoQ(levelQ, polypool, p2)
+	basisextender.ringQ.NTTLvl(levelQ, p2, p2)
+}
 
-	// First we get the P basis part of p1 out of the NTT domain
-	for j := 0; j < nPj; j++ {
-		InvNTT(p1.Coeffs[nQi+j], p1.Coeffs[nQi+j], ringP.N, ringP.GetNttPsiInv()[j], ringP.GetNttNInv()[j], ringP.Modulus[j], ringP.GetMredParams()[j])
-	}
+// ModDownSplitQPtoQ reduces the RNS basis of the polynomial of QP split into p1Q, at level levelQ, and p1P, from QP to
+// Q, and divides it by P: it writes floor(p1/P) mod {q0, ..., qlevelQ} on p2, which can be p1Q. p1Q, p1P and p2 are
+// outside of the NTT domain.
+func (basisextender *FastBasisExtender) ModDownSplitQPtoQ(levelQ uint64, p1Q, p1P, p2 *Poly) {
+	basisextender.ModUpPtoQ(levelQ, p1P, basisextender.polypoolQ)
+	basisextender.modDownSubAndMulPInv(levelQ, p1Q, p2)
+}
 
-	// Then we target this P basis of p1 and convert it to a Q basis (at the "level" of p1) and copy it on polypool
-	// polypool is now the representation of the P basis of p1 but in basis Q (at the "level" of p1)
-	modUpExact(p1.Coeffs[nQi:nQi+nPj], polypool.Coeffs[:level+1], basisextender.paramsPQ)
+// ModDownSplitQPtoQNTT is ModDownSplitQPtoQ for p1Q, p1P and p2 in the NTT domain. p1P is not modified.
+func (basisextender *FastBasisExtender) ModDownSplitQPtoQNTT(levelQ uint64, p1Q, p1P, p2 *Poly) {
+	// In total we do len(P) + levelQ + 1 NTT, which is optimal (linear in the number of moduli of P and Q)
+	basisextender.ModUpPtoQNTT(levelQ, p1P, basisextender.polypoolQ)
+	basisextender.modDownSubAndMulPInv(levelQ, p1Q, p2)
+}
 
-	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (P^-1) * (p1 - polypool) mod Q
-	for i := uint64(0); i < level+1; i++ {
+// ModDownQPtoQ is ModDownSplitQPtoQ for a contiguous polynomial p1 of QP, whose moduli of P start after all the
+// moduli of Q, regardless of levelQ.
+func (basisextender *FastBasisExtender) ModDownQPtoQ(levelQ uint64, p1, p2 *Poly) {
+	basisextender.ModDownSplitQPtoQ(levelQ, p1, basisextender.splitP(p1), p2)
+}
 
-		qi := ringQ.Modulus[i]
-		p1tmp := p1.Coeffs[i]
-		p2tmp := p2.Coeffs[i]
-		p3tmp := polypool.Coeffs[i]
-		params := modDownParams[i]
-		mredParams := ringQ.MredParams[i]
-		bredParams := ringQ.BredParams[i]
+// ModDownQPtoQNTT is ModDownSplitQPtoQNTT for a contiguous polynomial p1 of QP, whose moduli of P start after all
+// the moduli of Q, regardless of levelQ.
+func (basisextender *FastBasisExtender) ModDownQPtoQNTT(levelQ uint64, p1, p2 *Poly) {
+	basisextender.ModDownSplitQPtoQNTT(levelQ, p1, basisextender.splitP(p1), p2)
+}
 
-		// First we switch back the relevant polypool CRT array back to the NTT domain
-		NTT(p3tmp, p3tmp, ringQ.N, ringQ.GetNttPsi()[i], qi, mredParams, bredParams)
+// splitP returns a polynomial sharing the coefficients modulo P of the contiguous polynomial p1 of QP.
+func (basisextender *FastBasisExtender) splitP(p1 *Poly) *Poly {
+	nQi := len(basisextender.ringQ.Modulus)
+	return &Poly{Coeffs: p1.Coeffs[nQi : nQi+len(basisextender.ringP.Modulus)]}
+}
 
-		// Then for each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
-		for j := uint64(0); j < ringQ.N; j = j + 8 {
+// paramsQtoPLvl returns the parameters of the basis extension from the moduli of Q up to levelQ to P, computing them
+// on their first use below the maximum level.
+func (basisextender *FastBasisExtender) paramsQtoPLvl(levelQ uint64) *modupParams {
 
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+	if levelQ == uint64(len(basisextender.ringQ.Modulus)-1) {
+		return basisextender.paramsQP
+	}
 
-			z[0] = MRed(x[0]+(qi-y[0]), params, qi, mredParams)
-			z[1] = MRed(x[1]+(qi-y[1]), params, qi, mredParams)
-			z[2] = MRed(x[2]+(qi-y[2]), params, qi, mredParams)
-			z[3] = MRed(x[3]+(qi-y[3]), params, qi, mredParams)
-			z[4] = MRed(x[4]+(qi-y[4]), params, qi, mredParams)
-			z[5] = MRed(x[5]+(qi-y[5]), params, qi, mredParams)
-			z[6] = MRed(x[6]+(qi-y[6]), params, qi, mredParams)
-			z[7] = MRed(x[7]+(qi-y[7]), params, qi, mredParams)
-		}
+	if basisextender.paramsQPLvl == nil {
+		basisextender.paramsQPLvl = make([]*modupParams, len(basisextender.ringQ.Modulus)-1)
 	}
 
-	// In total we do len(P) + len(Q) NTT, which is optimal (linear in the number of moduli of P and Q)
+	if basisextender.paramsQPLvl[levelQ] == nil {
+		basisextender.paramsQPLvl[levelQ] = basisextenderparameters(basisextender.ringQ.Modulus[:levelQ+1], basisextender.ringP.Modulus)
+	}
+
+	return basisextender.paramsQPLvl[levelQ]
 }
 
-// ModDownSplitNTTPQ reduces the basis of a polynomial.
-// Given a polynomial with coefficients in basis {Q0,Q1....Qi} and {P0,P1...Pj},
-// it reduces its basis from {Q0,Q1....Qi} and {P0,P1...Pj} to {Q0,Q1....Qi}
-// and does a rounded integer division of the result by P.
-// Inputs must be in the NTT domain.
-func (basisextender *FastBasisExtender) ModDownSplitNTTPQ(level uint64, p1Q, p1P, p2 *Poly) {
+// modDownSubAndMulPInv computes p2 = (p1Q - polypoolQ) * P^-1 mod {q0, ..., qlevelQ}, where polypoolQ holds the
+// extension to Q of the moduli of P of the polynomial, in the same domain as p1Q.
+func (basisextender *FastBasisExtender) modDownSubAndMulPInv(levelQ uint64, p1Q, p2 *Poly) {
 
 	ringQ := basisextender.ringQ
-	ringP := basisextender.ringP
 	modDownParams := basisextender.modDownParamsPQ
 	polypool := basisextender.polypoolQ
 
-	// First we get the P basis part of p1 out of the NTT domain
-	ringP.InvNTT(p1P, p1P)
-
-	// Then we target this P basis of p1 and convert it to a Q basis (at the "level" of p1) and copy it on polypool
-	// polypool is now the representation of the P basis of p1 but in basis Q (at the "level" of p1)
-	modUpExact(p1P.Coeffs, polypool.Coeffs[:level+1], basisextender.paramsPQ)
-
-	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (P^-1) * (p1 - polypool) mod Q
-	for i := uint64(0); i < level+1; i++ {
+	for i := uint64(0); i < levelQ+1; i++ {
 
 		qi := ringQ.Modulus[i]
 		p1tmp := p1Q.Coeffs[i]
@@ -244,12 +241,8 @@ func (basisextender *FastBasisExtender) ModDownSplitNTTPQ(level uint64, p1Q, p1P
 		p3tmp := polypool.Coeffs[i]
 		params := modDownParams[i]
 		mredParams := ringQ.MredParams[i]
-		bredParams := ringQ.BredParams[i]
 
-		// First we switch back the relevant polypool CRT array back to the NTT domain
-		NTT(p3tmp, p3tmp, ringQ.N, ringQ.GetNttPsi()[i], ringQ.Modulus[i], mredParams, bredParams)
-
-		// Then for each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
+		// For each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
 		for j := uint64(0); j < ringQ.N; j = j + 8 {
 
 			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
@@ -266,99 +259,65 @@ func (basisextender *FastBasisExtender) ModDownSplitNTTPQ(level uint64, p1Q, p1P
 			z[7] = MRed(x[7]+(qi-y[7]), params, qi, mredParams)
 		}
 	}
+}
+
+// ModUpSplitQP extends the RNS basis of a polynomial from Q to QP.
+// Given a polynomial with coefficients in basis {Q0,Q1....Qlevel},
+// it extends its basis from {Q0,Q1....Qlevel} to {Q0,Q1....Qlevel,P0,P1...Pj}
+//
+// Deprecated: use ModUpQtoP.
+func (basisextender *FastBasisExtender) ModUpSplitQP(level uint64, p1, p2 *Poly) {
+	basisextender.ModUpQtoP(level, p1, p2)
+}
+
+// ModUpSplitPQ extends the RNS basis of a polynomial from P to PQ.
+// Given a polynomial with coefficients in basis {P0,P1....Plevel},
+// it extends its basis from {P0,P1....Plevel} to {Q0,Q1...Qj}
+func (basisextender *FastBasisExtender) ModUpSplitPQ(level uint64, p1, p2 *Poly) {
+	modUpExact(p1.Coeffs[:level+1], p2.Coeffs[:uint64(len(basisextender.paramsPQ.P))], basisextender.paramsPQ)
+}
 
-	// In total we do len(P) + len(Q) NTT, which is optimal (linear in the number of moduli of P and Q)
+// ModDownNTTPQ reduces the basis RNS of a polynomial in the NTT domain
+// from QP to Q and divides its coefficients by P.
+// Given a polynomial with coefficients in basis {Q0,Q1....Qlevel,P0,P1...Pj},
+// it reduces its basis from {Q0,Q1....Qlevel,P0,P1...Pj} to {Q0,Q1....Qlevel}
+// and performs a rounded integer division of the result by P.
+// Inputs must be in the NTT domain.
+//
+// Deprecated: use ModDownQPtoQNTT.
+func (basisextender *FastBasisExtender) ModDownNTTPQ(level uint64, p1, p2 *Poly) {
+	basisextender.ModDownQPtoQNTT(level, p1, p2)
+}
+
+// ModDownSplitNTTPQ reduces the basis of a polynomial.
+// Given a polynomial with coefficients in basis {Q0,Q1....Qi} and {P0,P1...Pj},
+// it reduces its basis from {Q0,Q1....Qi} and {P0,P1...Pj} to {Q0,Q1....Qi}
+// and does a rounded integer division of the result by P.
+// Inputs must be in the NTT domain.
+//
+// Deprecated: use ModDownSplitQPtoQNTT.
+func (basisextender *FastBasisExtender) ModDownSplitNTTPQ(level uint64, p1Q, p1P, p2 *Poly) {
+	basisextender.ModDownSplitQPtoQNTT(level, p1Q, p1P, p2)
 }
 
 // ModDownPQ reduces the basis of a polynomial.
 // Given a polynomial with coefficients in basis {Q0,Q1....Qlevel,P0,P1...Pj},
 // it reduces its basis from {Q0,Q1....Qlevel,P0,P1...Pj} to {Q0,Q1....Qlevel}
 // and does a rounded integer division of the result by P.
+// Unlike for ModDownQPtoQ, the moduli of P start right after Qlevel.
 func (basisextender *FastBasisExtender) ModDownPQ(level uint64, p1, p2 *Poly) {
-
-	ringQ := basisextender.ringQ
-	modDownParams := basisextender.modDownParamsPQ
-	polypool := basisextender.polypoolQ
-	nPi := uint64(len(basisextender.paramsQP.P))
-
-	// We target this P basis of p1 and convert it to a Q basis (at the "level" of p1) and copy it on polypool
-	// polypool is now the representation of the P basis of p1 but in basis Q (at the "level" of p1)
-	modUpExact(p1.Coeffs[level+1:level+1+nPi], polypool.Coeffs[:level+1], basisextender.paramsPQ)
-
-	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (P^-1) * (p1 - polypool) mod Q
-	for i := uint64(0); i < level+1; i++ {
-
-		qi := ringQ.Modulus[i]
-		p1tmp := p1.Coeffs[i]
-		p2tmp := p2.Coeffs[i]
-		p3tmp := polypool.Coeffs[i]
-		params := modDownParams[i]
-		mredParams := ringQ.MredParams[i]
-
-		// Then for each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
-		for j := uint64(0); j < ringQ.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-
-			z[0] = MRed(x[0]+(qi-y[0]), params, qi, mredParams)
-			z[1] = MRed(x[1]+(qi-y[1]), params, qi, mredParams)
-			z[2] = MRed(x[2]+(qi-y[2]), params, qi, mredParams)
-			z[3] = MRed(x[3]+(qi-y[3]), params, qi, mredParams)
-			z[4] = MRed(x[4]+(qi-y[4]), params, qi, mredParams)
-			z[5] = MRed(x[5]+(qi-y[5]), params, qi, mredParams)
-			z[6] = MRed(x[6]+(qi-y[6]), params, qi, mredParams)
-			z[7] = MRed(x[7]+(qi-y[7]), params, qi, mredParams)
-		}
-	}
-
-	// In total we do len(P) + len(Q) NTT, which is optimal (linear in the number of moduli of P and Q)
+	nPi := uint64(len(basisextender.ringP.Modulus))
+	basisextender.ModDownSplitQPtoQ(level, p1, &Poly{Coeffs: p1.Coeffs[level+1 : level+1+nPi]}, p2)
 }
 
 // ModDownSplitPQ reduces the basis of a polynomial.
 // Given a polynomial with coefficients in basis {Q0,Q1....Qlevel} and {P0,P1...Pj},
 // it reduces its basis from {Q0,Q1....Qlevel} and {P0,P1...Pj} to {Q0,Q1....Qlevel}
 // and does a rounded integer division of the result by P.
+//
+// Deprecated: use ModDownSplitQPtoQ.
 func (basisextender *FastBasisExtender) ModDownSplitPQ(level uint64, p1Q, p1P, p2 *Poly) {
-
-	ringQ := basisextender.ringQ
-	modDownParams := basisextender.modDownParamsPQ
-	polypool := basisextender.polypoolQ
-
-	// Then we target this P basis of p1 and convert it to a Q basis (at the "level" of p1) and copy it on polypool
-	// polypool is now the representation of the P basis of p1 but in basis Q (at the "level" of p1)
-	modUpExact(p1P.Coeffs, polypool.Coeffs[:level+1], basisextender.paramsPQ)
-
-	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (P^-1) * (p1 - polypool) mod Q
-	for i := uint64(0); i < level+1; i++ {
-
-		qi := ringQ.Modulus[i]
-		p1tmp := p1Q.Coeffs[i]
-		p2tmp := p2.Coeffs[i]
-		p3tmp := polypool.Coeffs[i]
-		params := modDownParams[i]
-		mredParams := ringQ.MredParams[i]
-
-		// Then for each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
-		for j := uint64(0); j < ringQ.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-
-			z[0] = MRed(x[0]+(qi-y[0]), params, qi, mredParams)
-			z[1] = MRed(x[1]+(qi-y[1]), params, qi, mredParams)
-			z[2] = MRed(x[2]+(qi-y[2]), params, qi, mredParams)
-			z[3] = MRed(x[3]+(qi-y[3]), params, qi, mredParams)
-			z[4] = MRed(x[4]+(qi-y[4]), params, qi, mredParams)
-			z[5] = MRed(x[5]+(qi-y[5]), params, qi, mredParams)
-			z[6] = MRed(x[6]+(qi-y[6]), params, qi, mredParams)
-			z[7] = MRed(x[7]+(qi-y[7]), params, qi, mredParams)
-		}
-	}
-
-	// In total we do len(P) + len(Q) NTT, which is optimal (linear in the number of moduli of P and Q)
+	basisextender.ModDownSplitQPtoQ(level, p1Q, p1P, p2)
 }
 
 // ModDownSplitQP reduces the basis of a polynomial.
@@ -371,11 +330,11 @@ func (basisextender *FastBasisExtender) ModDownSplitQP(levelQ, levelP uint64, p1
 	modDownParams := basisextender.modDownParamsQP
 	polypool := basisextender.polypoolP
 
-	// Then we target this P basis of p1 and convert it to a Q basis (at the "level" of p1) and copy it on polypool
-	// polypool is now the representation of the P basis of p1 but in basis Q (at the "level" of p1)
-	basisextender.ModUpSplitQP(levelQ, p1Q, polypool)
+	// Then we target this Q basis of p1 and convert it to a P basis (at the "level" of p1) and copy it on polypool
+	// polypool is now the representation of the Q basis of p1 but in basis P (at the "level" of p1)
+	basisextender.ModUpQtoP(levelQ, p1Q, polypool)
 
-	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (P^-1) * (p1 - polypool) mod Q
+	// Finally, for each level of p1 (and polypool since they now share the same basis) we compute p2 = (Q^-1) * (p1 - polypool) mod P
 	for i := uint64(0); i < levelP+1; i++ {
 
 		qi := ringP.Modulus[i]
@@ -385,13 +344,11 @@ func (basisextender *FastBasisExtender) ModDownSplitQP(levelQ, levelP uint64, p1
 		params := modDownParams[i]
 		mredParams := ringP.MredParams[i]
 
-		// Then for each coefficient we compute (P^-1) * (p1[i][j] - polypool[i][j]) mod qi
+		// Then for each coefficient we compute (Q^-1) * (p1[i][j] - polypool[i][j]) mod qi
 		for j := uint64(0); j < ringP.N; j++ {
 			p2tmp[j] = MRed(p1tmp[j]+(qi-p3tmp[j]), params, qi, mredParams)
 		}
 	}
-
-	// In total we do len(P) + len(Q) NTT, which is optimal (linear in the number of moduli of P and Q)
 }
 
 func modUpExact(p1, p2 [][]uint64, params *modupParams) {
@@ -469,7 +426,7 @@ func modUpExact(p1, p2 [][]uint64, params *modupParams) {
 				xpj6 += MRed(y6[i], qispjMont[i], pj, mredParams)
 				xpj7 += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-				if i&7 == 6 { //Only every 7 additions, since we add one more 60 bit integer after the loop
+				if uint64(i)&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 					xpj0 = BRedAdd(xpj0, pj, bredParams)
 					xpj1 = BRedAdd(xpj1, pj, bredParams)
 					xpj2 = BRedAdd(xpj2, pj, bredParams)
@@ -497,24 +454,69 @@ func modUpExact(p1, p2 [][]uint64, params *modupParams) {
 // Decomposer is a structure that stores the parameters of the arbitrary decomposer.
 // This decomposer takes a p(x)_Q (in basis Q) and returns p(x) mod qi in basis QP, where
 // qi = prod(Q_i) for 0<=i<=L, where L is the number of factors in P.
+// The i-th element of the decomposition is the product of the xalpha[i] consecutive
+// moduli of Q following the ones of the previous elements.
 type Decomposer struct {
 	nQprimes    uint64
 	nPprimes    uint64
-	alpha       uint64
 	beta        uint64
 	xalpha      []uint64
+	digitStart  []uint64
 	modUpParams [][]*modupParams
 	QInt        *big.Int
 	PInt        *big.Int
 }
 
-// Xalpha returns a slice that contains all the values of #Qi/#Pi.
+// Xalpha returns a slice that contains the number of moduli of Q of each element of the decomposition.
 func (decomposer *Decomposer) Xalpha() (xalpha []uint64) {
 	return decomposer.xalpha
 }
 
-// NewDecomposer creates a new Decomposer.
+// NewDecomposer creates a new Decomposer whose elements are the product of #Pi moduli of Q,
+// except for the last one which has the remaining #Qi mod #Pi moduli.
 func NewDecomposer(Q, P []uint64) (decomposer *Decomposer) {
+
+	alpha := uint64(len(P))
+	beta := uint64(math.Ceil(float64(len(Q)) / float64(alpha)))
+
+	xalpha := make([]uint64, beta)
+	for i := range xalpha {
+		xalpha[i] = alpha
+	}
+
+	if uint64(len(Q))%alpha != 0 {
+		xalpha[beta-1] = uint64(len(Q)) % alpha
+	}
+
+	return newDecomposer(Q, P, xalpha)
+}
+
+// NewArbitraryDecomposer creates a new Decomposer whose i-th element is the product of xalpha[i] moduli of Q.
+// The elements can have more moduli than P, which reduces the number of elements of the decomposition at
+// the cost of a larger error when the decomposition is used for the key-switching, as this error is
+// proportional to the ratio between the largest element and P.
+func NewArbitraryDecomposer(Q, P, xalpha []uint64) (decomposer *Decomposer, err error) {
+
+	if len(P) == 0 {
+		return nil, errors.New("invalid decomposition: P is empty")
+	}
+
+	var sum uint64
+	for _, alphai := range xalpha {
+		if alphai == 0 {
+			return nil, errors.New("invalid decomposition: elements must have at least one modulus")
+		}
+		sum += alphai
+	}
+
+	if sum != uint64(len(Q)) {
+		return nil, fmt.Errorf("invalid decomposition: elements have %d moduli but Q has %d", sum, len(Q))
+	}
+
+	return newDecomposer(Q, P, append([]uint64{}, xalpha...)), nil
+}
+
+func newDecomposer(Q, P, xalpha []uint64) (decomposer *Decomposer) {
 	decomposer = new(Decomposer)
 
 	decomposer.nQprimes = uint64(len(Q))
@@ -530,16 +532,12 @@ func NewDecomposer(Q, P []uint64) (decomposer *Decomposer) {
 		decomposer.PInt.Mul(decomposer.PInt, NewUint(P[i]))
 	}
 
-	decomposer.alpha = uint64(len(P))
-	decomposer.beta = uint64(math.Ceil(float64(len(Q)) / float64(decomposer.alpha)))
-
-	decomposer.xalpha = make([]uint64, decomposer.beta)
-	for i := range decomposer.xalpha {
-		decomposer.xalpha[i] = decomposer.alpha
-	}
+	decomposer.beta = uint64(len(xalpha))
+	decomposer.xalpha = xalpha
 
-	if uint64(len(Q))%decomposer.alpha != 0 {
-		decomposer.xalpha[decomposer.beta-1] = uint64(len(Q)) % decomposer.alpha
+	decomposer.digitStart = make([]uint64, decomposer.beta)
+	for i := uint64(1); i < decomposer.beta; i++ {
+		decomposer.digitStart[i] = decomposer.digitStart[i-1] + xalpha[i-1]
 	}
 
 	decomposer.modUpParams = make([][]*modupParams, decomposer.beta)
@@ -555,7 +553,7 @@ func NewDecomposer(Q, P []uint64) (decomposer *Decomposer) {
 			Pi := make([]uint64, len(Q)+len(P))
 
 			for k := uint64(0); k < j+2; k++ {
-				Qi[k] = Q[i*decomposer.alpha+k]
+				Qi[k] = Q[decomposer.digitStart[i]+k]
 			}
 
 			for k := 0; k < len(Q); k++ {
@@ -579,11 +577,11 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 
 	alphai := decomposer.xalpha[crtDecompLevel]
 
-	p0idxst := crtDecompLevel * decomposer.alpha
+	p0idxst := decomposer.digitStart[crtDecompLevel]
 	p0idxed := p0idxst + alphai
 
 	// First we check if the vector can simply by coping and rearranging elements (the case where no reconstruction is needed)
-	if (p0idxed > level+1 && (level+1)%decomposer.nPprimes == 1) || alphai == 1 {
+	if (p0idxed > level+1 && level == p0idxst) || alphai == 1 {
 
 		for x := uint64(0); x < uint64(len(p0.Coeffs[0])); x = x + 8 {
 
@@ -606,10 +604,10 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 	} else {
 
 		var index uint64
-		if level >= alphai+crtDecompLevel*decomposer.alpha {
-			index = decomposer.xalpha[crtDecompLevel] - 2
+		if level >= p0idxed {
+			index = alphai - 2
 		} else {
-			index = (level - 1) % decomposer.alpha
+			index = level - p0idxst - 1
 		}
 
 		params := decomposer.modUpParams[crtDecompLevel][index]
@@ -709,7 +707,7 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -733,7 +731,7 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 			}
 
 			// Coefficients of index greater than the ones to be decomposed
-			for j := decomposer.alpha * crtDecompLevel; j < level+1; j = j + 1 {
+			for j := p0idxst; j < level+1; j = j + 1 {
 
 				xpj[0], xpj[1], xpj[2], xpj[3], xpj[4], xpj[5], xpj[6], xpj[7] = 0, 0, 0, 0, 0, 0, 0, 0
 
@@ -755,7 +753,7 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -801,7 +799,7 @@ func (decomposer *Decomposer) Decompose(level, crtDecompLevel uint64, p0, p1 *Po
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -832,11 +830,11 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 
 	alphai := decomposer.xalpha[crtDecompLevel]
 
-	p0idxst := crtDecompLevel * decomposer.alpha
+	p0idxst := decomposer.digitStart[crtDecompLevel]
 	p0idxed := p0idxst + alphai
 
 	// First we check if the vector can simply by coping and rearranging elements (the case where no reconstruction is needed)
-	if (p0idxed > level+1 && (level+1)%decomposer.nPprimes == 1) || alphai == 1 {
+	if (p0idxed > level+1 && level == p0idxst) || alphai == 1 {
 
 		for x := uint64(0); x < uint64(len(p0.Coeffs[0])); x = x + 8 {
 
@@ -871,10 +869,10 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 	} else {
 
 		var index uint64
-		if level >= alphai+crtDecompLevel*decomposer.alpha {
-			index = decomposer.xalpha[crtDecompLevel] - 2
+		if level >= p0idxed {
+			index = alphai - 2
 		} else {
-			index = (level - 1) % decomposer.alpha
+			index = level - p0idxst - 1
 		}
 
 		params := decomposer.modUpParams[crtDecompLevel][index]
@@ -974,7 +972,7 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -998,7 +996,7 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 			}
 
 			// Coefficients of index greater than the ones to be decomposed
-			for j := decomposer.alpha * crtDecompLevel; j < level+1; j = j + 1 {
+			for j := p0idxst; j < level+1; j = j + 1 {
 
 				xpj[0], xpj[1], xpj[2], xpj[3], xpj[4], xpj[5], xpj[6], xpj[7] = 0, 0, 0, 0, 0, 0, 0, 0
 
@@ -1020,7 +1018,7 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -1066,7 +1064,7 @@ func (decomposer *Decomposer) DecomposeAndSplit(level, crtDecompLevel uint64, p0
 					xpj[6] += MRed(y6[i], qispjMont[i], pj, mredParams)
 					xpj[7] += MRed(y7[i], qispjMont[i], pj, mredParams)
 
-					if i&7 == 6 { // Only every 7 additions, since we add one more 60 bit integer after the loop
+					if i&params.lazyMaskP == params.lazyMaskP-1 { // Only every lazyMaskP+1 additions, since we add one more integer in [0, pj) after the loop
 						xpj[0] = BRedAdd(xpj[0], pj, bredParams)
 						xpj[1] = BRedAdd(xpj[1], pj, bredParams)
 						xpj[2] = BRedAdd(xpj[2], pj, bredParams)
@@ -155,6 +155,12 @@ func benchNTT(testContext *testParams, b *testing.B) {
 		}
 	})
 
+	b.Run(testString("NTT/NTTParallel/Montgomery/", testContext.ringQ), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			testContext.ringQ.NTTParallel(p, p)
+		}
+	})
+
 	b.Run(testString("NTT/NTT/Barrett/", testContext.ringQ), func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			testContext.ringQ.NTTBarrett(p, p)
@@ -285,19 +291,19 @@ func benchExtendBasis(testContext *testParams, b *testing.B) {
 
 	b.Run(fmt.Sprintf("ExtendBasis/ModUp/N=%d/limbsQ=%d/limbsP=%d", testContext.ringQ.N, len(testContext.ringQ.Modulus), len(testContext.ringP.Modulus)), func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			basisExtender.ModUpSplitQP(level, p0, p1)
+			basisExtender.ModUpQtoP(level, p0, p1)
 		}
 	})
 
 	b.Run(fmt.Sprintf("ExtendBasis/ModDown/N=%d/limbsQ=%d/limbsP=%d", testContext.ringQ.N, len(testContext.ringQ.Modulus), len(testContext.ringP.Modulus)), func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			basisExtender.ModDownSplitPQ(level, p0, p1, p0)
+			basisExtender.ModDownSplitQPtoQ(level, p0, p1, p0)
 		}
 	})
 
 	b.Run(fmt.Sprintf("ExtendBasis/ModDownNTT/N=%d/limbsQ=%d/limbsP=%d", testContext.ringQ.N, len(testContext.ringQ.Modulus), len(testContext.ringP.Modulus)), func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			basisExtender.ModDownSplitNTTPQ(level, p0, p1, p0)
+			basisExtender.ModDownSplitQPtoQNTT(level, p0, p1, p0)
 		}
 	})
 }
@@ -0,0 +1,249 @@
+package ring
+
+// ConjugateInvariantRing is a structure that keeps all the variables required to operate on a polynomial represented
+// in the conjugate invariant ring Z_Q[X + X^-1]/(X^2N + 1), that is the subring of Z_Q[X]/(X^2N + 1) of the
+// polynomials invariant under X -> X^-1. Such a polynomial is a_0 + sum_{i=1}^{N-1} a_i * (X^i + X^-i), since
+// X^N + X^-N = 0, and is stored as its N coefficients a_0, ..., a_(N-1). Its evaluations on the conjugate 4N-th roots
+// of unity psi^j and psi^-j are equal, hence its NTT is given by N evaluations: the CKKS variant on this ring encodes
+// N real slots in polynomials of degree N instead of 2N.
+//
+// The moduli must be primes congruent to 1 modulo 4N. The coefficient-wise operations are computed on a Ring of degree
+// N. The NTT is an N-point transform: X^2N + 1 = (X^N - zeta)(X^N + zeta) with zeta^2 = -1 splits each pair of conjugate
+// roots between its two factors, hence the N evaluations are the NTT of the reduction of the polynomial modulo
+// X^N - zeta, computed with the last log2(N) stages of the NTT of degree 2N.
+type ConjugateInvariantRing struct {
+
+	// Number of coefficients and of NTT slots
+	N uint64
+
+	Modulus []uint64
+
+	ringN  *Ring
+	ring2N *Ring
+
+	// Twiddle factors of the last log2(N) stages of the NTT of degree 2N, indexed as the ones of an NTT of degree N
+	nttPsi    [][]uint64
+	nttPsiInv [][]uint64
+}
+
+// NewConjugateInvariantRing creates a new ConjugateInvariantRing with N coefficients and the given moduli. It returns
+// an error if the moduli do not allow the NTT of degree 2N, and panics if N is not a power of two greater than or
+// equal to 16, the smallest degree of the unrolled stages of the NTT.
+func NewConjugateInvariantRing(N uint64, moduli []uint64) (r *ConjugateInvariantRing, err error) {
+
+	if N < 16 || N&(N-1) != 0 {
+		panic("cannot NewConjugateInvariantRing: N must be a power of two greater than or equal to 16")
+	}
+
+	r = new(ConjugateInvariantRing)
+
+	r.N = N
+
+	// The Ring of degree N is only used for its coefficient-wise operations, thus its NTT parameters are not generated
+	r.ringN = new(Ring)
+	r.ringN.setParameters(N, moduli)
+
+	if r.ring2N, err = NewRing(2*N, moduli); err != nil {
+		return nil, err
+	}
+
+	r.Modulus = r.ringN.Modulus
+
+	// The m butterflies of a stage of the NTT of degree N are the first m of the 2m butterflies of the same stage of
+	// the NTT of degree 2N, i.e. the ones on its first N coefficients
+	r.nttPsi = make([][]uint64, len(r.Modulus))
+	r.nttPsiInv = make([][]uint64, len(r.Modulus))
+	for i := range r.Modulus {
+		r.nttPsi[i] = make([]uint64, N)
+		r.nttPsiInv[i] = make([]uint64, N)
+		for m := uint64(1); m < N; m <<= 1 {
+			copy(r.nttPsi[i][m:2*m], r.ring2N.NttPsi[i][2*m:3*m])
+			copy(r.nttPsiInv[i][m:2*m], r.ring2N.NttPsiInv[i][2*m:3*m])
+		}
+	}
+
+	return r, nil
+}
+
+// FullRing returns the Ring Z_Q[X]/(X^2N + 1) in which the ConjugateInvariantRing is embedded.
+func (r *ConjugateInvariantRing) FullRing() *Ring {
+	return r.ring2N
+}
+
+// NewPoly creates a new polynomial with N coefficients set to 0.
+func (r *ConjugateInvariantRing) NewPoly() *Poly {
+	return r.ringN.NewPoly()
+}
+
+// NewPolyLvl creates a new polynomial with N coefficients set to 0 and level+1 moduli.
+func (r *ConjugateInvariantRing) NewPolyLvl(level uint64) *Poly {
+	return r.ringN.NewPolyLvl(level)
+}
+
+// Embed maps p1, in the ConjugateInvariantRing, to p2, in the Ring Z_Q[X]/(X^2N + 1), by writing
+// a_0 + sum_{i=1}^{N-1} a_i * (X^i + X^-i) with X^-i = -X^(2N-i).
+func (r *ConjugateInvariantRing) Embed(p1, p2 *Poly) {
+	r.EmbedLvl(uint64(len(p1.Coeffs)-1), p1, p2)
+}
+
+// EmbedLvl maps p1 to p2 in the Ring Z_Q[X]/(X^2N + 1), see Embed. The value level defines the number of moduli of the
+// input polynomials.
+func (r *ConjugateInvariantRing) EmbedLvl(level uint64, p1, p2 *Poly) {
+
+	N := r.N
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi := r.Modulus[i]
+		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
+
+		copy(p2tmp[:N], p1tmp[:N])
+
+		p2tmp[N] = 0
+		for j := uint64(1); j < N; j++ {
+			p2tmp[2*N-j] = CRed(qi-p1tmp[j], qi)
+		}
+	}
+}
+
+// Restrict maps p1, a polynomial of Z_Q[X]/(X^2N + 1) invariant under X -> X^-1, to p2 in the ConjugateInvariantRing,
+// by keeping its first N coefficients.
+func (r *ConjugateInvariantRing) Restrict(p1, p2 *Poly) {
+	r.RestrictLvl(uint64(len(p2.Coeffs)-1), p1, p2)
+}
+
+// RestrictLvl maps p1 to p2 in the ConjugateInvariantRing, see Restrict. The value level defines the number of moduli
+// of the input polynomials.
+func (r *ConjugateInvariantRing) RestrictLvl(level uint64, p1, p2 *Poly) {
+	for i := uint64(0); i < level+1; i++ {
+		copy(p2.Coeffs[i][:r.N], p1.Coeffs[i][:r.N])
+	}
+}
+
+// NTT computes the NTT of p1 and returns the result on p2. The j-th slot of the NTT domain is the evaluation of p1 on
+// the same 4N-th root of unity as the j-th coefficient of the NTT of the Ring of degree 2N, whose (2N-1-j)-th
+// coefficient is the evaluation on the conjugate root.
+func (r *ConjugateInvariantRing) NTT(p1, p2 *Poly) {
+	r.NTTLvl(uint64(len(p1.Coeffs)-1), p1, p2)
+}
+
+// NTTLvl computes the NTT of p1 and returns the result on p2. The value level defines the number of moduli of the
+// input polynomials. It costs an NTT of degree N and N multiplications per modulus, and p2 can be p1.
+func (r *ConjugateInvariantRing) NTTLvl(level uint64, p1, p2 *Poly) {
+
+	N := r.N
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi, mredParams := r.Modulus[i], r.ringN.MredParams[i]
+		zeta := r.ring2N.NttPsi[i][1]
+		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
+
+		// a_0 + sum_{j=1}^{N-1} a_j * (X^j - X^(2N-j)) mod X^N - zeta, whose j-th coefficient is a_j - zeta * a_(N-j)
+		p2tmp[0] = p1tmp[0]
+		for j := uint64(1); j <= N>>1; j++ {
+			x, y := p1tmp[j], p1tmp[N-j]
+			p2tmp[j] = CRed(x+qi-MRed(y, zeta, qi, mredParams), qi)
+			p2tmp[N-j] = CRed(y+qi-MRed(x, zeta, qi, mredParams), qi)
+		}
+
+		NTTBlocked(p2tmp, p2tmp, N, r.nttPsi[i], qi, mredParams, r.ringN.BredParams[i], r.ring2N.nttBlockSize)
+	}
+}
+
+// InvNTT computes the inverse NTT of p1 and returns the result on p2.
+func (r *ConjugateInvariantRing) InvNTT(p1, p2 *Poly) {
+	r.InvNTTLvl(uint64(len(p1.Coeffs)-1), p1, p2)
+}
+
+// InvNTTLvl computes the inverse NTT of p1 and returns the result on p2. The value level defines the number of moduli
+// of the input polynomials. It costs an inverse NTT of degree N and N multiplications per modulus, and p2 can be p1.
+func (r *ConjugateInvariantRing) InvNTTLvl(level uint64, p1, p2 *Poly) {
+
+	N := r.N
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi, mredParams := r.Modulus[i], r.ringN.MredParams[i]
+		zeta := r.ring2N.NttPsi[i][1]
+		p2tmp := p2.Coeffs[i]
+
+		// Multiplies by (2N)^-1 instead of N^-1, hence returns half of the reduction modulo X^N - zeta
+		invNTTLazy(p1.Coeffs[i], p2tmp, N, r.nttPsiInv[i], qi, mredParams, r.ring2N.nttBlockSize)
+		invNTTFinalize(p2tmp, N, r.ring2N.NttNInv[i], qi, mredParams)
+
+		// Since zeta^2 = -1, a_j = (c_j + zeta * c_(N-j)) / 2 for the j-th coefficient c_j of the reduction, and a_0 = c_0
+		p2tmp[0] = CRed(p2tmp[0]<<1, qi)
+		for j := uint64(1); j <= N>>1; j++ {
+			x, y := p2tmp[j], p2tmp[N-j]
+			p2tmp[j] = CRed(x+MRed(y, zeta, qi, mredParams), qi)
+			p2tmp[N-j] = CRed(y+MRed(x, zeta, qi, mredParams), qi)
+		}
+	}
+}
+
+// Add adds p1 to p2 coefficient-wise and writes the result on p3.
+func (r *ConjugateInvariantRing) Add(p1, p2, p3 *Poly) {
+	r.ringN.Add(p1, p2, p3)
+}
+
+// AddLvl adds p1 to p2 coefficient-wise for a given level and writes the result on p3.
+func (r *ConjugateInvariantRing) AddLvl(level uint64, p1, p2, p3 *Poly) {
+	r.ringN.AddLvl(level, p1, p2, p3)
+}
+
+// Sub subtracts p2 to p1 coefficient-wise and writes the result on p3.
+func (r *ConjugateInvariantRing) Sub(p1, p2, p3 *Poly) {
+	r.ringN.Sub(p1, p2, p3)
+}
+
+// SubLvl subtracts p2 to p1 coefficient-wise for a given level and writes the result on p3.
+func (r *ConjugateInvariantRing) SubLvl(level uint64, p1, p2, p3 *Poly) {
+	r.ringN.SubLvl(level, p1, p2, p3)
+}
+
+// Neg sets all coefficients of p1 to their additive inverse and writes the result on p2.
+func (r *ConjugateInvariantRing) Neg(p1, p2 *Poly) {
+	r.ringN.Neg(p1, p2)
+}
+
+// MulScalar multiplies each coefficient of p1 by a scalar and writes the result on p2.
+func (r *ConjugateInvariantRing) MulScalar(p1 *Poly, scalar uint64, p2 *Poly) {
+	r.ringN.MulScalar(p1, scalar, p2)
+}
+
+// MForm switches p1 to the Montgomery domain and writes the result on p2.
+func (r *ConjugateInvariantRing) MForm(p1, p2 *Poly) {
+	r.ringN.MForm(p1, p2)
+}
+
+// MulCoeffs multiplies p1 by p2 coefficient-wise with a Barrett modular reduction and writes the result on p3.
+func (r *ConjugateInvariantRing) MulCoeffs(p1, p2, p3 *Poly) {
+	r.ringN.MulCoeffs(p1, p2, p3)
+}
+
+// MulCoeffsMontgomery multiplies p1 by p2 coefficient-wise with a Montgomery modular reduction and writes the result
+// on p3.
+func (r *ConjugateInvariantRing) MulCoeffsMontgomery(p1, p2, p3 *Poly) {
+	r.ringN.MulCoeffsMontgomery(p1, p2, p3)
+}
+
+// MulCoeffsMontgomeryLvl multiplies p1 by p2 coefficient-wise with a Montgomery modular reduction for a given level
+// and writes the result on p3.
+func (r *ConjugateInvariantRing) MulCoeffsMontgomeryLvl(level uint64, p1, p2, p3 *Poly) {
+	r.ringN.MulCoeffsMontgomeryLvl(level, p1, p2, p3)
+}
+
+// MulPoly multiplies p1 by p2 and writes the result on p3. The inputs and the output are in the coefficient domain.
+func (r *ConjugateInvariantRing) MulPoly(p1, p2, p3 *Poly) {
+	a, b := r.NewPoly(), r.NewPoly()
+	r.NTT(p1, a)
+	r.NTT(p2, b)
+	r.MulCoeffs(a, b, a)
+	r.InvNTT(a, p3)
+}
+
+// Equal checks if p1 = p2 in the ConjugateInvariantRing.
+func (r *ConjugateInvariantRing) Equal(p1, p2 *Poly) bool {
+	return r.ringN.Equal(p1, p2)
+}
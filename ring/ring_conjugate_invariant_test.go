@@ -0,0 +1,96 @@
+package ring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ldsec/lattigo/v2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConjugateInvariantRing(t *testing.T) {
+
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Panics(t, func() { NewConjugateInvariantRing(8, GenerateNTTPrimes(55, 4, 1)) })
+
+	for _, logN := range []uint64{4, 6, 10} {
+
+		N := uint64(1) << logN
+
+		ringQ, err := NewConjugateInvariantRing(N, GenerateNTTPrimes(55, logN+1, 3))
+		assert.Nil(t, err)
+
+		ring2N := ringQ.FullRing()
+		sampler := NewUniformSampler(prng, ringQ.ringN)
+
+		t.Run(fmt.Sprintf("N=%d/limbs=%d", N, len(ringQ.Modulus)), func(t *testing.T) {
+
+			p1, p2 := sampler.ReadNew(), sampler.ReadNew()
+
+			t.Run("Embed", func(t *testing.T) {
+
+				full := ring2N.NewPoly()
+				ringQ.Embed(p1, full)
+
+				// a(X^-1) = a(X) in Z_Q[X]/(X^2N + 1)
+				inv := ring2N.NewPoly()
+				ring2N.Permute(full, 2*ring2N.N-1, inv)
+				assert.True(t, ring2N.Equal(full, inv))
+
+				// The evaluations on conjugate roots are equal
+				ring2N.NTT(full, full)
+				for i := range ringQ.Modulus {
+					for j := uint64(0); j < N; j++ {
+						assert.Equal(t, full.Coeffs[i][j], full.Coeffs[i][2*N-1-j])
+					}
+				}
+			})
+
+			t.Run("NTT", func(t *testing.T) {
+
+				x := ringQ.NewPoly()
+				ringQ.NTT(p1, x)
+
+				// The slots are the first N evaluations of the NTT of degree 2N
+				full := ring2N.NewPoly()
+				ringQ.Embed(p1, full)
+				ring2N.NTT(full, full)
+				for i := range ringQ.Modulus {
+					assert.Equal(t, full.Coeffs[i][:N], x.Coeffs[i])
+				}
+
+				ringQ.InvNTT(x, x)
+				assert.True(t, ringQ.Equal(p1, x), "invNTT should reverse NTT")
+
+				x = ringQ.NewPolyLvl(1)
+				ringQ.NTTLvl(1, p1, x)
+				ringQ.InvNTTLvl(1, x, x)
+				assert.True(t, ringQ.ringN.EqualLvl(1, p1, x), "invNTTLvl should reverse NTTLvl")
+
+				// In place
+				x = p1.CopyNew()
+				ringQ.NTT(x, x)
+				ringQ.InvNTT(x, x)
+				assert.True(t, ringQ.Equal(p1, x), "invNTT should reverse NTT in place")
+			})
+
+			t.Run("MulPoly", func(t *testing.T) {
+
+				have := ringQ.NewPoly()
+				ringQ.MulPoly(p1, p2, have)
+
+				a, b := ring2N.NewPoly(), ring2N.NewPoly()
+				ringQ.Embed(p1, a)
+				ringQ.Embed(p2, b)
+				ring2N.MulPoly(a, b, a)
+
+				ringQ.Embed(have, b)
+				assert.True(t, ring2N.Equal(a, b), "the product should match the one in the full ring")
+			})
+		})
+	}
+}
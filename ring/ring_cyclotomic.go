@@ -0,0 +1,589 @@
+package ring
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// CyclotomicRing is a structure that keeps all the variables required to operate on a polynomial represented in the
+// ring Z_Q[X]/(Phi_m(X)), where Phi_m(X) is the m-th cyclotomic polynomial and m is any integer, not necessarily a power
+// of two. The ring has degree N = phi(m), and its NTT evaluates a polynomial on the N primitive m-th roots of unity
+// omega^u mod each qi, for the exponents u of Units(). The NTT is computed with a length m DFT, reduced to a power of two
+// cyclic convolution of length L >= 2m-1 with Bluestein's algorithm, hence each modulus must be a prime congruent to
+// 1 modulo lcm(2m, L) (see GenerateCyclotomicNTTPrimes).
+//
+// The CyclotomicRing is stateless and can be used concurrently.
+type CyclotomicRing struct {
+
+	// Order of the cyclotomic polynomial
+	M uint64
+
+	// Degree of the cyclotomic polynomial, phi(m)
+	N uint64
+
+	Modulus    []uint64
+	BredParams [][]uint64
+
+	// Length of the Bluestein convolution
+	L uint64
+
+	units  []uint64
+	tables []*bluesteinTables
+}
+
+// bluesteinTables are the per modulus precomputed values of the CyclotomicRing.
+type bluesteinTables struct {
+
+	// Primitive 2m-th root of unity
+	psi uint64
+
+	// Chirps psi^(k^2) and psi^(-k^2) for k in [0, m)
+	chirp    []uint64
+	chirpInv []uint64
+
+	// NTTs of the convolution kernels psi^(-t^2)/L and psi^(t^2)/(L*m)
+	kernel    []uint64
+	kernelInv []uint64
+
+	// Powers of a primitive L-th root of unity and of its inverse, for the cyclic NTT of length L
+	roots    []uint64
+	rootsInv []uint64
+
+	// NTTs of Phi_m(X)/L and of rev(Phi_m)^-1 mod X^(m-phi(m)) / L, for the reduction modulo Phi_m(X), where rev(Phi_m)
+	// is the polynomial of reversed coefficients
+	phi       []uint64
+	phiRevInv []uint64
+}
+
+// NewCyclotomicRing creates a new CyclotomicRing of order m with the given moduli. It returns an error if m is smaller
+// than 2 or if a modulus is not a prime congruent to 1 modulo lcm(2m, L).
+func NewCyclotomicRing(m uint64, moduli []uint64) (r *CyclotomicRing, err error) {
+
+	if m < 2 || m > 1<<30 {
+		return nil, errors.New("invalid cyclotomic order (must be between 2 and 2^30)")
+	}
+
+	if len(moduli) == 0 {
+		return nil, errors.New("invalid moduli (must not be empty)")
+	}
+
+	r = new(CyclotomicRing)
+
+	r.M = m
+	r.L = 1 << uint64(bits.Len64(2*m-2))
+
+	for u := uint64(1); u < m; u++ {
+		if gcd(u, m) == 1 {
+			r.units = append(r.units, u)
+		}
+	}
+
+	r.N = uint64(len(r.units))
+
+	step := cyclotomicNTTStep(m)
+
+	r.Modulus = make([]uint64, len(moduli))
+	r.BredParams = make([][]uint64, len(moduli))
+	r.tables = make([]*bluesteinTables, len(moduli))
+
+	for i, qi := range moduli {
+
+		if qi >= 1<<61 || !IsPrime(qi) || qi%step != 1 {
+			return nil, errors.New("invalid modulus (must be a prime smaller than 2^61 congruent to 1 mod lcm(2m, L))")
+		}
+
+		r.Modulus[i] = qi
+		r.BredParams[i] = BRedParams(qi)
+		r.tables[i] = r.genBluesteinTables(qi, r.BredParams[i])
+	}
+
+	return r, nil
+}
+
+// cyclotomicNTTStep returns lcm(2m, L), where L is the smallest power of two greater than or equal to 2m-1.
+func cyclotomicNTTStep(m uint64) uint64 {
+	L := uint64(1) << uint64(bits.Len64(2*m-2))
+	return 2 * m / gcd(2*m, L) * L
+}
+
+// genBluesteinTables computes the precomputed values of the CyclotomicRing for the modulus qi.
+func (r *CyclotomicRing) genBluesteinTables(qi uint64, bredParams []uint64) (t *bluesteinTables) {
+
+	m, L := r.M, r.L
+
+	t = new(bluesteinTables)
+
+	g := primitiveRoot(qi)
+
+	t.psi = ModExp(g, (qi-1)/(2*m), qi)
+	psiInv := ModExp(t.psi, qi-2, qi)
+
+	zeta := ModExp(g, (qi-1)/L, qi)
+	zetaInv := ModExp(zeta, qi-2, qi)
+
+	t.roots = make([]uint64, L>>1)
+	t.rootsInv = make([]uint64, L>>1)
+	t.roots[0], t.rootsInv[0] = 1, 1
+	for j := uint64(1); j < L>>1; j++ {
+		t.roots[j] = BRed(t.roots[j-1], zeta, qi, bredParams)
+		t.rootsInv[j] = BRed(t.rootsInv[j-1], zetaInv, qi, bredParams)
+	}
+
+	LInv := ModExp(L%qi, qi-2, qi)
+	LmInv := BRed(LInv, ModExp(m%qi, qi-2, qi), qi, bredParams)
+
+	t.chirp = make([]uint64, m)
+	t.chirpInv = make([]uint64, m)
+	t.kernel = make([]uint64, L)
+	t.kernelInv = make([]uint64, L)
+
+	for k := uint64(0); k < m; k++ {
+
+		e := (k * k) % (2 * m)
+
+		// psi^(k^2) and psi^(-k^2), the exponents being taken mod 2m
+		pos := ModExp(t.psi, e, qi)
+		neg := ModExp(psiInv, e, qi)
+
+		t.chirp[k] = pos
+		t.chirpInv[k] = neg
+
+		t.kernel[k] = BRed(neg, LInv, qi, bredParams)
+		t.kernelInv[k] = BRed(pos, LmInv, qi, bredParams)
+
+		if k != 0 {
+			t.kernel[L-k] = t.kernel[k]
+			t.kernelInv[L-k] = t.kernelInv[k]
+		}
+	}
+
+	cyclicNTT(t.kernel, t.roots, qi, bredParams)
+	cyclicNTT(t.kernelInv, t.roots, qi, bredParams)
+
+	phi := cyclotomicPolynomial(m, qi)
+
+	k := m - r.N
+
+	phiRev := make([]uint64, len(phi))
+	for j, c := range phi {
+		phiRev[len(phi)-1-j] = c
+	}
+
+	t.phi = make([]uint64, L)
+	t.phiRevInv = make([]uint64, L)
+
+	for j, c := range phi {
+		t.phi[j] = BRed(c, LInv, qi, bredParams)
+	}
+
+	for j, c := range seriesInverse(phiRev, k, t, qi, bredParams) {
+		t.phiRevInv[j] = BRed(c, LInv, qi, bredParams)
+	}
+
+	cyclicNTT(t.phi, t.roots, qi, bredParams)
+	cyclicNTT(t.phiRevInv, t.roots, qi, bredParams)
+
+	return
+}
+
+// cyclotomicPolynomial returns the coefficients of Phi_m(X) mod q, computed as the product of the (X^d - 1)^mu(m/d)
+// for the divisors d of m, where mu is the Moebius function.
+func cyclotomicPolynomial(m, q uint64) (phi []uint64) {
+
+	var num, den []uint64
+
+	for d := uint64(1); d <= m; d++ {
+		if m%d == 0 {
+			switch moebius(m / d) {
+			case 1:
+				num = append(num, d)
+			case -1:
+				den = append(den, d)
+			}
+		}
+	}
+
+	phi = []uint64{1}
+
+	// Multiplies by X^d - 1
+	for _, d := range num {
+		tmp := make([]uint64, len(phi)+int(d))
+		for i, c := range phi {
+			tmp[i] = CRed(tmp[i]+q-c, q)
+			tmp[i+int(d)] = CRed(tmp[i+int(d)]+c, q)
+		}
+		phi = tmp
+	}
+
+	// Divides by X^d - 1, the division being exact: Q_i = Q_(i-d) - P_i
+	for _, d := range den {
+		tmp := make([]uint64, len(phi)-int(d))
+		for i := range tmp {
+			tmp[i] = q - phi[i]
+			if i >= int(d) {
+				tmp[i] += tmp[i-int(d)]
+			}
+			tmp[i] = CRed(tmp[i], q)
+		}
+		phi = tmp
+	}
+
+	return
+}
+
+// moebius returns the Moebius function of n.
+func moebius(n uint64) (mu int) {
+
+	mu = 1
+
+	for p := uint64(2); p*p <= n; p++ {
+		if n%p == 0 {
+			n /= p
+			if n%p == 0 {
+				return 0
+			}
+			mu = -mu
+		}
+	}
+
+	if n > 1 {
+		mu = -mu
+	}
+
+	return
+}
+
+// cyclicNTT computes in place the cyclic NTT of length len(a), a power of two, of a, given the powers of the
+// primitive len(a)-th root of unity used for the evaluation.
+func cyclicNTT(a, roots []uint64, q uint64, bredParams []uint64) {
+
+	L := uint64(len(a))
+	logL := uint64(bits.Len64(L) - 1)
+
+	for i := uint64(0); i < L; i++ {
+		if j := bits.Reverse64(i) >> (64 - logL); i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := uint64(2); size <= L; size <<= 1 {
+
+		half, stride := size>>1, L/size
+
+		for start := uint64(0); start < L; start += size {
+			for j := uint64(0); j < half; j++ {
+				u := a[start+j]
+				v := BRed(a[start+j+half], roots[j*stride], q, bredParams)
+				a[start+j] = CRed(u+v, q)
+				a[start+j+half] = CRed(u+q-v, q)
+			}
+		}
+	}
+}
+
+// bluestein computes on out the values chirp[k] * sum_j (in[j] * chirp[j]) * kernel[k-j] for k in [0, len(out)), where
+// the kernel is given in the NTT domain, that is the length m DFT of in evaluated with Bluestein's algorithm. buff
+// must be of length L.
+func bluestein(in, out, chirp, kernel []uint64, t *bluesteinTables, q uint64, bredParams []uint64, buff []uint64) {
+
+	for j := range buff {
+		buff[j] = 0
+	}
+
+	for j, c := range in {
+		buff[j] = BRed(c, chirp[j], q, bredParams)
+	}
+
+	cyclicNTT(buff, t.roots, q, bredParams)
+
+	for j := range buff {
+		buff[j] = BRed(buff[j], kernel[j], q, bredParams)
+	}
+
+	// The inverse cyclic NTT is the forward one with the inverse roots, the 1/L factor being in the kernel
+	cyclicNTT(buff, t.rootsInv, q, bredParams)
+
+	for k := range out {
+		out[k] = BRed(buff[k], chirp[k], q, bredParams)
+	}
+}
+
+// Units returns the exponents u in [1, m) coprime to m, in increasing order. The i-th coefficient of a polynomial in
+// the NTT domain is its evaluation on omega^Units()[i], where omega = psi^2 is the primitive m-th root of unity used
+// by the CyclotomicRing.
+func (r *CyclotomicRing) Units() []uint64 {
+	units := make([]uint64, len(r.units))
+	copy(units, r.units)
+	return units
+}
+
+// NewPoly creates a new polynomial with all coefficients set to 0.
+func (r *CyclotomicRing) NewPoly() *Poly {
+	p := new(Poly)
+	p.Coeffs = make([][]uint64, len(r.Modulus))
+	for i := range r.Modulus {
+		p.Coeffs[i] = make([]uint64, r.N)
+	}
+	return p
+}
+
+// NTT computes the NTT of p1 and returns the result on p2, that is the evaluations of p1 on the primitive m-th roots
+// of unity omega^u for u in Units().
+func (r *CyclotomicRing) NTT(p1, p2 *Poly) {
+
+	buff := make([]uint64, r.L)
+	dft := make([]uint64, r.M)
+
+	for i, qi := range r.Modulus {
+
+		t := r.tables[i]
+
+		// DFT_k = psi^(k^2) * sum_j (a_j * psi^(j^2)) * psi^(-(k-j)^2), since omega^(jk) = psi^(j^2 + k^2 - (k-j)^2)
+		bluestein(p1.Coeffs[i], dft, t.chirp, t.kernel, t, qi, r.BredParams[i], buff)
+
+		for j, u := range r.units {
+			p2.Coeffs[i][j] = dft[u]
+		}
+	}
+}
+
+// InvNTT computes the inverse NTT of p1 and returns the result on p2.
+func (r *CyclotomicRing) InvNTT(p1, p2 *Poly) {
+
+	buff := make([]uint64, r.L)
+	dft := make([]uint64, r.M)
+	coeffs := make([]uint64, r.M)
+
+	for i, qi := range r.Modulus {
+
+		t := r.tables[i]
+		bredParams := r.BredParams[i]
+
+		// The inverse DFT of the evaluations, completed with zeros on the exponents that are not units, is a
+		// polynomial of degree smaller than m congruent to p1 mod Phi_m(X)
+		for j := range dft {
+			dft[j] = 0
+		}
+
+		for j, u := range r.units {
+			dft[u] = p1.Coeffs[i][j]
+		}
+
+		// a_j = 1/m * sum_k DFT_k * omega^(-jk), the 1/m factor being in the kernel
+		bluestein(dft, coeffs, t.chirpInv, t.kernelInv, t, qi, bredParams, buff)
+
+		reduceCyclotomic(coeffs, r.N, t, qi, bredParams, buff, dft)
+
+		copy(p2.Coeffs[i], coeffs[:r.N])
+	}
+}
+
+// reduceCyclotomic reduces in place the polynomial a of degree smaller than m modulo Phi_m(X), of degree n. The
+// quotient is rev(rev(a) * rev(Phi_m)^-1 mod X^(m-n)), where rev reverses the coefficients, and both products are
+// computed with cyclic convolutions of length L without wrapping around. buff must be of length L and tmp of length
+// at least m-n.
+func reduceCyclotomic(a []uint64, n uint64, t *bluesteinTables, q uint64, bredParams []uint64, buff, tmp []uint64) {
+
+	m := uint64(len(a))
+	k := m - n
+
+	for j := range buff {
+		buff[j] = 0
+	}
+
+	for j := uint64(0); j < k; j++ {
+		buff[j] = a[m-1-j]
+	}
+
+	cyclicNTT(buff, t.roots, q, bredParams)
+
+	for j := range buff {
+		buff[j] = BRed(buff[j], t.phiRevInv[j], q, bredParams)
+	}
+
+	cyclicNTT(buff, t.rootsInv, q, bredParams)
+
+	for j := uint64(0); j < k; j++ {
+		tmp[j] = buff[k-1-j]
+	}
+
+	for j := range buff {
+		buff[j] = 0
+	}
+
+	copy(buff, tmp[:k])
+
+	cyclicNTT(buff, t.roots, q, bredParams)
+
+	for j := range buff {
+		buff[j] = BRed(buff[j], t.phi[j], q, bredParams)
+	}
+
+	cyclicNTT(buff, t.rootsInv, q, bredParams)
+
+	for j := uint64(0); j < n; j++ {
+		a[j] = CRed(a[j]+q-buff[j], q)
+	}
+
+	for j := n; j < m; j++ {
+		a[j] = 0
+	}
+}
+
+// seriesInverse returns the inverse of f modulo X^k, given that f[0] = 1, computed with the Newton iteration
+// g <- g - g * (f * g - 1) and the cyclic convolutions of length L of the bluesteinTables.
+func seriesInverse(f []uint64, k uint64, t *bluesteinTables, q uint64, bredParams []uint64) (g []uint64) {
+
+	L := uint64(len(t.roots)) << 1
+	LInv := ModExp(L%q, q-2, q)
+
+	a, b := make([]uint64, L), make([]uint64, L)
+
+	// Computes on a the cyclic convolution of x and y, of length L
+	convolve := func(x, y []uint64) {
+
+		for j := range a {
+			a[j], b[j] = 0, 0
+		}
+
+		copy(a, x)
+		copy(b, y)
+
+		cyclicNTT(a, t.roots, q, bredParams)
+		cyclicNTT(b, t.roots, q, bredParams)
+
+		for j := range a {
+			a[j] = BRed(BRed(a[j], b[j], q, bredParams), LInv, q, bredParams)
+		}
+
+		cyclicNTT(a, t.rootsInv, q, bredParams)
+	}
+
+	g = []uint64{1}
+
+	for l := uint64(1); l < k; {
+
+		l2 := 2 * l
+		if l2 > k {
+			l2 = k
+		}
+
+		// f * g = 1 + X^l * h mod X^l2, the product not wrapping around since l2 + l <= 2m - 1 <= L
+		fl := f
+		if uint64(len(fl)) > l2 {
+			fl = fl[:l2]
+		}
+
+		convolve(fl, g)
+
+		h := make([]uint64, l2-l)
+		copy(h, a[l:l2])
+
+		// g <- g - X^l * (g * h mod X^(l2-l))
+		convolve(g, h)
+
+		for j := uint64(0); j < l2-l; j++ {
+			g = append(g, CRed(q-a[j], q))
+		}
+
+		l = l2
+	}
+
+	return g[:k]
+}
+
+// Add adds p1 to p2 coefficient-wise and writes the result on p3.
+func (r *CyclotomicRing) Add(p1, p2, p3 *Poly) {
+	for i, qi := range r.Modulus {
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		for j := uint64(0); j < r.N; j++ {
+			p3tmp[j] = CRed(p1tmp[j]+p2tmp[j], qi)
+		}
+	}
+}
+
+// Sub subtracts p2 to p1 coefficient-wise and writes the result on p3.
+func (r *CyclotomicRing) Sub(p1, p2, p3 *Poly) {
+	for i, qi := range r.Modulus {
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		for j := uint64(0); j < r.N; j++ {
+			p3tmp[j] = CRed(p1tmp[j]+qi-p2tmp[j], qi)
+		}
+	}
+}
+
+// MulCoeffs multiplies p1 by p2 coefficient-wise and writes the result on p3.
+func (r *CyclotomicRing) MulCoeffs(p1, p2, p3 *Poly) {
+	for i, qi := range r.Modulus {
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		bredParams := r.BredParams[i]
+		for j := uint64(0); j < r.N; j++ {
+			p3tmp[j] = BRed(p1tmp[j], p2tmp[j], qi, bredParams)
+		}
+	}
+}
+
+// MulPoly multiplies p1 by p2 modulo Phi_m(X) and writes the result on p3. The inputs and the output are in the
+// coefficient domain.
+func (r *CyclotomicRing) MulPoly(p1, p2, p3 *Poly) {
+	a, b := r.NewPoly(), r.NewPoly()
+	r.NTT(p1, a)
+	r.NTT(p2, b)
+	r.MulCoeffs(a, b, a)
+	r.InvNTT(a, p3)
+}
+
+// GenerateCyclotomicNTTPrimes generates n different primes congruent to 1 modulo lcm(2m, L), as required by a
+// CyclotomicRing of order m, starting from 2^logQ and alternating between upward and downward.
+func GenerateCyclotomicNTTPrimes(logQ, m, n uint64) (primes []uint64) {
+
+	if logQ > 60 {
+		panic("logQ must be between 1 and 60")
+	}
+
+	step := cyclotomicNTTStep(m)
+
+	// Closest integer congruent to 1 mod step to 2^logQ
+	start := ((uint64(1)<<logQ)/step)*step + 1
+
+	nextPrime, previousPrime := start, start
+	checkfornextprime, checkforpreviousprime := true, true
+
+	if IsPrime(start) {
+		primes = append(primes, start)
+	}
+
+	for uint64(len(primes)) < n {
+
+		if !(checkfornextprime || checkforpreviousprime) {
+			panic("GenerateCyclotomicNTTPrimes error: cannot generate enough primes for the given parameters")
+		}
+
+		if checkfornextprime {
+			if nextPrime > (1<<61)-step {
+				checkfornextprime = false
+			} else {
+				nextPrime += step
+				if IsPrime(nextPrime) {
+					primes = append(primes, nextPrime)
+					if uint64(len(primes)) == n {
+						return
+					}
+				}
+			}
+		}
+
+		if checkforpreviousprime {
+			if previousPrime < step+1 {
+				checkforpreviousprime = false
+			} else {
+				previousPrime -= step
+				if IsPrime(previousPrime) {
+					primes = append(primes, previousPrime)
+				}
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+package ring
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCyclotomicRing(t *testing.T) {
+
+	// Prime powers, composite and even orders
+	for _, m := range []uint64{2, 3, 9, 12, 15, 25, 27, 105, 243, 1155} {
+
+		moduli := GenerateCyclotomicNTTPrimes(50, m, 2)
+
+		ringQ, err := NewCyclotomicRing(m, moduli)
+		assert.Nil(t, err)
+
+		t.Run(fmt.Sprintf("m=%d/N=%d/limbs=%d", m, ringQ.N, len(ringQ.Modulus)), func(t *testing.T) {
+
+			for _, qi := range moduli {
+				assert.Equal(t, uint64(1), qi%cyclotomicNTTStep(m))
+			}
+
+			p1 := randomCyclotomicPoly(ringQ)
+			p2 := randomCyclotomicPoly(ringQ)
+
+			t.Run("NTT", func(t *testing.T) {
+
+				x := ringQ.NewPoly()
+				ringQ.NTT(p1, x)
+
+				for i, qi := range ringQ.Modulus {
+
+					bredParams := ringQ.BredParams[i]
+					omega := BRed(ringQ.tables[i].psi, ringQ.tables[i].psi, qi, bredParams)
+
+					for j, u := range ringQ.Units() {
+
+						// Horner evaluation of p1 on omega^u
+						root, eval := ModExp(omega, u, qi), uint64(0)
+						for k := int(ringQ.N) - 1; k >= 0; k-- {
+							eval = CRed(BRed(eval, root, qi, bredParams)+p1.Coeffs[i][k], qi)
+						}
+
+						assert.Equal(t, eval, x.Coeffs[i][j])
+					}
+				}
+
+				ringQ.InvNTT(x, x)
+				assert.Equal(t, p1.Coeffs, x.Coeffs, "invNTT should reverse NTT")
+			})
+
+			t.Run("CyclotomicPolynomial", func(t *testing.T) {
+
+				for i, qi := range ringQ.Modulus {
+
+					bredParams := ringQ.BredParams[i]
+					omega := BRed(ringQ.tables[i].psi, ringQ.tables[i].psi, qi, bredParams)
+
+					// Phi_m(X) = prod (X - omega^u) for the units u of Z_m
+					want := []uint64{1}
+					for _, u := range ringQ.Units() {
+						root := qi - ModExp(omega, u, qi)
+						tmp := make([]uint64, len(want)+1)
+						for k, c := range want {
+							tmp[k] = CRed(tmp[k]+BRed(c, root, qi, bredParams), qi)
+							tmp[k+1] = CRed(tmp[k+1]+c, qi)
+						}
+						want = tmp
+					}
+
+					assert.Equal(t, want, cyclotomicPolynomial(m, qi))
+				}
+			})
+
+			t.Run("MulPoly", func(t *testing.T) {
+
+				have := ringQ.NewPoly()
+				ringQ.MulPoly(p1, p2, have)
+
+				for i, qi := range ringQ.Modulus {
+
+					bredParams := ringQ.BredParams[i]
+					phi := cyclotomicPolynomial(m, qi)
+					n := int(ringQ.N)
+
+					// Schoolbook product followed by the long division by Phi_m(X)
+					prod := make([]uint64, 2*n-1)
+					for j := 0; j < n; j++ {
+						for k := 0; k < n; k++ {
+							prod[j+k] = CRed(prod[j+k]+BRed(p1.Coeffs[i][j], p2.Coeffs[i][k], qi, bredParams), qi)
+						}
+					}
+
+					for j := len(prod) - 1; j >= n; j-- {
+						for k := 0; k <= n; k++ {
+							prod[j-n+k] = CRed(prod[j-n+k]+qi-BRed(prod[j], phi[k], qi, bredParams), qi)
+						}
+					}
+
+					assert.Equal(t, prod[:n], have.Coeffs[i])
+				}
+			})
+
+			t.Run("AddSub", func(t *testing.T) {
+
+				x := ringQ.NewPoly()
+				ringQ.Add(p1, p2, x)
+				ringQ.Sub(x, p2, x)
+				assert.Equal(t, p1.Coeffs, x.Coeffs)
+			})
+		})
+	}
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+
+		_, err := NewCyclotomicRing(1, []uint64{0x1fffffffffe00001})
+		assert.NotNil(t, err)
+
+		// 2^61 - 2^21 + 1 is congruent to 1 mod 2^21 but not mod 9
+		_, err = NewCyclotomicRing(9, []uint64{0x1fffffffffe00001})
+		assert.NotNil(t, err)
+	})
+}
+
+func randomCyclotomicPoly(r *CyclotomicRing) (p *Poly) {
+	p = r.NewPoly()
+	for i, qi := range r.Modulus {
+		for j := range p.Coeffs[i] {
+			p.Coeffs[i][j] = rand.Uint64() % qi
+		}
+	}
+	return
+}
+
+func BenchmarkCyclotomicRing(b *testing.B) {
+
+	for _, m := range []uint64{3 * 3 * 5 * 7 * 11 * 13, 3 * 3 * 3 * 3 * 3 * 3 * 3 * 3 * 3} {
+
+		ringQ, _ := NewCyclotomicRing(m, GenerateCyclotomicNTTPrimes(55, m, 1))
+
+		p := randomCyclotomicPoly(ringQ)
+
+		b.Run(fmt.Sprintf("NTT/m=%d/N=%d", m, ringQ.N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ringQ.NTT(p, p)
+			}
+		})
+
+		b.Run(fmt.Sprintf("InvNTT/m=%d/N=%d", m, ringQ.N), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ringQ.InvNTT(p, p)
+			}
+		})
+	}
+}
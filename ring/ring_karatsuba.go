@@ -0,0 +1,90 @@
+package ring
+
+// karatsubaThreshold is the size of the operands below which the Karatsuba multiplication falls back to the schoolbook
+// multiplication, which is faster on small operands.
+const karatsubaThreshold = 32
+
+// MulPolyKaratsuba multiplies p1 by p2 in Z_Q[X]/(X^N+1) with the Karatsuba algorithm and writes the result on p3.
+// Unlike MulPoly on a Ring allowing the NTT, it works for any moduli of at most 62 bits, e.g. moduli that are not
+// congruent to 1 mod 2N, composite moduli or powers of two, at the cost of O(N^1.58) operations per modulus instead of
+// O(N log N). MulPoly uses it on the Rings which do not allow the NTT. p3 can alias p1 or p2.
+func (r *Ring) MulPolyKaratsuba(p1, p2, p3 *Poly) {
+	r.MulPolyKaratsubaLvl(uint64(len(r.Modulus)-1), p1, p2, p3)
+}
+
+// MulPolyKaratsubaLvl multiplies p1 by p2 in Z_Q[X]/(X^N+1) with the Karatsuba algorithm for the moduli from q_0 up to
+// q_level and writes the result on p3, see MulPolyKaratsuba.
+func (r *Ring) MulPolyKaratsubaLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulPolyKaratsubaLvl", r, level, p1, p2, p3)
+	}
+
+	N := r.N
+
+	// Product of degree 2N-2 and buffer of the recursion, which needs less than 4N coefficients
+	prod := make([]uint64, 2*N)
+	buf := make([]uint64, 4*N)
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi := r.Modulus[i]
+
+		karatsuba(p1.Coeffs[i][:N], p2.Coeffs[i][:N], prod, buf, qi, r.BredParams[i])
+
+		// Reduction modulo X^N+1: X^(N+j) = -X^j
+		p3tmp := p3.Coeffs[i]
+		for j := uint64(0); j < N; j++ {
+			p3tmp[j] = CRed(prod[j]+qi-prod[j+N], qi)
+		}
+	}
+}
+
+// karatsuba writes the product of a and b, whose coefficients are in [0, q) and whose length is a power of two n, on
+// c[:2n], the coefficient c[2n-1] being zero. buf must have at least 4n coefficients, less the n of the last level of
+// the recursion, and u are the Barrett parameters of q.
+func karatsuba(a, b, c, buf []uint64, q uint64, u []uint64) {
+
+	n := len(a)
+
+	if n <= karatsubaThreshold {
+
+		for i := range c[:2*n] {
+			c[i] = 0
+		}
+
+		for i, ai := range a {
+			ci := c[i : i+n]
+			for j, bj := range b {
+				ci[j] = CRed(ci[j]+BRed(ai, bj, q, u), q)
+			}
+		}
+
+		return
+	}
+
+	h := n >> 1
+
+	// a0*b0 on c[:n] and a1*b1 on c[n:2n]
+	karatsuba(a[:h], b[:h], c[:n], buf, q, u)
+	karatsuba(a[h:], b[h:], c[n:2*n], buf, q, u)
+
+	// (a0+a1)*(b0+b1) on mid
+	sa, sb, mid := buf[:h], buf[h:n], buf[n:2*n]
+
+	for i := 0; i < h; i++ {
+		sa[i] = CRed(a[i]+a[h+i], q)
+		sb[i] = CRed(b[i]+b[h+i], q)
+	}
+
+	karatsuba(sa, sb, mid, buf[2*n:], q, u)
+
+	// c += X^h * ((a0+a1)*(b0+b1) - a0*b0 - a1*b1), the middle term being computed first as it overlaps both products
+	for i := 0; i < n; i++ {
+		mid[i] = CRed(mid[i]+q-c[i], q)
+		mid[i] = CRed(mid[i]+q-c[n+i], q)
+	}
+
+	for i := 0; i < n; i++ {
+		c[h+i] = CRed(c[h+i]+mid[i], q)
+	}
+}
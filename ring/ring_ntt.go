@@ -1,176 +1,266 @@
 package ring
 
 import (
+	"math/bits"
 	"unsafe"
+
+	"github.com/ldsec/lattigo/v2/utils"
 )
 
 // NTT computes the NTT of p1 and returns the result on p2.
 func (r *Ring) NTT(p1, p2 *Poly) {
-	for x := range r.Modulus {
-		NTT(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x])
-	}
+	r.NTTLvl(uint64(len(r.Modulus)-1), p1, p2)
 }
 
 // NTTLvl computes the NTT of p1 and returns the result on p2.
 // The value level defines the number of moduli of the input polynomials.
 func (r *Ring) NTTLvl(level uint64, p1, p2 *Poly) {
-	for x := uint64(0); x < level+1; x++ {
-		NTT(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x])
+	if debugChecks {
+		checkLevel("NTTLvl", r, level, p1, p2)
+		checkAliasing("NTTLvl", r.N, level, true, p2, p1)
 	}
+
+	r.Backend().NTTLvl(r, level, p1, p2)
+}
+
+// NTTParallel computes the NTT of p1 and returns the result on p2, in parallel across the moduli on the WorkerPool
+// of the Ring, or on the DefaultWorkerPool if the Ring has none. With a Backend other than the CPUBackend, it is
+// equivalent to NTT.
+func (r *Ring) NTTParallel(p1, p2 *Poly) {
+	r.NTTParallelLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
+
+// NTTParallelLvl computes the NTT of p1 and returns the result on p2 in parallel across the moduli, see NTTParallel.
+// The value level defines the number of moduli of the input polynomials.
+func (r *Ring) NTTParallelLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("NTTParallelLvl", r, level, p1, p2)
+		checkAliasing("NTTParallelLvl", r.N, level, true, p2, p1)
+	}
+
+	if _, ok := r.Backend().(CPUBackend); !ok {
+		r.Backend().NTTLvl(r, level, p1, p2)
+		return
+	}
+	r.nttLvlOn(r.parallelWorkerPool(), level, p1, p2)
+}
+
+// nttLvl is the implementation of NTTLvl of the CPUBackend.
+func (r *Ring) nttLvl(level uint64, p1, p2 *Poly) {
+	r.nttLvlOn(r.workerPool, level, p1, p2)
+}
+
+// nttLvlOn computes the NTT of p1 on p2 for the moduli from q_0 up to q_level, in parallel on pool, or sequentially
+// if pool is nil.
+func (r *Ring) nttLvlOn(pool *WorkerPool, level uint64, p1, p2 *Poly) {
+
+	if pool == nil || level == 0 {
+		for x := uint64(0); x < level+1; x++ {
+			NTTBlocked(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x], r.nttBlockSize)
+		}
+		return
+	}
+
+	pool.Run(int(level+1), func(x int) {
+		NTTBlocked(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x], r.nttBlockSize)
+	})
 }
 
 // InvNTT computes the inverse-NTT of p1 and returns the result on p2.
 func (r *Ring) InvNTT(p1, p2 *Poly) {
-	for x := range r.Modulus {
-		InvNTT(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x])
-	}
+	r.InvNTTLvl(uint64(len(r.Modulus)-1), p1, p2)
 }
 
 // InvNTTLvl computes the inverse-NTT of p1 and returns the result on p2.
 // The value level defines the number of moduli of the input polynomials.
 func (r *Ring) InvNTTLvl(level uint64, p1, p2 *Poly) {
-	for x := uint64(0); x < level+1; x++ {
-		InvNTT(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x])
+	if debugChecks {
+		checkLevel("InvNTTLvl", r, level, p1, p2)
+		checkAliasing("InvNTTLvl", r.N, level, true, p2, p1)
 	}
-}
 
-// butterfly computes X, Y = U + V*Psi, U - V*Psi mod Q.
-func butterfly(U, V, Psi, Q, Qinv uint64) (X, Y uint64) {
-	if U > 2*Q {
-		U -= 2 * Q
-	}
-	V = MRedConstant(V, Psi, Q, Qinv)
-	X = U + V
-	Y = U + 2*Q - V
-	return
+	r.Backend().InvNTTLvl(r, level, p1, p2)
 }
 
-// invbutterfly computes X, Y = U + V, (U - V) * Psi mod Q.
-func invbutterfly(U, V, Psi, Q, Qinv uint64) (X, Y uint64) {
-	X = U + V
-	if X > 2*Q {
-		X -= 2 * Q
-	}
-	Y = MRedConstant(U+2*Q-V, Psi, Q, Qinv) // At the moment it is not possible to use MRedConstant if Q > 61 bits
-	return
+// InvNTTParallel computes the inverse-NTT of p1 and returns the result on p2 in parallel across the moduli, see
+// NTTParallel.
+func (r *Ring) InvNTTParallel(p1, p2 *Poly) {
+	r.InvNTTParallelLvl(uint64(len(r.Modulus)-1), p1, p2)
 }
 
-// NTT computes the NTT on the input coefficients using the input parameters.
-func NTT(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams uint64, bredParams []uint64) {
-	var j1, j2, t uint64
-	var F uint64
+// InvNTTParallelLvl computes the inverse-NTT of p1 and returns the result on p2 in parallel across the moduli, see
+// NTTParallel. The value level defines the number of moduli of the input polynomials.
+func (r *Ring) InvNTTParallelLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("InvNTTParallelLvl", r, level, p1, p2)
+		checkAliasing("InvNTTParallelLvl", r.N, level, true, p2, p1)
+	}
 
-	// Copy the result of the first round of butterflies on p2 with approximate reduction
-	t = N >> 1
-	F = nttPsi[1]
-	for j := uint64(0); j <= t-1; j = j + 8 {
+	if _, ok := r.Backend().(CPUBackend); !ok {
+		r.Backend().InvNTTLvl(r, level, p1, p2)
+		return
+	}
+	r.invNTTLvlOn(r.parallelWorkerPool(), level, p1, p2)
+}
 
-		xin := (*[8]uint64)(unsafe.Pointer(&coeffsIn[j]))
-		yin := (*[8]uint64)(unsafe.Pointer(&coeffsIn[j+t]))
+// invNTTLvl is the implementation of InvNTTLvl of the CPUBackend.
+func (r *Ring) invNTTLvl(level uint64, p1, p2 *Poly) {
+	r.invNTTLvlOn(r.workerPool, level, p1, p2)
+}
 
-		xout := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j]))
-		yout := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j+t]))
+// invNTTLvlOn computes the inverse-NTT of p1 on p2 for the moduli from q_0 up to q_level, in parallel on pool, or
+// sequentially if pool is nil.
+func (r *Ring) invNTTLvlOn(pool *WorkerPool, level uint64, p1, p2 *Poly) {
 
-		xout[0], yout[0] = butterfly(xin[0], yin[0], F, Q, mredParams)
-		xout[1], yout[1] = butterfly(xin[1], yin[1], F, Q, mredParams)
-		xout[2], yout[2] = butterfly(xin[2], yin[2], F, Q, mredParams)
-		xout[3], yout[3] = butterfly(xin[3], yin[3], F, Q, mredParams)
-		xout[4], yout[4] = butterfly(xin[4], yin[4], F, Q, mredParams)
-		xout[5], yout[5] = butterfly(xin[5], yin[5], F, Q, mredParams)
-		xout[6], yout[6] = butterfly(xin[6], yin[6], F, Q, mredParams)
-		xout[7], yout[7] = butterfly(xin[7], yin[7], F, Q, mredParams)
+	if pool == nil || level == 0 {
+		for x := uint64(0); x < level+1; x++ {
+			InvNTTBlocked(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x], r.nttBlockSize)
+		}
+		return
 	}
 
-	// Continue the rest of the second to the n-1 butterflies on p2 with approximate reduction
-	for m := uint64(2); m < N; m <<= 1 {
+	pool.Run(int(level+1), func(x int) {
+		InvNTTBlocked(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x], r.nttBlockSize)
+	})
+}
 
-		t >>= 1
+// WorkerPool returns the WorkerPool on which the NTTs of the Ring are computed in parallel, or nil if they are
+// computed sequentially.
+func (r *Ring) WorkerPool() *WorkerPool {
+	return r.workerPool
+}
 
-		if t >= 8 {
+// SetWorkerPool sets the WorkerPool on which NTT, InvNTT, NTTMForm, InvNTTInvMForm, their Lvl variants and
+// DivRoundByLastModulusNTT are computed in parallel across the moduli, as NTTParallel. A nil pool restores the sequential computation.
+func (r *Ring) SetWorkerPool(pool *WorkerPool) {
+	r.workerPool = pool
+}
 
-			for i := uint64(0); i < m; i++ {
+// parallelWorkerPool returns the WorkerPool of the Ring, or the DefaultWorkerPool if it has none.
+func (r *Ring) parallelWorkerPool() *WorkerPool {
+	if r.workerPool != nil {
+		return r.workerPool
+	}
+	return DefaultWorkerPool()
+}
 
-				j1 = (i * t) << 1
+// NTTMForm computes the NTT of p1 and returns the result on p2 in the Montgomery form. It is equivalent to NTT followed by MForm,
+// the conversion to the Montgomery form being done during the final reduction of the NTT instead of in a separate pass.
+func (r *Ring) NTTMForm(p1, p2 *Poly) {
+	r.NTTMFormLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
 
-				j2 = j1 + t - 1
+// NTTMFormLvl computes the NTT of p1 and returns the result on p2 in the Montgomery form, see NTTMForm.
+// The value level defines the number of moduli of the input polynomials.
+func (r *Ring) NTTMFormLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("NTTMFormLvl", r, level, p1, p2)
+		checkAliasing("NTTMFormLvl", r.N, level, true, p2, p1)
+	}
 
-				F = nttPsi[m+i]
+	r.Backend().NTTMFormLvl(r, level, p1, p2)
+}
 
-				for j := j1; j <= j2; j = j + 8 {
+// nttMFormLvl is the implementation of NTTMFormLvl of the CPUBackend, in parallel on the WorkerPool of the Ring as nttLvl.
+func (r *Ring) nttMFormLvl(level uint64, p1, p2 *Poly) {
 
-					x := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j]))
-					y := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j+t]))
+	if r.workerPool == nil || level == 0 {
+		for x := uint64(0); x < level+1; x++ {
+			nttMForm(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x], r.nttBlockSize)
+		}
+		return
+	}
 
-					x[0], y[0] = butterfly(x[0], y[0], F, Q, mredParams)
-					x[1], y[1] = butterfly(x[1], y[1], F, Q, mredParams)
-					x[2], y[2] = butterfly(x[2], y[2], F, Q, mredParams)
-					x[3], y[3] = butterfly(x[3], y[3], F, Q, mredParams)
-					x[4], y[4] = butterfly(x[4], y[4], F, Q, mredParams)
-					x[5], y[5] = butterfly(x[5], y[5], F, Q, mredParams)
-					x[6], y[6] = butterfly(x[6], y[6], F, Q, mredParams)
-					x[7], y[7] = butterfly(x[7], y[7], F, Q, mredParams)
-				}
-			}
+	r.workerPool.Run(int(level+1), func(x int) {
+		nttMForm(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsi[x], r.Modulus[x], r.MredParams[x], r.BredParams[x], r.nttBlockSize)
+	})
+}
 
-		} else if t == 4 {
+// InvNTTInvMForm computes the inverse-NTT of p1, which is in the Montgomery form, and returns the result on p2 out of the Montgomery
+// form. It is equivalent to InvNTT followed by InvMForm, the conversion being merged with the multiplication by N^-1 of the inverse-NTT.
+func (r *Ring) InvNTTInvMForm(p1, p2 *Poly) {
+	r.InvNTTInvMFormLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
 
-			for i := uint64(0); i < m; i = i + 2 {
+// InvNTTInvMFormLvl computes the inverse-NTT of p1, which is in the Montgomery form, and returns the result on p2 out of the
+// Montgomery form, see InvNTTInvMForm. The value level defines the number of moduli of the input polynomials.
+func (r *Ring) InvNTTInvMFormLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("InvNTTInvMFormLvl", r, level, p1, p2)
+		checkAliasing("InvNTTInvMFormLvl", r.N, level, true, p2, p1)
+	}
 
-				j1 = (i * t) << 1
+	r.Backend().InvNTTInvMFormLvl(r, level, p1, p2)
+}
 
-				psi := (*[2]uint64)(unsafe.Pointer(&nttPsi[m+i]))
-				x := (*[16]uint64)(unsafe.Pointer(&coeffsOut[j1]))
+// invNTTInvMFormLvl is the implementation of InvNTTInvMFormLvl of the CPUBackend, in parallel on the WorkerPool of the
+// Ring as invNTTLvl.
+func (r *Ring) invNTTInvMFormLvl(level uint64, p1, p2 *Poly) {
 
-				x[0], x[4] = butterfly(x[0], x[4], psi[0], Q, mredParams)
-				x[1], x[5] = butterfly(x[1], x[5], psi[0], Q, mredParams)
-				x[2], x[6] = butterfly(x[2], x[6], psi[0], Q, mredParams)
-				x[3], x[7] = butterfly(x[3], x[7], psi[0], Q, mredParams)
-				x[8], x[12] = butterfly(x[8], x[12], psi[1], Q, mredParams)
-				x[9], x[13] = butterfly(x[9], x[13], psi[1], Q, mredParams)
-				x[10], x[14] = butterfly(x[10], x[14], psi[1], Q, mredParams)
-				x[11], x[15] = butterfly(x[11], x[15], psi[1], Q, mredParams)
+	if r.workerPool == nil || level == 0 {
+		for x := uint64(0); x < level+1; x++ {
+			invNTTInvMForm(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x], r.nttBlockSize)
+		}
+		return
+	}
 
-			}
+	r.workerPool.Run(int(level+1), func(x int) {
+		invNTTInvMForm(p1.Coeffs[x], p2.Coeffs[x], r.N, r.NttPsiInv[x], r.NttNInv[x], r.Modulus[x], r.MredParams[x], r.nttBlockSize)
+	})
+}
 
-		} else if t == 2 {
+// NTTRootsExponents returns the slice e of size N such that the j-th coefficient of a polynomial p
+// in the NTT domain is the evaluation p(psi^e[j]), with psi the primitive 2N-th root of unity of the NTT.
+// The NTT output is in bit-reversed order, so that e[j] = 2*BitReverse(j)+1; applying BitReverse
+// on a polynomial in the NTT domain sorts its evaluations by increasing exponent.
+func NTTRootsExponents(N uint64) (e []uint64) {
 
-			for i := uint64(0); i < m; i = i + 4 {
+	logN := uint64(bits.Len64(N) - 1)
 
-				j1 = (i * t) << 1
+	e = make([]uint64, N)
 
-				psi := (*[4]uint64)(unsafe.Pointer(&nttPsi[m+i]))
-				x := (*[16]uint64)(unsafe.Pointer(&coeffsOut[j1]))
+	for j := uint64(0); j < N; j++ {
+		e[j] = 2*utils.BitReverse64(j, logN) + 1
+	}
 
-				x[0], x[2] = butterfly(x[0], x[2], psi[0], Q, mredParams)
-				x[1], x[3] = butterfly(x[1], x[3], psi[0], Q, mredParams)
-				x[4], x[6] = butterfly(x[4], x[6], psi[1], Q, mredParams)
-				x[5], x[7] = butterfly(x[5], x[7], psi[1], Q, mredParams)
-				x[8], x[10] = butterfly(x[8], x[10], psi[2], Q, mredParams)
-				x[9], x[11] = butterfly(x[9], x[11], psi[2], Q, mredParams)
-				x[12], x[14] = butterfly(x[12], x[14], psi[3], Q, mredParams)
-				x[13], x[15] = butterfly(x[13], x[15], psi[3], Q, mredParams)
-			}
+	return
+}
 
-		} else {
+// butterfly computes X, Y = U + V*Psi, U - V*Psi mod Q.
+func butterfly(U, V, Psi, Q, Qinv uint64) (X, Y uint64) {
+	if U > 2*Q {
+		U -= 2 * Q
+	}
+	V = MRedConstant(V, Psi, Q, Qinv)
+	X = U + V
+	Y = U + 2*Q - V
+	return
+}
 
-			for i := uint64(0); i < m; i = i + 8 {
+// invbutterfly computes X, Y = U + V, (U - V) * Psi mod Q.
+func invbutterfly(U, V, Psi, Q, Qinv uint64) (X, Y uint64) {
+	X = U + V
+	if X > 2*Q {
+		X -= 2 * Q
+	}
+	Y = MRedConstant(U+2*Q-V, Psi, Q, Qinv) // U+2*Q-V < 4*Q, hence (U+2*Q-V)*Psi < Q*2^64 as Q < 2^62
+	return
+}
 
-				psi := (*[8]uint64)(unsafe.Pointer(&nttPsi[m+i]))
-				x := (*[16]uint64)(unsafe.Pointer(&coeffsOut[2*i]))
+// NTT computes the NTT on the input coefficients using the input parameters.
+func NTT(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams uint64, bredParams []uint64) {
+	NTTBlocked(coeffsIn, coeffsOut, N, nttPsi, Q, mredParams, bredParams, N)
+}
 
-				x[0], x[1] = butterfly(x[0], x[1], psi[0], Q, mredParams)
-				x[2], x[3] = butterfly(x[2], x[3], psi[1], Q, mredParams)
-				x[4], x[5] = butterfly(x[4], x[5], psi[2], Q, mredParams)
-				x[6], x[7] = butterfly(x[6], x[7], psi[3], Q, mredParams)
-				x[8], x[9] = butterfly(x[8], x[9], psi[4], Q, mredParams)
-				x[10], x[11] = butterfly(x[10], x[11], psi[5], Q, mredParams)
-				x[12], x[13] = butterfly(x[12], x[13], psi[6], Q, mredParams)
-				x[14], x[15] = butterfly(x[14], x[15], psi[7], Q, mredParams)
-			}
-		}
-	}
+// NTTBlocked computes the NTT on the input coefficients using the input parameters, see NTT. The butterflies are tiled on
+// contiguous blocks of blockSize coefficients: once the span of the butterflies fits in a block, all the remaining stages
+// are computed on a block before moving to the next one, so that the block stays in the cache. blockSize must be a power
+// of two greater or equal to 16; a blockSize of 0 or greater or equal to N computes the stages one after the other as NTT.
+func NTTBlocked(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams uint64, bredParams []uint64, blockSize uint64) {
 
-	// Finish with an exact reduction
+	nttLazy(coeffsIn, coeffsOut, N, nttPsi, Q, mredParams, blockSize)
+
+	// Finish with an exact reduction, as the inputs, and thus the outputs of nttLazy, can be larger than 4Q
 	for i := uint64(0); i < N; i = i + 8 {
 
 		x := (*[8]uint64)(unsafe.Pointer(&coeffsOut[i]))
@@ -184,126 +274,153 @@ func NTT(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams
 		x[6] = BRedAdd(x[6], Q, bredParams)
 		x[7] = BRedAdd(x[7], Q, bredParams)
 	}
+}
 
+// NTTMForm computes the NTT on the input coefficients using the input parameters and returns the result in the Montgomery form.
+func NTTMForm(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams uint64, bredParams []uint64) {
+	nttMForm(coeffsIn, coeffsOut, N, nttPsi, Q, mredParams, bredParams, N)
 }
 
-// InvNTT computes the InvNTT transformation on the input coefficients using the input parameters.
-func InvNTT(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, nttNInv, Q, mredParams uint64) {
+// nttMForm is NTTMForm with the butterflies tiled on blocks of blockSize coefficients, see NTTBlocked.
+func nttMForm(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams uint64, bredParams []uint64, blockSize uint64) {
 
-	var j1, j2, h, t uint64
-	var F uint64
+	nttLazy(coeffsIn, coeffsOut, N, nttPsi, Q, mredParams, blockSize)
 
-	// Copy the result of the first round of butterflies on p2 with approximate reduction
-	t = 1
-	h = N >> 1
+	// 2^128 mod Q, so that the Montgomery reduction of x * 2^128 is x * 2^64 mod Q
+	rSquare := MForm(MForm(1, Q, bredParams), Q, bredParams)
 
-	for i := uint64(0); i < h; i = i + 8 {
+	// Finish with an exact reduction, which multiplies by 2^64 at the same time
+	mulScalarMontgomeryVec(coeffsOut[:N], coeffsOut[:N], rSquare, Q, mredParams)
+}
 
-		psi := (*[8]uint64)(unsafe.Pointer(&nttPsiInv[h+i]))
-		xin := (*[16]uint64)(unsafe.Pointer(&coeffsIn[2*i]))
-		xout := (*[16]uint64)(unsafe.Pointer(&coeffsOut[2*i]))
+// nttLazy computes the butterflies of the NTT, leaving the output coefficients in [0, 4Q) for inputs in [0, 4Q). The
+// stages whose butterflies span more than blockSize coefficients are computed over the whole polynomial, and the
+// remaining stages block by block.
+func nttLazy(coeffsIn, coeffsOut []uint64, N uint64, nttPsi []uint64, Q, mredParams, blockSize uint64) {
 
-		xout[0], xout[1] = invbutterfly(xin[0], xin[1], psi[0], Q, mredParams)
-		xout[2], xout[3] = invbutterfly(xin[2], xin[3], psi[1], Q, mredParams)
-		xout[4], xout[5] = invbutterfly(xin[4], xin[5], psi[2], Q, mredParams)
-		xout[6], xout[7] = invbutterfly(xin[6], xin[7], psi[3], Q, mredParams)
-		xout[8], xout[9] = invbutterfly(xin[8], xin[9], psi[4], Q, mredParams)
-		xout[10], xout[11] = invbutterfly(xin[10], xin[11], psi[5], Q, mredParams)
-		xout[12], xout[13] = invbutterfly(xin[12], xin[13], psi[6], Q, mredParams)
-		xout[14], xout[15] = invbutterfly(xin[14], xin[15], psi[7], Q, mredParams)
+	if blockSize == 0 || blockSize > N {
+		blockSize = N
 	}
 
-	// Continue the rest of the second to the n-1 butterflies on p2 with approximate reduction
-	t <<= 1
-	for m := N >> 1; m > 1; m >>= 1 {
+	var t, F uint64
 
-		j1 = 0
-		h = m >> 1
-
-		if t >= 8 {
+	// Copy the result of the first round of butterflies on p2 with approximate reduction
+	t = N >> 1
+	F = nttPsi[1]
+	butterflyVec(coeffsIn[:t], coeffsIn[t:N], coeffsOut[:t], coeffsOut[t:N], F, Q, mredParams)
 
-			for i := uint64(0); i < h; i++ {
+	// Continue with the stages whose butterflies do not fit in a block on the whole polynomial
+	m := uint64(2)
+	for ; m < N && (t<<1) > blockSize; m <<= 1 {
+		t >>= 1
+		nttStageLazy(coeffsOut, t, m, 0, m, nttPsi, Q, mredParams)
+	}
 
-				j2 = j1 + t - 1
+	// Then finish the remaining stages block by block
+	for b := uint64(0); b < N; b += blockSize {
+		for mb, tb := m, t>>1; mb < N; mb, tb = mb<<1, tb>>1 {
+			nttStageLazy(coeffsOut, tb, mb, b/(tb<<1), (b+blockSize)/(tb<<1), nttPsi, Q, mredParams)
+		}
+	}
+}
 
-				F = nttPsiInv[h+i]
+// nttStageLazy computes the butterflies of span t of the groups i0 to i1-1 of the stage of the NTT with m groups.
+func nttStageLazy(coeffs []uint64, t, m, i0, i1 uint64, nttPsi []uint64, Q, mredParams uint64) {
 
-				for j := j1; j <= j2; j = j + 8 {
+	switch t {
+	case 4:
+		butterfly4Vec(coeffs[i0<<3:i1<<3], nttPsi[m+i0:m+i1], Q, mredParams)
+	case 2:
+		butterfly2Vec(coeffs[i0<<2:i1<<2], nttPsi[m+i0:m+i1], Q, mredParams)
+	case 1:
+		butterfly1Vec(coeffs[i0<<1:i1<<1], nttPsi[m+i0:m+i1], Q, mredParams)
+	default:
+		for i := i0; i < i1; i++ {
 
-					x := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j]))
-					y := (*[8]uint64)(unsafe.Pointer(&coeffsOut[j+t]))
+			j1 := (i * t) << 1
 
-					x[0], y[0] = invbutterfly(x[0], y[0], F, Q, mredParams)
-					x[1], y[1] = invbutterfly(x[1], y[1], F, Q, mredParams)
-					x[2], y[2] = invbutterfly(x[2], y[2], F, Q, mredParams)
-					x[3], y[3] = invbutterfly(x[3], y[3], F, Q, mredParams)
-					x[4], y[4] = invbutterfly(x[4], y[4], F, Q, mredParams)
-					x[5], y[5] = invbutterfly(x[5], y[5], F, Q, mredParams)
-					x[6], y[6] = invbutterfly(x[6], y[6], F, Q, mredParams)
-					x[7], y[7] = invbutterfly(x[7], y[7], F, Q, mredParams)
-				}
+			butterflyVec(coeffs[j1:j1+t], coeffs[j1+t:j1+2*t], coeffs[j1:j1+t], coeffs[j1+t:j1+2*t], nttPsi[m+i], Q, mredParams)
+		}
+	}
+}
 
-				j1 = j1 + (t << 1)
-			}
+// InvNTT computes the InvNTT transformation on the input coefficients using the input parameters.
+func InvNTT(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, nttNInv, Q, mredParams uint64) {
+	InvNTTBlocked(coeffsIn, coeffsOut, N, nttPsiInv, nttNInv, Q, mredParams, N)
+}
 
-		} else if t == 4 {
+// InvNTTBlocked computes the InvNTT transformation on the input coefficients using the input parameters, see InvNTT.
+// The stages whose butterflies span at most blockSize coefficients are computed block by block, see NTTBlocked.
+func InvNTTBlocked(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, nttNInv, Q, mredParams, blockSize uint64) {
+	invNTTLazy(coeffsIn, coeffsOut, N, nttPsiInv, Q, mredParams, blockSize)
+	invNTTFinalize(coeffsOut, N, nttNInv, Q, mredParams)
+}
 
-			for i := uint64(0); i < h; i = i + 2 {
+// InvNTTInvMForm computes the InvNTT transformation on the input coefficients, which are in the Montgomery form, using the input
+// parameters and returns the result out of the Montgomery form. nttNInv is N^-1 in the Montgomery form, as for InvNTT.
+func InvNTTInvMForm(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, nttNInv, Q, mredParams uint64) {
+	invNTTInvMForm(coeffsIn, coeffsOut, N, nttPsiInv, nttNInv, Q, mredParams, N)
+}
 
-				psi := (*[2]uint64)(unsafe.Pointer(&nttPsiInv[h+i]))
-				x := (*[16]uint64)(unsafe.Pointer(&coeffsOut[j1]))
+// invNTTInvMForm is InvNTTInvMForm with the butterflies tiled on blocks of blockSize coefficients, see NTTBlocked.
+func invNTTInvMForm(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, nttNInv, Q, mredParams, blockSize uint64) {
+	invNTTLazy(coeffsIn, coeffsOut, N, nttPsiInv, Q, mredParams, blockSize)
+	// The Montgomery reduction by N^-1 out of the Montgomery form multiplies by N^-1 * 2^-64
+	invNTTFinalize(coeffsOut, N, InvMForm(nttNInv, Q, mredParams), Q, mredParams)
+}
 
-				x[0], x[4] = invbutterfly(x[0], x[4], psi[0], Q, mredParams)
-				x[1], x[5] = invbutterfly(x[1], x[5], psi[0], Q, mredParams)
-				x[2], x[6] = invbutterfly(x[2], x[6], psi[0], Q, mredParams)
-				x[3], x[7] = invbutterfly(x[3], x[7], psi[0], Q, mredParams)
-				x[8], x[12] = invbutterfly(x[8], x[12], psi[1], Q, mredParams)
-				x[9], x[13] = invbutterfly(x[9], x[13], psi[1], Q, mredParams)
-				x[10], x[14] = invbutterfly(x[10], x[14], psi[1], Q, mredParams)
-				x[11], x[15] = invbutterfly(x[11], x[15], psi[1], Q, mredParams)
+// invNTTLazy computes the butterflies of the InvNTT, leaving the output coefficients in [0, 2Q). The stages whose butterflies
+// span at most blockSize coefficients are computed block by block, and the remaining stages over the whole polynomial.
+func invNTTLazy(coeffsIn, coeffsOut []uint64, N uint64, nttPsiInv []uint64, Q, mredParams, blockSize uint64) {
 
-				j1 = j1 + (t << 2)
-			}
+	if blockSize == 0 || blockSize > N {
+		blockSize = N
+	}
 
-		} else {
+	var t uint64
 
-			for i := uint64(0); i < h; i = i + 4 {
+	for b := uint64(0); b < N; b += blockSize {
 
-				psi := (*[4]uint64)(unsafe.Pointer(&nttPsiInv[h+i]))
-				x := (*[16]uint64)(unsafe.Pointer(&coeffsOut[j1]))
+		// Copy the result of the first round of butterflies on p2 with approximate reduction
+		h := N >> 1
 
-				x[0], x[2] = invbutterfly(x[0], x[2], psi[0], Q, mredParams)
-				x[1], x[3] = invbutterfly(x[1], x[3], psi[0], Q, mredParams)
-				x[4], x[6] = invbutterfly(x[4], x[6], psi[1], Q, mredParams)
-				x[5], x[7] = invbutterfly(x[5], x[7], psi[1], Q, mredParams)
-				x[8], x[10] = invbutterfly(x[8], x[10], psi[2], Q, mredParams)
-				x[9], x[11] = invbutterfly(x[9], x[11], psi[2], Q, mredParams)
-				x[12], x[14] = invbutterfly(x[12], x[14], psi[3], Q, mredParams)
-				x[13], x[15] = invbutterfly(x[13], x[15], psi[3], Q, mredParams)
+		invButterfly1Vec(coeffsIn[b:b+blockSize], coeffsOut[b:b+blockSize], nttPsiInv[h+(b>>1):h+((b+blockSize)>>1)], Q, mredParams)
 
-				j1 = j1 + (t << 3)
-			}
+		// Continue with the stages whose butterflies fit in the block
+		for t = 2; t < N && (t<<1) <= blockSize; t <<= 1 {
+			invNTTStageLazy(coeffsOut, t, N/(t<<1), b/(t<<1), (b+blockSize)/(t<<1), nttPsiInv, Q, mredParams)
 		}
+	}
 
-		t <<= 1
+	// Then finish the remaining stages on the whole polynomial
+	for ; t < N; t <<= 1 {
+		invNTTStageLazy(coeffsOut, t, N/(t<<1), 0, N/(t<<1), nttPsiInv, Q, mredParams)
 	}
+}
 
-	// Finish with an exact reduction
-	for i := uint64(0); i < N; i = i + 8 {
+// invNTTStageLazy computes the butterflies of span t >= 2 of the groups i0 to i1-1 of the stage of the InvNTT with h groups.
+func invNTTStageLazy(coeffs []uint64, t, h, i0, i1 uint64, nttPsiInv []uint64, Q, mredParams uint64) {
 
-		x := (*[8]uint64)(unsafe.Pointer(&coeffsOut[i]))
+	switch t {
+	case 4:
+		invButterfly4Vec(coeffs[i0<<3:i1<<3], nttPsiInv[h+i0:h+i1], Q, mredParams)
+	case 2:
+		invButterfly2Vec(coeffs[i0<<2:i1<<2], nttPsiInv[h+i0:h+i1], Q, mredParams)
+	default:
+		for i := i0; i < i1; i++ {
 
-		x[0] = MRed(x[0], nttNInv, Q, mredParams)
-		x[1] = MRed(x[1], nttNInv, Q, mredParams)
-		x[2] = MRed(x[2], nttNInv, Q, mredParams)
-		x[3] = MRed(x[3], nttNInv, Q, mredParams)
-		x[4] = MRed(x[4], nttNInv, Q, mredParams)
-		x[5] = MRed(x[5], nttNInv, Q, mredParams)
-		x[6] = MRed(x[6], nttNInv, Q, mredParams)
-		x[7] = MRed(x[7], nttNInv, Q, mredParams)
+			j1 := (i * t) << 1
+
+			invButterflyVec(coeffs[j1:j1+t], coeffs[j1+t:j1+2*t], coeffs[j1:j1+t], coeffs[j1+t:j1+2*t], nttPsiInv[h+i], Q, mredParams)
+		}
 	}
 }
 
+// invNTTFinalize multiplies the output of invNTTLazy by nInv * 2^-64 with an exact reduction.
+func invNTTFinalize(coeffsOut []uint64, N, nInv, Q, mredParams uint64) {
+	mulScalarMontgomeryVec(coeffsOut[:N], coeffsOut[:N], nInv, Q, mredParams)
+}
+
 ///////////////////////////////////
 /// For benchmark purposes only ///
 ///////////////////////////////////
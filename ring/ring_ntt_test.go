@@ -118,3 +118,82 @@ func TestNTT(t *testing.T) {
 		})
 	}
 }
+
+func TestNTTParallel(t *testing.T) {
+
+	pool := NewWorkerPool(4)
+	defer pool.Close()
+
+	for _, tv := range testVector[1:] {
+
+		ringQ, _ := NewRing(tv.N, tv.Qis)
+
+		t.Run(fmt.Sprintf("N=%d/limbs=%d", ringQ.N, len(ringQ.Modulus)), func(t *testing.T) {
+
+			x := ringQ.NewPoly()
+			ringQ.NTTParallel(tv.poly, x)
+			assert.True(t, ringQ.Equal(x, tv.polyNTT), "parallel NTT and polyNTT should match")
+
+			ringQ.InvNTTParallel(x, x)
+			assert.True(t, ringQ.Equal(tv.poly, x), "parallel invNTT should reverse NTT")
+
+			ringQ.SetWorkerPool(pool)
+			defer ringQ.SetWorkerPool(nil)
+
+			ringQ.NTT(tv.poly, x)
+			assert.True(t, ringQ.Equal(x, tv.polyNTT), "NTT on a WorkerPool and polyNTT should match")
+
+			ringQ.InvNTT(x, x)
+			assert.True(t, ringQ.Equal(x, tv.poly), "invNTT on a WorkerPool should reverse NTT")
+
+			ringQ.NTTMForm(tv.poly, x)
+			ringQ.InvMForm(x, x)
+			assert.True(t, ringQ.Equal(x, tv.polyNTT), "NTTMForm on a WorkerPool should be NTT followed by MForm")
+
+			ringQ.MForm(x, x)
+			ringQ.InvNTTInvMForm(x, x)
+			assert.True(t, ringQ.Equal(x, tv.poly), "invNTTInvMForm on a WorkerPool should reverse NTTMForm")
+		})
+	}
+}
+
+func TestNTTBlocked(t *testing.T) {
+
+	for _, tv := range testVector[2:] {
+
+		ringQ, _ := NewRing(tv.N, tv.Qis)
+
+		for blockSize := uint64(16); blockSize < ringQ.N; blockSize <<= 1 {
+
+			t.Run(fmt.Sprintf("N=%d/limbs=%d/blockSize=%d", ringQ.N, len(ringQ.Modulus), blockSize), func(t *testing.T) {
+
+				assert.Nil(t, ringQ.SetNTTBlockSize(blockSize))
+				defer ringQ.SetNTTBlockSize(0)
+
+				x := ringQ.NewPoly()
+				ringQ.NTT(tv.poly, x)
+
+				assert.True(t, ringQ.Equal(x, tv.polyNTT), "blocked NTT and polyNTT should match")
+
+				ringQ.InvNTT(x, x)
+
+				assert.True(t, ringQ.Equal(tv.poly, x), "blocked invNTT should reverse NTT")
+
+				ringQ.NTTMForm(tv.poly, x)
+				ringQ.InvMForm(x, x)
+
+				assert.True(t, ringQ.Equal(x, tv.polyNTT), "blocked NTTMForm should be NTT followed by MForm")
+			})
+		}
+	}
+
+	t.Run("Autotuning", func(t *testing.T) {
+
+		ringQ, err := NewRing(MinNTTBlockedN, GenerateNTTPrimes(55, 16, 1))
+		assert.Nil(t, err)
+		assert.Contains(t, nttBlockSizeCandidates, ringQ.NTTBlockSize())
+
+		assert.NotNil(t, ringQ.SetNTTBlockSize(24))
+		assert.NotNil(t, ringQ.SetNTTBlockSize(8))
+	})
+}
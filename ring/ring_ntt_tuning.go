@@ -0,0 +1,58 @@
+package ring
+
+import (
+	"sync"
+	"time"
+)
+
+// MinNTTBlockedN is the smallest ring degree for which the size of the blocks of the NTT is autotuned. Below it,
+// a polynomial fits in the cache and the NTT is not blocked.
+const MinNTTBlockedN = 1 << 16
+
+// nttBlockSizeCandidates are the block sizes compared by the autotuning, 0 standing for the unblocked NTT.
+var nttBlockSizeCandidates = []uint64{0, 1 << 10, 1 << 11, 1 << 12, 1 << 13, 1 << 14, 1 << 15}
+
+// nttBlockSizeCache stores the autotuned block size of each ring degree, so that it is measured once per process.
+var nttBlockSizeCache sync.Map
+
+// tuneNTTBlockSize returns the block size among nttBlockSizeCandidates for which an NTT followed by an InvNTT of
+// degree N is the fastest on this machine. The timings are done on a single modulus, the block size not depending
+// on it, and the result is cached by degree.
+func tuneNTTBlockSize(N, Q, mredParams uint64, bredParams, nttPsi, nttPsiInv []uint64, nttNInv uint64) uint64 {
+
+	if blockSize, ok := nttBlockSizeCache.Load(N); ok {
+		return blockSize.(uint64)
+	}
+
+	coeffs := make([]uint64, N)
+	for i := range coeffs {
+		coeffs[i] = uint64(i) % Q
+	}
+
+	var best uint64
+	bestTime := time.Duration(-1)
+
+	for _, blockSize := range nttBlockSizeCandidates {
+
+		if blockSize >= N {
+			continue
+		}
+
+		// Best of a few runs, to filter out the noise of the scheduler
+		for k := 0; k < 3; k++ {
+
+			start := time.Now()
+			NTTBlocked(coeffs, coeffs, N, nttPsi, Q, mredParams, bredParams, blockSize)
+			InvNTTBlocked(coeffs, coeffs, N, nttPsiInv, nttNInv, Q, mredParams, blockSize)
+			elapsed := time.Since(start)
+
+			if bestTime < 0 || elapsed < bestTime {
+				best, bestTime = blockSize, elapsed
+			}
+		}
+	}
+
+	blockSize, _ := nttBlockSizeCache.LoadOrStore(N, best)
+
+	return blockSize.(uint64)
+}
@@ -11,51 +11,26 @@ import (
 // Add adds p1 to p2 coefficient-wise and writes the result on p3.
 func (r *Ring) Add(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
-
-			z[0] = CRed(x[0]+y[0], qi)
-			z[1] = CRed(x[1]+y[1], qi)
-			z[2] = CRed(x[2]+y[2], qi)
-			z[3] = CRed(x[3]+y[3], qi)
-			z[4] = CRed(x[4]+y[4], qi)
-			z[5] = CRed(x[5]+y[5], qi)
-			z[6] = CRed(x[6]+y[6], qi)
-			z[7] = CRed(x[7]+y[7], qi)
-		}
+		addVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], p3.Coeffs[i][:r.N], qi)
 	}
 }
 
 // AddLvl adds p1 to p2 coefficient-wise for the moduli from
 // q_0 up to q_level and writes the result on p3.
 func (r *Ring) AddLvl(level uint64, p1, p2, p3 *Poly) {
-	for i := uint64(0); i < level+1; i++ {
-		qi := r.Modulus[i]
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+	if debugChecks {
+		checkLevel("AddLvl", r, level, p1, p2, p3)
+		checkAliasing("AddLvl", r.N, level, true, p3, p1, p2)
+	}
 
-			z[0] = CRed(x[0]+y[0], qi)
-			z[1] = CRed(x[1]+y[1], qi)
-			z[2] = CRed(x[2]+y[2], qi)
-			z[3] = CRed(x[3]+y[3], qi)
-			z[4] = CRed(x[4]+y[4], qi)
-			z[5] = CRed(x[5]+y[5], qi)
-			z[6] = CRed(x[6]+y[6], qi)
-			z[7] = CRed(x[7]+y[7], qi)
-		}
+	for i := uint64(0); i < level+1; i++ {
+		addVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], p3.Coeffs[i][:r.N], r.Modulus[i])
 	}
 }
 
 // AddNoMod adds p1 to p2 coefficient-wise without
 // modular reduction and writes the result on p3.
+// The range of the result is the sum of the ranges of the inputs, e.g. [0, 2q) for inputs in [0, q).
 func (r *Ring) AddNoMod(p1, p2, p3 *Poly) {
 	for i := range r.Modulus {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -79,7 +54,13 @@ func (r *Ring) AddNoMod(p1, p2, p3 *Poly) {
 
 // AddNoModLvl adds p1 to p2 coefficient-wise without modular reduction
 // for the moduli from q_0 up to q_level and writes the result on p3.
+// The range of the result is the sum of the ranges of the inputs, e.g. [0, 2q) for inputs in [0, q).
 func (r *Ring) AddNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("AddNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("AddNoModLvl", r.N, level, true, p3, p1, p2)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
 		for j := uint64(0); j < r.N; j = j + 8 {
@@ -103,50 +84,25 @@ func (r *Ring) AddNoModLvl(level uint64, p1, p2, p3 *Poly) {
 // Sub subtracts p2 to p1 coefficient-wise and writes the result on p3.
 func (r *Ring) Sub(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
-
-			z[0] = CRed((x[0]+qi)-y[0], qi)
-			z[1] = CRed((x[1]+qi)-y[1], qi)
-			z[2] = CRed((x[2]+qi)-y[2], qi)
-			z[3] = CRed((x[3]+qi)-y[3], qi)
-			z[4] = CRed((x[4]+qi)-y[4], qi)
-			z[5] = CRed((x[5]+qi)-y[5], qi)
-			z[6] = CRed((x[6]+qi)-y[6], qi)
-			z[7] = CRed((x[7]+qi)-y[7], qi)
-		}
+		subVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], p3.Coeffs[i][:r.N], qi)
 	}
 }
 
 // SubLvl subtracts p2 to p1 coefficient-wise and writes the result on p3.
 func (r *Ring) SubLvl(level uint64, p1, p2, p3 *Poly) {
-	for i := uint64(0); i < level+1; i++ {
-		qi := r.Modulus[i]
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+	if debugChecks {
+		checkLevel("SubLvl", r, level, p1, p2, p3)
+		checkAliasing("SubLvl", r.N, level, true, p3, p1, p2)
+	}
 
-			z[0] = CRed((x[0]+qi)-y[0], qi)
-			z[1] = CRed((x[1]+qi)-y[1], qi)
-			z[2] = CRed((x[2]+qi)-y[2], qi)
-			z[3] = CRed((x[3]+qi)-y[3], qi)
-			z[4] = CRed((x[4]+qi)-y[4], qi)
-			z[5] = CRed((x[5]+qi)-y[5], qi)
-			z[6] = CRed((x[6]+qi)-y[6], qi)
-			z[7] = CRed((x[7]+qi)-y[7], qi)
-		}
+	for i := uint64(0); i < level+1; i++ {
+		subVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], p3.Coeffs[i][:r.N], r.Modulus[i])
 	}
 }
 
 // SubNoMod subtracts p2 to p1 coefficient-wise without
 // modular reduction and returns the result on p3.
+// p2 must be in [0, q], and the range of the result is the one of p1 increased by q, e.g. [0, 2q) for p1 in [0, q).
 func (r *Ring) SubNoMod(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -170,7 +126,13 @@ func (r *Ring) SubNoMod(p1, p2, p3 *Poly) {
 
 // SubNoModLvl subtracts p2 to p1 coefficient-wise without modular reduction
 // for the moduli from q_0 up to q_level and writes the result on p3.
+// p2 must be in [0, q], and the range of the result is the one of p1 increased by q, e.g. [0, 2q) for p1 in [0, q).
 func (r *Ring) SubNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("SubNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("SubNoModLvl", r.N, level, true, p3, p1, p2)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -216,6 +178,11 @@ func (r *Ring) Neg(p1, p2 *Poly) {
 // NegLvl sets the coefficients of p1 to their additive inverse for
 // the moduli from q_0 up to q_level and writes the result on p2.
 func (r *Ring) NegLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("NegLvl", r, level, p1, p2)
+		checkAliasing("NegLvl", r.N, level, true, p2, p1)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
@@ -261,6 +228,11 @@ func (r *Ring) Reduce(p1, p2 *Poly) {
 // ReduceLvl applies a modular reduction on the coefficients of p1
 // for the moduli from q_0 up to q_level and writes the result on p2.
 func (r *Ring) ReduceLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("ReduceLvl", r, level, p1, p2)
+		checkAliasing("ReduceLvl", r.N, level, true, p2, p1)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
@@ -282,6 +254,41 @@ func (r *Ring) ReduceLvl(level uint64, p1, p2 *Poly) {
 	}
 }
 
+// ReduceConstant applies a modular reduction on the coefficients of p1 in constant time and writes the result on p2.
+// The coefficients of p1 can be any value in [0, 2^64), thus it finalizes a sequence of lazy operations.
+func (r *Ring) ReduceConstant(p1, p2 *Poly) {
+	r.ReduceConstantLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
+
+// ReduceConstantLvl applies a modular reduction on the coefficients of p1 in constant time for the moduli from q_0 up
+// to q_level and writes the result on p2, see ReduceConstant.
+func (r *Ring) ReduceConstantLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("ReduceConstantLvl", r, level, p1, p2)
+		checkAliasing("ReduceConstantLvl", r.N, level, true, p2, p1)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		qi := r.Modulus[i]
+		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
+		bredParams := r.BredParams[i]
+		for j := uint64(0); j < r.N; j = j + 8 {
+
+			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+
+			z[0] = constantTimeCRed(BRedAddConstant(x[0], qi, bredParams), qi)
+			z[1] = constantTimeCRed(BRedAddConstant(x[1], qi, bredParams), qi)
+			z[2] = constantTimeCRed(BRedAddConstant(x[2], qi, bredParams), qi)
+			z[3] = constantTimeCRed(BRedAddConstant(x[3], qi, bredParams), qi)
+			z[4] = constantTimeCRed(BRedAddConstant(x[4], qi, bredParams), qi)
+			z[5] = constantTimeCRed(BRedAddConstant(x[5], qi, bredParams), qi)
+			z[6] = constantTimeCRed(BRedAddConstant(x[6], qi, bredParams), qi)
+			z[7] = constantTimeCRed(BRedAddConstant(x[7], qi, bredParams), qi)
+		}
+	}
+}
+
 // Mod applies a modular reduction by m on the coefficients of p1 and writes the result on p2.
 func (r *Ring) Mod(p1 *Poly, m uint64, p2 *Poly) {
 	bredParams := BRedParams(m)
@@ -384,6 +391,7 @@ func (r *Ring) MulCoeffsAndAdd(p1, p2, p3 *Poly) {
 
 // MulCoeffsAndAddNoMod multiplies p1 by p2 coefficient-wise with a Barrett
 // modular reduction and adds the result to p3 without modular reduction.
+// The product is in [0, q), thus the range of p3 is increased by q.
 func (r *Ring) MulCoeffsAndAddNoMod(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -406,53 +414,58 @@ func (r *Ring) MulCoeffsAndAddNoMod(p1, p2, p3 *Poly) {
 	}
 }
 
-// MulCoeffsMontgomery multiplies p1 by p2 coefficient-wise with a
-// Montgomery modular reduction and returns the result on p3.
-func (r *Ring) MulCoeffsMontgomery(p1, p2, p3 *Poly) {
-	for i, qi := range r.Modulus {
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		mredParams := r.MredParams[i]
+// MulCoeffsAndAddNoModLvl multiplies p1 by p2 coefficient-wise with a Barrett modular reduction for the moduli
+// from q_0 up to q_level and adds the result to p3 without modular reduction.
+// The product is in [0, q), thus the range of p3 is increased by q.
+func (r *Ring) MulCoeffsAndAddNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsAndAddNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsAndAddNoModLvl", r.N, level, true, p3, p1, p2)
+	}
 
+	for i := uint64(0); i < level+1; i++ {
+		qi := r.Modulus[i]
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		bredParams := r.BredParams[i]
 		for j := uint64(0); j < r.N; j = j + 8 {
 
 			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
 			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
 			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
 
-			z[0] = MRed(x[0], y[0], qi, mredParams)
-			z[1] = MRed(x[1], y[1], qi, mredParams)
-			z[2] = MRed(x[2], y[2], qi, mredParams)
-			z[3] = MRed(x[3], y[3], qi, mredParams)
-			z[4] = MRed(x[4], y[4], qi, mredParams)
-			z[5] = MRed(x[5], y[5], qi, mredParams)
-			z[6] = MRed(x[6], y[6], qi, mredParams)
-			z[7] = MRed(x[7], y[7], qi, mredParams)
+			z[0] += BRed(x[0], y[0], qi, bredParams)
+			z[1] += BRed(x[1], y[1], qi, bredParams)
+			z[2] += BRed(x[2], y[2], qi, bredParams)
+			z[3] += BRed(x[3], y[3], qi, bredParams)
+			z[4] += BRed(x[4], y[4], qi, bredParams)
+			z[5] += BRed(x[5], y[5], qi, bredParams)
+			z[6] += BRed(x[6], y[6], qi, bredParams)
+			z[7] += BRed(x[7], y[7], qi, bredParams)
 		}
 	}
 }
 
+// MulCoeffsMontgomery multiplies p1 by p2 coefficient-wise with a
+// Montgomery modular reduction and returns the result on p3.
+func (r *Ring) MulCoeffsMontgomery(p1, p2, p3 *Poly) {
+	r.MulCoeffsMontgomeryLvl(uint64(len(r.Modulus)-1), p1, p2, p3)
+}
+
 // MulCoeffsMontgomeryLvl multiplies p1 by p2 coefficient-wise with a Montgomery
 // modular reduction for the moduli from q_0 up to q_level and returns the result on p3.
 func (r *Ring) MulCoeffsMontgomeryLvl(level uint64, p1, p2, p3 *Poly) {
-	for i := uint64(0); i < level+1; i++ {
-		qi := r.Modulus[i]
-		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
-		mredParams := r.MredParams[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryLvl", r.N, level, true, p3, p1, p2)
+	}
 
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+	r.Backend().MulCoeffsMontgomeryLvl(r, level, p1, p2, p3)
+}
 
-			z[0] = MRed(x[0], y[0], qi, mredParams)
-			z[1] = MRed(x[1], y[1], qi, mredParams)
-			z[2] = MRed(x[2], y[2], qi, mredParams)
-			z[3] = MRed(x[3], y[3], qi, mredParams)
-			z[4] = MRed(x[4], y[4], qi, mredParams)
-			z[5] = MRed(x[5], y[5], qi, mredParams)
-			z[6] = MRed(x[6], y[6], qi, mredParams)
-			z[7] = MRed(x[7], y[7], qi, mredParams)
-		}
+// mulCoeffsMontgomeryLvl is the implementation of MulCoeffsMontgomeryLvl of the CPUBackend.
+func (r *Ring) mulCoeffsMontgomeryLvl(level uint64, p1, p2, p3 *Poly) {
+	for i := uint64(0); i < level+1; i++ {
+		mulCoeffsMontgomeryVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], p3.Coeffs[i][:r.N], r.Modulus[i], r.MredParams[i])
 	}
 }
 
@@ -483,6 +496,11 @@ func (r *Ring) MulCoeffsMontgomeryAndAdd(p1, p2, p3 *Poly) {
 // MulCoeffsMontgomeryAndAddLvl multiplies p1 by p2 coefficient-wise with a Montgomery
 // modular reduction for the moduli from q_0 up to q_level and adds the result to p3.
 func (r *Ring) MulCoeffsMontgomeryAndAddLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryAndAddLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryAndAddLvl", r.N, level, true, p3, p1, p2)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -507,6 +525,7 @@ func (r *Ring) MulCoeffsMontgomeryAndAddLvl(level uint64, p1, p2, p3 *Poly) {
 
 // MulCoeffsMontgomeryAndAddNoMod multiplies p1 by p2 coefficient-wise with a
 // Montgomery modular reduction and adds the result to p3 without modular reduction.
+// The product is in [0, q), thus the range of p3 is increased by q.
 func (r *Ring) MulCoeffsMontgomeryAndAddNoMod(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -531,7 +550,13 @@ func (r *Ring) MulCoeffsMontgomeryAndAddNoMod(p1, p2, p3 *Poly) {
 
 // MulCoeffsMontgomeryAndAddNoModLvl multiplies p1 by p2 coefficient-wise with a Montgomery modular
 // reduction for the moduli from q_0 up to q_level and adds the result to p3 without modular reduction.
+// The product is in [0, q), thus the range of p3 is increased by q.
 func (r *Ring) MulCoeffsMontgomeryAndAddNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryAndAddNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryAndAddNoModLvl", r.N, level, true, p3, p1, p2)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -554,9 +579,22 @@ func (r *Ring) MulCoeffsMontgomeryAndAddNoModLvl(level uint64, p1, p2, p3 *Poly)
 	}
 }
 
+// MulCoeffsMontgomeryConstantAndAddNoMod multiplies p1 by p2 coefficient-wise with a constant-time Montgomery
+// modular reduction and adds the result to p3 without modular reduction.
+// The product is in [0, 2q), thus the range of p3 is increased by 2q.
+func (r *Ring) MulCoeffsMontgomeryConstantAndAddNoMod(p1, p2, p3 *Poly) {
+	r.MulCoeffsMontgomeryConstantAndAddNoModLvl(uint64(len(r.Modulus)-1), p1, p2, p3)
+}
+
 // MulCoeffsMontgomeryConstantAndAddNoModLvl multiplies p1 by p2 coefficient-wise with a constant-time Montgomery
 // modular reduction for the moduli from q_0 up to q_level and adds the result to p3 without modular reduction.
+// The product is in [0, 2q), thus the range of p3 is increased by 2q.
 func (r *Ring) MulCoeffsMontgomeryConstantAndAddNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryConstantAndAddNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryConstantAndAddNoModLvl", r.N, level, true, p3, p1, p2)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		qi := r.Modulus[i]
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -605,6 +643,7 @@ func (r *Ring) MulCoeffsMontgomeryAndSub(p1, p2, p3 *Poly) {
 
 // MulCoeffsMontgomeryAndSubNoMod multiplies p1 by p2 coefficient-wise with a Montgomery
 // modular reduction and subtracts the result from p3 without modular reduction.
+// The negated product is in (0, q], thus the range of p3 is increased by q.
 func (r *Ring) MulCoeffsMontgomeryAndSubNoMod(p1, p2, p3 *Poly) {
 	for i, qi := range r.Modulus {
 		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
@@ -627,6 +666,113 @@ func (r *Ring) MulCoeffsMontgomeryAndSubNoMod(p1, p2, p3 *Poly) {
 	}
 }
 
+// MulCoeffsMontgomeryAndSubLvl multiplies p1 by p2 coefficient-wise with a Montgomery
+// modular reduction for the moduli from q_0 up to q_level and subtracts the result from p3.
+func (r *Ring) MulCoeffsMontgomeryAndSubLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryAndSubLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryAndSubLvl", r.N, level, true, p3, p1, p2)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		qi := r.Modulus[i]
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		mredParams := r.MredParams[i]
+		for j := uint64(0); j < r.N; j = j + 8 {
+
+			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+
+			z[0] = CRed(z[0]+(qi-MRed(x[0], y[0], qi, mredParams)), qi)
+			z[1] = CRed(z[1]+(qi-MRed(x[1], y[1], qi, mredParams)), qi)
+			z[2] = CRed(z[2]+(qi-MRed(x[2], y[2], qi, mredParams)), qi)
+			z[3] = CRed(z[3]+(qi-MRed(x[3], y[3], qi, mredParams)), qi)
+			z[4] = CRed(z[4]+(qi-MRed(x[4], y[4], qi, mredParams)), qi)
+			z[5] = CRed(z[5]+(qi-MRed(x[5], y[5], qi, mredParams)), qi)
+			z[6] = CRed(z[6]+(qi-MRed(x[6], y[6], qi, mredParams)), qi)
+			z[7] = CRed(z[7]+(qi-MRed(x[7], y[7], qi, mredParams)), qi)
+		}
+	}
+}
+
+// MulCoeffsMontgomeryAndSubNoModLvl multiplies p1 by p2 coefficient-wise with a Montgomery modular
+// reduction for the moduli from q_0 up to q_level and subtracts the result from p3 without modular reduction.
+// The negated product is in (0, q], thus the range of p3 is increased by q.
+func (r *Ring) MulCoeffsMontgomeryAndSubNoModLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsMontgomeryAndSubNoModLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsMontgomeryAndSubNoModLvl", r.N, level, true, p3, p1, p2)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		qi := r.Modulus[i]
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		mredParams := r.MredParams[i]
+		for j := uint64(0); j < r.N; j = j + 8 {
+
+			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+
+			z[0] += (qi - MRed(x[0], y[0], qi, mredParams))
+			z[1] += (qi - MRed(x[1], y[1], qi, mredParams))
+			z[2] += (qi - MRed(x[2], y[2], qi, mredParams))
+			z[3] += (qi - MRed(x[3], y[3], qi, mredParams))
+			z[4] += (qi - MRed(x[4], y[4], qi, mredParams))
+			z[5] += (qi - MRed(x[5], y[5], qi, mredParams))
+			z[6] += (qi - MRed(x[6], y[6], qi, mredParams))
+			z[7] += (qi - MRed(x[7], y[7], qi, mredParams))
+		}
+	}
+}
+
+// MulCoeffsConstantMontgomeryAndAdd multiplies p1 by p2 coefficient-wise with a constant-time Montgomery
+// modular reduction and adds the result to p3. The sum, between 0 and 3*q-1, is lazily reduced in constant
+// time, without the intermediate reduction of the product.
+func (r *Ring) MulCoeffsConstantMontgomeryAndAdd(p1, p2, p3 *Poly) {
+	r.MulCoeffsConstantMontgomeryAndAddLvl(uint64(len(r.Modulus)-1), p1, p2, p3)
+}
+
+// MulCoeffsConstantMontgomeryAndAddLvl multiplies p1 by p2 coefficient-wise with a constant-time Montgomery
+// modular reduction for the moduli from q_0 up to q_level and adds the result to p3, see
+// MulCoeffsConstantMontgomeryAndAdd.
+func (r *Ring) MulCoeffsConstantMontgomeryAndAddLvl(level uint64, p1, p2, p3 *Poly) {
+	if debugChecks {
+		checkLevel("MulCoeffsConstantMontgomeryAndAddLvl", r, level, p1, p2, p3)
+		checkAliasing("MulCoeffsConstantMontgomeryAndAddLvl", r.N, level, true, p3, p1, p2)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		qi := r.Modulus[i]
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		mredParams := r.MredParams[i]
+		for j := uint64(0); j < r.N; j = j + 8 {
+
+			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+
+			z[0] = lazyCRed3(z[0]+MRedConstant(x[0], y[0], qi, mredParams), qi)
+			z[1] = lazyCRed3(z[1]+MRedConstant(x[1], y[1], qi, mredParams), qi)
+			z[2] = lazyCRed3(z[2]+MRedConstant(x[2], y[2], qi, mredParams), qi)
+			z[3] = lazyCRed3(z[3]+MRedConstant(x[3], y[3], qi, mredParams), qi)
+			z[4] = lazyCRed3(z[4]+MRedConstant(x[4], y[4], qi, mredParams), qi)
+			z[5] = lazyCRed3(z[5]+MRedConstant(x[5], y[5], qi, mredParams), qi)
+			z[6] = lazyCRed3(z[6]+MRedConstant(x[6], y[6], qi, mredParams), qi)
+			z[7] = lazyCRed3(z[7]+MRedConstant(x[7], y[7], qi, mredParams), qi)
+		}
+	}
+}
+
+// lazyCRed3 returns a mod q for a between 0 and 3*q-1, in constant time.
+func lazyCRed3(a, q uint64) uint64 {
+	a -= q
+	a += q & uint64(int64(a)>>63)
+	a -= q
+	return a + (q & uint64(int64(a)>>63))
+}
+
 // MulCoeffsConstant multiplies p1 by p2 coefficient-wise with a constant-time
 // Barrett modular reduction and writes the result on p3.
 func (r *Ring) MulCoeffsConstant(p1, p2, p3 *Poly) {
@@ -675,9 +821,15 @@ func (r *Ring) MulCoeffsMontgomeryConstant(p1, p2, p3 *Poly) {
 	}
 }
 
-// MulPoly multiplies p1 by p2 and writes the result on p3.
+// MulPoly multiplies p1 by p2 and writes the result on p3. If the Ring does not allow the NTT, the product is
+// computed with MulPolyKaratsuba.
 func (r *Ring) MulPoly(p1, p2, p3 *Poly) {
 
+	if !r.allowsNTT {
+		r.MulPolyKaratsuba(p1, p2, p3)
+		return
+	}
+
 	a := r.NewPoly()
 	b := r.NewPoly()
 
@@ -895,6 +1047,11 @@ func (r *Ring) MulScalar(p1 *Poly, scalar uint64, p2 *Poly) {
 
 // MulScalarLvl multiplies each coefficient of p1 by a scalar for the moduli from q_0 up to q_level and writes the result on p2.
 func (r *Ring) MulScalarLvl(level uint64, p1 *Poly, scalar uint64, p2 *Poly) {
+	if debugChecks {
+		checkLevel("MulScalarLvl", r, level, p1, p2)
+		checkAliasing("MulScalarLvl", r.N, level, true, p2, p1)
+	}
+
 	for i := uint64(0); i < level+1; i++ {
 		Qi := r.Modulus[i]
 		scalarMont := MForm(BRedAdd(scalar, Qi, r.BredParams[i]), Qi, r.BredParams[i])
@@ -917,6 +1074,42 @@ func (r *Ring) MulScalarLvl(level uint64, p1 *Poly, scalar uint64, p2 *Poly) {
 	}
 }
 
+// AddThenMulScalar adds p1 to p2 coefficient-wise, multiplies the result by a scalar and writes it on p3.
+func (r *Ring) AddThenMulScalar(p1, p2 *Poly, scalar uint64, p3 *Poly) {
+	r.AddThenMulScalarLvl(uint64(len(r.Modulus)-1), p1, p2, scalar, p3)
+}
+
+// AddThenMulScalarLvl adds p1 to p2 coefficient-wise, multiplies the result by a scalar for the moduli from q_0 up
+// to q_level and writes it on p3.
+func (r *Ring) AddThenMulScalarLvl(level uint64, p1, p2 *Poly, scalar uint64, p3 *Poly) {
+	if debugChecks {
+		checkLevel("AddThenMulScalarLvl", r, level, p1, p2, p3)
+		checkAliasing("AddThenMulScalarLvl", r.N, level, true, p3, p1, p2)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		Qi := r.Modulus[i]
+		scalarMont := MForm(BRedAdd(scalar, Qi, r.BredParams[i]), Qi, r.BredParams[i])
+		p1tmp, p2tmp, p3tmp := p1.Coeffs[i], p2.Coeffs[i], p3.Coeffs[i]
+		mredParams := r.MredParams[i]
+		for j := uint64(0); j < r.N; j = j + 8 {
+
+			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p3tmp[j]))
+
+			z[0] = MRed(x[0]+y[0], scalarMont, Qi, mredParams)
+			z[1] = MRed(x[1]+y[1], scalarMont, Qi, mredParams)
+			z[2] = MRed(x[2]+y[2], scalarMont, Qi, mredParams)
+			z[3] = MRed(x[3]+y[3], scalarMont, Qi, mredParams)
+			z[4] = MRed(x[4]+y[4], scalarMont, Qi, mredParams)
+			z[5] = MRed(x[5]+y[5], scalarMont, Qi, mredParams)
+			z[6] = MRed(x[6]+y[6], scalarMont, Qi, mredParams)
+			z[7] = MRed(x[7]+y[7], scalarMont, Qi, mredParams)
+		}
+	}
+}
+
 // MulScalarBigint multiplies each coefficient of p1 by a big.Int scalar and writes the result on p2.
 func (r *Ring) MulScalarBigint(p1 *Poly, scalar *big.Int, p2 *Poly) {
 	scalarQi := new(big.Int)
@@ -979,68 +1172,36 @@ func (r *Ring) Shift(p1 *Poly, n uint64, p2 *Poly) {
 
 // MForm switches p1 to the Montgomery domain and writes the result on p2.
 func (r *Ring) MForm(p1, p2 *Poly) {
-	for i, qi := range r.Modulus {
-		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
-		bredParams := r.BredParams[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
-
-			z[0] = MForm(x[0], qi, bredParams)
-			z[1] = MForm(x[1], qi, bredParams)
-			z[2] = MForm(x[2], qi, bredParams)
-			z[3] = MForm(x[3], qi, bredParams)
-			z[4] = MForm(x[4], qi, bredParams)
-			z[5] = MForm(x[5], qi, bredParams)
-			z[6] = MForm(x[6], qi, bredParams)
-			z[7] = MForm(x[7], qi, bredParams)
-		}
-	}
+	r.MFormLvl(uint64(len(r.Modulus)-1), p1, p2)
 }
 
 // MFormLvl switches p1 to the Montgomery domain for the moduli from q_0 up to q_level and writes the result on p2.
 func (r *Ring) MFormLvl(level uint64, p1, p2 *Poly) {
-	for i := uint64(0); i < level+1; i++ {
-		qi := r.Modulus[i]
-		bredParams := r.BredParams[i]
-		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
-
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+	if debugChecks {
+		checkLevel("MFormLvl", r, level, p1, p2)
+		checkAliasing("MFormLvl", r.N, level, true, p2, p1)
+	}
 
-			z[0] = MForm(x[0], qi, bredParams)
-			z[1] = MForm(x[1], qi, bredParams)
-			z[2] = MForm(x[2], qi, bredParams)
-			z[3] = MForm(x[3], qi, bredParams)
-			z[4] = MForm(x[4], qi, bredParams)
-			z[5] = MForm(x[5], qi, bredParams)
-			z[6] = MForm(x[6], qi, bredParams)
-			z[7] = MForm(x[7], qi, bredParams)
-		}
+	for i := uint64(0); i < level+1; i++ {
+		mFormVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], r.Modulus[i], r.BredParams[i])
 	}
 }
 
 // InvMForm switches back p1 from the Montgomery domain to the conventional domain and writes the result on p2.
 func (r *Ring) InvMForm(p1, p2 *Poly) {
-	for i, qi := range r.Modulus {
-		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
-		mredParams := r.MredParams[i]
-		for j := uint64(0); j < r.N; j = j + 8 {
+	r.InvMFormLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
 
-			x := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p2tmp[j]))
+// InvMFormLvl switches back p1 from the Montgomery domain to the conventional domain for the moduli from q_0 up to
+// q_level and writes the result on p2.
+func (r *Ring) InvMFormLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("InvMFormLvl", r, level, p1, p2)
+		checkAliasing("InvMFormLvl", r.N, level, true, p2, p1)
+	}
 
-			z[0] = InvMForm(x[0], qi, mredParams)
-			z[1] = InvMForm(x[1], qi, mredParams)
-			z[2] = InvMForm(x[2], qi, mredParams)
-			z[3] = InvMForm(x[3], qi, mredParams)
-			z[4] = InvMForm(x[4], qi, mredParams)
-			z[5] = InvMForm(x[5], qi, mredParams)
-			z[6] = InvMForm(x[6], qi, mredParams)
-			z[7] = InvMForm(x[7], qi, mredParams)
-		}
+	for i := uint64(0); i < level+1; i++ {
+		invMFormVec(p1.Coeffs[i][:r.N], p2.Coeffs[i][:r.N], r.Modulus[i], r.MredParams[i])
 	}
 }
 
@@ -1185,6 +1346,7 @@ func (r *Ring) MulByVectorMontgomery(p1 *Poly, vector []uint64, p2 *Poly) {
 }
 
 // MulByVectorMontgomeryAndAddNoMod multiplies p1 by a vector of uint64 coefficients and adds the result on p2 without modular reduction.
+// The product is in [0, q), thus the range of p2 is increased by q.
 func (r *Ring) MulByVectorMontgomeryAndAddNoMod(p1 *Poly, vector []uint64, p2 *Poly) {
 	for i, qi := range r.Modulus {
 		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
@@ -1210,17 +1372,30 @@ func (r *Ring) MulByVectorMontgomeryAndAddNoMod(p1 *Poly, vector []uint64, p2 *P
 // BitReverse applies a bit reverse permutation on the coefficients of p1 and writes the result on p2.
 // In can safely be used for in-place permutation.
 func (r *Ring) BitReverse(p1, p2 *Poly) {
+	r.BitReverseLvl(uint64(len(r.Modulus)-1), p1, p2)
+}
+
+// BitReverseLvl applies a bit reverse permutation on the coefficients of p1 for the moduli q_0 up to q_level
+// and writes the result on p2. In can safely be used for in-place permutation.
+// Since the bit reverse permutation is an involution, it converts from natural to bit-reversed
+// order as well as from bit-reversed to natural order.
+func (r *Ring) BitReverseLvl(level uint64, p1, p2 *Poly) {
+	if debugChecks {
+		checkLevel("BitReverseLvl", r, level, p1, p2)
+		checkAliasing("BitReverseLvl", r.N, level, true, p2, p1)
+	}
+
 	bitLenOfN := uint64(bits.Len64(r.N) - 1)
 
 	if p1 != p2 {
-		for i := range r.Modulus {
+		for i := uint64(0); i < level+1; i++ {
 			p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
 			for j := uint64(0); j < r.N; j++ {
 				p2tmp[utils.BitReverse64(j, bitLenOfN)] = p1tmp[j]
 			}
 		}
 	} else { // In place in case p1 = p2
-		for x := range r.Modulus {
+		for x := uint64(0); x < level+1; x++ {
 			p2tmp := p2.Coeffs[x]
 			for i := uint64(0); i < r.N; i++ {
 				j := utils.BitReverse64(i, bitLenOfN)
@@ -3,9 +3,16 @@ package ring
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"math/bits"
 )
 
+var (
+	_ io.WriterTo   = (*Poly)(nil)
+	_ io.ReaderFrom = (*Poly)(nil)
+)
+
 // Poly is the structure that contains the coefficients of a polynomial.
 type Poly struct {
 	Coeffs [][]uint64 // Coefficients in CRT representation
@@ -132,13 +139,18 @@ func WriteCoeffsTo(pointer, N, numberModuli uint64, coeffs [][]uint64, data []by
 	return pointer, nil
 }
 
-// WriteTo writes the given poly to the data array.
+// Encode writes the given poly to the data array.
 // It returns the number of written bytes, and the corresponding error, if it occurred.
-func (pol *Poly) WriteTo(data []byte) (uint64, error) {
+// It was named WriteTo before WriteTo implemented io.WriterTo: the calls to WriteTo on a byte slice must use Encode.
+func (pol *Poly) Encode(data []byte) (uint64, error) {
 
 	N := uint64(pol.GetDegree())
 	numberModuli := uint64(pol.GetLenModuli())
 
+	if numberModuli > MaxModuliCount {
+		return 0, errors.New("cannot write ring.Poly: more than 255 moduli")
+	}
+
 	if uint64(len(data)) < pol.GetDataLen(true) {
 		// The data is not big enough to write all the information
 		return 0, errors.New("Data array is too small to write ring.Poly")
@@ -151,6 +163,131 @@ func (pol *Poly) WriteTo(data []byte) (uint64, error) {
 	return cnt, err
 }
 
+// WriteTo writes the polynomial on w, in the format of MarshalBinary, and returns the number of written bytes.
+// The coefficients are written one modulus at a time, so that the polynomial is never entirely copied in memory.
+// It implements io.WriterTo.
+func (pol *Poly) WriteTo(w io.Writer) (n int64, err error) {
+	return pol.WriteToLvl(uint64(pol.GetLenModuli()-1), w)
+}
+
+// WriteToLvl is WriteTo for the moduli from q_0 up to q_level: the polynomial is written as a polynomial at this
+// level, whose level+1 moduli are the first ones of the target polynomial.
+func (pol *Poly) WriteToLvl(level uint64, w io.Writer) (n int64, err error) {
+
+	N := uint64(pol.GetDegree())
+	numberModuli := level + 1
+
+	if numberModuli > uint64(pol.GetLenModuli()) {
+		return 0, fmt.Errorf("cannot write ring.Poly: level %d is larger than the level of the polynomial", level)
+	}
+
+	if numberModuli > MaxModuliCount {
+		return 0, errors.New("cannot write ring.Poly: more than 255 moduli")
+	}
+
+	var cnt int
+
+	if cnt, err = w.Write([]byte{uint8(bits.Len64(N) - 1), uint8(numberModuli)}); err != nil {
+		return int64(cnt), err
+	}
+
+	n = int64(cnt)
+
+	buff := make([]byte, N<<3)
+
+	for i := uint64(0); i < numberModuli; i++ {
+
+		coeffs := pol.Coeffs[i]
+		for j := uint64(0); j < N; j++ {
+			binary.BigEndian.PutUint64(buff[j<<3:], coeffs[j])
+		}
+
+		cnt, err = w.Write(buff)
+		n += int64(cnt)
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// polyReadChunk is the number of coefficients that ReadFrom reads at once.
+const polyReadChunk = 1 << 10
+
+// ReadFrom reads on the target polynomial a polynomial written by WriteTo, WriteToLvl or MarshalBinary from r, and
+// returns the number of read bytes. The coefficients are read by chunks of fixed size, in the coefficients of the
+// target polynomial if they have the degree of the read polynomial, which are otherwise allocated as they are read,
+// so that the degree in the header of a short or malicious stream does not cause a large allocation. The target
+// polynomial then has the moduli of the read polynomial. It implements io.ReaderFrom.
+func (pol *Poly) ReadFrom(r io.Reader) (n int64, err error) {
+
+	var cnt int
+
+	header := make([]byte, 2)
+	if cnt, err = io.ReadFull(r, header); err != nil {
+		return int64(cnt), err
+	}
+
+	n = int64(cnt)
+
+	if header[0] > MaxLogN {
+		return n, fmt.Errorf("cannot read ring.Poly: invalid degree 2^%d", header[0])
+	}
+
+	N := uint64(1) << header[0]
+	numberModuli := uint64(header[1])
+
+	if uint64(cap(pol.Coeffs)) < numberModuli {
+		pol.Coeffs = append(pol.Coeffs[:cap(pol.Coeffs)], make([][]uint64, numberModuli-uint64(cap(pol.Coeffs)))...)
+	}
+
+	pol.Coeffs = pol.Coeffs[:numberModuli]
+
+	chunk := uint64(polyReadChunk)
+	if N < chunk {
+		chunk = N
+	}
+
+	buff := make([]byte, chunk<<3)
+
+	for i := uint64(0); i < numberModuli; i++ {
+
+		coeffs := pol.Coeffs[i]
+		inPlace := uint64(len(coeffs)) == N
+		if !inPlace {
+			coeffs = nil
+		}
+
+		for j := uint64(0); j < N; j += chunk {
+
+			cnt, err = io.ReadFull(r, buff)
+			n += int64(cnt)
+
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return n, err
+			}
+
+			for k := uint64(0); k < chunk; k++ {
+				c := binary.BigEndian.Uint64(buff[k<<3:])
+				if inPlace {
+					coeffs[j+k] = c
+				} else {
+					coeffs = append(coeffs, c)
+				}
+			}
+		}
+
+		pol.Coeffs[i] = coeffs
+	}
+
+	return n, nil
+}
+
 // WriteTo32 writes the given poly to the data array.
 // It returns the number of written bytes, and the corresponding error, if it occurred.
 func (pol *Poly) WriteTo32(data []byte) (uint64, error) {
@@ -158,6 +295,10 @@ func (pol *Poly) WriteTo32(data []byte) (uint64, error) {
 	N := uint64(pol.GetDegree())
 	numberModuli := uint64(pol.GetLenModuli())
 
+	if numberModuli > MaxModuliCount {
+		return 0, errors.New("cannot write ring.Poly: more than 255 moduli")
+	}
+
 	if uint64(len(data)) < pol.GetDataLen32(true) {
 		//the data is not big enough to write all the information
 		return 0, errors.New("Data array is too small to write ring.Poly")
@@ -244,13 +385,17 @@ func DecodeCoeffsNew(pointer, N, numberModuli uint64, coeffs [][]uint64, data []
 // MarshalBinary encodes the target polynomial on a slice of bytes.
 func (pol *Poly) MarshalBinary() (data []byte, err error) {
 	data = make([]byte, pol.GetDataLen(true))
-	_, err = pol.WriteTo(data)
+	_, err = pol.Encode(data)
 	return
 }
 
 // UnmarshalBinary decodes a slice of byte on the target polynomial.
 func (pol *Poly) UnmarshalBinary(data []byte) (err error) {
 
+	if len(data) < 2 || data[0] > MaxLogN {
+		return errors.New("error: invalid polynomial encoding")
+	}
+
 	N := uint64(1 << data[0])
 	numberModulies := uint64(data[1])
 	pointer := uint64(2)
@@ -0,0 +1,231 @@
+package ring
+
+import (
+	"fmt"
+)
+
+// SparsePoly is a polynomial in the coefficient domain with few non-zero coefficients, such as a monomial or a mask of
+// low Hamming weight, stored as the list of the degrees of these coefficients and of their values. Multiplying a Poly
+// by a SparsePoly costs O(hN) operations per modulus for h non-zero coefficients, against O(N log N) with the NTT,
+// and does not require to switch the Poly to the NTT domain.
+type SparsePoly struct {
+	Index  []uint64   // Degrees of the non-zero coefficients, in increasing order
+	Coeffs [][]uint64 // Coeffs[i][k] is the coefficient of degree Index[k] modulo the i-th modulus
+}
+
+// NewSparsePoly returns the SparsePoly of the Ring with the coefficients values of degrees index, for all the moduli
+// of the Ring. The values are signed, e.g. -1 for a negated monomial, and the degrees must be smaller than N and in
+// increasing order.
+func (r *Ring) NewSparsePoly(index []uint64, values []int64) (sp *SparsePoly) {
+
+	if len(index) != len(values) {
+		panic("cannot NewSparsePoly: index and values must have the same length")
+	}
+
+	r.checkSparseIndex("NewSparsePoly", index)
+
+	sp = new(SparsePoly)
+	sp.Index = append([]uint64{}, index...)
+	sp.Coeffs = make([][]uint64, len(r.Modulus))
+
+	for i, qi := range r.Modulus {
+		sp.Coeffs[i] = make([]uint64, len(values))
+		for k, v := range values {
+			if v < 0 {
+				sp.Coeffs[i][k] = CRed(qi-BRedAdd(uint64(-v), qi, r.BredParams[i]), qi)
+			} else {
+				sp.Coeffs[i][k] = BRedAdd(uint64(v), qi, r.BredParams[i])
+			}
+		}
+	}
+
+	return
+}
+
+// NewSparsePolyFromPolyLvl returns the SparsePoly of the coefficients of p, which must be in the coefficient domain,
+// that are non-zero for one of the moduli from q_0 up to q_level.
+func (r *Ring) NewSparsePolyFromPolyLvl(level uint64, p *Poly) (sp *SparsePoly) {
+
+	if debugChecks {
+		checkLevel("NewSparsePolyFromPolyLvl", r, level, p)
+	}
+
+	sp = new(SparsePoly)
+	sp.Coeffs = make([][]uint64, level+1)
+
+	for j := uint64(0); j < r.N; j++ {
+		for i := uint64(0); i < level+1; i++ {
+			if p.Coeffs[i][j] != 0 {
+				sp.Index = append(sp.Index, j)
+				break
+			}
+		}
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		sp.Coeffs[i] = make([]uint64, len(sp.Index))
+		for k, j := range sp.Index {
+			sp.Coeffs[i][k] = p.Coeffs[i][j]
+		}
+	}
+
+	return
+}
+
+// HammingWeight returns the number of non-zero coefficients of the SparsePoly.
+func (sp *SparsePoly) HammingWeight() int {
+	return len(sp.Index)
+}
+
+// Level returns the level of the SparsePoly, i.e. its number of moduli minus one.
+func (sp *SparsePoly) Level() uint64 {
+	return uint64(len(sp.Coeffs) - 1)
+}
+
+// SparsePolyToPolyLvl writes the SparsePoly sp on p for the moduli from q_0 up to q_level.
+func (r *Ring) SparsePolyToPolyLvl(level uint64, sp *SparsePoly, p *Poly) {
+
+	if debugChecks {
+		checkLevel("SparsePolyToPolyLvl", r, level, p)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		ptmp := p.Coeffs[i][:r.N]
+		for j := range ptmp {
+			ptmp[j] = 0
+		}
+		for k, j := range sp.Index {
+			ptmp[j] = sp.Coeffs[i][k]
+		}
+	}
+}
+
+// AddSparse adds the SparsePoly sp to p1 and writes the result on p2. p2 can alias p1.
+func (r *Ring) AddSparse(sp *SparsePoly, p1, p2 *Poly) {
+	r.AddSparseLvl(uint64(len(r.Modulus)-1), sp, p1, p2)
+}
+
+// AddSparseLvl adds the SparsePoly sp to p1 for the moduli from q_0 up to q_level and writes the result on p2. p2 can
+// alias p1.
+func (r *Ring) AddSparseLvl(level uint64, sp *SparsePoly, p1, p2 *Poly) {
+
+	if debugChecks {
+		checkLevel("AddSparseLvl", r, level, p1, p2)
+		checkAliasing("AddSparseLvl", r.N, level, true, p2, p1)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi := r.Modulus[i]
+		p1tmp, p2tmp := p1.Coeffs[i], p2.Coeffs[i]
+
+		if &p1tmp[0] != &p2tmp[0] {
+			copy(p2tmp[:r.N], p1tmp[:r.N])
+		}
+
+		for k, j := range sp.Index {
+			p2tmp[j] = CRed(p2tmp[j]+sp.Coeffs[i][k], qi)
+		}
+	}
+}
+
+// MulSparse multiplies p1 by the SparsePoly sp and writes the result on p2. p1 must be in the coefficient domain, and
+// p2 must not alias p1.
+func (r *Ring) MulSparse(sp *SparsePoly, p1, p2 *Poly) {
+	r.MulSparseLvl(uint64(len(r.Modulus)-1), sp, p1, p2)
+}
+
+// MulSparseLvl multiplies p1 by the SparsePoly sp for the moduli from q_0 up to q_level and writes the result on p2.
+// p1 must be in the coefficient domain, and p2 must not alias p1.
+func (r *Ring) MulSparseLvl(level uint64, sp *SparsePoly, p1, p2 *Poly) {
+
+	if debugChecks {
+		checkLevel("MulSparseLvl", r, level, p1, p2)
+		checkAliasing("MulSparseLvl", r.N, level, false, p2, p1)
+	}
+
+	for i := uint64(0); i < level+1; i++ {
+		p2tmp := p2.Coeffs[i][:r.N]
+		for j := range p2tmp {
+			p2tmp[j] = 0
+		}
+	}
+
+	r.mulSparseAndAddLvl(level, sp, p1, p2)
+}
+
+// MulSparseAndAdd multiplies p1 by the SparsePoly sp and adds the result to p2. p1 must be in the coefficient domain,
+// and p2 must not alias p1.
+func (r *Ring) MulSparseAndAdd(sp *SparsePoly, p1, p2 *Poly) {
+	r.MulSparseAndAddLvl(uint64(len(r.Modulus)-1), sp, p1, p2)
+}
+
+// MulSparseAndAddLvl multiplies p1 by the SparsePoly sp for the moduli from q_0 up to q_level and adds the result to
+// p2. p1 must be in the coefficient domain, and p2 must not alias p1.
+func (r *Ring) MulSparseAndAddLvl(level uint64, sp *SparsePoly, p1, p2 *Poly) {
+
+	if debugChecks {
+		checkLevel("MulSparseAndAddLvl", r, level, p1, p2)
+		checkAliasing("MulSparseAndAddLvl", r.N, level, false, p2, p1)
+	}
+
+	r.mulSparseAndAddLvl(level, sp, p1, p2)
+}
+
+// mulSparseAndAddLvl adds to p2 the product of p1 by each monomial of sp: the coefficient of degree j of p1 goes to
+// the degree j+d for j+d < N and, negated, to the degree j+d-N otherwise. The products by 1 and -1, which are the
+// coefficients of monomials and masks, are computed without multiplication.
+func (r *Ring) mulSparseAndAddLvl(level uint64, sp *SparsePoly, p1, p2 *Poly) {
+
+	N := r.N
+
+	for i := uint64(0); i < level+1; i++ {
+
+		qi := r.Modulus[i]
+		bredParams := r.BredParams[i]
+		p1tmp, p2tmp := p1.Coeffs[i][:N], p2.Coeffs[i][:N]
+
+		for k, d := range sp.Index {
+
+			c := sp.Coeffs[i][k]
+
+			// Coefficients of p1 which do and do not wrap around X^N = -1
+			low, high := p1tmp[:N-d], p1tmp[N-d:]
+			outLow, outHigh := p2tmp[d:], p2tmp[:d]
+
+			switch c {
+			case 0:
+			case 1:
+				for j, x := range low {
+					outLow[j] = CRed(outLow[j]+x, qi)
+				}
+				for j, x := range high {
+					outHigh[j] = CRed(outHigh[j]+qi-x, qi)
+				}
+			case qi - 1:
+				for j, x := range low {
+					outLow[j] = CRed(outLow[j]+qi-x, qi)
+				}
+				for j, x := range high {
+					outHigh[j] = CRed(outHigh[j]+x, qi)
+				}
+			default:
+				for j, x := range low {
+					outLow[j] = CRed(outLow[j]+BRed(x, c, qi, bredParams), qi)
+				}
+				for j, x := range high {
+					outHigh[j] = CRed(outHigh[j]+qi-BRed(x, c, qi, bredParams), qi)
+				}
+			}
+		}
+	}
+}
+
+// checkSparseIndex panics if the degrees of index are not smaller than N and in increasing order.
+func (r *Ring) checkSparseIndex(op string, index []uint64) {
+	for k, j := range index {
+		if j >= r.N || (k > 0 && j <= index[k-1]) {
+			panic(fmt.Sprintf("cannot %s: the degrees must be smaller than %d and in increasing order", op, r.N))
+		}
+	}
+}
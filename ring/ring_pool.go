@@ -0,0 +1,47 @@
+package ring
+
+import (
+	"sync"
+)
+
+// newPolyPools returns one pool of polynomials of the Ring per level, whose polynomials are allocated on demand.
+func (r *Ring) newPolyPools() []sync.Pool {
+	pools := make([]sync.Pool, len(r.Modulus))
+	for i := range pools {
+		level := uint64(i)
+		pools[i].New = func() interface{} {
+			return r.NewPolyLvl(level)
+		}
+	}
+	return pools
+}
+
+// GetPoly returns a polynomial of the Ring from its pool of polynomials, allocating it if the pool is empty. Unlike
+// NewPoly, the coefficients of the returned polynomial are arbitrary. The polynomial can be given back to the pool
+// with PutPoly once it is not used anymore, so that the temporary polynomials of hot loops are recycled instead of
+// being garbage collected. The pools are safe for concurrent use.
+func (r *Ring) GetPoly() *Poly {
+	return r.GetPolyLvl(uint64(len(r.Modulus) - 1))
+}
+
+// GetPolyLvl is GetPoly for a polynomial with the moduli from q_0 up to q_level only, taken from the pool of the level.
+func (r *Ring) GetPolyLvl(level uint64) *Poly {
+	return r.polyPools[level].Get().(*Poly)
+}
+
+// PutPoly gives p back to the pool of the Ring of its level, after which p must not be used anymore. Polynomials which
+// are not of the degree of the Ring or have more moduli than the Ring are discarded.
+func (r *Ring) PutPoly(p *Poly) {
+
+	if p == nil || len(p.Coeffs) == 0 || len(p.Coeffs) > len(r.polyPools) {
+		return
+	}
+
+	for i := range p.Coeffs {
+		if uint64(len(p.Coeffs[i])) != r.N {
+			return
+		}
+	}
+
+	r.polyPools[len(p.Coeffs)-1].Put(p)
+}
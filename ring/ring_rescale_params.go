@@ -0,0 +1,114 @@
+package ring
+
+import (
+	"math"
+	"math/big"
+)
+
+// GenRescaleParams returns the constants used to divide a polynomial by the last modulus of its level (see DivRoundByLastModulus),
+// for each level of the modulus chain moduli: params[j-1][i] = moduli[j]^-1 mod moduli[i] in Montgomery form, for 0 <= i < j.
+func GenRescaleParams(moduli []uint64) (params [][]uint64) {
+	if len(moduli) == 0 {
+		return [][]uint64{}
+	}
+	return ExtendRescaleParams(make([][]uint64, 0, len(moduli)-1), moduli)
+}
+
+// ExtendRescaleParams returns the rescaling constants of the modulus chain moduli, given the constants params of a prefix of
+// this chain, as returned by GenRescaleParams. Only the constants of the levels added to the prefix are computed. The constants
+// of a prefix of the chain are conversely obtained by truncating the ones of the full chain: params[:len(prefix)-1].
+func ExtendRescaleParams(params [][]uint64, moduli []uint64) [][]uint64 {
+
+	if len(moduli) != 0 && len(params) > len(moduli)-1 {
+		panic("cannot ExtendRescaleParams: params have more levels than the modulus chain")
+	}
+
+	bredParams := make([][]uint64, len(moduli))
+	for i, qi := range moduli {
+		bredParams[i] = BRedParams(qi)
+	}
+
+	for j := len(params) + 1; j < len(moduli); j++ {
+
+		row := make([]uint64, j)
+
+		for i := 0; i < j; i++ {
+			row[i] = MForm(ModExp(moduli[j], moduli[i]-2, moduli[i]), moduli[i], bredParams[i])
+		}
+
+		params = append(params, row)
+	}
+
+	return params
+}
+
+// GenRescaleCorrectionParams returns the constants used to divide a polynomial by the last modulus of its level and to
+// multiply it by a correction constant in a single pass (see DivRoundByLastModulusAndMulLvl), for each level of the
+// modulus chain moduli: params[j-1][i] = c * moduli[j]^-1 mod moduli[i] in Montgomery form, for 0 <= i < j, where c is
+// correction[j-1] rounded to the closest integer. The corrections should thus be large enough for the rounding error to
+// be negligible, e.g. a ratio between two scales multiplied by a power of two that is later divided out.
+func GenRescaleCorrectionParams(moduli []uint64, correction []float64) (params [][]uint64) {
+
+	if len(moduli) == 0 {
+		return [][]uint64{}
+	}
+
+	if len(correction) != len(moduli)-1 {
+		panic("cannot GenRescaleCorrectionParams: there must be one correction per level of the modulus chain")
+	}
+
+	params = GenRescaleParams(moduli)
+
+	bredParams := make([][]uint64, len(moduli))
+	for i, qi := range moduli {
+		bredParams[i] = BRedParams(qi)
+	}
+
+	c := new(big.Int)
+	tmp := new(big.Int)
+
+	for j := range params {
+
+		if math.IsNaN(correction[j]) || math.IsInf(correction[j], 0) {
+			panic("cannot GenRescaleCorrectionParams: the corrections must be finite")
+		}
+
+		new(big.Float).SetFloat64(math.Round(correction[j])).Int(c)
+
+		for i, qi := range moduli[:j+1] {
+			ci := tmp.Mod(c, NewUint(qi)).Uint64()
+			params[j][i] = BRed(params[j][i], ci, qi, bredParams[i])
+		}
+	}
+
+	return params
+}
+
+// GenModDownParams returns the constants used to divide a polynomial in the basis QP by P (see FastBasisExtender.ModDownPQ):
+// params[i] = P^-1 mod Q[i] in Montgomery form, where P is the product of the moduli of P.
+func GenModDownParams(Q, P []uint64) (params []uint64) {
+	return ExtendModDownParams(make([]uint64, 0, len(Q)), Q, P)
+}
+
+// ExtendModDownParams returns the constants of GenModDownParams(Q, P), given the constants params of GenModDownParams(Q[:len(params)], P).
+// Only the constants of the moduli added to Q are computed. If P changes, the constants must be generated again with GenModDownParams.
+func ExtendModDownParams(params []uint64, Q, P []uint64) []uint64 {
+
+	if len(params) > len(Q) {
+		panic("cannot ExtendModDownParams: params have more moduli than Q")
+	}
+
+	PBig := NewUint(1)
+	for _, pj := range P {
+		PBig.Mul(PBig, NewUint(pj))
+	}
+
+	tmp := new(big.Int)
+
+	for _, qi := range Q[len(params):] {
+		c := tmp.Mod(PBig, NewUint(qi)).Uint64()
+		params = append(params, MForm(ModExp(c, qi-2, qi), qi, BRedParams(qi)))
+	}
+
+	return params
+}
@@ -0,0 +1,33 @@
+package ring
+
+import (
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// Sampler is the interface implemented by the polynomial samplers of the ring package: the UniformSampler, the
+// TernarySampler, the GaussianSampler and the ConstantTimeGaussianSampler. The methods ending in Lvl only sample the
+// moduli from q_0 up to q_level, and the input polynomial must have at least level+1 moduli.
+type Sampler interface {
+	// Read samples a polynomial at the maximum level into pol.
+	Read(pol *Poly)
+	// ReadNew samples a new polynomial at the maximum level.
+	ReadNew() (pol *Poly)
+	// ReadLvl samples a polynomial at the given level into pol.
+	ReadLvl(level uint64, pol *Poly)
+	// ReadAndAdd samples a polynomial at the maximum level and adds it on pol.
+	ReadAndAdd(pol *Poly)
+	// ReadAndAddLvl samples a polynomial at the given level and adds it on pol.
+	ReadAndAddLvl(level uint64, pol *Poly)
+}
+
+var (
+	_ Sampler = (*UniformSampler)(nil)
+	_ Sampler = (*TernarySampler)(nil)
+	_ Sampler = (*GaussianSampler)(nil)
+	_ Sampler = (*ConstantTimeGaussianSampler)(nil)
+)
+
+type baseSampler struct {
+	prng     utils.PRNG
+	baseRing *Ring
+}
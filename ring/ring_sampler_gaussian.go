@@ -14,6 +14,11 @@ type GaussianSampler struct {
 	ptr           uint64
 	sigma         float64
 	bound         uint64
+
+	// Buffers of ReadAndAddNTTLvl, allocated on its first call
+	coeffsInt []uint64
+	signs     []uint64
+	buffNTT   []uint64
 }
 
 // NewGaussianSampler creates a new instance of GaussianSampler from a PRNG, a ring definition and the truncated
@@ -46,7 +51,6 @@ func (gaussianSampler *GaussianSampler) ReadNew() (pol *Poly) {
 // ReadLvl samples a polynomial at the given level into pol.
 func (gaussianSampler *GaussianSampler) ReadLvl(level uint64, pol *Poly) {
 
-	var coeffFlo float64
 	var coeffInt uint64
 	var sign uint64
 
@@ -54,13 +58,7 @@ func (gaussianSampler *GaussianSampler) ReadLvl(level uint64, pol *Poly) {
 
 	for i := uint64(0); i < gaussianSampler.baseRing.N; i++ {
 
-		for {
-			coeffFlo, sign = gaussianSampler.normFloat64()
-
-			if coeffInt = uint64(coeffFlo * gaussianSampler.sigma); coeffInt <= gaussianSampler.bound {
-				break
-			}
-		}
+		coeffInt, sign = gaussianSampler.sample()
 
 		for j, qi := range gaussianSampler.baseRing.Modulus[:level+1] {
 			pol.Coeffs[j][i] = (coeffInt * sign) | (qi-coeffInt)*(sign^1)
@@ -77,7 +75,6 @@ func (gaussianSampler *GaussianSampler) ReadAndAdd(pol *Poly) {
 // ReadAndAddLvl samples and adds a polynomial at the given level directly into pol. pol must be at the given level.
 func (gaussianSampler *GaussianSampler) ReadAndAddLvl(level uint64, pol *Poly) {
 
-	var coeffFlo float64
 	var coeffInt uint64
 	var sign uint64
 
@@ -85,13 +82,7 @@ func (gaussianSampler *GaussianSampler) ReadAndAddLvl(level uint64, pol *Poly) {
 
 	for i := uint64(0); i < gaussianSampler.baseRing.N; i++ {
 
-		for {
-			coeffFlo, sign = gaussianSampler.normFloat64()
-
-			if coeffInt = uint64(coeffFlo * gaussianSampler.sigma); coeffInt <= gaussianSampler.bound {
-				break
-			}
-		}
+		coeffInt, sign = gaussianSampler.sample()
 
 		for j, qi := range gaussianSampler.baseRing.Modulus[:level+1] {
 			pol.Coeffs[j][i] = CRed(pol.Coeffs[j][i]+((coeffInt*sign)|(qi-coeffInt)*(sign^1)), qi)
@@ -99,6 +90,62 @@ func (gaussianSampler *GaussianSampler) ReadAndAddLvl(level uint64, pol *Poly) {
 	}
 }
 
+// ReadAndAddNTT adds on pol, in the NTT domain, a truncated Gaussian polynomial at the maximum level.
+func (gaussianSampler *GaussianSampler) ReadAndAddNTT(pol *Poly) {
+	gaussianSampler.ReadAndAddNTTLvl(uint64(len(gaussianSampler.baseRing.Modulus)-1), pol)
+}
+
+// ReadAndAddNTTLvl samples a polynomial at the given level and adds its NTT directly into pol, which must be in the
+// NTT domain. It is equivalent to ReadLvl followed by NTTLvl and AddLvl, but the NTT is computed modulus per modulus
+// on a single buffer of N coefficients instead of on a temporary polynomial. The sampled polynomial is the same as
+// the one ReadLvl would have returned for the same PRNG state.
+func (gaussianSampler *GaussianSampler) ReadAndAddNTTLvl(level uint64, pol *Poly) {
+
+	r := gaussianSampler.baseRing
+
+	if gaussianSampler.coeffsInt == nil {
+		gaussianSampler.coeffsInt = make([]uint64, r.N)
+		gaussianSampler.signs = make([]uint64, r.N)
+		gaussianSampler.buffNTT = make([]uint64, r.N)
+	}
+
+	coeffsInt, signs, buff := gaussianSampler.coeffsInt, gaussianSampler.signs, gaussianSampler.buffNTT
+
+	gaussianSampler.prng.Clock(gaussianSampler.randomBufferN)
+
+	for i := uint64(0); i < r.N; i++ {
+		coeffsInt[i], signs[i] = gaussianSampler.sample()
+	}
+
+	for j, qi := range r.Modulus[:level+1] {
+
+		for i := uint64(0); i < r.N; i++ {
+			buff[i] = (coeffsInt[i] * signs[i]) | (qi-coeffsInt[i])*(signs[i]^1)
+		}
+
+		NTTBlocked(buff, buff, r.N, r.NttPsi[j], qi, r.MredParams[j], r.BredParams[j], r.nttBlockSize)
+
+		p1tmp := pol.Coeffs[j]
+		for i := uint64(0); i < r.N; i++ {
+			p1tmp[i] = CRed(p1tmp[i]+buff[i], qi)
+		}
+	}
+}
+
+// sample returns the absolute value and the sign (1 for positive) of a sample of the truncated Gaussian.
+func (gaussianSampler *GaussianSampler) sample() (coeffInt, sign uint64) {
+
+	var coeffFlo float64
+
+	for {
+		coeffFlo, sign = gaussianSampler.normFloat64()
+
+		if coeffInt = uint64(coeffFlo * gaussianSampler.sigma); coeffInt <= gaussianSampler.bound {
+			return
+		}
+	}
+}
+
 // randFloat64 returns a uniform float64 value between 0 and 1.
 func randFloat64(randomBytes []byte) float64 {
 	return float64(binary.BigEndian.Uint64(randomBytes)&0x1fffffffffffff) / float64(0x1fffffffffffff)
@@ -0,0 +1,265 @@
+package ring
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// cdtMaxSigma is the largest standard deviation sampled directly from a cumulative distribution table. Larger
+// standard deviations are sampled as the combination x1 + cdtConvolutionFactor * x2 of a sample x1 of standard
+// deviation cdtBaseSigma and of a sample x2 of smaller standard deviation, sampled recursively.
+const cdtMaxSigma = 32
+
+const cdtBaseSigma = 24
+
+// cdtConvolutionFactor is small enough with respect to cdtBaseSigma for the combination to be statistically close to
+// a discrete Gaussian.
+const cdtConvolutionFactor = 4
+
+// cdtTailCut is the number of standard deviations after which the tables are truncated, as the mass of the tail is
+// then negligible.
+const cdtTailCut = 12
+
+// ConstantTimeGaussianSampler keeps the state of a truncated discrete Gaussian polynomial sampler whose running time
+// does not depend on the sampled values. The samples are computed by a linear scan of a cumulative distribution table
+// (CDT) without branching, instead of the rejection of the GaussianSampler. Unlike the GaussianSampler, the standard
+// deviation and the bound can be given at each call, which allows sampling large smudging noise, also wider than the
+// moduli.
+//
+// A standard deviation up to 32 is sampled exactly from the discrete Gaussian truncated at the bound. A larger one is
+// sampled recursively by convolution of smaller ones, and the sample is clamped in constant time to the bound, which is
+// statistically close to the truncated distribution as long as the bound is a few standard deviations.
+type ConstantTimeGaussianSampler struct {
+	baseSampler
+	randomBuffer []byte
+	ptr          uint64
+	sigma        float64
+	bound        uint64
+
+	// baseTable is the table of the base samples of the combinations. table is the innermost table of the last call,
+	// for the innermost parameters params: only the last one is kept, such that the tables of per-call parameters do not
+	// accumulate.
+	baseTable []uint64
+	params    cdtParameters
+	table     []uint64
+}
+
+type cdtParameters struct {
+	sigma float64
+	bound uint64
+}
+
+// NewConstantTimeGaussianSampler creates a new instance of ConstantTimeGaussianSampler from a PRNG, a ring definition
+// and the default truncated Gaussian distribution parameters used by Read and ReadAndAdd. Sigma is the standard
+// deviation and bound is the maximum coefficient norm in absolute value.
+func NewConstantTimeGaussianSampler(prng utils.PRNG, baseRing *Ring, sigma float64, bound uint64) *ConstantTimeGaussianSampler {
+
+	if sigma <= 0 {
+		panic("cannot NewConstantTimeGaussianSampler: sigma must be positive")
+	}
+
+	gaussianSampler := new(ConstantTimeGaussianSampler)
+	gaussianSampler.baseRing = baseRing
+	gaussianSampler.prng = prng
+	gaussianSampler.randomBuffer = make([]byte, 8*baseRing.N)
+	gaussianSampler.ptr = uint64(len(gaussianSampler.randomBuffer))
+	gaussianSampler.sigma = sigma
+	gaussianSampler.bound = bound
+	gaussianSampler.baseTable = newCDT(cdtBaseSigma, math.MaxUint64)
+	return gaussianSampler
+}
+
+// Read samples a polynomial at the maximum level into pol.
+func (gaussianSampler *ConstantTimeGaussianSampler) Read(pol *Poly) {
+	gaussianSampler.ReadLvl(uint64(len(gaussianSampler.baseRing.Modulus)-1), pol)
+}
+
+// ReadNew samples a new truncated Gaussian polynomial at the maximum level.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadNew() (pol *Poly) {
+	pol = gaussianSampler.baseRing.NewPoly()
+	gaussianSampler.Read(pol)
+	return pol
+}
+
+// ReadLvl samples a polynomial at the given level into pol.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadLvl(level uint64, pol *Poly) {
+	gaussianSampler.ReadLvlWithParameters(level, gaussianSampler.sigma, gaussianSampler.bound, pol)
+}
+
+// ReadAndAdd adds on pol a truncated Gaussian polynomial at the maximum level.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadAndAdd(pol *Poly) {
+	gaussianSampler.ReadAndAddLvl(uint64(len(gaussianSampler.baseRing.Modulus)-1), pol)
+}
+
+// ReadAndAddLvl samples and adds a polynomial at the given level directly into pol. pol must be at the given level.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadAndAddLvl(level uint64, pol *Poly) {
+	gaussianSampler.ReadAndAddLvlWithParameters(level, gaussianSampler.sigma, gaussianSampler.bound, pol)
+}
+
+// ReadLvlWithParameters samples a polynomial at the given level into pol, with standard deviation sigma within the
+// given bound instead of the default parameters of the sampler.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadLvlWithParameters(level uint64, sigma float64, bound uint64, pol *Poly) {
+	gaussianSampler.readLvl(level, sigma, bound, pol, false)
+}
+
+// ReadAndAddLvlWithParameters samples and adds a polynomial at the given level directly into pol, with standard
+// deviation sigma within the given bound instead of the default parameters of the sampler.
+func (gaussianSampler *ConstantTimeGaussianSampler) ReadAndAddLvlWithParameters(level uint64, sigma float64, bound uint64, pol *Poly) {
+	gaussianSampler.readLvl(level, sigma, bound, pol, true)
+}
+
+func (gaussianSampler *ConstantTimeGaussianSampler) readLvl(level uint64, sigma float64, bound uint64, pol *Poly, add bool) {
+
+	if sigma <= 0 {
+		panic("cannot sample: sigma must be positive")
+	}
+
+	if bound > 1<<61 {
+		panic("cannot sample: bound must be at most 2^61")
+	}
+
+	moduli := gaussianSampler.baseRing.Modulus[:level+1]
+	bredParams := gaussianSampler.baseRing.BredParams
+
+	baseTable, table, bounds := gaussianSampler.tablesFor(sigma, bound)
+
+	for i := uint64(0); i < gaussianSampler.baseRing.N; i++ {
+
+		coeff := gaussianSampler.sample(baseTable, table, bounds)
+
+		for j, qi := range moduli {
+			c := constantTimeReduce(coeff, qi, bredParams[j])
+			if add {
+				c = constantTimeCRed(pol.Coeffs[j][i]+c, qi)
+			}
+			pol.Coeffs[j][i] = c
+		}
+	}
+}
+
+// tablesFor returns the tables and the bounds with which sample draws the discrete Gaussian of standard deviation
+// sigma truncated at the bound: the table of the base samples x1, the table of the innermost sample, and the bound of
+// each combination from the outermost one. They are looked up once per call and not for each coefficient, and the
+// innermost table is only computed if the parameters differ from those of the previous call.
+//
+// The sample x2 of a combination is truncated at the bound of the combination divided by cdtConvolutionFactor, so that
+// |x1 + cdtConvolutionFactor * x2| <= 12 * cdtBaseSigma + bound, which does not overflow for bounds up to 2^61.
+func (gaussianSampler *ConstantTimeGaussianSampler) tablesFor(sigma float64, bound uint64) (baseTable, table []uint64, bounds []int64) {
+
+	for sigma > cdtMaxSigma {
+
+		bounds = append(bounds, int64(bound))
+
+		// sigma^2 = cdtBaseSigma^2 + cdtConvolutionFactor^2 * sigma'^2
+		sigma = math.Sqrt(sigma*sigma-cdtBaseSigma*cdtBaseSigma) / cdtConvolutionFactor
+		bound /= cdtConvolutionFactor
+	}
+
+	if len(bounds) > 0 {
+		baseTable = gaussianSampler.baseTable
+	}
+
+	if params := (cdtParameters{sigma, bound}); gaussianSampler.table == nil || gaussianSampler.params != params {
+		gaussianSampler.params = params
+		gaussianSampler.table = newCDT(sigma, bound)
+	}
+
+	return baseTable, gaussianSampler.table, bounds
+}
+
+// sample returns a sample of the discrete Gaussian whose tables and bounds are given by tablesFor: a sample of the
+// innermost table, combined from the innermost to the outermost bound as x1 + cdtConvolutionFactor * x with a sample
+// x1 of the base table, and clamped in constant time to the bound after each combination.
+func (gaussianSampler *ConstantTimeGaussianSampler) sample(baseTable, table []uint64, bounds []int64) int64 {
+
+	x := gaussianSampler.sampleCDT(table)
+
+	for i := len(bounds) - 1; i >= 0; i-- {
+
+		x = gaussianSampler.sampleCDT(baseTable) + cdtConvolutionFactor*x
+
+		// Clamps x to [-b, b] without branching
+		b := bounds[i]
+		above := (b - x) >> 63
+		x = (x &^ above) | (b & above)
+		below := (x + b) >> 63
+		x = (x &^ below) | (-b & below)
+	}
+
+	return x
+}
+
+// sampleCDT returns a sample of the distribution whose table is given, by comparing a uniform value in [0, 2^63) with
+// all its entries, and applies a uniform sign.
+func (gaussianSampler *ConstantTimeGaussianSampler) sampleCDT(table []uint64) int64 {
+
+	if gaussianSampler.ptr == uint64(len(gaussianSampler.randomBuffer)) {
+		gaussianSampler.prng.Clock(gaussianSampler.randomBuffer)
+		gaussianSampler.ptr = 0
+	}
+
+	r := binary.BigEndian.Uint64(gaussianSampler.randomBuffer[gaussianSampler.ptr : gaussianSampler.ptr+8])
+	gaussianSampler.ptr += 8
+
+	u, sign := r&0x7fffffffffffffff, r>>63
+
+	// x = #{k : u >= table[k]}, with u >= table[k] iff table[k] - u - 1 < 0 as both are smaller than 2^63
+	var x uint64
+	for _, t := range table {
+		x += (t - u - 1) >> 63
+	}
+
+	// (-1)^sign * x
+	mask := -sign
+	return int64((x ^ mask) + sign)
+}
+
+// newCDT returns the cumulative distribution table of the absolute value of the discrete Gaussian of standard deviation
+// sigma truncated at the bound.
+func newCDT(sigma float64, bound uint64) []uint64 {
+
+	if tail := uint64(math.Ceil(cdtTailCut * sigma)); bound > tail {
+		bound = tail
+	}
+
+	// Weights of |x| = k, 2 * exp(-k^2/(2 sigma^2)) for k > 0 as both signs are merged
+	weights := make([]float64, bound+1)
+	weights[0] = 1
+	var sum float64
+	for k := bound; k > 0; k-- {
+		weights[k] = 2 * math.Exp(-float64(k*k)/(2*sigma*sigma))
+		sum += weights[k]
+	}
+	sum++
+
+	// table[k] = 2^63 * P(|x| <= k), computed from the tail to keep its precision
+	table := make([]uint64, bound)
+	var tail float64
+	for k := bound; k > 0; k-- {
+		tail += weights[k]
+		table[k-1] = (1 << 63) - uint64(math.Round(tail/sum*(1<<63)))
+	}
+
+	return table
+}
+
+// constantTimeReduce returns coeff mod q in [0, q) for |coeff| < 2^62, without branching on coeff, given the Barrett
+// parameters of q.
+func constantTimeReduce(coeff int64, q uint64, bredParams []uint64) uint64 {
+
+	// |coeff| mod q
+	sign := uint64(coeff >> 63)
+	abs := (uint64(coeff) ^ sign) - sign
+	abs = constantTimeCRed(BRedAddConstant(abs, q, bredParams), q)
+
+	// q - |coeff| mod q if coeff is negative, where q is reduced to 0
+	return constantTimeCRed((abs&^sign)|((q-abs)&sign), q)
+}
+
+// constantTimeCRed returns a mod q for a in [0, 2q), without branching.
+func constantTimeCRed(a, q uint64) uint64 {
+	a -= q
+	return a + (q & uint64(int64(a)>>63))
+}
@@ -9,11 +9,6 @@ import (
 
 const precision = uint64(56)
 
-type baseSampler struct {
-	prng     utils.PRNG
-	baseRing *Ring
-}
-
 // TernarySampler keeps the state of a polynomial sampler in the ternary distribution.
 type TernarySampler struct {
 	baseSampler
@@ -21,7 +16,7 @@ type TernarySampler struct {
 	matrixValues [][3]uint64
 	p            float64
 	hw           uint64
-	sample       func(poly *Poly)
+	sample       func(level uint64, poly *Poly)
 }
 
 // NewTernarySampler creates a new instance of TernarySampler from a PRNG, the ring definition and the distribution
@@ -60,16 +55,36 @@ func NewTernarySamplerSparse(prng utils.PRNG, baseRing *Ring, hw uint64, montgom
 
 // Read samples a polynomial into pol.
 func (ts *TernarySampler) Read(pol *Poly) {
-	ts.sample(pol)
+	ts.ReadLvl(uint64(len(ts.baseRing.Modulus)-1), pol)
 }
 
 // ReadNew allocates and samples a polynomial.
 func (ts *TernarySampler) ReadNew() (pol *Poly) {
 	pol = ts.baseRing.NewPoly()
-	ts.sample(pol)
+	ts.Read(pol)
 	return pol
 }
 
+// ReadLvl samples a polynomial at the given level into pol.
+func (ts *TernarySampler) ReadLvl(level uint64, pol *Poly) {
+	ts.sample(level, pol)
+}
+
+// ReadAndAdd samples a polynomial and adds it on pol.
+func (ts *TernarySampler) ReadAndAdd(pol *Poly) {
+	ts.ReadAndAddLvl(uint64(len(ts.baseRing.Modulus)-1), pol)
+}
+
+// ReadAndAddLvl samples a polynomial at the given level and adds it on pol.
+func (ts *TernarySampler) ReadAndAddLvl(level uint64, pol *Poly) {
+	// The sparse sampler only writes the non-zero coefficients, hence the sample is read on a zero polynomial
+	tmp := ts.baseRing.GetPolyLvl(level)
+	tmp.Zero()
+	ts.sample(level, tmp)
+	ts.baseRing.AddLvl(level, pol, tmp, pol)
+	ts.baseRing.PutPoly(tmp)
+}
+
 func (ts *TernarySampler) initializeMatrix(montgomery bool) {
 	ts.matrixValues = make([][3]uint64, len(ts.baseRing.Modulus))
 
@@ -109,7 +124,7 @@ func (ts *TernarySampler) computeMatrixTernary(p float64) {
 
 }
 
-func (ts *TernarySampler) sampleProba(pol *Poly) {
+func (ts *TernarySampler) sampleProba(level uint64, pol *Poly) {
 
 	if ts.p == 0 {
 		panic("cannot sample -> p = 0")
@@ -134,7 +149,7 @@ func (ts *TernarySampler) sampleProba(pol *Poly) {
 
 			index = (coeff & (sign ^ 1)) | ((sign & coeff) << 1)
 
-			for j := range ts.baseRing.Modulus {
+			for j := uint64(0); j < level+1; j++ {
 				pol.Coeffs[j][i] = ts.matrixValues[j][index] //(coeff & (sign^1)) | (qi - 1) * (sign & coeff)
 			}
 		}
@@ -154,14 +169,14 @@ func (ts *TernarySampler) sampleProba(pol *Poly) {
 
 			index = (coeff & (sign ^ 1)) | ((sign & coeff) << 1)
 
-			for j := range ts.baseRing.Modulus {
+			for j := uint64(0); j < level+1; j++ {
 				pol.Coeffs[j][i] = ts.matrixValues[j][index] //(coeff & (sign^1)) | (qi - 1) * (sign & coeff)
 			}
 		}
 	}
 }
 
-func (ts *TernarySampler) sampleSparse(pol *Poly) {
+func (ts *TernarySampler) sampleSparse(level uint64, pol *Poly) {
 
 	if ts.hw > ts.baseRing.N {
 		ts.hw = ts.baseRing.N
@@ -189,7 +204,7 @@ func (ts *TernarySampler) sampleSparse(pol *Poly) {
 		}
 
 		coeff = (uint8(randomBytes[0]) >> (i & 7)) & 1 // random binary digit [0, 1] from the random bytes
-		for i := range ts.baseRing.Modulus {
+		for i := uint64(0); i < level+1; i++ {
 			pol.Coeffs[i][index[j]] = ts.matrixValues[i][coeff]
 		}
 
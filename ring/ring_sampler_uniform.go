@@ -10,65 +10,70 @@ import (
 type UniformSampler struct {
 	baseSampler
 	randomBufferN []byte
+	isNTT         bool
+	isMForm       bool
 }
 
 // NewUniformSampler creates a new instance of UniformSampler from a PRNG and ring definition.
 func NewUniformSampler(prng utils.PRNG, baseRing *Ring) *UniformSampler {
+	return NewUniformSamplerWithDomain(prng, baseRing, false, false)
+}
+
+// NewUniformSamplerWithDomain creates a new instance of UniformSampler from a PRNG and ring definition, whose
+// polynomials are marked as being in the NTT domain if "isNTT" is set to true and in the Montgomery form if
+// "isMForm" is set to true. As the NTT and the Montgomery form are bijections, a uniform polynomial is uniform in
+// any domain: the marks do not change the sampled polynomials, but they let their consumers use them directly in
+// the marked domain, instead of applying the NTT or the Montgomery form to them.
+func NewUniformSamplerWithDomain(prng utils.PRNG, baseRing *Ring, isNTT, isMForm bool) *UniformSampler {
 	uniformSampler := new(UniformSampler)
 	uniformSampler.baseRing = baseRing
 	uniformSampler.prng = prng
 	uniformSampler.randomBufferN = make([]byte, baseRing.N)
+	uniformSampler.isNTT = isNTT
+	uniformSampler.isMForm = isMForm
 	return uniformSampler
 }
 
-// Read generates a new polynomial with coefficients following a uniform distribution over [0, Qi-1].
-func (uniformSampler *UniformSampler) Read(Pol *Poly) {
-
-	var randomUint, mask, qi uint64
-	var ptr uint64
-
-	uniformSampler.prng.Clock(uniformSampler.randomBufferN)
-
-	for j := range uniformSampler.baseRing.Modulus {
-
-		qi = uniformSampler.baseRing.Modulus[j]
-
-		// Start by computing the mask
-		mask = uniformSampler.baseRing.Mask[j]
-
-		ptmp := Pol.Coeffs[j]
-
-		// Iterate for each modulus over each coefficient
-		for i := uint64(0); i < uniformSampler.baseRing.N; i++ {
+// IsNTT returns true if the polynomials sampled by the UniformSampler are marked as being in the NTT domain.
+func (uniformSampler *UniformSampler) IsNTT() bool {
+	return uniformSampler.isNTT
+}
 
-			// Sample an integer between [0, qi-1]
-			for {
+// IsMForm returns true if the polynomials sampled by the UniformSampler are marked as being in the Montgomery form.
+func (uniformSampler *UniformSampler) IsMForm() bool {
+	return uniformSampler.isMForm
+}
 
-				// Refill the pool if it runs empty
-				if ptr == uniformSampler.baseRing.N {
-					uniformSampler.prng.Clock(uniformSampler.randomBufferN)
-					ptr = 0
-				}
+// Read generates a new polynomial with coefficients following a uniform distribution over [0, Qi-1].
+func (uniformSampler *UniformSampler) Read(Pol *Poly) {
+	uniformSampler.ReadLvl(uint64(len(uniformSampler.baseRing.Modulus)-1), Pol)
+}
 
-				// Read bytes from the pool
-				randomUint = binary.BigEndian.Uint64(uniformSampler.randomBufferN[ptr:ptr+8]) & mask
-				ptr += 8
+// Readlvl is an alias of ReadLvl.
+//
+// Deprecated: use ReadLvl.
+func (uniformSampler *UniformSampler) Readlvl(level uint64, Pol *Poly) {
+	uniformSampler.ReadLvl(level, Pol)
+}
 
-				// If the integer is between [0, qi-1], break the loop
-				if randomUint < qi {
-					break
-				}
-			}
+// ReadLvl generates a new polynomial with coefficients following a uniform distribution over [0, Qi-1], for the
+// moduli from q_0 up to q_level.
+func (uniformSampler *UniformSampler) ReadLvl(level uint64, Pol *Poly) {
+	uniformSampler.readLvl(level, Pol, false)
+}
 
-			ptmp[i] = randomUint
-		}
-	}
+// ReadAndAdd adds on Pol a polynomial with coefficients following a uniform distribution over [0, Qi-1].
+func (uniformSampler *UniformSampler) ReadAndAdd(Pol *Poly) {
+	uniformSampler.ReadAndAddLvl(uint64(len(uniformSampler.baseRing.Modulus)-1), Pol)
+}
 
-	return
+// ReadAndAddLvl adds on Pol a polynomial with coefficients following a uniform distribution over [0, Qi-1], for the
+// moduli from q_0 up to q_level.
+func (uniformSampler *UniformSampler) ReadAndAddLvl(level uint64, Pol *Poly) {
+	uniformSampler.readLvl(level, Pol, true)
 }
 
-// Readlvl generates a new polynomial with coefficients following a uniform distribution over [0, Qi-1].
-func (uniformSampler *UniformSampler) Readlvl(level uint64, Pol *Poly) {
+func (uniformSampler *UniformSampler) readLvl(level uint64, Pol *Poly, add bool) {
 
 	var randomUint, mask, qi uint64
 	var ptr uint64
@@ -106,11 +111,13 @@ func (uniformSampler *UniformSampler) Readlvl(level uint64, Pol *Poly) {
 				}
 			}
 
-			ptmp[i] = randomUint
+			if add {
+				ptmp[i] = CRed(ptmp[i]+randomUint, qi)
+			} else {
+				ptmp[i] = randomUint
+			}
 		}
 	}
-
-	return
 }
 
 // ReadNew generates a new polynomial with coefficients following a uniform distribution over [0, Qi-1].
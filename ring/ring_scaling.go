@@ -290,137 +290,173 @@ func (ss *SimpleScaler) reconstructAndScale(p1, p2 *Poly) {
 }
 
 // ============== Scaling-related methods ==============
+//
+// The polynomials are divided by the last modulus q_level of their level with either a floored division, which
+// subtracts the remainder in [0, q_level), or a rounded division, which subtracts the centered remainder in
+// (-q_level/2, q_level/2]. Each division has a variant for the NTT domain, and the Lvl variants write the result,
+// at level-1, on a second polynomial, whereas the other variants are computed in place and drop the last modulus
+// of the polynomial. The Many variants divide sequentially by the nbRescales last moduli.
 
 // DivFloorByLastModulusNTT divides (floored) the polynomial by its last modulus. The input must be in the NTT domain.
 func (r *Ring) DivFloorByLastModulusNTT(p0 *Poly) {
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivFloorByLastModulusNTTLvl(level, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level]
+}
 
-	level := len(p0.Coeffs) - 1
+// DivFloorByLastModulusNTTLvl divides (floored) p0 by q_level and writes the result on p1, for the moduli from q_0 up
+// to q_level-1. The input must be in the NTT domain.
+func (r *Ring) DivFloorByLastModulusNTTLvl(level uint64, p0, p1 *Poly) {
 
+	pLast := make([]uint64, r.N)
 	pTmp := make([]uint64, r.N)
 
-	InvNTT(p0.Coeffs[level], p0.Coeffs[level], r.N, r.NttPsiInv[level], r.NttNInv[level], r.Modulus[level], r.MredParams[level])
+	InvNTT(p0.Coeffs[level], pLast, r.N, r.NttPsiInv[level], r.NttNInv[level], r.Modulus[level], r.MredParams[level])
 
-	for i := 0; i < level; i++ {
+	for i := uint64(0); i < level; i++ {
 
-		NTT(p0.Coeffs[level], pTmp, r.N, r.NttPsi[i], r.Modulus[i], r.MredParams[i], r.BredParams[i])
+		NTT(pLast, pTmp, r.N, r.NttPsi[i], r.Modulus[i], r.MredParams[i], r.BredParams[i])
 
-		p0tmp := p0.Coeffs[i]
+		p0tmp, p1tmp := p0.Coeffs[i], p1.Coeffs[i]
 
 		qi := r.Modulus[i]
 		mredParams := r.MredParams[i]
-		rescalParams := r.RescaleParams[level-1][i]
+		rescaleParams := r.RescaleParams[level-1][i]
 
 		// (x[i] - x[-1]) * InvQ
 		for j := uint64(0); j < r.N; j = j + 8 {
 
 			x := (*[8]uint64)(unsafe.Pointer(&pTmp[j]))
-			z := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
-
-			z[0] = MRed(z[0]+(qi-x[0]), rescalParams, qi, mredParams)
-			z[1] = MRed(z[1]+(qi-x[1]), rescalParams, qi, mredParams)
-			z[2] = MRed(z[2]+(qi-x[2]), rescalParams, qi, mredParams)
-			z[3] = MRed(z[3]+(qi-x[3]), rescalParams, qi, mredParams)
-			z[4] = MRed(z[4]+(qi-x[4]), rescalParams, qi, mredParams)
-			z[5] = MRed(z[5]+(qi-x[5]), rescalParams, qi, mredParams)
-			z[6] = MRed(z[6]+(qi-x[6]), rescalParams, qi, mredParams)
-			z[7] = MRed(z[7]+(qi-x[7]), rescalParams, qi, mredParams)
+			y := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
+			z := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
 
+			z[0] = MRed(y[0]+(qi-x[0]), rescaleParams, qi, mredParams)
+			z[1] = MRed(y[1]+(qi-x[1]), rescaleParams, qi, mredParams)
+			z[2] = MRed(y[2]+(qi-x[2]), rescaleParams, qi, mredParams)
+			z[3] = MRed(y[3]+(qi-x[3]), rescaleParams, qi, mredParams)
+			z[4] = MRed(y[4]+(qi-x[4]), rescaleParams, qi, mredParams)
+			z[5] = MRed(y[5]+(qi-x[5]), rescaleParams, qi, mredParams)
+			z[6] = MRed(y[6]+(qi-x[6]), rescaleParams, qi, mredParams)
+			z[7] = MRed(y[7]+(qi-x[7]), rescaleParams, qi, mredParams)
 		}
 	}
-
-	p0.Coeffs = p0.Coeffs[:level]
 }
 
 // DivFloorByLastModulus divides (floored) the polynomial by its last modulus.
 func (r *Ring) DivFloorByLastModulus(p0 *Poly) {
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivFloorByLastModulusLvl(level, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level]
+}
+
+// DivFloorByLastModulusLvl divides (floored) p0 by q_level and writes the result on p1, for the moduli from q_0 up
+// to q_level-1.
+func (r *Ring) DivFloorByLastModulusLvl(level uint64, p0, p1 *Poly) {
 
-	level := len(p0.Coeffs) - 1
+	pLast := p0.Coeffs[level]
+
+	for i := uint64(0); i < level; i++ {
+
+		p0tmp, p1tmp := p0.Coeffs[i], p1.Coeffs[i]
 
-	for i := 0; i < level; i++ {
-		p0tmp := p0.Coeffs[level]
-		p1tmp := p0.Coeffs[i]
 		qi := r.Modulus[i]
 		bredParams := r.BredParams[i]
 		mredParams := r.MredParams[i]
 		rescaleParams := r.RescaleParams[level-1][i]
+
 		// (x[i] - x[-1]) * InvQ
 		for j := uint64(0); j < r.N; j = j + 8 {
 
-			x := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
+			x := (*[8]uint64)(unsafe.Pointer(&pLast[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
 			z := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
 
-			z[0] = MRed(z[0]+(qi-BRedAdd(x[0], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[1] = MRed(z[1]+(qi-BRedAdd(x[1], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[2] = MRed(z[2]+(qi-BRedAdd(x[2], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[3] = MRed(z[3]+(qi-BRedAdd(x[3], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[4] = MRed(z[4]+(qi-BRedAdd(x[4], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[5] = MRed(z[5]+(qi-BRedAdd(x[5], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[6] = MRed(z[6]+(qi-BRedAdd(x[6], qi, bredParams)), rescaleParams, qi, mredParams)
-			z[7] = MRed(z[7]+(qi-BRedAdd(x[7], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[0] = MRed(y[0]+(qi-BRedAdd(x[0], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[1] = MRed(y[1]+(qi-BRedAdd(x[1], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[2] = MRed(y[2]+(qi-BRedAdd(x[2], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[3] = MRed(y[3]+(qi-BRedAdd(x[3], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[4] = MRed(y[4]+(qi-BRedAdd(x[4], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[5] = MRed(y[5]+(qi-BRedAdd(x[5], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[6] = MRed(y[6]+(qi-BRedAdd(x[6], qi, bredParams)), rescaleParams, qi, mredParams)
+			z[7] = MRed(y[7]+(qi-BRedAdd(x[7], qi, bredParams)), rescaleParams, qi, mredParams)
 		}
 	}
-
-	p0.Coeffs = p0.Coeffs[:level]
 }
 
 // DivFloorByLastModulusManyNTT divides (floored) sequentially nbRescales times the polynomial by its last modulus. Input must be in the NTT domain.
 func (r *Ring) DivFloorByLastModulusManyNTT(p0 *Poly, nbRescales uint64) {
-	r.InvNTTLvl(uint64(len(p0.Coeffs)-1), p0, p0)
-	r.DivFloorByLastModulusMany(p0, nbRescales)
-	r.NTTLvl(uint64(len(p0.Coeffs)-1), p0, p0)
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivFloorByLastModulusManyNTTLvl(level, nbRescales, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level+1-nbRescales]
+}
+
+// DivFloorByLastModulusManyNTTLvl divides (floored) sequentially nbRescales times p0 by q_level, ..., q_level-nbRescales+1
+// and writes the result on p1, for the moduli from q_0 up to q_level-nbRescales. The input must be in the NTT domain.
+// The intermediate results are stored on p1, which must thus have at least level moduli.
+func (r *Ring) DivFloorByLastModulusManyNTTLvl(level, nbRescales uint64, p0, p1 *Poly) {
+	r.divByLastModulusManyNTTLvl(level, nbRescales, p0, p1, r.DivFloorByLastModulusNTTLvl, r.DivFloorByLastModulusManyLvl)
 }
 
 // DivFloorByLastModulusMany divides (floored) sequentially nbRescales times the polynomial by its last modulus.
 func (r *Ring) DivFloorByLastModulusMany(p0 *Poly, nbRescales uint64) {
-	for k := uint64(0); k < nbRescales; k++ {
-		r.DivFloorByLastModulus(p0)
-	}
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivFloorByLastModulusManyLvl(level, nbRescales, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level+1-nbRescales]
+}
+
+// DivFloorByLastModulusManyLvl divides (floored) sequentially nbRescales times p0 by q_level, ..., q_level-nbRescales+1
+// and writes the result on p1, for the moduli from q_0 up to q_level-nbRescales. The intermediate results are stored
+// on p1, which must thus have at least level moduli.
+func (r *Ring) DivFloorByLastModulusManyLvl(level, nbRescales uint64, p0, p1 *Poly) {
+	r.divByLastModulusManyLvl(level, nbRescales, p0, p1, r.DivFloorByLastModulusLvl)
 }
 
 // DivRoundByLastModulusNTT divides (rounded) the polynomial by its last modulus. The input must be in the NTT domain.
 func (r *Ring) DivRoundByLastModulusNTT(p0 *Poly) {
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivRoundByLastModulusNTTLvl(level, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level]
+}
 
-	var pHalf, pHalfNegQi uint64
-
-	level := len(p0.Coeffs) - 1
-
-	pTmp := make([]uint64, r.N)
+// DivRoundByLastModulusNTTLvl divides (rounded) p0 by q_level and writes the result on p1, for the moduli from q_0 up
+// to q_level-1. The input must be in the NTT domain.
+func (r *Ring) DivRoundByLastModulusNTTLvl(level uint64, p0, p1 *Poly) {
+	r.divRoundByLastModulusNTTLvl(level, r.RescaleParams[level-1], p0, p1)
+}
 
-	InvNTT(p0.Coeffs[level], p0.Coeffs[level], r.N, r.NttPsiInv[level], r.NttNInv[level], r.Modulus[level], r.MredParams[level])
+// DivRoundByLastModulusAndMulNTTLvl divides (rounded) p0 by q_level, multiplies it by the correction constant of the
+// level and writes the result on p1, for the moduli from q_0 up to q_level-1. The constants correction must be
+// generated with GenRescaleCorrectionParams, the multiplication then comes at no cost. The input must be in the NTT domain.
+func (r *Ring) DivRoundByLastModulusAndMulNTTLvl(level uint64, correction [][]uint64, p0, p1 *Poly) {
+	r.divRoundByLastModulusNTTLvl(level, correction[level-1], p0, p1)
+}
 
-	// Center by (p-1)/2
-	pHalf = (r.Modulus[level] - 1) >> 1
-	p0tmp := p0.Coeffs[level]
-	pj := r.Modulus[level]
+// divRoundByLastModulusNTTLvl divides (rounded) p0 by q_level and writes the result on p1, multiplying it by the
+// constants rescaleParams, which are in Montgomery form and include q_level^-1.
+func (r *Ring) divRoundByLastModulusNTTLvl(level uint64, rescaleParams []uint64, p0, p1 *Poly) {
 
-	for i := uint64(0); i < r.N; i = i + 8 {
+	pLast := make([]uint64, r.N)
 
-		z := (*[8]uint64)(unsafe.Pointer(&p0tmp[i]))
+	InvNTT(p0.Coeffs[level], pLast, r.N, r.NttPsiInv[level], r.NttNInv[level], r.Modulus[level], r.MredParams[level])
 
-		z[0] = CRed(z[0]+pHalf, pj)
-		z[1] = CRed(z[1]+pHalf, pj)
-		z[2] = CRed(z[2]+pHalf, pj)
-		z[3] = CRed(z[3]+pHalf, pj)
-		z[4] = CRed(z[4]+pHalf, pj)
-		z[5] = CRed(z[5]+pHalf, pj)
-		z[6] = CRed(z[6]+pHalf, pj)
-		z[7] = CRed(z[7]+pHalf, pj)
-	}
+	// Center by (p-1)/2
+	pHalf := r.centerLastModulus(level, pLast)
 
-	for i := 0; i < level; i++ {
+	// Divides the coefficients of the modulus q_i, using the buffer pTmp
+	divRound := func(i int, pTmp []uint64) {
 
-		p1tmp := p0.Coeffs[i]
+		p0tmp, p1tmp := p0.Coeffs[i], p1.Coeffs[i]
 
 		qi := r.Modulus[i]
 		bredParams := r.BredParams[i]
 		mredParams := r.MredParams[i]
-		rescaleParams := r.RescaleParams[level-1][i]
+		rescaleParam := rescaleParams[i]
 
-		pHalfNegQi = r.Modulus[i] - BRedAdd(pHalf, qi, bredParams)
+		pHalfNegQi := r.Modulus[i] - BRedAdd(pHalf, qi, bredParams)
 
 		for j := uint64(0); j < r.N; j = j + 8 {
 
-			x := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
+			x := (*[8]uint64)(unsafe.Pointer(&pLast[j]))
 			z := (*[8]uint64)(unsafe.Pointer(&pTmp[j]))
 
 			z[0] = x[0] + pHalfNegQi
@@ -439,90 +475,176 @@ func (r *Ring) DivRoundByLastModulusNTT(p0 *Poly) {
 		for j := uint64(0); j < r.N; j = j + 8 {
 
 			x := (*[8]uint64)(unsafe.Pointer(&pTmp[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
 			z := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
 
-			z[0] = MRed(z[0]+(qi-x[0]), rescaleParams, qi, mredParams)
-			z[1] = MRed(z[1]+(qi-x[1]), rescaleParams, qi, mredParams)
-			z[2] = MRed(z[2]+(qi-x[2]), rescaleParams, qi, mredParams)
-			z[3] = MRed(z[3]+(qi-x[3]), rescaleParams, qi, mredParams)
-			z[4] = MRed(z[4]+(qi-x[4]), rescaleParams, qi, mredParams)
-			z[5] = MRed(z[5]+(qi-x[5]), rescaleParams, qi, mredParams)
-			z[6] = MRed(z[6]+(qi-x[6]), rescaleParams, qi, mredParams)
-			z[7] = MRed(z[7]+(qi-x[7]), rescaleParams, qi, mredParams)
+			z[0] = MRed(y[0]+(qi-x[0]), rescaleParam, qi, mredParams)
+			z[1] = MRed(y[1]+(qi-x[1]), rescaleParam, qi, mredParams)
+			z[2] = MRed(y[2]+(qi-x[2]), rescaleParam, qi, mredParams)
+			z[3] = MRed(y[3]+(qi-x[3]), rescaleParam, qi, mredParams)
+			z[4] = MRed(y[4]+(qi-x[4]), rescaleParam, qi, mredParams)
+			z[5] = MRed(y[5]+(qi-x[5]), rescaleParam, qi, mredParams)
+			z[6] = MRed(y[6]+(qi-x[6]), rescaleParam, qi, mredParams)
+			z[7] = MRed(y[7]+(qi-x[7]), rescaleParam, qi, mredParams)
 		}
 	}
 
-	p0.Coeffs = p0.Coeffs[:level]
+	if r.workerPool == nil || level < 2 {
+		pTmp := make([]uint64, r.N)
+		for i := 0; i < int(level); i++ {
+			divRound(i, pTmp)
+		}
+	} else {
+		r.workerPool.Run(int(level), func(i int) {
+			divRound(i, make([]uint64, r.N))
+		})
+	}
 }
 
-// DivRoundByLastModulus divides (rounded) the polynomial by its last modulus. The input must be in the NTT domain.
+// DivRoundByLastModulus divides (rounded) the polynomial by its last modulus.
 func (r *Ring) DivRoundByLastModulus(p0 *Poly) {
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivRoundByLastModulusLvl(level, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level]
+}
 
-	var pHalf, pHalfNegQi uint64
-
-	level := len(p0.Coeffs) - 1
+// DivRoundByLastModulusLvl divides (rounded) p0 by q_level and writes the result on p1, for the moduli from q_0 up
+// to q_level-1.
+func (r *Ring) DivRoundByLastModulusLvl(level uint64, p0, p1 *Poly) {
+	r.divRoundByLastModulusLvl(level, r.RescaleParams[level-1], p0, p1)
+}
 
-	// Center by (p-1)/2
-	pHalf = (r.Modulus[level] - 1) >> 1
-	p0tmp := p0.Coeffs[level]
-	pj := r.Modulus[level]
-	pHalf = (r.Modulus[level] - 1) >> 1
+// DivRoundByLastModulusAndMulLvl divides (rounded) p0 by q_level, multiplies it by the correction constant of the
+// level and writes the result on p1, for the moduli from q_0 up to q_level-1. The constants correction must be
+// generated with GenRescaleCorrectionParams, the multiplication then comes at no cost.
+func (r *Ring) DivRoundByLastModulusAndMulLvl(level uint64, correction [][]uint64, p0, p1 *Poly) {
+	r.divRoundByLastModulusLvl(level, correction[level-1], p0, p1)
+}
 
-	for i := uint64(0); i < r.N; i = i + 8 {
+// divRoundByLastModulusLvl divides (rounded) p0 by q_level and writes the result on p1, multiplying it by the
+// constants rescaleParams, which are in Montgomery form and include q_level^-1.
+func (r *Ring) divRoundByLastModulusLvl(level uint64, rescaleParams []uint64, p0, p1 *Poly) {
 
-		z := (*[8]uint64)(unsafe.Pointer(&p0tmp[i]))
+	pLast := make([]uint64, r.N)
+	copy(pLast, p0.Coeffs[level])
 
-		z[0] = CRed(z[0]+pHalf, pj)
-		z[1] = CRed(z[1]+pHalf, pj)
-		z[2] = CRed(z[2]+pHalf, pj)
-		z[3] = CRed(z[3]+pHalf, pj)
-		z[4] = CRed(z[4]+pHalf, pj)
-		z[5] = CRed(z[5]+pHalf, pj)
-		z[6] = CRed(z[6]+pHalf, pj)
-		z[7] = CRed(z[7]+pHalf, pj)
-	}
+	// Center by (p-1)/2
+	pHalf := r.centerLastModulus(level, pLast)
 
-	for i := 0; i < level; i++ {
+	for i := uint64(0); i < level; i++ {
 
-		p1tmp := p0.Coeffs[i]
+		p0tmp, p1tmp := p0.Coeffs[i], p1.Coeffs[i]
 
 		qi := r.Modulus[i]
 		bredParams := r.BredParams[i]
 		mredParams := r.MredParams[i]
-		rescaleParams := r.RescaleParams[level-1][i]
+		rescaleParam := rescaleParams[i]
 
-		pHalfNegQi = r.Modulus[i] - BRedAdd(pHalf, qi, bredParams)
+		pHalfNegQi := r.Modulus[i] - BRedAdd(pHalf, qi, bredParams)
 
 		// (x[i] - x[-1]) * InvQ
 		for j := uint64(0); j < r.N; j = j + 8 {
 
-			x := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
+			x := (*[8]uint64)(unsafe.Pointer(&pLast[j]))
+			y := (*[8]uint64)(unsafe.Pointer(&p0tmp[j]))
 			z := (*[8]uint64)(unsafe.Pointer(&p1tmp[j]))
 
-			z[0] = MRed(z[0]+(qi-BRedAdd(x[0]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[1] = MRed(z[1]+(qi-BRedAdd(x[1]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[2] = MRed(z[2]+(qi-BRedAdd(x[2]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[3] = MRed(z[3]+(qi-BRedAdd(x[3]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[4] = MRed(z[4]+(qi-BRedAdd(x[4]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[5] = MRed(z[5]+(qi-BRedAdd(x[5]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[6] = MRed(z[6]+(qi-BRedAdd(x[6]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
-			z[7] = MRed(z[7]+(qi-BRedAdd(x[7]+pHalfNegQi, qi, bredParams)), rescaleParams, qi, mredParams)
+			z[0] = MRed(y[0]+(qi-BRedAdd(x[0]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[1] = MRed(y[1]+(qi-BRedAdd(x[1]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[2] = MRed(y[2]+(qi-BRedAdd(x[2]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[3] = MRed(y[3]+(qi-BRedAdd(x[3]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[4] = MRed(y[4]+(qi-BRedAdd(x[4]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[5] = MRed(y[5]+(qi-BRedAdd(x[5]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[6] = MRed(y[6]+(qi-BRedAdd(x[6]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
+			z[7] = MRed(y[7]+(qi-BRedAdd(x[7]+pHalfNegQi, qi, bredParams)), rescaleParam, qi, mredParams)
 		}
 	}
-
-	p0.Coeffs = p0.Coeffs[:level]
 }
 
 // DivRoundByLastModulusManyNTT divides (rounded) sequentially nbRescales times the polynomial by its last modulus. The input must be in the NTT domain.
 func (r *Ring) DivRoundByLastModulusManyNTT(p0 *Poly, nbRescales uint64) {
-	r.InvNTTLvl(uint64(len(p0.Coeffs)-1), p0, p0)
-	r.DivRoundByLastModulusMany(p0, nbRescales)
-	r.NTTLvl(uint64(len(p0.Coeffs)-1), p0, p0)
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivRoundByLastModulusManyNTTLvl(level, nbRescales, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level+1-nbRescales]
+}
+
+// DivRoundByLastModulusManyNTTLvl divides (rounded) sequentially nbRescales times p0 by q_level, ..., q_level-nbRescales+1
+// and writes the result on p1, for the moduli from q_0 up to q_level-nbRescales. The input must be in the NTT domain.
+// The intermediate results are stored on p1, which must thus have at least level moduli.
+func (r *Ring) DivRoundByLastModulusManyNTTLvl(level, nbRescales uint64, p0, p1 *Poly) {
+	r.divByLastModulusManyNTTLvl(level, nbRescales, p0, p1, r.DivRoundByLastModulusNTTLvl, r.DivRoundByLastModulusManyLvl)
 }
 
 // DivRoundByLastModulusMany divides (rounded) sequentially nbRescales times the polynomial by its last modulus.
 func (r *Ring) DivRoundByLastModulusMany(p0 *Poly, nbRescales uint64) {
-	for k := uint64(0); k < nbRescales; k++ {
-		r.DivRoundByLastModulus(p0)
+	level := uint64(len(p0.Coeffs) - 1)
+	r.DivRoundByLastModulusManyLvl(level, nbRescales, p0, p0)
+	p0.Coeffs = p0.Coeffs[:level+1-nbRescales]
+}
+
+// DivRoundByLastModulusManyLvl divides (rounded) sequentially nbRescales times p0 by q_level, ..., q_level-nbRescales+1
+// and writes the result on p1, for the moduli from q_0 up to q_level-nbRescales. The intermediate results are stored
+// on p1, which must thus have at least level moduli.
+func (r *Ring) DivRoundByLastModulusManyLvl(level, nbRescales uint64, p0, p1 *Poly) {
+	r.divByLastModulusManyLvl(level, nbRescales, p0, p1, r.DivRoundByLastModulusLvl)
+}
+
+// centerLastModulus adds (q_level-1)/2 modulo q_level to the coefficients of pLast, which are reduced modulo q_level,
+// and returns (q_level-1)/2.
+func (r *Ring) centerLastModulus(level uint64, pLast []uint64) (pHalf uint64) {
+
+	pj := r.Modulus[level]
+	pHalf = (pj - 1) >> 1
+
+	for i := uint64(0); i < r.N; i = i + 8 {
+
+		z := (*[8]uint64)(unsafe.Pointer(&pLast[i]))
+
+		z[0] = CRed(z[0]+pHalf, pj)
+		z[1] = CRed(z[1]+pHalf, pj)
+		z[2] = CRed(z[2]+pHalf, pj)
+		z[3] = CRed(z[3]+pHalf, pj)
+		z[4] = CRed(z[4]+pHalf, pj)
+		z[5] = CRed(z[5]+pHalf, pj)
+		z[6] = CRed(z[6]+pHalf, pj)
+		z[7] = CRed(z[7]+pHalf, pj)
+	}
+
+	return
+}
+
+func (r *Ring) divByLastModulusManyLvl(level, nbRescales uint64, p0, p1 *Poly, divLvl func(level uint64, p0, p1 *Poly)) {
+
+	if nbRescales == 0 {
+		r.CopyLvl(level, p0, p1)
+		return
+	}
+
+	divLvl(level, p0, p1)
+
+	for k := uint64(1); k < nbRescales; k++ {
+		divLvl(level-k, p1, p1)
 	}
 }
+
+func (r *Ring) divByLastModulusManyNTTLvl(level, nbRescales uint64, p0, p1 *Poly, divNTTLvl func(level uint64, p0, p1 *Poly), divManyLvl func(level, nbRescales uint64, p0, p1 *Poly)) {
+
+	if nbRescales == 0 {
+		r.CopyLvl(level, p0, p1)
+		return
+	}
+
+	// The first division is done in the NTT domain if it is not in place, as p1 may not have the moduli of p0
+	if p0 != p1 || nbRescales == 1 {
+		divNTTLvl(level, p0, p1)
+		level, nbRescales = level-1, nbRescales-1
+	}
+
+	if nbRescales == 0 {
+		return
+	}
+
+	r.InvNTTLvl(level, p1, p1)
+	divManyLvl(level, nbRescales, p1, p1)
+	r.NTTLvl(level-nbRescales, p1, p1)
+}
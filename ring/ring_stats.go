@@ -0,0 +1,112 @@
+package ring
+
+import (
+	"math"
+	"math/big"
+)
+
+// The statistics below are computed on the centered CRT reconstruction of the coefficients of a polynomial in the
+// coefficient domain, i.e. on its coefficients as integers in (-Q/2, Q/2] for Q = q_0 * ... * q_level, as needed for
+// the noise of a decryption or the output of a sampler.
+
+// Norm returns the infinity norm of the centered reconstruction of p1 from all its moduli.
+func (r *Ring) Norm(p1 *Poly) *big.Int {
+	return r.NormLvl(uint64(len(p1.Coeffs)-1), p1)
+}
+
+// NormLvl returns the infinity norm of the centered reconstruction of p1 from the moduli q_0 up to q_level.
+func (r *Ring) NormLvl(level uint64, p1 *Poly) (norm *big.Int) {
+
+	coeffs := make([]*big.Int, r.N)
+	r.PolyToBigintCenteredLvl(level, p1, 1, coeffs)
+
+	norm = new(big.Int)
+	for _, c := range coeffs {
+		if c.CmpAbs(norm) > 0 {
+			norm.Abs(c)
+		}
+	}
+
+	return
+}
+
+// MeanAndVariance returns the mean and the variance of the coefficients of the centered reconstruction of p1 from all
+// its moduli, see MeanAndVarianceLvl.
+func (r *Ring) MeanAndVariance(p1 *Poly) (mean, variance float64) {
+	return r.MeanAndVarianceLvl(uint64(len(p1.Coeffs)-1), p1)
+}
+
+// MeanAndVarianceLvl returns the mean and the variance of the coefficients of the centered reconstruction of p1 from
+// the moduli q_0 up to q_level. The sums are computed exactly and only the results are rounded to float64, which are
+// infinite if they exceed its range, e.g. for uniform coefficients modulo a Q of more than 512 bits. The standard
+// deviation of a noise is math.Sqrt(variance).
+func (r *Ring) MeanAndVarianceLvl(level uint64, p1 *Poly) (mean, variance float64) {
+
+	coeffs := make([]*big.Int, r.N)
+	r.PolyToBigintCenteredLvl(level, p1, 1, coeffs)
+
+	sum := new(big.Int)
+	sumSquares := new(big.Int)
+	tmp := new(big.Int)
+
+	for _, c := range coeffs {
+		sum.Add(sum, c)
+		sumSquares.Add(sumSquares, tmp.Mul(c, c))
+	}
+
+	n := new(big.Float).SetUint64(uint64(len(coeffs)))
+
+	meanF := new(big.Float).SetInt(sum)
+	meanF.Quo(meanF, n)
+
+	// variance = sum(c^2)/n - mean^2
+	varianceF := new(big.Float).SetInt(sumSquares)
+	varianceF.Quo(varianceF, n)
+	varianceF.Sub(varianceF, new(big.Float).Mul(meanF, meanF))
+
+	mean, _ = meanF.Float64()
+	variance, _ = varianceF.Float64()
+
+	return mean, math.Max(variance, 0)
+}
+
+// Histogram returns the histogram of the coefficients of the centered reconstruction of p1 from all its moduli, see
+// HistogramLvl.
+func (r *Ring) Histogram(p1 *Poly, binWidth uint64) map[int64]uint64 {
+	return r.HistogramLvl(uint64(len(p1.Coeffs)-1), p1, binWidth)
+}
+
+// HistogramLvl returns the histogram of the coefficients of the centered reconstruction of p1 from the moduli q_0 up
+// to q_level: the bin k counts the coefficients c such that k*binWidth <= c < (k+1)*binWidth. The coefficients whose
+// bin does not fit an int64 are counted in the bins math.MinInt64 and math.MaxInt64. binWidth must be at least 1.
+func (r *Ring) HistogramLvl(level uint64, p1 *Poly, binWidth uint64) (histogram map[int64]uint64) {
+
+	if binWidth == 0 {
+		panic("cannot HistogramLvl: binWidth must be at least 1")
+	}
+
+	coeffs := make([]*big.Int, r.N)
+	r.PolyToBigintCenteredLvl(level, p1, 1, coeffs)
+
+	width := NewUint(binWidth)
+	bin := new(big.Int)
+
+	histogram = make(map[int64]uint64)
+
+	for _, c := range coeffs {
+
+		// Div rounds towards minus infinity for a positive divisor
+		bin.Div(c, width)
+
+		switch {
+		case bin.IsInt64():
+			histogram[bin.Int64()]++
+		case bin.Sign() < 0:
+			histogram[math.MinInt64]++
+		default:
+			histogram[math.MaxInt64]++
+		}
+	}
+
+	return
+}
@@ -1,11 +1,14 @@
 package ring
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"math/bits"
 	"math/rand"
+	"runtime"
 	"testing"
 	"time"
 
@@ -71,19 +74,38 @@ func TestRing(t *testing.T) {
 		testPRNG(testContext, t)
 		testGenerateNTTPrimes(testContext, t)
 		testImportExportPolyString(testContext, t)
+		testPolyToBigint(testContext, t)
 		testDivFloorByLastModulusMany(testContext, t)
 		testDivRoundByLastModulusMany(testContext, t)
+		testDivByLastModulusVariants(testContext, t)
 		testMarshalBinary(testContext, t)
 		testUniformSampler(testContext, t)
 		testGaussianSampler(testContext, t)
+		testConstantTimeGaussianSampler(testContext, t)
+		testSampler(testContext, t)
+		testGaussianSamplerNTT(testContext, t)
 		testTernarySampler(testContext, t)
 		testGaloisShift(testContext, t)
+		testBitReverse(testContext, t)
 		testModularReduction(testContext, t)
+		testMForm(testContext, t)
 		testMulScalarBigint(testContext, t)
 		testMulPoly(testContext, t)
+		testFusedOperations(testContext, t)
+		testLazyReduction(testContext, t)
 		testExtendBasis(testContext, t)
+		testDecomposer(testContext, t)
+		testLargeModuli(testContext, t)
+		testRescaleParams(testContext, t)
 		testScaling(testContext, t)
 		testMultByMonomial(testContext, t)
+		testSparsePoly(testContext, t)
+		testStats(testContext, t)
+		testGrowLvl(testContext, t)
+		testPolyPool(testContext, t)
+		testDebugChecks(testContext, t)
+		testAtLevel(testContext, t)
+		testBackend(testContext, t)
 	}
 }
 
@@ -125,6 +147,82 @@ func testGenerateNTTPrimes(testContext *testParams, t *testing.T) {
 			require.True(t, IsPrime(q), q)
 		}
 	})
+
+	t.Run(testString("GenerateNTTPrimes/Near/", testContext.ringQ), func(t *testing.T) {
+
+		logN := uint64(bits.Len64(testContext.ringQ.N) - 1)
+		_2N := testContext.ringQ.N << 1
+
+		// Primes = 1 + 4N mod 8N, i.e. NTT-friendly for the degree N but not for 2N, around an arbitrary target
+		search := NTTPrimeSearch{LogN: logN, Target: 0x3fed5b1a2c3d4e5f, Min: 0x3fe0000000000000, Max: 0x3ff0000000000000, Modulus: 4 * _2N, Residue: 1 + 2*_2N}
+
+		primes, err := GenerateNTTPrimesNear(search, 8)
+		require.NoError(t, err)
+		require.Len(t, primes, 8)
+
+		distance := func(q uint64) uint64 {
+			if q > search.Target {
+				return q - search.Target
+			}
+			return search.Target - q
+		}
+
+		for i, q := range primes {
+			require.True(t, IsPrime(q), q)
+			require.Equal(t, search.Residue, q%search.Modulus)
+			require.True(t, search.Min <= q && q <= search.Max)
+			if i > 0 {
+				require.LessOrEqual(t, distance(primes[i-1]), distance(q))
+			}
+		}
+
+		// No candidate closer than the farthest prime is skipped
+		found := map[uint64]bool{}
+		for _, q := range primes {
+			found[q] = true
+		}
+
+		last := distance(primes[len(primes)-1])
+		for q := search.Target - search.Target%search.Modulus + search.Residue; distance(q) < last; q += search.Modulus {
+			require.Equal(t, IsPrime(q), found[q], q)
+		}
+		for q := search.Target - search.Target%search.Modulus + search.Residue - search.Modulus; distance(q) < last; q -= search.Modulus {
+			require.Equal(t, IsPrime(q), found[q], q)
+		}
+
+		// Downward from a power of two, as GenerateNTTPrimesP
+		primes, err = GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 50, Max: 1 << 50}, 4)
+		require.NoError(t, err)
+		require.Equal(t, GenerateNTTPrimesP(50, logN, 4), primes)
+		for _, q := range primes {
+			require.Less(t, q, uint64(1<<50))
+		}
+	})
+
+	t.Run(testString("GenerateNTTPrimes/Near/Errors/", testContext.ringQ), func(t *testing.T) {
+
+		logN := uint64(bits.Len64(testContext.ringQ.N) - 1)
+		_2N := testContext.ringQ.N << 1
+
+		// Congruence not implying q = 1 mod 2N
+		_, err := GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 40, Modulus: _2N, Residue: 3}, 1)
+		require.Error(t, err)
+
+		_, err = GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 40, Modulus: _2N + 2, Residue: 1}, 1)
+		require.Error(t, err)
+
+		// Congruence implying q = 0 mod 3
+		_, err = GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 40, Modulus: 3 * _2N, Residue: 1 + _2N*(3-_2N%3)}, 1)
+		require.Error(t, err)
+
+		// Target out of the bounds
+		_, err = GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 40, Min: 1 << 41}, 1)
+		require.Error(t, err)
+
+		// Too few primes within the bounds
+		_, err = GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << 40, Min: 1<<40 - 4*_2N, Max: 1<<40 + 4*_2N}, 16)
+		require.Error(t, err)
+	})
 }
 
 func testImportExportPolyString(testContext *testParams, t *testing.T) {
@@ -140,6 +238,81 @@ func testImportExportPolyString(testContext *testParams, t *testing.T) {
 	})
 }
 
+func testPolyToBigint(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	t.Run(testString("PolyToBigintLvl/", ringQ), func(t *testing.T) {
+
+		level := uint64(len(ringQ.Modulus) - 2)
+		gap := uint64(16)
+
+		p := testContext.uniformSamplerQ.ReadNew()
+
+		want := make([]*big.Int, ringQ.N)
+		ringQ.PolyToBigint(&Poly{Coeffs: p.Coeffs[:level+1]}, want)
+
+		have := make([]*big.Int, ringQ.N/gap)
+		ringQ.PolyToBigintLvl(level, p, gap, have)
+
+		for i := range have {
+			require.Zero(t, want[uint64(i)*gap].Cmp(have[i]))
+		}
+
+		// The caller-provided values are overwritten
+		ringQ.PolyToBigintLvl(level, p, gap, have)
+
+		for i := range have {
+			require.Zero(t, want[uint64(i)*gap].Cmp(have[i]))
+		}
+	})
+
+	t.Run(testString("PolyToBigintCentered/", ringQ), func(t *testing.T) {
+
+		level := uint64(len(ringQ.Modulus) - 1)
+
+		Q := new(big.Int).Set(ringQ.ModulusBigint)
+		QHalf := new(big.Int).Rsh(Q, 1)
+
+		// Signed coefficients, including the bounds of (-Q/2, Q/2]
+		coeffs := make([]*big.Int, ringQ.N)
+		for i := range coeffs {
+			coeffs[i] = big.NewInt(rand.Int63() - rand.Int63())
+		}
+		coeffs[0].Set(QHalf)
+		coeffs[1].Neg(QHalf)
+		coeffs[1].Add(coeffs[1], big.NewInt(1))
+
+		p := ringQ.NewPoly()
+		ringQ.SetCoefficientsBigintLvl(level, coeffs, p)
+
+		have := make([]*big.Int, ringQ.N)
+		ringQ.PolyToBigintCentered(p, have)
+
+		for i := range have {
+			require.Zero(t, coeffs[i].Cmp(have[i]), i)
+		}
+
+		// Lift of small coefficients from q_0 to the full modulus
+		if level > 0 {
+
+			small := make([]*big.Int, ringQ.N)
+			for i := range small {
+				small[i] = big.NewInt(int64(i%17) - 8)
+			}
+
+			ringQ.SetCoefficientsBigintLvl(0, small, p)
+			ringQ.PolyToBigintCenteredLvl(0, p, 1, have)
+			ringQ.SetCoefficientsBigintLvl(level, have, p)
+
+			ringQ.PolyToBigintCentered(p, have)
+			for i := range have {
+				require.Zero(t, small[i].Cmp(have[i]), i)
+			}
+		}
+	})
+}
+
 func testDivFloorByLastModulusMany(testContext *testParams, t *testing.T) {
 
 	t.Run(testString("DivFloorByLastModulusMany/", testContext.ringQ), func(t *testing.T) {
@@ -210,6 +383,111 @@ func testDivRoundByLastModulusMany(testContext *testParams, t *testing.T) {
 	})
 }
 
+func testDivByLastModulusVariants(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+	level := uint64(len(ringQ.Modulus) - 1)
+
+	type variants struct {
+		many       func(p0 *Poly, nbRescales uint64)
+		manyLvl    func(level, nbRescales uint64, p0, p1 *Poly)
+		manyNTTLvl func(level, nbRescales uint64, p0, p1 *Poly)
+		manyNTT    func(p0 *Poly, nbRescales uint64)
+		divNTTLvl  func(level uint64, p0, p1 *Poly)
+		divNTT     func(p0 *Poly)
+	}
+
+	for name, v := range map[string]variants{
+		"Floor": {ringQ.DivFloorByLastModulusMany, ringQ.DivFloorByLastModulusManyLvl, ringQ.DivFloorByLastModulusManyNTTLvl,
+			ringQ.DivFloorByLastModulusManyNTT, ringQ.DivFloorByLastModulusNTTLvl, ringQ.DivFloorByLastModulusNTT},
+		"Round": {ringQ.DivRoundByLastModulusMany, ringQ.DivRoundByLastModulusManyLvl, ringQ.DivRoundByLastModulusManyNTTLvl,
+			ringQ.DivRoundByLastModulusManyNTT, ringQ.DivRoundByLastModulusNTTLvl, ringQ.DivRoundByLastModulusNTT},
+	} {
+
+		t.Run(testString("DivByLastModulus/"+name+"/", ringQ), func(t *testing.T) {
+
+			for nbRescales := uint64(1); nbRescales <= level; nbRescales++ {
+
+				p0 := testContext.uniformSamplerQ.ReadNew()
+				p0Copy := p0.CopyNew()
+
+				// Reference: in place in the coefficient domain
+				want := p0.CopyNew()
+				v.many(want, nbRescales)
+
+				// Out of place in the coefficient domain, p0 is unchanged
+				have := ringQ.NewPolyLvl(level - 1)
+				v.manyLvl(level, nbRescales, p0, have)
+				require.True(t, ringQ.Equal(p0, p0Copy))
+				require.True(t, ringQ.EqualLvl(level-nbRescales, want, have))
+
+				p0NTT := ringQ.NewPoly()
+				ringQ.NTT(p0, p0NTT)
+
+				// Out of place in the NTT domain
+				have = ringQ.NewPolyLvl(level - 1)
+				v.manyNTTLvl(level, nbRescales, p0NTT, have)
+				ringQ.InvNTTLvl(level-nbRescales, have, have)
+				require.True(t, ringQ.EqualLvl(level-nbRescales, want, have))
+
+				// In place in the NTT domain
+				have = p0NTT.CopyNew()
+				v.manyNTT(have, nbRescales)
+				require.Equal(t, int(level-nbRescales+1), len(have.Coeffs))
+				ringQ.InvNTTLvl(level-nbRescales, have, have)
+				require.True(t, ringQ.EqualLvl(level-nbRescales, want, have))
+			}
+
+			// Single division in the NTT domain, in place and out of place
+			p0 := testContext.uniformSamplerQ.ReadNew()
+			have := ringQ.NewPolyLvl(level - 1)
+			v.divNTTLvl(level, p0, have)
+			v.divNTT(p0)
+			require.True(t, ringQ.EqualLvl(level-1, p0, have))
+		})
+	}
+
+	t.Run(testString("DivByLastModulus/Round/AndMul/", ringQ), func(t *testing.T) {
+
+		correction := make([]float64, level)
+		for i := range correction {
+			correction[i] = float64(int64(i+1)<<40) * (1 - 2*float64(i&1)) * 1.3
+		}
+
+		params := GenRescaleCorrectionParams(ringQ.Modulus, correction)
+
+		for lvl := uint64(1); lvl <= level; lvl++ {
+
+			p0 := testContext.uniformSamplerQ.ReadNew()
+
+			c, _ := new(big.Float).SetFloat64(math.Round(correction[lvl-1])).Int(nil)
+
+			want := ringQ.NewPolyLvl(lvl - 1)
+			ringQ.DivRoundByLastModulusLvl(lvl, p0, want)
+			ringQ.MulScalarBigintLvl(lvl-1, want, c, want)
+
+			have := ringQ.NewPolyLvl(lvl - 1)
+			ringQ.DivRoundByLastModulusAndMulLvl(lvl, params, p0, have)
+			require.True(t, ringQ.EqualLvl(lvl-1, want, have))
+
+			p0NTT := ringQ.NewPoly()
+			ringQ.NTT(p0, p0NTT)
+
+			have = ringQ.NewPolyLvl(lvl - 1)
+			ringQ.DivRoundByLastModulusAndMulNTTLvl(lvl, params, p0NTT, have)
+			ringQ.InvNTTLvl(lvl-1, have, have)
+			require.True(t, ringQ.EqualLvl(lvl-1, want, have))
+		}
+
+		// Unit corrections give the plain rescaling constants
+		ones := make([]float64, level)
+		for i := range ones {
+			ones[i] = 1
+		}
+		require.Equal(t, ringQ.RescaleParams, GenRescaleCorrectionParams(ringQ.Modulus, ones))
+	})
+}
+
 func testMarshalBinary(testContext *testParams, t *testing.T) {
 
 	t.Run(testString("MarshalBinary/Ring/", testContext.ringQ), func(t *testing.T) {
@@ -236,6 +514,80 @@ func testMarshalBinary(testContext *testParams, t *testing.T) {
 			require.Equal(t, p.Coeffs[i][:testContext.ringQ.N], pTest.Coeffs[i][:testContext.ringQ.N])
 		}
 	})
+
+	t.Run(testString("MarshalBinary/Poly/MaxModuliCount/", testContext.ringQ), func(t *testing.T) {
+
+		p := NewPoly(8, MaxModuliCount)
+		pTest := new(Poly)
+
+		data, err := p.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, pTest.UnmarshalBinary(data))
+		require.Equal(t, MaxModuliCount, pTest.GetLenModuli())
+
+		_, err = NewPoly(8, MaxModuliCount+1).MarshalBinary()
+		require.Error(t, err)
+	})
+
+	t.Run(testString("MarshalBinary/Poly/WriteTo/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		level := uint64(len(ringQ.Modulus) - 1)
+
+		p := testContext.uniformSamplerQ.ReadNew()
+
+		data, err := p.MarshalBinary()
+		require.NoError(t, err)
+
+		buff := new(bytes.Buffer)
+		n, err := p.WriteTo(buff)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), n)
+		require.Equal(t, data, buff.Bytes())
+
+		// Reads in the coefficients of the target polynomial
+		pTest := ringQ.NewPoly()
+		coeffs := pTest.Coeffs[0]
+		n, err = pTest.ReadFrom(buff)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), n)
+		require.True(t, ringQ.Equal(p, pTest))
+		require.Equal(t, &coeffs[0], &pTest.Coeffs[0][0])
+
+		// Truncation to level 0, read on a polynomial with more moduli and on an empty one
+		_, err = p.WriteToLvl(0, buff)
+		require.NoError(t, err)
+		_, err = p.WriteToLvl(0, buff)
+		require.NoError(t, err)
+
+		_, err = pTest.ReadFrom(buff)
+		require.NoError(t, err)
+		require.Equal(t, 1, pTest.GetLenModuli())
+		require.True(t, ringQ.EqualLvl(0, p, pTest))
+
+		pTest = new(Poly)
+		_, err = pTest.ReadFrom(buff)
+		require.NoError(t, err)
+		require.Equal(t, 1, pTest.GetLenModuli())
+		require.True(t, ringQ.EqualLvl(0, p, pTest))
+
+		_, err = p.WriteToLvl(level+1, buff)
+		require.Error(t, err)
+
+		// Truncated stream
+		_, err = pTest.ReadFrom(bytes.NewReader(data[:len(data)-1]))
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+
+		// Header of a polynomial of maximum degree followed by a short stream: the allocations are bounded by the
+		// length of the stream, not by the degree in the header
+		short := append([]byte{MaxLogN, 1}, data[2:2+(polyReadChunk<<3)]...)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err = new(Poly).ReadFrom(bytes.NewReader(short))
+		runtime.ReadMemStats(&after)
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+		require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	})
 }
 
 func testUniformSampler(testContext *testParams, t *testing.T) {
@@ -258,6 +610,28 @@ func testUniformSampler(testContext *testParams, t *testing.T) {
 			}
 		}
 	})
+
+	t.Run(testString("UniformSampler/Domain/", testContext.ringQ), func(t *testing.T) {
+
+		require.False(t, testContext.uniformSamplerQ.IsNTT())
+		require.False(t, testContext.uniformSamplerQ.IsMForm())
+
+		seed := []byte{'l', 'a', 't', 't', 'i', 'g', 'o'}
+
+		prng, err := utils.NewKeyedPRNG(seed)
+		require.NoError(t, err)
+		samplerNTT := NewUniformSamplerWithDomain(prng, testContext.ringQ, true, true)
+
+		require.True(t, samplerNTT.IsNTT())
+		require.True(t, samplerNTT.IsMForm())
+
+		prng, err = utils.NewKeyedPRNG(seed)
+		require.NoError(t, err)
+		sampler := NewUniformSampler(prng, testContext.ringQ)
+
+		// The marks do not change the sampled polynomials
+		require.True(t, testContext.ringQ.Equal(sampler.ReadNew(), samplerNTT.ReadNew()))
+	})
 }
 
 func testGaussianSampler(testContext *testParams, t *testing.T) {
@@ -274,6 +648,202 @@ func testGaussianSampler(testContext *testParams, t *testing.T) {
 	})
 }
 
+func testConstantTimeGaussianSampler(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	// Returns the coefficients of pol mod q_0 centered in (-q_0/2, q_0/2]
+	centered := func(pol *Poly) (coeffs []int64) {
+		q := ringQ.Modulus[0]
+		coeffs = make([]int64, ringQ.N)
+		for i, c := range pol.Coeffs[0] {
+			if c > q>>1 {
+				coeffs[i] = -int64(q - c)
+			} else {
+				coeffs[i] = int64(c)
+			}
+		}
+		return
+	}
+
+	for _, params := range []struct {
+		sigma float64
+		bound uint64
+	}{
+		{DefaultSigma, DefaultBound},
+		{1 << 20, 6 << 20},
+		{1 << 40, 6 << 40},
+	} {
+
+		t.Run(testString(fmt.Sprintf("ConstantTimeGaussianSampler/sigma=%g/", params.sigma), ringQ), func(t *testing.T) {
+
+			gaussianSampler := NewConstantTimeGaussianSampler(testContext.prng, ringQ, DefaultSigma, DefaultBound)
+
+			pol := ringQ.NewPoly()
+			gaussianSampler.ReadLvlWithParameters(uint64(len(ringQ.Modulus)-1), params.sigma, params.bound, pol)
+
+			var variance float64
+			for i, c := range centered(pol) {
+
+				require.LessOrEqual(t, uint64(math.Abs(float64(c))), params.bound)
+
+				for j, qi := range ringQ.Modulus {
+					require.Equal(t, (uint64(c)+qi)%qi, pol.Coeffs[j][i])
+				}
+
+				variance += float64(c) * float64(c)
+			}
+
+			std := math.Sqrt(variance / float64(ringQ.N))
+			require.InDelta(t, params.sigma, std, params.sigma*0.1)
+
+			// ReadAndAdd adds a fresh sample
+			sum := pol.CopyNew()
+			gaussianSampler.ReadAndAddLvlWithParameters(uint64(len(ringQ.Modulus)-1), params.sigma, params.bound, sum)
+			ringQ.Sub(sum, pol, sum)
+			for _, c := range centered(sum) {
+				require.LessOrEqual(t, uint64(math.Abs(float64(c))), params.bound)
+			}
+		})
+	}
+
+	t.Run(testString("ConstantTimeGaussianSampler/SmallModulus/", ringQ), func(t *testing.T) {
+
+		// 28-bit NTT-friendly modulus for N up to 2^16
+		q := uint64(0xffa0001)
+
+		ringSmall, err := NewRing(ringQ.N, []uint64{q})
+		require.NoError(t, err)
+
+		gaussianSampler := NewConstantTimeGaussianSampler(testContext.prng, ringSmall, DefaultSigma, DefaultBound)
+
+		pol := ringSmall.NewPoly()
+
+		// The noise can be wider than the modulus
+		for _, bound := range []uint64{q - 1, q, 6 << 40} {
+			gaussianSampler.ReadLvlWithParameters(0, float64(bound)/6, bound, pol)
+			for _, c := range pol.Coeffs[0] {
+				require.Less(t, c, q)
+			}
+		}
+
+		// Only the table of the last parameters is kept
+		for sigma := 1.0; sigma <= cdtMaxSigma; sigma += 0.5 {
+			gaussianSampler.ReadLvlWithParameters(0, sigma, uint64(6*sigma), pol)
+		}
+		require.Equal(t, cdtParameters{cdtMaxSigma, 6 * cdtMaxSigma}, gaussianSampler.params)
+		require.Equal(t, newCDT(cdtMaxSigma, 6*cdtMaxSigma), gaussianSampler.table)
+	})
+
+	t.Run(testString("ConstantTimeGaussianSampler/Reduce/", ringQ), func(t *testing.T) {
+
+		q := ringQ.Modulus[0]
+		bredParams := ringQ.BredParams[0]
+		bigQ := NewUint(q)
+
+		coeffs := []int64{0, 1, -1, int64(q) - 1, int64(q), int64(q) + 1, -int64(q), -int64(q) - 1, 1<<62 - 1, -(1<<62 - 1)}
+		for i := 0; i < 1024; i++ {
+			coeffs = append(coeffs, rand.Int63()-(1<<62))
+		}
+
+		for _, coeff := range coeffs {
+			want := new(big.Int).Mod(big.NewInt(coeff), bigQ).Uint64()
+			require.Equal(t, want, constantTimeReduce(coeff, q, bredParams), coeff)
+		}
+	})
+
+	t.Run(testString("ConstantTimeGaussianSampler/MaxBound/", ringQ), func(t *testing.T) {
+
+		// The combinations of the samples do not overflow for the largest bound, larger than the moduli
+		gaussianSampler := NewConstantTimeGaussianSampler(testContext.prng, ringQ, DefaultSigma, DefaultBound)
+
+		bound := uint64(1 << 61)
+		baseTable, table, bounds := gaussianSampler.tablesFor(1<<60, bound)
+
+		// |x1| <= 12 * cdtBaseSigma and |x2| is at most the bound of the combination divided by cdtConvolutionFactor
+		require.Equal(t, 12*cdtBaseSigma, len(baseTable))
+		require.LessOrEqual(t, int64(cdtConvolutionFactor*len(table)), bounds[len(bounds)-1])
+		for i := range bounds[1:] {
+			require.LessOrEqual(t, cdtConvolutionFactor*bounds[i+1], bounds[i])
+		}
+
+		for i := uint64(0); i < ringQ.N; i++ {
+			c := gaussianSampler.sample(baseTable, table, bounds)
+			require.LessOrEqual(t, c, int64(bound))
+			require.GreaterOrEqual(t, c, -int64(bound))
+		}
+	})
+}
+
+func testSampler(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	samplers := map[string]func(prng utils.PRNG) Sampler{
+		"Uniform":       func(prng utils.PRNG) Sampler { return NewUniformSampler(prng, ringQ) },
+		"Ternary":       func(prng utils.PRNG) Sampler { return NewTernarySampler(prng, ringQ, 1.0/3, false) },
+		"TernarySparse": func(prng utils.PRNG) Sampler { return NewTernarySamplerSparse(prng, ringQ, 64, true) },
+		"Gaussian":      func(prng utils.PRNG) Sampler { return NewGaussianSampler(prng, ringQ, DefaultSigma, DefaultBound) },
+		"ConstantTimeGaussian": func(prng utils.PRNG) Sampler {
+			return NewConstantTimeGaussianSampler(prng, ringQ, DefaultSigma, DefaultBound)
+		},
+	}
+
+	for name, newSampler := range samplers {
+
+		t.Run(testString("Sampler/"+name+"/", ringQ), func(t *testing.T) {
+
+			level := uint64(len(ringQ.Modulus) - 2)
+
+			prng1, _ := utils.NewKeyedPRNG([]byte{'l', 'a', 't', 't', 'i', 'g', 'o'})
+			prng2, _ := utils.NewKeyedPRNG([]byte{'l', 'a', 't', 't', 'i', 'g', 'o'})
+
+			sampler1, sampler2 := newSampler(prng1), newSampler(prng2)
+
+			// Twice, so that the temporary polynomials of ReadAndAddLvl are recycled
+			for i := 0; i < 2; i++ {
+
+				// ReadLvl only samples the moduli up to the level
+				p1 := ringQ.NewPoly()
+				sampler1.ReadLvl(level, p1)
+				require.Equal(t, make([]uint64, ringQ.N), p1.Coeffs[level+1])
+
+				// ReadAndAddLvl adds the same sample on a polynomial
+				p2 := testContext.uniformSamplerQ.ReadNew()
+				p3 := p2.CopyNew()
+				sampler2.ReadAndAddLvl(level, p2)
+				ringQ.SubLvl(level, p2, p3, p2)
+				require.True(t, ringQ.EqualLvl(level, p1, p2))
+			}
+		})
+	}
+}
+
+func testGaussianSamplerNTT(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	t.Run(testString("GaussianSampler/ReadAndAddNTT/", ringQ), func(t *testing.T) {
+
+		level := uint64(len(ringQ.Modulus) - 2)
+
+		prng1, _ := utils.NewKeyedPRNG([]byte{'l', 'a', 't', 't', 'i', 'g', 'o'})
+		prng2, _ := utils.NewKeyedPRNG([]byte{'l', 'a', 't', 't', 'i', 'g', 'o'})
+
+		// ReadLvl, NTTLvl and AddLvl
+		want := testContext.uniformSamplerQ.ReadNew()
+		have := want.CopyNew()
+		noise := ringQ.NewPoly()
+		NewGaussianSampler(prng1, ringQ, DefaultSigma, DefaultBound).ReadLvl(level, noise)
+		ringQ.NTTLvl(level, noise, noise)
+		ringQ.AddLvl(level, want, noise, want)
+
+		NewGaussianSampler(prng2, ringQ, DefaultSigma, DefaultBound).ReadAndAddNTTLvl(level, have)
+
+		require.True(t, ringQ.Equal(want, have))
+	})
+}
+
 func testTernarySampler(testContext *testParams, t *testing.T) {
 
 	t.Run(testString("TernarySampler/", testContext.ringQ), func(t *testing.T) {
@@ -384,6 +954,82 @@ func testGaloisShift(testContext *testParams, t *testing.T) {
 			require.Equal(t, pTest.Coeffs[i][:testContext.ringQ.N], pWant.Coeffs[i][:testContext.ringQ.N])
 		}
 	})
+
+	t.Run(testString("PermuteNTTIndexShared/", testContext.ringQ), func(t *testing.T) {
+
+		N := testContext.ringQ.N
+
+		index := PermuteNTTIndexShared(5, 3, N)
+		require.Equal(t, PermuteNTTIndex(5, 3, N), index)
+
+		// Same Galois element, hence same table
+		require.True(t, &index[0] == &PermuteNTTIndexShared(125, 1, N)[0])
+		require.False(t, &index[0] == &PermuteNTTIndexShared(5, 1, N)[0])
+
+		pol := testContext.uniformSamplerQ.ReadNew()
+		want := testContext.ringQ.NewPoly()
+		have := testContext.ringQ.NewPoly()
+		PermuteNTT(pol, 125, want)
+		PermuteNTTWithIndexLvl(uint64(len(pol.Coeffs)-1), pol, index, have)
+		require.True(t, testContext.ringQ.Equal(want, have))
+
+		ClearPermuteNTTIndexCache()
+		require.False(t, &index[0] == &PermuteNTTIndexShared(5, 3, N)[0])
+		require.Equal(t, PermuteNTTIndex(5, 3, N), index)
+	})
+}
+
+func testBitReverse(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	t.Run(testString("BitReverse/Lvl/", ringQ), func(t *testing.T) {
+
+		level := uint64(len(ringQ.Modulus) - 1)
+
+		pWant := testContext.uniformSamplerQ.ReadNew()
+		pTest := ringQ.NewPoly()
+
+		ringQ.BitReverseLvl(level, pWant, pTest)
+		ringQ.BitReverseLvl(level, pTest, pTest)
+
+		require.True(t, ringQ.Equal(pWant, pTest))
+
+		slice := make([]uint64, ringQ.N)
+		for i := range slice {
+			slice[i] = uint64(i)
+		}
+		utils.BitReverseInPlaceUint64(slice, ringQ.N)
+		logN := uint64(bits.Len64(ringQ.N) - 1)
+		for i := range slice {
+			require.Equal(t, utils.BitReverse64(uint64(i), logN), slice[i])
+		}
+	})
+
+	t.Run(testString("BitReverse/NTTRootsExponents/", ringQ), func(t *testing.T) {
+
+		p := testContext.uniformSamplerQ.ReadNew()
+		pNTT := ringQ.NewPoly()
+		ringQ.NTT(p, pNTT)
+
+		e := NTTRootsExponents(ringQ.N)
+
+		qi := ringQ.Modulus[0]
+		psi := InvMForm(ringQ.PsiMont[0], qi, ringQ.MredParams[0])
+
+		// Checks a few evaluations against a naive Horner evaluation
+		for _, j := range []uint64{0, 1, ringQ.N/2 + 3, ringQ.N - 1} {
+
+			x := ModExp(psi, e[j], qi)
+
+			var eval uint64
+			for i := int(ringQ.N) - 1; i >= 0; i-- {
+				eval = BRedAdd(BRed(eval, x, qi, ringQ.BredParams[0])+p.Coeffs[0][i], qi, ringQ.BredParams[0])
+			}
+
+			require.Equal(t, eval, pNTT.Coeffs[0][j])
+		}
+	})
 }
 
 func testMForm(testContext *testParams, t *testing.T) {
@@ -398,6 +1044,60 @@ func testMForm(testContext *testParams, t *testing.T) {
 
 		require.True(t, testContext.ringQ.Equal(polWant, polTest))
 	})
+
+	t.Run(testString("MForm/Lvl/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		level := uint64(len(ringQ.Modulus) - 1)
+		if level > 0 {
+			level--
+		}
+
+		polWant := testContext.uniformSamplerQ.ReadNew()
+		polTest := ringQ.NewPolyLvl(level)
+
+		ringQ.MFormLvl(level, polWant, polTest)
+
+		for i := uint64(0); i < level+1; i++ {
+			for j := uint64(0); j < ringQ.N; j++ {
+				require.Equal(t, MForm(polWant.Coeffs[i][j], ringQ.Modulus[i], ringQ.BredParams[i]), polTest.Coeffs[i][j])
+			}
+		}
+
+		ringQ.InvMFormLvl(level, polTest, polTest)
+		require.True(t, ringQ.EqualLvl(level, polWant, polTest))
+	})
+
+	t.Run(testString("MForm/NTT/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+
+		pol := testContext.uniformSamplerQ.ReadNew()
+		polWant := ringQ.NewPoly()
+		polTest := ringQ.NewPoly()
+
+		ringQ.NTT(pol, polWant)
+		ringQ.MForm(polWant, polWant)
+		ringQ.NTTMForm(pol, polTest)
+
+		require.True(t, ringQ.Equal(polWant, polTest))
+
+		ringQ.InvNTTInvMForm(polTest, polTest)
+
+		require.True(t, ringQ.Equal(pol, polTest))
+
+		level := uint64(len(ringQ.Modulus) - 1)
+		if level > 0 {
+			level--
+		}
+
+		ringQ.NTTMFormLvl(level, pol, polTest)
+		ringQ.InvNTTInvMFormLvl(level, polTest, polTest)
+
+		for i := uint64(0); i < level+1; i++ {
+			require.Equal(t, pol.Coeffs[i], polTest.Coeffs[i])
+		}
+	})
 }
 
 func testMulScalarBigint(testContext *testParams, t *testing.T) {
@@ -440,6 +1140,48 @@ func testMulPoly(testContext *testParams, t *testing.T) {
 		require.Equal(t, p3Want.Coeffs[0][:testContext.ringQ.N], p3Test.Coeffs[0][:testContext.ringQ.N])
 	})
 
+	t.Run(testString("MulPoly/Karatsuba/", testContext.ringQ), func(t *testing.T) {
+
+		testContext.ringQ.MulPolyKaratsuba(p1, p2, p3Test)
+
+		require.True(t, testContext.ringQ.Equal(p3Want, p3Test))
+	})
+
+	t.Run("MulPoly/Karatsuba/NonNTTModuli", func(t *testing.T) {
+
+		// Power of two, composite and prime not congruent to 1 mod 2N
+		N := uint64(256)
+		ringQ, err := NewRing(N, []uint64{1 << 40, 847288609443, 0x1fffffffffffffff})
+		require.Error(t, err)
+		require.False(t, ringQ.AllowsNTT())
+
+		p1, p2 := ringQ.NewPoly(), ringQ.NewPoly()
+		for i, qi := range ringQ.Modulus {
+			for j := uint64(0); j < N; j++ {
+				p1.Coeffs[i][j] = rand.Uint64() % qi
+				p2.Coeffs[i][j] = rand.Uint64() % qi
+			}
+		}
+
+		// Schoolbook negacyclic convolution
+		p3Want := ringQ.NewPoly()
+		for i, qi := range ringQ.Modulus {
+			for j := uint64(0); j < N; j++ {
+				for k := uint64(0); k < N; k++ {
+					c := BRed(p1.Coeffs[i][j], p2.Coeffs[i][k], qi, ringQ.BredParams[i])
+					if j+k < N {
+						p3Want.Coeffs[i][j+k] = CRed(p3Want.Coeffs[i][j+k]+c, qi)
+					} else {
+						p3Want.Coeffs[i][j+k-N] = CRed(p3Want.Coeffs[i][j+k-N]+qi-c, qi)
+					}
+				}
+			}
+		}
+
+		ringQ.MulPoly(p1, p2, p1)
+		require.True(t, ringQ.Equal(p3Want, p1))
+	})
+
 	t.Run(testString("MulPoly/Montgomery/", testContext.ringQ), func(t *testing.T) {
 
 		testContext.ringQ.MForm(p1, p1)
@@ -453,6 +1195,126 @@ func testMulPoly(testContext *testParams, t *testing.T) {
 	})
 }
 
+func testFusedOperations(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+	level := uint64(len(ringQ.Modulus) - 2)
+
+	p1 := testContext.uniformSamplerQ.ReadNew()
+	p2 := testContext.uniformSamplerQ.ReadNew()
+	p3 := testContext.uniformSamplerQ.ReadNew()
+
+	t.Run(testString("Fused/MulCoeffsMontgomeryAndSubLvl/", ringQ), func(t *testing.T) {
+
+		want := ringQ.NewPoly()
+		ringQ.MulCoeffsMontgomeryLvl(level, p1, p2, want)
+		ringQ.SubLvl(level, p3, want, want)
+
+		have := p3.CopyNew()
+		ringQ.MulCoeffsMontgomeryAndSubLvl(level, p1, p2, have)
+		require.True(t, ringQ.EqualLvl(level, want, have))
+
+		have = p3.CopyNew()
+		ringQ.MulCoeffsMontgomeryAndSubNoModLvl(level, p1, p2, have)
+		ringQ.ReduceLvl(level, have, have)
+		require.True(t, ringQ.EqualLvl(level, want, have))
+	})
+
+	t.Run(testString("Fused/MulCoeffsConstantMontgomeryAndAdd/", ringQ), func(t *testing.T) {
+
+		want := p3.CopyNew()
+		ringQ.MulCoeffsMontgomeryAndAdd(p1, p2, want)
+
+		have := p3.CopyNew()
+		ringQ.MulCoeffsConstantMontgomeryAndAdd(p1, p2, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+	})
+
+	t.Run(testString("Fused/AddThenMulScalar/", ringQ), func(t *testing.T) {
+
+		scalar := uint64(0xfffffffffffffff)
+
+		want := ringQ.NewPoly()
+		ringQ.Add(p1, p2, want)
+		ringQ.MulScalar(want, scalar, want)
+
+		have := ringQ.NewPoly()
+		ringQ.AddThenMulScalar(p1, p2, scalar, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+	})
+}
+
+func testLazyReduction(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	p1 := testContext.uniformSamplerQ.ReadNew()
+	p2 := testContext.uniformSamplerQ.ReadNew()
+
+	t.Run(testString("Lazy/Accumulation/", ringQ), func(t *testing.T) {
+
+		// 2 * p1 - p2 + p1 * p2 + p1 * p2 * 2^-64, eagerly reduced
+		want := ringQ.NewPoly()
+		tmp := ringQ.NewPoly()
+		ringQ.Add(p1, p1, want)
+		ringQ.Sub(want, p2, want)
+		ringQ.MulCoeffs(p1, p2, tmp)
+		ringQ.Add(want, tmp, want)
+		ringQ.MulCoeffsMontgomeryAndAdd(p1, p2, want)
+
+		// The same value lazily reduced, in [0, 6q)
+		have := ringQ.NewPoly()
+		ringQ.AddNoMod(p1, p1, have)
+		ringQ.SubNoMod(have, p2, have)
+		ringQ.MulCoeffsAndAddNoMod(p1, p2, have)
+		ringQ.MulCoeffsMontgomeryConstantAndAddNoMod(p1, p2, have)
+
+		for i, qi := range ringQ.Modulus {
+			for _, c := range have.Coeffs[i] {
+				require.Less(t, c, 6*qi)
+			}
+		}
+
+		ringQ.ReduceConstant(have, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+	})
+
+	t.Run(testString("Lazy/ReduceConstant/", ringQ), func(t *testing.T) {
+
+		p := ringQ.NewPoly()
+		for i := range p.Coeffs {
+			for j := range p.Coeffs[i] {
+				p.Coeffs[i][j] = 0xffffffffffffffff - uint64(j)
+			}
+		}
+
+		want, have := ringQ.NewPoly(), ringQ.NewPoly()
+		ringQ.Reduce(p, want)
+		ringQ.ReduceConstant(p, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+	})
+
+	t.Run(testString("Lazy/NTT/", ringQ), func(t *testing.T) {
+
+		// The NTT reduces inputs up to 2^61, as the coefficients modulo a larger modulus given by DivRoundByLastModulusNTT
+		p := ringQ.NewPoly()
+		for i, qi := range ringQ.Modulus {
+			for j, c := range p1.Coeffs[i] {
+				p.Coeffs[i][j] = c + qi*(rand.Uint64()%((1<<61)/qi))
+			}
+		}
+
+		want, have := ringQ.NewPoly(), ringQ.NewPoly()
+		ringQ.NTT(p1, want)
+		ringQ.NTT(p, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+
+		ringQ.NTTMForm(p1, want)
+		ringQ.NTTMForm(p, have)
+		require.Equal(t, want.Coeffs, have.Coeffs)
+	})
+}
+
 func testExtendBasis(testContext *testParams, t *testing.T) {
 
 	t.Run(testString("ExtendBasis/", testContext.ringQ), func(t *testing.T) {
@@ -471,12 +1333,303 @@ func testExtendBasis(testContext *testParams, t *testing.T) {
 		testContext.ringQ.SetCoefficientsBigint(coeffs, Pol)
 		testContext.ringP.SetCoefficientsBigint(coeffs, PolWant)
 
-		basisextender.ModUpSplitQP(uint64(len(testContext.ringQ.Modulus)-1), Pol, PolTest)
+		basisextender.ModUpQtoP(uint64(len(testContext.ringQ.Modulus)-1), Pol, PolTest)
 
 		for i := range testContext.ringP.Modulus {
 			require.Equal(t, PolTest.Coeffs[i][:testContext.ringQ.N], PolWant.Coeffs[i][:testContext.ringQ.N])
 		}
 	})
+	t.Run(testString("ExtendBasis/Lvl/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		ringP := testContext.ringP
+
+		if len(ringQ.Modulus) < 2 {
+			t.Skip("#Qi < 2")
+		}
+
+		basisextender := NewFastBasisExtender(ringQ, ringP)
+
+		levelQ := uint64(len(ringQ.Modulus) - 2)
+
+		Q := new(big.Int).Set(ringQ.ModulusBigint)
+		Q.Quo(Q, NewUint(ringQ.Modulus[levelQ+1]))
+		P := ringP.ModulusBigint
+
+		QP := new(big.Int).Mul(Q, P)
+
+		coeffs := make([]*big.Int, ringQ.N)
+		coeffsQ := make([]*big.Int, ringQ.N)
+		coeffsDiv := make([]*big.Int, ringQ.N)
+		for i := range coeffs {
+			coeffs[i] = RandInt(QP)
+			coeffsQ[i] = new(big.Int).Mod(coeffs[i], Q)
+			coeffsDiv[i] = new(big.Int).Quo(coeffs[i], P)
+		}
+
+		// Contiguous polynomial of QP, the moduli of P following all the moduli of Q
+		ringQP, err := NewRing(ringQ.N, append(append([]uint64{}, ringQ.Modulus...), ringP.Modulus...))
+		require.NoError(t, err)
+
+		polQP := ringQP.NewPoly()
+		polQ := ringQ.NewPoly()
+		polP := ringP.NewPoly()
+		ringQ.SetCoefficientsBigintLvl(levelQ, coeffs, polQ)
+		ringP.SetCoefficientsBigint(coeffs, polP)
+		for i := uint64(0); i < levelQ+1; i++ {
+			copy(polQP.Coeffs[i], polQ.Coeffs[i])
+		}
+		for i := range ringP.Modulus {
+			copy(polQP.Coeffs[len(ringQ.Modulus)+i], polP.Coeffs[i])
+		}
+
+		polPWant := ringP.NewPoly()
+		ringP.SetCoefficientsBigint(coeffsQ, polPWant)
+
+		polDivWant := ringQ.NewPoly()
+		ringQ.SetCoefficientsBigintLvl(levelQ, coeffsDiv, polDivWant)
+
+		polQLvl := ringQ.NewPoly()
+		ringQ.SetCoefficientsBigintLvl(levelQ, coeffsQ, polQLvl)
+
+		t.Run("ModUpQtoP", func(t *testing.T) {
+			polPTest := ringP.NewPoly()
+			basisextender.ModUpQtoP(levelQ, polQLvl, polPTest)
+			require.True(t, ringP.Equal(polPWant, polPTest))
+
+			ringQ.NTTLvl(levelQ, polQLvl, polQLvl)
+			basisextender.ModUpQtoPNTT(levelQ, polQLvl, polPTest)
+			ringQ.InvNTTLvl(levelQ, polQLvl, polQLvl)
+			ringP.InvNTT(polPTest, polPTest)
+			require.True(t, ringP.Equal(polPWant, polPTest))
+		})
+
+		t.Run("ModDownQPtoQ", func(t *testing.T) {
+			polQTest := ringQ.NewPoly()
+			basisextender.ModDownQPtoQ(levelQ, polQP, polQTest)
+			require.True(t, ringQ.EqualLvl(levelQ, polDivWant, polQTest))
+
+			basisextender.ModDownSplitQPtoQ(levelQ, polQ, polP, polQTest)
+			require.True(t, ringQ.EqualLvl(levelQ, polDivWant, polQTest))
+		})
+
+		t.Run("ModDownQPtoQNTT", func(t *testing.T) {
+			polQTest := ringQ.NewPoly()
+			ringQ.NTTLvl(levelQ, polQ, polQ)
+			ringP.NTT(polP, polP)
+			ringQP.NTT(polQP, polQP)
+
+			basisextender.ModDownQPtoQNTT(levelQ, polQP, polQTest)
+			ringQ.InvNTTLvl(levelQ, polQTest, polQTest)
+			require.True(t, ringQ.EqualLvl(levelQ, polDivWant, polQTest))
+
+			polPCopy := polP.CopyNew()
+			basisextender.ModDownSplitQPtoQNTT(levelQ, polQ, polP, polQ)
+			ringQ.InvNTTLvl(levelQ, polQ, polQ)
+			require.True(t, ringQ.EqualLvl(levelQ, polDivWant, polQ))
+			require.True(t, ringP.Equal(polPCopy, polP))
+		})
+	})
+}
+
+func testDecomposer(testContext *testParams, t *testing.T) {
+
+	t.Run(testString("Decomposer/Arbitrary/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		ringP := testContext.ringP
+
+		nQi := uint64(len(ringQ.Modulus))
+
+		// A first element with a single modulus and a second one with all the others, which can be more than #Pi
+		xalpha := []uint64{1}
+		if nQi > 1 {
+			xalpha = append(xalpha, nQi-1)
+		}
+
+		_, err := NewArbitraryDecomposer(ringQ.Modulus, ringP.Modulus, []uint64{nQi + 1})
+		require.Error(t, err)
+		_, err = NewArbitraryDecomposer(ringQ.Modulus, ringP.Modulus, append([]uint64{0}, xalpha...))
+		require.Error(t, err)
+
+		decomposer, err := NewArbitraryDecomposer(ringQ.Modulus, ringP.Modulus, xalpha)
+		require.NoError(t, err)
+		require.Equal(t, xalpha, decomposer.Xalpha())
+
+		for _, level := range []uint64{nQi - 1, nQi / 2} {
+
+			coeffs := make([]*big.Int, ringQ.N)
+			Q := NewUint(1)
+			for _, qi := range ringQ.Modulus[:level+1] {
+				Q.Mul(Q, NewUint(qi))
+			}
+			for i := range coeffs {
+				coeffs[i] = RandInt(Q)
+			}
+
+			p0 := ringQ.NewPoly()
+			ringQ.SetCoefficientsBigintLvl(level, coeffs, p0)
+
+			p1Q := ringQ.NewPoly()
+			p1P := ringP.NewPoly()
+
+			var start uint64
+			for i, alphai := range xalpha {
+
+				if start > level {
+					break
+				}
+
+				// The element of the decomposition is the product of the moduli of Q of the element up to the level
+				Qi := NewUint(1)
+				for _, qi := range ringQ.Modulus[start:utils.MinUint64(start+alphai, level+1)] {
+					Qi.Mul(Qi, NewUint(qi))
+				}
+
+				want := make([]*big.Int, ringQ.N)
+				for j := range want {
+					want[j] = new(big.Int).Mod(coeffs[j], Qi)
+				}
+
+				p1QWant := ringQ.NewPoly()
+				p1PWant := ringP.NewPoly()
+				ringQ.SetCoefficientsBigintLvl(level, want, p1QWant)
+				ringP.SetCoefficientsBigint(want, p1PWant)
+
+				decomposer.DecomposeAndSplit(level, uint64(i), p0, p1Q, p1P)
+
+				require.True(t, ringQ.EqualLvl(level, p1QWant, p1Q))
+				require.True(t, ringP.Equal(p1PWant, p1P))
+
+				start += alphai
+			}
+		}
+	})
+}
+
+func testLargeModuli(testContext *testParams, t *testing.T) {
+
+	N := testContext.ringQ.N
+	logN := uint64(bits.Len64(N) - 1)
+
+	primes := GenerateNTTPrimes(MaxModulusSize, logN, 12)
+
+	ringQ, err := NewRing(N, primes[:10])
+	require.NoError(t, err)
+	ringP, err := NewRing(N, primes[10:])
+	require.NoError(t, err)
+
+	t.Run(testString("LargeModuli/GenerateNTTPrimes/", ringQ), func(t *testing.T) {
+		for _, qi := range primes {
+			require.Equal(t, MaxModulusSize, bits.Len64(qi))
+		}
+		require.Equal(t, uint64(2), ringQ.LazyReductionPeriod())
+		require.Equal(t, uint64(8), testContext.ringQ.LazyReductionPeriod())
+
+		_, err := NewRing(N, GenerateNTTPrimesP(MaxModulusSize+1, logN, 1))
+		require.Error(t, err)
+	})
+
+	t.Run(testString("LargeModuli/MulPoly/", ringQ), func(t *testing.T) {
+
+		ringQ0, err := NewRing(N, primes[:1])
+		require.NoError(t, err)
+
+		sampler := NewUniformSampler(testContext.prng, ringQ0)
+
+		p1 := sampler.ReadNew()
+		p2 := sampler.ReadNew()
+		p3Test := ringQ0.NewPoly()
+		p3Want := ringQ0.NewPoly()
+
+		ringQ0.MulPolyNaive(p1, p2, p3Want)
+		ringQ0.MulPoly(p1, p2, p3Test)
+
+		require.True(t, ringQ0.Equal(p3Want, p3Test))
+	})
+
+	t.Run(testString("LargeModuli/ExtendBasis/", ringQ), func(t *testing.T) {
+
+		basisextender := NewFastBasisExtender(ringQ, ringP)
+
+		coeffs := make([]*big.Int, N)
+		for i := range coeffs {
+			coeffs[i] = RandInt(ringQ.ModulusBigint)
+		}
+
+		polQ := ringQ.NewPoly()
+		polTest := ringP.NewPoly()
+		polWant := ringP.NewPoly()
+
+		ringQ.SetCoefficientsBigint(coeffs, polQ)
+		ringP.SetCoefficientsBigint(coeffs, polWant)
+
+		basisextender.ModUpQtoP(uint64(len(ringQ.Modulus)-1), polQ, polTest)
+
+		require.True(t, ringP.Equal(polWant, polTest))
+	})
+
+	t.Run(testString("LargeModuli/InnerProduct/", ringQ), func(t *testing.T) {
+
+		sampler := NewUniformSampler(testContext.prng, ringQ)
+
+		p1 := make([]*Poly, 17)
+		p2 := make([]*Poly, 17)
+		for i := range p1 {
+			p1[i] = sampler.ReadNew()
+			p2[i] = sampler.ReadNew()
+		}
+
+		want := ringQ.NewPoly()
+		tmp := ringQ.NewPoly()
+		for i := range p1 {
+			ringQ.MulCoeffsMontgomery(p1[i], p2[i], tmp)
+			ringQ.Add(want, tmp, want)
+		}
+
+		have := ringQ.NewPoly()
+		ringQ.InnerProductMontgomeryLvl(uint64(len(ringQ.Modulus)-1), p1, p2, have)
+
+		require.True(t, ringQ.Equal(want, have))
+	})
+}
+
+func testRescaleParams(testContext *testParams, t *testing.T) {
+
+	t.Run(testString("RescaleParams/", testContext.ringQ), func(t *testing.T) {
+
+		Q := testContext.ringQ.Modulus
+
+		params := GenRescaleParams(Q)
+		require.Equal(t, testContext.ringQ.RescaleParams, params)
+
+		for j := 1; j < len(Q); j++ {
+			for i := 0; i < j; i++ {
+				// q_j * q_j^-1 = 1 mod q_i
+				require.Equal(t, uint64(1), BRed(Q[j]%Q[i], InvMForm(params[j-1][i], Q[i], MRedParams(Q[i])), Q[i], BRedParams(Q[i])))
+			}
+		}
+
+		// Incremental update from a prefix of the modulus chain
+		require.Equal(t, params, ExtendRescaleParams(GenRescaleParams(Q[:1]), Q))
+		require.Equal(t, params, ExtendRescaleParams(params[:len(Q)/2], Q))
+	})
+
+	t.Run(testString("ModDownParams/", testContext.ringQ), func(t *testing.T) {
+
+		Q := testContext.ringQ.Modulus
+		P := testContext.ringP.Modulus
+
+		params := GenModDownParams(Q, P)
+
+		for i, qi := range Q {
+			// P * P^-1 = 1 mod q_i
+			PModqi := new(big.Int).Mod(testContext.ringP.ModulusBigint, NewUint(qi)).Uint64()
+			require.Equal(t, uint64(1), BRed(PModqi, InvMForm(params[i], qi, MRedParams(qi)), qi, BRedParams(qi)))
+		}
+
+		require.Equal(t, params, ExtendModDownParams(GenModDownParams(Q[:1], P), Q, P))
+	})
 }
 
 func testScaling(testContext *testParams, t *testing.T) {
@@ -555,3 +1708,344 @@ func testMultByMonomial(testContext *testParams, t *testing.T) {
 		require.Equal(t, p3Want.Coeffs[0][:testContext.ringQ.N], p3Test.Coeffs[0][:testContext.ringQ.N])
 	})
 }
+
+func testSparsePoly(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+	N := ringQ.N
+	level := uint64(len(ringQ.Modulus) - 1)
+
+	sp := ringQ.NewSparsePoly([]uint64{0, 3, N / 2, N - 1}, []int64{1, -1, 7, -123456789})
+
+	dense := ringQ.NewPoly()
+	ringQ.SparsePolyToPolyLvl(level, sp, dense)
+
+	t.Run(testString("SparsePoly/Conversion/", ringQ), func(t *testing.T) {
+		require.Equal(t, 4, sp.HammingWeight())
+		require.Equal(t, ringQ.Modulus[0]-123456789, dense.Coeffs[0][N-1])
+		require.Equal(t, sp, ringQ.NewSparsePolyFromPolyLvl(level, dense))
+		require.Panics(t, func() { ringQ.NewSparsePoly([]uint64{3, 3}, []int64{1, 1}) })
+		require.Panics(t, func() { ringQ.NewSparsePoly([]uint64{N}, []int64{1}) })
+	})
+
+	t.Run(testString("SparsePoly/MulSparse/", ringQ), func(t *testing.T) {
+
+		p1 := testContext.uniformSamplerQ.ReadNew()
+
+		want := ringQ.NewPoly()
+		ringQ.MulPoly(p1, dense, want)
+
+		have := ringQ.NewPoly()
+		ringQ.MulSparse(sp, p1, have)
+		require.True(t, ringQ.Equal(want, have))
+
+		// Monomial
+		ringQ.MultByMonomial(p1, N-1, want)
+		ringQ.MulSparse(ringQ.NewSparsePoly([]uint64{N - 1}, []int64{1}), p1, have)
+		require.True(t, ringQ.Equal(want, have))
+
+		// Accumulation at a lower level
+		p2 := testContext.uniformSamplerQ.ReadNew()
+		want = p2.CopyNew()
+		ringQ.MulPoly(p1, dense, have)
+		ringQ.AddLvl(level-1, want, have, want)
+
+		have = p2.CopyNew()
+		ringQ.MulSparseAndAddLvl(level-1, sp, p1, have)
+		require.True(t, ringQ.EqualLvl(level-1, want, have))
+		require.Equal(t, p2.Coeffs[level], have.Coeffs[level])
+	})
+
+	t.Run(testString("SparsePoly/AddSparse/", ringQ), func(t *testing.T) {
+
+		p1 := testContext.uniformSamplerQ.ReadNew()
+
+		want := ringQ.NewPoly()
+		ringQ.Add(p1, dense, want)
+
+		ringQ.AddSparse(sp, p1, p1)
+		require.True(t, ringQ.Equal(want, p1))
+	})
+}
+
+func testStats(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+	level := uint64(len(ringQ.Modulus) - 1)
+
+	// Coefficients -4, ..., 3 repeated, and one large coefficient -2^70
+	coeffs := make([]*big.Int, ringQ.N)
+	for i := range coeffs {
+		coeffs[i] = big.NewInt(int64(i%8) - 4)
+	}
+
+	p := ringQ.NewPoly()
+	ringQ.SetCoefficientsBigintLvl(level, coeffs, p)
+
+	t.Run(testString("Stats/Norm/", ringQ), func(t *testing.T) {
+		require.Equal(t, big.NewInt(4), ringQ.Norm(p))
+		require.Equal(t, big.NewInt(4), ringQ.NormLvl(0, p))
+	})
+
+	t.Run(testString("Stats/MeanAndVariance/", ringQ), func(t *testing.T) {
+		mean, variance := ringQ.MeanAndVariance(p)
+		require.Equal(t, -0.5, mean)
+		require.Equal(t, 5.25, variance)
+	})
+
+	t.Run(testString("Stats/Histogram/", ringQ), func(t *testing.T) {
+
+		n := ringQ.N / 8
+
+		require.Equal(t, map[int64]uint64{-4: n, -3: n, -2: n, -1: n, 0: n, 1: n, 2: n, 3: n}, ringQ.Histogram(p, 1))
+		require.Equal(t, map[int64]uint64{-2: 2 * n, -1: 2 * n, 0: 2 * n, 1: 2 * n}, ringQ.HistogramLvl(0, p, 2))
+
+		if ringQ.ModulusBigint.BitLen() > 72 {
+			large := ringQ.NewPoly()
+			ringQ.SetCoefficientsBigintLvl(level, []*big.Int{new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 70))}, large)
+			require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 70), ringQ.Norm(large))
+			require.Equal(t, map[int64]uint64{math.MinInt64: 1, 0: ringQ.N - 1}, ringQ.Histogram(large, 1))
+		}
+	})
+}
+
+func testGrowLvl(testContext *testParams, t *testing.T) {
+
+	t.Run(testString("GrowLvl/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		level := uint64(len(ringQ.Modulus) - 1)
+
+		p := ringQ.NewPolyLvl(0)
+		p.Coeffs[0][0] = 1
+
+		ringQ.GrowLvl(level, p)
+		require.Equal(t, len(ringQ.Modulus), len(p.Coeffs))
+		require.Equal(t, uint64(1), p.Coeffs[0][0])
+
+		// Adds and multiplies on all the moduli once grown
+		ringQ.Add(p, p, p)
+		require.Equal(t, uint64(2), p.Coeffs[0][0])
+
+		// Never shrinks
+		ringQ.GrowLvl(0, p)
+		require.Equal(t, len(ringQ.Modulus), len(p.Coeffs))
+	})
+}
+
+func testPolyPool(testContext *testParams, t *testing.T) {
+
+	t.Run(testString("PolyPool/", testContext.ringQ), func(t *testing.T) {
+
+		ringQ := testContext.ringQ
+		level := uint64(len(ringQ.Modulus) - 1)
+
+		p := ringQ.GetPoly()
+		require.Equal(t, len(ringQ.Modulus), len(p.Coeffs))
+		for i := range p.Coeffs {
+			require.Equal(t, ringQ.N, uint64(len(p.Coeffs[i])))
+		}
+
+		// Operates as a polynomial of the Ring before and after a round trip through the pool
+		testContext.uniformSamplerQ.Read(p)
+		ringQ.PutPoly(p)
+
+		p = ringQ.GetPoly()
+		q := ringQ.NewPoly()
+		ringQ.Add(p, q, q)
+		require.True(t, ringQ.Equal(p, q))
+		ringQ.PutPoly(p)
+		ringQ.PutPoly(q)
+
+		// Polynomials are pooled per level
+		for lvl := uint64(0); lvl < level+1; lvl++ {
+			p = ringQ.GetPolyLvl(lvl)
+			require.Equal(t, int(lvl+1), len(p.Coeffs))
+			ringQ.PutPoly(p)
+			require.Equal(t, int(lvl+1), len(ringQ.GetPolyLvl(lvl).Coeffs))
+		}
+
+		// Polynomials of another degree or with too many moduli are discarded
+		ringQ.PutPoly(NewPoly(ringQ.N>>1, level+1))
+		ringQ.PutPoly(NewPoly(ringQ.N, level+2))
+		ringQ.PutPoly(nil)
+		for lvl := uint64(0); lvl < level+1; lvl++ {
+			p = ringQ.GetPolyLvl(lvl)
+			require.Equal(t, int(lvl+1), len(p.Coeffs))
+			require.Equal(t, ringQ.N, uint64(len(p.Coeffs[lvl])))
+		}
+	})
+}
+
+func testDebugChecks(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+	level := uint64(len(ringQ.Modulus) - 1)
+
+	t.Run(testString("DebugChecks/Level/", ringQ), func(t *testing.T) {
+
+		p0 := ringQ.NewPoly()
+		p1 := ringQ.NewPolyLvl(0)
+
+		require.NotPanics(t, func() { checkLevel("AddLvl", ringQ, 0, p0, p1) })
+		require.Panics(t, func() { checkLevel("AddLvl", ringQ, level, p0, p1) })
+		require.Panics(t, func() { checkLevel("AddLvl", ringQ, level+1, p0) })
+		require.Panics(t, func() { checkPolyLevel("AddLvl", ringQ.N<<1, 0, p0) })
+	})
+
+	t.Run(testString("DebugChecks/Aliasing/", ringQ), func(t *testing.T) {
+
+		p0 := ringQ.NewPoly()
+		p1 := ringQ.NewPoly()
+
+		// Shifted by one coefficient and with the moduli in reverse order
+		buff := make([]uint64, ringQ.N+1)
+		p0.Coeffs[0] = buff[:ringQ.N]
+		shifted := &Poly{Coeffs: [][]uint64{buff[1:]}}
+		reversed := &Poly{Coeffs: make([][]uint64, level+1)}
+		for i := range p0.Coeffs {
+			reversed.Coeffs[i] = p0.Coeffs[level-uint64(i)]
+		}
+
+		require.NotPanics(t, func() { checkAliasing("AddLvl", ringQ.N, level, true, p0, p0, p1) })
+		require.NotPanics(t, func() { checkAliasing("Permute", ringQ.N, level, false, p1, p0) })
+		require.Panics(t, func() { checkAliasing("Permute", ringQ.N, level, false, p0, p0) })
+		require.Panics(t, func() { checkAliasing("AddLvl", ringQ.N, 0, true, shifted, p0) })
+
+		if level > 0 {
+			require.Panics(t, func() { checkAliasing("AddLvl", ringQ.N, level, true, reversed, p1, p0) })
+			require.NotPanics(t, func() { checkAliasing("AddLvl", ringQ.N, 0, true, reversed, p1, p0) })
+		}
+
+		// The operations only check their operands if built with the ringdebug tag
+		if debugChecks {
+			require.Panics(t, func() { ringQ.AddLvl(0, p0, p1, shifted) })
+			require.Panics(t, func() { ringQ.MulCoeffsMontgomeryLvl(level, p0, ringQ.NewPolyLvl(0), p1) })
+			require.Panics(t, func() { ringQ.AddThenMulScalarLvl(level, p0, ringQ.NewPolyLvl(0), 3, p1) })
+			require.Panics(t, func() { ringQ.AddThenMulScalarLvl(0, p0, p1, 3, shifted) })
+			require.Panics(t, func() { PermuteNTTLvl(level, p0, 5, p0) })
+		}
+	})
+}
+
+func testAtLevel(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	t.Run(testString("AtLevel/", ringQ), func(t *testing.T) {
+
+		require.True(t, ringQ.AtLevel(ringQ.Level()) == ringQ)
+		require.Panics(t, func() { ringQ.AtLevel(ringQ.Level() + 1) })
+
+		for level := uint64(0); level < ringQ.Level(); level++ {
+
+			view := ringQ.AtLevel(level)
+
+			require.Equal(t, level, view.Level())
+			require.Equal(t, int(level+1), len(view.NewPoly().Coeffs))
+
+			modulus := NewUint(1)
+			for _, qi := range ringQ.Modulus[:level+1] {
+				modulus.Mul(modulus, NewUint(qi))
+			}
+			require.Equal(t, modulus, view.ModulusBigint)
+
+			p0 := testContext.uniformSamplerQ.ReadNew()
+			p1 := testContext.uniformSamplerQ.ReadNew()
+
+			// The view operates as the Lvl operations, and leaves the moduli above the level untouched
+			want := ringQ.NewPoly()
+			have := ringQ.NewPoly()
+
+			ringQ.MulCoeffsMontgomeryLvl(level, p0, p1, want)
+			ringQ.AddLvl(level, want, p1, want)
+			ringQ.NTTLvl(level, want, want)
+
+			view.MulCoeffsMontgomery(p0, p1, have)
+			view.Add(have, p1, have)
+			view.NTT(have, have)
+
+			require.True(t, ringQ.Equal(want, have))
+
+			if level > 0 {
+				p0 = &Poly{Coeffs: p0.Coeffs[:level+1]}
+				ringQ.DivRoundByLastModulusNTTLvl(level, p0, want)
+				view.DivRoundByLastModulusNTT(p0)
+				require.Equal(t, int(level), len(p0.Coeffs))
+				require.True(t, ringQ.EqualLvl(level-1, want, p0))
+			}
+		}
+	})
+}
+
+// countingBackend counts the calls to its NTTs, and is faulty if its NTTs are skipped.
+type countingBackend struct {
+	CPUBackend
+	calls    int
+	skipsNTT bool
+}
+
+func (b *countingBackend) NTTLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	b.calls++
+	if !b.skipsNTT {
+		b.CPUBackend.NTTLvl(r, level, p1, p2)
+	}
+}
+
+func (b *countingBackend) NTTMFormLvl(r *Ring, level uint64, p1, p2 *Poly) {
+	b.calls++
+	if !b.skipsNTT {
+		b.CPUBackend.NTTMFormLvl(r, level, p1, p2)
+	}
+}
+
+func testBackend(testContext *testParams, t *testing.T) {
+
+	ringQ := testContext.ringQ
+
+	t.Run(testString("Backend/CPU/", ringQ), func(t *testing.T) {
+		require.Nil(t, CheckBackend(ringQ, CPUBackend{}, testContext.prng))
+	})
+
+	t.Run(testString("Backend/Dispatch/", ringQ), func(t *testing.T) {
+
+		backend := &countingBackend{}
+		ringQ.SetBackend(backend)
+		defer ringQ.SetBackend(nil)
+
+		require.Nil(t, CheckBackend(ringQ, backend, testContext.prng))
+
+		calls := backend.calls
+		p := testContext.uniformSamplerQ.ReadNew()
+		ringQ.NTT(p, p)
+		ringQ.NTTLvl(0, p, p)
+		ringQ.NTTMForm(p, p)
+		ringQ.NTTMFormLvl(0, p, p)
+		require.Equal(t, calls+4, backend.calls)
+
+		backend.skipsNTT = true
+		require.NotNil(t, CheckBackend(ringQ, backend, testContext.prng))
+	})
+
+	t.Run(testString("Backend/InnerProduct/", ringQ), func(t *testing.T) {
+
+		p1 := make([]*Poly, 17)
+		p2 := make([]*Poly, 17)
+		for i := range p1 {
+			p1[i] = testContext.uniformSamplerQ.ReadNew()
+			p2[i] = testContext.uniformSamplerQ.ReadNew()
+		}
+
+		want := ringQ.NewPoly()
+		tmp := ringQ.NewPoly()
+		for i := range p1 {
+			ringQ.MulCoeffsMontgomery(p1[i], p2[i], tmp)
+			ringQ.Add(want, tmp, want)
+		}
+
+		have := ringQ.NewPoly()
+		ringQ.InnerProductMontgomeryLvl(uint64(len(ringQ.Modulus)-1), p1, p2, have)
+
+		require.True(t, ringQ.Equal(want, have))
+	})
+}
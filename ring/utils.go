@@ -1,6 +1,8 @@
 package ring
 
 import (
+	"errors"
+	"fmt"
 	"math/bits"
 
 	"github.com/ldsec/lattigo/v2/utils"
@@ -133,11 +135,12 @@ func IsPrime(num uint64) bool {
 // best available deviation from the base power of 2 for the given level.
 func GenerateNTTPrimes(logQ, logN, levels uint64) (primes []uint64) {
 
-	if logQ > 61 {
-		panic("logQ must be between 1 and 61")
+	if logQ > MaxModulusSize {
+		panic("logQ must be between 1 and 62")
 	}
 
-	if logQ == 61 {
+	// Above 2^60, the primes are generated downward so that they stay below 2^logQ
+	if logQ > 60 {
 		return GenerateNTTPrimesP(logQ, logN, levels)
 	}
 
@@ -221,39 +224,118 @@ func GenerateNTTPrimesQ(logQ, logN, levels uint64) (primes []uint64) {
 // Special case were primes close to 2^{LogP} but with a smaller bit-size than LogP are sought.
 func GenerateNTTPrimesP(logP, logN, n uint64) (primes []uint64) {
 
-	var x, Ppow2, _2N uint64
+	primes, err := GenerateNTTPrimesNear(NTTPrimeSearch{LogN: logN, Target: 1 << logP, Max: 1 << logP}, n)
+	if err != nil {
+		panic("GenerateNTTPrimesP error: cannot generate enough primes for the given parameters")
+	}
 
-	primes = []uint64{}
+	return primes
+}
 
-	Ppow2 = 1 << logP
+// NTTPrimeSearch describes the primes searched by GenerateNTTPrimesNear.
+type NTTPrimeSearch struct {
 
-	_2N = 2 << logN
+	// The primes are NTT-friendly for the ring degree 2^LogN, i.e. congruent to 1 mod 2^(LogN+1)
+	LogN uint64
 
-	x = Ppow2 + 1
+	// The primes are searched from the closest to Target
+	Target uint64
 
-	for true {
+	// Inclusive bounds of the search below and above Target (no upper bound if Max is 0)
+	Min, Max uint64
+
+	// Additional congruence condition q = Residue mod Modulus, which must imply q = 1 mod 2^(LogN+1) (none if Modulus
+	// is 0), e.g. Modulus = 2^(LogN+2) and Residue = 1 for primes which are NTT-friendly for the ring degree 2^(LogN+1)
+	Modulus, Residue uint64
+}
+
+// GenerateNTTPrimesNear returns the n primes satisfying the conditions of search that are the closest to its target,
+// ordered by increasing distance to the target, the prime above the target first for equal distances. Unlike
+// GenerateNTTPrimes, which searches around powers of two, the target can be arbitrary, e.g. the scale of a
+// ciphertext, so that the error of the rescaling by the primes is controlled. It returns an error if the conditions
+// are inconsistent or if there are less than n primes within the bounds of the search. The primes larger than
+// 2^MaxModulusSize do not allow the NTT of a Ring.
+func GenerateNTTPrimesNear(search NTTPrimeSearch, n uint64) (primes []uint64, err error) {
 
-		// We start by subtracting 2N to ensure that the prime bit-length is smaller than LogP
+	if search.LogN > 62 {
+		return nil, errors.New("cannot GenerateNTTPrimesNear: LogN must be at most 62")
+	}
 
-		if x > _2N {
+	_2N := uint64(2) << search.LogN
 
-			x -= _2N
+	modulus, residue := search.Modulus, search.Residue
+	if modulus == 0 {
+		modulus, residue = _2N, 1
+	}
 
-			if IsPrime(x) {
+	if modulus%_2N != 0 || residue >= modulus || residue%_2N != 1 {
+		return nil, errors.New("cannot GenerateNTTPrimesNear: the congruence must imply q = 1 mod 2N")
+	}
 
-				primes = append(primes, x)
+	// Otherwise all the candidates would be divisible by gcd(residue, modulus)
+	if gcd(residue, modulus) != 1 {
+		return nil, errors.New("cannot GenerateNTTPrimesNear: the residue must be coprime with the modulus")
+	}
 
-				if uint64(len(primes)) == n {
-					return primes
-				}
+	max := search.Max
+	if max == 0 {
+		max = 0xffffffffffffffff
+	}
+
+	if search.Min > search.Target || search.Target > max {
+		return nil, errors.New("cannot GenerateNTTPrimesNear: the target must be within the bounds")
+	}
+
+	target := search.Target
+
+	// Candidates above and below the target, the target itself being a candidate above it
+	var up, down uint64
+	var hasUp, hasDown bool
+
+	if rem := (target%modulus + modulus - residue) % modulus; target >= rem {
+		if down = target - rem; down == target {
+			up, hasUp = down, true
+			down, hasDown = down-modulus, down >= modulus
+		} else {
+			hasDown = true
+			up, hasUp = down+modulus, down <= 0xffffffffffffffff-modulus
+		}
+	} else {
+		up, hasUp = residue, true
+	}
+
+	hasUp = hasUp && up <= max
+	hasDown = hasDown && down >= search.Min
+
+	primes = make([]uint64, 0, n)
+
+	for uint64(len(primes)) < n {
+
+		if !hasUp && !hasDown {
+			return nil, fmt.Errorf("cannot GenerateNTTPrimesNear: only %d primes within the bounds", len(primes))
+		}
+
+		if hasUp && (!hasDown || up-target <= target-down) {
+
+			if IsPrime(up) {
+				primes = append(primes, up)
 			}
 
+			hasUp = max >= modulus && up <= max-modulus
+			up += modulus
+
 		} else {
-			panic("GenerateNTTPrimesP error: cannot generate enough primes for the given parameters")
+
+			if IsPrime(down) {
+				primes = append(primes, down)
+			}
+
+			hasDown = down >= modulus && down-modulus >= search.Min
+			down -= modulus
 		}
 	}
 
-	return
+	return primes, nil
 }
 
 // primitiveRoot computes one primitive root (the smallest) of for the given prime q
@@ -0,0 +1,97 @@
+package ring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// WorkerPool is a pool of goroutines on which the operations of a Ring are computed in parallel across the moduli.
+// A WorkerPool can be shared by several Rings, and used concurrently.
+type WorkerPool struct {
+	workers int
+	tasks   chan func()
+	close   sync.Once
+}
+
+var defaultWorkerPool struct {
+	sync.Once
+	*WorkerPool
+}
+
+// DefaultWorkerPool returns the WorkerPool shared by the Rings that have none, with one worker per CPU.
+// It is created on its first use.
+func DefaultWorkerPool() *WorkerPool {
+	defaultWorkerPool.Do(func() {
+		defaultWorkerPool.WorkerPool = NewWorkerPool(runtime.NumCPU())
+	})
+	return defaultWorkerPool.WorkerPool
+}
+
+// NewWorkerPool creates a new WorkerPool with the given number of workers, which must be at least 1. The calls to Run
+// are computed by the caller along with the idle workers, hence a WorkerPool with a single worker computes them
+// sequentially.
+func NewWorkerPool(workers int) *WorkerPool {
+
+	if workers < 1 {
+		panic("cannot NewWorkerPool: the number of workers must be at least 1")
+	}
+
+	pool := &WorkerPool{workers: workers, tasks: make(chan func())}
+
+	// The caller of Run is the first worker
+	for i := 1; i < workers; i++ {
+		go func() {
+			for task := range pool.tasks {
+				task()
+			}
+		}()
+	}
+
+	return pool
+}
+
+// Workers returns the number of workers of the WorkerPool.
+func (pool *WorkerPool) Workers() int {
+	return pool.workers
+}
+
+// Run calls f(i) for i from 0 to n-1, in parallel on the workers of the WorkerPool, and returns once all the calls
+// have returned. The calls are handed out to the idle workers only, so that f can itself call Run without deadlock.
+func (pool *WorkerPool) Run(n int, f func(i int)) {
+
+	next := int64(-1)
+
+	work := func() {
+		for i := int(atomic.AddInt64(&next, 1)); i < n; i = int(atomic.AddInt64(&next, 1)) {
+			f(i)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+dispatch:
+	for helpers := 1; helpers < pool.workers && helpers < n; helpers++ {
+
+		wg.Add(1)
+
+		select {
+		case pool.tasks <- func() { work(); wg.Done() }:
+		default:
+			// No worker is idle: the caller computes the remaining calls
+			wg.Done()
+			break dispatch
+		}
+	}
+
+	work()
+
+	wg.Wait()
+}
+
+// Close stops the workers of the WorkerPool once they are idle. The WorkerPool must not be used after being closed.
+func (pool *WorkerPool) Close() {
+	pool.close.Do(func() {
+		close(pool.tasks)
+	})
+}
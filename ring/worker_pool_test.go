@@ -0,0 +1,42 @@
+package ring
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool(t *testing.T) {
+
+	pool := NewWorkerPool(4)
+	defer pool.Close()
+
+	t.Run("Run", func(t *testing.T) {
+
+		calls := make([]int32, 100)
+		pool.Run(len(calls), func(i int) {
+			atomic.AddInt32(&calls[i], 1)
+		})
+
+		for i := range calls {
+			require.Equal(t, int32(1), calls[i])
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+
+		var calls int32
+		pool.Run(8, func(i int) {
+			pool.Run(8, func(j int) {
+				atomic.AddInt32(&calls, 1)
+			})
+		})
+
+		require.Equal(t, int32(64), calls)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.Panics(t, func() { NewWorkerPool(0) })
+	})
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is a minimal structured logging interface through which the objects of the library report their internal
+// events, such as the drift of the scales, the fallbacks of heuristics or the generation of keys. The message is
+// followed by alternating keys and values describing the event. The library logs nothing by default, see NopLogger;
+// an application can surface the events in its own logging stack by implementing Logger on top of it.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+}
+
+// NopLogger is a Logger discarding all the messages. It is the default Logger of the library.
+type NopLogger struct{}
+
+// Debug discards the message.
+func (NopLogger) Debug(msg string, keyvals ...interface{}) {}
+
+// Info discards the message.
+func (NopLogger) Info(msg string, keyvals ...interface{}) {}
+
+// Warn discards the message.
+func (NopLogger) Warn(msg string, keyvals ...interface{}) {}
+
+// LogLevel is the severity of a message of a Logger.
+type LogLevel int
+
+// Levels of the messages, in increasing order of severity.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+)
+
+// String returns the name of the level.
+func (level LogLevel) String() string {
+	switch level {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(level))
+}
+
+// StdLogger is a Logger writing the messages of at least a given level on a standard library log.Logger, as a line
+// "LEVEL msg key=value key=value...".
+type StdLogger struct {
+	out      *log.Logger
+	minLevel LogLevel
+}
+
+// NewStdLogger creates a new StdLogger writing the messages of level minLevel or higher on out.
+func NewStdLogger(out *log.Logger, minLevel LogLevel) *StdLogger {
+	return &StdLogger{out: out, minLevel: minLevel}
+}
+
+// Debug writes a message of level LogDebug.
+func (l *StdLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LogDebug, msg, keyvals)
+}
+
+// Info writes a message of level LogInfo.
+func (l *StdLogger) Info(msg string, keyvals ...interface{}) {
+	l.log(LogInfo, msg, keyvals)
+}
+
+// Warn writes a message of level LogWarn.
+func (l *StdLogger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LogWarn, msg, keyvals)
+}
+
+func (l *StdLogger) log(level LogLevel, msg string, keyvals []interface{}) {
+
+	if level < l.minLevel {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v=?", keyvals[i])
+		}
+	}
+
+	l.out.Print(b.String())
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdLogger(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LogInfo)
+
+	logger.Debug("discarded", "k", 1)
+	assert.Equal(t, "", buf.String())
+
+	logger.Info("message", "k", 1, "plan", []int{2, 3})
+	assert.Equal(t, "INFO message k=1 plan=[2 3]\n", buf.String())
+
+	buf.Reset()
+	logger.Warn("odd", "k")
+	assert.Equal(t, "WARN odd k=?\n", buf.String())
+}
+
+func TestNopLogger(t *testing.T) {
+	var logger Logger = NopLogger{}
+	logger.Debug("message", "k", 1)
+	logger.Info("message", "k", 1)
+	logger.Warn("message", "k", 1)
+}
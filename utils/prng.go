@@ -1,9 +1,16 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"errors"
+	"fmt"
+	"io"
+
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/sys/cpu"
 )
 
 // PRNG is an interface for secure (keyed) deterministic generation of random bytes
@@ -11,39 +18,156 @@ type PRNG interface {
 	Clock(sum []byte)
 	GetClock() uint64
 	SetClock(sum []byte, n uint64) error
+	Fork(label []byte) PRNG
+}
+
+// PRNGAlgorithm is the construction generating the bytes of a KeyedPRNG. The parties sharing a sequence of random
+// bytes, e.g. to expand a common reference string, must use the same algorithm, as each one generates a different
+// sequence from the same key.
+type PRNGAlgorithm int
+
+const (
+	// PRNGBlake2b generates the bytes with the extendable-output function of blake2b keyed with the key. It is the
+	// algorithm of NewKeyedPRNG.
+	PRNGBlake2b = PRNGAlgorithm(iota)
+	// PRNGChaCha20 generates the bytes with the keystream of ChaCha20, whose key and nonce are derived from the key
+	// with blake2b. It is the fastest algorithm without hardware support for AES.
+	PRNGChaCha20
+	// PRNGAESCTR generates the bytes with the keystream of AES-256 in counter mode, whose key and initial counter are
+	// derived from the key with blake2b. It is the fastest algorithm with hardware support for AES (e.g. AES-NI).
+	PRNGAESCTR
+)
+
+// String returns the name of the algorithm.
+func (alg PRNGAlgorithm) String() string {
+	switch alg {
+	case PRNGBlake2b:
+		return "Blake2b"
+	case PRNGChaCha20:
+		return "ChaCha20"
+	case PRNGAESCTR:
+		return "AES-CTR"
+	default:
+		return fmt.Sprintf("PRNGAlgorithm(%d)", int(alg))
+	}
+}
+
+// FastestPRNGAlgorithm returns the algorithm with the largest throughput on this machine according to the benchmarks
+// of the package (see BenchmarkPRNG): AES-CTR if the CPU implements AES, ChaCha20 otherwise. It is the algorithm of
+// NewPRNG, whose sequences are not reproduced by other parties.
+func FastestPRNGAlgorithm() PRNGAlgorithm {
+	if cpu.X86.HasAES || cpu.ARM64.HasAES {
+		return PRNGAESCTR
+	}
+	return PRNGChaCha20
 }
 
 // KeyedPRNG is a structure storing the parameters used to securely and deterministically generate shared
-// sequences of random bytes among different parties using the hash function blake2b. Backward sequence
+// sequences of random bytes among different parties using the hash function blake2b, or the stream cipher
+// selected with NewKeyedPRNGWithAlgorithm. Backward sequence
 // security (given the digest i, compute the digest i-1) is ensured by default, however forward sequence
 // security (given the digest i, compute the digest i+1) is only ensured if the KeyedPRNG is keyed.
 type KeyedPRNG struct {
-	clock uint64
-	xof   blake2b.XOF
+	clock   uint64
+	xof     io.Reader
+	alg     PRNGAlgorithm
+	forkKey [blake2b.Size]byte
 }
 
 // NewKeyedPRNG creates a new instance of KeyedPRNG.
 // Accepts an optional key, else set key=nil which is treated as key=[]byte{}
 // WARNING: A PRNG INITIALISED WITH key=nil IS INSECURE!
 func NewKeyedPRNG(key []byte) (*KeyedPRNG, error) {
-	var err error
-	prng := new(KeyedPRNG)
+	return NewKeyedPRNGWithAlgorithm(PRNGBlake2b, key)
+}
+
+// NewKeyedPRNGWithAlgorithm creates a new instance of KeyedPRNG generating its bytes with the algorithm alg.
+// The key can have any length for PRNGChaCha20 and PRNGAESCTR, and at most 64 bytes for PRNGBlake2b.
+// WARNING: A PRNG INITIALISED WITH key=nil IS INSECURE!
+func NewKeyedPRNGWithAlgorithm(alg PRNGAlgorithm, key []byte) (prng *KeyedPRNG, err error) {
+
+	prng = new(KeyedPRNG)
 	prng.clock = 0
-	prng.xof, err = blake2b.NewXOF(blake2b.OutputLengthUnknown, key)
+	prng.alg = alg
+
+	switch alg {
+
+	case PRNGBlake2b:
+		prng.xof, err = blake2b.NewXOF(blake2b.OutputLengthUnknown, key)
+
+	case PRNGChaCha20:
+		seed := derivePRNGSeed(alg, key)
+		var stream *chacha20.Cipher
+		if stream, err = chacha20.NewUnauthenticatedCipher(seed[:chacha20.KeySize], seed[chacha20.KeySize:chacha20.KeySize+chacha20.NonceSize]); err == nil {
+			prng.xof = keystream{stream}
+		}
+
+	case PRNGAESCTR:
+		seed := derivePRNGSeed(alg, key)
+		var block cipher.Block
+		if block, err = aes.NewCipher(seed[:32]); err == nil {
+			prng.xof = keystream{cipher.NewCTR(block, seed[32:32+aes.BlockSize])}
+		}
+
+	default:
+		return nil, fmt.Errorf("cannot NewKeyedPRNGWithAlgorithm: unknown algorithm %v", alg)
+	}
+
+	prng.forkKey = blake2b.Sum512(append([]byte("lattigo-prng-fork-"+alg.String()+":"), key...))
+
 	return prng, err
 }
 
-// NewPRNG creates KeyedPRNG keyed from rand.Read for instances were no key should be provided by the user
+// Fork returns a new KeyedPRNG, of the same algorithm, whose sequence is derived from the key of the KeyedPRNG and
+// from label only: it does not depend on the clock of the KeyedPRNG nor on the other forks, and is independent of
+// their sequences as long as the labels are distinct. The parties sharing a key can thereby derive reproducibly as
+// many generators as needed, e.g. one per element of a common reference string, in any order. Forks can be forked.
+func (prng *KeyedPRNG) Fork(label []byte) PRNG {
+
+	// The key of the fork is the blake2b MAC of the label, under a key derived from the one of the KeyedPRNG
+	mac, err := blake2b.New512(prng.forkKey[:])
+	if err != nil {
+		panic(err)
+	}
+
+	mac.Write(label)
+
+	fork, err := NewKeyedPRNGWithAlgorithm(prng.alg, mac.Sum(nil))
+	if err != nil {
+		panic(err)
+	}
+
+	return fork
+}
+
+// NewPRNG creates KeyedPRNG keyed from rand.Read for instances were no key should be provided by the user.
+// It uses the algorithm returned by FastestPRNGAlgorithm.
 func NewPRNG() (*KeyedPRNG, error) {
-	var err error
-	prng := new(KeyedPRNG)
-	prng.clock = 0
 	randomBytes := make([]byte, 64)
 	if _, err := rand.Read(randomBytes); err != nil {
 		panic("crypto rand error")
 	}
-	prng.xof, err = blake2b.NewXOF(blake2b.OutputLengthUnknown, randomBytes)
-	return prng, err
+	return NewKeyedPRNGWithAlgorithm(FastestPRNGAlgorithm(), randomBytes)
+}
+
+// derivePRNGSeed returns the 64 bytes from which the key and nonce of the stream cipher of alg are taken, as the
+// blake2b hash of the key, separated by the name of the algorithm.
+func derivePRNGSeed(alg PRNGAlgorithm, key []byte) [blake2b.Size]byte {
+	return blake2b.Sum512(append([]byte("lattigo-prng-"+alg.String()+":"), key...))
+}
+
+// keystream reads the keystream of a stream cipher.
+type keystream struct {
+	cipher.Stream
+}
+
+// Read writes the next len(p) bytes of the keystream on p.
+func (ks keystream) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	ks.XORKeyStream(p, p)
+	return len(p), nil
 }
 
 // GetClock returns the value of the clock cycle of the KeyedPRNG.
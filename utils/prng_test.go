@@ -29,4 +29,103 @@ func Test_PRNG(t *testing.T) {
 		require.Equal(t, sum0, sum1)
 	})
 
+	t.Run("PRNG/Algorithms", func(t *testing.T) {
+
+		key := []byte("a key of arbitrary length, longer than the 64 bytes allowed by blake2b")
+
+		sums := map[string]bool{}
+
+		for _, alg := range []PRNGAlgorithm{PRNGBlake2b, PRNGChaCha20, PRNGAESCTR} {
+
+			Ha, err := NewKeyedPRNGWithAlgorithm(alg, key[:32])
+			require.NoError(t, err)
+			Hb, err := NewKeyedPRNGWithAlgorithm(alg, key[:32])
+			require.NoError(t, err)
+
+			sum0 := make([]byte, 512)
+			sum1 := make([]byte, 512)
+
+			require.NoError(t, Ha.SetClock(sum0, 3))
+			require.NoError(t, Hb.SetClock(sum1, 3))
+			Ha.Clock(sum0)
+			Hb.Clock(sum1)
+
+			require.Equal(t, sum0, sum1)
+			require.Equal(t, uint64(4), Ha.GetClock())
+
+			// The sequence does not depend on the size of the reads
+			Hc, _ := NewKeyedPRNGWithAlgorithm(alg, key[:32])
+			sum2 := make([]byte, 4*512)
+			Hc.Clock(sum2)
+			require.Equal(t, sum2[3*512:], sum0)
+
+			// The algorithms generate different sequences from the same key
+			require.False(t, sums[string(sum2)], alg)
+			sums[string(sum2)] = true
+		}
+
+		_, err := NewKeyedPRNGWithAlgorithm(PRNGChaCha20, key)
+		require.NoError(t, err)
+		_, err = NewKeyedPRNGWithAlgorithm(PRNGBlake2b, key)
+		require.Error(t, err)
+		_, err = NewKeyedPRNGWithAlgorithm(PRNGAlgorithm(-1), key)
+		require.Error(t, err)
+	})
+}
+
+func Test_PRNGFork(t *testing.T) {
+
+	key := []byte("shared key")
+
+	for _, alg := range []PRNGAlgorithm{PRNGBlake2b, PRNGChaCha20, PRNGAESCTR} {
+
+		t.Run("PRNG/Fork/"+alg.String(), func(t *testing.T) {
+
+			Ha, _ := NewKeyedPRNGWithAlgorithm(alg, key)
+			Hb, _ := NewKeyedPRNGWithAlgorithm(alg, key)
+
+			sum0 := make([]byte, 256)
+			sum1 := make([]byte, 256)
+
+			// The forks do not depend on the clock of the parent
+			Ha.Clock(sum0)
+			Ha.Fork([]byte("crs")).Clock(sum0)
+			Hb.Fork([]byte("crs")).Clock(sum1)
+			require.Equal(t, sum0, sum1)
+			require.Equal(t, uint64(1), Ha.GetClock())
+
+			// Distinct labels, parent and nested forks give distinct sequences
+			sums := map[string]bool{string(sum0): true}
+
+			for _, prng := range []PRNG{Ha, Hb.Fork([]byte("crs ")), Hb.Fork(nil), Hb.Fork([]byte("crs")).Fork([]byte("crs"))} {
+				sum := make([]byte, 256)
+				prng.Clock(sum)
+				require.False(t, sums[string(sum)])
+				sums[string(sum)] = true
+			}
+
+			// Forks of other keys differ
+			Hc, _ := NewKeyedPRNGWithAlgorithm(alg, []byte("other key"))
+			Hc.Fork([]byte("crs")).Clock(sum1)
+			require.NotEqual(t, sum0, sum1)
+		})
+	}
+}
+
+// BenchmarkPRNG measures the throughput of each algorithm, from which FastestPRNGAlgorithm is chosen.
+func BenchmarkPRNG(b *testing.B) {
+
+	sum := make([]byte, 1<<16)
+
+	for _, alg := range []PRNGAlgorithm{PRNGBlake2b, PRNGChaCha20, PRNGAESCTR} {
+
+		prng, _ := NewKeyedPRNGWithAlgorithm(alg, []byte("key"))
+
+		b.Run(alg.String(), func(b *testing.B) {
+			b.SetBytes(int64(len(sum)))
+			for i := 0; i < b.N; i++ {
+				prng.Clock(sum)
+			}
+		})
+	}
 }
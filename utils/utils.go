@@ -59,6 +59,50 @@ func BitReverse64(index, bitLen uint64) uint64 {
 	return bits.Reverse64(index) >> (64 - bitLen)
 }
 
+// BitReverseInPlaceUint64 applies an in-place bit-reverse permutation on the first N elements of slice, with N a power of two.
+func BitReverseInPlaceUint64(slice []uint64, N uint64) {
+
+	var bit, j uint64
+
+	for i := uint64(1); i < N; i++ {
+
+		bit = N >> 1
+
+		for j >= bit {
+			j -= bit
+			bit >>= 1
+		}
+
+		j += bit
+
+		if i < j {
+			slice[i], slice[j] = slice[j], slice[i]
+		}
+	}
+}
+
+// BitReverseInPlaceComplex128 applies an in-place bit-reverse permutation on the first N elements of slice, with N a power of two.
+func BitReverseInPlaceComplex128(slice []complex128, N uint64) {
+
+	var bit, j uint64
+
+	for i := uint64(1); i < N; i++ {
+
+		bit = N >> 1
+
+		for j >= bit {
+			j -= bit
+			bit >>= 1
+		}
+
+		j += bit
+
+		if i < j {
+			slice[i], slice[j] = slice[j], slice[i]
+		}
+	}
+}
+
 // HammingWeight64 returns the hammingweight if the input value.
 func HammingWeight64(x uint64) uint64 {
 	x -= (x >> 1) & 0x5555555555555555